@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// abortCmd undoes the side effects of a previous run
+var abortCmd = &cobra.Command{
+	Use:   "abort",
+	Short: "Abort a run by closing its merge requests and deleting its branches",
+	Long: `Abort uses the run journal to undo the remote side effects of a previous run.
+
+Open merge requests created by the run are closed with an explanatory note,
+branches it created are deleted, and the run is marked as aborted. Merge
+requests that were already merged or closed are left untouched.`,
+	RunE: runAbort,
+}
+
+func init() {
+	abortCmd.Flags().String("run", "", "Correlation ID of the run to abort")
+	_ = abortCmd.MarkFlagRequired("run")
+
+	rootCmd.AddCommand(abortCmd)
+}
+
+func runAbort(cmd *cobra.Command, _ []string) error {
+	runID, err := cmd.Flags().GetString("run")
+	if err != nil {
+		return fmt.Errorf("failed to read run flag: %w", err)
+	}
+	if runID == "" {
+		return errors.NewValidationError("run is required")
+	}
+
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	log := logger.New(cfg.Debug)
+	log.WithFields(map[string]interface{}{
+		"token_source": cfg.TokenSource,
+		"auth_mode":    cfg.AuthMode,
+	}).Debug("GitLab token resolved")
+
+	runJournal, err := journal.New(cfg.StateDir)
+	if err != nil {
+		return fmt.Errorf("failed to open run journal: %w", err)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"run_id":      runID,
+		"journal_dir": runJournal.Dir(),
+		"operation":   "abort_start",
+	}).Info("Aborting run")
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := workflow.AbortRun(ctx, cfg, runJournal, runID, log)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"run_id":                 result.RunID,
+		"closed_merge_requests":  result.ClosedMergeRequests,
+		"skipped_merge_requests": result.SkippedMergeRequests,
+		"deleted_branches":       result.DeletedBranches,
+		"operation":              "abort_complete",
+	}).Info(result.Message)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+)
+
+// backupsCmd groups the commands working on the backups of local mode
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Manage the backups written by local mode",
+	Args:  cobra.NoArgs,
+}
+
+// backupsPruneCmd removes the backups of a file beyond the retention limits
+var backupsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old backups of a local file",
+	Long: `Prune removes the backups that update --local --backup wrote for a file,
+oldest first by modification time: every backup beyond the --backup-keep newest
+ones and, with --backup-max-age, every backup older than that.
+
+Backups are looked up in --backup-dir, or next to the file when it is not set.
+Use --dry-run to list the backups that would be removed.`,
+	Example: `  go-tag-updater backups prune --file=values.yaml --backup-keep=3
+  go-tag-updater backups prune --file=values.yaml --backup-dir=.backups --backup-max-age=720h --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runBackupsPrune,
+}
+
+func init() {
+	backupsPruneCmd.Flags().StringP("file", "f", "", "File whose backups are pruned")
+	backupsPruneCmd.Flags().String("backup-dir", "", "Directory holding the backups (default next to the file)")
+	addBackupRetentionFlags(backupsPruneCmd.Flags())
+	_ = backupsPruneCmd.MarkFlagRequired("file")
+
+	backupsCmd.AddCommand(backupsPruneCmd)
+	rootCmd.AddCommand(backupsCmd)
+}
+
+func runBackupsPrune(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	flags := cmd.Flags()
+	if cfg.FilePath, err = flags.GetString("file"); err != nil {
+		return fmt.Errorf("failed to read file flag: %w", err)
+	}
+	if cfg.BackupDir, err = flags.GetString("backup-dir"); err != nil {
+		return fmt.Errorf("failed to read backup-dir flag: %w", err)
+	}
+	if cfg.BackupKeep, err = flags.GetInt("backup-keep"); err != nil {
+		return fmt.Errorf("failed to read backup-keep flag: %w", err)
+	}
+	if cfg.BackupMaxAge, err = flags.GetDuration("backup-max-age"); err != nil {
+		return fmt.Errorf("failed to read backup-max-age flag: %w", err)
+	}
+
+	log := logger.New(cfg.Debug)
+	removed, err := workflow.PruneLocalBackups(cfg, log)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Removed %d backups of %s", len(removed), cfg.FilePath)
+	if cfg.DryRun {
+		message = fmt.Sprintf("Dry run: would remove %d backups of %s", len(removed), cfg.FilePath)
+	}
+	log.WithFields(map[string]interface{}{
+		"file_path":       cfg.FilePath,
+		"removed_backups": removed,
+		"dry_run":         cfg.DryRun,
+		"operation":       "backups_prune_complete",
+	}).Info(message)
+	return nil
+}
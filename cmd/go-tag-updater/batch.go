@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// batchCmd updates the files of many projects in one run
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Update the tag of many projects through a pipeline of worker pools",
+	Long: `Batch updates the files listed in a targets file. Each target goes through
+the stages of a pipeline: project resolution, content fetch, YAML update,
+commit and merge request creation. Every stage works on up to --workers
+targets at a time, so a slow project does not hold up the others. A target
+failing at one stage skips the remaining stages and the other targets carry
+on; the failures are reported together at the end.
+
+The outcome of each target is saved to a state file as soon as it finishes
+(default: the targets file followed by .state.json). After an interruption or
+failures, --resume skips the targets completed before and retries the others.
+
+The other settings come from the configuration file and the environment,
+like for the update command. The --targets file lists the files:
+
+  targets:
+    - project_id: mygroup/app-a
+      file: deploy/values.yaml
+    - project_id: mygroup/app-b
+      file: helm/values.yaml
+      yaml_path: image.tag
+      target_branch: develop
+      new_tag: v1.5.0-b`,
+	Example: `  go-tag-updater batch --targets=targets.yaml --new-tag=v1.5.0
+  go-tag-updater batch --targets=targets.yaml --new-tag=v1.5.0 --workers=16 --dry-run
+  go-tag-updater batch --targets=targets.yaml --new-tag=v1.5.0 --resume`,
+	Args:   cobra.NoArgs,
+	PreRun: bindUpdateFlags,
+	RunE:   runBatch,
+}
+
+func init() {
+	flags := batchCmd.Flags()
+	flags.String("targets", "", "File listing the projects and files to update")
+	flags.Int("workers", workflow.DefaultBatchWorkers, "Targets each stage of the pipeline works on at a time")
+	flags.StringP("new-tag", "t", "", "New tag value to set in the YAML files of targets without their own")
+	flags.String("target-branch", DefaultTargetBranch, "Target branch of targets without their own")
+	flags.Bool("auto-merge", false, "Merge the merge requests once their pipelines succeed")
+	flags.String("state-file", "", "File recording the outcome of each target (default <targets>"+
+		workflow.BatchStateFileSuffix+")")
+	flags.Bool("resume", false, "Skip the targets the state file records as completed and retry the others")
+
+	_ = viper.BindPFlag("batch.targets", flags.Lookup("targets"))
+	_ = viper.BindPFlag("batch.workers", flags.Lookup("workers"))
+	_ = viper.BindPFlag("batch.state_file", flags.Lookup("state-file"))
+	rootCmd.AddCommand(batchCmd)
+}
+
+func runBatch(cmd *cobra.Command, _ []string) error {
+	targetsFile := viper.GetString("batch.targets")
+	if targetsFile == "" {
+		return errors.NewValidationError("targets file is required: set --targets or batch.targets")
+	}
+
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	targets, err := workflow.LoadBatchTargets(targetsFile)
+	if err != nil {
+		return err
+	}
+	for i, target := range targets {
+		if target.NewTag == "" && cfg.NewTag == "" {
+			return errors.NewValidationError(fmt.Sprintf("target %d (%s) has no new tag: set --new-tag or new_tag",
+				i+1, target.ProjectID))
+		}
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.AuditSigningKey)
+	logger.RegisterSecret(cfg.ApproverToken)
+	logger.RegisterSecret(cfg.NotifyEmailPassword)
+	log := logger.New(cfg.Debug)
+
+	stateFile := viper.GetString("batch.state_file")
+	if stateFile == "" {
+		stateFile = workflow.DefaultBatchStateFile(targetsFile)
+	}
+	resume, _ := cmd.Flags().GetBool("resume")
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	result, err := workflow.RunBatch(ctx, workflow.BatchOptions{
+		Base:      cfg,
+		Targets:   targets,
+		Workers:   viper.GetInt("batch.workers"),
+		StateFile: stateFile,
+		Resume:    resume,
+	}, log)
+	if result == nil {
+		return err
+	}
+	if printErr := printBatchResult(os.Stdout, result); printErr != nil {
+		return printErr
+	}
+	if err != nil {
+		return fmt.Errorf("%d of %d targets failed: %w", result.Failed, len(targets), err)
+	}
+	return nil
+}
+
+// printBatchResult writes one row per target of a batch run
+func printBatchResult(w io.Writer, result *workflow.BatchResult) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROJECT\tFILE\tSTATUS\tRESULT")
+	for _, target := range result.Targets {
+		status, detail := "updated", ""
+		switch {
+		case target.Resumed:
+			status, detail = "resumed", "completed by a previous run"
+		case target.Err != nil:
+			status, detail = "failed", target.Stage+": "+target.Err.Error()
+		case target.Result.Skipped:
+			status, detail = "skipped", target.Result.Message
+		case target.Result.MergeRequest != nil:
+			detail = target.Result.MergeRequest.WebURL
+		default:
+			detail = target.Result.Message
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", target.Target.ProjectID, target.Target.File, status, detail)
+	}
+	fmt.Fprintf(writer, "\n%d updated, %d skipped, %d failed, %d resumed\n",
+		result.Succeeded, result.Skipped, result.Failed, result.Resumed)
+	return writer.Flush()
+}
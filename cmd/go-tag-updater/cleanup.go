@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// cleanupCmd deletes update branches whose merge requests are finished
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Delete stale update-tag branches whose merge requests are merged or closed",
+	Long: `Cleanup lists the update-tag/ branches of a project and deletes those whose
+merge requests were all merged or closed.
+
+Branches with an open merge request, without any merge request and protected
+branches are kept. With --older-than, branches whose last commit is more recent
+are kept as well. Use --dry-run to list the branches that would be deleted.`,
+	Example: `  go-tag-updater cleanup --project-id=mygroup/myproject --dry-run
+  go-tag-updater cleanup --project-id=mygroup/myproject --older-than=168h`,
+	Args: cobra.NoArgs,
+	RunE: runCleanup,
+}
+
+func init() {
+	cleanupCmd.Flags().StringP("project-id", "p", "", "GitLab project ID or path (group/subgroup/project)")
+	cleanupCmd.Flags().Duration("older-than", 0, "Only delete branches whose last commit is older, e.g. 72h")
+	_ = cleanupCmd.MarkFlagRequired("project-id")
+
+	rootCmd.AddCommand(cleanupCmd)
+}
+
+func runCleanup(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.ProjectID, err = cmd.Flags().GetString("project-id"); err != nil {
+		return fmt.Errorf("failed to read project-id flag: %w", err)
+	}
+	olderThan, err := cmd.Flags().GetDuration("older-than")
+	if err != nil {
+		return fmt.Errorf("failed to read older-than flag: %w", err)
+	}
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	log := logger.New(cfg.Debug)
+	log.WithFields(map[string]interface{}{
+		"project_id": cfg.ProjectID,
+		"older_than": olderThan.String(),
+		"dry_run":    cfg.DryRun,
+		"operation":  "cleanup_start",
+	}).Info("Cleaning up stale branches")
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := workflow.CleanupBranches(ctx, cfg, workflow.CleanupOptions{
+		OlderThan: olderThan,
+		Now:       time.Now(),
+	}, log)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"deleted_branches": result.DeletedBranches,
+		"kept_branches":    len(result.KeptBranches),
+		"operation":        "cleanup_complete",
+	}).Info(result.Message)
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// configCmd groups the commands working on the configuration file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration file",
+	Args:  cobra.NoArgs,
+}
+
+// configValidateCmd checks the configuration file and reports every value
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration file and print a report",
+	Long: `Validate loads the configuration file and the selected profile like every
+command does and checks its values: types, positive timeouts, sane rate limits
+and retry counts, known policies, parseable URLs and time zones.
+
+With --online the GitLab token is then verified against the configured instance
+when every other check passed. Each check is reported as PASS, WARN or FAIL; the
+command fails when any check fails.`,
+	Example: `  go-tag-updater config validate
+  go-tag-updater config validate --config=deploy/go-tag-updater.yaml --profile=prod --online`,
+	Args: cobra.NoArgs,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	configValidateCmd.Flags().Bool("online", false, "Verify the GitLab token against the configured instance")
+
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// validatingConfig reports whether config validate runs; it loads the configuration
+// itself so that load errors end up in its report
+func validatingConfig() bool {
+	cmd, _, err := rootCmd.Find(os.Args[1:])
+	return err == nil && cmd == configValidateCmd
+}
+
+func runConfigValidate(cmd *cobra.Command, _ []string) error {
+	online, err := cmd.Flags().GetBool("online")
+	if err != nil {
+		return fmt.Errorf("failed to read online flag: %w", err)
+	}
+
+	report := config.Validate(config.LoadOptions{ConfigFile: configFile, Profile: configProfile})
+	if online && report.Failures() == 0 {
+		ctx, cancel := commandContext()
+		defer cancel()
+		checkToken(ctx, report)
+	}
+
+	if err := printConfigReport(os.Stdout, report); err != nil {
+		return err
+	}
+	if failures := report.Failures(); failures > 0 {
+		return errors.NewConfigError(fmt.Sprintf("configuration failed %d of %d checks", failures, len(report.Checks)))
+	}
+	return nil
+}
+
+// checkToken adds the outcome of verifying the GitLab token to the report
+func checkToken(ctx context.Context, report *config.ValidationReport) {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		report.Add("token", config.CheckFail, err.Error())
+		return
+	}
+	if cfg.GitLabToken == "" {
+		report.Add("token", config.CheckFail, TokenRequiredMessage)
+		return
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	detail, err := workflow.CheckToken(ctx, cfg)
+	if err != nil {
+		report.Add("token", config.CheckFail, err.Error())
+		return
+	}
+	report.Add("token", config.CheckPass, detail)
+}
+
+// printConfigReport writes one row per check and the overall outcome
+func printConfigReport(w io.Writer, report *config.ValidationReport) error {
+	if err := printChecks(w, "KEY", report); err != nil {
+		return err
+	}
+
+	outcome := config.CheckPass
+	if report.Failures() > 0 {
+		outcome = config.CheckFail
+	}
+	file := report.File
+	if file == "" {
+		file = "none"
+	}
+	if report.Profile != "" {
+		file += ", profile " + report.Profile
+	}
+	_, err := fmt.Fprintf(w, "\nconfig validate %s: file %s\n", outcome, file)
+	return err
+}
+
+// printChecks writes one row per check of a report under the given first column header
+func printChecks(w io.Writer, header string, report *config.ValidationReport) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "%s\tRESULT\tDETAIL\n", header)
+	for _, check := range report.Checks {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", check.Key, check.Status, check.Detail)
+	}
+	return writer.Flush()
+}
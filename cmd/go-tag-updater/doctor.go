@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// doctorCmd diagnoses the environment an update runs in
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check connectivity, TLS, token, project and file and print a checklist",
+	Long: `Doctor runs the checks an update depends on, in order, and prints a checklist:
+
+  connect  the GitLab instance answers
+  tls      its certificate verifies and does not expire within 14 days
+  token    the token is accepted and has the api scope
+  project  the project resolves and the token has at least the Developer role
+  file     the file exists on the target branch, or the default branch
+  yaml     the file parses and holds the tag an update would change
+
+Each check is reported as PASS, WARN, FAIL or SKIP. Checks after a failure are
+skipped, as are the project and file checks when no project or file is given.
+The command fails when any check fails, which makes it a useful first step of a
+CI job.`,
+	Example: `  go-tag-updater doctor
+  go-tag-updater doctor --project-id=mygroup/myproject --file=values.yaml --yaml-path=image.tag`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().StringP("project-id", "p", "", "GitLab project ID or path (group/subgroup/project)")
+	doctorCmd.Flags().StringP("file", "f", "", "Path to the YAML file within repository")
+	doctorCmd.Flags().String("target-branch", "", "Branch to read the file from (default: the default branch)")
+	doctorCmd.Flags().String("yaml-path", "", "Tag field to read (default: auto-detected)")
+
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Flags override the project and file of the configuration only when given
+	for flag, value := range map[string]*string{
+		"project-id":    &cfg.ProjectID,
+		"file":          &cfg.FilePath,
+		"target-branch": &cfg.TargetBranch,
+		"yaml-path":     &cfg.YAMLPath,
+	} {
+		given, err := cmd.Flags().GetString(flag)
+		if err != nil {
+			return fmt.Errorf("failed to read %s flag: %w", flag, err)
+		}
+		if given != "" {
+			*value = given
+		}
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	ctx, cancel := commandContext()
+	defer cancel()
+	report := workflow.RunDoctor(ctx, cfg)
+
+	if err := printDoctorReport(os.Stdout, report); err != nil {
+		return err
+	}
+	if failures := report.Failures(); failures > 0 {
+		return errors.NewValidationError(fmt.Sprintf("doctor found %d failed checks", failures))
+	}
+	return nil
+}
+
+// printDoctorReport writes one row per check and the overall outcome
+func printDoctorReport(w io.Writer, report *config.ValidationReport) error {
+	if err := printChecks(w, "CHECK", report); err != nil {
+		return err
+	}
+
+	outcome := config.CheckPass
+	if report.Failures() > 0 {
+		outcome = config.CheckFail
+	}
+	_, err := fmt.Fprintf(w, "\ndoctor %s\n", outcome)
+	return err
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/features"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+// loadFeatures resolves the feature flags from the configuration file and the
+// environment and warns about deprecated features they set
+func loadFeatures(log *logger.Logger) (*features.Set, error) {
+	set, err := features.Resolve(viper.GetStringMap(features.ConfigKey), os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	set.WarnConfigured(log)
+	return set, nil
+}
+
+// requireFeature fails a command whose feature is disabled
+func requireFeature(name string) error {
+	set, err := loadFeatures(logger.New(viper.GetBool("debug")))
+	if err != nil {
+		return err
+	}
+	return set.Require(name)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DetectedTagMarker marks the tag field an update changes when --yaml-path is not set
+	DetectedTagMarker = "*"
+	// InheritedTagNote marks tag fields merged in through a merge key
+	InheritedTagNote = "inherited"
+	// DocumentNoteFormat names the document of tag fields in multi-document files
+	DocumentNoteFormat = "document %d"
+	// NoteSeparator joins the notes of a tag field
+	NoteSeparator = ", "
+)
+
+// listTagsCmd prints the tag fields detected in a YAML file
+var listTagsCmd = &cobra.Command{
+	Use:   "list-tags",
+	Short: "List the tag fields detected in a YAML file",
+	Long: `List-tags reads a YAML file from GitLab, or from disk with --local, and prints
+every tag field the parser detects with its YAML path, line and current value.
+
+The field marked with * is the one an update changes when --yaml-path is not
+set. Pass another listed path to update --yaml-path to change that field instead.
+Fields merged in through a merge key (<<) are marked inherited and must be
+updated at their source. In multi-document files each field notes the index of
+its document, which update --doc-selector accepts.`,
+	Example: `  go-tag-updater list-tags --project-id=mygroup/myproject --file=values.yaml
+  go-tag-updater list-tags --local --file=charts/app/values.yaml`,
+	Args: cobra.NoArgs,
+	RunE: runListTags,
+}
+
+func init() {
+	listTagsCmd.Flags().StringP("project-id", "p", "", "GitLab project ID or path (group/subgroup/project)")
+	listTagsCmd.Flags().StringP("file", "f", "", "Path to the YAML file within repository, or on disk with --local")
+	listTagsCmd.Flags().String("ref", DefaultTargetBranch, "Branch, tag or commit to read the file from")
+	listTagsCmd.Flags().Bool("local", false, "Read the file from disk instead of GitLab")
+	_ = listTagsCmd.MarkFlagRequired("file")
+
+	rootCmd.AddCommand(listTagsCmd)
+}
+
+func runListTags(cmd *cobra.Command, _ []string) error {
+	filePath, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return fmt.Errorf("failed to read file flag: %w", err)
+	}
+	local, err := cmd.Flags().GetBool("local")
+	if err != nil {
+		return fmt.Errorf("failed to read local flag: %w", err)
+	}
+
+	var parseResult *yaml.ParseResult
+	if local {
+		parseResult, err = workflow.ListLocalTags(filePath)
+	} else {
+		parseResult, err = listRemoteTags(cmd, filePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printTagLocations(os.Stdout, parseResult)
+}
+
+// listRemoteTags reads and parses the file from GitLab
+func listRemoteTags(cmd *cobra.Command, filePath string) (*yaml.ParseResult, error) {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	cfg.FilePath = filePath
+
+	if cfg.ProjectID, err = cmd.Flags().GetString("project-id"); err != nil {
+		return nil, fmt.Errorf("failed to read project-id flag: %w", err)
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("project-id is required unless --local is set")
+	}
+	ref, err := cmd.Flags().GetString("ref")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ref flag: %w", err)
+	}
+
+	if cfg.GitLabToken == "" {
+		return nil, errors.NewValidationError(TokenRequiredMessage)
+	}
+	logger.RegisterSecret(cfg.GitLabToken)
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	return workflow.ListTags(ctx, cfg, ref)
+}
+
+// printTagLocations writes one row per tag field, marking the auto-detected one
+func printTagLocations(w io.Writer, parseResult *yaml.ParseResult) error {
+	if len(parseResult.TagLocations) == 0 {
+		return errors.NewValidationError("no tag fields found in YAML content")
+	}
+
+	detected, err := yaml.DetectTagPath(parseResult)
+	if err != nil {
+		return err
+	}
+	detectedPath := policy.FormatYAMLPath(detected)
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "\tPATH\tLINE\tVALUE\tNOTE")
+	for _, location := range parseResult.TagLocations {
+		path := policy.FormatYAMLPath(location.Path)
+
+		marker := ""
+		if path == detectedPath && !location.Inherited {
+			marker = DetectedTagMarker
+		}
+		var notes []string
+		if len(parseResult.Documents) > 1 {
+			notes = append(notes, fmt.Sprintf(DocumentNoteFormat, location.Document))
+		}
+		if location.Inherited {
+			notes = append(notes, InheritedTagNote)
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%v\t%s\n", marker, path, location.Line, location.Value,
+			strings.Join(notes, NoteSeparator))
+	}
+	return writer.Flush()
+}
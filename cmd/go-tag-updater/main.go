@@ -4,18 +4,14 @@ package main
 import (
 	"fmt"
 	"os"
-)
 
-const (
-	// ExitCodeSuccess indicates successful program execution
-	ExitCodeSuccess = 0
-	// ExitCodeError indicates program execution failed
-	ExitCodeError = 1
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
 func main() {
+	documentFlagEnv(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
-		os.Exit(ExitCodeError)
+		os.Exit(errors.ExitCode(err))
 	}
 }
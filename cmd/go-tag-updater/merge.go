@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// mergeCmd merges a merge request opened by an earlier update
+var mergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Merge a merge request opened by go-tag-updater",
+	Long: `Merge merges an open merge request that go-tag-updater opened, for a
+two-phase flow in CI: one job runs update, a later job merges its merge request.
+
+With --wait-pipeline the head pipeline must succeed first, and with
+--wait-approvals every approval rule must be satisfied; --approver-token
+approves the merge request before waiting. The source branch is deleted after
+the merge unless --keep-branch is set. Drafts and merge requests opened by
+anyone else are refused.`,
+	Example: `  go-tag-updater merge --project-id=mygroup/myproject --mr=42
+  go-tag-updater merge --project-id=mygroup/myproject --mr=42 --wait-pipeline --wait-approvals`,
+	Args:   cobra.NoArgs,
+	PreRun: bindUpdateFlags,
+	RunE:   runMerge,
+}
+
+func init() {
+	flags := mergeCmd.Flags()
+	flags.StringP("project-id", "p", "", "GitLab project ID or path (required)")
+	flags.Int("mr", 0, "IID of the merge request to merge (required)")
+	flags.Bool("wait-pipeline", false, "Wait for the head pipeline to succeed before merging")
+	flags.Duration("pipeline-timeout", gitlabapi.DefaultPipelineTimeout, "Maximum time to wait for the pipeline")
+	flags.Bool("wait-approvals", false, "Wait until every approval rule is satisfied before merging")
+	flags.Duration("approval-timeout", workflow.DefaultApprovalTimeout, "Maximum time to wait for the approvals")
+	flags.String("approver-token", "", "Token of a second user approving the merge request before waiting")
+	flags.Bool("squash", false, "Squash the commits of the merge request")
+	flags.Bool("keep-branch", false, "Keep the source branch after the merge")
+	_ = mergeCmd.MarkFlagRequired("project-id")
+	_ = mergeCmd.MarkFlagRequired("mr")
+	rootCmd.AddCommand(mergeCmd)
+}
+
+func runMerge(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	flags := cmd.Flags()
+	opts := workflow.MergeOptions{
+		WaitPipeline:    cfg.WaitPipeline,
+		PipelineTimeout: cfg.PipelineTimeout,
+		Squash:          cfg.Squash,
+	}
+	opts.MergeRequestIID, _ = flags.GetInt("mr")
+	opts.WaitApprovals, _ = flags.GetBool("wait-approvals")
+	opts.ApprovalTimeout, _ = flags.GetDuration("approval-timeout")
+	opts.KeepBranch, _ = flags.GetBool("keep-branch")
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.ApproverToken)
+	log := logger.New(cfg.Debug)
+
+	log.WithFields(map[string]interface{}{
+		"project_id": cfg.ProjectID,
+		"mr_id":      opts.MergeRequestIID,
+		"operation":  "merge_start",
+	}).Info("Merging merge request")
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := workflow.MergeMergeRequest(ctx, cfg, opts, log)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"mr_url":         result.MergeRequest.WebURL,
+		"branch_deleted": result.BranchDeleted,
+		"operation":      "merge_complete",
+	}).Info(result.Message)
+	return nil
+}
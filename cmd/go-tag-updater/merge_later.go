@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// mergeLaterCmd enables auto-merges that were deferred to working hours
+var mergeLaterCmd = &cobra.Command{
+	Use:   "merge-later",
+	Short: "Enable auto-merge for merge requests deferred to working hours",
+	Long: `Merge-later enables the auto-merges that runs deferred because they finished
+outside the configured working hours (--merge-window).
+
+Each deferred merge request whose time has come gets "merge when pipeline
+succeeds" enabled, as long as it is still open and the current time is within
+the working hours. Run it periodically, for example from a scheduled pipeline.`,
+	RunE: runMergeLater,
+}
+
+func init() {
+	rootCmd.AddCommand(mergeLaterCmd)
+}
+
+func runMergeLater(_ *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	log := logger.New(cfg.Debug)
+
+	runJournal, err := journal.New(cfg.StateDir)
+	if err != nil {
+		return fmt.Errorf("failed to open run journal: %w", err)
+	}
+
+	log.WithFields(map[string]interface{}{
+		"journal_dir":  runJournal.Dir(),
+		"merge_window": cfg.MergeWindow,
+		"operation":    "merge_later_start",
+	}).Info("Processing deferred auto-merges")
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := workflow.ProcessDeferredMerges(ctx, cfg, runJournal, time.Now(), log)
+	if result != nil {
+		log.WithFields(map[string]interface{}{
+			"enabled_runs": result.EnabledRuns,
+			"skipped_runs": result.SkippedRuns,
+			"waiting_runs": result.WaitingRuns,
+			"operation":    "merge_later_complete",
+		}).Info(result.Message)
+	}
+
+	return err
+}
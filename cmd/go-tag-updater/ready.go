@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+var (
+	readyProjectID string
+	readyBatchSize int
+)
+
+// readyCmd marks the draft merge requests of quiet rollouts as ready for review
+var readyCmd = &cobra.Command{
+	Use:   "ready",
+	Short: "Mark draft merge requests from quiet rollouts as ready for review",
+	Long: `Ready converts the draft merge requests opened with --quiet-rollout into
+regular merge requests, oldest first, which notifies their reviewers.
+
+Use --batch-size to convert only a few at a time and run the command again for
+the next batch. Deferred auto-merges of converted merge requests are enabled by
+the merge-later command.`,
+	RunE: runReady,
+}
+
+func init() {
+	readyCmd.Flags().StringVar(&readyProjectID, "project-id", "", "GitLab project ID or path (required)")
+	readyCmd.Flags().IntVar(&readyBatchSize, "batch-size", 0, "Maximum number of merge requests to mark ready (0 = all)")
+	_ = readyCmd.MarkFlagRequired("project-id")
+	rootCmd.AddCommand(readyCmd)
+}
+
+func runReady(_ *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	cfg.ProjectID = readyProjectID
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	log := logger.New(cfg.Debug)
+
+	log.WithFields(map[string]interface{}{
+		"project_id": cfg.ProjectID,
+		"batch_size": readyBatchSize,
+		"operation":  "ready_start",
+	}).Info("Marking quiet rollout drafts as ready")
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := workflow.MarkDraftsReady(ctx, cfg, readyBatchSize, log)
+	if result != nil {
+		log.WithFields(map[string]interface{}{
+			"ready_mrs":   result.ReadyMergeRequests,
+			"pending_mrs": result.PendingMergeRequests,
+			"operation":   "ready_complete",
+		}).Info(result.Message)
+	}
+
+	return err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/features"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/registry"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// registryWatchCmd polls container registries for new tags
+var registryWatchCmd = &cobra.Command{
+	Use:   "registry-watch",
+	Short: "Poll container registries for new tags and open update merge requests",
+	Long: `Registry-watch periodically lists the tags of container images through the
+Docker Registry HTTP API v2. When the highest tag satisfying the version
+constraint of a watch is newer than the tag in its YAML file, an update merge
+request is opened with the settings of the configuration file. An open merge
+request for the same tag is reused, so restarts do not open duplicates.
+
+The --watches file lists the images:
+
+  watches:
+    - image: registry.example.com/team/app
+      constraint: "^1.4"
+      project_id: mygroup/deployments
+      file: apps/app/values.yaml
+      yaml_path: image.tag
+      username_env: REGISTRY_USER
+      password_env: REGISTRY_PASSWORD`,
+	Example: `  go-tag-updater registry-watch --watches=watches.yaml --interval=10m
+  go-tag-updater registry-watch --watches=watches.yaml --once`,
+	RunE: runRegistryWatch,
+}
+
+func init() {
+	registryWatchCmd.Flags().String("watches", "",
+		"File listing the watched images and the files their tags are written to")
+	registryWatchCmd.Flags().Duration("interval", config.DefaultRegistryWatchInterval, "Time between two polls")
+	registryWatchCmd.Flags().Bool("once", false, "Poll every watch once and exit, for use from cron jobs")
+
+	_ = viper.BindPFlag("registry_watch.watches", registryWatchCmd.Flags().Lookup("watches"))
+	_ = viper.BindPFlag("registry_watch.interval", registryWatchCmd.Flags().Lookup("interval"))
+	rootCmd.AddCommand(registryWatchCmd)
+}
+
+func runRegistryWatch(cmd *cobra.Command, _ []string) error {
+	if err := requireFeature(features.RegistryWatch); err != nil {
+		return err
+	}
+
+	watchesFile := viper.GetString("registry_watch.watches")
+	if watchesFile == "" {
+		return errors.NewValidationError("watches file is required: set --watches or registry_watch.watches")
+	}
+
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+	if cfg.TargetBranch == "" {
+		cfg.TargetBranch = viper.GetString("defaults.target_branch")
+	}
+
+	watches, err := registry.LoadWatches(watchesFile)
+	if err != nil {
+		return err
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.AuditSigningKey)
+	logger.RegisterSecret(cfg.ApproverToken)
+	logger.RegisterSecret(cfg.NotifyEmailPassword)
+	for _, watch := range watches {
+		if watch.PasswordEnv != "" {
+			logger.RegisterSecret(os.Getenv(watch.PasswordEnv))
+		}
+	}
+	log := logger.New(cfg.Debug)
+
+	watcher, err := registry.NewWatcher(registry.WatcherOptions{
+		Base:     cfg,
+		Watches:  watches,
+		Interval: viper.GetDuration("registry_watch.interval"),
+	}, log)
+	if err != nil {
+		return err
+	}
+
+	once, _ := cmd.Flags().GetBool("once")
+	if !once {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return watcher.Run(ctx)
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	failed := 0
+	for _, result := range watcher.Poll(ctx) {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d watches failed", failed, len(watches))
+	}
+	return nil
+}
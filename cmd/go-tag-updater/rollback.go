@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// rollbackCmd restores the tag a previous run replaced
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore the tag a completed run replaced through a new merge request",
+	Long: `Rollback uses the run journal to restore the tag value that a completed run
+replaced. It runs an ordinary update with the previous tag, so the rollback is
+proposed through its own branch and merge request.
+
+When the journal of the run is not on this machine, for example because the run
+happened in another CI job, give --project-id: the run is then found through the
+metadata block of its merge request, and counts as completed once that was merged.
+
+Runs that did not complete are cleaned up with the abort command instead.`,
+	Example: `  go-tag-updater rollback --run 20260101T120000Z-1a2b3c4d
+  go-tag-updater rollback --run 20260101T120000Z-1a2b3c4d --project-id=mygroup/myproject`,
+	Args: cobra.NoArgs,
+	RunE: runRollback,
+}
+
+func init() {
+	rollbackCmd.Flags().String("run", "", "Correlation ID of the run to roll back")
+	_ = rollbackCmd.MarkFlagRequired("run")
+	rollbackCmd.Flags().StringP("project-id", "p", "",
+		"Project to find the merge request of the run in when its journal is not on this machine")
+
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func runRollback(cmd *cobra.Command, _ []string) error {
+	runID, err := cmd.Flags().GetString("run")
+	if err != nil {
+		return fmt.Errorf("failed to read run flag: %w", err)
+	}
+	if runID == "" {
+		return errors.NewValidationError("run is required")
+	}
+	projectID, err := cmd.Flags().GetString("project-id")
+	if err != nil {
+		return fmt.Errorf("failed to read project-id flag: %w", err)
+	}
+
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	runJournal, err := journal.New(cfg.StateDir)
+	if err != nil {
+		return fmt.Errorf("failed to open run journal: %w", err)
+	}
+
+	entry, err := runJournal.Load(runID)
+	if errors.GetErrorCode(err) == errors.ErrCodeFileNotFound && projectID != "" {
+		searchCfg := *cfg
+		searchCfg.ProjectID = projectID
+		logger.RegisterSecret(cfg.GitLabToken)
+		ctx, cancel := commandContext()
+		defer cancel()
+		entry, err = workflow.FindRunEntry(ctx, &searchCfg, runID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load run %s: %w", runID, err)
+	}
+
+	rollbackCfg, err := workflow.RollbackConfig(cfg, entry)
+	if err != nil {
+		return err
+	}
+
+	return runTagUpdate(rollbackCfg)
+}
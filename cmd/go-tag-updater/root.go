@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/Gosayram/go-tag-updater/internal/clock"
 	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/features"
 	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/manifest"
 	"github.com/Gosayram/go-tag-updater/internal/version"
 	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
@@ -30,12 +38,21 @@ const (
 	MinTagLength = 1
 	// MaxTagLength is the maximum allowed length for tag values
 	MaxTagLength = 255
+
+	// TokenRequiredMessage lists every source a GitLab token is read from
+	TokenRequiredMessage = "token is required: set --token, GO_TAG_UPDATER_TOKEN, GITLAB_TOKEN or CI_JOB_TOKEN"
 )
 
 var (
 	// showVersion flag
 	showVersion bool
 
+	// configFile is an explicit configuration file path
+	configFile string
+
+	// configProfile selects a named profile from the configuration file
+	configProfile string
+
 	// Root command
 	rootCmd = &cobra.Command{
 		Use:   AppName,
@@ -44,136 +61,197 @@ var (
 
 It provides intelligent conflict detection, flexible project identification, 
 and comprehensive merge request lifecycle management. The tool supports both 
-numeric project IDs and human-readable project paths.`,
-		RunE: runCommand,
+numeric project IDs and human-readable project paths.
+
+Run "go-tag-updater update" to update a tag through a merge request, or
+"go-tag-updater preview" to see the change first.
+
+` + errors.ExitCodeHelp,
+		PersistentPreRunE: applyFlagEnv,
+		PreRun:            bindUpdateFlags,
+		RunE:              runRoot,
 	}
 )
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	// Required flags
-	rootCmd.Flags().StringP("project-id", "p", "", "GitLab project ID or path (group/subgroup/project)")
-	rootCmd.Flags().StringP("file", "f", "", "Path to target YAML file within repository")
-	rootCmd.Flags().StringP("new-tag", "t", "", "New tag value to set in YAML file")
-	rootCmd.Flags().StringP("token", "", "", "GitLab Personal Access Token")
-
-	// Optional flags
-	rootCmd.Flags().StringP("branch-name", "b", "", "Name for the new feature branch (auto-generated if empty)")
-	rootCmd.Flags().String("target-branch", DefaultTargetBranch, "Target branch for merge request")
-	rootCmd.Flags().Bool("wait-previous-mr", false, "Wait for conflicting merge requests to complete")
-	rootCmd.Flags().Bool("debug", false, "Enable verbose debugging output")
-	rootCmd.Flags().Bool("dry-run", false, "Preview changes without execution")
-	rootCmd.Flags().Bool("auto-merge", false, "Automatically merge when pipeline passes")
+	// Report malformed flags with the validation exit code
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return errors.NewValidationError(err.Error())
+	})
+
+	rootCmd.PersistentFlags().StringP("token", "", "",
+		"GitLab access token (default from GO_TAG_UPDATER_TOKEN, GITLAB_TOKEN or CI_JOB_TOKEN)")
+	rootCmd.PersistentFlags().String("auth-mode", config.AuthModeAuto,
+		"How the token authenticates: auto, pat, oauth or job")
+
+	// TLS flags for self-hosted instances
+	rootCmd.PersistentFlags().String("ca-cert", "", "PEM bundle of CAs trusted in addition to the system ones")
+	rootCmd.PersistentFlags().String("client-cert", "", "PEM client certificate for GitLab instances requiring mTLS")
+	rootCmd.PersistentFlags().String("client-key", "", "PEM key of --client-cert")
+	rootCmd.PersistentFlags().Bool("insecure-skip-tls-verify", false,
+		"Do not verify the TLS certificate of GitLab (insecure, for testing only)")
+
+	// Proxy flags layered over HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	rootCmd.PersistentFlags().String("proxy", "", "Proxy URL of GitLab requests (default from HTTPS_PROXY/HTTP_PROXY)")
+	rootCmd.PersistentFlags().String("no-proxy", "", "Hosts reached without the proxy (default from NO_PROXY)")
+	rootCmd.PersistentFlags().String("proxy-username", "", "User name authenticating with the proxy")
+	rootCmd.PersistentFlags().String("proxy-password", "",
+		"Password authenticating with the proxy; prefer GO_TAG_UPDATER_PROXY_PASSWORD")
+
+	// HTTP cache flags
+	rootCmd.PersistentFlags().String("http-cache", config.HTTPCacheOff,
+		"Cache project info and file content revalidated with ETags: off, memory or disk")
+	rootCmd.PersistentFlags().String("http-cache-dir", "",
+		"Directory of --http-cache=disk (default ~/.go-tag-updater/http-cache)")
+
+	// Circuit breaker flags
+	rootCmd.PersistentFlags().Int("circuit-breaker-threshold", config.DefaultCircuitBreakerThreshold,
+		"Consecutive GitLab 5xx responses or timeouts pausing all requests (0 disables the circuit breaker)")
+	rootCmd.PersistentFlags().Duration("circuit-breaker-cooldown", config.DefaultCircuitBreakerCoolDown,
+		"How long requests are paused before a probe request checks whether GitLab recovered")
+
+	// Configuration file flags
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "",
+		"Configuration file (default ./"+config.DefaultConfigFile+")")
+	rootCmd.PersistentFlags().StringVar(&configProfile, "profile", "",
+		"Named profile from the configuration file to apply over its defaults, or a built-in updater profile: "+
+			strings.Join(manifest.Names(), ", "))
+
+	// Flags shared by every subcommand
+	rootCmd.PersistentFlags().Bool("debug", false, "Enable verbose debugging output")
+	rootCmd.PersistentFlags().Bool("trace-http", false,
+		"Log GitLab requests with their bodies, secrets redacted; implies tracing of --debug")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Preview changes without execution")
+	rootCmd.PersistentFlags().String("merge-window", "",
+		"Working hours for auto-merge, e.g. \"Mon-Fri 09:00-17:00\"; outside them auto-merge is deferred")
+	rootCmd.PersistentFlags().String("merge-timezone", "", "IANA time zone of --merge-window (default UTC)")
+	rootCmd.PersistentFlags().String("state-dir", "", "Directory holding run journals (default ~/.go-tag-updater/runs)")
+	rootCmd.PersistentFlags().String("timezone", "",
+		"IANA time zone of log, branch name and backup timestamps (default UTC)")
+	rootCmd.PersistentFlags().Duration("timeout", 0,
+		"Abort the command, including in-flight GitLab requests, when it runs longer (0 disables the limit)")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show version information")
 
-	// Bind flags to viper
-	_ = viper.BindPFlag("project-id", rootCmd.Flags().Lookup("project-id"))
-	_ = viper.BindPFlag("file", rootCmd.Flags().Lookup("file"))
-	_ = viper.BindPFlag("new-tag", rootCmd.Flags().Lookup("new-tag"))
-	_ = viper.BindPFlag("token", rootCmd.Flags().Lookup("token"))
-	_ = viper.BindPFlag("branch-name", rootCmd.Flags().Lookup("branch-name"))
-	_ = viper.BindPFlag("target-branch", rootCmd.Flags().Lookup("target-branch"))
-	_ = viper.BindPFlag("wait-previous-mr", rootCmd.Flags().Lookup("wait-previous-mr"))
-	_ = viper.BindPFlag("debug", rootCmd.Flags().Lookup("debug"))
-	_ = viper.BindPFlag("dry-run", rootCmd.Flags().Lookup("dry-run"))
-	_ = viper.BindPFlag("auto-merge", rootCmd.Flags().Lookup("auto-merge"))
-
-	// Don't mark flags as required here - we'll check them in runCommand
-	// This allows version flag to work without other required flags
+	_ = viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
+	_ = viper.BindPFlag("auth-mode", rootCmd.PersistentFlags().Lookup("auth-mode"))
+	_ = viper.BindPFlag("gitlab.ca_cert", rootCmd.PersistentFlags().Lookup("ca-cert"))
+	_ = viper.BindPFlag("gitlab.client_cert", rootCmd.PersistentFlags().Lookup("client-cert"))
+	_ = viper.BindPFlag("gitlab.client_key", rootCmd.PersistentFlags().Lookup("client-key"))
+	_ = viper.BindPFlag("gitlab.insecure_skip_tls_verify", rootCmd.PersistentFlags().Lookup("insecure-skip-tls-verify"))
+	_ = viper.BindPFlag("gitlab.proxy", rootCmd.PersistentFlags().Lookup("proxy"))
+	_ = viper.BindPFlag("gitlab.no_proxy", rootCmd.PersistentFlags().Lookup("no-proxy"))
+	_ = viper.BindPFlag("gitlab.proxy_username", rootCmd.PersistentFlags().Lookup("proxy-username"))
+	_ = viper.BindPFlag("gitlab.proxy_password", rootCmd.PersistentFlags().Lookup("proxy-password"))
+	_ = viper.BindPFlag("gitlab.http_cache", rootCmd.PersistentFlags().Lookup("http-cache"))
+	_ = viper.BindPFlag("gitlab.http_cache_dir", rootCmd.PersistentFlags().Lookup("http-cache-dir"))
+	_ = viper.BindPFlag("gitlab.circuit_breaker_threshold",
+		rootCmd.PersistentFlags().Lookup("circuit-breaker-threshold"))
+	_ = viper.BindPFlag("gitlab.circuit_breaker_cooldown", rootCmd.PersistentFlags().Lookup("circuit-breaker-cooldown"))
+	_ = viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+	_ = viper.BindPFlag("trace-http", rootCmd.PersistentFlags().Lookup("trace-http"))
+	_ = viper.BindPFlag("dry-run", rootCmd.PersistentFlags().Lookup("dry-run"))
+	_ = viper.BindPFlag("defaults.merge_window", rootCmd.PersistentFlags().Lookup("merge-window"))
+	_ = viper.BindPFlag("defaults.merge_timezone", rootCmd.PersistentFlags().Lookup("merge-timezone"))
+	_ = viper.BindPFlag("state.dir", rootCmd.PersistentFlags().Lookup("state-dir"))
+	_ = viper.BindPFlag("timezone", rootCmd.PersistentFlags().Lookup("timezone"))
+	_ = viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
+
+	// The update flags stay on the root command so that invocations predating the
+	// subcommands keep working; they are documented on the update subcommand instead
+	addUpdateFlags(rootCmd.Flags())
+	rootCmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		flag.Hidden = flag.Name != "version"
+	})
 }
 
 func initConfig() {
-	_, err := config.Load()
-	if err != nil {
+	// The configuration file and profile come from the environment before it is loaded
+	if err := config.ApplyFlagEnv(rootCmd.PersistentFlags()); err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
-		os.Exit(1)
+		os.Exit(errors.ExitCode(err))
 	}
-}
-
-func runCommand(_ *cobra.Command, _ []string) error {
-	// Handle version flag first
-	if showVersion {
-		fmt.Println(version.GetFullVersionInfo())
-		return nil
+	if validatingConfig() {
+		return
 	}
 
-	// Check required flags manually
-	projectID := viper.GetString("project-id")
-	filePath := viper.GetString("file")
-	newTag := viper.GetString("new-tag")
-	token := viper.GetString("token")
-
-	if projectID == "" {
-		return errors.NewValidationError("project-id is required")
-	}
-	if filePath == "" {
-		return errors.NewValidationError("file is required")
-	}
-	if newTag == "" {
-		return errors.NewValidationError("new-tag is required")
-	}
-	if token == "" {
-		return errors.NewValidationError("token is required")
-	}
-
-	// Load configuration from viper
-	cfg, err := config.NewFromViper()
+	_, err := config.LoadWithOptions(config.LoadOptions{
+		ConfigFile: configFile,
+		Profile:    configProfile,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(errors.ExitCode(err))
 	}
 
-	// Initialize logger
-	log := logger.New(cfg.Debug)
-
-	log.WithFields(map[string]interface{}{
-		"file_path":  cfg.FilePath,
-		"new_tag":    cfg.NewTag,
-		"project_id": cfg.ProjectID,
-		"operation":  "cli_start",
-	}).Info("Starting go-tag-updater")
+	logger.RegisterSecret(viper.GetString("gitlab.proxy_password"))
 
-	if cfg.DryRun {
-		log.WithField("mode", "dry_run").Info("Dry run mode enabled - no changes will be made")
+	location, err := clock.LoadLocation(viper.GetString("timezone"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		os.Exit(errors.ExitCode(err))
 	}
+	clock.SetLocation(location)
+}
 
-	// Validate inputs (additional validation)
-	if cfg.FilePath == "" {
-		return errors.NewValidationError("file path cannot be empty")
+// commandContext returns the context of a command: it is canceled on an interrupt or
+// SIGTERM and, when --timeout is set, once the command runs longer, so that in-flight
+// GitLab requests are aborted either way. The command then cleans up; a second
+// interrupt exits at once.
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	timeout := viper.GetDuration("timeout")
+	if timeout <= 0 {
+		return ctx, stop
 	}
 
-	if cfg.NewTag == "" {
-		return errors.NewValidationError("new tag cannot be empty")
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
 	}
+}
 
-	if cfg.ProjectID == "" {
-		return errors.NewValidationError("project ID cannot be empty")
-	}
+// applyFlagEnv sets the flags of the running command that were not passed from their
+// GO_TAG_UPDATER_* environment variables
+func applyFlagEnv(cmd *cobra.Command, _ []string) error {
+	return config.ApplyFlagEnv(cmd.Flags())
+}
 
-	if cfg.GitLabToken == "" {
-		return errors.NewValidationError("GitLab token cannot be empty")
+// documentFlagEnv appends the environment variable of every flag to its usage
+func documentFlagEnv(cmd *cobra.Command) {
+	annotate := func(flag *pflag.Flag) {
+		if suffix := fmt.Sprintf(" [$%s]", config.EnvName(flag.Name)); !strings.HasSuffix(flag.Usage, suffix) {
+			flag.Usage += suffix
+		}
 	}
-
-	log.WithOperation("validation").Info("Configuration validated successfully")
-
-	// Execute workflow - just report what would be done for now
-	log.WithFields(map[string]interface{}{
-		"file_path":     cfg.FilePath,
-		"new_tag":       cfg.NewTag,
-		"project_id":    cfg.ProjectID,
-		"target_branch": cfg.TargetBranch,
-		"operation":     "preview",
-	}).Info("Workflow preview")
-
-	if cfg.AutoMerge {
-		log.WithField("auto_merge", true).Info("Auto-merge would be enabled")
+	cmd.PersistentFlags().VisitAll(annotate)
+	cmd.Flags().VisitAll(annotate)
+	for _, child := range cmd.Commands() {
+		documentFlagEnv(child)
 	}
+}
 
-	if cfg.WaitForPreviousMR {
-		log.WithField("wait_previous_mr", true).Info("Would wait for previous merge requests")
+// runRoot shows the version or, for backward compatibility, runs a tag update when
+// update flags are passed to the root command
+func runRoot(cmd *cobra.Command, args []string) error {
+	if showVersion {
+		fmt.Println(version.GetFullVersionInfo())
+		return nil
 	}
 
-	log.WithOperation("cli_complete").Info("Tag update process completed successfully")
+	log := logger.New(viper.GetBool("debug"))
+	set, err := loadFeatures(log)
+	if err != nil {
+		return err
+	}
+	if !set.Enabled(features.RootUpdate) {
+		return errors.NewValidationError("running an update from the root command is disabled: " +
+			"use go-tag-updater update with the same flags")
+	}
+	set.Use(log, features.RootUpdate)
 
-	return nil
+	return runUpdate(cmd, args)
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Self-test phase outcomes as printed
+const (
+	SelfTestPass = "PASS"
+	SelfTestFail = "FAIL"
+	SelfTestSkip = "SKIP"
+)
+
+// selftestCmd verifies a deployment of the tool against a sandbox project
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run a full update cycle against a sandbox project and report each phase",
+	Long: `Selftest verifies a new deployment of the tool end to end against a sandbox
+project: it creates a YAML file on the target branch, updates its tag through a
+branch and merge request, merges the merge request, checks the merged file and
+finally removes the file, branch and merge request again.
+
+Each phase is reported as PASS, FAIL or SKIP; the command fails when any phase
+fails. Only point it at a project meant for testing: the token must be able to
+push to and merge into the target branch.`,
+	Example: `  go-tag-updater selftest --project sandbox/group/project
+  go-tag-updater selftest --project sandbox/group/project --target-branch=selftest`,
+	Args: cobra.NoArgs,
+	RunE: runSelftest,
+}
+
+func init() {
+	selftestCmd.Flags().String("project", "", "Sandbox GitLab project ID or path")
+	selftestCmd.Flags().String("target-branch", "",
+		"Branch of the sandbox project to test against (default: its default branch)")
+	_ = selftestCmd.MarkFlagRequired("project")
+
+	rootCmd.AddCommand(selftestCmd)
+}
+
+func runSelftest(cmd *cobra.Command, _ []string) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if cfg.ProjectID, err = cmd.Flags().GetString("project"); err != nil {
+		return fmt.Errorf("failed to read project flag: %w", err)
+	}
+	if cfg.TargetBranch, err = cmd.Flags().GetString("target-branch"); err != nil {
+		return fmt.Errorf("failed to read target-branch flag: %w", err)
+	}
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.AuditSigningKey)
+	log := logger.New(cfg.Debug)
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	result, err := workflow.RunSelfTest(ctx, cfg, log)
+	if result != nil {
+		if printErr := printSelfTest(os.Stdout, result); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// printSelfTest writes one row per phase and the overall outcome
+func printSelfTest(w io.Writer, result *workflow.SelfTestResult) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PHASE\tRESULT\tDURATION\tDETAIL")
+	for _, phase := range result.Phases {
+		outcome, detail := SelfTestPass, phase.Detail
+		switch {
+		case phase.Skipped:
+			outcome = SelfTestSkip
+		case !phase.Passed:
+			outcome, detail = SelfTestFail, phase.Err.Error()
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", phase.Name, outcome, phase.Duration.Round(time.Millisecond), detail)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	outcome := SelfTestPass
+	if !result.Passed {
+		outcome = SelfTestFail
+	}
+	_, err := fmt.Fprintf(w, "\nselftest %s: project %s, run %s\n", outcome, result.ProjectID, result.RunID)
+	return err
+}
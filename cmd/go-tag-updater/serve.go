@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/features"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/server"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// WebhookSecretEnv is the environment variable the webhook secret is read from
+// when it is not configured otherwise
+const WebhookSecretEnv = "GO_TAG_UPDATER_WEBHOOK_SECRET"
+
+// serveCmd runs tag updates requested over HTTP
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run tag updates triggered by HTTP webhooks",
+	Long: `Serve starts an HTTP server so that registries and CI systems can trigger tag
+updates through webhooks. Every request must carry the webhook secret in the
+X-Webhook-Token header, or as the secret token of a GitLab webhook; registry
+webhooks that cannot set headers may pass it as the token query parameter instead.
+
+  POST /update     {"project_id", "file", "new_tag", "yaml_path", "target_branch", "token_ref"}
+                   starts an update job and returns its job ID and status URL
+  POST /registry/<dockerhub|harbor|gitlab>
+                   receives image push events and starts a job for every route in
+                   the --routes file matching the pushed repository and tag
+  POST /gitlab     receives GitLab push, tag push and merge request events; events
+                   caused by go-tag-updater (a --bot-username author, its label,
+                   description marker or commit trailer) are skipped, and tag
+                   pushes start the routes matching the project path
+  GET  /jobs/<id>  reports the state of a job
+  GET  /healthz    reports that the server is up
+
+Jobs run asynchronously with the settings of the configuration file. Their job ID
+is the run ID, so a job can be aborted or rolled back like any other run. Requests
+never carry GitLab tokens: token_ref names an entry of serve.tokens, which maps to
+the environment variable holding the token, and the server's own token is used
+when token_ref is omitted.`,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().String("listen", config.DefaultServeListen, "Address to listen on")
+	serveCmd.Flags().String("webhook-secret", "", "Secret every request must send (default from "+WebhookSecretEnv+")")
+	serveCmd.Flags().Int("max-concurrent-jobs", config.DefaultServeMaxJobs,
+		"Maximum number of updates run at the same time")
+
+	serveCmd.Flags().String("routes", "", "File mapping image repositories to the files their tags are written to")
+	serveCmd.Flags().StringSlice("bot-username", nil, "GitLab accounts the tool acts as; events they cause are skipped")
+
+	_ = viper.BindPFlag("serve.routes", serveCmd.Flags().Lookup("routes"))
+	_ = viper.BindPFlag("serve.bot_usernames", serveCmd.Flags().Lookup("bot-username"))
+	_ = viper.BindPFlag("serve.listen", serveCmd.Flags().Lookup("listen"))
+	_ = viper.BindPFlag("serve.webhook_secret", serveCmd.Flags().Lookup("webhook-secret"))
+	_ = viper.BindPFlag("serve.max_concurrent_jobs", serveCmd.Flags().Lookup("max-concurrent-jobs"))
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(_ *cobra.Command, _ []string) error {
+	if err := requireFeature(features.Serve); err != nil {
+		return err
+	}
+
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.TargetBranch == "" {
+		cfg.TargetBranch = viper.GetString("defaults.target_branch")
+	}
+
+	secret := viper.GetString("serve.webhook_secret")
+	if secret == "" {
+		secret = os.Getenv(WebhookSecretEnv)
+	}
+	if secret == "" {
+		return errors.NewValidationError("webhook secret is required: set --webhook-secret, serve.webhook_secret or " +
+			WebhookSecretEnv)
+	}
+
+	var routes []server.Route
+	if routesFile := viper.GetString("serve.routes"); routesFile != "" {
+		if routes, err = server.LoadRoutes(routesFile); err != nil {
+			return err
+		}
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.AuditSigningKey)
+	logger.RegisterSecret(cfg.ApproverToken)
+	logger.RegisterSecret(cfg.NotifyEmailPassword)
+	logger.RegisterSecret(secret)
+	log := logger.New(cfg.Debug)
+
+	srv, err := server.New(server.Options{
+		Base:              cfg,
+		WebhookSecret:     secret,
+		Tokens:            viper.GetStringMapString("serve.tokens"),
+		MaxConcurrentJobs: viper.GetInt("serve.max_concurrent_jobs"),
+		Routes:            routes,
+		BotUsernames:      viper.GetStringSlice("serve.bot_usernames"),
+	}, log)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return srv.ListenAndServe(ctx, viper.GetString("serve.listen"))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Output formats of the status command
+const (
+	StatusOutputText = "text"
+	StatusOutputJSON = "json"
+)
+
+// statusCmd reports the state of an update merge request
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Report the state of a merge request opened by go-tag-updater",
+	Long: `Status reports where an update merge request stands: its state, head
+pipeline, approvals and conflicts, and whether the updated file still
+references the new tag. The file is read from the source branch, or from the
+target branch once the merge request was merged.
+
+The merge request is selected by its IID with --mr, or by the run ID recorded
+in its description with --run. --output json prints the status as JSON for
+scripts.`,
+	Example: `  go-tag-updater status --project-id=mygroup/myproject --mr=42
+  go-tag-updater status --project-id=mygroup/myproject --run 20260101T120000Z-1a2b3c4d --output json`,
+	Args:   cobra.NoArgs,
+	PreRun: bindUpdateFlags,
+	RunE:   runStatus,
+}
+
+func init() {
+	flags := statusCmd.Flags()
+	flags.StringP("project-id", "p", "", "GitLab project ID or path (required)")
+	flags.Int("mr", 0, "IID of the merge request")
+	flags.String("run", "", "Correlation ID of the run whose merge request to report")
+	flags.String("output", StatusOutputText, "Output format: text or json")
+	_ = statusCmd.MarkFlagRequired("project-id")
+	rootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	iid, _ := flags.GetInt("mr")
+	runID, _ := flags.GetString("run")
+	output, _ := flags.GetString("output")
+	if (iid > 0) == (runID != "") {
+		return errors.NewValidationError("exactly one of --mr and --run is required")
+	}
+	if output != StatusOutputText && output != StatusOutputJSON {
+		return errors.NewValidationError(fmt.Sprintf("unsupported output format %q: use text or json", output))
+	}
+
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	log := logger.New(cfg.Debug)
+
+	ctx, cancel := commandContext()
+	defer cancel()
+	status, err := workflow.GetMergeRequestStatus(ctx, cfg, iid, runID, log)
+	if err != nil {
+		return err
+	}
+
+	if output == StatusOutputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(status)
+	}
+	return printStatus(os.Stdout, status)
+}
+
+// printStatus writes the status of a merge request as a key/value listing
+func printStatus(w io.Writer, status *workflow.MergeRequestStatus) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(writer, "%s:\t%s\n", key, value)
+		}
+	}
+
+	row("Merge request", fmt.Sprintf("!%d %s", status.IID, status.Title))
+	row("URL", status.WebURL)
+	state := status.State
+	if status.Draft {
+		state += " (draft)"
+	}
+	row("State", state)
+	row("Branches", status.SourceBranch+" -> "+status.TargetBranch)
+	if !status.OwnMergeRequest {
+		row("Opened by", "not go-tag-updater")
+	}
+	row("Run", status.RunID)
+	if status.NewTag != "" {
+		row("Tag", status.OldTag+" -> "+status.NewTag)
+	}
+
+	pipeline := "none"
+	if status.Pipeline != nil {
+		pipeline = fmt.Sprintf("#%d %s", status.Pipeline.ID, status.Pipeline.Status)
+	}
+	row("Pipeline", pipeline)
+	if approvals := status.Approvals; approvals != nil {
+		value := "approved"
+		if !approvals.Approved {
+			value = fmt.Sprintf("%d of %d left", approvals.Left, approvals.Required)
+			if len(approvals.RulesLeft) > 0 {
+				value += " (" + strings.Join(approvals.RulesLeft, ", ") + ")"
+			}
+		}
+		if len(approvals.ApprovedBy) > 0 {
+			value += ", approved by " + strings.Join(approvals.ApprovedBy, ", ")
+		}
+		row("Approvals", value)
+	}
+	row("Conflicts", strconv.FormatBool(status.HasConflicts))
+	row("Merge status", status.DetailedMergeStatus)
+
+	switch {
+	case status.FileError != "":
+		row("File", fmt.Sprintf("%s@%s: %s", status.File, status.FileRef, status.FileError))
+	case status.TagPresent != nil:
+		row("File", fmt.Sprintf("%s@%s references %s: %t", status.File, status.FileRef, status.NewTag,
+			*status.TagPresent))
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/clock"
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/manifest"
+	"github.com/Gosayram/go-tag-updater/internal/metrics"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+	"github.com/Gosayram/go-tag-updater/internal/terminal"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// updateFlagKeys maps the update and preview flags to their configuration keys
+var updateFlagKeys = map[string]string{
+	"project-id":             "project-id",
+	"file":                   "file",
+	"new-tag":                "new-tag",
+	"tag-transform":          "tag-transform",
+	"yaml-path":              "yaml-path",
+	"doc-selector":           "doc-selector",
+	"repo-path-glob":         "repo-path-glob",
+	"old-tag":                "old-tag",
+	"repo-url":               "repo-url",
+	"flux-mode":              "flux-mode",
+	"service":                "service",
+	"branch-name":            "branch-name",
+	"target-branch":          "target-branch",
+	"source-ref":             "source-ref",
+	"on-source-drift":        "defaults.on_source_drift",
+	"create-target-branch":   "create-target-branch",
+	"from":                   "from",
+	"min-interval":           "min-interval",
+	"on-recent-update":       "on-recent-update",
+	"wait-previous-mr":       "wait-previous-mr",
+	"conflict-policy":        "defaults.conflict_policy",
+	"wait-pipeline":          "wait-pipeline",
+	"pipeline-timeout":       "pipeline-timeout",
+	"watch-conflicts":        "watch-conflicts",
+	"auto-rebase":            "auto-rebase",
+	"recreate-on-conflict":   "recreate-on-conflict",
+	"conflict-timeout":       "conflict-timeout",
+	"update-existing-mr":     "update-existing-mr",
+	"auto-merge":             "auto-merge",
+	"quiet-rollout":          "defaults.quiet_rollout",
+	"squash":                 "squash",
+	"remove-source-branch":   "remove-source-branch",
+	"keep-branch-on-failure": "keep-branch-on-failure",
+	"fallback-raw":           "fallback-raw",
+	"resolve-anchors":        "resolve-anchors",
+	"follow-renames":         "follow-renames",
+	"commit-backend":         "commit-backend",
+	"original-backup":        "original-backup",
+	"validate-schema":        "validate-schema",
+	"validate-cmd":           "validate-cmd",
+	"pre-update-cmd":         "hooks.pre_update",
+	"post-commit-cmd":        "hooks.post_commit",
+	"post-mr-cmd":            "hooks.post_mr",
+	"release-notes-file":     "release-notes-file",
+	"release-notes-project":  "release-notes-project",
+	"closes-issues":          "closes-issues",
+	"related-issues":         "related-issues",
+	"comment-issues":         "comment-issues",
+	"approver-token":         "approver-token",
+	"check-approvals":        "check-approvals",
+	"skip-preflight":         "skip-preflight",
+	"gpg-key":                "gpg-key",
+	"run-id":                 "run-id",
+	"metrics-push":           "metrics.push_url",
+	"report-file":            "report-file",
+	"least-privilege":        "policy.least_privilege",
+	"allowed-files":          "policy.allowed_files",
+	"allowed-paths":          "policy.allowed_paths",
+	"max-open-mrs":           "policy.max_open_mrs",
+	"allow":                  "policy.allow_bump",
+	"tag-prefix":             "policy.tag_prefix",
+	"allow-downgrade":        "policy.allow_downgrade",
+	"local":                  "local",
+	"backup":                 "backup",
+	"backup-dir":             "backup-dir",
+	"restore-backup":         "restore-backup",
+	"backup-keep":            "backup-keep",
+	"backup-max-age":         "backup-max-age",
+}
+
+var (
+	// updateCmd updates a tag through a merge request
+	updateCmd = &cobra.Command{
+		Use:   "update",
+		Short: "Update a tag in a YAML file through a merge request",
+		Long: `Update sets a new tag value in a YAML file of a GitLab project.
+
+The change is committed to a new branch and proposed through a merge request,
+optionally merged automatically once its pipeline succeeds. Every run is
+recorded in the run journal so it can be aborted or rolled back later.`,
+		Example: `  go-tag-updater update --project-id=mygroup/myproject --file=values.yaml --new-tag=v1.2.3`,
+		PreRun:  bindUpdateFlags,
+		RunE:    runUpdate,
+	}
+
+	// previewCmd shows the change an update would make without making it
+	previewCmd = &cobra.Command{
+		Use:   "preview",
+		Short: "Preview a tag update without changing anything",
+		Long: `Preview reads the YAML file from GitLab and shows the change an update
+would make, including policy checks, without creating branches, commits or
+merge requests. It is the update command with --dry-run always enabled.`,
+		Example: `  go-tag-updater preview --project-id=mygroup/myproject --file=values.yaml --new-tag=v1.2.3`,
+		PreRun:  bindUpdateFlags,
+		RunE:    runPreview,
+	}
+)
+
+func init() {
+	addUpdateFlags(updateCmd.Flags())
+	addTargetFlags(previewCmd.Flags())
+	addPolicyFlags(previewCmd.Flags())
+	previewCmd.Flags().Bool("local", false, localFlagUsage)
+
+	rootCmd.AddCommand(updateCmd, previewCmd)
+}
+
+// addTargetFlags defines the flags selecting the file, tag and branch to update
+func addTargetFlags(flags *pflag.FlagSet) {
+	flags.StringP("project-id", "p", "", "GitLab project ID or path (group/subgroup/project)")
+	flags.StringP("file", "f", "", "Path to target YAML file within repository")
+	flags.StringP("new-tag", "t", "", "New tag value to set in YAML file")
+	flags.StringArray("tag-transform", nil,
+		"Rewrite the new tag before writing it: strip-v, add-v, prefix=<text>, suffix=<text> or "+
+			"template=<go template> such as {{.Major}}.{{.Minor}}; repeat to chain")
+	flags.String("yaml-path", "",
+		"YAML path of the tag field to update, e.g. image.tag, or the key in .env and .properties files "+
+			"(auto-detected if empty)")
+	flags.String("doc-selector", "",
+		"Document of a multi-document file to update: an index or fields such as kind=Deployment,name=api")
+	flags.String("repo-path-glob", "",
+		"Update every file matching this glob, e.g. environments/prod/**/*.yaml, instead of --file")
+	flags.String("old-tag", "", "With --repo-path-glob, the tag or image tag replaced wherever it is found")
+	flags.String("repo-url", "", "With --profile argocd, update the source of the Application with this repoURL")
+	flags.String("flux-mode", manifest.FluxModeChart,
+		"With --profile flux-helmrelease, the field updated: chart (spec.chart.spec.version) or image "+
+			"(spec.values.image.tag)")
+	flags.String("service", "", "With --profile compose, the service whose image tag is updated")
+	flags.String("target-branch", DefaultTargetBranch, "Target branch for merge request")
+	flags.String("source-ref", "", "Branch, tag or commit the update starts from (default the target branch)")
+	flags.String("on-source-drift", config.SourceDriftRefuse,
+		"What to do when the file differs between --source-ref and the target branch: refuse or warn")
+	flags.Bool("create-target-branch", false, "Create the target branch from --from when it does not exist")
+	flags.String("from", "", "Branch, tag or commit a missing target branch is created from")
+	flags.Bool("fallback-raw", false,
+		"Replace only the tag line when the YAML shares values through anchors and aliases")
+	flags.Bool("resolve-anchors", false,
+		"Update a tag aliases refer to, and re-encode YAML with shared anchors keeping every anchor and alias")
+	flags.Bool("follow-renames", false, "Update the new path of a file that was renamed instead of failing")
+	flags.Duration("min-interval", 0,
+		"Skip the update when go-tag-updater made the last commit to the file within this interval (0 = off)")
+	flags.String("on-recent-update", config.RecentUpdateSkip,
+		"What to do when the file was updated within --min-interval: skip or warn")
+}
+
+// localFlagUsage describes the --local flag shared by update and preview
+const localFlagUsage = "Update the file on disk with the YAML engine only, without GitLab"
+
+// addLocalFlags defines the flags of local mode
+func addLocalFlags(flags *pflag.FlagSet) {
+	flags.Bool("local", false, localFlagUsage)
+	flags.Bool("backup", false, "In local mode, keep a backup of the original file")
+	flags.String("backup-dir", "", "In local mode, directory for backups (default next to the file)")
+	flags.String("restore-backup", "", "In local mode, restore --file from this backup instead of updating it")
+	addBackupRetentionFlags(flags)
+}
+
+// addBackupRetentionFlags defines the flags limiting the backups kept of a file
+func addBackupRetentionFlags(flags *pflag.FlagSet) {
+	flags.Int("backup-keep", yaml.MaxBackupFiles, "In local mode, number of newest backups to keep (0 keeps all)")
+	flags.Duration("backup-max-age", 0, "In local mode, remove backups older than this, e.g. 720h (0 keeps all)")
+}
+
+// addPolicyFlags defines the flags restricting what an update may change
+func addPolicyFlags(flags *pflag.FlagSet) {
+	flags.Bool("least-privilege", false, "Only allow scalar changes to allowed files and YAML paths")
+	flags.StringSlice("allowed-files", nil, "File globs the tool may modify in least-privilege mode")
+	flags.StringSlice("allowed-paths", nil, "YAML paths the tool may modify in least-privilege mode")
+	flags.String("allow", "", "Largest version bump the new tag may make over the current one: patch, minor or major")
+	flags.String("tag-prefix", "", "Prefix every new tag must carry, such as v; the rest must be a semantic version")
+	flags.Bool("allow-downgrade", false, "Allow a new tag lower than the current one")
+}
+
+// addUpdateFlags defines every flag of the update command
+func addUpdateFlags(flags *pflag.FlagSet) {
+	addTargetFlags(flags)
+
+	flags.StringP("branch-name", "b", "", "Name for the new feature branch (auto-generated if empty)")
+	flags.String("conflict-policy", config.ConflictPolicyFail,
+		"What to do while other open merge requests change the same file: fail, wait, force or queue")
+	flags.Bool("wait-previous-mr", false, "Wait for conflicting merge requests to complete")
+	_ = flags.MarkDeprecated("wait-previous-mr", "use --conflict-policy=wait instead")
+	flags.Bool("wait-pipeline", false, "Wait for the merge request pipeline to finish and fail if it does not pass")
+	flags.Duration("pipeline-timeout", gitlabapi.DefaultPipelineTimeout, "Maximum time to wait for the pipeline")
+	flags.Bool("watch-conflicts", false,
+		"Label merge requests with merge conflicts needs-rebase and re-check them until the conflicts are resolved")
+	flags.Bool("auto-rebase", false, "With --watch-conflicts, ask GitLab to rebase a conflicting merge request")
+	flags.Bool("recreate-on-conflict", false,
+		"With --watch-conflicts, replace a conflicting merge request with one from the latest target branch")
+	flags.Duration("conflict-timeout", config.DefaultConflictTimeout,
+		"Maximum time to wait for merge conflicts to be resolved or conflicting merge requests to close")
+	flags.Bool("update-existing-mr", false, "Reuse an open merge request that updates the same file to the same tag")
+	flags.Bool("auto-merge", false, "Automatically merge when pipeline passes")
+	flags.Bool("quiet-rollout", false,
+		"Open merge requests as drafts without notifying reviewers; mark them ready later with the ready command")
+	flags.Bool("squash", false, "Squash commits when the merge request is merged")
+	flags.Bool("remove-source-branch", false, "Delete the source branch when the merge request is merged")
+	flags.Bool("keep-branch-on-failure", false,
+		"Keep the branch of a run that fails or is interrupted before its merge request is opened")
+	flags.String("commit-backend", config.CommitBackendAPI,
+		"How the update is committed: api, or git to push from a local clone with the git binary")
+	flags.String("gpg-key", "", "GPG key ID the commits of --commit-backend=git are signed with")
+	flags.Bool("skip-preflight", false,
+		"Skip the permission, protected branch and push rule checks made before the update branch is created")
+	flags.String("original-backup", config.OriginalBackupNone,
+		"Keep the original file in GitLab for manual rollback: none, description (collapsed in the MR) or file (.orig)")
+	flags.String("validate-schema", "",
+		"Run a minimal structural check of the updated content before committing: k8s for Kubernetes manifests")
+	flags.StringArray("validate-cmd", nil,
+		"Command the updated content must pass before committing, {} standing for its file (repeatable)")
+	flags.StringArray("pre-update-cmd", nil,
+		"Command run before the update branch is changed; failing stops the update (repeatable)")
+	flags.StringArray("post-commit-cmd", nil, "Command run after the update is committed (repeatable)")
+	flags.StringArray("post-mr-cmd", nil, "Command run after the merge request is opened (repeatable)")
+	flags.String("release-notes-file", "", "File whose release notes are included in the merge request description")
+	flags.String("release-notes-project", "",
+		"Project whose GitLab release of the new tag provides the release notes of the merge request description")
+	flags.StringSlice("closes-issues", nil, "Issues the merge request closes when merged, e.g. 123,456")
+	flags.StringSlice("related-issues", nil, "Issues the merge request is related to, e.g. 789")
+	flags.Bool("comment-issues", false, "Comment on the closed and related issues with a link to the merge request")
+	flags.String("approver-token", "",
+		"Token of a second user approving the merge request; the approvals still required are reported")
+	flags.Bool("check-approvals", false, "Report the approvals the merge request still requires")
+	flags.String("run-id", "", "Correlation ID recorded in the run journal (auto-generated if empty)")
+	flags.String("metrics-push", "", "Prometheus Pushgateway URL receiving GitLab API metrics after the run")
+	flags.String("report-file", "", "Write a report of the run to this .json or .md file, e.g. as a CI artifact")
+
+	addPolicyFlags(flags)
+	flags.Int("max-open-mrs", 0,
+		"Refuse to open a merge request when the project has this many open ones from this tool (0 = no limit)")
+
+	addLocalFlags(flags)
+}
+
+// bindUpdateFlags binds the update flags of the command being run to their
+// configuration keys; the root, update and preview commands define their own copies
+func bindUpdateFlags(cmd *cobra.Command, _ []string) {
+	for name, key := range updateFlagKeys {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			_ = viper.BindPFlag(key, flag)
+		}
+	}
+}
+
+// runUpdate runs a tag update
+func runUpdate(_ *cobra.Command, _ []string) error {
+	return executeUpdate(false)
+}
+
+// runPreview runs a tag update in dry-run mode
+func runPreview(_ *cobra.Command, _ []string) error {
+	return executeUpdate(true)
+}
+
+// executeUpdate checks the required flags, loads the configuration and runs the update
+func executeUpdate(preview bool) error {
+	if viper.GetBool("local") {
+		return executeLocalUpdate(preview)
+	}
+
+	// Check required flags manually
+	projectID := viper.GetString("project-id")
+	filePath := viper.GetString("file")
+	repoPathGlob := viper.GetString("repo-path-glob")
+	newTag := viper.GetString("new-tag")
+	token, _ := config.ResolveToken()
+
+	if projectID == "" {
+		return errors.NewValidationError("project-id is required")
+	}
+	if filePath != "" && repoPathGlob != "" {
+		return errors.NewValidationError("file and repo-path-glob cannot be combined")
+	}
+	if filePath == "" && repoPathGlob == "" {
+		return errors.NewValidationError("file is required")
+	}
+	if repoPathGlob != "" && viper.GetString("old-tag") == "" {
+		return errors.NewValidationError("old-tag is required with repo-path-glob")
+	}
+	if newTag == "" {
+		return errors.NewValidationError("new-tag is required")
+	}
+	if token == "" {
+		return errors.NewValidationError(TokenRequiredMessage)
+	}
+
+	// Load configuration from viper
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if preview {
+		cfg.DryRun = true
+	}
+
+	return runTagUpdate(cfg)
+}
+
+// executeLocalUpdate updates or restores a file on disk without contacting GitLab
+func executeLocalUpdate(preview bool) error {
+	cfg, err := config.NewFromViper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if preview {
+		cfg.DryRun = true
+	}
+
+	if cfg.FilePath == "" {
+		return errors.NewValidationError("file is required")
+	}
+
+	log := logger.New(cfg.Debug)
+
+	if cfg.RestoreBackup != "" {
+		if cfg.DryRun {
+			log.WithField("backup_path", cfg.RestoreBackup).Info("Dry run mode: would restore local file from backup")
+			return nil
+		}
+		if err := workflow.RestoreLocalFile(cfg.FilePath, cfg.RestoreBackup); err != nil {
+			return err
+		}
+		log.WithFields(map[string]interface{}{
+			"file_path":   cfg.FilePath,
+			"backup_path": cfg.RestoreBackup,
+		}).Info("Local file restored from backup")
+		return nil
+	}
+
+	if cfg.NewTag == "" {
+		return errors.NewValidationError("new-tag is required")
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	result, err := workflow.UpdateLocalFile(ctx, cfg, log)
+	if result != nil {
+		logLocalValidations(log, result.Validations)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"file_path":   result.FilePath,
+		"old_tag":     result.OldTag,
+		"backup_path": result.BackupPath,
+		"skipped":     result.Skipped,
+		"timezone":    clock.Location().String(),
+		"operation":   "local_update_complete",
+	}).Info(result.Message)
+
+	return nil
+}
+
+// logLocalValidations logs the outcome of each validation command of a local update
+func logLocalValidations(log *logger.Logger, validations []report.Validation) {
+	for _, validation := range validations {
+		log.WithFields(map[string]interface{}{
+			"file_path":    validation.Path,
+			"validate_cmd": validation.Command,
+			"passed":       validation.Passed,
+			"exit_code":    validation.ExitCode,
+			"duration":     validation.Duration.String(),
+		}).Info("Validation command completed")
+	}
+}
+
+// runTagUpdate executes the tag update workflow for a loaded configuration
+func runTagUpdate(cfg *config.CLIConfig) error {
+	// Initialize logger; the token never appears in log output
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.AuditSigningKey)
+	logger.RegisterSecret(cfg.ApproverToken)
+	logger.RegisterSecret(cfg.NotifyEmailPassword)
+	log := logger.New(cfg.Debug)
+	log.WithFields(map[string]interface{}{
+		"token_source": cfg.TokenSource,
+		"auth_mode":    cfg.AuthMode,
+	}).Debug("GitLab token resolved")
+	log.WithField("interactive", terminal.IsInteractive(os.Stdout)).Debug("Terminal mode detected")
+
+	log.WithFields(map[string]interface{}{
+		"file_path":  cfg.FilePath,
+		"new_tag":    cfg.NewTag,
+		"project_id": cfg.ProjectID,
+		"operation":  "cli_start",
+	}).Info("Starting go-tag-updater")
+
+	if cfg.DryRun {
+		log.WithField("mode", "dry_run").Info("Dry run mode enabled - no changes will be made")
+	}
+
+	// Validate inputs (additional validation)
+	if cfg.FilePath == "" && cfg.RepoPathGlob == "" {
+		return errors.NewValidationError("file path cannot be empty")
+	}
+
+	if cfg.NewTag == "" {
+		return errors.NewValidationError("new tag cannot be empty")
+	}
+
+	if cfg.ProjectID == "" {
+		return errors.NewValidationError("project ID cannot be empty")
+	}
+
+	if cfg.GitLabToken == "" {
+		return errors.NewValidationError("GitLab token cannot be empty")
+	}
+
+	log.WithOperation("validation").Info("Configuration validated successfully")
+
+	if cfg.LeastPrivilege {
+		log.WithFields(map[string]interface{}{
+			"allowed_files": cfg.AllowedFiles,
+			"allowed_paths": cfg.AllowedPaths,
+		}).Info("Least-privilege policy enabled")
+	}
+
+	if cfg.AutoMerge {
+		log.WithField("auto_merge", true).Info("Auto-merge enabled")
+	}
+
+	if cfg.CheckFileConflicts {
+		log.WithField("conflict_policy", cfg.ConflictPolicy).Info("Merge requests changing the same file are checked")
+	}
+
+	// Execute workflow; the CLI runs the update rather than previewing it, which is
+	// left to preview and --dry-run
+	ctx, cancel := commandContext()
+	defer cancel()
+	// Metrics of interrupted runs are pushed as well
+	defer pushMetrics(context.WithoutCancel(ctx), cfg, log)
+
+	result, err := workflow.RunUpdate(ctx, cfg, log)
+	if result != nil && (cfg.Debug || cfg.ReportFile != "") {
+		if printErr := printStepTimings(os.Stderr, result.Steps); printErr != nil {
+			log.WithError(printErr).Warn("Failed to print step timings")
+		}
+	}
+	if err != nil {
+		if result == nil {
+			return err
+		}
+		log.WithFields(map[string]interface{}{
+			"run_id":     result.RunID,
+			"branch_url": result.BranchURL,
+			"commit_url": result.CommitURL,
+		}).Error("Tag update failed; run 'go-tag-updater abort --run <run_id>' to remove created branches and merge requests")
+		return err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"run_id":       result.RunID,
+		"branch_name":  result.BranchName,
+		"file_updated": result.FileUpdated,
+		"skipped":      result.Skipped,
+		"timezone":     clock.Location().String(),
+		"operation":    "cli_complete",
+	}).Info(result.Message)
+
+	for _, count := range result.FileChanges {
+		log.WithFields(map[string]interface{}{
+			"file_path": count.FilePath,
+			"changes":   count.Changes,
+		}).Info("File changes")
+	}
+
+	return nil
+}
+
+// printStepTimings writes one row per workflow step and the total, to find the steps
+// slowing down updates against a slow GitLab instance
+func printStepTimings(w io.Writer, steps []workflow.StepResult) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STEP\tSTATUS\tDURATION\tERROR")
+	var total time.Duration
+	for _, step := range steps {
+		total += step.Duration
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", step.Name, step.Status, step.Duration.Round(time.Millisecond), step.Error)
+	}
+	fmt.Fprintf(writer, "total\t\t%s\t\n", total.Round(time.Millisecond))
+	return writer.Flush()
+}
+
+// pushMetrics sends the GitLab API metrics of this run to the configured Pushgateway;
+// a failed push is only reported since the update itself is already done
+func pushMetrics(ctx context.Context, cfg *config.CLIConfig, log *logger.Logger) {
+	if cfg.MetricsPushURL == "" {
+		return
+	}
+
+	if err := metrics.Default.Push(ctx, cfg.MetricsPushURL, cfg.MetricsJob); err != nil {
+		log.WithError(err).WithField("metrics_push", cfg.MetricsPushURL).Warn("Failed to push metrics")
+		return
+	}
+
+	log.WithField("metrics_push", cfg.MetricsPushURL).Debug("Metrics pushed")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Gosayram/go-tag-updater/internal/version"
+)
+
+// versionCmd prints version and build information
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show version information",
+	Args:  cobra.NoArgs,
+	RunE:  runVersion,
+}
+
+func init() {
+	versionCmd.Flags().Bool("short", false, "Print only the version number")
+	rootCmd.AddCommand(versionCmd)
+}
+
+func runVersion(cmd *cobra.Command, _ []string) error {
+	short, err := cmd.Flags().GetBool("short")
+	if err != nil {
+		return fmt.Errorf("failed to read short flag: %w", err)
+	}
+
+	if short {
+		fmt.Println(version.GetVersion())
+		return nil
+	}
+
+	fmt.Println(version.GetFullVersionInfo())
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package audit provides audit trail primitives for go-tag-updater.
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// AlgorithmHMACSHA256 identifies HMAC-SHA256 record signatures
+	AlgorithmHMACSHA256 = "hmac-sha256"
+	// MinHMACKeyLength defines the minimum accepted HMAC key length in bytes
+	MinHMACKeyLength = 32
+	// KeyIDLength defines the number of hex characters used for key identifiers
+	KeyIDLength = 16
+)
+
+// Signer signs and verifies serialized audit records
+type Signer interface {
+	// Algorithm returns the signature algorithm identifier
+	Algorithm() string
+	// KeyID returns a non-secret identifier of the signing key
+	KeyID() string
+	// Sign returns the signature of payload
+	Sign(payload []byte) (string, error)
+	// Verify checks signature against payload
+	Verify(payload []byte, signature string) error
+}
+
+// Signature describes the signature attached to an audit record
+type Signature struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Value     string `json:"sig"`
+}
+
+// HMACSigner signs records with a shared secret key
+type HMACSigner struct {
+	key   []byte
+	keyID string
+}
+
+// NewHMACSigner creates a new HMAC-SHA256 signer
+func NewHMACSigner(key []byte) (*HMACSigner, error) {
+	if len(key) < MinHMACKeyLength {
+		return nil, errors.NewConfigError(fmt.Sprintf(
+			"audit signing key too short: %d bytes (min %d)", len(key), MinHMACKeyLength))
+	}
+
+	digest := sha256.Sum256(key)
+	keyCopy := make([]byte, len(key))
+	copy(keyCopy, key)
+
+	return &HMACSigner{
+		key:   keyCopy,
+		keyID: hex.EncodeToString(digest[:])[:KeyIDLength],
+	}, nil
+}
+
+// Algorithm returns the signature algorithm identifier
+func (s *HMACSigner) Algorithm() string {
+	return AlgorithmHMACSHA256
+}
+
+// KeyID returns a fingerprint of the key that does not reveal the secret
+func (s *HMACSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign returns the hex encoded HMAC of payload
+func (s *HMACSigner) Sign(payload []byte) (string, error) {
+	mac := hmac.New(sha256.New, s.key)
+	if _, err := mac.Write(payload); err != nil {
+		return "", fmt.Errorf("failed to compute audit signature: %w", err)
+	}
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Verify checks that signature matches payload
+func (s *HMACSigner) Verify(payload []byte, signature string) error {
+	expected, err := s.Sign(payload)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.NewValidationError("audit record signature mismatch")
+	}
+
+	return nil
+}
+
+// SignPayload signs payload and returns the signature envelope
+func SignPayload(signer Signer, payload []byte) (*Signature, error) {
+	if signer == nil {
+		return nil, errors.NewValidationError("audit signer cannot be nil")
+	}
+
+	value, err := signer.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signature{
+		Algorithm: signer.Algorithm(),
+		KeyID:     signer.KeyID(),
+		Value:     value,
+	}, nil
+}
+
+// VerifyPayload checks a signature envelope against payload
+func VerifyPayload(signer Signer, payload []byte, signature *Signature) error {
+	if signer == nil {
+		return errors.NewValidationError("audit signer cannot be nil")
+	}
+
+	if signature == nil {
+		return errors.NewValidationError("audit record is not signed")
+	}
+
+	if signature.Algorithm != signer.Algorithm() {
+		return errors.NewValidationError(fmt.Sprintf("unsupported audit signature algorithm: %s", signature.Algorithm))
+	}
+
+	if signature.KeyID != signer.KeyID() {
+		return errors.NewValidationErrorWithContext("audit record signed with a different key", signature.KeyID)
+	}
+
+	return signer.Verify(payload, signature.Value)
+}
+
+// NewSignerFromKey returns an HMAC signer for key, or nil when signing is disabled
+func NewSignerFromKey(key string) (Signer, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	signer, err := NewHMACSigner([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
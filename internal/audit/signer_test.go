@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	TestSigningKey = "0123456789abcdef0123456789abcdef"
+	TestOtherKey   = "fedcba9876543210fedcba9876543210"
+	TestPayload    = `{"project":"group/project","new_tag":"v1.2.3"}`
+)
+
+func TestNewHMACSigner(t *testing.T) {
+	if _, err := NewHMACSigner([]byte("short")); err == nil {
+		t.Error("NewHMACSigner() should reject short keys")
+	}
+
+	signer, err := NewHMACSigner([]byte(TestSigningKey))
+	if err != nil {
+		t.Fatalf("NewHMACSigner() unexpected error: %v", err)
+	}
+
+	if signer.Algorithm() != AlgorithmHMACSHA256 {
+		t.Errorf("Algorithm() = %q, want %q", signer.Algorithm(), AlgorithmHMACSHA256)
+	}
+
+	if len(signer.KeyID()) != KeyIDLength {
+		t.Errorf("KeyID() length = %d, want %d", len(signer.KeyID()), KeyIDLength)
+	}
+
+	if strings.Contains(TestSigningKey, signer.KeyID()) {
+		t.Error("KeyID() should not reveal the signing key")
+	}
+}
+
+func TestSignAndVerifyPayload(t *testing.T) {
+	signer, err := NewHMACSigner([]byte(TestSigningKey))
+	if err != nil {
+		t.Fatalf("NewHMACSigner() unexpected error: %v", err)
+	}
+
+	signature, err := SignPayload(signer, []byte(TestPayload))
+	if err != nil {
+		t.Fatalf("SignPayload() unexpected error: %v", err)
+	}
+
+	if err := VerifyPayload(signer, []byte(TestPayload), signature); err != nil {
+		t.Errorf("VerifyPayload() unexpected error: %v", err)
+	}
+
+	tampered := strings.Replace(TestPayload, "v1.2.3", "v6.6.6", 1)
+	if err := VerifyPayload(signer, []byte(tampered), signature); err == nil {
+		t.Error("VerifyPayload() should reject tampered payload")
+	}
+
+	other, err := NewHMACSigner([]byte(TestOtherKey))
+	if err != nil {
+		t.Fatalf("NewHMACSigner() unexpected error: %v", err)
+	}
+	if err := VerifyPayload(other, []byte(TestPayload), signature); err == nil {
+		t.Error("VerifyPayload() should reject signature from a different key")
+	}
+
+	if err := VerifyPayload(signer, []byte(TestPayload), nil); err == nil {
+		t.Error("VerifyPayload() should reject unsigned records")
+	}
+}
+
+func TestNewSignerFromKey(t *testing.T) {
+	signer, err := NewSignerFromKey("")
+	if err != nil || signer != nil {
+		t.Errorf("NewSignerFromKey(\"\") = %v, %v; want nil, nil", signer, err)
+	}
+
+	signer, err = NewSignerFromKey("short")
+	if err == nil || signer != nil {
+		t.Errorf("NewSignerFromKey(short) should fail with nil signer, got %v, %v", signer, err)
+	}
+
+	signer, err = NewSignerFromKey(TestSigningKey)
+	if err != nil || signer == nil {
+		t.Errorf("NewSignerFromKey() = %v, %v; want signer", signer, err)
+	}
+}
@@ -0,0 +1,249 @@
+// Package audit provides audit trail primitives for go-tag-updater.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// FilePermissions defines permissions for the local audit file
+	FilePermissions = 0o600
+	// DirPermissions defines permissions for the directory holding the audit file
+	DirPermissions = 0o700
+	// DefaultHTTPTimeout bounds how long delivery to the audit endpoint may take
+	DefaultHTTPTimeout = 10 * time.Second
+	// ContentTypeJSON is the content type of records posted to the audit endpoint
+	ContentTypeJSON = "application/json"
+	// EnvGitLabUserLogin names the CI variable holding the user who started the pipeline
+	EnvGitLabUserLogin = "GITLAB_USER_LOGIN"
+	// UnknownActor is recorded when the actor cannot be determined
+	UnknownActor = "unknown"
+)
+
+// Record describes one successfully performed tag update
+type Record struct {
+	Timestamp       time.Time  `json:"timestamp"`
+	RunID           string     `json:"run_id,omitempty"`
+	Project         string     `json:"project"`
+	File            string     `json:"file"`
+	OldTag          string     `json:"old_tag"`
+	NewTag          string     `json:"new_tag"`
+	Branch          string     `json:"branch"`
+	MergeRequestIID int        `json:"mr_iid,omitempty"`
+	Actor           string     `json:"actor"`
+	Signature       *Signature `json:"signature,omitempty"`
+}
+
+// Sink receives serialized audit records
+type Sink interface {
+	// Write delivers one JSON encoded record
+	Write(ctx context.Context, data []byte) error
+}
+
+// Options configures where audit records are delivered
+type Options struct {
+	// FilePath appends records as JSON lines to a local file when set
+	FilePath string
+	// Endpoint posts every record to an HTTP endpoint when set
+	Endpoint string
+	// SigningKey signs every record with HMAC-SHA256 when set
+	SigningKey string
+	// Timeout bounds delivery to the endpoint; DefaultHTTPTimeout when zero
+	Timeout time.Duration
+}
+
+// Trail signs audit records and delivers them to every configured sink
+type Trail struct {
+	sinks  []Sink
+	signer Signer
+}
+
+// NewTrail creates an audit trail from options, or returns nil when no sink is configured
+func NewTrail(opts Options) (*Trail, error) {
+	var sinks []Sink
+	if opts.FilePath != "" {
+		sinks = append(sinks, NewFileSink(opts.FilePath))
+	}
+	if opts.Endpoint != "" {
+		sinks = append(sinks, NewHTTPSink(opts.Endpoint, opts.Timeout))
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	signer, err := NewSignerFromKey(opts.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTrailWithSinks(signer, sinks...), nil
+}
+
+// NewTrailWithSinks creates an audit trail delivering to the given sinks; signer may be nil
+func NewTrailWithSinks(signer Signer, sinks ...Sink) *Trail {
+	return &Trail{sinks: sinks, signer: signer}
+}
+
+// Record stamps, signs and delivers a record to every sink. Delivery continues after
+// a failing sink and all failures are returned together.
+func (t *Trail) Record(ctx context.Context, record *Record) error {
+	if record == nil {
+		return errors.NewValidationError("audit record cannot be nil")
+	}
+
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now().UTC()
+	}
+
+	data, err := t.encode(record)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sink := range t.sinks {
+		if err := sink.Write(ctx, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
+
+// encode serializes a record, signing it without the signature field first
+func (t *Trail) encode(record *Record) ([]byte, error) {
+	record.Signature = nil
+	if t.signer != nil {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode audit record: %w", err)
+		}
+
+		record.Signature, err = SignPayload(t.signer, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	return data, nil
+}
+
+// VerifyRecord checks the signature of a serialized audit record
+func VerifyRecord(signer Signer, data []byte) error {
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return errors.NewValidationError(fmt.Sprintf("invalid audit record: %v", err))
+	}
+
+	signature := record.Signature
+	record.Signature = nil
+	payload, err := json.Marshal(&record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	return VerifyPayload(signer, payload, signature)
+}
+
+// FileSink appends records as JSON lines to a local file
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSink creates a sink appending to path
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: filepath.Clean(path)}
+}
+
+// Write appends one record followed by a newline
+func (s *FileSink) Write(_ context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), DirPermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to create audit directory: %v", err))
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, FilePermissions)
+	if err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to open audit file: %v", err))
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write audit file: %v", err))
+	}
+
+	return nil
+}
+
+// HTTPSink posts records to an HTTP endpoint
+type HTTPSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPSink creates a sink posting to endpoint with the given timeout
+func NewHTTPSink(endpoint string, timeout time.Duration) *HTTPSink {
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+
+	return &HTTPSink{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Write posts one record; any non-2xx response is an error
+func (s *HTTPSink) Write(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return errors.NewConfigErrorWithCause("invalid audit endpoint "+s.endpoint, err)
+	}
+	req.Header.Set("Content-Type", ContentTypeJSON)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.NewNetworkErrorWithCause("failed to deliver audit record", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.NewNetworkError(fmt.Sprintf("audit endpoint returned %s", resp.Status))
+	}
+
+	return nil
+}
+
+// DefaultActor identifies who performed the run: the user who started the CI
+// pipeline, or the local operating system user
+func DefaultActor() string {
+	if login := os.Getenv(EnvGitLabUserLogin); login != "" {
+		return login
+	}
+
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+
+	return UnknownActor
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	TestProject = "group/project"
+	TestFile    = "deploy/values.yaml"
+	TestOldTag  = "v1.0.0"
+	TestNewTag  = "v1.2.3"
+	TestBranch  = "update-tag/v1.2.3"
+	TestActor   = "release-bot"
+	TestMRIID   = 42
+)
+
+// newTestRecord returns a fully populated audit record
+func newTestRecord() *Record {
+	return &Record{
+		Project:         TestProject,
+		File:            TestFile,
+		OldTag:          TestOldTag,
+		NewTag:          TestNewTag,
+		Branch:          TestBranch,
+		MergeRequestIID: TestMRIID,
+		Actor:           TestActor,
+	}
+}
+
+func TestNewTrail(t *testing.T) {
+	trail, err := NewTrail(Options{})
+	if err != nil || trail != nil {
+		t.Errorf("NewTrail() without sinks = %v, %v; want nil, nil", trail, err)
+	}
+
+	if _, err := NewTrail(Options{FilePath: "audit.log", SigningKey: "short"}); err == nil {
+		t.Error("NewTrail() should reject a short signing key")
+	}
+}
+
+func TestTrail_RecordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	trail, err := NewTrail(Options{FilePath: path, SigningKey: TestSigningKey})
+	if err != nil {
+		t.Fatalf("NewTrail() unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := trail.Record(context.Background(), newTestRecord()); err != nil {
+			t.Fatalf("Record() unexpected error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit file has %d records, want 2", len(lines))
+	}
+
+	for _, field := range []string{`"timestamp":`, `"old_tag":"v1.0.0"`, `"new_tag":"v1.2.3"`, `"mr_iid":42`,
+		`"actor":"release-bot"`, `"sig":`} {
+		if !strings.Contains(lines[0], field) {
+			t.Errorf("audit record %s missing %s", lines[0], field)
+		}
+	}
+
+	signer, err := NewHMACSigner([]byte(TestSigningKey))
+	if err != nil {
+		t.Fatalf("NewHMACSigner() unexpected error: %v", err)
+	}
+	if err := VerifyRecord(signer, []byte(lines[0])); err != nil {
+		t.Errorf("VerifyRecord() unexpected error: %v", err)
+	}
+
+	tampered := strings.Replace(lines[0], TestNewTag, "v6.6.6", 1)
+	if err := VerifyRecord(signer, []byte(tampered)); err == nil {
+		t.Error("VerifyRecord() should reject a tampered record")
+	}
+}
+
+func TestTrail_RecordHTTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "rejected", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != ContentTypeJSON {
+					t.Errorf("Content-Type = %q, want %q", r.Header.Get("Content-Type"), ContentTypeJSON)
+				}
+				received, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			trail, err := NewTrail(Options{Endpoint: server.URL})
+			if err != nil {
+				t.Fatalf("NewTrail() unexpected error: %v", err)
+			}
+
+			err = trail.Record(context.Background(), newTestRecord())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Record() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Contains(received, []byte(`"project":"group/project"`)) {
+				t.Errorf("endpoint received %s", received)
+			}
+			if bytes.Contains(received, []byte(`"signature"`)) {
+				t.Errorf("unsigned trail should not attach a signature: %s", received)
+			}
+		})
+	}
+}
+
+func TestDefaultActor(t *testing.T) {
+	t.Setenv(EnvGitLabUserLogin, TestActor)
+	if actor := DefaultActor(); actor != TestActor {
+		t.Errorf("DefaultActor() = %q, want %q", actor, TestActor)
+	}
+
+	t.Setenv(EnvGitLabUserLogin, "")
+	if actor := DefaultActor(); actor == "" {
+		t.Error("DefaultActor() should never be empty")
+	}
+}
@@ -0,0 +1,56 @@
+// Package clock provides the time zone of every timestamp the tool generates, so
+// branch names, backup files and logs correlate across runners. It is UTC unless
+// overridden.
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+var (
+	mu       sync.RWMutex
+	location = time.UTC
+)
+
+// Location returns the time zone of generated timestamps
+func Location() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
+	return location
+}
+
+// SetLocation changes the time zone of generated timestamps; nil restores UTC
+func SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	location = loc
+}
+
+// LoadLocation parses an IANA time zone name such as "Europe/Berlin"; an empty name is UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid time zone %q: %v", name, err))
+	}
+	return loc, nil
+}
+
+// Now returns the current time in the time zone of generated timestamps
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// In converts a time to the time zone of generated timestamps
+func In(t time.Time) time.Time {
+	return t.In(Location())
+}
@@ -0,0 +1,48 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty is UTC", zone: "", want: "UTC"},
+		{name: "IANA name", zone: "Europe/Berlin", want: "Europe/Berlin"},
+		{name: "invalid", zone: "Mars/Olympus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := LoadLocation(tt.zone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadLocation(%q) error = %v, wantErr %v", tt.zone, err, tt.wantErr)
+			}
+			if err == nil && loc.String() != tt.want {
+				t.Errorf("LoadLocation(%q) = %s, want %s", tt.zone, loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestNow(t *testing.T) {
+	if got := Now().Location(); got != time.UTC {
+		t.Errorf("Now() location = %s, want UTC by default", got)
+	}
+
+	berlin, err := LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	SetLocation(berlin)
+	defer SetLocation(nil)
+
+	if got := Now().Location(); got != berlin {
+		t.Errorf("Now() location = %s, want %s", got, berlin)
+	}
+}
@@ -0,0 +1,97 @@
+// Package config provides configuration management for go-tag-updater.
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// GitLab token sources, listed in resolution order
+const (
+	// TokenSourceFlag is the --token flag or a token key in the configuration file
+	TokenSourceFlag = "flag"
+	// TokenSourceEnv is the tool-specific GO_TAG_UPDATER_TOKEN variable
+	TokenSourceEnv = EnvPrefix + "_TOKEN"
+	// TokenSourceGitLabEnv is the conventional GITLAB_TOKEN variable
+	TokenSourceGitLabEnv = "GITLAB_TOKEN"
+	// TokenSourceCIJob is the job token GitLab CI provides to every job
+	TokenSourceCIJob = "CI_JOB_TOKEN"
+)
+
+// Authentication modes selecting how the token is sent to GitLab
+const (
+	// AuthModeAuto uses a job token when it comes from CI_JOB_TOKEN and a personal access token otherwise
+	AuthModeAuto = "auto"
+	// AuthModePAT sends a personal, project or group access token
+	AuthModePAT = "pat"
+	// AuthModeOAuth sends an OAuth 2.0 access token
+	AuthModeOAuth = "oauth"
+	// AuthModeJob sends a CI/CD job token
+	AuthModeJob = "job"
+)
+
+// Credentials is a resolved GitLab token together with its source and authentication mode
+type Credentials struct {
+	Token  string
+	Source string
+	Mode   string
+}
+
+// ResolveToken returns the GitLab token and the source it was taken from, trying the
+// --token flag, GO_TAG_UPDATER_TOKEN, GITLAB_TOKEN and CI_JOB_TOKEN in that order.
+// Both values are empty when no source provides a token.
+func ResolveToken() (token, source string) {
+	// viper resolves the flag, GO_TAG_UPDATER_TOKEN and the configuration file in order
+	if token = viper.GetString("token"); token != "" {
+		if os.Getenv(TokenSourceEnv) == token {
+			return token, TokenSourceEnv
+		}
+		return token, TokenSourceFlag
+	}
+
+	for _, envVar := range []string{TokenSourceGitLabEnv, TokenSourceCIJob} {
+		if token = os.Getenv(envVar); token != "" {
+			return token, envVar
+		}
+	}
+
+	return "", ""
+}
+
+// ResolveCredentials resolves the token for an authentication mode. Personal access
+// and OAuth tokens never come from CI_JOB_TOKEN, and job tokens only come from the
+// --token flag or CI_JOB_TOKEN; a token resolved from a source of another mode is
+// rejected rather than replaced, so a set GO_TAG_UPDATER_TOKEN or GITLAB_TOKEN is
+// never silently ignored. An empty mode means AuthModeAuto.
+func ResolveCredentials(mode string) (*Credentials, error) {
+	token, source := ResolveToken()
+
+	switch mode {
+	case "", AuthModeAuto:
+		mode = AuthModePAT
+		if source == TokenSourceCIJob {
+			mode = AuthModeJob
+		}
+	case AuthModePAT, AuthModeOAuth:
+		if source == TokenSourceCIJob {
+			return nil, errors.NewConfigError(fmt.Sprintf(
+				"%s holds a job token and cannot be used with auth mode %s; use --auth-mode=%s",
+				TokenSourceCIJob, mode, AuthModeJob))
+		}
+	case AuthModeJob:
+		if source == TokenSourceEnv || source == TokenSourceGitLabEnv {
+			return nil, errors.NewConfigError(fmt.Sprintf(
+				"%s holds a token that conflicts with auth mode %s, which reads the token from --token or %s; "+
+					"unset %s or choose another auth mode", source, AuthModeJob, TokenSourceCIJob, source))
+		}
+	default:
+		return nil, errors.NewConfigError(fmt.Sprintf("unknown auth mode %q: use %s, %s, %s or %s",
+			mode, AuthModeAuto, AuthModePAT, AuthModeOAuth, AuthModeJob))
+	}
+
+	return &Credentials{Token: token, Source: source, Mode: mode}, nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestResolveToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag       string
+		env        map[string]string
+		wantToken  string
+		wantSource string
+	}{
+		{name: "no token"},
+		{
+			name:       "flag wins",
+			flag:       "flag-token",
+			env:        map[string]string{TokenSourceEnv: "env-token", TokenSourceCIJob: "job-token"},
+			wantToken:  "flag-token",
+			wantSource: TokenSourceFlag,
+		},
+		{
+			name:       "tool variable before GITLAB_TOKEN",
+			env:        map[string]string{TokenSourceEnv: "env-token", TokenSourceGitLabEnv: "gitlab-token"},
+			wantToken:  "env-token",
+			wantSource: TokenSourceEnv,
+		},
+		{
+			name:       "GITLAB_TOKEN before CI_JOB_TOKEN",
+			env:        map[string]string{TokenSourceGitLabEnv: "gitlab-token", TokenSourceCIJob: "job-token"},
+			wantToken:  "gitlab-token",
+			wantSource: TokenSourceGitLabEnv,
+		},
+		{
+			name:       "CI_JOB_TOKEN fallback",
+			env:        map[string]string{TokenSourceCIJob: "job-token"},
+			wantToken:  "job-token",
+			wantSource: TokenSourceCIJob,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, envVar := range []string{TokenSourceEnv, TokenSourceGitLabEnv, TokenSourceCIJob} {
+				t.Setenv(envVar, tt.env[envVar])
+			}
+
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.SetEnvPrefix(EnvPrefix)
+			viper.AutomaticEnv()
+			if tt.flag != "" {
+				viper.Set("token", tt.flag)
+			}
+
+			token, source := ResolveToken()
+			if token != tt.wantToken || source != tt.wantSource {
+				t.Errorf("ResolveToken() = %q, %q, want %q, %q", token, source, tt.wantToken, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		flag        string
+		env         map[string]string
+		want        Credentials
+		expectError bool
+	}{
+		{
+			name: "auto with personal token",
+			env:  map[string]string{TokenSourceGitLabEnv: "gitlab-token"},
+			want: Credentials{Token: "gitlab-token", Source: TokenSourceGitLabEnv, Mode: AuthModePAT},
+		},
+		{
+			name: "auto with job token",
+			mode: AuthModeAuto,
+			env:  map[string]string{TokenSourceCIJob: "job-token"},
+			want: Credentials{Token: "job-token", Source: TokenSourceCIJob, Mode: AuthModeJob},
+		},
+		{
+			name: "oauth from flag",
+			mode: AuthModeOAuth,
+			flag: "oauth-token",
+			want: Credentials{Token: "oauth-token", Source: TokenSourceFlag, Mode: AuthModeOAuth},
+		},
+		{
+			name:        "personal mode rejects job token",
+			mode:        AuthModePAT,
+			env:         map[string]string{TokenSourceCIJob: "job-token"},
+			expectError: true,
+		},
+		{
+			name:        "job mode rejects personal token variable",
+			mode:        AuthModeJob,
+			env:         map[string]string{TokenSourceGitLabEnv: "gitlab-token", TokenSourceCIJob: "job-token"},
+			expectError: true,
+		},
+		{
+			name: "job mode from job token",
+			mode: AuthModeJob,
+			env:  map[string]string{TokenSourceCIJob: "job-token"},
+			want: Credentials{Token: "job-token", Source: TokenSourceCIJob, Mode: AuthModeJob},
+		},
+		{
+			name: "job mode from flag",
+			mode: AuthModeJob,
+			flag: "job-token",
+			env:  map[string]string{TokenSourceCIJob: "other-job-token"},
+			want: Credentials{Token: "job-token", Source: TokenSourceFlag, Mode: AuthModeJob},
+		},
+		{
+			name:        "job mode without job token",
+			mode:        AuthModeJob,
+			env:         map[string]string{TokenSourceEnv: "env-token"},
+			expectError: true,
+		},
+		{
+			name:        "unknown mode",
+			mode:        "basic",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, envVar := range []string{TokenSourceEnv, TokenSourceGitLabEnv, TokenSourceCIJob} {
+				t.Setenv(envVar, tt.env[envVar])
+			}
+
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			viper.SetEnvPrefix(EnvPrefix)
+			viper.AutomaticEnv()
+			if tt.flag != "" {
+				viper.Set("token", tt.flag)
+			}
+
+			got, err := ResolveCredentials(tt.mode)
+			if tt.expectError {
+				if code := errors.GetErrorCode(err); code != errors.ErrCodeConfiguration {
+					t.Errorf("ResolveCredentials() error = %v, want configuration error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveCredentials() unexpected error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ResolveCredentials() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
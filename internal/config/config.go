@@ -2,10 +2,16 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/manifest"
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+	"github.com/Gosayram/go-tag-updater/internal/tagtransform"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
 const (
@@ -13,10 +19,16 @@ const (
 	DefaultConfigFile = "go-tag-updater.yaml"
 	// AlternateConfigFile specifies the alternative configuration file name
 	AlternateConfigFile = "go-tag-updater.yml"
+	// ProfilesKey is the configuration key holding named profiles
+	ProfilesKey = "profiles"
+	// UpdaterKey is the configuration key selecting the updater profile of a manifest kind
+	UpdaterKey = "updater"
 	// EnvPrefix defines the prefix for environment variables
 	EnvPrefix = "GO_TAG_UPDATER"
 	// DefaultMergeTimeout specifies the default timeout for merge operations
 	DefaultMergeTimeout = 300 * time.Second
+	// DefaultConflictTimeout specifies how long --watch-conflicts waits for conflicts to be resolved
+	DefaultConflictTimeout = 15 * time.Minute
 
 	// DefaultBufferSize specifies the default buffer size for I/O operations
 	DefaultBufferSize = 1024
@@ -33,6 +45,56 @@ const (
 	DefaultMaxConcurrentReqs = 5
 	// DefaultRetryCount specifies the default number of retry attempts
 	DefaultRetryCount = 3
+	// DefaultCircuitBreakerThreshold consecutive GitLab failures open the circuit breaker
+	DefaultCircuitBreakerThreshold = 5
+	// DefaultCircuitBreakerCoolDown is how long the open circuit breaker pauses requests
+	DefaultCircuitBreakerCoolDown = 30 * time.Second
+
+	// DefaultServeListen specifies the address the serve command listens on
+	DefaultServeListen = ":8080"
+	// DefaultServeMaxJobs specifies how many update jobs the serve command runs at the same time
+	DefaultServeMaxJobs = 2
+	// DefaultRegistryWatchInterval specifies how often registry-watch polls the registries
+	DefaultRegistryWatchInterval = 15 * time.Minute
+
+	// HTTPCacheOff sends every GitLab request; HTTPCacheMemory revalidates responses cached
+	// for the lifetime of the process and HTTPCacheDisk responses cached across runs
+	HTTPCacheOff    = "off"
+	HTTPCacheMemory = "memory"
+	HTTPCacheDisk   = "disk"
+
+	// SourceDriftRefuse fails the update when the file differs between source ref and target branch
+	SourceDriftRefuse = "refuse"
+	// SourceDriftWarn only logs a warning when the file differs between source ref and target branch
+	SourceDriftWarn = "warn"
+
+	// ConflictPolicyFail refuses the update while other open merge requests change the same file
+	ConflictPolicyFail = "fail"
+	// ConflictPolicyWait waits for the conflicting merge requests to close before updating
+	ConflictPolicyWait = "wait"
+	// ConflictPolicyForce only logs a warning and proceeds with the update
+	ConflictPolicyForce = "force"
+	// ConflictPolicyQueue pushes the update branch right away but opens the merge request
+	// only after the conflicting merge requests closed
+	ConflictPolicyQueue = "queue"
+
+	// RecentUpdateSkip skips the update when go-tag-updater changed the file within --min-interval
+	RecentUpdateSkip = "skip"
+	// RecentUpdateWarn only logs a warning when go-tag-updater changed the file within --min-interval
+	RecentUpdateWarn = "warn"
+
+	// CommitBackendAPI commits file updates through the GitLab API
+	CommitBackendAPI = "api"
+	// CommitBackendGit commits file updates through a local clone pushed with git,
+	// which can sign the commits
+	CommitBackendGit = "git"
+
+	// OriginalBackupNone keeps no copy of the original file in GitLab
+	OriginalBackupNone = "none"
+	// OriginalBackupDescription embeds the original file in the merge request description
+	OriginalBackupDescription = "description"
+	// OriginalBackupFile commits the original file next to the updated one with a .orig suffix
+	OriginalBackupFile = "file"
 )
 
 // Config holds the application configuration
@@ -48,6 +110,33 @@ type Config struct {
 
 	// Logging settings
 	Logging LoggingConfig `mapstructure:"logging"`
+
+	// Change scope policy settings
+	Policy PolicyConfig `mapstructure:"policy"`
+
+	// Run state settings
+	State StateConfig `mapstructure:"state"`
+
+	// Metrics export settings
+	Metrics MetricsConfig `mapstructure:"metrics"`
+
+	// Run notification settings
+	Notify NotifyConfig `mapstructure:"notify"`
+
+	// Webhook server settings
+	Serve ServeConfig `mapstructure:"serve"`
+
+	// Local commands run at points of each update
+	Hooks HooksConfig `mapstructure:"hooks"`
+
+	// Registry polling settings
+	RegistryWatch RegistryWatchConfig `mapstructure:"registry_watch"`
+
+	// Feature flags by name, resolved by the features package
+	Features map[string]interface{} `mapstructure:"features"`
+
+	// Timezone of generated timestamps, UTC when empty
+	Timezone string `mapstructure:"timezone"`
 }
 
 // GitLabConfig contains GitLab-specific configuration
@@ -57,15 +146,40 @@ type GitLabConfig struct {
 	Timeout      time.Duration `mapstructure:"timeout"`
 	RetryCount   int           `mapstructure:"retry_count"`
 	RateLimitRPS int           `mapstructure:"rate_limit_rps"`
+
+	// TLS settings for self-hosted instances behind private CAs or mutual TLS
+	CACert                string `mapstructure:"ca_cert"`
+	ClientCert            string `mapstructure:"client_cert"`
+	ClientKey             string `mapstructure:"client_key"`
+	InsecureSkipTLSVerify bool   `mapstructure:"insecure_skip_tls_verify"`
+
+	// Proxy settings layered over the proxy environment variables
+	Proxy         string `mapstructure:"proxy"`
+	NoProxy       string `mapstructure:"no_proxy"`
+	ProxyUsername string `mapstructure:"proxy_username"`
+	ProxyPassword string `mapstructure:"proxy_password"`
+
+	// HTTP cache of project info and file content, revalidated with ETags
+	HTTPCache    string `mapstructure:"http_cache"`
+	HTTPCacheDir string `mapstructure:"http_cache_dir"`
+
+	// Circuit breaker pausing requests to an instance that keeps failing
+	CircuitBreakerThreshold int           `mapstructure:"circuit_breaker_threshold"`
+	CircuitBreakerCoolDown  time.Duration `mapstructure:"circuit_breaker_cooldown"`
 }
 
 // DefaultsConfig contains default values for common operations
 type DefaultsConfig struct {
-	TargetBranch   string        `mapstructure:"target_branch"`
-	BranchPrefix   string        `mapstructure:"branch_prefix"`
-	MergeTimeout   time.Duration `mapstructure:"merge_timeout"`
-	WaitPreviousMR bool          `mapstructure:"wait_previous_mr"`
-	AutoMerge      bool          `mapstructure:"auto_merge"`
+	TargetBranch       string        `mapstructure:"target_branch"`
+	BranchPrefix       string        `mapstructure:"branch_prefix"`
+	MergeTimeout       time.Duration `mapstructure:"merge_timeout"`
+	ConflictPolicy     string        `mapstructure:"conflict_policy"`
+	AutoMerge          bool          `mapstructure:"auto_merge"`
+	MergeWindow        string        `mapstructure:"merge_window"`
+	MergeTimezone      string        `mapstructure:"merge_timezone"`
+	QuietRollout       bool          `mapstructure:"quiet_rollout"`
+	OnSourceDrift      string        `mapstructure:"on_source_drift"`
+	CheckFileConflicts bool          `mapstructure:"check_file_conflicts"`
 }
 
 // PerformanceConfig contains performance-related settings
@@ -77,10 +191,118 @@ type PerformanceConfig struct {
 
 // LoggingConfig contains logging configuration
 type LoggingConfig struct {
-	Level      string `mapstructure:"level"`
-	Format     string `mapstructure:"format"`
-	EnableFile bool   `mapstructure:"enable_file"`
-	FilePath   string `mapstructure:"file_path"`
+	Level      string      `mapstructure:"level"`
+	Format     string      `mapstructure:"format"`
+	EnableFile bool        `mapstructure:"enable_file"`
+	FilePath   string      `mapstructure:"file_path"`
+	Audit      AuditConfig `mapstructure:"audit"`
+}
+
+// AuditConfig contains audit trail configuration
+type AuditConfig struct {
+	// SigningKey enables HMAC-SHA256 signing of every audit record when set
+	SigningKey string `mapstructure:"signing_key"`
+	// File appends a JSON record of every performed update to a local file when set
+	File string `mapstructure:"file"`
+	// Endpoint posts a JSON record of every performed update to an HTTP endpoint when set
+	Endpoint string `mapstructure:"endpoint"`
+	// Timeout bounds delivery to the endpoint
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// PolicyConfig restricts which files and YAML values the tool may modify
+type PolicyConfig struct {
+	LeastPrivilege bool     `mapstructure:"least_privilege"`
+	AllowedFiles   []string `mapstructure:"allowed_files"`
+	AllowedPaths   []string `mapstructure:"allowed_paths"`
+	MaxOpenMRs     int      `mapstructure:"max_open_mrs"`
+	// AllowBump is the largest version bump a new tag may make: patch, minor or major
+	AllowBump string `mapstructure:"allow_bump"`
+	// TagPrefix must start every new tag, such as "v"
+	TagPrefix      string `mapstructure:"tag_prefix"`
+	AllowDowngrade bool   `mapstructure:"allow_downgrade"`
+}
+
+// StateConfig contains settings for the run journal used by the abort command
+type StateConfig struct {
+	Dir string `mapstructure:"dir"`
+}
+
+// MetricsConfig contains settings for exporting GitLab API metrics
+type MetricsConfig struct {
+	// PushURL is a Prometheus Pushgateway receiving the metrics after each run when set
+	PushURL string `mapstructure:"push_url"`
+	// Job is the Pushgateway job name the metrics are grouped under
+	Job string `mapstructure:"job"`
+}
+
+// HooksConfig contains the local commands run at points of each update, with
+// environment variables describing the run
+type HooksConfig struct {
+	// PreUpdate runs before the update branch is changed; a failing command stops the update
+	PreUpdate []string `mapstructure:"pre_update"`
+	// PostCommit runs after the updated content was committed
+	PostCommit []string `mapstructure:"post_commit"`
+	// PostMR runs after the merge request was opened or refreshed
+	PostMR []string `mapstructure:"post_mr"`
+}
+
+// NotifyConfig contains the destinations notified of every run
+type NotifyConfig struct {
+	Slack NotifyChatConfig `mapstructure:"slack"`
+	Teams NotifyChatConfig `mapstructure:"teams"`
+	// Webhook receives every run as a JSON event
+	Webhook NotifyWebhookConfig `mapstructure:"webhook"`
+	// Email sends every run as a summary email
+	Email NotifyEmailConfig `mapstructure:"email"`
+	// Timeout bounds delivery to each destination
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// NotifyChatConfig contains the incoming webhook of a chat channel
+type NotifyChatConfig struct {
+	WebhookURL string `mapstructure:"webhook_url"`
+}
+
+// NotifyWebhookConfig contains the URL of a generic JSON webhook
+type NotifyWebhookConfig struct {
+	URL string `mapstructure:"url"`
+}
+
+// NotifyEmailConfig contains the SMTP server and recipients of email notifications
+type NotifyEmailConfig struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
+	// TLS is starttls, tls for implicit TLS, or none
+	TLS      string   `mapstructure:"tls"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+}
+
+// ServeConfig contains settings for the webhook server of the serve command
+type ServeConfig struct {
+	// Listen is the address the server listens on
+	Listen string `mapstructure:"listen"`
+	// WebhookSecret must be sent by every request in the X-Webhook-Token header
+	WebhookSecret string `mapstructure:"webhook_secret"`
+	// MaxConcurrentJobs bounds how many updates run at the same time
+	MaxConcurrentJobs int `mapstructure:"max_concurrent_jobs"`
+	// Tokens maps the token refs requests may name to environment variables holding GitLab tokens
+	Tokens map[string]string `mapstructure:"tokens"`
+	// Routes is a file mapping container image repositories to the files their tags are written to
+	Routes string `mapstructure:"routes"`
+	// BotUsernames are the GitLab accounts the tool acts as; GitLab events they cause are skipped
+	BotUsernames []string `mapstructure:"bot_usernames"`
+}
+
+// RegistryWatchConfig contains settings for polling registries with the registry-watch command
+type RegistryWatchConfig struct {
+	// Watches is a file listing the watched images and the files their tags are written to
+	Watches string `mapstructure:"watches"`
+	// Interval is the time between two polls
+	Interval time.Duration `mapstructure:"interval"`
 }
 
 // CLIConfig represents configuration from command line arguments
@@ -90,19 +312,143 @@ type CLIConfig struct {
 	FilePath  string
 	NewTag    string
 
+	// TagTransforms rewrite the new tag before it is written, such as strip-v; NewTag
+	// holds the transformed tag when set through SetNewTag
+	TagTransforms []string
+
+	// YAMLPath selects the tag field to update, such as "image.tag"; auto-detected when empty
+	YAMLPath string
+	// DocSelector picks the document of a multi-document file, by index or by fields
+	// such as "kind=Deployment,name=api"; the tag path must be unambiguous when empty
+	DocSelector string
+
+	// RepoPathGlob selects the repository files, such as "environments/prod/**/*.yaml",
+	// in which every value carrying OldTag is replaced with NewTag instead of updating
+	// one FilePath
+	RepoPathGlob string
+	OldTag       string
+
+	// Updater is the updater profile of a manifest kind, such as argocd, locating the
+	// field to update instead of YAMLPath; RepoURL narrows it to one Argo CD source
+	// and FluxMode picks the chart version or image tag of a Flux HelmRelease.
+	// Service selects the Docker Compose service whose image tag is updated.
+	Updater  string
+	RepoURL  string
+	FluxMode string
+	Service  string
+
+	// ReleaseNotesFile or the GitLab release of the new tag in ReleaseNotesProject
+	// provides the release notes included in the merge request description
+	ReleaseNotesFile    string
+	ReleaseNotesProject string
+
+	// ClosesIssues and RelatedIssues are issue numbers referenced in the merge request
+	// description, the former with a closing keyword; CommentIssues also links the
+	// merge request from each issue
+	ClosesIssues  []string
+	RelatedIssues []string
+	CommentIssues bool
+
+	// ApproverToken belongs to a second user approving the merge requests of a run;
+	// with it or CheckApprovals the approvals still required are reported
+	ApproverToken  string
+	CheckApprovals bool
+
 	// GitLab configuration
 	GitLabToken string
+	TokenSource string
+	AuthMode    string
 	GitLabURL   string
 
+	// CACert is a PEM bundle of CAs trusted in addition to the system ones; ClientCert
+	// and ClientKey are presented to instances requiring mutual TLS
+	CACert                string
+	ClientCert            string
+	ClientKey             string
+	InsecureSkipTLSVerify bool
+
+	// Proxy is the proxy of GitLab requests and NoProxy the hosts reached directly,
+	// layered over HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy         string
+	NoProxy       string
+	ProxyUsername string
+	ProxyPassword string
+
+	// HTTPCache caches project info and file content in memory or in HTTPCacheDir and
+	// revalidates them with ETags, so repeated reads cost GitLab little
+	HTTPCache    string
+	HTTPCacheDir string
+
+	// CircuitBreakerThreshold consecutive 5xx responses or timeouts pause GitLab
+	// requests for CircuitBreakerCoolDown; 0 disables the breaker
+	CircuitBreakerThreshold int
+	CircuitBreakerCoolDown  time.Duration
+
 	// Branch configuration
 	BranchName   string
 	TargetBranch string
 
+	// SourceRef is the branch, tag or commit the update branch starts from; the
+	// target branch when empty. OnSourceDrift decides what happens when the file
+	// differs between the source ref and the target branch.
+	SourceRef     string
+	OnSourceDrift string
+
+	// CreateTargetBranch creates a missing target branch from TargetBranchFrom
+	// before the update, such as a new release branch
+	CreateTargetBranch bool
+	TargetBranchFrom   string
+
+	// MinInterval guards against update loops between automation systems: when the
+	// last commit to the file was made by go-tag-updater more recently, OnRecentUpdate
+	// decides whether the update is skipped or only warned about. Zero disables it.
+	MinInterval    time.Duration
+	OnRecentUpdate string
+
 	// Behavior flags
-	WaitForPreviousMR bool
-	AutoMerge         bool
-	DryRun            bool
-	Debug             bool
+	ConflictPolicy     string
+	CheckFileConflicts bool
+	UpdateExistingMR   bool
+	AutoMerge          bool
+	Squash             bool
+	RemoveSourceBranch bool
+	// KeepBranchOnFailure keeps the branch of a run that fails before opening its merge request
+	KeepBranchOnFailure bool
+	DryRun              bool
+	Debug               bool
+	FallbackRaw         bool
+	ResolveAnchors      bool
+	FollowRenames       bool
+
+	// TraceHTTP logs every GitLab request like --debug, with the bodies of requests
+	// and responses
+	TraceHTTP bool
+
+	// CommitBackend selects how the update is committed: through the API or a git
+	// clone. GPGKey signs the commits of the git backend.
+	CommitBackend string
+	GPGKey        string
+
+	// SkipPreflight skips the permission, protected branch and push rule checks run
+	// before the update branch is created
+	SkipPreflight bool
+
+	// OriginalBackup keeps the original file in GitLab for manual rollback: in the
+	// merge request description or as a .orig file committed next to it
+	OriginalBackup string
+
+	// ValidateSchema validates the updated content against the schemas of a format,
+	// such as k8s, before it is committed
+	ValidateSchema string
+	// ValidateCommands run on a temporary copy of the updated content, replacing {}
+	// with its path; each must exit with 0 for the update to proceed
+	ValidateCommands []string
+
+	// Hook commands run before the update, after the commit and after the merge
+	// request; only a failing pre-update command stops the update
+	PreUpdateCommands  []string
+	PostCommitCommands []string
+	PostMRCommands     []string
 
 	// Logging configuration
 	LogLevel  string
@@ -110,26 +456,237 @@ type CLIConfig struct {
 
 	// Timeouts
 	Timeout time.Duration
+
+	// Pipeline gating
+	WaitPipeline    bool
+	PipelineTimeout time.Duration
+
+	// Merge conflict handling after the merge request is opened
+	WatchConflicts  bool
+	AutoRebase      bool
+	ConflictTimeout time.Duration
+	// RecreateOnConflict closes a conflicting merge request and re-applies the
+	// update on a branch from the latest target branch
+	RecreateOnConflict bool
+
+	// Working hours auto-merge is restricted to, such as "Mon-Fri 09:00-17:00"
+	MergeWindow   string
+	MergeTimezone string
+
+	// Quiet rollout opens merge requests as drafts to be marked ready in batches later
+	QuietRollout bool
+
+	// Least-privilege policy
+	LeastPrivilege bool
+	AllowedFiles   []string
+	AllowedPaths   []string
+
+	// Maximum open merge requests created by the tool per project; 0 disables the limit
+	MaxOpenMRs int
+
+	// Semantic version policy of new tags
+	AllowBump      string
+	TagPrefix      string
+	AllowDowngrade bool
+
+	// Local mode updates the file on disk instead of going through GitLab
+	Local         bool
+	Backup        bool
+	BackupDir     string
+	RestoreBackup string
+	// BackupKeep and BackupMaxAge limit the backups kept of a file; zero disables a limit
+	BackupKeep   int
+	BackupMaxAge time.Duration
+
+	// Run tracking
+	RunID    string
+	StateDir string
+
+	// Audit trail
+	AuditFile       string
+	AuditEndpoint   string
+	AuditSigningKey string
+	AuditTimeout    time.Duration
+
+	// Metrics export
+	MetricsPushURL string
+	MetricsJob     string
+
+	// ReportFile receives a JSON or Markdown report of the run, chosen by extension
+	ReportFile string
+
+	// Run notifications
+	NotifySlackWebhook string
+	NotifyTeamsWebhook string
+	NotifyWebhookURL   string
+	NotifyTimeout      time.Duration
+
+	// Email notifications
+	NotifyEmailHost     string
+	NotifyEmailPort     int
+	NotifyEmailTLS      string
+	NotifyEmailFrom     string
+	NotifyEmailTo       []string
+	NotifyEmailUsername string
+	NotifyEmailPassword string
+}
+
+// conflictPolicy returns the configured conflict policy; the deprecated
+// --wait-previous-mr flag selects the wait policy
+func conflictPolicy() string {
+	if viper.GetBool("wait-previous-mr") {
+		return ConflictPolicyWait
+	}
+	return viper.GetString("defaults.conflict_policy")
 }
 
 // NewFromViper creates a CLI configuration from viper values
 func NewFromViper() (*CLIConfig, error) {
-	return &CLIConfig{
-		ProjectID:         viper.GetString("project-id"),
-		FilePath:          viper.GetString("file"),
-		NewTag:            viper.GetString("new-tag"),
-		GitLabToken:       viper.GetString("token"),
-		GitLabURL:         viper.GetString("gitlab-url"),
-		BranchName:        viper.GetString("branch-name"),
-		TargetBranch:      viper.GetString("target-branch"),
-		WaitForPreviousMR: viper.GetBool("wait-previous-mr"),
-		AutoMerge:         viper.GetBool("auto-merge"),
-		DryRun:            viper.GetBool("dry-run"),
-		Debug:             viper.GetBool("debug"),
-		LogLevel:          viper.GetString("log-level"),
-		LogFormat:         viper.GetString("log-format"),
-		Timeout:           viper.GetDuration("timeout"),
-	}, nil
+	credentials, err := ResolveCredentials(viper.GetString("auth-mode"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &CLIConfig{
+		ProjectID:               viper.GetString("project-id"),
+		FilePath:                viper.GetString("file"),
+		NewTag:                  viper.GetString("new-tag"),
+		TagTransforms:           viper.GetStringSlice("tag-transform"),
+		YAMLPath:                viper.GetString("yaml-path"),
+		DocSelector:             viper.GetString("doc-selector"),
+		RepoPathGlob:            viper.GetString("repo-path-glob"),
+		OldTag:                  viper.GetString("old-tag"),
+		Updater:                 viper.GetString(UpdaterKey),
+		RepoURL:                 viper.GetString("repo-url"),
+		FluxMode:                viper.GetString("flux-mode"),
+		Service:                 viper.GetString("service"),
+		ReleaseNotesFile:        viper.GetString("release-notes-file"),
+		ReleaseNotesProject:     viper.GetString("release-notes-project"),
+		ClosesIssues:            viper.GetStringSlice("closes-issues"),
+		RelatedIssues:           viper.GetStringSlice("related-issues"),
+		CommentIssues:           viper.GetBool("comment-issues"),
+		ApproverToken:           viper.GetString("approver-token"),
+		CheckApprovals:          viper.GetBool("check-approvals"),
+		GitLabToken:             credentials.Token,
+		TokenSource:             credentials.Source,
+		AuthMode:                credentials.Mode,
+		GitLabURL:               viper.GetString("gitlab-url"),
+		CACert:                  viper.GetString("gitlab.ca_cert"),
+		ClientCert:              viper.GetString("gitlab.client_cert"),
+		ClientKey:               viper.GetString("gitlab.client_key"),
+		InsecureSkipTLSVerify:   viper.GetBool("gitlab.insecure_skip_tls_verify"),
+		Proxy:                   viper.GetString("gitlab.proxy"),
+		NoProxy:                 viper.GetString("gitlab.no_proxy"),
+		ProxyUsername:           viper.GetString("gitlab.proxy_username"),
+		ProxyPassword:           viper.GetString("gitlab.proxy_password"),
+		HTTPCache:               viper.GetString("gitlab.http_cache"),
+		HTTPCacheDir:            viper.GetString("gitlab.http_cache_dir"),
+		CircuitBreakerThreshold: viper.GetInt("gitlab.circuit_breaker_threshold"),
+		CircuitBreakerCoolDown:  viper.GetDuration("gitlab.circuit_breaker_cooldown"),
+		BranchName:              viper.GetString("branch-name"),
+		TargetBranch:            viper.GetString("target-branch"),
+		SourceRef:               viper.GetString("source-ref"),
+		OnSourceDrift:           viper.GetString("defaults.on_source_drift"),
+		CreateTargetBranch:      viper.GetBool("create-target-branch"),
+		TargetBranchFrom:        viper.GetString("from"),
+		MinInterval:             viper.GetDuration("min-interval"),
+		OnRecentUpdate:          viper.GetString("on-recent-update"),
+		ConflictPolicy:          conflictPolicy(),
+		CheckFileConflicts:      viper.GetBool("defaults.check_file_conflicts"),
+		UpdateExistingMR:        viper.GetBool("update-existing-mr"),
+		AutoMerge:               viper.GetBool("auto-merge"),
+		Squash:                  viper.GetBool("squash"),
+		RemoveSourceBranch:      viper.GetBool("remove-source-branch"),
+		KeepBranchOnFailure:     viper.GetBool("keep-branch-on-failure"),
+		DryRun:                  viper.GetBool("dry-run"),
+		Debug:                   viper.GetBool("debug"),
+		TraceHTTP:               viper.GetBool("trace-http"),
+		FallbackRaw:             viper.GetBool("fallback-raw"),
+		ResolveAnchors:          viper.GetBool("resolve-anchors"),
+		FollowRenames:           viper.GetBool("follow-renames"),
+		CommitBackend:           viper.GetString("commit-backend"),
+		OriginalBackup:          viper.GetString("original-backup"),
+		ValidateSchema:          viper.GetString("validate-schema"),
+		ValidateCommands:        viper.GetStringSlice("validate-cmd"),
+		PreUpdateCommands:       viper.GetStringSlice("hooks.pre_update"),
+		PostCommitCommands:      viper.GetStringSlice("hooks.post_commit"),
+		PostMRCommands:          viper.GetStringSlice("hooks.post_mr"),
+		SkipPreflight:           viper.GetBool("skip-preflight"),
+		GPGKey:                  viper.GetString("gpg-key"),
+		LogLevel:                viper.GetString("log-level"),
+		LogFormat:               viper.GetString("log-format"),
+		Timeout:                 viper.GetDuration("timeout"),
+		WaitPipeline:            viper.GetBool("wait-pipeline"),
+		PipelineTimeout:         viper.GetDuration("pipeline-timeout"),
+		WatchConflicts:          viper.GetBool("watch-conflicts"),
+		AutoRebase:              viper.GetBool("auto-rebase"),
+		RecreateOnConflict:      viper.GetBool("recreate-on-conflict"),
+		ConflictTimeout:         viper.GetDuration("conflict-timeout"),
+		MergeWindow:             viper.GetString("defaults.merge_window"),
+		MergeTimezone:           viper.GetString("defaults.merge_timezone"),
+		QuietRollout:            viper.GetBool("defaults.quiet_rollout"),
+		LeastPrivilege:          viper.GetBool("policy.least_privilege"),
+		AllowedFiles:            viper.GetStringSlice("policy.allowed_files"),
+		AllowedPaths:            viper.GetStringSlice("policy.allowed_paths"),
+		MaxOpenMRs:              viper.GetInt("policy.max_open_mrs"),
+		AllowBump:               viper.GetString("policy.allow_bump"),
+		TagPrefix:               viper.GetString("policy.tag_prefix"),
+		AllowDowngrade:          viper.GetBool("policy.allow_downgrade"),
+		Local:                   viper.GetBool("local"),
+		Backup:                  viper.GetBool("backup"),
+		BackupDir:               viper.GetString("backup-dir"),
+		RestoreBackup:           viper.GetString("restore-backup"),
+		BackupKeep:              viper.GetInt("backup-keep"),
+		BackupMaxAge:            viper.GetDuration("backup-max-age"),
+		RunID:                   viper.GetString("run-id"),
+		StateDir:                viper.GetString("state.dir"),
+		AuditFile:               viper.GetString("logging.audit.file"),
+		AuditEndpoint:           viper.GetString("logging.audit.endpoint"),
+		AuditSigningKey:         viper.GetString("logging.audit.signing_key"),
+		AuditTimeout:            viper.GetDuration("logging.audit.timeout"),
+		MetricsPushURL:          viper.GetString("metrics.push_url"),
+		MetricsJob:              viper.GetString("metrics.job"),
+		ReportFile:              viper.GetString("report-file"),
+		NotifySlackWebhook:      viper.GetString("notify.slack.webhook_url"),
+		NotifyTeamsWebhook:      viper.GetString("notify.teams.webhook_url"),
+		NotifyWebhookURL:        viper.GetString("notify.webhook.url"),
+		NotifyTimeout:           viper.GetDuration("notify.timeout"),
+		NotifyEmailHost:         viper.GetString("notify.email.host"),
+		NotifyEmailPort:         viper.GetInt("notify.email.port"),
+		NotifyEmailTLS:          viper.GetString("notify.email.tls"),
+		NotifyEmailFrom:         viper.GetString("notify.email.from"),
+		NotifyEmailTo:           viper.GetStringSlice("notify.email.to"),
+		NotifyEmailUsername:     viper.GetString("notify.email.username"),
+		NotifyEmailPassword:     viper.GetString("notify.email.password"),
+	}
+	if err := cfg.SetNewTag(cfg.NewTag); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SetNewTag sets the new tag as rewritten by the tag transforms. Every entry point
+// taking a tag from outside, such as a flag, a webhook or a registry, sets it this
+// way, so the tag is transformed exactly once.
+func (c *CLIConfig) SetNewTag(tag string) error {
+	c.NewTag = tag
+	if len(c.TagTransforms) == 0 || tag == "" {
+		return nil
+	}
+	chain, err := tagtransform.Parse(c.TagTransforms)
+	if err != nil {
+		return err
+	}
+	c.NewTag, err = chain.Apply(tag)
+	return err
+}
+
+// LoadOptions selects the configuration file and profile to load
+type LoadOptions struct {
+	// ConfigFile is an explicit configuration file; it must exist when set
+	ConfigFile string
+	// Profile names an entry under profiles whose values override the top-level values
+	Profile string
 }
 
 // Load loads configuration from file and environment variables
@@ -139,6 +696,25 @@ func Load() (*Config, error) {
 
 // LoadFromFile loads configuration from a specific file
 func LoadFromFile(configFile string) (*Config, error) {
+	return load(configFile, "")
+}
+
+// LoadWithOptions loads configuration from an explicit file, falling back to the
+// default file, and merges the selected profile over it. CLI flags and environment
+// variables still take precedence over profile values.
+func LoadWithOptions(opts LoadOptions) (*Config, error) {
+	configFile := opts.ConfigFile
+	if configFile == "" {
+		configFile = DefaultConfigFile
+	} else if !fileExists(configFile) {
+		return nil, errors.NewConfigError(fmt.Sprintf("config file not found: %s", configFile))
+	}
+
+	return load(configFile, opts.Profile)
+}
+
+// load reads the configuration file if present and applies the named profile
+func load(configFile, profile string) (*Config, error) {
 	viper.SetConfigName("go-tag-updater")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
@@ -147,6 +723,7 @@ func LoadFromFile(configFile string) (*Config, error) {
 
 	// Set environment variable prefix
 	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	viper.AutomaticEnv()
 
 	// Set defaults
@@ -156,10 +733,14 @@ func LoadFromFile(configFile string) (*Config, error) {
 	if configFile != "" && fileExists(configFile) {
 		viper.SetConfigFile(configFile)
 		if err := viper.ReadInConfig(); err != nil {
-			return nil, err
+			return nil, errors.NewConfigErrorWithCause("failed to read config file "+configFile, err)
 		}
 	}
 
+	if err := applyProfile(profile); err != nil {
+		return nil, err
+	}
+
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, err
@@ -168,6 +749,29 @@ func LoadFromFile(configFile string) (*Config, error) {
 	return &config, nil
 }
 
+// applyProfile merges the values of a named profile over the loaded configuration
+func applyProfile(profile string) error {
+	if profile == "" {
+		return nil
+	}
+
+	key := ProfilesKey + "." + profile
+	values := viper.GetStringMap(key)
+	if !viper.IsSet(key) {
+		// Updater profiles of manifest kinds are built in
+		if !manifest.IsKnown(profile) {
+			return errors.NewConfigError(fmt.Sprintf("profile %q is not defined in the configuration file", profile))
+		}
+		values = map[string]interface{}{UpdaterKey: profile}
+	}
+
+	if err := viper.MergeConfigMap(values); err != nil {
+		return errors.NewConfigErrorWithCause("failed to apply profile "+profile, err)
+	}
+
+	return nil
+}
+
 // setDefaults sets default configuration values
 func setDefaults() {
 	// GitLab defaults
@@ -175,13 +779,18 @@ func setDefaults() {
 	viper.SetDefault("gitlab.timeout", DefaultTimeout)
 	viper.SetDefault("gitlab.retry_count", DefaultRetryCount)
 	viper.SetDefault("gitlab.rate_limit_rps", DefaultRateLimitRPS)
+	viper.SetDefault("gitlab.http_cache", HTTPCacheOff)
+	viper.SetDefault("gitlab.circuit_breaker_threshold", DefaultCircuitBreakerThreshold)
+	viper.SetDefault("gitlab.circuit_breaker_cooldown", DefaultCircuitBreakerCoolDown)
 
 	// Default behavior
 	viper.SetDefault("defaults.target_branch", "main")
 	viper.SetDefault("defaults.branch_prefix", "update-tag")
 	viper.SetDefault("defaults.merge_timeout", DefaultMergeTimeout)
-	viper.SetDefault("defaults.wait_previous_mr", false)
+	viper.SetDefault("defaults.conflict_policy", ConflictPolicyFail)
+	viper.SetDefault("defaults.check_file_conflicts", true)
 	viper.SetDefault("defaults.auto_merge", false)
+	viper.SetDefault("defaults.on_source_drift", SourceDriftRefuse)
 
 	// Performance defaults
 	viper.SetDefault("performance.max_concurrent_requests", DefaultMaxConcurrentReqs)
@@ -193,6 +802,25 @@ func setDefaults() {
 	viper.SetDefault("logging.format", "text")
 	viper.SetDefault("logging.enable_file", false)
 	viper.SetDefault("logging.file_path", "go-tag-updater.log")
+	viper.SetDefault("logging.audit.timeout", DefaultTimeout)
+
+	// Metrics defaults
+	viper.SetDefault("metrics.job", "go-tag-updater")
+
+	// Notification defaults
+	viper.SetDefault("notify.timeout", DefaultTimeout)
+	viper.SetDefault("notify.email.port", notify.DefaultSMTPPort)
+	viper.SetDefault("notify.email.tls", notify.EmailTLSStartTLS)
+
+	// Policy defaults
+	viper.SetDefault("policy.least_privilege", false)
+
+	// Webhook server defaults
+	viper.SetDefault("serve.listen", DefaultServeListen)
+	viper.SetDefault("serve.max_concurrent_jobs", DefaultServeMaxJobs)
+
+	// Registry polling defaults
+	viper.SetDefault("registry_watch.interval", DefaultRegistryWatchInterval)
 }
 
 // fileExists checks if a file exists
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestProfileConfig = `target-branch: main
+defaults:
+  branch_prefix: update-tag
+profiles:
+  staging:
+    target-branch: staging
+  prod:
+    target-branch: production
+    defaults:
+      branch_prefix: release-tag
+`
+	TestConfigFilePermission = 0o600
+)
+
+// writeTestConfig writes a configuration file into a temporary directory
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), DefaultConfigFile)
+	if err := os.WriteFile(path, []byte(content), TestConfigFilePermission); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadWithOptions_Profiles(t *testing.T) {
+	path := writeTestConfig(t, TestProfileConfig)
+
+	tests := []struct {
+		name         string
+		profile      string
+		targetBranch string
+		branchPrefix string
+		updater      string
+	}{
+		{name: "no profile", targetBranch: "main", branchPrefix: "update-tag"},
+		{name: "staging profile", profile: "staging", targetBranch: "staging", branchPrefix: "update-tag"},
+		{name: "prod profile", profile: "prod", targetBranch: "production", branchPrefix: "release-tag"},
+		{name: "built-in updater profile", profile: "argocd", targetBranch: "main", branchPrefix: "update-tag",
+			updater: "argocd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			cfg, err := LoadWithOptions(LoadOptions{ConfigFile: path, Profile: tt.profile})
+			if err != nil {
+				t.Fatalf("LoadWithOptions() unexpected error: %v", err)
+			}
+
+			if got := viper.GetString("target-branch"); got != tt.targetBranch {
+				t.Errorf("target-branch = %q, want %q", got, tt.targetBranch)
+			}
+			if cfg.Defaults.BranchPrefix != tt.branchPrefix {
+				t.Errorf("Defaults.BranchPrefix = %q, want %q", cfg.Defaults.BranchPrefix, tt.branchPrefix)
+			}
+			if got := viper.GetString(UpdaterKey); got != tt.updater {
+				t.Errorf("%s = %q, want %q", UpdaterKey, got, tt.updater)
+			}
+		})
+	}
+}
+
+func TestLoadWithOptions_Errors(t *testing.T) {
+	path := writeTestConfig(t, TestProfileConfig)
+
+	tests := []struct {
+		name string
+		opts LoadOptions
+	}{
+		{name: "missing explicit file", opts: LoadOptions{ConfigFile: filepath.Join(t.TempDir(), "missing.yaml")}},
+		{name: "unknown profile", opts: LoadOptions{ConfigFile: path, Profile: "qa"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			_, err := LoadWithOptions(tt.opts)
+			if code := errors.GetErrorCode(err); code != errors.ErrCodeConfiguration {
+				t.Errorf("LoadWithOptions() error code = %d, want %d (%v)", code, errors.ErrCodeConfiguration, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		failing []string
+	}{
+		{name: "valid", content: TestProfileConfig},
+		{name: "wrong type", content: "gitlab:\n  retry_count: many\n", failing: []string{"load"}},
+		{
+			name: "out of range values",
+			content: `gitlab:
+  base_url: gitlab.example.com
+  timeout: -5s
+  rate_limit_rps: 0
+defaults:
+  conflict_policy: ignore
+metrics:
+  push_url: "http://"
+timezone: Mars/Olympus
+`,
+			failing: []string{
+				"gitlab.base_url", "gitlab.timeout", "gitlab.rate_limit_rps",
+				"defaults.conflict_policy", "metrics.push_url", "timezone",
+			},
+		},
+		{
+			name:    "incomplete email notifications",
+			content: "notify:\n  email:\n    host: smtp.example.com\n    tls: ssl\n",
+			failing: []string{"notify.email.tls", "notify.email.from", "notify.email.to"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			report := Validate(LoadOptions{ConfigFile: writeTestConfig(t, tt.content)})
+			var failing []string
+			for _, check := range report.Checks {
+				if check.Status == CheckFail {
+					failing = append(failing, check.Key)
+				}
+			}
+			if strings.Join(failing, ",") != strings.Join(tt.failing, ",") {
+				t.Errorf("failing checks = %v, want %v (%+v)", failing, tt.failing, report.Checks)
+			}
+			if report.Failures() != len(tt.failing) {
+				t.Errorf("Failures() = %d, want %d", report.Failures(), len(tt.failing))
+			}
+		})
+	}
+}
+
+func TestApplyFlagEnv_Precedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		env    string
+		config bool
+		want   string
+	}{
+		{name: "flag over environment", args: []string{"--conflict-policy=force"}, env: "wait", config: true, want: "force"},
+		{name: "environment over config", env: "wait", config: true, want: "wait"},
+		{name: "config over default", config: true, want: "queue"},
+		{name: "default", want: ConflictPolicyFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			if tt.env != "" {
+				t.Setenv(EnvName("conflict-policy"), tt.env)
+			}
+			opts := LoadOptions{}
+			if tt.config {
+				opts.ConfigFile = writeTestConfig(t, "defaults:\n  conflict_policy: queue\n")
+			}
+
+			flags := pflag.NewFlagSet("update", pflag.ContinueOnError)
+			flags.String("conflict-policy", ConflictPolicyFail, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if err := ApplyFlagEnv(flags); err != nil {
+				t.Fatalf("ApplyFlagEnv() unexpected error: %v", err)
+			}
+			_ = viper.BindPFlag("defaults.conflict_policy", flags.Lookup("conflict-policy"))
+			if _, err := LoadWithOptions(opts); err != nil {
+				t.Fatalf("LoadWithOptions() unexpected error: %v", err)
+			}
+
+			if got := viper.GetString("defaults.conflict_policy"); got != tt.want {
+				t.Errorf("defaults.conflict_policy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_EnvKeys(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("GO_TAG_UPDATER_PROJECT_ID", "group/project")
+	t.Setenv("GO_TAG_UPDATER_GITLAB_BASE_URL", "https://gitlab.example.com")
+
+	cfg, err := LoadWithOptions(LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() unexpected error: %v", err)
+	}
+	if got := viper.GetString("project-id"); got != "group/project" {
+		t.Errorf("project-id = %q, want group/project", got)
+	}
+	if cfg.GitLab.BaseURL != "https://gitlab.example.com" {
+		t.Errorf("GitLab.BaseURL = %q, want the environment value", cfg.GitLab.BaseURL)
+	}
+
+	flags := pflag.NewFlagSet("cleanup", pflag.ContinueOnError)
+	flags.Duration("older-than", 0, "")
+	t.Setenv(EnvName("older-than"), "soon")
+	if err := ApplyFlagEnv(flags); errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("ApplyFlagEnv() error = %v, want a validation error", err)
+	}
+}
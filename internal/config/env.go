@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// HelpFlag is the flag cobra adds to every command; it is never read from the environment
+const HelpFlag = "help"
+
+// envKeyReplacer maps configuration keys to environment variable names: the dots of
+// nested keys and the dashes of flag keys both become underscores
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// EnvName returns the environment variable setting a CLI flag or configuration key,
+// such as GO_TAG_UPDATER_PROJECT_ID for --project-id and GO_TAG_UPDATER_GITLAB_BASE_URL
+// for gitlab.base_url
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// ApplyFlagEnv sets every flag that was not passed on the command line from its
+// environment variable. Flags bound to configuration keys then take precedence over
+// the configuration file, so values resolve as flag, environment, configuration
+// file and default, in that order. Empty variables are ignored.
+func ApplyFlagEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == HelpFlag {
+			return
+		}
+		name := EnvName(flag.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+		if setErr := flags.Set(flag.Name, value); setErr != nil {
+			err = errors.NewValidationError(fmt.Sprintf("invalid value %q of %s for --%s: %v",
+				value, name, flag.Name, setErr))
+		}
+	})
+	return err
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+)
+
+// Validation check outcomes; CheckSkip marks checks that did not run
+const (
+	CheckPass = "PASS"
+	CheckWarn = "WARN"
+	CheckFail = "FAIL"
+	CheckSkip = "SKIP"
+)
+
+// Bounds of the values config validate accepts
+const (
+	// MaxRateLimitRPS is the highest sane GitLab request rate
+	MaxRateLimitRPS = 1000
+	// MaxRetryCount is the highest sane number of retries of a GitLab request
+	MaxRetryCount = 10
+	// MaxConcurrentRequests is the highest sane number of concurrent GitLab requests
+	MaxConcurrentRequests = 100
+
+	// maxPort is the highest TCP port
+	maxPort = 65535
+)
+
+// ValidationCheck is the outcome of checking one configuration value
+type ValidationCheck struct {
+	Key    string
+	Status string
+	Detail string
+}
+
+// ValidationReport lists the checks of a configuration file and its profile
+type ValidationReport struct {
+	// File is the configuration file that was loaded, empty when only defaults apply
+	File    string
+	Profile string
+	Checks  []ValidationCheck
+}
+
+// Add records the outcome of a check
+func (r *ValidationReport) Add(key, status, detail string) {
+	r.Checks = append(r.Checks, ValidationCheck{Key: key, Status: status, Detail: detail})
+}
+
+// Failures returns the number of failed checks
+func (r *ValidationReport) Failures() int {
+	failures := 0
+	for _, check := range r.Checks {
+		if check.Status == CheckFail {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Validate loads the configuration like every command does and checks its values:
+// types while loading, then positive timeouts, sane rate limits and counts, known
+// policies and parseable URLs and time zones. Load errors are reported as a
+// failed check rather than returned.
+func Validate(opts LoadOptions) *ValidationReport {
+	report := &ValidationReport{Profile: opts.Profile}
+
+	cfg, err := LoadWithOptions(opts)
+	report.File = viper.ConfigFileUsed()
+	if err != nil {
+		report.Add("load", CheckFail, err.Error())
+		return report
+	}
+	if report.File == "" {
+		report.Add("load", CheckWarn, "no configuration file found, only defaults and environment apply")
+	} else {
+		report.Add("load", CheckPass, "types match the configuration schema")
+	}
+
+	checkURL(report, "gitlab.base_url", cfg.GitLab.BaseURL, true)
+	checkURL(report, "gitlab-url", viper.GetString("gitlab-url"), false)
+	checkPositive(report, "gitlab.timeout", cfg.GitLab.Timeout)
+	checkRange(report, "gitlab.retry_count", cfg.GitLab.RetryCount, 0, MaxRetryCount)
+	checkRange(report, "gitlab.rate_limit_rps", cfg.GitLab.RateLimitRPS, 1, MaxRateLimitRPS)
+	checkOneOf(report, "gitlab.http_cache", cfg.GitLab.HTTPCache, HTTPCacheOff, HTTPCacheMemory, HTTPCacheDisk)
+	if cfg.GitLab.CircuitBreakerThreshold < 0 {
+		report.Add("gitlab.circuit_breaker_threshold", CheckFail,
+			fmt.Sprintf("%d is negative; use 0 to disable the circuit breaker", cfg.GitLab.CircuitBreakerThreshold))
+	}
+	checkPositive(report, "gitlab.circuit_breaker_cooldown", cfg.GitLab.CircuitBreakerCoolDown)
+
+	checkPositive(report, "defaults.merge_timeout", cfg.Defaults.MergeTimeout)
+	checkOneOf(report, "defaults.conflict_policy", cfg.Defaults.ConflictPolicy,
+		ConflictPolicyFail, ConflictPolicyWait, ConflictPolicyForce, ConflictPolicyQueue)
+	checkOneOf(report, "defaults.on_source_drift", cfg.Defaults.OnSourceDrift, SourceDriftRefuse, SourceDriftWarn)
+	checkTimezone(report, "defaults.merge_timezone", cfg.Defaults.MergeTimezone)
+
+	checkRange(report, "performance.max_concurrent_requests", cfg.Performance.MaxConcurrentRequests,
+		1, MaxConcurrentRequests)
+	checkPositive(report, "performance.request_timeout", cfg.Performance.RequestTimeout)
+	checkRange(report, "performance.buffer_size", cfg.Performance.BufferSize, 1, MaxConfigFileSize)
+
+	checkURL(report, "logging.audit.endpoint", cfg.Logging.Audit.Endpoint, false)
+	checkPositive(report, "logging.audit.timeout", cfg.Logging.Audit.Timeout)
+	checkURL(report, "metrics.push_url", cfg.Metrics.PushURL, false)
+	checkURL(report, "notify.slack.webhook_url", cfg.Notify.Slack.WebhookURL, false)
+	checkURL(report, "notify.teams.webhook_url", cfg.Notify.Teams.WebhookURL, false)
+	checkURL(report, "notify.webhook.url", cfg.Notify.Webhook.URL, false)
+	checkPositive(report, "notify.timeout", cfg.Notify.Timeout)
+	checkEmail(report, &cfg.Notify.Email)
+
+	if cfg.Policy.MaxOpenMRs < 0 {
+		report.Add("policy.max_open_mrs", CheckFail, fmt.Sprintf("%d is negative; use 0 to disable the limit",
+			cfg.Policy.MaxOpenMRs))
+	}
+	checkRange(report, "serve.max_concurrent_jobs", cfg.Serve.MaxConcurrentJobs, 1, MaxConcurrentRequests)
+	checkPositive(report, "registry_watch.interval", cfg.RegistryWatch.Interval)
+	checkTimezone(report, "timezone", cfg.Timezone)
+
+	return report
+}
+
+// checkURL requires an absolute http or https URL; optional values may be empty
+func checkURL(report *ValidationReport, key, value string, required bool) {
+	if value == "" {
+		if required {
+			report.Add(key, CheckFail, "is required")
+		}
+		return
+	}
+
+	parsed, err := url.Parse(value)
+	switch {
+	case err != nil:
+		report.Add(key, CheckFail, fmt.Sprintf("cannot be parsed: %v", err))
+	case parsed.Scheme != "http" && parsed.Scheme != "https":
+		report.Add(key, CheckFail, fmt.Sprintf("%q must use http or https", value))
+	case parsed.Host == "":
+		report.Add(key, CheckFail, fmt.Sprintf("%q has no host", value))
+	default:
+		report.Add(key, CheckPass, value)
+	}
+}
+
+// checkEmail requires a sender and recipients when email notifications are enabled
+func checkEmail(report *ValidationReport, email *NotifyEmailConfig) {
+	if email.Host == "" {
+		return
+	}
+	checkRange(report, "notify.email.port", email.Port, 1, maxPort)
+	checkOneOf(report, "notify.email.tls", email.TLS, notify.EmailTLSStartTLS, notify.EmailTLSImplicit,
+		notify.EmailTLSNone)
+	if email.From == "" {
+		report.Add("notify.email.from", CheckFail, "is required with notify.email.host")
+	}
+	if len(email.To) == 0 {
+		report.Add("notify.email.to", CheckFail, "is required with notify.email.host")
+	}
+}
+
+// checkPositive requires a duration greater than zero
+func checkPositive(report *ValidationReport, key string, value time.Duration) {
+	if value <= 0 {
+		report.Add(key, CheckFail, fmt.Sprintf("%s must be positive", value))
+		return
+	}
+	report.Add(key, CheckPass, value.String())
+}
+
+// checkRange requires a value between minimum and maximum, both included
+func checkRange(report *ValidationReport, key string, value, minimum, maximum int) {
+	if value < minimum || value > maximum {
+		report.Add(key, CheckFail, fmt.Sprintf("%d is outside %d..%d", value, minimum, maximum))
+		return
+	}
+	report.Add(key, CheckPass, fmt.Sprint(value))
+}
+
+// checkOneOf requires one of the allowed values
+func checkOneOf(report *ValidationReport, key, value string, allowed ...string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			report.Add(key, CheckPass, value)
+			return
+		}
+	}
+	report.Add(key, CheckFail, fmt.Sprintf("%q is not one of %v", value, allowed))
+}
+
+// checkTimezone requires an IANA time zone name when the value is set
+func checkTimezone(report *ValidationReport, key, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.LoadLocation(value); err != nil {
+		report.Add(key, CheckFail, fmt.Sprintf("unknown time zone %q", value))
+		return
+	}
+	report.Add(key, CheckPass, value)
+}
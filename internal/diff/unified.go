@@ -0,0 +1,165 @@
+// Package diff renders line-based unified diffs for previewing file changes.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultContextLines defines how many unchanged lines surround each hunk
+	DefaultContextLines = 3
+	// MaxLCSCells bounds the line comparison table; larger inputs fall back to a full replacement hunk
+	MaxLCSCells = 4_000_000
+)
+
+// opKind identifies the type of a diff line
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// op is a single line of the edit script
+type op struct {
+	kind opKind
+	line string
+	// aLine and bLine are the zero-based positions of the line in each input
+	aLine, bLine int
+}
+
+// Unified returns a unified diff between original and updated, or an empty string
+// when both are identical
+func Unified(originalName, updatedName, original, updated string, contextLines int) string {
+	if original == updated {
+		return ""
+	}
+	if contextLines < 0 {
+		contextLines = DefaultContextLines
+	}
+
+	ops := editScript(splitLines(original), splitLines(updated))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", originalName, updatedName)
+	for _, hunk := range hunks(ops, contextLines) {
+		writeHunk(&builder, ops[hunk[0]:hunk[1]])
+	}
+
+	return builder.String()
+}
+
+// splitLines splits content into lines keeping a trailing empty line out
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// editScript computes a minimal line edit script using the longest common subsequence
+func editScript(a, b []string) []op {
+	if len(a)*len(b) > MaxLCSCells {
+		return replaceAll(a, b)
+	}
+
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{kind: opEqual, line: a[i], aLine: i, bLine: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{kind: opDelete, line: a[i], aLine: i, bLine: j})
+			i++
+		default:
+			ops = append(ops, op{kind: opInsert, line: b[j], aLine: i, bLine: j})
+			j++
+		}
+	}
+
+	return ops
+}
+
+// replaceAll returns an edit script deleting every line of a and inserting every line of b
+func replaceAll(a, b []string) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, op{kind: opDelete, line: line, aLine: i})
+	}
+	for j, line := range b {
+		ops = append(ops, op{kind: opInsert, line: line, aLine: len(a), bLine: j})
+	}
+	return ops
+}
+
+// hunks groups changed lines with their context into [start, end) ranges of ops
+func hunks(ops []op, contextLines int) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+
+		start := max(i-contextLines, 0)
+		end := min(i+1+contextLines, len(ops))
+
+		if n := len(result); n > 0 && start <= result[n-1][1] {
+			result[n-1][1] = end
+		} else {
+			result = append(result, [2]int{start, end})
+		}
+	}
+	return result
+}
+
+// writeHunk writes one hunk header and its lines
+func writeHunk(builder *strings.Builder, ops []op) {
+	aStart, bStart := ops[0].aLine, ops[0].bLine
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			aCount++
+		}
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(builder, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		builder.WriteByte(byte(o.kind))
+		builder.WriteString(o.line)
+		builder.WriteByte('\n')
+	}
+}
+
+// hunkRange formats a hunk range in unified diff notation
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	TestOriginal = `image:
+  repository: test/repo
+  tag: v1.0.0
+replicas: 2
+`
+	TestUpdated = `image:
+  repository: test/repo
+  tag: v1.2.3
+replicas: 2
+`
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		updated  string
+		context  int
+		expected string
+	}{
+		{
+			name:     "identical content",
+			original: TestOriginal,
+			updated:  TestOriginal,
+			context:  DefaultContextLines,
+			expected: "",
+		},
+		{
+			name:     "single line change",
+			original: TestOriginal,
+			updated:  TestUpdated,
+			context:  DefaultContextLines,
+			expected: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n image:\n   repository: test/repo\n-  tag: v1.0.0\n+  tag: v1.2.3\n replicas: 2\n",
+		},
+		{
+			name:     "no context",
+			original: TestOriginal,
+			updated:  TestUpdated,
+			context:  0,
+			expected: "--- a\n+++ b\n@@ -3 +3 @@\n-  tag: v1.0.0\n+  tag: v1.2.3\n",
+		},
+		{
+			name:     "insertion into empty file",
+			original: "",
+			updated:  "a\n",
+			context:  DefaultContextLines,
+			expected: "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("a", "b", tt.original, tt.updated, tt.context)
+			if got != tt.expected {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	original := strings.Join(lines, "\n") + "\n"
+
+	lines[1] = "first"
+	lines[18] = "second"
+	updated := strings.Join(lines, "\n") + "\n"
+
+	got := Unified("a", "b", original, updated, 1)
+	if count := strings.Count(got, "@@ -"); count != 2 {
+		t.Errorf("Unified() produced %d hunks, want 2:\n%s", count, got)
+	}
+}
@@ -0,0 +1,221 @@
+// Package features gates subsystems behind feature flags that are enabled per user
+// through the configuration file or the environment, so large subsystems can ship
+// disabled and features slated for removal warn the users still relying on them.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Stage is the maturity of a feature
+type Stage string
+
+const (
+	// StageAlpha features are incomplete and disabled unless enabled explicitly
+	StageAlpha Stage = "alpha"
+	// StageBeta features are complete but may still change; they may be disabled
+	StageBeta Stage = "beta"
+	// StageDeprecated features are slated for removal and warn when used
+	StageDeprecated Stage = "deprecated"
+
+	// EnvPrefix starts the environment variables setting a feature, such as
+	// GO_TAG_UPDATER_FEATURE_SERVE=false; they take precedence over the configuration file
+	EnvPrefix = "GO_TAG_UPDATER_FEATURE_"
+	// ConfigKey is the configuration file section mapping feature names to booleans
+	ConfigKey = "features"
+)
+
+// Names of the registered features
+const (
+	// Serve gates the serve command running the webhook server
+	Serve = "serve"
+	// RegistryWatch gates the registry-watch command polling image registries
+	RegistryWatch = "registry-watch"
+	// RootUpdate runs an update when update flags are passed to the root command
+	// instead of the update subcommand
+	RootUpdate = "root-update"
+)
+
+// Feature describes a feature flag
+type Feature struct {
+	Name        string
+	Description string
+	Stage       Stage
+	// Default is the state of the feature when neither configuration nor environment set it
+	Default bool
+	// RemovedIn names the release removing a deprecated feature, and Replacement what
+	// to use instead
+	RemovedIn   string
+	Replacement string
+}
+
+// registry lists every feature flag
+var registry = []Feature{
+	{
+		Name:        Serve,
+		Description: "Webhook server started by the serve command",
+		Stage:       StageBeta,
+		Default:     true,
+	},
+	{
+		Name:        RegistryWatch,
+		Description: "Registry polling started by the registry-watch command",
+		Stage:       StageBeta,
+		Default:     true,
+	},
+	{
+		Name:        RootUpdate,
+		Description: "Tag updates run by passing update flags to the root command",
+		Stage:       StageDeprecated,
+		Default:     true,
+		RemovedIn:   "v2.0.0",
+		Replacement: "run go-tag-updater update with the same flags",
+	},
+}
+
+// All returns every registered feature sorted by name
+func All() []Feature {
+	all := append([]Feature(nil), registry...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// lookup returns the registered feature of a name
+func lookup(name string) (Feature, bool) {
+	for _, feature := range registry {
+		if feature.Name == name {
+			return feature, true
+		}
+	}
+	return Feature{}, false
+}
+
+// EnvName returns the environment variable setting a feature
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Set holds the resolved state of every feature
+type Set struct {
+	enabled map[string]bool
+	// explicit records the features set by configuration or environment
+	explicit map[string]bool
+
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+// Resolve combines the defaults with the configuration file section and the
+// environment; lookupEnv is usually os.LookupEnv. Unknown feature names and values
+// that are not booleans are configuration errors.
+func Resolve(configured map[string]interface{}, lookupEnv func(string) (string, bool)) (*Set, error) {
+	set := &Set{
+		enabled:  make(map[string]bool, len(registry)),
+		explicit: make(map[string]bool),
+		warned:   make(map[string]bool),
+	}
+	for _, feature := range registry {
+		set.enabled[feature.Name] = feature.Default
+	}
+
+	for name, value := range configured {
+		if _, ok := lookup(name); !ok {
+			return nil, errors.NewConfigError(fmt.Sprintf("unknown feature %q in %s", name, ConfigKey))
+		}
+		enabled, err := parseBool(value)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid value of feature %q: %v", name, err))
+		}
+		set.enabled[name] = enabled
+		set.explicit[name] = true
+	}
+
+	if lookupEnv == nil {
+		return set, nil
+	}
+	for _, feature := range registry {
+		value, ok := lookupEnv(EnvName(feature.Name))
+		if !ok {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid value of %s: %q is not a boolean",
+				EnvName(feature.Name), value))
+		}
+		set.enabled[feature.Name] = enabled
+		set.explicit[feature.Name] = true
+	}
+	return set, nil
+}
+
+// parseBool accepts booleans and their string forms, as YAML and viper deliver them
+func parseBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("%q is not a boolean", v)
+		}
+		return enabled, nil
+	default:
+		return false, fmt.Errorf("%v is not a boolean", value)
+	}
+}
+
+// Enabled reports whether a feature is enabled; unknown features are disabled
+func (s *Set) Enabled(name string) bool {
+	return s.enabled[name]
+}
+
+// Require returns a configuration error naming how to enable a disabled feature
+func (s *Set) Require(name string) error {
+	if s.Enabled(name) {
+		return nil
+	}
+	return errors.NewConfigError(fmt.Sprintf("feature %s is disabled: enable it with %s.%s: true or %s=true",
+		name, ConfigKey, name, EnvName(name)))
+}
+
+// WarnConfigured logs a deprecation warning for every deprecated feature the
+// configuration or environment sets, since the setting stops working on removal
+func (s *Set) WarnConfigured(log *logger.Logger) {
+	for _, feature := range All() {
+		if feature.Stage == StageDeprecated && s.explicit[feature.Name] {
+			s.warn(log, feature, "feature flag is set")
+		}
+	}
+}
+
+// Use logs a deprecation warning, once per set, when code relies on a deprecated feature
+func (s *Set) Use(log *logger.Logger, name string) {
+	if feature, ok := lookup(name); ok && feature.Stage == StageDeprecated {
+		s.warn(log, feature, "feature is used")
+	}
+}
+
+// warn logs the deprecation of a feature unless it was logged before
+func (s *Set) warn(log *logger.Logger, feature Feature, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.warned[feature.Name] {
+		return
+	}
+	s.warned[feature.Name] = true
+
+	log.WithFields(map[string]interface{}{
+		"feature":     feature.Name,
+		"removed_in":  feature.RemovedIn,
+		"replacement": feature.Replacement,
+		"reason":      reason,
+	}).Warnf("Deprecated: %s will be removed in %s; %s", feature.Description, feature.RemovedIn, feature.Replacement)
+}
@@ -0,0 +1,102 @@
+package features
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func envFunc(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured map[string]interface{}
+		env        map[string]string
+		feature    string
+		want       bool
+		wantErr    bool
+	}{
+		{name: "default", feature: Serve, want: true},
+		{name: "config disables", configured: map[string]interface{}{Serve: false}, feature: Serve},
+		{name: "config string", configured: map[string]interface{}{RegistryWatch: "false"}, feature: RegistryWatch},
+		{
+			name:       "env overrides config",
+			configured: map[string]interface{}{Serve: false},
+			env:        map[string]string{"GO_TAG_UPDATER_FEATURE_SERVE": "true"},
+			feature:    Serve,
+			want:       true,
+		},
+		{
+			name:    "env with dashes in the name",
+			env:     map[string]string{"GO_TAG_UPDATER_FEATURE_ROOT_UPDATE": "0"},
+			feature: RootUpdate,
+		},
+		{name: "unknown feature", configured: map[string]interface{}{"daemon": true}, wantErr: true},
+		{name: "invalid config value", configured: map[string]interface{}{Serve: 3}, wantErr: true},
+		{name: "invalid env value", env: map[string]string{"GO_TAG_UPDATER_FEATURE_SERVE": "maybe"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := Resolve(tt.configured, envFunc(tt.env))
+			if tt.wantErr {
+				if errors.GetErrorCode(err) != errors.ErrCodeConfiguration {
+					t.Fatalf("Resolve() error = %v, want a configuration error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got := set.Enabled(tt.feature); got != tt.want {
+				t.Errorf("Enabled(%s) = %v, want %v", tt.feature, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet_Require(t *testing.T) {
+	set, err := Resolve(map[string]interface{}{Serve: false}, nil)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	if err := set.Require(RegistryWatch); err != nil {
+		t.Errorf("Require(%s) error = %v", RegistryWatch, err)
+	}
+	err = set.Require(Serve)
+	if err == nil || !strings.Contains(err.Error(), "GO_TAG_UPDATER_FEATURE_SERVE=true") {
+		t.Errorf("Require(%s) error = %v, want the enable hint", Serve, err)
+	}
+}
+
+func TestSet_DeprecationWarnings(t *testing.T) {
+	set, err := Resolve(nil, envFunc(map[string]string{"GO_TAG_UPDATER_FEATURE_ROOT_UPDATE": "true"}))
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	log := logger.New(false)
+	log.SetOutput(&out)
+
+	set.WarnConfigured(log)
+	set.Use(log, RootUpdate)
+	set.Use(log, Serve)
+
+	if got := strings.Count(out.String(), "Deprecated:"); got != 1 {
+		t.Errorf("logged %d deprecation warnings, want 1:\n%s", got, out.String())
+	}
+	if !strings.Contains(out.String(), "v2.0.0") {
+		t.Errorf("warning does not name the removal release:\n%s", out.String())
+	}
+}
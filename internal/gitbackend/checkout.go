@@ -0,0 +1,134 @@
+package gitbackend
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Checkout is a shallow clone of one ref of the remote. Files are read and written
+// as raw bytes, so their encoding and line endings survive the round trip, and their
+// size is not bound by the limits of the GitLab files API.
+type Checkout struct {
+	backend *Backend
+	dir     string
+	ref     string
+}
+
+// Clone fetches ref, a branch, tag or commit, into a fresh temporary directory with
+// a history depth of one. The caller must Close the checkout.
+func (b *Backend) Clone(ctx context.Context, ref string) (*Checkout, error) {
+	if ref == "" {
+		return nil, errors.NewValidationError("ref to clone is required")
+	}
+
+	dir, err := os.MkdirTemp(b.opts.WorkDir, CloneDirPattern)
+	if err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to create clone directory: %v", err))
+	}
+	checkout := &Checkout{backend: b, dir: dir, ref: ref}
+
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "origin", b.opts.RemoteURL},
+		{"fetch", "--quiet", "--depth", "1", "origin", ref},
+		{"checkout", "--quiet", "--detach", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		if _, err := b.run(ctx, dir, args...); err != nil {
+			_ = checkout.Close()
+			return nil, err
+		}
+	}
+	return checkout, nil
+}
+
+// Ref returns the ref the checkout was cloned from
+func (c *Checkout) Ref() string {
+	return c.ref
+}
+
+// ReadFile returns the content of a file; a missing file is a file-not-found error
+func (c *Checkout) ReadFile(filePath string) ([]byte, error) {
+	fullPath, err := c.path(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(fullPath) // #nosec G304 -- path checked to stay within the clone
+	if stderrors.Is(err, fs.ErrNotExist) {
+		return nil, errors.NewFileNotFoundError(filePath)
+	}
+	if err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to read %s: %v", filePath, err))
+	}
+	return content, nil
+}
+
+// WriteFile replaces the content of a file, keeping its permissions, and stages it
+func (c *Checkout) WriteFile(ctx context.Context, filePath string, content []byte) error {
+	fullPath, err := c.path(filePath)
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(FilePermissions)
+	if info, statErr := os.Stat(fullPath); statErr == nil {
+		perm = info.Mode().Perm()
+	} else if err := os.MkdirAll(filepath.Dir(fullPath), DirPermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to create directory of %s: %v", filePath, err))
+	}
+
+	if err := os.WriteFile(fullPath, content, perm); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write %s: %v", filePath, err))
+	}
+	_, err = c.backend.run(ctx, c.dir, "add", "--", filePath)
+	return err
+}
+
+// Commit records the staged changes, signed when the backend has a signing key, and
+// returns the ID of the new commit
+func (c *Checkout) Commit(ctx context.Context, message string) (string, error) {
+	if _, err := c.backend.run(ctx, c.dir, c.backend.commitArgs(message)...); err != nil {
+		return "", err
+	}
+
+	commitID, err := c.backend.run(ctx, c.dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commitID), nil
+}
+
+// Push updates branch on the remote to the checked out commit, creating the branch
+// when it does not exist. Pushes that would drop commits of the branch are refused.
+func (c *Checkout) Push(ctx context.Context, branch string) error {
+	if branch == "" {
+		return errors.NewValidationError("branch to push is required")
+	}
+	_, err := c.backend.run(ctx, c.dir, "push", "--quiet", "origin", "HEAD:refs/heads/"+branch)
+	return err
+}
+
+// Close removes the clone from disk
+func (c *Checkout) Close() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to remove clone %s: %v", c.dir, err))
+	}
+	return nil
+}
+
+// path resolves a repository path inside the clone, refusing paths that leave it
+func (c *Checkout) path(filePath string) (string, error) {
+	local := filepath.FromSlash(filePath)
+	if filePath == "" || !filepath.IsLocal(local) {
+		return "", errors.NewValidationError(fmt.Sprintf("file path %q leaves the repository", filePath))
+	}
+	return filepath.Join(c.dir, local), nil
+}
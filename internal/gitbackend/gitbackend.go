@@ -0,0 +1,222 @@
+// Package gitbackend commits file updates through a local clone of the repository.
+//
+// It is an alternative to the GitLab files API: the ref is shallow-cloned with the
+// git binary, the file is read and written locally, and the commit is pushed back.
+// Files keep their exact bytes and are not bound by the API size limits, and the
+// commits can be GPG-signed with the configured key, which the API cannot do.
+package gitbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// GitBinary is the git executable looked up on PATH
+	GitBinary = "git"
+	// DefaultAuthorName is the author of commits when none is configured
+	DefaultAuthorName = "go-tag-updater"
+	// DefaultAuthorEmail is the author email of commits when none is configured
+	DefaultAuthorEmail = "go-tag-updater@localhost"
+	// DefaultUsername is the HTTPS user GitLab accepts with access and OAuth tokens
+	DefaultUsername = "oauth2"
+	// JobTokenUsername is the HTTPS user GitLab expects with CI/CD job tokens
+	JobTokenUsername = "gitlab-ci-token"
+	// CloneDirPattern names the temporary directory of each clone
+	CloneDirPattern = "go-tag-updater-clone-"
+	// FilePermissions defines permissions of files the clone did not hold yet
+	FilePermissions = 0o644
+	// DirPermissions defines permissions of directories created for new files
+	DirPermissions = 0o755
+	// redactedToken replaces the token in git output
+	redactedToken = "[REDACTED]"
+	// gitConfigCount is the variable counting the configuration entries passed to git
+	// in GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n>
+	gitConfigCount = "GIT_CONFIG_COUNT"
+)
+
+// Options configures the remote and the commits of a backend
+type Options struct {
+	// RemoteURL is the HTTPS clone URL of the project, or a local path
+	RemoteURL string
+	// Username and Token authenticate HTTPS requests; no credentials are sent
+	// when Token is empty
+	Username string
+	Token    string
+	// SigningKey is the GPG key ID commits are signed with; commits are unsigned when empty
+	SigningKey string
+	// AuthorName and AuthorEmail identify the commit author and committer
+	AuthorName  string
+	AuthorEmail string
+	// WorkDir holds the temporary clones; the system temporary directory when empty
+	WorkDir string
+	// CACertFile replaces the CA bundle git verifies the remote with; ClientCertFile and
+	// ClientKeyFile are presented to remotes requiring mutual TLS
+	CACertFile     string
+	ClientCertFile string
+	ClientKeyFile  string
+	// InsecureSkipTLSVerify disables certificate verification of the remote
+	InsecureSkipTLSVerify bool
+	// ProxyURL and NoProxy replace the proxy environment variables of git when set
+	ProxyURL string
+	NoProxy  string
+}
+
+// Backend commits files by cloning, committing and pushing with the git binary
+type Backend struct {
+	opts Options
+	git  string
+}
+
+// LookGit returns the path of the git binary on PATH, or a configuration error
+// explaining the backend needs it; callers check it before the first change
+func LookGit() (string, error) {
+	git, err := exec.LookPath(GitBinary)
+	if err != nil {
+		return "", errors.NewConfigError(fmt.Sprintf(
+			"the git commit backend requires the %s binary on PATH (%v): install git, "+
+				"or use the GitLab API backend", GitBinary, err))
+	}
+	return git, nil
+}
+
+// New creates a backend; it fails when no git binary is installed
+func New(opts Options) (*Backend, error) {
+	if opts.RemoteURL == "" {
+		return nil, errors.NewConfigError("git backend requires a remote URL")
+	}
+	git, err := LookGit()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Username == "" {
+		opts.Username = DefaultUsername
+	}
+	if opts.AuthorName == "" {
+		opts.AuthorName = DefaultAuthorName
+	}
+	if opts.AuthorEmail == "" {
+		opts.AuthorEmail = DefaultAuthorEmail
+	}
+
+	return &Backend{opts: opts, git: git}, nil
+}
+
+// CommitFile writes content to filePath on branch in a fresh shallow clone, commits
+// it, signed when a signing key is configured, and pushes the commit. It returns the
+// ID of the pushed commit.
+func (b *Backend) CommitFile(ctx context.Context, branch, filePath, content, message string) (string, error) {
+	if branch == "" {
+		return "", errors.NewValidationError("branch is required")
+	}
+
+	checkout, err := b.Clone(ctx, branch)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = checkout.Close() }()
+
+	if err := checkout.WriteFile(ctx, filePath, []byte(content)); err != nil {
+		return "", err
+	}
+	commitID, err := checkout.Commit(ctx, message)
+	if err != nil {
+		return "", err
+	}
+	if err := checkout.Push(ctx, branch); err != nil {
+		return "", err
+	}
+	return commitID, nil
+}
+
+// commitArgs returns the arguments of the commit, signing it with the configured key
+func (b *Backend) commitArgs(message string) []string {
+	args := []string{"commit", "--quiet", "--message", message}
+	if b.opts.SigningKey != "" {
+		args = append(args, "--gpg-sign="+b.opts.SigningKey)
+	} else {
+		args = append(args, "--no-gpg-sign")
+	}
+	return args
+}
+
+// networkEnv returns the environment variables applying the TLS and proxy options to git.
+// curl, which git uses for HTTPS, only reads the lower-case http_proxy.
+func (b *Backend) networkEnv() []string {
+	var env []string
+	if b.opts.CACertFile != "" {
+		env = append(env, "GIT_SSL_CAINFO="+b.opts.CACertFile)
+	}
+	if b.opts.ClientCertFile != "" {
+		env = append(env, "GIT_SSL_CERT="+b.opts.ClientCertFile, "GIT_SSL_KEY="+b.opts.ClientKeyFile)
+	}
+	if b.opts.InsecureSkipTLSVerify {
+		env = append(env, "GIT_SSL_NO_VERIFY=true")
+	}
+	if b.opts.ProxyURL != "" {
+		env = append(env, "http_proxy="+b.opts.ProxyURL, "https_proxy="+b.opts.ProxyURL,
+			"HTTPS_PROXY="+b.opts.ProxyURL)
+	}
+	if b.opts.NoProxy != "" {
+		env = append(env, "no_proxy="+b.opts.NoProxy, "NO_PROXY="+b.opts.NoProxy)
+	}
+	return env
+}
+
+// configEnv returns the environment variables adding one git configuration entry
+// after the count entries the environment already passes to git, which would be
+// dropped if the entry replaced them. An invalid count, which git rejects, is replaced.
+func configEnv(count, key, value string) []string {
+	index := 0
+	if count != "" {
+		if n, err := strconv.Atoi(count); err == nil && n > 0 {
+			index = n
+		}
+	}
+	return []string{
+		fmt.Sprintf("%s=%d", gitConfigCount, index+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", index, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", index, value),
+	}
+}
+
+// run executes git in dir. Credentials travel in the environment rather than the
+// remote URL or arguments, so they are neither stored in the clone nor visible in
+// the process list.
+func (b *Backend) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, b.git, args...) // #nosec G204 -- fixed git binary, arguments built here
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_AUTHOR_NAME="+b.opts.AuthorName,
+		"GIT_AUTHOR_EMAIL="+b.opts.AuthorEmail,
+		"GIT_COMMITTER_NAME="+b.opts.AuthorName,
+		"GIT_COMMITTER_EMAIL="+b.opts.AuthorEmail)
+	cmd.Env = append(cmd.Env, b.networkEnv()...)
+	if b.opts.Token != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(b.opts.Username + ":" + b.opts.Token))
+		cmd.Env = append(cmd.Env, configEnv(os.Getenv(gitConfigCount), "http.extraHeader",
+			"Authorization: Basic "+credentials)...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stderr.String())
+		if b.opts.Token != "" {
+			output = strings.ReplaceAll(output, b.opts.Token, redactedToken)
+		}
+		return "", errors.NewGitOperationError(fmt.Sprintf("git %s failed: %v: %s", args[0], err, output))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,233 @@
+package gitbackend
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestBranch        = "update-tag/v1.2.3"
+	TestFilePath      = "deploy/values.yaml"
+	TestFileContent   = "image:\n  tag: v1.0.0\n"
+	TestUpdatedFile   = "image:\n  tag: v1.2.3\n"
+	TestCommitMessage = "Update image tag to v1.2.3"
+	TestSigningUser   = "go-tag-updater test <signing@example.com>"
+)
+
+// git runs a git command for test setup and returns its trimmed output
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command(GitBinary, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// newRemote creates a bare repository whose branch holds the test file and returns its file URL
+func newRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath(GitBinary); err != nil {
+		t.Skip("git binary not installed")
+	}
+
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	git(t, root, "init", "--quiet", "--bare", remote)
+	git(t, root, "init", "--quiet", work)
+
+	if err := os.MkdirAll(filepath.Join(work, "deploy"), DirPermissions); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(work, TestFilePath), []byte(TestFileContent), FilePermissions); err != nil {
+		t.Fatal(err)
+	}
+	git(t, work, "add", ".")
+	git(t, work, "commit", "--quiet", "--no-gpg-sign", "--message", "Initial commit")
+	git(t, work, "push", "--quiet", remote, "HEAD:refs/heads/"+TestBranch)
+
+	return "file://" + remote
+}
+
+// remoteFile reads a file of the remote branch
+func remoteFile(t *testing.T, remoteURL, filePath string) string {
+	t.Helper()
+	return git(t, strings.TrimPrefix(remoteURL, "file://"), "show", TestBranch+":"+filePath)
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("New() should require a remote URL")
+	}
+}
+
+func TestBackend_CommitFile(t *testing.T) {
+	remoteURL := newRemote(t)
+	backend, err := New(Options{RemoteURL: remoteURL, WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	commitID, err := backend.CommitFile(ctx, TestBranch, TestFilePath, TestUpdatedFile, TestCommitMessage)
+	if err != nil {
+		t.Fatalf("CommitFile() unexpected error: %v", err)
+	}
+
+	bare := strings.TrimPrefix(remoteURL, "file://")
+	if head := git(t, bare, "rev-parse", TestBranch); head != commitID {
+		t.Errorf("CommitFile() = %s, remote branch at %s", commitID, head)
+	}
+	if got := remoteFile(t, remoteURL, TestFilePath); got+"\n" != TestUpdatedFile {
+		t.Errorf("remote file = %q, want %q", got, TestUpdatedFile)
+	}
+	if got := git(t, bare, "log", "-1", "--format=%an <%ae>|%s", TestBranch); got !=
+		DefaultAuthorName+" <"+DefaultAuthorEmail+">|"+TestCommitMessage {
+		t.Errorf("commit = %q", got)
+	}
+
+	if _, err := backend.CommitFile(ctx, TestBranch, "../outside.yaml", TestUpdatedFile, TestCommitMessage); err == nil {
+		t.Error("CommitFile() should reject paths leaving the repository")
+	}
+	if _, err := backend.CommitFile(ctx, "missing", TestFilePath, TestUpdatedFile, TestCommitMessage); err == nil {
+		t.Error("CommitFile() should fail for a missing branch")
+	}
+}
+
+func TestCheckout_RawContent(t *testing.T) {
+	remoteURL := newRemote(t)
+	backend, err := New(Options{RemoteURL: remoteURL, WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	checkout, err := backend.Clone(ctx, TestBranch)
+	if err != nil {
+		t.Fatalf("Clone() unexpected error: %v", err)
+	}
+	defer func() { _ = checkout.Close() }()
+
+	content, err := checkout.ReadFile(TestFilePath)
+	if err != nil || string(content) != TestFileContent {
+		t.Fatalf("ReadFile() = %q, %v", content, err)
+	}
+	if _, err := checkout.ReadFile("missing.yaml"); errors.GetErrorCode(err) != errors.ErrCodeFileNotFound {
+		t.Errorf("ReadFile() of a missing file = %v, want file not found", err)
+	}
+
+	// Latin-1 text with CRLF line endings must reach the remote byte for byte
+	raw := []byte("# Gr\xfc\xdfe\r\nimage:\r\n  tag: v1.2.3\r\n")
+	if err := checkout.WriteFile(ctx, TestFilePath, raw); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if _, err := checkout.Commit(ctx, TestCommitMessage); err != nil {
+		t.Fatalf("Commit() unexpected error: %v", err)
+	}
+	const newBranch = "update-tag/raw"
+	if err := checkout.Push(ctx, newBranch); err != nil {
+		t.Fatalf("Push() unexpected error: %v", err)
+	}
+
+	pushed, err := backend.Clone(ctx, newBranch)
+	if err != nil {
+		t.Fatalf("Clone() of the pushed branch unexpected error: %v", err)
+	}
+	defer func() { _ = pushed.Close() }()
+	if content, err := pushed.ReadFile(TestFilePath); err != nil || !bytes.Equal(content, raw) {
+		t.Errorf("pushed file = %q, %v; want %q", content, err, raw)
+	}
+}
+
+func TestBackend_CommitFileSigned(t *testing.T) {
+	remoteURL := newRemote(t)
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	// GnuPG agent sockets need a shorter path than t.TempDir may give
+	gnupgHome, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GNUPGHOME", gnupgHome)
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		_ = os.RemoveAll(gnupgHome)
+	})
+
+	keygen := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", TestSigningUser,
+		"ed25519", "sign", "never")
+	if output, err := keygen.CombinedOutput(); err != nil {
+		t.Skipf("cannot generate a GPG key: %v: %s", err, output)
+	}
+
+	backend, err := New(Options{RemoteURL: remoteURL, SigningKey: TestSigningUser, WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := backend.CommitFile(context.Background(), TestBranch, TestFilePath, TestUpdatedFile,
+		TestCommitMessage); err != nil {
+		t.Fatalf("CommitFile() unexpected error: %v", err)
+	}
+
+	bare := strings.TrimPrefix(remoteURL, "file://")
+	if status := git(t, bare, "log", "-1", "--format=%G?", TestBranch); status != "G" {
+		t.Errorf("signature status = %q, want G (good signature)", status)
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		count string
+		want  []string
+	}{
+		{
+			name: "no entries",
+			want: []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=value"},
+		},
+		{
+			name:  "entries of the environment kept",
+			count: "2",
+			want:  []string{"GIT_CONFIG_COUNT=3", "GIT_CONFIG_KEY_2=http.extraHeader", "GIT_CONFIG_VALUE_2=value"},
+		},
+		{
+			name:  "invalid count",
+			count: "many",
+			want:  []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configEnv(tt.count, "http.extraHeader", "value"); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("configEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookGit_Missing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, err := LookGit(); errors.GetErrorCode(err) != errors.ErrCodeConfiguration {
+		t.Errorf("LookGit() error = %v, want a configuration error", err)
+	}
+	if _, err := New(Options{RemoteURL: "https://gitlab.example.com/group/project.git"}); err == nil {
+		t.Error("New() expected an error without the git binary")
+	}
+}
@@ -0,0 +1,597 @@
+// Package gitlab provides utilities for GitLab API operations
+package gitlab
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// FileAPI is the subset of the GitLab API used for repository file operations
+type FileAPI interface {
+	GetFile(
+		pid interface{},
+		fileName string,
+		opt *gitlab.GetFileOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.File, *gitlab.Response, error)
+	CreateFile(
+		pid interface{},
+		fileName string,
+		opt *gitlab.CreateFileOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.FileInfo, *gitlab.Response, error)
+	UpdateFile(
+		pid interface{},
+		fileName string,
+		opt *gitlab.UpdateFileOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.FileInfo, *gitlab.Response, error)
+	DeleteFile(
+		pid interface{},
+		fileName string,
+		opt *gitlab.DeleteFileOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Response, error)
+	ListCommits(
+		pid interface{},
+		opt *gitlab.ListCommitsOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.Commit, *gitlab.Response, error)
+	GetCommitDiff(
+		pid interface{},
+		sha string,
+		opt *gitlab.GetCommitDiffOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.Diff, *gitlab.Response, error)
+	Compare(
+		pid interface{},
+		opt *gitlab.CompareOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Compare, *gitlab.Response, error)
+	CreateCommit(
+		pid interface{},
+		opt *gitlab.CreateCommitOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Commit, *gitlab.Response, error)
+	StreamRawFile(
+		ctx context.Context,
+		pid interface{},
+		fileName string,
+		opt *gitlab.GetRawFileOptions,
+		w io.Writer,
+	) (*gitlab.Response, error)
+	ListTree(
+		pid interface{},
+		opt *gitlab.ListTreeOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.TreeNode, *gitlab.Response, error)
+}
+
+// BranchAPI is the subset of the GitLab API used for branch operations
+type BranchAPI interface {
+	CreateBranch(
+		pid interface{},
+		opt *gitlab.CreateBranchOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Branch, *gitlab.Response, error)
+	GetBranch(
+		pid interface{},
+		branch string,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Branch, *gitlab.Response, error)
+	ListBranches(
+		pid interface{},
+		opt *gitlab.ListBranchesOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.Branch, *gitlab.Response, error)
+	DeleteBranch(pid interface{}, branch string, options ...gitlab.RequestOptionFunc) (*gitlab.Response, error)
+	ListProtectedBranches(
+		pid interface{},
+		opt *gitlab.ListProtectedBranchesOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.ProtectedBranch, *gitlab.Response, error)
+}
+
+// MergeRequestAPI is the subset of the GitLab API used for merge request operations
+type MergeRequestAPI interface {
+	CreateMergeRequest(
+		pid interface{},
+		opt *gitlab.CreateMergeRequestOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.MergeRequest, *gitlab.Response, error)
+	GetMergeRequest(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.GetMergeRequestsOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.MergeRequest, *gitlab.Response, error)
+	ListProjectMergeRequests(
+		pid interface{},
+		opt *gitlab.ListProjectMergeRequestsOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.BasicMergeRequest, *gitlab.Response, error)
+	UpdateMergeRequest(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.UpdateMergeRequestOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.MergeRequest, *gitlab.Response, error)
+	AcceptMergeRequest(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.AcceptMergeRequestOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.MergeRequest, *gitlab.Response, error)
+	CreateMergeRequestNote(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.CreateMergeRequestNoteOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Note, *gitlab.Response, error)
+	RebaseMergeRequest(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.RebaseMergeRequestOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Response, error)
+	ListMergeRequestDiffs(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.ListMergeRequestDiffsOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.MergeRequestDiff, *gitlab.Response, error)
+}
+
+// ProjectAPI is the subset of the GitLab API used for project operations
+type ProjectAPI interface {
+	GetProject(
+		pid interface{},
+		opt *gitlab.GetProjectOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Project, *gitlab.Response, error)
+	ListProjects(
+		opt *gitlab.ListProjectsOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.Project, *gitlab.Response, error)
+	GetProjectPushRules(
+		pid interface{},
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.ProjectPushRules, *gitlab.Response, error)
+}
+
+// TokenAPI is the subset of the GitLab API used to inspect the token and its user
+type TokenAPI interface {
+	GetSinglePersonalAccessToken(
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.PersonalAccessToken, *gitlab.Response, error)
+	CurrentUser(options ...gitlab.RequestOptionFunc) (*gitlab.User, *gitlab.Response, error)
+	GetInheritedProjectMember(
+		pid interface{},
+		user int,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.ProjectMember, *gitlab.Response, error)
+}
+
+// JobAPI is the subset of the GitLab API used to inspect pipeline jobs
+type JobAPI interface {
+	ListPipelineJobs(
+		pid interface{},
+		pipelineID int,
+		opts *gitlab.ListJobsOptions,
+		options ...gitlab.RequestOptionFunc,
+	) ([]*gitlab.Job, *gitlab.Response, error)
+}
+
+// ReleaseAPI is the subset of the GitLab API used to read release notes
+type ReleaseAPI interface {
+	GetRelease(
+		pid interface{},
+		tagName string,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Release, *gitlab.Response, error)
+}
+
+// IssueAPI is the subset of the GitLab API used to comment on issues
+type IssueAPI interface {
+	CreateIssueNote(
+		pid interface{},
+		issue int,
+		opt *gitlab.CreateIssueNoteOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.Note, *gitlab.Response, error)
+}
+
+// ApprovalAPI is the subset of the GitLab API used to read and give merge request approvals
+type ApprovalAPI interface {
+	GetConfiguration(
+		pid interface{},
+		mergeRequest int,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.MergeRequestApprovals, *gitlab.Response, error)
+	ApproveMergeRequest(
+		pid interface{},
+		mergeRequest int,
+		opt *gitlab.ApproveMergeRequestOptions,
+		options ...gitlab.RequestOptionFunc,
+	) (*gitlab.MergeRequestApprovals, *gitlab.Response, error)
+}
+
+// PipelineAPI is the subset of the GitLab API used to watch merge request pipelines
+type PipelineAPI interface {
+	MergeRequestAPI
+	JobAPI
+}
+
+// API combines every GitLab API subset used by the managers. Like the client-go services
+// each method accepts request options; managers pass gitlab.WithContext so that
+// cancellation and deadlines abort in-flight requests.
+type API interface {
+	FileAPI
+	BranchAPI
+	MergeRequestAPI
+	ProjectAPI
+	TokenAPI
+	JobAPI
+	ReleaseAPI
+	IssueAPI
+	ApprovalAPI
+}
+
+// APIAdapter implements API on top of the official GitLab client
+type APIAdapter struct {
+	client *gitlab.Client
+}
+
+// Compile-time check that the adapter implements every API subset
+var _ API = (*APIAdapter)(nil)
+
+// NewAPIAdapter wraps a GitLab client in the API interfaces
+func NewAPIAdapter(client *gitlab.Client) *APIAdapter {
+	return &APIAdapter{client: client}
+}
+
+// Client returns the wrapped GitLab client
+func (a *APIAdapter) Client() *gitlab.Client {
+	return a.client
+}
+
+// GetFile retrieves a repository file
+func (a *APIAdapter) GetFile(
+	pid interface{},
+	fileName string,
+	opt *gitlab.GetFileOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.File, *gitlab.Response, error) {
+	return a.client.RepositoryFiles.GetFile(pid, fileName, opt, options...)
+}
+
+// CreateFile creates a repository file
+func (a *APIAdapter) CreateFile(
+	pid interface{},
+	fileName string,
+	opt *gitlab.CreateFileOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.FileInfo, *gitlab.Response, error) {
+	return a.client.RepositoryFiles.CreateFile(pid, fileName, opt, options...)
+}
+
+// UpdateFile updates a repository file
+func (a *APIAdapter) UpdateFile(
+	pid interface{},
+	fileName string,
+	opt *gitlab.UpdateFileOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.FileInfo, *gitlab.Response, error) {
+	return a.client.RepositoryFiles.UpdateFile(pid, fileName, opt, options...)
+}
+
+// StreamRawFile copies the raw content of a repository file to w as it arrives,
+// without holding it in memory; cancelling ctx aborts the transfer
+func (a *APIAdapter) StreamRawFile(
+	ctx context.Context,
+	pid interface{},
+	fileName string,
+	opt *gitlab.GetRawFileOptions,
+	w io.Writer,
+) (*gitlab.Response, error) {
+	u := fmt.Sprintf("projects/%s/repository/files/%s/raw",
+		gitlab.PathEscape(fmt.Sprint(pid)), gitlab.PathEscape(fileName))
+	req, err := a.client.NewRequest(http.MethodGet, u, opt, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	return a.client.Do(req, w)
+}
+
+// ListTree lists the files and directories of a repository
+func (a *APIAdapter) ListTree(
+	pid interface{},
+	opt *gitlab.ListTreeOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.TreeNode, *gitlab.Response, error) {
+	return a.client.Repositories.ListTree(pid, opt, options...)
+}
+
+// DeleteFile deletes a repository file
+func (a *APIAdapter) DeleteFile(
+	pid interface{},
+	fileName string,
+	opt *gitlab.DeleteFileOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Response, error) {
+	return a.client.RepositoryFiles.DeleteFile(pid, fileName, opt, options...)
+}
+
+// ListCommits lists repository commits
+func (a *APIAdapter) ListCommits(
+	pid interface{},
+	opt *gitlab.ListCommitsOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.Commit, *gitlab.Response, error) {
+	return a.client.Commits.ListCommits(pid, opt, options...)
+}
+
+// GetCommitDiff lists the file changes of a commit
+func (a *APIAdapter) GetCommitDiff(
+	pid interface{},
+	sha string,
+	opt *gitlab.GetCommitDiffOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.Diff, *gitlab.Response, error) {
+	return a.client.Commits.GetCommitDiff(pid, sha, opt, options...)
+}
+
+// Compare compares two branches, tags or commits
+func (a *APIAdapter) Compare(
+	pid interface{},
+	opt *gitlab.CompareOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Compare, *gitlab.Response, error) {
+	return a.client.Repositories.Compare(pid, opt, options...)
+}
+
+// CreateCommit creates a commit with multiple file actions
+func (a *APIAdapter) CreateCommit(
+	pid interface{},
+	opt *gitlab.CreateCommitOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Commit, *gitlab.Response, error) {
+	return a.client.Commits.CreateCommit(pid, opt, options...)
+}
+
+// CreateBranch creates a branch
+func (a *APIAdapter) CreateBranch(
+	pid interface{},
+	opt *gitlab.CreateBranchOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Branch, *gitlab.Response, error) {
+	return a.client.Branches.CreateBranch(pid, opt, options...)
+}
+
+// GetBranch retrieves a branch
+func (a *APIAdapter) GetBranch(
+	pid interface{},
+	branch string,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Branch, *gitlab.Response, error) {
+	return a.client.Branches.GetBranch(pid, branch, options...)
+}
+
+// ListBranches lists branches
+func (a *APIAdapter) ListBranches(
+	pid interface{},
+	opt *gitlab.ListBranchesOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.Branch, *gitlab.Response, error) {
+	return a.client.Branches.ListBranches(pid, opt, options...)
+}
+
+// DeleteBranch deletes a branch
+func (a *APIAdapter) DeleteBranch(
+	pid interface{},
+	branch string,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Response, error) {
+	return a.client.Branches.DeleteBranch(pid, branch, options...)
+}
+
+// ListProtectedBranches lists protected branches
+func (a *APIAdapter) ListProtectedBranches(
+	pid interface{},
+	opt *gitlab.ListProtectedBranchesOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.ProtectedBranch, *gitlab.Response, error) {
+	return a.client.ProtectedBranches.ListProtectedBranches(pid, opt, options...)
+}
+
+// CreateMergeRequest creates a merge request
+func (a *APIAdapter) CreateMergeRequest(
+	pid interface{},
+	opt *gitlab.CreateMergeRequestOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	return a.client.MergeRequests.CreateMergeRequest(pid, opt, options...)
+}
+
+// GetMergeRequest retrieves a merge request
+func (a *APIAdapter) GetMergeRequest(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.GetMergeRequestsOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	return a.client.MergeRequests.GetMergeRequest(pid, mergeRequest, opt, options...)
+}
+
+// ListProjectMergeRequests lists the merge requests of a project
+func (a *APIAdapter) ListProjectMergeRequests(
+	pid interface{},
+	opt *gitlab.ListProjectMergeRequestsOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.BasicMergeRequest, *gitlab.Response, error) {
+	return a.client.MergeRequests.ListProjectMergeRequests(pid, opt, options...)
+}
+
+// UpdateMergeRequest updates a merge request
+func (a *APIAdapter) UpdateMergeRequest(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.UpdateMergeRequestOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	return a.client.MergeRequests.UpdateMergeRequest(pid, mergeRequest, opt, options...)
+}
+
+// AcceptMergeRequest merges a merge request or schedules its merge
+func (a *APIAdapter) AcceptMergeRequest(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.AcceptMergeRequestOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.MergeRequest, *gitlab.Response, error) {
+	return a.client.MergeRequests.AcceptMergeRequest(pid, mergeRequest, opt, options...)
+}
+
+// CreateMergeRequestNote comments on a merge request
+func (a *APIAdapter) CreateMergeRequestNote(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.CreateMergeRequestNoteOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Note, *gitlab.Response, error) {
+	return a.client.Notes.CreateMergeRequestNote(pid, mergeRequest, opt, options...)
+}
+
+// RebaseMergeRequest asks GitLab to rebase the source branch of a merge request
+func (a *APIAdapter) RebaseMergeRequest(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.RebaseMergeRequestOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Response, error) {
+	return a.client.MergeRequests.RebaseMergeRequest(pid, mergeRequest, opt, options...)
+}
+
+// ListMergeRequestDiffs lists the files changed by a merge request, one page at a time
+func (a *APIAdapter) ListMergeRequestDiffs(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.ListMergeRequestDiffsOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.MergeRequestDiff, *gitlab.Response, error) {
+	return a.client.MergeRequests.ListMergeRequestDiffs(pid, mergeRequest, opt, options...)
+}
+
+// GetProject retrieves a project
+func (a *APIAdapter) GetProject(
+	pid interface{},
+	opt *gitlab.GetProjectOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Project, *gitlab.Response, error) {
+	return a.client.Projects.GetProject(pid, opt, options...)
+}
+
+// ListProjects lists projects
+func (a *APIAdapter) ListProjects(
+	opt *gitlab.ListProjectsOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.Project, *gitlab.Response, error) {
+	return a.client.Projects.ListProjects(opt, options...)
+}
+
+// GetProjectPushRules retrieves the push rules of a project
+func (a *APIAdapter) GetProjectPushRules(
+	pid interface{},
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.ProjectPushRules, *gitlab.Response, error) {
+	return a.client.Projects.GetProjectPushRules(pid, options...)
+}
+
+// GetSinglePersonalAccessToken retrieves the token the client authenticates with
+func (a *APIAdapter) GetSinglePersonalAccessToken(
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.PersonalAccessToken, *gitlab.Response, error) {
+	return a.client.PersonalAccessTokens.GetSinglePersonalAccessToken(options...)
+}
+
+// CurrentUser retrieves the user the token belongs to
+func (a *APIAdapter) CurrentUser(options ...gitlab.RequestOptionFunc) (*gitlab.User, *gitlab.Response, error) {
+	return a.client.Users.CurrentUser(options...)
+}
+
+// GetInheritedProjectMember retrieves a member of a project, including members
+// inherited from its groups
+func (a *APIAdapter) GetInheritedProjectMember(
+	pid interface{},
+	user int,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.ProjectMember, *gitlab.Response, error) {
+	return a.client.ProjectMembers.GetInheritedProjectMember(pid, user, options...)
+}
+
+// ListPipelineJobs lists the jobs of a pipeline
+func (a *APIAdapter) ListPipelineJobs(
+	pid interface{},
+	pipelineID int,
+	opts *gitlab.ListJobsOptions,
+	options ...gitlab.RequestOptionFunc,
+) ([]*gitlab.Job, *gitlab.Response, error) {
+	return a.client.Jobs.ListPipelineJobs(pid, pipelineID, opts, options...)
+}
+
+// GetRelease retrieves the release of a tag
+func (a *APIAdapter) GetRelease(
+	pid interface{},
+	tagName string,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Release, *gitlab.Response, error) {
+	return a.client.Releases.GetRelease(pid, tagName, options...)
+}
+
+// CreateIssueNote comments on an issue
+func (a *APIAdapter) CreateIssueNote(
+	pid interface{},
+	issue int,
+	opt *gitlab.CreateIssueNoteOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.Note, *gitlab.Response, error) {
+	return a.client.Notes.CreateIssueNote(pid, issue, opt, options...)
+}
+
+// GetConfiguration retrieves the approval state of a merge request
+func (a *APIAdapter) GetConfiguration(
+	pid interface{},
+	mergeRequest int,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.MergeRequestApprovals, *gitlab.Response, error) {
+	return a.client.MergeRequestApprovals.GetConfiguration(pid, mergeRequest, options...)
+}
+
+// ApproveMergeRequest approves a merge request as the token user
+func (a *APIAdapter) ApproveMergeRequest(
+	pid interface{},
+	mergeRequest int,
+	opt *gitlab.ApproveMergeRequestOptions,
+	options ...gitlab.RequestOptionFunc,
+) (*gitlab.MergeRequestApprovals, *gitlab.Response, error) {
+	return a.client.MergeRequestApprovals.ApproveMergeRequest(pid, mergeRequest, opt, options...)
+}
+
+// ResponseStatus returns the HTTP status GitLab answered a failed call with, or 0 when
+// err carries no API response, like a network error. The client reports 404 Not
+// Found as gitlab.ErrNotFound instead of an error response, so it maps back to it.
+func ResponseStatus(err error) int {
+	if stderrors.Is(err, gitlab.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	var response *gitlab.ErrorResponse
+	if stderrors.As(err, &response) && response.Response != nil {
+		return response.Response.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// ApprovalManager reads the approval rules of merge requests and approves them with
+// the token of a second, approving user
+type ApprovalManager struct {
+	api       ApprovalAPI
+	approver  ApprovalAPI
+	projectID interface{}
+}
+
+// ApprovalStatus summarizes the approvals of a merge request
+type ApprovalStatus struct {
+	// Required is the number of approvals the rules of the merge request require
+	Required int
+	// Left is the number of approvals still missing
+	Left int
+	// Approved reports whether every approval rule is satisfied
+	Approved bool
+	// RulesLeft names the approval rules still missing approvals
+	RulesLeft []string
+	// ApprovedBy lists the usernames that approved the merge request
+	ApprovedBy []string
+}
+
+// NewApprovalManager creates a new approval manager
+func NewApprovalManager(client *gitlab.Client, projectID interface{}) *ApprovalManager {
+	return NewApprovalManagerWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewApprovalManagerWithAPI creates a new approval manager on top of the given API implementation
+func NewApprovalManagerWithAPI(api ApprovalAPI, projectID interface{}) *ApprovalManager {
+	return &ApprovalManager{
+		api:       api,
+		projectID: projectID,
+	}
+}
+
+// SetApprover sets the API authenticated as the user approving merge requests. GitLab
+// usually forbids authors to approve their own merge requests, so it should use
+// another token than the one creating them.
+func (am *ApprovalManager) SetApprover(approver ApprovalAPI) {
+	am.approver = approver
+}
+
+// Status returns the approvals of a merge request
+func (am *ApprovalManager) Status(ctx context.Context, mrIID int) (*ApprovalStatus, error) {
+	approvals, _, err := am.api.GetConfiguration(am.projectID, mrIID, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to get approvals of merge request !%d: %v", mrIID, err))
+	}
+	return newApprovalStatus(approvals), nil
+}
+
+// Approve approves a merge request as the approver and returns its approvals
+func (am *ApprovalManager) Approve(ctx context.Context, mrIID int) (*ApprovalStatus, error) {
+	if am.approver == nil {
+		return nil, errors.NewValidationError("no approver token configured")
+	}
+	approvals, _, err := am.approver.ApproveMergeRequest(am.projectID, mrIID, &gitlab.ApproveMergeRequestOptions{},
+		gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to approve merge request !%d: %v", mrIID, err))
+	}
+	return newApprovalStatus(approvals), nil
+}
+
+// newApprovalStatus summarizes the approvals returned by GitLab
+func newApprovalStatus(approvals *gitlab.MergeRequestApprovals) *ApprovalStatus {
+	status := &ApprovalStatus{
+		Required: approvals.ApprovalsRequired,
+		Left:     approvals.ApprovalsLeft,
+		Approved: approvals.Approved,
+	}
+	for _, rule := range approvals.ApprovalRulesLeft {
+		status.RulesLeft = append(status.RulesLeft, rule.Name)
+	}
+	for _, approver := range approvals.ApprovedBy {
+		if approver != nil && approver.User != nil {
+			status.ApprovedBy = append(status.ApprovedBy, approver.User.Username)
+		}
+	}
+	return status
+}
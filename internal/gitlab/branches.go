@@ -4,11 +4,12 @@ package gitlab
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
-	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
+	"github.com/Gosayram/go-tag-updater/internal/clock"
 	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
@@ -20,11 +21,14 @@ const (
 	// Branch name constraints
 	MaxBranchNameLength = 100
 	MinBranchNameLength = 1
+
+	// MaxBranchCreateAttempts bounds how many alternative names are tried on collision
+	MaxBranchCreateAttempts = 3
 )
 
 // BranchManager handles GitLab branch operations
 type BranchManager struct {
-	client    *gitlab.Client
+	api       BranchAPI
 	projectID interface{}
 }
 
@@ -41,8 +45,13 @@ type BranchInfo struct {
 
 // NewBranchManager creates a new branch manager
 func NewBranchManager(client *gitlab.Client, projectID interface{}) *BranchManager {
+	return NewBranchManagerWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewBranchManagerWithAPI creates a new branch manager on top of the given API implementation
+func NewBranchManagerWithAPI(api BranchAPI, projectID interface{}) *BranchManager {
 	return &BranchManager{
-		client:    client,
+		api:       api,
 		projectID: projectID,
 	}
 }
@@ -71,9 +80,10 @@ func (bm *BranchManager) CreateBranch(ctx context.Context, branchName, ref strin
 		Ref:    gitlab.Ptr(ref),
 	}
 
-	branch, _, err := bm.client.Branches.CreateBranch(bm.projectID, opts)
+	branch, _, err := bm.api.CreateBranch(bm.projectID, opts, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, errors.NewAPIError(fmt.Sprintf("failed to create branch %s: %v", branchName, err))
+		return nil, errors.NewAppErrorWithCause(errors.ErrCodeAPIError, errors.CategoryAPI,
+			fmt.Sprintf("failed to create branch %s: %v", branchName, err), err)
 	}
 
 	return bm.convertToBranchInfo(branch), nil
@@ -85,7 +95,7 @@ func (bm *BranchManager) GetBranch(ctx context.Context, branchName string) (*Bra
 		return nil, errors.NewValidationError("branch name cannot be empty")
 	}
 
-	branch, _, err := bm.client.Branches.GetBranch(bm.projectID, branchName)
+	branch, _, err := bm.api.GetBranch(bm.projectID, branchName, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to get branch %s: %v", branchName, err))
 	}
@@ -99,18 +109,16 @@ func (bm *BranchManager) ListBranches(ctx context.Context, search string, maxRes
 		maxResults = 20
 	}
 
-	opts := &gitlab.ListBranchesOptions{
-		ListOptions: gitlab.ListOptions{
-			PerPage: maxResults,
-			Page:    1,
-		},
-	}
-
+	opts := &gitlab.ListBranchesOptions{}
 	if search != "" {
 		opts.Search = gitlab.Ptr(search)
 	}
 
-	branches, _, err := bm.client.Branches.ListBranches(bm.projectID, opts)
+	branches, err := collectPages(ctx, maxResults, maxResults,
+		func(page gitlab.ListOptions) ([]*gitlab.Branch, *gitlab.Response, error) {
+			opts.ListOptions = page
+			return bm.api.ListBranches(bm.projectID, opts, gitlab.WithContext(ctx))
+		})
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list branches: %v", err))
 	}
@@ -139,7 +147,7 @@ func (bm *BranchManager) DeleteBranch(ctx context.Context, branchName string) er
 		return errors.NewValidationError(fmt.Sprintf("cannot delete protected branch: %s", branchName))
 	}
 
-	_, err = bm.client.Branches.DeleteBranch(bm.projectID, branchName)
+	_, err = bm.api.DeleteBranch(bm.projectID, branchName, gitlab.WithContext(ctx))
 	if err != nil {
 		return errors.NewAPIError(fmt.Sprintf("failed to delete branch %s: %v", branchName, err))
 	}
@@ -162,7 +170,7 @@ func (bm *BranchManager) BranchExists(ctx context.Context, branchName string) (b
 
 // GetProtectedBranches lists protected branches
 func (bm *BranchManager) GetProtectedBranches(ctx context.Context) ([]*gitlab.ProtectedBranch, error) {
-	branches, _, err := bm.client.ProtectedBranches.ListProtectedBranches(bm.projectID, nil)
+	branches, _, err := bm.api.ListProtectedBranches(bm.projectID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list protected branches: %v", err))
 	}
@@ -176,10 +184,9 @@ func (bm *BranchManager) GenerateUniqueBranchName(ctx context.Context, prefix, t
 	}
 
 	// Clean the tag for use in branch name
-	cleanTag := strings.ReplaceAll(tag, "/", "-")
-	cleanTag = strings.ReplaceAll(cleanTag, ":", "-")
+	cleanTag := cleanTagForBranch(tag)
 
-	timestamp := time.Now().Format("20060102-150405")
+	timestamp := clock.Now().Format("20060102-150405")
 	baseName := fmt.Sprintf("%s%s-%s", prefix, cleanTag, timestamp)
 
 	// Ensure the name doesn't exceed maximum length
@@ -208,6 +215,49 @@ func (bm *BranchManager) GenerateUniqueBranchName(ctx context.Context, prefix, t
 	return baseName, nil
 }
 
+// GenerateIdempotentBranchName generates a deterministic branch name from a tag and idempotency key
+func (bm *BranchManager) GenerateIdempotentBranchName(prefix, tag, key string) string {
+	if prefix == "" {
+		prefix = UpdateBranchPrefix
+	}
+
+	cleanTag := cleanTagForBranch(tag)
+	baseName := fmt.Sprintf("%s%s-%s", prefix, cleanTag, key)
+
+	if len(baseName) > MaxBranchNameLength {
+		maxTagLength := MaxBranchNameLength - len(prefix) - len(key) - 1 // 1 for dash
+		if maxTagLength > 0 {
+			baseName = fmt.Sprintf("%s%s-%s", prefix, strings.TrimRight(cleanTag[:maxTagLength], "-."), key)
+		} else {
+			baseName = fmt.Sprintf("%s%s", prefix, key)
+		}
+	}
+
+	return baseName
+}
+
+// AlternativeBranchName returns the branch name to try on the given attempt;
+// attempt 0 is the base name itself, later attempts append a numeric suffix
+func (bm *BranchManager) AlternativeBranchName(baseName string, attempt int) string {
+	if attempt <= 0 {
+		return baseName
+	}
+
+	suffix := fmt.Sprintf("-%d", attempt+1)
+	if len(baseName)+len(suffix) > MaxBranchNameLength {
+		baseName = strings.TrimRight(baseName[:MaxBranchNameLength-len(suffix)], "-.")
+	}
+
+	return baseName + suffix
+}
+
+// IsBranchExistsError reports whether creating a branch was rejected with 400 Bad
+// Request, the status GitLab answers an existing branch name with. Invalid names and
+// refs share the status, so callers confirm the branch exists with GetBranch.
+func IsBranchExistsError(err error) bool {
+	return ResponseStatus(err) == http.StatusBadRequest
+}
+
 // FindBranchesByTag finds branches that might be related to a specific tag
 func (bm *BranchManager) FindBranchesByTag(ctx context.Context, tag string) ([]*BranchInfo, error) {
 	if tag == "" {
@@ -234,6 +284,12 @@ func (bm *BranchManager) convertToBranchInfo(branch *gitlab.Branch) *BranchInfo
 	}
 }
 
+// cleanTagForBranch replaces characters that are not allowed in branch names
+func cleanTagForBranch(tag string) string {
+	cleanTag := strings.ReplaceAll(tag, "/", "-")
+	return strings.ReplaceAll(cleanTag, ":", "-")
+}
+
 // validateBranchName validates branch name according to Git rules
 func validateBranchName(name string) error {
 	if name == "" {
@@ -2,11 +2,16 @@ package gitlab
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
 // Constants for testing
@@ -53,7 +58,7 @@ func TestNewBranchManager(t *testing.T) {
 		t.Fatal("NewBranchManager() should return non-nil manager")
 	}
 
-	if bm.client != client.GetGitLabClient() {
+	if adapter, ok := bm.api.(*APIAdapter); !ok || adapter.Client() != client.GetGitLabClient() {
 		t.Error("NewBranchManager() should set client correctly")
 	}
 
@@ -69,7 +74,7 @@ func TestNewBranchManager_NilClient(t *testing.T) {
 		t.Fatal("NewBranchManager() should return non-nil manager even with nil client")
 	}
 
-	if bm.client != nil {
+	if adapter, ok := bm.api.(*APIAdapter); !ok || adapter.Client() != nil {
 		t.Error("NewBranchManager() should accept nil client")
 	}
 
@@ -545,6 +550,59 @@ func TestBranchManager_GenerateUniqueBranchName(t *testing.T) {
 	}
 }
 
+func TestBranchManager_GenerateIdempotentBranchName(t *testing.T) {
+	bm := NewBranchManager(nil, TestProjectID)
+	key := "0123456789ab"
+
+	tests := []struct {
+		name     string
+		prefix   string
+		tag      string
+		expected string
+	}{
+		{
+			name:     "default prefix",
+			prefix:   "",
+			tag:      TestTag,
+			expected: UpdateBranchPrefix + TestTag + "-" + key,
+		},
+		{
+			name:     "tag with slash",
+			prefix:   TestUpdatePrefix,
+			tag:      TestTagWithSlash,
+			expected: TestUpdatePrefix + "releases-v1.2.3-" + key,
+		},
+		{
+			name:     "tag with colon",
+			prefix:   TestUpdatePrefix,
+			tag:      TestTagWithColon,
+			expected: TestUpdatePrefix + "namespace-v1.2.3-" + key,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branchName := bm.GenerateIdempotentBranchName(tt.prefix, tt.tag, key)
+			if branchName != tt.expected {
+				t.Errorf("GenerateIdempotentBranchName() = %q, want %q", branchName, tt.expected)
+			}
+			if err := validateBranchName(branchName); err != nil {
+				t.Errorf("GenerateIdempotentBranchName() produced invalid name: %v", err)
+			}
+		})
+	}
+
+	t.Run("long tag is truncated", func(t *testing.T) {
+		branchName := bm.GenerateIdempotentBranchName(TestUpdatePrefix, strings.Repeat("a", MaxBranchNameLength), key)
+		if len(branchName) > MaxBranchNameLength {
+			t.Errorf("GenerateIdempotentBranchName() length = %d, max %d", len(branchName), MaxBranchNameLength)
+		}
+		if !strings.HasSuffix(branchName, key) {
+			t.Errorf("GenerateIdempotentBranchName() = %q, should keep key suffix", branchName)
+		}
+	})
+}
+
 func TestBranchManager_ConvertToBranchInfo(t *testing.T) {
 	client, err := NewClient(TestGitLabToken, TestGitLabURL)
 	if err != nil {
@@ -696,3 +754,86 @@ func BenchmarkGenerateTimestamp(b *testing.B) {
 		_ = time.Now().Format("20060102-150405")
 	}
 }
+
+func TestBranchManager_AlternativeBranchName(t *testing.T) {
+	bm := NewBranchManager(nil, TestProjectID)
+	base := TestUpdatePrefix + TestTag
+
+	tests := []struct {
+		name     string
+		baseName string
+		attempt  int
+		expected string
+	}{
+		{name: "first attempt keeps base name", baseName: base, attempt: 0, expected: base},
+		{name: "second attempt", baseName: base, attempt: 1, expected: base + "-2"},
+		{name: "third attempt", baseName: base, attempt: 2, expected: base + "-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bm.AlternativeBranchName(tt.baseName, tt.attempt); got != tt.expected {
+				t.Errorf("AlternativeBranchName() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("long name is truncated", func(t *testing.T) {
+		branchName := bm.AlternativeBranchName(strings.Repeat("a", MaxBranchNameLength), 1)
+		if len(branchName) > MaxBranchNameLength {
+			t.Errorf("AlternativeBranchName() length = %d, max %d", len(branchName), MaxBranchNameLength)
+		}
+		if !strings.HasSuffix(branchName, "-2") {
+			t.Errorf("AlternativeBranchName() = %q, want suffix -2", branchName)
+		}
+	})
+}
+
+func TestIsBranchExistsError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil error", err: nil, expected: false},
+		{name: "bad request", err: errors.NewAppErrorWithCause(errors.ErrCodeAPIError, errors.CategoryAPI,
+			"failed to create branch", apiErrorResponse(http.StatusBadRequest)), expected: true},
+		{name: "forbidden", err: apiErrorResponse(http.StatusForbidden), expected: false},
+		{name: "message without response", err: fmt.Errorf("400 Bad Request: Branch already exists"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBranchExistsError(tt.err); got != tt.expected {
+				t.Errorf("IsBranchExistsError() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "nil error", err: nil, expected: 0},
+		{name: "error response", err: apiErrorResponse(http.StatusForbidden), expected: http.StatusForbidden},
+		{name: "wrapped not found", err: errors.NewAppErrorWithCause(errors.ErrCodeAPIError, errors.CategoryAPI,
+			"failed to get file", gitlab.ErrNotFound), expected: http.StatusNotFound},
+		{name: "message without response", err: fmt.Errorf("404 Not Found"), expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResponseStatus(tt.err); got != tt.expected {
+				t.Errorf("ResponseStatus() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+// apiErrorResponse returns the error the GitLab client reports for a response status
+func apiErrorResponse(status int) error {
+	return &gitlab.ErrorResponse{Response: &http.Response{StatusCode: status, Request: &http.Request{URL: &url.URL{}}}}
+}
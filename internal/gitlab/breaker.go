@@ -0,0 +1,200 @@
+package gitlab
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/metrics"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Circuit breaker states
+const (
+	// BreakerClosed sends every request
+	BreakerClosed = "closed"
+	// BreakerOpen rejects every request until the cool-down ends
+	BreakerOpen = "open"
+	// BreakerHalfOpen sends a single probe request deciding whether to close again
+	BreakerHalfOpen = "half-open"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failures opening the circuit
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCoolDown is how long an open circuit rejects requests
+	DefaultBreakerCoolDown = 30 * time.Second
+)
+
+// ErrCircuitOpen is the cause of requests rejected while the circuit is open
+var ErrCircuitOpen = stderrors.New("GitLab circuit breaker is open")
+
+// BreakerOptions configures the circuit breaker of GitLab requests
+type BreakerOptions struct {
+	// Threshold is the number of consecutive 5xx responses and timeouts opening the
+	// circuit; the breaker is off when zero
+	Threshold int
+	// CoolDown is how long the open circuit rejects requests before a probe is sent;
+	// DefaultBreakerCoolDown when zero
+	CoolDown time.Duration
+	// Log writes an entry with the given fields for each state change; silent when nil
+	Log func(fields map[string]interface{}, message string)
+}
+
+// Enabled reports whether requests go through the circuit breaker
+func (o BreakerOptions) Enabled() bool {
+	return o.Threshold > 0
+}
+
+// BreakerTransport stops sending requests to a GitLab instance that keeps failing.
+// After Threshold consecutive 5xx responses or timeouts the circuit opens and
+// requests fail at once for CoolDown. The circuit then half-opens: one probe request
+// is sent, closing the circuit when it succeeds and opening it again when it fails.
+type BreakerTransport struct {
+	next http.RoundTripper
+	opts BreakerOptions
+	now  func() time.Time
+
+	mu          sync.Mutex
+	state       string
+	failures    int
+	openedUntil time.Time
+	// probe numbers the probe request in flight while half-open, 0 when none is;
+	// only its completion decides whether the circuit closes
+	probe     uint64
+	lastProbe uint64
+}
+
+// NewBreakerTransport wraps next with a circuit breaker
+func NewBreakerTransport(next http.RoundTripper, opts BreakerOptions) *BreakerTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = DefaultBreakerCoolDown
+	}
+	return &BreakerTransport{next: next, opts: opts, now: time.Now, state: BreakerClosed}
+}
+
+// State returns the current state of the circuit
+func (t *BreakerTransport) State() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+// RoundTrip sends the request unless the circuit is open and records its outcome
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	probe, err := t.allow(req)
+	if err != nil {
+		metrics.Default.ObserveCircuitRejected()
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	t.record(req, probe, resp, err)
+	return resp, err
+}
+
+// allow returns an error when the request must not be sent, half-opening the circuit
+// once its cool-down has ended. The request sent while half-open is the probe, and
+// its number is returned; other requests get 0.
+func (t *BreakerTransport) allow(req *http.Request) (uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == BreakerOpen {
+		remaining := t.openedUntil.Sub(t.now())
+		if remaining > 0 {
+			return 0, errors.NewNetworkErrorWithCause(fmt.Sprintf("%s %s rejected: GitLab requests are paused for %s",
+				req.Method, req.URL.Path, remaining.Round(time.Second)), ErrCircuitOpen)
+		}
+		t.transition(BreakerHalfOpen, nil)
+	}
+	if t.state == BreakerHalfOpen {
+		if t.probe != 0 {
+			return 0, errors.NewNetworkErrorWithCause(fmt.Sprintf("%s %s rejected: waiting for the probe request",
+				req.Method, req.URL.Path), ErrCircuitOpen)
+		}
+		t.lastProbe++
+		t.probe = t.lastProbe
+		return t.probe, nil
+	}
+	return 0, nil
+}
+
+// record counts a failed request towards opening the circuit, or closes it after a
+// success. While half-open only the probe decides: requests sent before the circuit
+// opened may still complete and are ignored. Requests canceled by the caller say
+// nothing about GitLab and are ignored as well.
+func (t *BreakerTransport) record(req *http.Request, probe uint64, resp *http.Response, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	halfOpen := t.state == BreakerHalfOpen
+	if halfOpen && (probe == 0 || probe != t.probe) {
+		return
+	}
+	if halfOpen {
+		t.probe = 0
+	}
+
+	if err != nil && stderrors.Is(req.Context().Err(), context.Canceled) {
+		return
+	}
+	if err == nil && resp.StatusCode < http.StatusInternalServerError {
+		t.failures = 0
+		if halfOpen {
+			t.transition(BreakerClosed, nil)
+		}
+		return
+	}
+
+	t.failures++
+	if halfOpen || t.failures >= t.opts.Threshold {
+		t.openedUntil = t.now().Add(t.opts.CoolDown)
+		t.transition(BreakerOpen, map[string]interface{}{
+			"failures":  t.failures,
+			"cool_down": t.opts.CoolDown.String(),
+			"last_path": req.URL.Path,
+		})
+	}
+}
+
+// transition changes the state, logging and counting it
+func (t *BreakerTransport) transition(state string, fields map[string]interface{}) {
+	previous := t.state
+	t.state = state
+	if state == BreakerClosed {
+		t.failures = 0
+	}
+
+	switch state {
+	case BreakerOpen:
+		metrics.Default.ObserveCircuitState(metrics.CircuitOpen)
+	case BreakerHalfOpen:
+		metrics.Default.ObserveCircuitState(metrics.CircuitHalfOpen)
+	default:
+		metrics.Default.ObserveCircuitState(metrics.CircuitClosed)
+	}
+
+	if t.opts.Log == nil || previous == state {
+		return
+	}
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["circuit_state"] = state
+	fields["previous_state"] = previous
+	switch state {
+	case BreakerOpen:
+		t.opts.Log(fields, "GitLab keeps failing; circuit breaker opened, pausing requests")
+	case BreakerHalfOpen:
+		t.opts.Log(fields, "Circuit breaker half-open; probing GitLab")
+	default:
+		t.opts.Log(fields, "GitLab recovered; circuit breaker closed")
+	}
+}
@@ -0,0 +1,174 @@
+package gitlab
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerTransport(t *testing.T) {
+	var status, sent atomic.Int32
+	status.Store(http.StatusBadGateway)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		sent.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	var changes []string
+	transport := NewBreakerTransport(nil, BreakerOptions{
+		Threshold: 3,
+		CoolDown:  time.Minute,
+		Log: func(fields map[string]interface{}, _ string) {
+			changes = append(changes, fields["circuit_state"].(string))
+		},
+	})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	transport.now = func() time.Time { return now }
+	client := &http.Client{Transport: transport}
+
+	get := func() error {
+		resp, err := client.Get(server.URL + "/api/v4/projects/1")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("request %d error = %v, want the 502 response", i, err)
+		}
+	}
+	if transport.State() != BreakerOpen {
+		t.Fatalf("State() = %s after 3 failures, want %s", transport.State(), BreakerOpen)
+	}
+	if err := get(); !stderrors.Is(err, ErrCircuitOpen) || sent.Load() != 3 {
+		t.Errorf("request while open error = %v, sent = %d, want rejected without sending", err, sent.Load())
+	}
+
+	// A failed probe opens the circuit again for another cool-down
+	now = now.Add(time.Minute)
+	if err := get(); err != nil || sent.Load() != 4 || transport.State() != BreakerOpen {
+		t.Errorf("probe error = %v, sent = %d, state = %s, want one failed probe reopening",
+			err, sent.Load(), transport.State())
+	}
+
+	status.Store(http.StatusOK)
+	now = now.Add(time.Minute)
+	if err := get(); err != nil || transport.State() != BreakerClosed {
+		t.Errorf("probe error = %v, state = %s, want the circuit closed", err, transport.State())
+	}
+
+	want := []string{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state changes = %v, want %v", changes, want)
+			break
+		}
+	}
+}
+
+func TestBreakerTransport_ResetOnSuccess(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	transport := NewBreakerTransport(nil, BreakerOptions{Threshold: 2})
+	client := &http.Client{Transport: transport}
+	for _, failing := range []bool{true, false, true, false, true} {
+		fail.Store(failing)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	if transport.State() != BreakerClosed {
+		t.Errorf("State() = %s, want %s when failures are not consecutive", transport.State(), BreakerClosed)
+	}
+}
+
+func TestBreakerTransport_ProbeDecidesHalfOpen(t *testing.T) {
+	arrived := make(chan string, 2)
+	release := map[string]chan struct{}{"/slow": make(chan struct{}), "/probe": make(chan struct{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := release[r.URL.Path]; ok {
+			arrived <- r.URL.Path
+			<-wait
+		}
+		if r.URL.Path != "/slow" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	transport := NewBreakerTransport(nil, BreakerOptions{Threshold: 2, CoolDown: time.Minute})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	transport.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	client := &http.Client{Transport: transport}
+	get := func(path string) error {
+		resp, err := client.Get(server.URL + path)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	inFlight := func(path string) chan error {
+		done := make(chan error, 1)
+		go func() { done <- get(path) }()
+		if got := <-arrived; got != path {
+			t.Fatalf("server received %s, want %s", got, path)
+		}
+		return done
+	}
+
+	// A request sent while closed is still in flight when the circuit opens
+	slow := inFlight("/slow")
+	for i := 0; i < 2; i++ {
+		if err := get("/fail"); err != nil {
+			t.Fatalf("request %d error = %v, want the 502 response", i, err)
+		}
+	}
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	probe := inFlight("/probe")
+
+	// Its success must not close the circuit while the probe is still pending
+	close(release["/slow"])
+	if err := <-slow; err != nil {
+		t.Fatalf("slow request error = %v", err)
+	}
+	if transport.State() != BreakerHalfOpen {
+		t.Errorf("State() = %s after a request older than the probe completed, want %s",
+			transport.State(), BreakerHalfOpen)
+	}
+	if err := get("/other"); !stderrors.Is(err, ErrCircuitOpen) {
+		t.Errorf("request during the probe error = %v, want rejected", err)
+	}
+
+	close(release["/probe"])
+	if err := <-probe; err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if transport.State() != BreakerOpen {
+		t.Errorf("State() = %s after the failed probe, want %s", transport.State(), BreakerOpen)
+	}
+}
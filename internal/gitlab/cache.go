@@ -0,0 +1,317 @@
+package gitlab
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/metrics"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// HTTP cache modes
+const (
+	// CacheModeOff sends every request to GitLab
+	CacheModeOff = "off"
+	// CacheModeMemory keeps responses for the lifetime of the process
+	CacheModeMemory = "memory"
+	// CacheModeDisk keeps responses in a directory across runs
+	CacheModeDisk = "disk"
+)
+
+const (
+	// MaxMemoryCacheEntries bounds the responses kept in memory; the oldest are evicted first
+	MaxMemoryCacheEntries = 1024
+	// DefaultCacheDirName is the directory under the user's home holding the disk cache
+	DefaultCacheDirName = ".go-tag-updater/http-cache"
+	// cacheDirPermissions keeps cached file contents private; entries are created 0600
+	cacheDirPermissions = 0o700
+)
+
+// cacheablePath matches the endpoints whose responses are cached: project info and
+// file content, read repeatedly by batch runs over the same projects
+var cacheablePath = regexp.MustCompile(`/projects/[^/]+(/repository/files/[^/]+(/raw)?)?$`)
+
+// credentialHeaders carry the token of a request; responses are cached per token so
+// that a cache shared between tokens never serves what another token may not read
+var credentialHeaders = []string{"Private-Token", "Job-Token", "Authorization"}
+
+// CachedResponse is a response stored with the ETag that revalidates it
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// CacheStore keeps cached responses by key
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse) error
+}
+
+// CacheOptions selects the HTTP cache of GitLab requests
+type CacheOptions struct {
+	// Mode is CacheModeOff, CacheModeMemory or CacheModeDisk; empty means off
+	Mode string
+	// Dir holds the disk cache; ~/.go-tag-updater/http-cache when empty
+	Dir string
+}
+
+// Enabled reports whether responses are cached
+func (o CacheOptions) Enabled() bool {
+	return o.Mode != "" && o.Mode != CacheModeOff
+}
+
+// Store returns the store of the mode. Memory caches are shared by every client of
+// the process, so that batch runs and the server reuse each other's responses.
+func (o CacheOptions) Store() (CacheStore, error) {
+	switch o.Mode {
+	case "", CacheModeOff:
+		return nil, nil
+	case CacheModeMemory:
+		return sharedMemoryCache(), nil
+	case CacheModeDisk:
+		return NewDiskCache(o.Dir)
+	default:
+		return nil, errors.NewConfigError(fmt.Sprintf("unknown HTTP cache mode %q, use %s, %s or %s",
+			o.Mode, CacheModeOff, CacheModeMemory, CacheModeDisk))
+	}
+}
+
+var (
+	sharedMemoryCacheOnce  sync.Once
+	sharedMemoryCacheStore *MemoryCache
+)
+
+// sharedMemoryCache returns the memory cache of the process
+func sharedMemoryCache() *MemoryCache {
+	sharedMemoryCacheOnce.Do(func() {
+		sharedMemoryCacheStore = NewMemoryCache(MaxMemoryCacheEntries)
+	})
+	return sharedMemoryCacheStore
+}
+
+// MemoryCache keeps a bounded number of responses in memory
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*CachedResponse
+	order      []string
+}
+
+// NewMemoryCache creates a memory cache holding at most maxEntries responses
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, entries: make(map[string]*CachedResponse)}
+}
+
+// Get returns the response stored under key
+func (c *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.entries[key]
+	return resp, ok
+}
+
+// Set stores a response under key, evicting the oldest entry when the cache is full
+func (c *MemoryCache) Set(key string, resp *CachedResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		if len(c.order) >= c.maxEntries {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = resp
+	return nil
+}
+
+// DiskCache keeps one JSON file per response in a directory, so that runs in the same
+// CI runner or workstation revalidate instead of downloading again
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a disk cache in dir, or in the default directory when dir is empty
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.NewFileSystemError(fmt.Sprintf("failed to determine home directory: %v", err))
+		}
+		dir = filepath.Join(home, DefaultCacheDirName)
+	}
+	return &DiskCache{dir: filepath.Clean(dir)}, nil
+}
+
+// Dir returns the directory holding the cache
+func (c *DiskCache) Dir() string {
+	return c.dir
+}
+
+// Get returns the response stored under key; unreadable entries are misses
+func (c *DiskCache) Get(key string) (*CachedResponse, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var resp CachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// Set stores a response under key, replacing the file atomically
+func (c *DiskCache) Set(key string, resp *CachedResponse) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to encode cached response: %v", err))
+	}
+	if err := os.MkdirAll(c.dir, cacheDirPermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to create cache directory: %v", err))
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write cached response: %v", err))
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write cached response: %v", err))
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write cached response: %v", err))
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write cached response: %v", err))
+	}
+	return nil
+}
+
+// path returns the file of a key
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// CachingTransport revalidates GET requests of project info and file content with
+// If-None-Match. GitLab answers 304 Not Modified for unchanged resources, which is
+// cheap for the instance, and the transport serves the stored response instead.
+type CachingTransport struct {
+	next  http.RoundTripper
+	store CacheStore
+}
+
+// NewCachingTransport wraps next with a cache kept in store
+func NewCachingTransport(next http.RoundTripper, store CacheStore) *CachingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &CachingTransport{next: next, store: store}
+}
+
+// RoundTrip sends the request, conditional when a cached response exists, and stores
+// successful responses carrying an ETag
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached, ok := t.store.Get(key)
+	etag := ""
+	if ok {
+		etag = cached.Header.Get("ETag")
+	}
+	if etag != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if etag != "" && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		metrics.Default.ObserveCacheHit()
+		return cached.response(req), nil
+	}
+
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "" {
+		t.save(key, resp)
+	}
+	return resp, nil
+}
+
+// save stores a response of at most MaxResponseSize bytes and hands its body on to the
+// caller; a response that cannot be stored is still served
+func (t *CachingTransport) save(key string, resp *http.Response) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil || len(body) > MaxResponseSize {
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	_ = t.store.Set(key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		StoredAt:   time.Now(),
+	})
+}
+
+// readCloser reads from one reader and closes another
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cacheable reports whether a request may be answered from the cache
+func cacheable(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		req.Header.Get("If-None-Match") == "" && req.Header.Get("Range") == "" &&
+		cacheablePath.MatchString(req.URL.EscapedPath())
+}
+
+// cacheKey identifies a request by its URL and a hash of its credentials
+func cacheKey(req *http.Request) string {
+	hash := sha256.New()
+	for _, header := range credentialHeaders {
+		fmt.Fprintf(hash, "%s:%s\n", header, req.Header.Get(header))
+	}
+	fmt.Fprintf(hash, "%s %s", req.Method, req.URL.String())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// response rebuilds the cached response for req
+func (c *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
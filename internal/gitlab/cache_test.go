@@ -0,0 +1,134 @@
+package gitlab
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCachingTransport(t *testing.T) {
+	var full, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `W/"` + r.Header.Get("Private-Token") + `"`
+		if r.URL.Path == "/api/v4/projects/1/merge_requests" {
+			etag = ""
+		}
+		if etag != "" && r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		_, _ = io.WriteString(w, "body of "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	get := func(t *testing.T, client *http.Client, path, token string) string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Private-Token", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s = %d, want 200", path, resp.StatusCode)
+		}
+		return string(body)
+	}
+
+	disk, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]func() CacheStore{
+		"memory": func() CacheStore { return NewMemoryCache(MaxMemoryCacheEntries) },
+		"disk":   func() CacheStore { return disk },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			full.Store(0)
+			notModified.Store(0)
+			store := newStore()
+			client := &http.Client{Transport: NewCachingTransport(nil, store)}
+
+			file := "/api/v4/projects/group%2Fapp/repository/files/values.yaml/raw?ref=main"
+			want := "body of /api/v4/projects/group/app/repository/files/values.yaml/raw"
+			for range 3 {
+				if got := get(t, client, file, "token-a"); got != want {
+					t.Fatalf("file body = %q", got)
+				}
+			}
+			if full.Load() != 1 || notModified.Load() != 2 {
+				t.Errorf("file: %d full and %d not modified responses, want 1 and 2", full.Load(), notModified.Load())
+			}
+
+			// Another token must not be served what the first one cached
+			get(t, client, file, "token-b")
+			if full.Load() != 2 {
+				t.Errorf("another token was served from the cache")
+			}
+
+			// A second transport over the same store reuses its responses
+			other := &http.Client{Transport: NewCachingTransport(nil, store)}
+			get(t, other, "/api/v4/projects/1", "token-a")
+			get(t, other, "/api/v4/projects/1", "token-a")
+			if full.Load() != 3 || notModified.Load() != 3 {
+				t.Errorf("project: %d full and %d not modified responses, want 3 and 3", full.Load(), notModified.Load())
+			}
+
+			// Other endpoints are never cached
+			get(t, client, "/api/v4/projects/1/merge_requests", "token-a")
+			get(t, client, "/api/v4/projects/1/merge_requests", "token-a")
+			if full.Load() != 5 {
+				t.Errorf("merge requests were cached")
+			}
+		})
+	}
+}
+
+func TestMemoryCache_Evicts(t *testing.T) {
+	cache := NewMemoryCache(2)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(key, &CachedResponse{StatusCode: http.StatusOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Error("oldest entry was not evicted")
+	}
+	if _, ok := cache.Get("c"); !ok {
+		t.Error("newest entry is missing")
+	}
+}
+
+func TestCacheOptions_Store(t *testing.T) {
+	if store, err := (CacheOptions{Mode: CacheModeOff}).Store(); err != nil || store != nil {
+		t.Errorf("off = %v, %v; want no store", store, err)
+	}
+	if _, err := (CacheOptions{Mode: "redis"}).Store(); err == nil {
+		t.Error("unknown mode accepted")
+	}
+
+	dir := t.TempDir()
+	store, err := (CacheOptions{Mode: CacheModeDisk, Dir: dir}).Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disk, ok := store.(*DiskCache); !ok || disk.Dir() != dir {
+		t.Errorf("disk store = %#v, want a disk cache in %s", store, dir)
+	}
+}
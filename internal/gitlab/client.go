@@ -6,7 +6,10 @@ import (
 	"net/http"
 	"time"
 
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
 	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/metrics"
 )
 
 const (
@@ -31,12 +34,25 @@ const (
 	MaxResponseSize = 10 * 1024 * 1024 // 10MB
 )
 
+// AuthMode selects how the client sends its token to GitLab
+type AuthMode string
+
+const (
+	// AuthModePAT sends a personal, project or group access token in the PRIVATE-TOKEN header
+	AuthModePAT AuthMode = "pat"
+	// AuthModeOAuth sends an OAuth 2.0 access token as a bearer token
+	AuthModeOAuth AuthMode = "oauth"
+	// AuthModeJob sends a CI/CD job token in the JOB-TOKEN header
+	AuthModeJob AuthMode = "job"
+)
+
 // Client wraps the GitLab API client with additional functionality
 type Client struct {
 	client     *gitlab.Client
 	debug      bool
 	baseURL    string
 	token      string
+	authMode   AuthMode
 	timeout    time.Duration
 	retryCount int
 }
@@ -46,8 +62,36 @@ func NewClient(token, baseURL string) (*Client, error) {
 	return NewClientWithConfig(token, baseURL, false, DefaultTimeout, MaxRetryAttempts)
 }
 
+// NewJobTokenClient creates a GitLab client authenticating with a CI/CD job token
+func NewJobTokenClient(token, baseURL string) (*Client, error) {
+	return NewClientWithAuth(token, baseURL, AuthModeJob)
+}
+
+// NewClientWithAuth creates a GitLab client sending its token according to the auth mode
+func NewClientWithAuth(token, baseURL string, mode AuthMode) (*Client, error) {
+	return newClient(token, baseURL, mode, nil, false, DefaultTimeout, MaxRetryAttempts)
+}
+
+// NewClientWithTransport creates a GitLab client sending its requests through the
+// given transport, so embedding applications can observe them; nil uses the default
+func NewClientWithTransport(token, baseURL string, mode AuthMode, transport http.RoundTripper) (*Client, error) {
+	return newClient(token, baseURL, mode, transport, false, DefaultTimeout, MaxRetryAttempts)
+}
+
 // NewClientWithConfig creates a new GitLab client with custom configuration
 func NewClientWithConfig(token, baseURL string, debug bool, timeout time.Duration, retryCount int) (*Client, error) {
+	return newClient(token, baseURL, AuthModePAT, nil, debug, timeout, retryCount)
+}
+
+// newClient creates a GitLab client using the client-go constructor of the auth mode
+func newClient(
+	token, baseURL string,
+	mode AuthMode,
+	transport http.RoundTripper,
+	debug bool,
+	timeout time.Duration,
+	retryCount int,
+) (*Client, error) {
 	if token == "" {
 		return nil, fmt.Errorf("GitLab token cannot be empty")
 	}
@@ -56,12 +100,26 @@ func NewClientWithConfig(token, baseURL string, debug bool, timeout time.Duratio
 		baseURL = DefaultGitLabURL
 	}
 
-	// Create GitLab client with custom HTTP client
+	// Create GitLab client with custom HTTP client; every attempt is counted in the metrics
 	httpClient := &http.Client{
-		Timeout: timeout,
+		Timeout:   timeout,
+		Transport: metrics.Default.Transport(transport),
+	}
+
+	var newGitLabClient func(string, ...gitlab.ClientOptionFunc) (*gitlab.Client, error)
+	switch mode {
+	case AuthModePAT, "":
+		mode, newGitLabClient = AuthModePAT, gitlab.NewClient
+	case AuthModeOAuth:
+		newGitLabClient = gitlab.NewOAuthClient
+	case AuthModeJob:
+		newGitLabClient = gitlab.NewJobClient
+	default:
+		return nil, fmt.Errorf("unsupported auth mode %q", mode)
 	}
 
-	gitlabClient, err := gitlab.NewClient(token, gitlab.WithBaseURL(baseURL), gitlab.WithHTTPClient(httpClient))
+	gitlabClient, err := newGitLabClient(token, gitlab.WithBaseURL(baseURL), gitlab.WithHTTPClient(httpClient),
+		gitlab.WithRequestLogHook(observeRetry))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
 	}
@@ -71,18 +129,26 @@ func NewClientWithConfig(token, baseURL string, debug bool, timeout time.Duratio
 		debug:      debug,
 		baseURL:    baseURL,
 		token:      token,
+		authMode:   mode,
 		timeout:    timeout,
 		retryCount: retryCount,
 	}, nil
 }
 
+// observeRetry counts every attempt after the first one of a request as a retry
+func observeRetry(_ retryablehttp.Logger, _ *http.Request, attempt int) {
+	if attempt > 0 {
+		metrics.Default.ObserveRetry()
+	}
+}
+
 // GetProject retrieves project information by ID or path
-func (c *Client) GetProject(projectID interface{}) (*gitlab.Project, error) {
+func (c *Client) GetProject(ctx context.Context, projectID interface{}) (*gitlab.Project, error) {
 	if c.client == nil {
 		return nil, fmt.Errorf("GitLab client not initialized")
 	}
 
-	project, _, err := c.client.Projects.GetProject(projectID, nil)
+	project, _, err := c.client.Projects.GetProject(projectID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project %v: %w", projectID, err)
 	}
@@ -91,23 +157,28 @@ func (c *Client) GetProject(projectID interface{}) (*gitlab.Project, error) {
 }
 
 // ResolveProjectID converts a project path to numeric ID using ProjectManager
-func (c *Client) ResolveProjectID(projectIdentifier string) (int, error) {
+func (c *Client) ResolveProjectID(ctx context.Context, projectIdentifier string) (int, error) {
 	if c.client == nil {
 		return 0, fmt.Errorf("GitLab client not initialized")
 	}
 
 	projectManager := NewProjectManager(c.client)
-	return projectManager.ResolveProjectIdentifier(context.Background(), projectIdentifier)
+	return projectManager.ResolveProjectIdentifier(ctx, projectIdentifier)
 }
 
 // IsHealthy checks if the GitLab instance is accessible
-func (c *Client) IsHealthy() error {
+func (c *Client) IsHealthy(ctx context.Context) error {
 	if c.client == nil {
 		return fmt.Errorf("GitLab client not initialized")
 	}
 
+	// Job tokens cannot read the current user; their first API call acts as the check
+	if c.authMode == AuthModeJob {
+		return nil
+	}
+
 	// Try to get current user as a health check
-	_, _, err := c.client.Users.CurrentUser()
+	_, _, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("GitLab health check failed: %w", err)
 	}
@@ -115,6 +186,11 @@ func (c *Client) IsHealthy() error {
 	return nil
 }
 
+// GetAuthMode returns how the client authenticates
+func (c *Client) GetAuthMode() AuthMode {
+	return c.authMode
+}
+
 // GetBaseURL returns the base URL of the GitLab instance
 func (c *Client) GetBaseURL() string {
 	return c.baseURL
@@ -1,6 +1,9 @@
 package gitlab
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -439,7 +442,7 @@ func TestClient_GetProject_NilClient(t *testing.T) {
 		token:  TestGitLabToken,
 	}
 
-	_, err := client.GetProject(TestProjectID)
+	_, err := client.GetProject(context.Background(), TestProjectID)
 	if err == nil {
 		t.Error("GetProject() should return error when client is nil")
 	}
@@ -457,7 +460,7 @@ func TestClient_ResolveProjectID_NilClient(t *testing.T) {
 		token:  TestGitLabToken,
 	}
 
-	_, err := client.ResolveProjectID(TestProjectPath)
+	_, err := client.ResolveProjectID(context.Background(), TestProjectPath)
 	if err == nil {
 		t.Error("ResolveProjectID() should return error when client is nil")
 	}
@@ -475,7 +478,7 @@ func TestClient_IsHealthy_NilClient(t *testing.T) {
 		token:  TestGitLabToken,
 	}
 
-	err := client.IsHealthy()
+	err := client.IsHealthy(context.Background())
 	if err == nil {
 		t.Error("IsHealthy() should return error when client is nil")
 	}
@@ -531,3 +534,100 @@ func BenchmarkClient_GetTimeout(b *testing.B) {
 		_ = client.GetTimeout()
 	}
 }
+
+func TestNewJobTokenClient(t *testing.T) {
+	if _, err := NewJobTokenClient(InvalidToken, TestGitLabURL); err == nil {
+		t.Error("NewJobTokenClient() expected error for empty token")
+	}
+
+	client, err := NewJobTokenClient(TestGitLabToken, TestGitLabURL)
+	if err != nil {
+		t.Fatalf("NewJobTokenClient() unexpected error: %v", err)
+	}
+	if client.GetAuthMode() != AuthModeJob || client.GetBaseURL() != TestGitLabURL {
+		t.Errorf("NewJobTokenClient() = %+v, want job token client for %s", client, TestGitLabURL)
+	}
+
+	// Job tokens cannot read the current user, so the health check must not call the API
+	if err := client.IsHealthy(context.Background()); err != nil {
+		t.Errorf("IsHealthy() with job token = %v, want nil", err)
+	}
+}
+
+func TestNewClientWithAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        AuthMode
+		want        AuthMode
+		expectError bool
+	}{
+		{name: "default to personal access token", mode: "", want: AuthModePAT},
+		{name: "personal access token", mode: AuthModePAT, want: AuthModePAT},
+		{name: "oauth token", mode: AuthModeOAuth, want: AuthModeOAuth},
+		{name: "job token", mode: AuthModeJob, want: AuthModeJob},
+		{name: "unknown mode", mode: "basic", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientWithAuth(TestGitLabToken, TestGitLabURL, tt.mode)
+			if tt.expectError {
+				if err == nil {
+					t.Error("NewClientWithAuth() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewClientWithAuth() unexpected error: %v", err)
+			}
+			if client.GetAuthMode() != tt.want {
+				t.Errorf("GetAuthMode() = %q, want %q", client.GetAuthMode(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_ContextCancelsRequests(t *testing.T) {
+	// The handler answers only once the client gives up, so every request is in flight
+	// when the context ends
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(TestTimeout * time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(TestGitLabToken, server.URL)
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	branches := NewBranchManager(client.GetGitLabClient(), TestProjectID)
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{name: "health check", call: client.IsHealthy},
+		{name: "branch manager", call: func(ctx context.Context) error {
+			_, err := branches.GetBranch(ctx, TestBranchName)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			started := time.Now()
+			if err := tt.call(ctx); err == nil {
+				t.Fatal("expected an error once the context expired")
+			}
+			if elapsed := time.Since(started); elapsed > TestTimeout*time.Millisecond/2 {
+				t.Errorf("request returned after %v, want it aborted with the context", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// CommitManager writes several repository files in a single commit through the
+// commit actions API, so an update of many files applies atomically
+type CommitManager struct {
+	api       FileAPI
+	files     *FileManager
+	projectID interface{}
+}
+
+// FileChange is the new content of one file of a commit
+type FileChange struct {
+	FilePath string
+	Content  string
+}
+
+// CommitOptions contains options for a commit of several files
+type CommitOptions struct {
+	Branch        string
+	StartBranch   string
+	CommitMessage string
+	AuthorEmail   string
+	AuthorName    string
+	Changes       []FileChange
+}
+
+// NewCommitManager creates a new commit manager
+func NewCommitManager(client *gitlab.Client, projectID interface{}) *CommitManager {
+	return NewCommitManagerWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewCommitManagerWithAPI creates a new commit manager on top of the given API implementation
+func NewCommitManagerWithAPI(api FileAPI, projectID interface{}) *CommitManager {
+	return &CommitManager{
+		api:       api,
+		files:     NewFileManagerWithAPI(api, projectID),
+		projectID: projectID,
+	}
+}
+
+// CommitFiles writes all changes to the branch in one commit. Files missing on the
+// branch, or on StartBranch when the branch is created by the commit, are created
+// and existing ones updated. Either every change is applied or none is.
+func (cm *CommitManager) CommitFiles(ctx context.Context, opts *CommitOptions) (*gitlab.Commit, error) {
+	if opts == nil {
+		return nil, errors.NewValidationError("commit options cannot be nil")
+	}
+	if len(opts.Changes) == 0 {
+		return nil, errors.NewValidationError("commit must change at least one file")
+	}
+
+	branch := opts.Branch
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	ref := branch
+	if opts.StartBranch != "" {
+		ref = opts.StartBranch
+	}
+
+	actions, err := cm.buildActions(ctx, ref, opts.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	message := opts.CommitMessage
+	if message == "" {
+		message = DefaultCommitMessage
+	}
+
+	commitOpts := &gitlab.CreateCommitOptions{
+		Branch:        gitlab.Ptr(branch),
+		CommitMessage: gitlab.Ptr(message),
+		Actions:       actions,
+	}
+	if opts.StartBranch != "" {
+		commitOpts.StartBranch = gitlab.Ptr(opts.StartBranch)
+	}
+	if opts.AuthorEmail != "" {
+		commitOpts.AuthorEmail = gitlab.Ptr(opts.AuthorEmail)
+	}
+	if opts.AuthorName != "" {
+		commitOpts.AuthorName = gitlab.Ptr(opts.AuthorName)
+	}
+
+	commit, _, err := cm.api.CreateCommit(cm.projectID, commitOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to commit %d files to %s: %v",
+			len(opts.Changes), branch, err))
+	}
+
+	return commit, nil
+}
+
+// buildActions turns the changes into create or update actions depending on whether
+// each file exists on ref
+func (cm *CommitManager) buildActions(
+	ctx context.Context,
+	ref string,
+	changes []FileChange,
+) ([]*gitlab.CommitActionOptions, error) {
+	actions := make([]*gitlab.CommitActionOptions, 0, len(changes))
+	seen := make(map[string]bool, len(changes))
+
+	for _, change := range changes {
+		if change.FilePath == "" {
+			return nil, errors.NewValidationError("file path cannot be empty")
+		}
+		if change.Content == "" {
+			return nil, errors.NewValidationError(fmt.Sprintf("file content of %s cannot be empty", change.FilePath))
+		}
+		if seen[change.FilePath] {
+			return nil, errors.NewValidationError(fmt.Sprintf("file %s is changed more than once", change.FilePath))
+		}
+		seen[change.FilePath] = true
+
+		exists, err := cm.files.FileExists(ctx, change.FilePath, ref)
+		if err != nil {
+			return nil, err
+		}
+		action := gitlab.FileCreate
+		if exists {
+			action = gitlab.FileUpdate
+		}
+
+		content, encoding := encodeContent(change.Content)
+		actions = append(actions, &gitlab.CommitActionOptions{
+			Action:   gitlab.Ptr(action),
+			FilePath: gitlab.Ptr(change.FilePath),
+			Content:  gitlab.Ptr(content),
+			Encoding: encoding,
+		})
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,48 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// HeadCommit returns the commit a branch, tag or commit ref points to
+func (fm *FileManager) HeadCommit(ctx context.Context, ref string) (*gitlab.Commit, error) {
+	if ref == "" {
+		return nil, errors.NewValidationError("ref cannot be empty")
+	}
+
+	opts := &gitlab.ListCommitsOptions{
+		RefName:     gitlab.Ptr(ref),
+		ListOptions: gitlab.ListOptions{PerPage: 1, Page: 1},
+	}
+
+	commits, _, err := fm.api.ListCommits(fm.projectID, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to get head commit of %s: %v", ref, err))
+	}
+	if len(commits) == 0 {
+		return nil, errors.NewAPIError(fmt.Sprintf("ref %s has no commits", ref))
+	}
+	return commits[0], nil
+}
+
+// CompareRefs returns the commits and file changes on to that are missing from from,
+// as the Repository Compare API reports them
+func (fm *FileManager) CompareRefs(ctx context.Context, from, to string) (*gitlab.Compare, error) {
+	if from == "" || to == "" {
+		return nil, errors.NewValidationError("both refs are required for a comparison")
+	}
+
+	compare, _, err := fm.api.Compare(fm.projectID, &gitlab.CompareOptions{
+		From: gitlab.Ptr(from),
+		To:   gitlab.Ptr(to),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to compare %s with %s: %v", from, to, err))
+	}
+	return compare, nil
+}
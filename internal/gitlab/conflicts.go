@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
@@ -14,20 +15,43 @@ import (
 
 const (
 	// Conflict detection constants
-	MaxConflictCheckAttempts = 15
-	ConflictCheckInterval    = 10 * time.Second
-	DefaultWaitTimeout       = 15 * time.Minute
+	ConflictCheckInterval = 10 * time.Second
+	DefaultWaitTimeout    = 15 * time.Minute
 
 	// MR states for conflict checking
 	StateOpened = "opened"
 	StateMerged = "merged"
 	StateClosed = "closed"
+	// StateAll selects merge requests of every state in list filters
+	StateAll = "all"
+
+	// Kinds of conflicting merge requests
+	ConflictTypeSameBranch = "same_branch"
+	ConflictTypeSameFile   = "same_file"
+	ConflictTypeSameTarget = "same_target"
+
+	// MergeRequestDiffsPerPage defines the page size when listing the files of a merge request
+	MergeRequestDiffsPerPage = 100
+	// MaxMergeRequestDiffPages bounds the pages read per merge request; files beyond
+	// them are not considered
+	MaxMergeRequestDiffPages = 20
 )
 
 // ConflictDetector handles merge request conflict detection and prevention
 type ConflictDetector struct {
-	client    *gitlab.Client
+	api       MergeRequestAPI
 	projectID interface{}
+	interval  time.Duration
+	progress  ProgressFunc
+
+	// fileCheck enables the same-file check, which lists the files of every open
+	// merge request to the target branch
+	fileCheck    bool
+	diffsPerPage int
+
+	// changedFiles caches the files of a merge request by IID and head commit
+	mu           sync.Mutex
+	changedFiles map[string]map[string]bool
 }
 
 // ConflictInfo contains information about conflicting merge requests
@@ -49,17 +73,45 @@ type ConflictingMR struct {
 	CreatedAt    *time.Time
 	UpdatedAt    *time.Time
 	Author       string
-	ConflictType string // "same_branch", "same_file", "same_target"
+	ConflictType string // ConflictTypeSameBranch, ConflictTypeSameFile or ConflictTypeSameTarget
 }
 
 // NewConflictDetector creates a new conflict detector
 func NewConflictDetector(client *gitlab.Client, projectID interface{}) *ConflictDetector {
+	return NewConflictDetectorWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewConflictDetectorWithAPI creates a new conflict detector on top of the given API implementation
+func NewConflictDetectorWithAPI(api MergeRequestAPI, projectID interface{}) *ConflictDetector {
 	return &ConflictDetector{
-		client:    client,
-		projectID: projectID,
+		api:          api,
+		projectID:    projectID,
+		interval:     ConflictCheckInterval,
+		fileCheck:    true,
+		diffsPerPage: MergeRequestDiffsPerPage,
+		changedFiles: make(map[string]map[string]bool),
+	}
+}
+
+// SetProgress sets the function told how many conflicting merge requests are still
+// open after every check of WaitForConflictsToResolve
+func (cd *ConflictDetector) SetProgress(fn ProgressFunc) {
+	cd.progress = fn
+}
+
+// SetInterval sets how often WaitForConflictsToResolve re-checks the conflicting merge requests
+func (cd *ConflictDetector) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		cd.interval = interval
 	}
 }
 
+// SetFileCheck enables or disables the same-file check; disabling it saves one
+// request per page of files of every open merge request when rate limits are tight
+func (cd *ConflictDetector) SetFileCheck(enabled bool) {
+	cd.fileCheck = enabled
+}
+
 // CheckForConflicts performs comprehensive conflict detection
 func (cd *ConflictDetector) CheckForConflicts(ctx context.Context, sourceBranch, targetBranch, filePath string) (*ConflictInfo, error) {
 	if sourceBranch == "" {
@@ -83,7 +135,7 @@ func (cd *ConflictDetector) CheckForConflicts(ctx context.Context, sourceBranch,
 	conflictInfo.ConflictingMRs = append(conflictInfo.ConflictingMRs, sameBranchConflicts...)
 
 	// Check for same file conflicts
-	if filePath != "" {
+	if filePath != "" && cd.fileCheck {
 		sameFileConflicts, err := cd.checkSameFileConflicts(ctx, targetBranch, filePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check same file conflicts: %w", err)
@@ -115,7 +167,7 @@ func (cd *ConflictDetector) checkSameBranchConflicts(ctx context.Context, source
 		State:        gitlab.Ptr(StateOpened),
 	}
 
-	mrs, _, err := cd.client.MergeRequests.ListProjectMergeRequests(cd.projectID, opts)
+	mrs, _, err := cd.api.ListProjectMergeRequests(cd.projectID, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list merge requests for branch %s: %v", sourceBranch, err))
 	}
@@ -133,7 +185,7 @@ func (cd *ConflictDetector) checkSameBranchConflicts(ctx context.Context, source
 			CreatedAt:    mr.CreatedAt,
 			UpdatedAt:    mr.UpdatedAt,
 			Author:       getAuthorName(mr.Author),
-			ConflictType: "same_branch",
+			ConflictType: ConflictTypeSameBranch,
 		})
 	}
 
@@ -151,7 +203,7 @@ func (cd *ConflictDetector) checkSameFileConflicts(ctx context.Context, targetBr
 		State:        gitlab.Ptr(StateOpened),
 	}
 
-	mrs, _, err := cd.client.MergeRequests.ListProjectMergeRequests(cd.projectID, opts)
+	mrs, _, err := cd.api.ListProjectMergeRequests(cd.projectID, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list merge requests for target branch %s: %v", targetBranch, err))
 	}
@@ -159,7 +211,7 @@ func (cd *ConflictDetector) checkSameFileConflicts(ctx context.Context, targetBr
 	var conflicts []ConflictingMR
 	for _, mr := range mrs {
 		// Check if this MR affects the same file
-		affectsFile, err := cd.checkMRAffectsFile(ctx, mr.IID, filePath)
+		affectsFile, err := cd.checkMRAffectsFile(ctx, mr.IID, mr.SHA, filePath)
 		if err != nil {
 			// Log warning but continue checking other MRs
 			continue
@@ -177,7 +229,7 @@ func (cd *ConflictDetector) checkSameFileConflicts(ctx context.Context, targetBr
 				CreatedAt:    mr.CreatedAt,
 				UpdatedAt:    mr.UpdatedAt,
 				Author:       getAuthorName(mr.Author),
-				ConflictType: "same_file",
+				ConflictType: ConflictTypeSameFile,
 			})
 		}
 	}
@@ -198,7 +250,7 @@ func (cd *ConflictDetector) checkTargetBranchConflicts(ctx context.Context, targ
 		Sort:         gitlab.Ptr("desc"),
 	}
 
-	mrs, _, err := cd.client.MergeRequests.ListProjectMergeRequests(cd.projectID, opts)
+	mrs, _, err := cd.api.ListProjectMergeRequests(cd.projectID, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list merge requests for target branch %s: %v", targetBranch, err))
 	}
@@ -223,7 +275,7 @@ func (cd *ConflictDetector) checkTargetBranchConflicts(ctx context.Context, targ
 				CreatedAt:    mr.CreatedAt,
 				UpdatedAt:    mr.UpdatedAt,
 				Author:       getAuthorName(mr.Author),
-				ConflictType: "same_target",
+				ConflictType: ConflictTypeSameTarget,
 			})
 		}
 	}
@@ -231,13 +283,47 @@ func (cd *ConflictDetector) checkTargetBranchConflicts(ctx context.Context, targ
 	return conflicts, nil
 }
 
-// checkMRAffectsFile checks if a merge request affects a specific file
-func (cd *ConflictDetector) checkMRAffectsFile(ctx context.Context, mrIID int, filePath string) (bool, error) {
-	// Note: This is a simplified implementation
-	// In a real implementation, you would use the MR changes API
-	// For now, we'll just return false to avoid API calls that might not be available
-	// TODO: Implement proper file change detection when the API supports it
-	return false, nil
+// checkMRAffectsFile checks if a merge request adds, changes, renames or deletes a
+// specific file. The files of a merge request are cached until its head commit moves.
+func (cd *ConflictDetector) checkMRAffectsFile(ctx context.Context, mrIID int, sha, filePath string) (bool, error) {
+	cacheKey := fmt.Sprintf("%d@%s", mrIID, sha)
+
+	cd.mu.Lock()
+	files, ok := cd.changedFiles[cacheKey]
+	cd.mu.Unlock()
+	if ok {
+		return files[filePath], nil
+	}
+
+	files, err := cd.listChangedFiles(ctx, mrIID)
+	if err != nil {
+		return false, err
+	}
+
+	cd.mu.Lock()
+	cd.changedFiles[cacheKey] = files
+	cd.mu.Unlock()
+	return files[filePath], nil
+}
+
+// listChangedFiles pages through the diffs of a merge request and returns the old
+// and new paths of every changed file
+func (cd *ConflictDetector) listChangedFiles(ctx context.Context, mrIID int) (map[string]bool, error) {
+	diffs, err := collectPages(ctx, MaxMergeRequestDiffPages*cd.diffsPerPage, cd.diffsPerPage,
+		func(page gitlab.ListOptions) ([]*gitlab.MergeRequestDiff, *gitlab.Response, error) {
+			opts := &gitlab.ListMergeRequestDiffsOptions{ListOptions: page}
+			return cd.api.ListMergeRequestDiffs(cd.projectID, mrIID, opts, gitlab.WithContext(ctx))
+		})
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to list changes of merge request %d: %v", mrIID, err))
+	}
+
+	files := make(map[string]bool, len(diffs))
+	for _, d := range diffs {
+		files[d.OldPath] = true
+		files[d.NewPath] = true
+	}
+	return files, nil
 }
 
 // isPotentiallyConflicting checks if an MR might conflict based on title/branch patterns
@@ -275,10 +361,9 @@ func (cd *ConflictDetector) WaitForConflictsToResolve(ctx context.Context, confl
 	}
 
 	timeout := time.After(maxWaitTime)
-	ticker := time.NewTicker(ConflictCheckInterval)
+	ticker := time.NewTicker(cd.interval)
 	defer ticker.Stop()
 
-	attempt := 0
 	for {
 		select {
 		case <-ctx.Done():
@@ -286,13 +371,14 @@ func (cd *ConflictDetector) WaitForConflictsToResolve(ctx context.Context, confl
 		case <-timeout:
 			return errors.NewAPIError(fmt.Sprintf("timeout waiting for %d conflicts to resolve after %v", conflicts.TotalConflicts, maxWaitTime))
 		case <-ticker.C:
-			attempt++
-
 			// Re-check conflicts
 			stillConflicting := 0
 			for _, conflict := range conflicts.ConflictingMRs {
-				mr, _, err := cd.client.MergeRequests.GetMergeRequest(cd.projectID, conflict.IID, nil)
+				mr, _, err := cd.api.GetMergeRequest(cd.projectID, conflict.IID, nil, gitlab.WithContext(ctx))
 				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
 					continue // MR might have been deleted, which is good
 				}
 
@@ -304,10 +390,8 @@ func (cd *ConflictDetector) WaitForConflictsToResolve(ctx context.Context, confl
 			if stillConflicting == 0 {
 				return nil // All conflicts resolved
 			}
-
-			if attempt >= MaxConflictCheckAttempts {
-				return errors.NewAPIError(fmt.Sprintf("gave up waiting for conflicts to resolve after %d attempts", attempt))
-			}
+			cd.progress.report(fmt.Sprintf("%d of %d conflicting merge requests still open",
+				stillConflicting, conflicts.TotalConflicts))
 		}
 	}
 }
@@ -326,11 +410,11 @@ func (cd *ConflictDetector) generateRecommendation(conflictInfo *ConflictInfo) s
 
 	for _, conflict := range conflictInfo.ConflictingMRs {
 		switch conflict.ConflictType {
-		case "same_branch":
+		case ConflictTypeSameBranch:
 			sameBranchCount++
-		case "same_file":
+		case ConflictTypeSameFile:
 			sameFileCount++
-		case "same_target":
+		case ConflictTypeSameTarget:
 			sameTargetCount++
 		}
 	}
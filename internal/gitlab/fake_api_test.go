@@ -0,0 +1,687 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestFakeFilePath      = "deploy/values.yaml"
+	TestFakeFileContent   = "image:\n  tag: v1.0.0\n"
+	TestFakeUpdatedFile   = "image:\n  tag: v1.2.3\n"
+	TestFakeUpdateBranch  = "update-tag/v1.2.3"
+	TestFakeProtected     = "release"
+	TestFakeCommitMessage = "Update image tag to v1.2.3"
+	TestFakeCloseNote     = "Superseded by a newer update"
+)
+
+// newFakeProject starts a fake GitLab API with one project holding a YAML file on main
+func newFakeProject(t *testing.T) (*gitlabtest.Server, int) {
+	t.Helper()
+
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestNestedNamespace)
+	server.SetFile(projectID, TestMainBranch, TestFakeFilePath, TestFakeFileContent)
+
+	return server, projectID
+}
+
+func TestProjectManager_FakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	pm := NewProjectManager(server.Client())
+	ctx := context.Background()
+
+	resolved, err := pm.ResolveProjectIdentifier(ctx, TestNestedNamespace)
+	if err != nil {
+		t.Fatalf("ResolveProjectIdentifier() unexpected error: %v", err)
+	}
+	if resolved != projectID {
+		t.Errorf("ResolveProjectIdentifier() = %d, want %d", resolved, projectID)
+	}
+
+	_, err = pm.ResolveProjectPath(ctx, "missing-group/missing-project")
+	if code := errors.GetErrorCode(err); code != errors.ErrCodeInvalidProject {
+		t.Errorf("ResolveProjectPath() error code = %d, want %d (%v)", code, errors.ErrCodeInvalidProject, err)
+	}
+
+	branch, err := pm.GetProjectDefaultBranch(ctx, projectID)
+	if err != nil || branch != gitlabtest.DefaultBranch {
+		t.Errorf("GetProjectDefaultBranch() = %q, %v", branch, err)
+	}
+
+	server.FailRequests(http.MethodGet, "/projects/", http.StatusInternalServerError)
+	if _, err := pm.GetProjectInfo(ctx, projectID); errors.GetErrorCode(err) != errors.ErrCodeAPIError {
+		t.Errorf("GetProjectInfo() with server error = %v, want API error", err)
+	}
+}
+
+func TestBranchManager_FakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeProtected, TestMainBranch, true)
+	bm := NewBranchManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	created, err := bm.CreateBranch(ctx, TestFakeUpdateBranch, TestMainBranch)
+	if err != nil {
+		t.Fatalf("CreateBranch() unexpected error: %v", err)
+	}
+	if created.Name != TestFakeUpdateBranch || created.Protected {
+		t.Errorf("CreateBranch() = %+v", created)
+	}
+
+	_, err = bm.CreateBranch(ctx, TestFakeUpdateBranch, TestMainBranch)
+	if !IsBranchExistsError(err) {
+		t.Errorf("CreateBranch() duplicate error = %v, want branch exists error", err)
+	}
+
+	exists, err := bm.BranchExists(ctx, TestFakeUpdateBranch)
+	if err != nil || !exists {
+		t.Errorf("BranchExists() = %v, %v, want true", exists, err)
+	}
+
+	if err := bm.DeleteBranch(ctx, TestFakeProtected); err == nil {
+		t.Error("DeleteBranch() expected error for protected branch")
+	}
+
+	if err := bm.DeleteBranch(ctx, TestFakeUpdateBranch); err != nil {
+		t.Fatalf("DeleteBranch() unexpected error: %v", err)
+	}
+
+	exists, err = bm.BranchExists(ctx, TestFakeUpdateBranch)
+	if err != nil || exists {
+		t.Errorf("BranchExists() after delete = %v, %v, want false", exists, err)
+	}
+
+	protected, err := bm.GetProtectedBranches(ctx)
+	if err != nil || len(protected) != 1 || protected[0].Name != TestFakeProtected {
+		t.Errorf("GetProtectedBranches() = %v, %v", protected, err)
+	}
+}
+
+func TestFileManager_FakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+	fm := NewFileManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	content, err := fm.GetFileContent(ctx, TestFakeFilePath, TestMainBranch)
+	if err != nil || content != TestFakeFileContent {
+		t.Fatalf("GetFileContent() = %q, %v", content, err)
+	}
+
+	exists, err := fm.FileExists(ctx, "missing.yaml", TestMainBranch)
+	if err != nil || exists {
+		t.Errorf("FileExists() for missing file = %v, %v, want false", exists, err)
+	}
+
+	_, err = fm.UpdateFileContent(ctx, TestFakeFilePath, &FileUpdateOptions{
+		Branch:        TestFakeUpdateBranch,
+		Content:       TestFakeUpdatedFile,
+		CommitMessage: TestFakeCommitMessage,
+	})
+	if err != nil {
+		t.Fatalf("UpdateFileContent() unexpected error: %v", err)
+	}
+
+	if got, _ := server.File(projectID, TestFakeUpdateBranch, TestFakeFilePath); got != TestFakeUpdatedFile {
+		t.Errorf("file on update branch = %q, want %q", got, TestFakeUpdatedFile)
+	}
+	if got, _ := server.File(projectID, TestMainBranch, TestFakeFilePath); got != TestFakeFileContent {
+		t.Errorf("file on main branch changed to %q", got)
+	}
+
+	history, err := fm.GetFileHistory(ctx, TestFakeFilePath, TestFakeUpdateBranch, 1)
+	if err != nil || len(history) != 1 || history[0].Title != TestFakeCommitMessage {
+		t.Errorf("GetFileHistory() = %v, %v", history, err)
+	}
+
+	server.FailRequests(http.MethodPut, "/repository/files/", http.StatusForbidden)
+	_, err = fm.UpdateFileContent(ctx, TestFakeFilePath, &FileUpdateOptions{
+		Branch:  TestFakeUpdateBranch,
+		Content: TestFakeFileContent,
+	})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("UpdateFileContent() with forbidden push = %v, want 403 error", err)
+	}
+}
+
+func TestFileManager_RawContentFakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+	fm := NewFileManager(server.Client(), projectID)
+	cm := NewCommitManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	// Latin-1 text is not valid UTF-8 and must be written byte for byte
+	latin1 := "# Gr\xfc\xdfe\nimage:\n  tag: v1.2.3\n"
+	if _, err := fm.UpdateFile(ctx, TestFakeFilePath, &FileUpdateOptions{
+		Branch:  TestFakeUpdateBranch,
+		Content: latin1,
+	}); err != nil {
+		t.Fatalf("UpdateFile() unexpected error: %v", err)
+	}
+	if got, _ := server.File(projectID, TestFakeUpdateBranch, TestFakeFilePath); got != latin1 {
+		t.Errorf("file after UpdateFile() = %q, want %q", got, latin1)
+	}
+
+	binary := string([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe})
+	if _, err := cm.CommitFiles(ctx, &CommitOptions{
+		Branch:  TestFakeUpdateBranch,
+		Changes: []FileChange{{FilePath: "logo.png", Content: binary}},
+	}); err != nil {
+		t.Fatalf("CommitFiles() unexpected error: %v", err)
+	}
+	if got, _ := server.File(projectID, TestFakeUpdateBranch, "logo.png"); got != binary {
+		t.Errorf("file after CommitFiles() = %q, want %q", got, binary)
+	}
+	if got, err := fm.GetFileContent(ctx, "logo.png", TestFakeUpdateBranch); err != nil || got != binary {
+		t.Errorf("GetFileContent() = %q, %v; want %q", got, err, binary)
+	}
+
+	var downloaded strings.Builder
+	n, err := fm.DownloadFile(ctx, TestFakeFilePath, TestFakeUpdateBranch, &downloaded, 0)
+	if err != nil || downloaded.String() != latin1 || n != int64(len(latin1)) {
+		t.Errorf("DownloadFile() = %d, %q, %v; want %q", n, downloaded.String(), err, latin1)
+	}
+}
+
+func TestFileManager_SizeLimitsFakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	fm := NewFileManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	large := strings.Repeat("# padding\n", MaxFileSize/10+1)
+	server.SetFile(projectID, TestMainBranch, "large.yaml", large)
+
+	_, err := fm.GetFile(ctx, "large.yaml", TestMainBranch)
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("GetFile() of a file over MaxFileSize = %v, want a too large error", err)
+	}
+
+	// Streaming reads files beyond MaxFileSize up to the given limit
+	var downloaded strings.Builder
+	if _, err := fm.DownloadFile(ctx, "large.yaml", TestMainBranch, &downloaded, 0); err != nil ||
+		downloaded.String() != large {
+		t.Errorf("DownloadFile() of %d bytes = %d bytes, %v", len(large), downloaded.Len(), err)
+	}
+
+	_, err = fm.DownloadFile(ctx, "large.yaml", TestMainBranch, io.Discard, MaxFileSize)
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("DownloadFile() over its limit = %v, want a too large error", err)
+	}
+
+	if _, err := fm.DownloadFile(ctx, "missing.yaml", TestMainBranch, io.Discard, 0); err == nil ||
+		!strings.Contains(err.Error(), "404") {
+		t.Errorf("DownloadFile() of a missing file = %v, want 404 error", err)
+	}
+}
+
+func TestFileManager_ListTreeFakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	for i := range MaxPageSize + 20 {
+		server.SetFile(projectID, TestMainBranch, fmt.Sprintf("environments/prod/app-%03d.yaml", i), "tag: v1\n")
+	}
+	fm := NewFileManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	root, err := fm.ListTree(ctx, "", TestMainBranch, false)
+	if err != nil {
+		t.Fatalf("ListTree() unexpected error: %v", err)
+	}
+	var names []string
+	for _, node := range root {
+		names = append(names, node.Path+" "+node.Type)
+	}
+	if want := []string{"deploy tree", "environments tree"}; !slices.Equal(names, want) {
+		t.Errorf("ListTree() of the root = %v, want %v", names, want)
+	}
+
+	prod, err := fm.ListTree(ctx, "environments/prod/", TestMainBranch, true)
+	if err != nil || len(prod) != MaxPageSize+20 || prod[0].Type != TreeEntryBlob {
+		t.Fatalf("ListTree() across pages = %d entries, %v; want %d files", len(prod), err, MaxPageSize+20)
+	}
+
+	files, err := fm.ListFiles(ctx, TestMainBranch)
+	if err != nil || len(files) != MaxPageSize+21 || !slices.Contains(files, TestFakeFilePath) {
+		t.Errorf("ListFiles() = %d files, %v; want every file", len(files), err)
+	}
+
+	// Listings are cached for the run
+	listed := func() int {
+		count := 0
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request, "/repository/tree") {
+				count++
+			}
+		}
+		return count
+	}
+	before := listed()
+	if _, err := fm.ListTree(ctx, "environments/prod", TestMainBranch, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fm.ListFiles(ctx, TestMainBranch); err != nil {
+		t.Fatal(err)
+	}
+	if listed() != before {
+		t.Errorf("cached listings requested the tree again")
+	}
+
+	if _, err := fm.ListTree(ctx, "", "missing", true); err == nil {
+		t.Error("ListTree() of a missing branch succeeded")
+	}
+}
+
+func TestCommitManager_FakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	cm := NewCommitManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	const newFile = "deploy/staging.yaml"
+	commit, err := cm.CommitFiles(ctx, &CommitOptions{
+		Branch:        TestFakeUpdateBranch,
+		StartBranch:   TestMainBranch,
+		CommitMessage: TestFakeCommitMessage,
+		Changes: []FileChange{
+			{FilePath: TestFakeFilePath, Content: TestFakeUpdatedFile},
+			{FilePath: newFile, Content: TestFakeUpdatedFile},
+		},
+	})
+	if err != nil || commit == nil || commit.Title != TestFakeCommitMessage {
+		t.Fatalf("CommitFiles() = %v, %v", commit, err)
+	}
+
+	for _, filePath := range []string{TestFakeFilePath, newFile} {
+		if got, _ := server.File(projectID, TestFakeUpdateBranch, filePath); got != TestFakeUpdatedFile {
+			t.Errorf("%s on update branch = %q, want %q", filePath, got, TestFakeUpdatedFile)
+		}
+	}
+	if got, _ := server.File(projectID, TestMainBranch, TestFakeFilePath); got != TestFakeFileContent {
+		t.Errorf("file on main branch changed to %q", got)
+	}
+
+	commits := 0
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, http.MethodPost+" ") && strings.HasSuffix(request, "/repository/commits") {
+			commits++
+		}
+	}
+	if commits != 1 {
+		t.Errorf("CommitFiles() created %d commits, want 1", commits)
+	}
+
+	// A failing commit changes none of the files
+	server.FailRequests(http.MethodPost, "/repository/commits", http.StatusForbidden)
+	_, err = cm.CommitFiles(ctx, &CommitOptions{
+		Branch: TestFakeUpdateBranch,
+		Changes: []FileChange{
+			{FilePath: TestFakeFilePath, Content: TestFakeFileContent},
+			{FilePath: newFile, Content: TestFakeFileContent},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("CommitFiles() with forbidden push = %v, want 403 error", err)
+	}
+	if got, _ := server.File(projectID, TestFakeUpdateBranch, TestFakeFilePath); got != TestFakeUpdatedFile {
+		t.Errorf("file after failed commit = %q, want %q", got, TestFakeUpdatedFile)
+	}
+
+	invalid := [][]FileChange{
+		nil,
+		{{FilePath: TestFakeFilePath}},
+		{{FilePath: TestFakeFilePath, Content: "a"}, {FilePath: TestFakeFilePath, Content: "b"}},
+	}
+	for _, changes := range invalid {
+		_, err := cm.CommitFiles(ctx, &CommitOptions{Branch: TestFakeUpdateBranch, Changes: changes})
+		if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+			t.Errorf("CommitFiles(%v) error = %v, want validation error", changes, err)
+		}
+	}
+}
+
+func TestSimpleMergeRequestManager_FakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+	smr := NewSimpleMergeRequestManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	opts := &SimpleMergeRequestOptions{
+		Title:        TestFakeCommitMessage,
+		SourceBranch: TestFakeUpdateBranch,
+		TargetBranch: TestMainBranch,
+	}
+
+	mr, err := smr.CreateMergeRequest(ctx, opts)
+	if err != nil {
+		t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+	}
+
+	if _, err := smr.CreateMergeRequest(ctx, opts); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("CreateMergeRequest() duplicate error = %v, want 409 conflict", err)
+	}
+
+	open, err := smr.ListOpenMergeRequests(ctx, TestMainBranch)
+	if err != nil || len(open) != 1 || open[0].IID != mr.IID {
+		t.Errorf("ListOpenMergeRequests() = %v, %v", open, err)
+	}
+
+	closed, err := smr.CloseMergeRequest(ctx, mr.IID, TestFakeCloseNote)
+	if err != nil {
+		t.Fatalf("CloseMergeRequest() unexpected error: %v", err)
+	}
+	if closed.State != "closed" {
+		t.Errorf("CloseMergeRequest() state = %q, want closed", closed.State)
+	}
+
+	notes := server.Notes(projectID, mr.IID)
+	if len(notes) != 1 || notes[0] != TestFakeCloseNote {
+		t.Errorf("merge request notes = %v", notes)
+	}
+
+	if _, err := smr.GetMergeRequest(ctx, mr.IID+1); err == nil {
+		t.Error("GetMergeRequest() expected error for unknown merge request")
+	}
+}
+
+func TestSimpleMergeRequestManager_ListOpenPagesFakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	smr := NewSimpleMergeRequestManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	// One more than a page, so the last merge request is only on the second page
+	total := OpenMergeRequestsPageSize + 1
+	for i := 0; i < total; i++ {
+		branch := fmt.Sprintf("%s-%d", TestFakeUpdateBranch, i)
+		server.AddBranch(projectID, branch, TestMainBranch, false)
+		if _, err := smr.CreateMergeRequest(ctx, &SimpleMergeRequestOptions{
+			Title:        TestFakeCommitMessage,
+			SourceBranch: branch,
+			TargetBranch: TestMainBranch,
+		}); err != nil {
+			t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+		}
+	}
+
+	open, err := smr.ListOpenMergeRequests(ctx, TestMainBranch)
+	if err != nil || len(open) != total {
+		t.Errorf("ListOpenMergeRequests() = %d merge requests, %v, want %d", len(open), err, total)
+	}
+}
+
+func TestSimpleMergeRequestManager_ListBySourceBranchPagesFakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+	smr := NewSimpleMergeRequestManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	// Closed merge requests pile up on a reused branch; the oldest is only on the second page
+	total := OpenMergeRequestsPageSize + 1
+	for i := 0; i < total; i++ {
+		mr, err := smr.CreateMergeRequest(ctx, &SimpleMergeRequestOptions{
+			Title:        TestFakeCommitMessage,
+			SourceBranch: TestFakeUpdateBranch,
+			TargetBranch: TestMainBranch,
+		})
+		if err != nil {
+			t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+		}
+		if i < total-1 {
+			if _, err := smr.CloseMergeRequest(ctx, mr.IID, ""); err != nil {
+				t.Fatalf("CloseMergeRequest() unexpected error: %v", err)
+			}
+		}
+	}
+
+	mrs, err := smr.ListMergeRequestsBySourceBranch(ctx, TestFakeUpdateBranch)
+	if err != nil || len(mrs) != total {
+		t.Errorf("ListMergeRequestsBySourceBranch() = %d merge requests, %v, want %d", len(mrs), err, total)
+	}
+}
+
+func TestSimpleMergeRequestManager_DraftFakeAPI(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+	smr := NewSimpleMergeRequestManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	mr, err := smr.CreateMergeRequest(ctx, &SimpleMergeRequestOptions{
+		Title:        TestFakeCommitMessage,
+		SourceBranch: TestFakeUpdateBranch,
+		TargetBranch: TestMainBranch,
+		Draft:        true,
+	})
+	if err != nil {
+		t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+	}
+	if !mr.Draft || mr.Title != DraftTitlePrefix+TestFakeCommitMessage {
+		t.Errorf("CreateMergeRequest() title = %q, draft = %v, want a draft", mr.Title, mr.Draft)
+	}
+
+	ready, err := smr.MarkReady(ctx, mr.IID)
+	if err != nil {
+		t.Fatalf("MarkReady() unexpected error: %v", err)
+	}
+	if ready.Draft || ready.Title != TestFakeCommitMessage {
+		t.Errorf("MarkReady() title = %q, draft = %v, want ready", ready.Title, ready.Draft)
+	}
+}
+
+func TestDraftTitle(t *testing.T) {
+	tests := []struct {
+		title     string
+		wantDraft string
+		wantReady string
+	}{
+		{title: "Update tag", wantDraft: "Draft: Update tag", wantReady: "Update tag"},
+		{title: "Draft: Update tag", wantDraft: "Draft: Update tag", wantReady: "Update tag"},
+		{title: "[Draft] Update tag", wantDraft: "[Draft] Update tag", wantReady: "Update tag"},
+		{title: "(draft) Draft: Update tag", wantDraft: "(draft) Draft: Update tag", wantReady: "Update tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := DraftTitle(tt.title); got != tt.wantDraft {
+				t.Errorf("DraftTitle() = %q, want %q", got, tt.wantDraft)
+			}
+			if got := ReadyTitle(tt.title); got != tt.wantReady {
+				t.Errorf("ReadyTitle() = %q, want %q", got, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestMergeabilityWatcher_FakeAPI(t *testing.T) {
+	tests := []struct {
+		name          string
+		rebase        bool
+		surviveRebase bool
+		stop          bool
+		wantErr       bool
+		wantRebases   int
+		wantLabels    []string
+	}{
+		{name: "rebase resolves conflicts", rebase: true, wantRebases: 1},
+		{name: "conflicts without rebase time out", wantErr: true, wantLabels: []string{NeedsRebaseLabel}},
+		{name: "failed rebase times out", rebase: true, surviveRebase: true, wantErr: true, wantRebases: 1,
+			wantLabels: []string{NeedsRebaseLabel}},
+		{name: "stop on conflict without rebase", stop: true, wantErr: true,
+			wantLabels: []string{NeedsRebaseLabel}},
+		{name: "stop on conflict after failed rebase", rebase: true, surviveRebase: true, stop: true,
+			wantErr: true, wantRebases: 1, wantLabels: []string{NeedsRebaseLabel}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newFakeProject(t)
+			server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+			server.SetConflicts(projectID, true, tt.surviveRebase)
+
+			smr := NewSimpleMergeRequestManager(server.Client(), projectID)
+			mr, err := smr.CreateMergeRequest(context.Background(), &SimpleMergeRequestOptions{
+				Title:        TestFakeCommitMessage,
+				SourceBranch: TestFakeUpdateBranch,
+				TargetBranch: TestMainBranch,
+			})
+			if err != nil {
+				t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+			}
+
+			watcher := NewMergeabilityWatcher(server.Client(), projectID)
+			watcher.SetInterval(time.Millisecond)
+			watcher.SetStopOnConflict(tt.stop)
+			// Stopping on conflicts must not wait for the timeout
+			timeout := 50 * time.Millisecond
+			if tt.stop {
+				timeout = time.Minute
+			}
+			result, err := watcher.WaitForMergeable(context.Background(), mr.IID, timeout, tt.rebase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WaitForMergeable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && errors.ExitCode(err) != errors.ExitCodeConflict {
+				t.Errorf("WaitForMergeable() exit code = %d, want %d", errors.ExitCode(err), errors.ExitCodeConflict)
+			}
+			if !result.HadConflicts || !result.Labeled || result.Resolved == tt.wantErr {
+				t.Errorf("WaitForMergeable() = %+v", result)
+			}
+
+			if got := server.Rebases(projectID, mr.IID); got != tt.wantRebases {
+				t.Errorf("rebases = %d, want %d", got, tt.wantRebases)
+			}
+			if labels := server.MergeRequests(projectID)[0].Labels; !slices.Equal(labels, tt.wantLabels) {
+				t.Errorf("labels = %v, want %v", labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestListPaginationFakeAPI(t *testing.T) {
+	const branchCount = 150
+
+	server, projectID := newFakeProject(t)
+	for i := 0; i < branchCount; i++ {
+		server.AddBranch(projectID, fmt.Sprintf("%s%03d", UpdateBranchPrefix, i), TestMainBranch, false)
+	}
+	for i := 0; i < 3; i++ {
+		server.AddProject(fmt.Sprintf("group/extra-%d", i))
+	}
+	ctx := context.Background()
+
+	bm := NewBranchManager(server.Client(), projectID)
+	branches, err := bm.ListBranches(ctx, UpdateBranchPrefix, 1000)
+	if err != nil || len(branches) != branchCount {
+		t.Errorf("ListBranches() = %d branches, %v, want all %d across pages", len(branches), err, branchCount)
+	}
+	branches, err = bm.ListBranches(ctx, UpdateBranchPrefix, 120)
+	if err != nil || len(branches) != 120 {
+		t.Errorf("ListBranches() = %d branches, %v, want 120", len(branches), err)
+	}
+
+	pm := NewProjectManager(server.Client())
+	projects, err := pm.SearchProjects(ctx, "group/", 2)
+	if err != nil || len(projects) != 2 {
+		t.Errorf("SearchProjects() = %d projects, %v, want 2", len(projects), err)
+	}
+}
+
+func TestConflictDetector_SameFileFakeAPI(t *testing.T) {
+	const otherBranch = "docs"
+
+	server, projectID := newFakeProject(t)
+	server.AddBranch(projectID, TestFakeUpdateBranch, TestMainBranch, false)
+	server.SetFile(projectID, TestFakeUpdateBranch, "aaa.yaml", "a: 1\n")
+	server.SetFile(projectID, TestFakeUpdateBranch, TestFakeFilePath, TestFakeUpdatedFile)
+	server.AddBranch(projectID, otherBranch, TestMainBranch, false)
+	server.SetFile(projectID, otherBranch, "README.md", "docs\n")
+
+	smr := NewSimpleMergeRequestManager(server.Client(), projectID)
+	ctx := context.Background()
+	for _, branch := range []string{TestFakeUpdateBranch, otherBranch} {
+		if _, err := smr.CreateMergeRequest(ctx, &SimpleMergeRequestOptions{
+			Title:        "Change " + branch,
+			SourceBranch: branch,
+			TargetBranch: TestMainBranch,
+		}); err != nil {
+			t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+		}
+	}
+
+	sameFile := func(cd *ConflictDetector) []int {
+		t.Helper()
+		info, err := cd.CheckForConflicts(ctx, "update-tag/v2.0.0", TestMainBranch, TestFakeFilePath)
+		if err != nil {
+			t.Fatalf("CheckForConflicts() unexpected error: %v", err)
+		}
+		var iids []int
+		for _, conflict := range info.ConflictingMRs {
+			if conflict.ConflictType == ConflictTypeSameFile {
+				iids = append(iids, conflict.IID)
+			}
+		}
+		return iids
+	}
+	diffRequests := func() int {
+		count := 0
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request, "/diffs") {
+				count++
+			}
+		}
+		return count
+	}
+
+	cd := NewConflictDetector(server.Client(), projectID)
+	// One file per page makes the changed file appear on the second page
+	cd.diffsPerPage = 1
+	if got := sameFile(cd); !slices.Equal(got, []int{1}) {
+		t.Errorf("same-file conflicts = %v, want [1]", got)
+	}
+	// Two pages for the first merge request and one for the second
+	if got := diffRequests(); got != 3 {
+		t.Errorf("diff requests = %d, want 3", got)
+	}
+
+	if got := sameFile(cd); !slices.Equal(got, []int{1}) || diffRequests() != 3 {
+		t.Errorf("cached same-file conflicts = %v after %d diff requests, want [1] after 3", got, diffRequests())
+	}
+
+	cd.SetFileCheck(false)
+	if got := sameFile(cd); len(got) != 0 || diffRequests() != 3 {
+		t.Errorf("disabled same-file conflicts = %v after %d diff requests, want none", got, diffRequests())
+	}
+}
+
+func TestFileManager_FindRenameFakeAPI(t *testing.T) {
+	const (
+		movedPath = "apps/app/values.yaml"
+		finalPath = "apps/app/helm/values.yaml"
+	)
+
+	server, projectID := newFakeProject(t)
+	server.RenameFile(projectID, TestMainBranch, TestFakeFilePath, movedPath)
+	server.RenameFile(projectID, TestMainBranch, movedPath, finalPath)
+	fm := NewFileManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	rename, err := fm.FindRename(ctx, TestFakeFilePath, TestMainBranch)
+	if err != nil {
+		t.Fatalf("FindRename() unexpected error: %v", err)
+	}
+	if rename == nil || rename.OldPath != TestFakeFilePath || rename.NewPath != finalPath || rename.Commit == nil {
+		t.Errorf("FindRename() = %+v, want a rename to %s", rename, finalPath)
+	}
+
+	rename, err = fm.FindRename(ctx, "missing.yaml", TestMainBranch)
+	if err != nil || rename != nil {
+		t.Errorf("FindRename() for a file that never existed = %+v, %v, want nil", rename, err)
+	}
+}
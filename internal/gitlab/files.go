@@ -5,7 +5,10 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
@@ -15,16 +18,37 @@ import (
 const (
 	// DefaultCommitMessage for file updates
 	DefaultCommitMessage = "Update file via go-tag-updater"
-	// MaxFileSize defines maximum file size for processing
+	// MaxFileSize defines maximum file size for processing; GetFile refuses larger files
 	MaxFileSize = 1024 * 1024 // 1MB
+	// MaxDownloadSize bounds the files DownloadFile streams unless the caller sets a limit
+	MaxDownloadSize = 100 * 1024 * 1024 // 100MB
+	// EncodingBase64 marks file content sent base64-encoded; used for content that is not UTF-8
+	EncodingBase64 = "base64"
 	// DefaultBranch is the default branch name
 	DefaultBranch = "main"
+	// MaxTreeEntries bounds the entries one repository tree listing returns
+	MaxTreeEntries = 10000
+	// TreeEntryBlob is the tree entry type of files
+	TreeEntryBlob = "blob"
+	// TreeEntryTree is the tree entry type of directories
+	TreeEntryTree = "tree"
 )
 
 // FileManager handles repository file operations
 type FileManager struct {
-	client    *gitlab.Client
+	api       FileAPI
 	projectID interface{}
+
+	// Repository trees already listed, by treeKey; a file manager lives for one run
+	treeMu sync.Mutex
+	trees  map[treeKey][]*gitlab.TreeNode
+}
+
+// treeKey identifies one repository tree listing
+type treeKey struct {
+	path      string
+	branch    string
+	recursive bool
 }
 
 // FileInfo represents file information
@@ -50,8 +74,13 @@ type FileUpdateOptions struct {
 
 // NewFileManager creates a new file manager
 func NewFileManager(client *gitlab.Client, projectID interface{}) *FileManager {
+	return NewFileManagerWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewFileManagerWithAPI creates a new file manager on top of the given API implementation
+func NewFileManagerWithAPI(api FileAPI, projectID interface{}) *FileManager {
 	return &FileManager{
-		client:    client,
+		api:       api,
 		projectID: projectID,
 	}
 }
@@ -70,9 +99,15 @@ func (fm *FileManager) GetFile(ctx context.Context, filePath, branch string) (*F
 		Ref: gitlab.Ptr(branch),
 	}
 
-	file, _, err := fm.client.RepositoryFiles.GetFile(fm.projectID, filePath, opts)
+	file, _, err := fm.api.GetFile(fm.projectID, filePath, opts, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, errors.NewAPIError(fmt.Sprintf("failed to get file %s: %v", filePath, err))
+		return nil, errors.NewAppErrorWithCause(errors.ErrCodeAPIError, errors.CategoryAPI,
+			fmt.Sprintf("failed to get file %s: %v", filePath, err), err)
+	}
+
+	if file.Size > MaxFileSize {
+		return nil, errors.NewValidationError(fmt.Sprintf("file %s is too large: %d bytes (max %d)",
+			filePath, file.Size, MaxFileSize))
 	}
 
 	// Decode base64 content
@@ -118,9 +153,11 @@ func (fm *FileManager) UpdateFile(ctx context.Context, filePath string, opts *Fi
 	_, err := fm.GetFile(ctx, filePath, opts.Branch)
 	fileExists := err == nil
 
+	content, encoding := encodeContent(opts.Content)
 	updateOpts := &gitlab.UpdateFileOptions{
 		Branch:        gitlab.Ptr(opts.Branch),
-		Content:       gitlab.Ptr(opts.Content),
+		Content:       gitlab.Ptr(content),
+		Encoding:      encoding,
 		CommitMessage: gitlab.Ptr(opts.CommitMessage),
 	}
 
@@ -139,18 +176,19 @@ func (fm *FileManager) UpdateFile(ctx context.Context, filePath string, opts *Fi
 
 	if fileExists {
 		// Update existing file
-		fileInfo, response, err = fm.client.RepositoryFiles.UpdateFile(fm.projectID, filePath, updateOpts)
+		fileInfo, response, err = fm.api.UpdateFile(fm.projectID, filePath, updateOpts, gitlab.WithContext(ctx))
 	} else {
 		// Create new file
 		createOpts := &gitlab.CreateFileOptions{
 			Branch:        updateOpts.Branch,
 			Content:       updateOpts.Content,
+			Encoding:      updateOpts.Encoding,
 			CommitMessage: updateOpts.CommitMessage,
 			AuthorEmail:   updateOpts.AuthorEmail,
 			AuthorName:    updateOpts.AuthorName,
 			StartBranch:   updateOpts.StartBranch,
 		}
-		fileInfo, response, err = fm.client.RepositoryFiles.CreateFile(fm.projectID, filePath, createOpts)
+		fileInfo, response, err = fm.api.CreateFile(fm.projectID, filePath, createOpts, gitlab.WithContext(ctx))
 	}
 
 	if err != nil {
@@ -164,6 +202,137 @@ func (fm *FileManager) UpdateFile(ctx context.Context, filePath string, opts *Fi
 	return fileInfo, nil
 }
 
+// DownloadFile streams the raw content of a file to w without holding it in memory,
+// so files beyond MaxFileSize can be read. The download is aborted with a clear
+// error once it exceeds maxSize bytes, MaxDownloadSize when maxSize is not positive.
+// It returns the number of bytes written.
+func (fm *FileManager) DownloadFile(
+	ctx context.Context,
+	filePath, branch string,
+	w io.Writer,
+	maxSize int64,
+) (int64, error) {
+	if filePath == "" {
+		return 0, errors.NewValidationError("file path cannot be empty")
+	}
+	if w == nil {
+		return 0, errors.NewValidationError("download writer cannot be nil")
+	}
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	if maxSize <= 0 {
+		maxSize = MaxDownloadSize
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limited := &limitedWriter{w: w, remaining: maxSize, abort: cancel}
+
+	opts := &gitlab.GetRawFileOptions{Ref: gitlab.Ptr(branch)}
+	_, err := fm.api.StreamRawFile(ctx, fm.projectID, filePath, opts, limited)
+	if limited.tooLarge {
+		return limited.written, errors.NewValidationError(fmt.Sprintf("file %s is too large: over %d bytes",
+			filePath, maxSize))
+	}
+	if err != nil {
+		return limited.written, errors.NewAPIError(fmt.Sprintf("failed to download file %s: %v", filePath, err))
+	}
+	return limited.written, nil
+}
+
+// ListTree lists the files and directories below path on a branch, the repository
+// root when path is empty, descending into subdirectories when recursive. Listings
+// are cached for the lifetime of the file manager, so callers must not modify the
+// returned entries.
+func (fm *FileManager) ListTree(
+	ctx context.Context,
+	path, branch string,
+	recursive bool,
+) ([]*gitlab.TreeNode, error) {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	path = strings.Trim(path, "/")
+	key := treeKey{path: path, branch: branch, recursive: recursive}
+
+	fm.treeMu.Lock()
+	defer fm.treeMu.Unlock()
+	if nodes, ok := fm.trees[key]; ok {
+		return nodes, nil
+	}
+
+	opts := &gitlab.ListTreeOptions{Ref: gitlab.Ptr(branch), Recursive: gitlab.Ptr(recursive)}
+	if path != "" {
+		opts.Path = gitlab.Ptr(path)
+	}
+	nodes, err := collectPages(ctx, MaxTreeEntries, MaxPageSize,
+		func(page gitlab.ListOptions) ([]*gitlab.TreeNode, *gitlab.Response, error) {
+			opts.ListOptions = page
+			return fm.api.ListTree(fm.projectID, opts, gitlab.WithContext(ctx))
+		})
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to list repository tree of %s at %q: %v",
+			branch, path, err))
+	}
+
+	if fm.trees == nil {
+		fm.trees = make(map[treeKey][]*gitlab.TreeNode)
+	}
+	fm.trees[key] = nodes
+	return nodes, nil
+}
+
+// ListFiles returns the paths of every file on a branch, sorted as the repository
+// tree API lists them
+func (fm *FileManager) ListFiles(ctx context.Context, branch string) ([]string, error) {
+	nodes, err := fm.ListTree(ctx, "", branch, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, node := range nodes {
+		if node.Type == TreeEntryBlob {
+			files = append(files, node.Path)
+		}
+	}
+	return files, nil
+}
+
+// limitedWriter passes up to remaining bytes to w and aborts the download beyond them
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	written   int64
+	tooLarge  bool
+	abort     context.CancelFunc
+}
+
+// Write implements io.Writer
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.remaining {
+		lw.tooLarge = true
+		lw.abort()
+		return 0, io.ErrShortWrite
+	}
+
+	n, err := lw.w.Write(p)
+	lw.remaining -= int64(n)
+	lw.written += int64(n)
+	return n, err
+}
+
+// encodeContent returns content as sent to the API: as is when it is UTF-8, otherwise
+// base64-encoded together with the base64 encoding, so binary and legacy-encoded
+// files are written byte for byte
+func encodeContent(content string) (string, *string) {
+	if utf8.ValidString(content) {
+		return content, nil
+	}
+	return base64.StdEncoding.EncodeToString([]byte(content)), gitlab.Ptr(EncodingBase64)
+}
+
 // DeleteFile deletes a file from repository
 func (fm *FileManager) DeleteFile(ctx context.Context, filePath, branch, commitMessage string) error {
 	if filePath == "" {
@@ -183,7 +352,7 @@ func (fm *FileManager) DeleteFile(ctx context.Context, filePath, branch, commitM
 		CommitMessage: gitlab.Ptr(commitMessage),
 	}
 
-	_, err := fm.client.RepositoryFiles.DeleteFile(fm.projectID, filePath, opts)
+	_, err := fm.api.DeleteFile(fm.projectID, filePath, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return errors.NewAPIError(fmt.Sprintf("failed to delete file %s: %v", filePath, err))
 	}
@@ -297,7 +466,7 @@ func (fm *FileManager) GetFileHistory(ctx context.Context, filePath, branch stri
 		},
 	}
 
-	commits, _, err := fm.client.Commits.ListCommits(fm.projectID, opts)
+	commits, _, err := fm.api.ListCommits(fm.projectID, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to get file history for %s: %v", filePath, err))
 	}
@@ -0,0 +1,1045 @@
+package gitlabtest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+)
+
+const (
+	// mergeRequestsPath is the merge request collection below a project
+	mergeRequestsPath = APIPrefix + "/projects/{id}/merge_requests"
+	// stateAll selects merge requests in any state
+	stateAll = "all"
+	// Merge request states
+	stateOpened = "opened"
+	stateClosed = "closed"
+	stateMerged = "merged"
+)
+
+// routes registers the served endpoints behind the request recorder and failure injector
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET "+APIPrefix+"/user", s.handleCurrentUser)
+	mux.HandleFunc("GET "+APIPrefix+"/personal_access_tokens/self", s.handleGetToken)
+	mux.HandleFunc("GET "+APIPrefix+"/projects", s.handleListProjects)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}", s.handleGetProject)
+
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/branches", s.handleListBranches)
+	mux.HandleFunc("POST "+APIPrefix+"/projects/{id}/repository/branches", s.handleCreateBranch)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/branches/{branch}", s.handleGetBranch)
+	mux.HandleFunc("DELETE "+APIPrefix+"/projects/{id}/repository/branches/{branch}", s.handleDeleteBranch)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/protected_branches", s.handleListProtectedBranches)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/push_rule", s.handleGetPushRules)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/members/all/{user}", s.handleGetMember)
+
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/files/{file}", s.handleGetFile)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/files/{file}/raw", s.handleGetRawFile)
+	mux.HandleFunc("POST "+APIPrefix+"/projects/{id}/repository/files/{file}", s.handleWriteFile)
+	mux.HandleFunc("PUT "+APIPrefix+"/projects/{id}/repository/files/{file}", s.handleWriteFile)
+	mux.HandleFunc("DELETE "+APIPrefix+"/projects/{id}/repository/files/{file}", s.handleDeleteFile)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/tree", s.handleListTree)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/commits", s.handleListCommits)
+	mux.HandleFunc("POST "+APIPrefix+"/projects/{id}/repository/commits", s.handleCreateCommit)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/commits/{sha}/diff", s.handleGetCommitDiff)
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/repository/compare", s.handleCompare)
+
+	mux.HandleFunc("GET "+APIPrefix+"/projects/{id}/releases/{tag}", s.handleGetRelease)
+	mux.HandleFunc("POST "+APIPrefix+"/projects/{id}/issues/{iid}/notes", s.handleCreateIssueNote)
+
+	mux.HandleFunc("GET "+mergeRequestsPath, s.handleListMergeRequests)
+	mux.HandleFunc("POST "+mergeRequestsPath, s.handleCreateMergeRequest)
+	mux.HandleFunc("GET "+mergeRequestsPath+"/{iid}", s.handleGetMergeRequest)
+	mux.HandleFunc("GET "+mergeRequestsPath+"/{iid}/diffs", s.handleListMergeRequestDiffs)
+	mux.HandleFunc("PUT "+mergeRequestsPath+"/{iid}", s.handleUpdateMergeRequest)
+	mux.HandleFunc("PUT "+mergeRequestsPath+"/{iid}/merge", s.handleAcceptMergeRequest)
+	mux.HandleFunc("PUT "+mergeRequestsPath+"/{iid}/rebase", s.handleRebaseMergeRequest)
+	mux.HandleFunc("POST "+mergeRequestsPath+"/{iid}/notes", s.handleCreateNote)
+	mux.HandleFunc("GET "+mergeRequestsPath+"/{iid}/approvals", s.handleGetApprovals)
+	mux.HandleFunc("POST "+mergeRequestsPath+"/{iid}/approve", s.handleApproveMergeRequest)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, r.Method+" "+r.URL.EscapedPath())
+		if status := s.matchFailure(r); status != 0 {
+			writeError(w, status, fmt.Sprintf("%d %s", status, http.StatusText(status)))
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// project resolves the project of a request or writes a 404 response
+func (s *Server) project(w http.ResponseWriter, r *http.Request) *project {
+	p := s.findProject(pathValue(r, "id"))
+	if p == nil {
+		writeError(w, http.StatusNotFound, "404 Project Not Found")
+	}
+	return p
+}
+
+func (s *Server) handleCurrentUser(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &gitlab.User{ID: currentUserID, Username: s.currentLogin})
+}
+
+func (s *Server) handleGetToken(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &gitlab.PersonalAccessToken{
+		ID:     1,
+		Name:   "go-tag-updater",
+		Active: true,
+		Scopes: s.tokenScopes,
+		UserID: currentUserID,
+	})
+}
+
+func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+
+	ids := make([]int, 0, len(s.projects))
+	for id := range s.projects {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	result := make([]*gitlab.Project, 0, len(ids))
+	for _, id := range ids {
+		info := s.projects[id].info
+		if search == "" || strings.Contains(info.PathWithNamespace, search) {
+			result = append(result, info)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, paginate(w, r, result))
+}
+
+func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
+	if p := s.project(w, r); p != nil {
+		writeJSON(w, http.StatusOK, p.info)
+	}
+}
+
+func (s *Server) handleListBranches(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	search := r.URL.Query().Get("search")
+	names := make([]string, 0, len(p.branches))
+	for name := range p.branches {
+		if search == "" || strings.Contains(name, search) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make([]*gitlab.Branch, 0, len(names))
+	for _, name := range names {
+		result = append(result, p.branchJSON(name))
+	}
+
+	writeJSON(w, http.StatusOK, paginate(w, r, result))
+}
+
+func (s *Server) handleCreateBranch(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	var opts gitlab.CreateBranchOptions
+	if err := decodeBody(r, &opts); err != nil || opts.Branch == nil || opts.Ref == nil {
+		writeError(w, http.StatusBadRequest, "branch and ref are required")
+		return
+	}
+
+	if _, exists := p.branches[*opts.Branch]; exists {
+		writeError(w, http.StatusBadRequest, "Branch already exists")
+		return
+	}
+
+	source := p.branches[*opts.Ref]
+	if source == nil {
+		writeError(w, http.StatusBadRequest, "Invalid reference name: "+*opts.Ref)
+		return
+	}
+
+	p.branches[*opts.Branch] = &branch{commit: source.commit, files: copyFiles(source.files)}
+	writeJSON(w, http.StatusCreated, p.branchJSON(*opts.Branch))
+}
+
+func (s *Server) handleGetBranch(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	name := pathValue(r, "branch")
+	if _, ok := p.branches[name]; !ok {
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p.branchJSON(name))
+}
+
+func (s *Server) handleDeleteBranch(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	name := pathValue(r, "branch")
+	b, ok := p.branches[name]
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, "404 Branch Not Found")
+	case b.protected || name == p.info.DefaultBranch:
+		writeError(w, http.StatusForbidden, "403 Forbidden")
+	default:
+		delete(p.branches, name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleListProtectedBranches(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	names := make([]string, 0, len(p.branches))
+	for name, b := range p.branches {
+		if b.protected {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make([]*gitlab.ProtectedBranch, 0, len(names)+len(p.protectedBranches))
+	for _, name := range names {
+		result = append(result, &gitlab.ProtectedBranch{Name: name})
+	}
+	result = append(result, p.protectedBranches...)
+	for i, protected := range result {
+		protected.ID = i + 1
+	}
+
+	writeJSON(w, http.StatusOK, paginate(w, r, result))
+}
+
+func (s *Server) handleGetPushRules(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	if p.pushRules == nil {
+		writeError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p.pushRules)
+}
+
+func (s *Server) handleGetMember(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	if r.PathValue("user") != strconv.Itoa(currentUserID) || p.memberAccess == gitlab.NoPermissions {
+		writeError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &gitlab.ProjectMember{
+		ID:          currentUserID,
+		Username:    s.currentLogin,
+		AccessLevel: p.memberAccess,
+	})
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	filePath := pathValue(r, "file")
+	ref := r.URL.Query().Get("ref")
+	b := p.branches[ref]
+	if b == nil {
+		writeError(w, http.StatusNotFound, "404 File Not Found")
+		return
+	}
+
+	content, ok := b.files[filePath]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 File Not Found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &gitlab.File{
+		FileName:     path.Base(filePath),
+		FilePath:     filePath,
+		Size:         len(content),
+		Encoding:     "base64",
+		Content:      base64.StdEncoding.EncodeToString([]byte(content)),
+		Ref:          ref,
+		BlobID:       hashID(content),
+		CommitID:     b.commit.ID,
+		LastCommitID: b.commit.ID,
+	})
+}
+
+// handleGetRawFile serves the plain content of a file
+func (s *Server) handleGetRawFile(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	b := p.branches[r.URL.Query().Get("ref")]
+	if b == nil {
+		writeError(w, http.StatusNotFound, "404 File Not Found")
+		return
+	}
+	content, ok := b.files[pathValue(r, "file")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 File Not Found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(content))
+}
+
+// decodeContent returns written file content, decoding it when sent base64-encoded
+func decodeContent(content string, encoding *string) (string, error) {
+	if encoding == nil || *encoding == "text" {
+		return content, nil
+	}
+	if *encoding != "base64" {
+		return "", fmt.Errorf("unsupported encoding %q", *encoding)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 content: %v", err)
+	}
+	return string(decoded), nil
+}
+
+// handleWriteFile serves both file creation (POST) and file update (PUT)
+func (s *Server) handleWriteFile(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	var opts gitlab.CreateFileOptions
+	if err := decodeBody(r, &opts); err != nil || opts.Branch == nil || opts.Content == nil || opts.CommitMessage == nil {
+		writeError(w, http.StatusBadRequest, "branch, content and commit_message are required")
+		return
+	}
+
+	b := p.branches[*opts.Branch]
+	if b == nil && opts.StartBranch != nil && p.branches[*opts.StartBranch] != nil {
+		start := p.branches[*opts.StartBranch]
+		b = &branch{commit: start.commit, files: copyFiles(start.files)}
+		p.branches[*opts.Branch] = b
+	}
+	if b == nil {
+		writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+		return
+	}
+
+	filePath := pathValue(r, "file")
+	_, exists := b.files[filePath]
+	if r.Method == http.MethodPost && exists {
+		writeError(w, http.StatusBadRequest, "A file with this name already exists")
+		return
+	}
+	if r.Method == http.MethodPut && !exists {
+		writeError(w, http.StatusBadRequest, "A file with this name doesn't exist")
+		return
+	}
+
+	content, err := decodeContent(*opts.Content, opts.Encoding)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	b.files[filePath] = content
+	b.commit = s.newCommit(p, *opts.CommitMessage)
+
+	status := http.StatusOK
+	if r.Method == http.MethodPost {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, &gitlab.FileInfo{FilePath: filePath, Branch: *opts.Branch})
+}
+
+func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	b := p.branches[query.Get("branch")]
+	filePath := pathValue(r, "file")
+	if b == nil {
+		writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+		return
+	}
+	if _, ok := b.files[filePath]; !ok {
+		writeError(w, http.StatusBadRequest, "A file with this name doesn't exist")
+		return
+	}
+
+	delete(b.files, filePath)
+	b.commit = s.newCommit(p, query.Get("commit_message"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListTree lists the files and directories below path on a branch, sorted by
+// path; directories are derived from the file paths
+func (s *Server) handleListTree(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	ref := query.Get("ref")
+	if ref == "" {
+		ref = p.info.DefaultBranch
+	}
+	b := p.branches[ref]
+	if b == nil {
+		writeError(w, http.StatusNotFound, "404 Tree Not Found")
+		return
+	}
+
+	prefix := strings.Trim(query.Get("path"), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	recursive := query.Get("recursive") == "true"
+
+	entries := make(map[string]string)
+	for filePath := range b.files {
+		rest, ok := strings.CutPrefix(filePath, prefix)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(rest, "/")
+		for depth := range parts {
+			if depth > 0 && !recursive {
+				break
+			}
+			entryType := "tree"
+			if depth == len(parts)-1 {
+				entryType = "blob"
+			}
+			entries[prefix+strings.Join(parts[:depth+1], "/")] = entryType
+		}
+	}
+
+	paths := make([]string, 0, len(entries))
+	for entryPath := range entries {
+		paths = append(paths, entryPath)
+	}
+	sort.Strings(paths)
+
+	result := make([]*gitlab.TreeNode, 0, len(paths))
+	for _, entryPath := range paths {
+		result = append(result, &gitlab.TreeNode{
+			ID:   entryPath,
+			Name: path.Base(entryPath),
+			Type: entries[entryPath],
+			Path: entryPath,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, paginate(w, r, result))
+}
+
+// handleListCommits returns the head commit of the requested ref, or for a path
+// that was renamed away, the commit renaming it
+func (s *Server) handleListCommits(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	ref := r.URL.Query().Get("ref_name")
+	if ref == "" {
+		ref = p.info.DefaultBranch
+	}
+
+	b := p.branches[ref]
+	if b == nil {
+		writeError(w, http.StatusNotFound, "404 Reference Not Found")
+		return
+	}
+
+	result := []*gitlab.Commit{}
+	filePath := r.URL.Query().Get("path")
+	switch {
+	case filePath == "" || b.files[filePath] != "":
+		result = append(result, b.commit)
+	case p.renamedBy[filePath] != nil:
+		result = append(result, p.renamedBy[filePath])
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleCreateCommit applies the file actions of a commit; when any action fails
+// the branch is left unchanged
+func (s *Server) handleCreateCommit(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	var opts gitlab.CreateCommitOptions
+	if err := decodeBody(r, &opts); err != nil || opts.Branch == nil || opts.CommitMessage == nil ||
+		len(opts.Actions) == 0 {
+		writeError(w, http.StatusBadRequest, "branch, commit_message and actions are required")
+		return
+	}
+
+	b := p.branches[*opts.Branch]
+	start := b
+	if b == nil && opts.StartBranch != nil {
+		start = p.branches[*opts.StartBranch]
+	}
+	if start == nil {
+		writeError(w, http.StatusBadRequest, "You can only create or edit files when you are on a branch")
+		return
+	}
+
+	files := copyFiles(start.files)
+	diffs := make([]*gitlab.Diff, 0, len(opts.Actions))
+	for _, action := range opts.Actions {
+		if action.Action == nil || action.FilePath == nil {
+			writeError(w, http.StatusBadRequest, "action and file_path are required")
+			return
+		}
+		filePath := *action.FilePath
+		_, exists := files[filePath]
+
+		switch *action.Action {
+		case gitlab.FileCreate, gitlab.FileUpdate:
+			if *action.Action == gitlab.FileCreate && exists {
+				writeError(w, http.StatusBadRequest, "A file with this name already exists")
+				return
+			}
+			if *action.Action == gitlab.FileUpdate && !exists {
+				writeError(w, http.StatusBadRequest, "A file with this name doesn't exist")
+				return
+			}
+			if action.Content == nil {
+				writeError(w, http.StatusBadRequest, "content is required")
+				return
+			}
+			content, err := decodeContent(*action.Content, action.Encoding)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			files[filePath] = content
+		case gitlab.FileDelete:
+			if !exists {
+				writeError(w, http.StatusBadRequest, "A file with this name doesn't exist")
+				return
+			}
+			delete(files, filePath)
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported action %q", *action.Action))
+			return
+		}
+		diffs = append(diffs, &gitlab.Diff{
+			OldPath:     filePath,
+			NewPath:     filePath,
+			NewFile:     *action.Action == gitlab.FileCreate,
+			DeletedFile: *action.Action == gitlab.FileDelete,
+		})
+	}
+
+	if b == nil {
+		b = &branch{}
+		p.branches[*opts.Branch] = b
+	}
+	b.files = files
+	b.commit = s.newCommit(p, *opts.CommitMessage)
+	p.diffs[b.commit.ID] = diffs
+
+	writeJSON(w, http.StatusCreated, b.commit)
+}
+
+// handleGetCommitDiff returns the recorded file changes of a commit
+func (s *Server) handleGetCommitDiff(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	diffs := p.diffs[pathValue(r, "sha")]
+	if diffs == nil {
+		diffs = []*gitlab.Diff{}
+	}
+	writeJSON(w, http.StatusOK, diffs)
+}
+
+// handleCompare compares the file trees of two branches; branches have no history, so
+// the head commit of "to" is the only commit reported when the heads differ
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	fromName, toName := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	from, to := p.branches[fromName], p.branches[toName]
+	if from == nil || to == nil {
+		writeError(w, http.StatusNotFound, "404 Ref Not Found")
+		return
+	}
+
+	compare := &gitlab.Compare{
+		Commit:         to.commit,
+		Commits:        []*gitlab.Commit{},
+		CompareSameRef: fromName == toName,
+	}
+	if from.commit.ID != to.commit.ID {
+		compare.Commits = append(compare.Commits, to.commit)
+	}
+
+	compare.Diffs = branchDiffs(from, to)
+
+	writeJSON(w, http.StatusOK, compare)
+}
+
+// branchDiffs returns the diffs of the files that differ between two branches, sorted by path
+func branchDiffs(from, to *branch) []*gitlab.Diff {
+	diffs := []*gitlab.Diff{}
+	paths := make([]string, 0, len(from.files)+len(to.files))
+	for filePath := range from.files {
+		paths = append(paths, filePath)
+	}
+	for filePath := range to.files {
+		if _, ok := from.files[filePath]; !ok {
+			paths = append(paths, filePath)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, filePath := range paths {
+		oldContent, inFrom := from.files[filePath]
+		newContent, inTo := to.files[filePath]
+		if inFrom && inTo && oldContent == newContent {
+			continue
+		}
+		diffs = append(diffs, &gitlab.Diff{
+			OldPath:     filePath,
+			NewPath:     filePath,
+			NewFile:     !inFrom,
+			DeletedFile: !inTo,
+			Diff:        diff.Unified("a/"+filePath, "b/"+filePath, oldContent, newContent, diff.DefaultContextLines),
+		})
+	}
+
+	return diffs
+}
+
+func (s *Server) handleListMergeRequests(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	sourceBranch := query.Get("source_branch")
+	targetBranch := query.Get("target_branch")
+
+	result := make([]*gitlab.BasicMergeRequest, 0, len(p.mergeRequests))
+	for iid := p.nextIID - 1; iid > 0; iid-- {
+		mr, ok := p.mergeRequests[iid]
+		if !ok ||
+			(state != "" && state != stateAll && mr.State != state) ||
+			(sourceBranch != "" && mr.SourceBranch != sourceBranch) ||
+			(targetBranch != "" && mr.TargetBranch != targetBranch) {
+			continue
+		}
+		basic := mr.BasicMergeRequest
+		result = append(result, &basic)
+	}
+
+	writeJSON(w, http.StatusOK, paginate(w, r, result))
+}
+
+func (s *Server) handleCreateMergeRequest(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+
+	var opts gitlab.CreateMergeRequestOptions
+	if err := decodeBody(r, &opts); err != nil || opts.SourceBranch == nil || opts.TargetBranch == nil || opts.Title == nil {
+		writeError(w, http.StatusBadRequest, "source_branch, target_branch and title are required")
+		return
+	}
+
+	source := p.branches[*opts.SourceBranch]
+	if source == nil || p.branches[*opts.TargetBranch] == nil {
+		writeError(w, http.StatusUnprocessableEntity, "Source branch or target branch does not exist")
+		return
+	}
+
+	for _, existing := range p.mergeRequests {
+		if existing.State == stateOpened && existing.SourceBranch == *opts.SourceBranch &&
+			existing.TargetBranch == *opts.TargetBranch {
+			writeError(w, http.StatusConflict,
+				fmt.Sprintf("Another open merge request already exists for this source branch: !%d", existing.IID))
+			return
+		}
+	}
+
+	iid := p.nextIID
+	p.nextIID++
+
+	mr := &gitlab.MergeRequest{}
+	mr.ID = s.newID()
+	mr.IID = iid
+	mr.ProjectID = p.info.ID
+	mr.Title = *opts.Title
+	mr.Draft = isDraftTitle(mr.Title)
+	mr.SourceBranch = *opts.SourceBranch
+	mr.TargetBranch = *opts.TargetBranch
+	mr.State = stateOpened
+	mr.SHA = source.commit.ID
+	mr.HasConflicts = p.conflictNewMRs
+	mr.DetailedMergeStatus = detailedMergeStatus(mr)
+	mr.WebURL = fmt.Sprintf("%s/-/merge_requests/%d", p.info.WebURL, iid)
+	if opts.Description != nil {
+		mr.Description = *opts.Description
+	}
+	if opts.Labels != nil {
+		mr.Labels = append(mr.Labels, *opts.Labels...)
+	}
+	if opts.RemoveSourceBranch != nil {
+		mr.ForceRemoveSourceBranch = *opts.RemoveSourceBranch
+	}
+
+	p.mergeRequests[iid] = mr
+	writeJSON(w, http.StatusCreated, mr)
+}
+
+// mergeRequest resolves the merge request of a request or writes a 404 response
+func (s *Server) mergeRequest(w http.ResponseWriter, r *http.Request) (*project, *gitlab.MergeRequest) {
+	p := s.project(w, r)
+	if p == nil {
+		return nil, nil
+	}
+
+	iid, err := strconv.Atoi(r.PathValue("iid"))
+	mr := p.mergeRequests[iid]
+	if err != nil || mr == nil {
+		writeError(w, http.StatusNotFound, "404 Not found")
+		return nil, nil
+	}
+
+	return p, mr
+}
+
+// isDraftTitle reports whether GitLab treats a merge request title as a draft
+func isDraftTitle(title string) bool {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	return strings.HasPrefix(lower, "draft:") || strings.HasPrefix(lower, "[draft]") ||
+		strings.HasPrefix(lower, "(draft)")
+}
+
+func (s *Server) handleGetMergeRequest(w http.ResponseWriter, r *http.Request) {
+	if _, mr := s.mergeRequest(w, r); mr != nil {
+		writeJSON(w, http.StatusOK, mr)
+	}
+}
+
+// handleListMergeRequestDiffs pages through the files the source branch of a merge
+// request changes over its target branch
+func (s *Server) handleListMergeRequestDiffs(w http.ResponseWriter, r *http.Request) {
+	p, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+
+	diffs := []*gitlab.Diff{}
+	if source, target := p.branches[mr.SourceBranch], p.branches[mr.TargetBranch]; source != nil && target != nil {
+		diffs = branchDiffs(target, source)
+	}
+
+	page := paginate(w, r, diffs)
+	result := make([]*gitlab.MergeRequestDiff, 0, len(page))
+	for _, d := range page {
+		result = append(result, &gitlab.MergeRequestDiff{
+			OldPath:     d.OldPath,
+			NewPath:     d.NewPath,
+			Diff:        d.Diff,
+			NewFile:     d.NewFile,
+			DeletedFile: d.DeletedFile,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleUpdateMergeRequest(w http.ResponseWriter, r *http.Request) {
+	_, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+
+	var opts gitlab.UpdateMergeRequestOptions
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Title != nil {
+		mr.Title = *opts.Title
+		mr.Draft = isDraftTitle(mr.Title)
+	}
+	if opts.Description != nil {
+		mr.Description = *opts.Description
+	}
+	if opts.AddLabels != nil {
+		for _, label := range *opts.AddLabels {
+			if !slices.Contains(mr.Labels, label) {
+				mr.Labels = append(mr.Labels, label)
+			}
+		}
+	}
+	if opts.RemoveLabels != nil {
+		mr.Labels = slices.DeleteFunc(mr.Labels, func(label string) bool {
+			return slices.Contains(*opts.RemoveLabels, label)
+		})
+	}
+	if opts.StateEvent != nil {
+		switch *opts.StateEvent {
+		case "close":
+			mr.State = stateClosed
+		case "reopen":
+			mr.State = stateOpened
+		default:
+			writeError(w, http.StatusBadRequest, "state_event does not have a valid value")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, mr)
+}
+
+// handleRebaseMergeRequest rebases the source branch, which resolves the conflicts
+// of the merge request unless the project is set to keep them
+func (s *Server) handleRebaseMergeRequest(w http.ResponseWriter, r *http.Request) {
+	p, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+
+	p.rebases[mr.IID]++
+	if !p.conflictsSurviveRebase {
+		mr.HasConflicts = false
+		mr.DetailedMergeStatus = detailedMergeStatus(mr)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]bool{"rebase_in_progress": true})
+}
+
+// detailedMergeStatus returns the detailed merge status GitLab reports for a merge request
+func detailedMergeStatus(mr *gitlab.MergeRequest) string {
+	if mr.HasConflicts {
+		return "conflict"
+	}
+	return "mergeable"
+}
+
+// handleAcceptMergeRequest merges immediately or, when requested, schedules the
+// merge for when the pipeline succeeds
+func (s *Server) handleAcceptMergeRequest(w http.ResponseWriter, r *http.Request) {
+	p, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+
+	if mr.State != stateOpened {
+		writeError(w, http.StatusMethodNotAllowed, "405 Method Not Allowed")
+		return
+	}
+
+	var opts gitlab.AcceptMergeRequestOptions
+	if err := decodeBody(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.ShouldRemoveSourceBranch != nil {
+		mr.ShouldRemoveSourceBranch = *opts.ShouldRemoveSourceBranch
+	}
+	if opts.MergeWhenPipelineSucceeds != nil && *opts.MergeWhenPipelineSucceeds {
+		mr.MergeWhenPipelineSucceeds = true
+		writeJSON(w, http.StatusOK, mr)
+		return
+	}
+
+	source, target := p.branches[mr.SourceBranch], p.branches[mr.TargetBranch]
+	if source == nil || target == nil {
+		writeError(w, http.StatusNotAcceptable, "Branch cannot be merged")
+		return
+	}
+
+	for filePath, content := range source.files {
+		target.files[filePath] = content
+	}
+	target.commit = s.newCommit(p, fmt.Sprintf("Merge branch '%s' into '%s'", mr.SourceBranch, mr.TargetBranch))
+	mr.State = stateMerged
+	mr.MergeCommitSHA = target.commit.ID
+	if mr.ShouldRemoveSourceBranch || mr.ForceRemoveSourceBranch {
+		delete(p.branches, mr.SourceBranch)
+	}
+
+	writeJSON(w, http.StatusOK, mr)
+}
+
+func (s *Server) handleCreateNote(w http.ResponseWriter, r *http.Request) {
+	p, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+
+	var opts gitlab.CreateMergeRequestNoteOptions
+	if err := decodeBody(r, &opts); err != nil || opts.Body == nil || *opts.Body == "" {
+		writeError(w, http.StatusBadRequest, "body is missing")
+		return
+	}
+
+	note := &gitlab.Note{ID: s.newID(), Body: *opts.Body, NoteableID: mr.ID, NoteableIID: mr.IID}
+	p.notes[mr.IID] = append(p.notes[mr.IID], note)
+	mr.UserNotesCount++
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+// branchJSON renders a branch in the API representation
+func (p *project) branchJSON(name string) *gitlab.Branch {
+	b := p.branches[name]
+	return &gitlab.Branch{
+		Name:      name,
+		Commit:    b.commit,
+		Protected: b.protected,
+		Default:   name == p.info.DefaultBranch,
+		CanPush:   !b.protected,
+		WebURL:    fmt.Sprintf("%s/-/tree/%s", p.info.WebURL, name),
+	}
+}
+
+func (s *Server) handleGetRelease(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	release, ok := p.releases[pathValue(r, "tag")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "404 Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, release)
+}
+
+func (s *Server) handleCreateIssueNote(w http.ResponseWriter, r *http.Request) {
+	p := s.project(w, r)
+	if p == nil {
+		return
+	}
+	iid, err := strconv.Atoi(pathValue(r, "iid"))
+	if _, ok := p.issueNotes[iid]; err != nil || !ok {
+		writeError(w, http.StatusNotFound, "404 Issue Not Found")
+		return
+	}
+
+	var opts gitlab.CreateIssueNoteOptions
+	if err := decodeBody(r, &opts); err != nil || opts.Body == nil || *opts.Body == "" {
+		writeError(w, http.StatusBadRequest, "body is missing")
+		return
+	}
+
+	note := &gitlab.Note{ID: s.newID(), Body: *opts.Body, NoteableIID: iid, NoteableType: "Issue"}
+	p.issueNotes[iid] = append(p.issueNotes[iid], note)
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (s *Server) handleGetApprovals(w http.ResponseWriter, r *http.Request) {
+	p, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, p.approvals(mr.IID))
+}
+
+func (s *Server) handleApproveMergeRequest(w http.ResponseWriter, r *http.Request) {
+	p, mr := s.mergeRequest(w, r)
+	if mr == nil {
+		return
+	}
+	username, ok := s.approvers[r.Header.Get("PRIVATE-TOKEN")]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "401 Unauthorized")
+		return
+	}
+	if !slices.Contains(p.approvedBy[mr.IID], username) {
+		p.approvedBy[mr.IID] = append(p.approvedBy[mr.IID], username)
+	}
+	writeJSON(w, http.StatusCreated, p.approvals(mr.IID))
+}
+
+// approvals renders the approval state of a merge request in the API representation
+func (p *project) approvals(iid int) *gitlab.MergeRequestApprovals {
+	approvedBy := p.approvedBy[iid]
+	left := max(p.approvalsRequired-len(approvedBy), 0)
+	approvals := &gitlab.MergeRequestApprovals{
+		IID:               iid,
+		ProjectID:         p.info.ID,
+		Approved:          left == 0,
+		ApprovalsRequired: p.approvalsRequired,
+		ApprovalsLeft:     left,
+		HasApprovalRules:  p.approvalRule != "",
+	}
+	for _, username := range approvedBy {
+		approvals.ApprovedBy = append(approvals.ApprovedBy,
+			&gitlab.MergeRequestApproverUser{User: &gitlab.BasicUser{Username: username}})
+	}
+	if left > 0 && p.approvalRule != "" {
+		approvals.ApprovalRulesLeft = []*gitlab.MergeRequestApprovalRule{
+			{Name: p.approvalRule, ApprovalsRequired: p.approvalsRequired},
+		}
+	}
+	return approvals
+}
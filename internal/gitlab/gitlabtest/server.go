@@ -0,0 +1,597 @@
+// Package gitlabtest provides an in-memory fake of the GitLab REST API for tests.
+//
+// The fake serves the endpoints used by go-tag-updater (projects, branches,
+// repository files, commits, merge requests and notes) over httptest so that
+// managers can be exercised against realistic success and failure responses.
+package gitlabtest
+
+import (
+	"crypto/sha1" // #nosec G505 -- used only to fabricate commit and blob IDs
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// APIPrefix is the path prefix of every served endpoint
+	APIPrefix = "/api/v4"
+	// DefaultBranch is the branch created together with every project
+	DefaultBranch = "main"
+	// InitialCommitTitle is the title of the first commit of every project
+	InitialCommitTitle = "Initial commit"
+	// ShortIDLength defines the length of abbreviated commit IDs
+	ShortIDLength = 8
+	// MaxUnescapeDepth bounds how often a double-escaped path parameter is decoded
+	MaxUnescapeDepth = 3
+	// DefaultPerPage is the page size of paginated lists without a per_page parameter
+	DefaultPerPage = 20
+	// DefaultToken is the token of the clients returned by Client; it belongs to the
+	// author of merge requests, who cannot approve them
+	DefaultToken = "test-token"
+	// currentUserID is the ID of the user the token belongs to
+	currentUserID = 1
+)
+
+// Server is a fake GitLab API backed by in-memory state
+type Server struct {
+	t      testing.TB
+	server *httptest.Server
+
+	mu           sync.Mutex
+	nextID       int
+	projects     map[int]*project
+	failures     []failure
+	requests     []string
+	currentLogin string
+	tokenScopes  []string
+	// approvers maps the tokens allowed to approve merge requests to their usernames
+	approvers map[string]string
+}
+
+// project holds the state of a single fake project
+type project struct {
+	info          *gitlab.Project
+	branches      map[string]*branch
+	mergeRequests map[int]*gitlab.MergeRequest
+	notes         map[int][]*gitlab.Note
+	rebases       map[int]int
+	releases      map[string]*gitlab.Release
+	issueNotes    map[int][]*gitlab.Note
+	approvedBy    map[int][]string
+	nextIID       int
+
+	// File renames: the commit that moved each old path and the diffs of such commits
+	renamedBy map[string]*gitlab.Commit
+	diffs     map[string][]*gitlab.Diff
+
+	// Protection rules added with ProtectBranch, which may be wildcard patterns, and
+	// the push rules served when set
+	protectedBranches []*gitlab.ProtectedBranch
+	pushRules         *gitlab.ProjectPushRules
+	// Role of the token user served by the member API; NoPermissions makes it a non-member
+	memberAccess gitlab.AccessLevelValue
+
+	// Approval rule every merge request must satisfy
+	approvalRule      string
+	approvalsRequired int
+
+	// Merge request conflict simulation
+	conflictNewMRs         bool
+	conflictsSurviveRebase bool
+}
+
+// branch holds the head commit, protection flag and file tree of a branch
+type branch struct {
+	commit    *gitlab.Commit
+	protected bool
+	files     map[string]string
+}
+
+// failure makes matching requests fail with the given status
+type failure struct {
+	method  string
+	pattern string
+	status  int
+	// remaining is how many more requests fail; 0 fails every request
+	remaining int
+}
+
+// NewServer starts a fake GitLab API that is shut down when the test finishes
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:            t,
+		nextID:       1,
+		projects:     make(map[int]*project),
+		currentLogin: "go-tag-updater",
+		tokenScopes:  []string{"api"},
+		approvers:    make(map[string]string),
+	}
+
+	s.server = httptest.NewServer(s.routes())
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// URL returns the base URL of the fake GitLab instance
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns a GitLab API client talking to the fake server without retries
+func (s *Server) Client() *gitlab.Client {
+	s.t.Helper()
+	return s.ClientWithToken(DefaultToken)
+}
+
+// ClientWithToken returns a client like Client authenticating with another token,
+// such as one registered with AddApprover
+func (s *Server) ClientWithToken(token string) *gitlab.Client {
+	s.t.Helper()
+
+	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(s.server.URL), gitlab.WithoutRetries())
+	if err != nil {
+		s.t.Fatalf("failed to create GitLab client: %v", err)
+	}
+
+	return client
+}
+
+// AddProject registers a project with an initial commit on the default branch
+// and returns its numeric ID
+func (s *Server) AddProject(pathWithNamespace string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.newID()
+	p := &project{
+		info: &gitlab.Project{
+			ID:                id,
+			Name:              path.Base(pathWithNamespace),
+			Path:              path.Base(pathWithNamespace),
+			PathWithNamespace: pathWithNamespace,
+			DefaultBranch:     DefaultBranch,
+			WebURL:            s.server.URL + "/" + pathWithNamespace,
+			HTTPURLToRepo:     s.server.URL + "/" + pathWithNamespace + ".git",
+		},
+		branches:      make(map[string]*branch),
+		mergeRequests: make(map[int]*gitlab.MergeRequest),
+		notes:         make(map[int][]*gitlab.Note),
+		rebases:       make(map[int]int),
+		releases:      make(map[string]*gitlab.Release),
+		issueNotes:    make(map[int][]*gitlab.Note),
+		approvedBy:    make(map[int][]string),
+		renamedBy:     make(map[string]*gitlab.Commit),
+		diffs:         make(map[string][]*gitlab.Diff),
+		nextIID:       1,
+		memberAccess:  gitlab.MaintainerPermissions,
+	}
+	p.branches[DefaultBranch] = &branch{commit: s.newCommit(p, InitialCommitTitle), files: make(map[string]string)}
+	s.projects[id] = p
+
+	return id
+}
+
+// SetRepositoryURL points the clone URL of a project at a real repository, such as a
+// local bare repository, for code that pushes with git instead of the API
+func (s *Server) SetRepositoryURL(projectID int, url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mustProject(projectID).info.HTTPURLToRepo = url
+}
+
+// AddBranch creates a branch from ref with the given protection flag
+func (s *Server) AddBranch(projectID int, name, ref string, protected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	source := p.branches[ref]
+	if source == nil {
+		s.t.Fatalf("gitlabtest: unknown ref %q in project %d", ref, projectID)
+	}
+
+	p.branches[name] = &branch{commit: source.commit, protected: protected, files: copyFiles(source.files)}
+}
+
+// ProtectBranch adds a protection rule for a branch name or wildcard pattern such as
+// "release/*" with the roles allowed to push and merge
+func (s *Server) ProtectBranch(projectID int, pattern string, push, merge gitlab.AccessLevelValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	p.protectedBranches = append(p.protectedBranches, &gitlab.ProtectedBranch{
+		Name:              pattern,
+		PushAccessLevels:  []*gitlab.BranchAccessDescription{{AccessLevel: push}},
+		MergeAccessLevels: []*gitlab.BranchAccessDescription{{AccessLevel: merge}},
+	})
+}
+
+// SetAccessLevel sets the role of the token in the project reported with the project
+func (s *Server) SetAccessLevel(projectID int, access gitlab.AccessLevelValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mustProject(projectID).info.Permissions = &gitlab.Permissions{
+		ProjectAccess: &gitlab.ProjectAccess{AccessLevel: access},
+	}
+}
+
+// SetMemberAccess sets the role of the token user served by the member API, which
+// defaults to Maintainer; NoPermissions removes the user from the project
+func (s *Server) SetMemberAccess(projectID int, access gitlab.AccessLevelValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mustProject(projectID).memberAccess = access
+}
+
+// SetTokenScopes sets the scopes reported for the token, which default to api
+func (s *Server) SetTokenScopes(scopes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenScopes = scopes
+}
+
+// SetPushRules sets the push rules of a project; without them the push rule endpoint
+// responds 404 like GitLab editions without push rules
+func (s *Server) SetPushRules(projectID int, rules *gitlab.ProjectPushRules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mustProject(projectID).pushRules = rules
+}
+
+// SetFile stores file content on a branch as a new commit
+func (s *Server) SetFile(projectID int, branchName, filePath, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	b := p.branches[branchName]
+	if b == nil {
+		s.t.Fatalf("gitlabtest: unknown branch %q in project %d", branchName, projectID)
+	}
+
+	b.files[filePath] = content
+	b.commit = s.newCommit(p, "Update "+filePath)
+}
+
+// RenameFile moves a file on a branch as a new commit that the commit history of
+// the old path and the commit diff API report as a rename
+func (s *Server) RenameFile(projectID int, branchName, oldPath, newPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	b := p.branches[branchName]
+	if b == nil {
+		s.t.Fatalf("gitlabtest: unknown branch %q in project %d", branchName, projectID)
+	}
+	content, ok := b.files[oldPath]
+	if !ok {
+		s.t.Fatalf("gitlabtest: unknown file %q on branch %q", oldPath, branchName)
+	}
+
+	delete(b.files, oldPath)
+	b.files[newPath] = content
+	b.commit = s.newCommit(p, fmt.Sprintf("Move %s to %s", oldPath, newPath))
+	p.renamedBy[oldPath] = b.commit
+	p.diffs[b.commit.ID] = []*gitlab.Diff{{OldPath: oldPath, NewPath: newPath, RenamedFile: true}}
+}
+
+// AddRelease publishes a release of tag with the given notes as its description
+func (s *Server) AddRelease(projectID int, tag, notes string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mustProject(projectID).releases[tag] = &gitlab.Release{TagName: tag, Name: tag, Description: notes}
+}
+
+// File returns the content of a file on a branch and whether it exists
+func (s *Server) File(projectID int, branchName, filePath string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.mustProject(projectID).branches[branchName]
+	if b == nil {
+		return "", false
+	}
+
+	content, ok := b.files[filePath]
+	return content, ok
+}
+
+// BranchExists reports whether a branch exists in the project
+func (s *Server) BranchExists(projectID int, branchName string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.mustProject(projectID).branches[branchName]
+	return ok
+}
+
+// MergeRequests returns copies of all merge requests of a project ordered by IID
+func (s *Server) MergeRequests(projectID int) []gitlab.MergeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	result := make([]gitlab.MergeRequest, 0, len(p.mergeRequests))
+	for iid := 1; iid < p.nextIID; iid++ {
+		if mr, ok := p.mergeRequests[iid]; ok {
+			result = append(result, *mr)
+		}
+	}
+
+	return result
+}
+
+// Notes returns the bodies of the notes left on a merge request
+func (s *Server) Notes(projectID, mrIID int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bodies []string
+	for _, note := range s.mustProject(projectID).notes[mrIID] {
+		bodies = append(bodies, note.Body)
+	}
+
+	return bodies
+}
+
+// AddIssue registers an issue that can be commented on
+func (s *Server) AddIssue(projectID, iid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	if _, ok := p.issueNotes[iid]; !ok {
+		p.issueNotes[iid] = []*gitlab.Note{}
+	}
+}
+
+// SetApprovalRule makes the merge requests of a project require approvals from a rule
+func (s *Server) SetApprovalRule(projectID int, name string, required int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	p.approvalRule = name
+	p.approvalsRequired = required
+}
+
+// AddApprover lets the user of token approve merge requests
+func (s *Server) AddApprover(token, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.approvers[token] = username
+}
+
+// IssueNotes returns the bodies of the comments on an issue
+func (s *Server) IssueNotes(projectID, iid int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bodies []string
+	for _, note := range s.mustProject(projectID).issueNotes[iid] {
+		bodies = append(bodies, note.Body)
+	}
+
+	return bodies
+}
+
+// SetConflicts makes merge requests opened from now on report merge conflicts with
+// their target branch. A rebase resolves them unless surviveRebase is set.
+func (s *Server) SetConflicts(projectID int, conflicts, surviveRebase bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.mustProject(projectID)
+	p.conflictNewMRs = conflicts
+	p.conflictsSurviveRebase = surviveRebase
+}
+
+// Rebases returns how often the rebase of a merge request was requested
+func (s *Server) Rebases(projectID, mrIID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mustProject(projectID).rebases[mrIID]
+}
+
+// FailRequests makes every request whose method matches and whose path contains
+// pattern fail with status. An empty method matches any method.
+func (s *Server) FailRequests(method, pattern string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failure{method: method, pattern: pattern, status: status})
+}
+
+// FailRequestsTimes makes the next times requests matching like FailRequests fail
+// with status, like a call GitLab refuses until a pipeline was created
+func (s *Server) FailRequestsTimes(method, pattern string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failure{method: method, pattern: pattern, status: status, remaining: times})
+}
+
+// ClearFailures removes all failure rules
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = nil
+}
+
+// Requests returns the served requests as "METHOD /path" strings in order
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// newID returns the next unique numeric ID; callers must hold the lock
+func (s *Server) newID() int {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// newCommit fabricates a commit of the project with a unique ID; callers must hold the lock
+func (s *Server) newCommit(p *project, message string) *gitlab.Commit {
+	id := hashID(fmt.Sprintf("%d:%s", s.newID(), message))
+	title, _, _ := strings.Cut(message, "\n")
+	now := time.Now()
+
+	return &gitlab.Commit{
+		ID:            id,
+		ShortID:       id[:ShortIDLength],
+		Title:         title,
+		Message:       message,
+		CreatedAt:     &now,
+		CommittedDate: &now,
+		WebURL:        p.info.WebURL + "/-/commit/" + id,
+	}
+}
+
+// hashID derives a hex object ID from content
+func hashID(content string) string {
+	sum := sha1.Sum([]byte(content)) // #nosec G401 -- not a security use
+	return hex.EncodeToString(sum[:])
+}
+
+// mustProject returns a project or fails the test; callers must hold the lock
+func (s *Server) mustProject(projectID int) *project {
+	p := s.projects[projectID]
+	if p == nil {
+		s.t.Fatalf("gitlabtest: unknown project %d", projectID)
+	}
+	return p
+}
+
+// findProject resolves a numeric or path project identifier; callers must hold the lock
+func (s *Server) findProject(identifier string) *project {
+	if id, err := strconv.Atoi(identifier); err == nil {
+		return s.projects[id]
+	}
+
+	for _, p := range s.projects {
+		if p.info.PathWithNamespace == identifier {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// matchFailure returns the status of the first failure rule matching the request,
+// dropping rules whose failures ran out
+func (s *Server) matchFailure(r *http.Request) int {
+	for i, f := range s.failures {
+		if (f.method == "" || f.method == r.Method) && strings.Contains(r.URL.EscapedPath(), f.pattern) {
+			if f.remaining == 1 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			} else if f.remaining > 1 {
+				s.failures[i].remaining--
+			}
+			return f.status
+		}
+	}
+	return 0
+}
+
+// copyFiles returns a shallow copy of a file tree
+func copyFiles(files map[string]string) map[string]string {
+	result := make(map[string]string, len(files))
+	for name, content := range files {
+		result[name] = content
+	}
+	return result
+}
+
+// pathValue returns a decoded path parameter. The project path is escaped twice
+// by the client, so decoding is repeated until the value is stable.
+func pathValue(r *http.Request, name string) string {
+	value := r.PathValue(name)
+	for i := 0; i < MaxUnescapeDepth; i++ {
+		decoded, err := url.PathUnescape(value)
+		if err != nil || decoded == value {
+			break
+		}
+		value = decoded
+	}
+	return value
+}
+
+// pageParams returns the 1-based page and page size of a paginated list request
+func pageParams(r *http.Request) (page, perPage int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err = strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	return page, perPage
+}
+
+// paginate returns the page of items a list request asks for and sets the
+// X-Next-Page header when more pages follow
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	page, perPage := pageParams(r)
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	if end < len(items) {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+	return items[start:end]
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a GitLab style error message
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+// decodeBody decodes a JSON request body into v
+func decodeBody(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	defer func() { _ = r.Body.Close() }()
+
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
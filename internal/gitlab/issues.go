@@ -0,0 +1,104 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// IssueReferencePrefix starts the reference of an issue of the same project
+	IssueReferencePrefix = "#"
+	// ClosingKeyword makes GitLab close the referenced issues when the merge request merges
+	ClosingKeyword = "Closes"
+	// RelatedKeyword introduces issues the merge request only relates to
+	RelatedKeyword = "Related to"
+)
+
+// IssuesManager links merge requests to the issues of a project
+type IssuesManager struct {
+	api       IssueAPI
+	projectID interface{}
+}
+
+// NewIssuesManager creates a new issues manager
+func NewIssuesManager(client *gitlab.Client, projectID interface{}) *IssuesManager {
+	return NewIssuesManagerWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewIssuesManagerWithAPI creates a new issues manager on top of the given API implementation
+func NewIssuesManagerWithAPI(api IssueAPI, projectID interface{}) *IssuesManager {
+	return &IssuesManager{
+		api:       api,
+		projectID: projectID,
+	}
+}
+
+// ParseIssueIIDs parses issue IIDs such as 123 or #123
+func ParseIssueIIDs(values []string) ([]int, error) {
+	iids := make([]int, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimPrefix(strings.TrimSpace(value), IssueReferencePrefix)
+		if value == "" {
+			continue
+		}
+		iid, err := strconv.Atoi(value)
+		if err != nil || iid < 1 {
+			return nil, errors.NewValidationError(fmt.Sprintf("invalid issue %q: expected an issue number such as 123",
+				value))
+		}
+		iids = append(iids, iid)
+	}
+	return iids, nil
+}
+
+// IssueReferences returns the description lines referencing the issues a merge request
+// closes and the ones it relates to, or "" without issues
+func IssueReferences(closes, related []int) string {
+	var b strings.Builder
+	if len(closes) > 0 {
+		fmt.Fprintf(&b, "%s %s\n", ClosingKeyword, joinIssueReferences(closes))
+	}
+	if len(related) > 0 {
+		fmt.Fprintf(&b, "%s %s\n", RelatedKeyword, joinIssueReferences(related))
+	}
+	return b.String()
+}
+
+// joinIssueReferences formats issue IIDs as #1, #2
+func joinIssueReferences(iids []int) string {
+	references := make([]string, 0, len(iids))
+	for _, iid := range iids {
+		references = append(references, IssueReferencePrefix+strconv.Itoa(iid))
+	}
+	return strings.Join(references, ", ")
+}
+
+// CommentMergeRequest posts a link to the merge request on each issue. Every issue is
+// tried; the error lists the issues that could not be commented on.
+func (im *IssuesManager) CommentMergeRequest(ctx context.Context, iids []int, mr *gitlab.MergeRequest) error {
+	if mr == nil {
+		return errors.NewValidationError("merge request cannot be nil")
+	}
+
+	body := fmt.Sprintf("Merge request !%d (%s) references this issue: %s", mr.IID, mr.Title, mr.WebURL)
+	var failed []string
+	for _, iid := range iids {
+		_, _, err := im.api.CreateIssueNote(im.projectID, iid, &gitlab.CreateIssueNoteOptions{
+			Body: gitlab.Ptr(body),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("#%d: %v", iid, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.NewAPIError(fmt.Sprintf("failed to comment on %d issue(s): %s", len(failed),
+			strings.Join(failed, "; ")))
+	}
+	return nil
+}
@@ -4,15 +4,26 @@ package gitlab
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
 	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
+const (
+	// OpenMergeRequestsPageSize defines how many open merge requests are inspected per lookup
+	OpenMergeRequestsPageSize = 100
+	// DraftTitlePrefix marks a merge request as a draft, which does not request reviews
+	DraftTitlePrefix = "Draft: "
+)
+
+// draftTitlePattern matches the title prefixes GitLab recognizes as draft markers
+var draftTitlePattern = regexp.MustCompile(`(?i)^\s*(?:draft:|\[draft\]|\(draft\))\s*`)
+
 // SimpleMergeRequestManager handles basic GitLab merge request operations
 type SimpleMergeRequestManager struct {
-	client    *gitlab.Client
+	api       MergeRequestAPI
 	projectID interface{}
 }
 
@@ -22,12 +33,45 @@ type SimpleMergeRequestOptions struct {
 	Description  string
 	SourceBranch string
 	TargetBranch string
+
+	// Squash combines all source branch commits into one on merge
+	Squash bool
+	// RemoveSourceBranch deletes the source branch once the merge request is merged
+	RemoveSourceBranch bool
+	// MergeWhenPipelineSucceeds schedules the merge for when the head pipeline passes
+	MergeWhenPipelineSucceeds bool
+	// Draft opens the merge request as a draft so that reviewers are not notified
+	// until it is marked ready
+	Draft bool
+	// Labels are added to a newly created merge request
+	Labels []string
+}
+
+// DraftTitle returns title marked as a draft
+func DraftTitle(title string) string {
+	if draftTitlePattern.MatchString(title) {
+		return title
+	}
+	return DraftTitlePrefix + title
+}
+
+// ReadyTitle returns title without its draft markers
+func ReadyTitle(title string) string {
+	for draftTitlePattern.MatchString(title) {
+		title = draftTitlePattern.ReplaceAllString(title, "")
+	}
+	return title
 }
 
 // NewSimpleMergeRequestManager creates a new simple merge request manager
 func NewSimpleMergeRequestManager(client *gitlab.Client, projectID interface{}) *SimpleMergeRequestManager {
+	return NewSimpleMergeRequestManagerWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewSimpleMergeRequestManagerWithAPI creates a new simple merge request manager on top of the given API implementation
+func NewSimpleMergeRequestManagerWithAPI(api MergeRequestAPI, projectID interface{}) *SimpleMergeRequestManager {
 	return &SimpleMergeRequestManager{
-		client:    client,
+		api:       api,
 		projectID: projectID,
 	}
 }
@@ -50,14 +94,30 @@ func (smr *SimpleMergeRequestManager) CreateMergeRequest(ctx context.Context, op
 		opts.Title = "Update tag via go-tag-updater"
 	}
 
+	title := opts.Title
+	if opts.Draft {
+		title = DraftTitle(title)
+	}
+
 	createOpts := &gitlab.CreateMergeRequestOptions{
-		Title:        gitlab.Ptr(opts.Title),
+		Title:        gitlab.Ptr(title),
 		Description:  gitlab.Ptr(opts.Description),
 		SourceBranch: gitlab.Ptr(opts.SourceBranch),
 		TargetBranch: gitlab.Ptr(opts.TargetBranch),
 	}
 
-	mr, _, err := smr.client.MergeRequests.CreateMergeRequest(smr.projectID, createOpts)
+	if opts.Squash {
+		createOpts.Squash = gitlab.Ptr(true)
+	}
+	if opts.RemoveSourceBranch {
+		createOpts.RemoveSourceBranch = gitlab.Ptr(true)
+	}
+	if len(opts.Labels) > 0 {
+		labels := gitlab.LabelOptions(opts.Labels)
+		createOpts.Labels = &labels
+	}
+
+	mr, _, err := smr.api.CreateMergeRequest(smr.projectID, createOpts, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to create merge request: %v", err))
 	}
@@ -65,13 +125,73 @@ func (smr *SimpleMergeRequestManager) CreateMergeRequest(ctx context.Context, op
 	return mr, nil
 }
 
+// SetMergeWhenPipelineSucceeds schedules the merge request to be merged once its pipeline passes
+func (smr *SimpleMergeRequestManager) SetMergeWhenPipelineSucceeds(
+	ctx context.Context,
+	mrIID int,
+	opts *SimpleMergeRequestOptions,
+) (*gitlab.MergeRequest, error) {
+	if mrIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	acceptOpts := &gitlab.AcceptMergeRequestOptions{
+		MergeWhenPipelineSucceeds: gitlab.Ptr(true),
+	}
+
+	if opts != nil {
+		if opts.Squash {
+			acceptOpts.Squash = gitlab.Ptr(true)
+		}
+		if opts.RemoveSourceBranch {
+			acceptOpts.ShouldRemoveSourceBranch = gitlab.Ptr(true)
+		}
+	}
+
+	mr, _, err := smr.api.AcceptMergeRequest(smr.projectID, mrIID, acceptOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAppErrorWithCause(errors.ErrCodeAPIError, errors.CategoryAPI,
+			fmt.Sprintf("failed to enable merge when pipeline succeeds for %d: %v", mrIID, err), err)
+	}
+
+	return mr, nil
+}
+
+// MergeMergeRequest merges a merge request immediately
+func (smr *SimpleMergeRequestManager) MergeMergeRequest(
+	ctx context.Context,
+	mrIID int,
+	opts *SimpleMergeRequestOptions,
+) (*gitlab.MergeRequest, error) {
+	if mrIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	acceptOpts := &gitlab.AcceptMergeRequestOptions{}
+	if opts != nil {
+		if opts.Squash {
+			acceptOpts.Squash = gitlab.Ptr(true)
+		}
+		if opts.RemoveSourceBranch {
+			acceptOpts.ShouldRemoveSourceBranch = gitlab.Ptr(true)
+		}
+	}
+
+	mr, _, err := smr.api.AcceptMergeRequest(smr.projectID, mrIID, acceptOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to merge merge request %d: %v", mrIID, err))
+	}
+
+	return mr, nil
+}
+
 // GetMergeRequest retrieves merge request by IID
 func (smr *SimpleMergeRequestManager) GetMergeRequest(ctx context.Context, mrIID int) (*gitlab.MergeRequest, error) {
 	if mrIID <= 0 {
 		return nil, errors.NewValidationError("merge request IID must be positive")
 	}
 
-	mr, _, err := smr.client.MergeRequests.GetMergeRequest(smr.projectID, mrIID, nil)
+	mr, _, err := smr.api.GetMergeRequest(smr.projectID, mrIID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to get merge request %d: %v", mrIID, err))
 	}
@@ -92,10 +212,132 @@ func (smr *SimpleMergeRequestManager) ListMergeRequests(ctx context.Context, sta
 		opts.State = gitlab.Ptr(state)
 	}
 
-	mrs, _, err := smr.client.MergeRequests.ListProjectMergeRequests(smr.projectID, opts)
+	mrs, _, err := smr.api.ListProjectMergeRequests(smr.projectID, opts, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list merge requests: %v", err))
 	}
 
 	return mrs, nil
 }
+
+// ListOpenMergeRequests lists open merge requests targeting the given branch, reading
+// every page up to MaxListPages
+func (smr *SimpleMergeRequestManager) ListOpenMergeRequests(ctx context.Context, targetBranch string) ([]*gitlab.BasicMergeRequest, error) {
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		State: gitlab.Ptr(StateOpened),
+	}
+
+	if targetBranch != "" {
+		opts.TargetBranch = gitlab.Ptr(targetBranch)
+	}
+
+	mrs, err := collectPages(ctx, OpenMergeRequestsPageSize*MaxListPages, OpenMergeRequestsPageSize,
+		func(page gitlab.ListOptions) ([]*gitlab.BasicMergeRequest, *gitlab.Response, error) {
+			opts.ListOptions = page
+			return smr.api.ListProjectMergeRequests(smr.projectID, opts, gitlab.WithContext(ctx))
+		})
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to list open merge requests: %v", err))
+	}
+
+	return mrs, nil
+}
+
+// ListMergeRequestsBySourceBranch lists the merge requests of any state opened from a
+// branch, reading every page up to MaxListPages
+func (smr *SimpleMergeRequestManager) ListMergeRequestsBySourceBranch(
+	ctx context.Context,
+	sourceBranch string,
+) ([]*gitlab.BasicMergeRequest, error) {
+	if sourceBranch == "" {
+		return nil, errors.NewValidationError("source branch cannot be empty")
+	}
+
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		SourceBranch: gitlab.Ptr(sourceBranch),
+		State:        gitlab.Ptr(StateAll),
+	}
+
+	mrs, err := collectPages(ctx, OpenMergeRequestsPageSize*MaxListPages, OpenMergeRequestsPageSize,
+		func(page gitlab.ListOptions) ([]*gitlab.BasicMergeRequest, *gitlab.Response, error) {
+			opts.ListOptions = page
+			return smr.api.ListProjectMergeRequests(smr.projectID, opts, gitlab.WithContext(ctx))
+		})
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to list merge requests for branch %s: %v", sourceBranch, err))
+	}
+
+	return mrs, nil
+}
+
+// UpdateMergeRequest updates title and description of an existing merge request
+func (smr *SimpleMergeRequestManager) UpdateMergeRequest(ctx context.Context, mrIID int, opts *SimpleMergeRequestOptions) (*gitlab.MergeRequest, error) {
+	if mrIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	if opts == nil {
+		return nil, errors.NewValidationError("merge request options cannot be nil")
+	}
+
+	updateOpts := &gitlab.UpdateMergeRequestOptions{}
+	if opts.Title != "" && opts.Draft {
+		updateOpts.Title = gitlab.Ptr(DraftTitle(opts.Title))
+	} else if opts.Title != "" {
+		updateOpts.Title = gitlab.Ptr(opts.Title)
+	}
+	if opts.Description != "" {
+		updateOpts.Description = gitlab.Ptr(opts.Description)
+	}
+
+	mr, _, err := smr.api.UpdateMergeRequest(smr.projectID, mrIID, updateOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to update merge request %d: %v", mrIID, err))
+	}
+
+	return mr, nil
+}
+
+// MarkReady removes the draft markers from the title of a merge request, which
+// requests reviews from its reviewers
+func (smr *SimpleMergeRequestManager) MarkReady(ctx context.Context, mrIID int) (*gitlab.MergeRequest, error) {
+	mr, err := smr.GetMergeRequest(ctx, mrIID)
+	if err != nil {
+		return nil, err
+	}
+
+	title := ReadyTitle(mr.Title)
+	if title == mr.Title {
+		return mr, nil
+	}
+
+	updateOpts := &gitlab.UpdateMergeRequestOptions{Title: gitlab.Ptr(title)}
+	mr, _, err = smr.api.UpdateMergeRequest(smr.projectID, mrIID, updateOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to mark merge request %d as ready: %v", mrIID, err))
+	}
+
+	return mr, nil
+}
+
+// CloseMergeRequest closes a merge request, leaving an explanatory note when one is given
+func (smr *SimpleMergeRequestManager) CloseMergeRequest(ctx context.Context, mrIID int, note string) (*gitlab.MergeRequest, error) {
+	if mrIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	if note != "" {
+		noteOpts := &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.Ptr(note)}
+		if _, _, err := smr.api.CreateMergeRequestNote(smr.projectID, mrIID, noteOpts, gitlab.WithContext(ctx)); err != nil {
+			return nil, errors.NewAPIError(fmt.Sprintf("failed to comment on merge request %d: %v", mrIID, err))
+		}
+	}
+
+	updateOpts := &gitlab.UpdateMergeRequestOptions{StateEvent: gitlab.Ptr("close")}
+	mr, _, err := smr.api.UpdateMergeRequest(smr.projectID, mrIID, updateOpts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to close merge request %d: %v", mrIID, err))
+	}
+
+	return mr, nil
+}
@@ -0,0 +1,204 @@
+// Package gitlab provides utilities for GitLab API operations
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// NeedsRebaseLabel marks merge requests that have merge conflicts with their target branch
+	NeedsRebaseLabel = "needs-rebase"
+	// MergeabilityCheckInterval defines how often the mergeability of a merge request is re-checked
+	MergeabilityCheckInterval = 30 * time.Second
+	// DefaultMergeabilityTimeout defines how long to wait for conflicts to be resolved when no timeout is given
+	DefaultMergeabilityTimeout = 15 * time.Minute
+
+	// Detailed merge statuses that mean GitLab has not finished computing mergeability
+	mergeStatusUnchecked = "unchecked"
+	mergeStatusChecking  = "checking"
+	mergeStatusPreparing = "preparing"
+	// mergeStatusConflict is the detailed merge status of a merge request with conflicts
+	mergeStatusConflict = "conflict"
+)
+
+// MergeabilityWatcher re-checks merge requests that GitLab reports as conflicting
+// with their target branch until the conflicts are resolved
+type MergeabilityWatcher struct {
+	api       MergeRequestAPI
+	projectID interface{}
+	interval  time.Duration
+	progress  ProgressFunc
+
+	// stopOnConflict ends the watch as soon as conflicts are found, or persist after
+	// the requested rebase, instead of waiting for someone to resolve them
+	stopOnConflict bool
+}
+
+// MergeabilityResult describes how the conflicts of a merge request were handled
+type MergeabilityResult struct {
+	// HadConflicts is set when GitLab reported conflicts at any check
+	HadConflicts bool
+	// Labeled is set when the needs-rebase label was added
+	Labeled bool
+	// RebaseRequested is set when the rebase API was called
+	RebaseRequested bool
+	// Resolved is set when the last check found no conflicts
+	Resolved bool
+	// Checks counts how often mergeability was checked
+	Checks int
+}
+
+// NewMergeabilityWatcher creates a new mergeability watcher
+func NewMergeabilityWatcher(client *gitlab.Client, projectID interface{}) *MergeabilityWatcher {
+	return NewMergeabilityWatcherWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewMergeabilityWatcherWithAPI creates a new mergeability watcher on top of the given API implementation
+func NewMergeabilityWatcherWithAPI(api MergeRequestAPI, projectID interface{}) *MergeabilityWatcher {
+	return &MergeabilityWatcher{
+		api:       api,
+		projectID: projectID,
+		interval:  MergeabilityCheckInterval,
+	}
+}
+
+// SetInterval overrides the polling interval
+func (mw *MergeabilityWatcher) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		mw.interval = interval
+	}
+}
+
+// SetProgress sets the function told the merge status after every check
+func (mw *MergeabilityWatcher) SetProgress(fn ProgressFunc) {
+	mw.progress = fn
+}
+
+// SetStopOnConflict makes WaitForMergeable return a merge conflict error as soon as
+// conflicts are found, or when they persist after the rebase it requested
+func (mw *MergeabilityWatcher) SetStopOnConflict(stop bool) {
+	mw.stopOnConflict = stop
+}
+
+// WaitForMergeable checks the merge request until GitLab reports it free of conflicts
+// or the timeout elapses. Conflicts add the needs-rebase label, removed again once
+// they are resolved, and with rebase set the rebase API is called once. Conflicts
+// that outlast the timeout return a merge conflict error.
+func (mw *MergeabilityWatcher) WaitForMergeable(
+	ctx context.Context,
+	mrIID int,
+	timeout time.Duration,
+	rebase bool,
+) (*MergeabilityResult, error) {
+	if mrIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultMergeabilityTimeout
+	}
+
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(mw.interval)
+	defer ticker.Stop()
+
+	result := &MergeabilityResult{}
+	for {
+		done, err := mw.checkMergeability(ctx, mrIID, rebase, result)
+		if err != nil || done {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-deadline:
+			if !result.HadConflicts {
+				// GitLab never finished computing mergeability; nothing to report
+				return result, nil
+			}
+			return result, errors.NewMergeConflictError(fmt.Sprintf(
+				"merge request %d still has merge conflicts after %v; rebase it manually", mrIID, timeout))
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkMergeability inspects the merge request once, labels and rebases it when it
+// has conflicts, and reports whether the watch is over
+func (mw *MergeabilityWatcher) checkMergeability(
+	ctx context.Context,
+	mrIID int,
+	rebase bool,
+	result *MergeabilityResult,
+) (bool, error) {
+	result.Checks++
+
+	mr, _, err := mw.api.GetMergeRequest(mw.projectID, mrIID,
+		&gitlab.GetMergeRequestsOptions{IncludeRebaseInProgress: gitlab.Ptr(true)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return false, errors.NewAPIError(fmt.Sprintf("failed to get merge request %d: %v", mrIID, err))
+	}
+
+	if mr.State != StateOpened {
+		return true, nil
+	}
+	mw.progress.report(fmt.Sprintf("merge status %s", mr.DetailedMergeStatus))
+
+	switch {
+	case mr.RebaseInProgress, mr.DetailedMergeStatus == mergeStatusUnchecked,
+		mr.DetailedMergeStatus == mergeStatusChecking, mr.DetailedMergeStatus == mergeStatusPreparing:
+		return false, nil
+	case mr.HasConflicts || mr.DetailedMergeStatus == mergeStatusConflict:
+		result.HadConflicts = true
+		rebased := result.RebaseRequested
+		if err := mw.handleConflicts(ctx, mr, rebase, result); err != nil {
+			return false, err
+		}
+		if mw.stopOnConflict && (!rebase || rebased) {
+			return true, errors.NewMergeConflictError(fmt.Sprintf("merge request %d has merge conflicts", mrIID))
+		}
+		return false, nil
+	}
+
+	result.Resolved = true
+	if slices.Contains(mr.Labels, NeedsRebaseLabel) {
+		opts := &gitlab.UpdateMergeRequestOptions{RemoveLabels: &gitlab.LabelOptions{NeedsRebaseLabel}}
+		if _, _, err := mw.api.UpdateMergeRequest(mw.projectID, mrIID, opts, gitlab.WithContext(ctx)); err != nil {
+			return true, errors.NewAPIError(fmt.Sprintf("failed to remove label from merge request %d: %v", mrIID, err))
+		}
+	}
+	return true, nil
+}
+
+// handleConflicts adds the needs-rebase label and requests a rebase once
+func (mw *MergeabilityWatcher) handleConflicts(
+	ctx context.Context,
+	mr *gitlab.MergeRequest,
+	rebase bool,
+	result *MergeabilityResult,
+) error {
+	if !slices.Contains(mr.Labels, NeedsRebaseLabel) {
+		opts := &gitlab.UpdateMergeRequestOptions{AddLabels: &gitlab.LabelOptions{NeedsRebaseLabel}}
+		if _, _, err := mw.api.UpdateMergeRequest(mw.projectID, mr.IID, opts, gitlab.WithContext(ctx)); err != nil {
+			return errors.NewAPIError(fmt.Sprintf("failed to label merge request %d: %v", mr.IID, err))
+		}
+		result.Labeled = true
+	}
+
+	if rebase && !result.RebaseRequested {
+		if _, err := mw.api.RebaseMergeRequest(mw.projectID, mr.IID, nil, gitlab.WithContext(ctx)); err != nil {
+			return errors.NewAPIError(fmt.Sprintf("failed to rebase merge request %d: %v", mr.IID, err))
+		}
+		result.RebaseRequested = true
+	}
+
+	return nil
+}
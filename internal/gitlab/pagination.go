@@ -0,0 +1,51 @@
+package gitlab
+
+import (
+	"context"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// MaxPageSize is the largest page size the GitLab API accepts
+	MaxPageSize = 100
+	// MaxListPages bounds the pages one listing reads, whatever maxResults asks for
+	MaxListPages = 100
+)
+
+// fetchPage reads one page of a GitLab list endpoint
+type fetchPage[T any] func(opts gitlab.ListOptions) ([]T, *gitlab.Response, error)
+
+// collectPages reads successive pages until maxResults items were collected, the pages
+// run out or MaxListPages pages were read. Pages hold up to perPage items, capped at
+// MaxPageSize; at most maxResults items are returned.
+func collectPages[T any](ctx context.Context, maxResults, perPage int, fetch fetchPage[T]) ([]T, error) {
+	if perPage <= 0 || perPage > MaxPageSize {
+		perPage = MaxPageSize
+	}
+	perPage = min(perPage, maxResults)
+
+	var items []T
+	opts := gitlab.ListOptions{PerPage: perPage, Page: 1}
+	for page := 0; page < MaxListPages && len(items) < maxResults; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		pageItems, resp, err := fetch(opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, pageItems...)
+
+		if len(pageItems) == 0 || resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if len(items) > maxResults {
+		items = items[:maxResults]
+	}
+	return items, nil
+}
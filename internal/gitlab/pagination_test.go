@@ -0,0 +1,84 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestCollectPages(t *testing.T) {
+	tests := []struct {
+		name       string
+		total      int
+		maxResults int
+		perPage    int
+		wantItems  int
+		wantPages  int
+		wantSize   int
+	}{
+		{name: "single page", total: 5, maxResults: 20, perPage: 20, wantItems: 5, wantPages: 1, wantSize: 20},
+		{name: "stops at max results", total: 250, maxResults: 120, perPage: 120, wantItems: 120, wantPages: 2,
+			wantSize: MaxPageSize},
+		{name: "pages run out", total: 250, maxResults: 1000, perPage: 1000, wantItems: 250, wantPages: 3,
+			wantSize: MaxPageSize},
+		{name: "small pages", total: 7, maxResults: 10, perPage: 2, wantItems: 7, wantPages: 4, wantSize: 2},
+		{name: "page size bounded by max results", total: 50, maxResults: 3, perPage: 10, wantItems: 3,
+			wantPages: 1, wantSize: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := 0
+			items, err := collectPages(context.Background(), tt.maxResults, tt.perPage,
+				func(opts gitlab.ListOptions) ([]int, *gitlab.Response, error) {
+					pages++
+					if opts.PerPage != tt.wantSize {
+						t.Errorf("page size = %d, want %d", opts.PerPage, tt.wantSize)
+					}
+					start := (opts.Page - 1) * opts.PerPage
+					end := min(start+opts.PerPage, tt.total)
+					var page []int
+					for i := start; i < end; i++ {
+						page = append(page, i)
+					}
+					resp := &gitlab.Response{}
+					if end < tt.total {
+						resp.NextPage = opts.Page + 1
+					}
+					return page, resp, nil
+				})
+			if err != nil {
+				t.Fatalf("collectPages() unexpected error: %v", err)
+			}
+			if len(items) != tt.wantItems || pages != tt.wantPages {
+				t.Errorf("collectPages() = %d items from %d pages, want %d from %d",
+					len(items), pages, tt.wantItems, tt.wantPages)
+			}
+			for i, item := range items {
+				if item != i {
+					t.Fatalf("items[%d] = %d, want items in page order", i, item)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectPages_Error(t *testing.T) {
+	_, err := collectPages(context.Background(), 10, 10, func(gitlab.ListOptions) ([]int, *gitlab.Response, error) {
+		return nil, nil, fmt.Errorf("boom")
+	})
+	if err == nil {
+		t.Error("collectPages() expected the fetch error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := collectPages(ctx, 10, 10, func(gitlab.ListOptions) ([]int, *gitlab.Response, error) {
+		t.Error("fetch called with a canceled context")
+		return nil, nil, nil
+	}); err == nil {
+		t.Error("collectPages() expected the context error")
+	}
+}
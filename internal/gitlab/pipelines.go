@@ -0,0 +1,199 @@
+// Package gitlab provides utilities for GitLab API operations
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// PipelineCheckInterval defines how often the merge request head pipeline is polled
+	PipelineCheckInterval = 15 * time.Second
+	// DefaultPipelineTimeout defines how long to wait for a pipeline when no timeout is given
+	DefaultPipelineTimeout = 30 * time.Minute
+	// FailedJobsPageSize defines how many failed jobs are listed for the error report
+	FailedJobsPageSize = 20
+)
+
+// PipelineWatcher waits for merge request pipelines to finish
+type PipelineWatcher struct {
+	api       PipelineAPI
+	projectID interface{}
+	interval  time.Duration
+	progress  ProgressFunc
+}
+
+// PipelineResult describes the final state of a merge request pipeline
+type PipelineResult struct {
+	PipelineID int
+	Status     string
+	WebURL     string
+	FailedJobs []FailedJob
+}
+
+// Skipped reports whether the pipeline finished without running, which is neither
+// a success nor a failure
+func (pr *PipelineResult) Skipped() bool {
+	return pr != nil && gitlab.BuildStateValue(pr.Status) == gitlab.Skipped
+}
+
+// FailedJob describes a job that did not succeed
+type FailedJob struct {
+	ID     int
+	Name   string
+	Stage  string
+	WebURL string
+}
+
+// NewPipelineWatcher creates a new pipeline watcher
+func NewPipelineWatcher(client *gitlab.Client, projectID interface{}) *PipelineWatcher {
+	return NewPipelineWatcherWithAPI(NewAPIAdapter(client), projectID)
+}
+
+// NewPipelineWatcherWithAPI creates a new pipeline watcher on top of the given API implementation
+func NewPipelineWatcherWithAPI(api PipelineAPI, projectID interface{}) *PipelineWatcher {
+	return &PipelineWatcher{
+		api:       api,
+		projectID: projectID,
+		interval:  PipelineCheckInterval,
+	}
+}
+
+// SetInterval overrides the polling interval
+func (pw *PipelineWatcher) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		pw.interval = interval
+	}
+}
+
+// SetProgress sets the function told the pipeline status after every check
+func (pw *PipelineWatcher) SetProgress(fn ProgressFunc) {
+	pw.progress = fn
+}
+
+// WaitForMergeRequestPipeline blocks until the head pipeline of the merge request
+// succeeds, fails, is skipped, or the timeout elapses. A failed pipeline returns an
+// error naming the failed jobs; a skipped one returns without an error, so callers
+// tell it apart with PipelineResult.Skipped. Canceling ctx stops the wait with the
+// context error.
+func (pw *PipelineWatcher) WaitForMergeRequestPipeline(ctx context.Context, mrIID int, timeout time.Duration) (*PipelineResult, error) {
+	if mrIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultPipelineTimeout
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	var last *PipelineResult
+	for {
+		result, done, err := pw.checkPipeline(ctx, mrIID)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return last, ctxErr
+		}
+		if err != nil || done {
+			return result, err
+		}
+		last = result
+		if result == nil {
+			pw.progress.report("waiting for a pipeline to start")
+		} else {
+			pw.progress.report(fmt.Sprintf("pipeline %d %s", result.PipelineID, result.Status))
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-deadline.C:
+			status := "not started"
+			if last != nil {
+				status = last.Status
+			}
+			return last, errors.NewPipelineError(fmt.Sprintf(
+				"timeout waiting for pipeline of merge request %d after %v (status: %s)", mrIID, timeout, status))
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkPipeline inspects the head pipeline once and reports whether it has finished
+func (pw *PipelineWatcher) checkPipeline(ctx context.Context, mrIID int) (*PipelineResult, bool, error) {
+	mr, _, err := pw.api.GetMergeRequest(pw.projectID, mrIID, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, false, errors.NewAPIError(fmt.Sprintf("failed to get merge request %d: %v", mrIID, err))
+	}
+
+	if mr.HeadPipeline == nil {
+		return nil, false, nil
+	}
+
+	result := &PipelineResult{
+		PipelineID: mr.HeadPipeline.ID,
+		Status:     mr.HeadPipeline.Status,
+		WebURL:     mr.HeadPipeline.WebURL,
+	}
+
+	switch gitlab.BuildStateValue(result.Status) {
+	case gitlab.Success, gitlab.Skipped:
+		return result, true, nil
+	case gitlab.Failed, gitlab.Canceled:
+		result.FailedJobs, err = pw.listFailedJobs(ctx, result.PipelineID)
+		if err != nil {
+			return result, true, err
+		}
+		return result, true, errors.NewPipelineError(fmt.Sprintf("pipeline %d finished with status %s; %s",
+			result.PipelineID, result.Status, formatFailedJobs(result.FailedJobs)))
+	default:
+		return result, false, nil
+	}
+}
+
+// listFailedJobs lists the failed jobs of a pipeline
+func (pw *PipelineWatcher) listFailedJobs(ctx context.Context, pipelineID int) ([]FailedJob, error) {
+	opts := &gitlab.ListJobsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: FailedJobsPageSize},
+		Scope:       &[]gitlab.BuildStateValue{gitlab.Failed},
+	}
+
+	jobs, _, err := pw.api.ListPipelineJobs(pw.projectID, pipelineID, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to list failed jobs of pipeline %d: %v", pipelineID, err))
+	}
+
+	failed := make([]FailedJob, 0, len(jobs))
+	for _, job := range jobs {
+		failed = append(failed, FailedJob{
+			ID:     job.ID,
+			Name:   job.Name,
+			Stage:  job.Stage,
+			WebURL: job.WebURL,
+		})
+	}
+
+	return failed, nil
+}
+
+// formatFailedJobs renders failed jobs as "stage/name" pairs
+func formatFailedJobs(jobs []FailedJob) string {
+	if len(jobs) == 0 {
+		return "no failed jobs reported"
+	}
+
+	names := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		names = append(names, fmt.Sprintf("%s/%s", job.Stage, job.Name))
+	}
+
+	return "failed jobs: " + strings.Join(names, ", ")
+}
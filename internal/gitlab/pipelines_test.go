@@ -0,0 +1,151 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestPipelineMRIID    = 5
+	TestPipelineID       = 77
+	TestPipelineInterval = 5 * time.Millisecond
+	TestPipelineTimeout  = time.Second
+)
+
+// newPipelineTestWatcher serves the given head pipeline statuses in order, repeating the last one
+func newPipelineTestWatcher(t *testing.T, statuses []string) *PipelineWatcher {
+	t.Helper()
+
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%d/merge_requests/%d", TestProjectID, TestPipelineMRIID),
+		func(w http.ResponseWriter, _ *http.Request) {
+			idx := int(atomic.AddInt32(&calls, 1)) - 1
+			if idx >= len(statuses) {
+				idx = len(statuses) - 1
+			}
+			if statuses[idx] == "" {
+				fmt.Fprintf(w, `{"iid": %d}`, TestPipelineMRIID)
+				return
+			}
+			fmt.Fprintf(w, `{"iid": %d, "head_pipeline": {"id": %d, "status": %q}}`,
+				TestPipelineMRIID, TestPipelineID, statuses[idx])
+		})
+	mux.HandleFunc(fmt.Sprintf("/api/v4/projects/%d/pipelines/%d/jobs", TestProjectID, TestPipelineID),
+		func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `[{"id": 1, "name": "unit-tests", "stage": "test"}]`)
+		})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := gitlab.NewClient("token", gitlab.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	watcher := NewPipelineWatcher(client, TestProjectID)
+	watcher.SetInterval(TestPipelineInterval)
+	return watcher
+}
+
+func TestPipelineWatcher_WaitForMergeRequestPipeline(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []string
+		timeout      time.Duration
+		expectError  bool
+		errorContain string
+	}{
+		{name: "success after running", statuses: []string{"", "pending", "running", "success"}, timeout: TestPipelineTimeout},
+		{name: "failure reports jobs", statuses: []string{"running", "failed"}, timeout: TestPipelineTimeout,
+			expectError: true, errorContain: "test/unit-tests"},
+		{name: "canceled pipeline fails", statuses: []string{"running", "canceled"}, timeout: TestPipelineTimeout,
+			expectError: true, errorContain: "status canceled"},
+		{name: "timeout while running", statuses: []string{"running"}, timeout: 20 * TestPipelineInterval,
+			expectError: true, errorContain: "timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := newPipelineTestWatcher(t, tt.statuses)
+
+			result, err := watcher.WaitForMergeRequestPipeline(context.Background(), TestPipelineMRIID, tt.timeout)
+			if !tt.expectError {
+				if err != nil {
+					t.Fatalf("WaitForMergeRequestPipeline() unexpected error: %v", err)
+				}
+				if result == nil || result.PipelineID != TestPipelineID {
+					t.Errorf("WaitForMergeRequestPipeline() result = %+v", result)
+				}
+				return
+			}
+
+			if errors.GetErrorCode(err) != errors.ErrCodePipelineFailed {
+				t.Fatalf("WaitForMergeRequestPipeline() error = %v, want pipeline error", err)
+			}
+			if !strings.Contains(err.Error(), tt.errorContain) {
+				t.Errorf("WaitForMergeRequestPipeline() error = %q, want it to contain %q", err, tt.errorContain)
+			}
+		})
+	}
+}
+
+func TestPipelineWatcher_Skipped(t *testing.T) {
+	watcher := newPipelineTestWatcher(t, []string{"pending", "skipped"})
+
+	result, err := watcher.WaitForMergeRequestPipeline(context.Background(), TestPipelineMRIID, TestPipelineTimeout)
+	if err != nil {
+		t.Fatalf("WaitForMergeRequestPipeline() unexpected error for a skipped pipeline: %v", err)
+	}
+	if !result.Skipped() {
+		t.Errorf("WaitForMergeRequestPipeline() result = %+v, want a skipped pipeline", result)
+	}
+}
+
+func TestPipelineWatcher_Canceled(t *testing.T) {
+	watcher := newPipelineTestWatcher(t, []string{"running"})
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher.SetProgress(func(string) { cancel() })
+
+	start := time.Now()
+	_, err := watcher.WaitForMergeRequestPipeline(ctx, TestPipelineMRIID, time.Hour)
+	if err != context.Canceled {
+		t.Fatalf("WaitForMergeRequestPipeline() error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > TestPipelineTimeout {
+		t.Errorf("WaitForMergeRequestPipeline() returned after %v, want it to stop on cancellation", elapsed)
+	}
+}
+
+func TestPipelineWatcher_InvalidMergeRequest(t *testing.T) {
+	watcher := NewPipelineWatcher(nil, TestProjectID)
+	if _, err := watcher.WaitForMergeRequestPipeline(context.Background(), 0, TestPipelineTimeout); err == nil {
+		t.Error("WaitForMergeRequestPipeline() expected error for invalid IID")
+	}
+}
+
+func TestPipelineWatcher_Progress(t *testing.T) {
+	watcher := newPipelineTestWatcher(t, []string{"", "running", "success"})
+	var statuses []string
+	watcher.SetProgress(func(status string) { statuses = append(statuses, status) })
+
+	_, err := watcher.WaitForMergeRequestPipeline(context.Background(), TestPipelineMRIID, TestPipelineTimeout)
+	if err != nil {
+		t.Fatalf("WaitForMergeRequestPipeline() unexpected error: %v", err)
+	}
+	want := []string{"waiting for a pipeline to start", fmt.Sprintf("pipeline %d running", TestPipelineID)}
+	if strings.Join(statuses, "; ") != strings.Join(want, "; ") {
+		t.Errorf("progress = %v, want %v", statuses, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Package gitlab provides utilities for GitLab API operations
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DefaultPrefetchConcurrency bounds how many files are fetched at the same time
+	DefaultPrefetchConcurrency = MaxConcurrentReqs
+)
+
+// PrefetchedFile holds the state of a file fetched before any mutation
+type PrefetchedFile struct {
+	FilePath string
+	Exists   bool
+	Content  string
+}
+
+// PrefetchFiles fetches existence and content of all files concurrently, with at
+// most concurrency requests in flight. Every file is inspected before returning so
+// that all missing files are reported together in a single file-not-found error;
+// the returned map is populated in that case as well. Any other API error aborts
+// the prefetch.
+func (fm *FileManager) PrefetchFiles(
+	ctx context.Context,
+	filePaths []string,
+	branch string,
+	concurrency int,
+) (map[string]*PrefetchedFile, error) {
+	if len(filePaths) == 0 {
+		return nil, errors.NewValidationError("at least one file path is required")
+	}
+
+	if concurrency <= 0 {
+		concurrency = DefaultPrefetchConcurrency
+	}
+
+	if branch == "" {
+		branch = DefaultBranch
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		files    = make(map[string]*PrefetchedFile, len(filePaths))
+		slots    = make(chan struct{}, concurrency)
+	)
+
+	for _, filePath := range filePaths {
+		if _, seen := files[filePath]; seen {
+			continue
+		}
+		files[filePath] = &PrefetchedFile{FilePath: filePath}
+
+		wg.Add(1)
+		go func(file *PrefetchedFile) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return
+			}
+
+			err := fm.prefetchFile(ctx, file, branch)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}(files[filePath])
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, missingFilesError(files, branch)
+}
+
+// prefetchFile fetches a single file; a missing file, answered with 404 Not Found,
+// is not an error
+func (fm *FileManager) prefetchFile(ctx context.Context, file *PrefetchedFile, branch string) error {
+	info, err := fm.GetFile(ctx, file.FilePath, branch)
+	if err != nil {
+		if ResponseStatus(err) == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+
+	file.Exists = true
+	file.Content = info.Content
+	return nil
+}
+
+// missingFilesError lists every missing file in one error, or returns nil
+func missingFilesError(files map[string]*PrefetchedFile, branch string) error {
+	var missing []string
+	for filePath, file := range files {
+		if !file.Exists {
+			missing = append(missing, filePath)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	return errors.NewAppError(errors.ErrCodeFileNotFound, errors.CategoryFile,
+		fmt.Sprintf("%d file(s) not found in branch %s: %s", len(missing), branch, strings.Join(missing, ", ")))
+}
@@ -0,0 +1,150 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestPrefetchFileCount   = 8
+	TestPrefetchConcurrency = 2
+	TestPrefetchDelay       = 5 * time.Millisecond
+)
+
+// countingFileAPI serves every file after a short delay and records peak concurrency
+type countingFileAPI struct {
+	FileAPI
+	inFlight int32
+	peak     int32
+	mu       sync.Mutex
+}
+
+func (c *countingFileAPI) GetFile(
+	_ interface{},
+	fileName string,
+	_ *gitlab.GetFileOptions,
+	_ ...gitlab.RequestOptionFunc,
+) (*gitlab.File, *gitlab.Response, error) {
+	current := atomic.AddInt32(&c.inFlight, 1)
+	defer atomic.AddInt32(&c.inFlight, -1)
+
+	c.mu.Lock()
+	if current > c.peak {
+		c.peak = current
+	}
+	c.mu.Unlock()
+
+	time.Sleep(TestPrefetchDelay)
+	return &gitlab.File{Content: base64.StdEncoding.EncodeToString([]byte(fileName))}, nil, nil
+}
+
+func TestFileManager_PrefetchFiles(t *testing.T) {
+	server, projectID := newFakeProject(t)
+	server.SetFile(projectID, TestMainBranch, "other.yaml", TestFakeUpdatedFile)
+	fm := NewFileManager(server.Client(), projectID)
+	ctx := context.Background()
+
+	files, err := fm.PrefetchFiles(ctx, []string{TestFakeFilePath, "other.yaml", TestFakeFilePath}, TestMainBranch, 0)
+	if err != nil {
+		t.Fatalf("PrefetchFiles() unexpected error: %v", err)
+	}
+	if len(files) != 2 || files[TestFakeFilePath].Content != TestFakeFileContent ||
+		files["other.yaml"].Content != TestFakeUpdatedFile {
+		t.Errorf("PrefetchFiles() = %v", files)
+	}
+
+	files, err = fm.PrefetchFiles(ctx, []string{"b.yaml", TestFakeFilePath, "a.yaml"}, TestMainBranch, 0)
+	if code := errors.GetErrorCode(err); code != errors.ErrCodeFileNotFound {
+		t.Fatalf("PrefetchFiles() error code = %d, want %d (%v)", code, errors.ErrCodeFileNotFound, err)
+	}
+	if !strings.Contains(err.Error(), "2 file(s) not found in branch main: a.yaml, b.yaml") {
+		t.Errorf("PrefetchFiles() error should list all missing files, got %v", err)
+	}
+	if !files[TestFakeFilePath].Exists || files["a.yaml"].Exists {
+		t.Errorf("PrefetchFiles() existence = %v", files)
+	}
+
+	server.FailRequests(http.MethodGet, "/repository/files/", http.StatusInternalServerError)
+	_, err = fm.PrefetchFiles(ctx, []string{TestFakeFilePath}, TestMainBranch, 0)
+	if errors.GetErrorCode(err) != errors.ErrCodeAPIError {
+		t.Errorf("PrefetchFiles() with server error = %v, want API error", err)
+	}
+
+	if _, err := fm.PrefetchFiles(ctx, nil, TestMainBranch, 0); errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("PrefetchFiles() without files = %v, want validation error", err)
+	}
+}
+
+// messageFileAPI fails every file with an error carrying no API response
+type messageFileAPI struct {
+	FileAPI
+	err error
+}
+
+func (m *messageFileAPI) GetFile(
+	_ interface{},
+	_ string,
+	_ *gitlab.GetFileOptions,
+	_ ...gitlab.RequestOptionFunc,
+) (*gitlab.File, *gitlab.Response, error) {
+	return nil, nil, m.err
+}
+
+func TestFileManager_PrefetchFilesNotFoundStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantMissing bool
+	}{
+		{name: "404 from the client", err: gitlab.ErrNotFound, wantMissing: true},
+		{name: "message mentioning not found", err: fmt.Errorf("proxy: upstream not found, retry after 404s")},
+		{name: "other status", err: apiErrorResponse(http.StatusForbidden)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm := NewFileManagerWithAPI(&messageFileAPI{err: tt.err}, TestProjectID)
+
+			_, err := fm.PrefetchFiles(context.Background(), []string{TestFakeFilePath}, TestMainBranch, 0)
+			want := errors.ErrCodeAPIError
+			if tt.wantMissing {
+				want = errors.ErrCodeFileNotFound
+			}
+			if code := errors.GetErrorCode(err); code != want {
+				t.Errorf("PrefetchFiles() error code = %d, want %d (%v)", code, want, err)
+			}
+		})
+	}
+}
+
+func TestFileManager_PrefetchFilesConcurrencyBound(t *testing.T) {
+	api := &countingFileAPI{}
+	fm := NewFileManagerWithAPI(api, TestProjectID)
+
+	paths := make([]string, TestPrefetchFileCount)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file-%d.yaml", i)
+	}
+
+	files, err := fm.PrefetchFiles(context.Background(), paths, TestMainBranch, TestPrefetchConcurrency)
+	if err != nil {
+		t.Fatalf("PrefetchFiles() unexpected error: %v", err)
+	}
+	if len(files) != TestPrefetchFileCount {
+		t.Errorf("PrefetchFiles() returned %d files, want %d", len(files), TestPrefetchFileCount)
+	}
+	if api.peak > TestPrefetchConcurrency {
+		t.Errorf("PrefetchFiles() peak concurrency = %d, want at most %d", api.peak, TestPrefetchConcurrency)
+	}
+}
@@ -0,0 +1,366 @@
+// Package gitlab provides utilities for GitLab API operations
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// Pre-flight check names
+const (
+	PreflightCheckAccess          = "access"
+	PreflightCheckArchived        = "archived"
+	PreflightCheckMergeRequests   = "merge_requests"
+	PreflightCheckProtectedBranch = "protected_branch"
+	PreflightCheckPushRules       = "push_rules"
+	PreflightCheckToken           = "token"
+)
+
+// TokenScopeAPI is the token scope updates need: opening merge requests is not
+// possible with read_api or write_repository alone
+const TokenScopeAPI = "api"
+
+// PreflightAPI is the subset of the GitLab API read by the pre-flight checks
+type PreflightAPI interface {
+	ProjectAPI
+	BranchAPI
+	TokenAPI
+}
+
+// PreflightOptions describes the update the pre-flight checks verify
+type PreflightOptions struct {
+	TargetBranch  string
+	UpdateBranch  string
+	FilePaths     []string
+	CommitMessage string
+
+	// AutoMerge requires the token to be allowed to merge into the target branch
+	AutoMerge bool
+	// SignedCommits is set when the commits are GPG-signed
+	SignedCommits bool
+	// CheckToken verifies the scopes of the token and, when GitLab does not report
+	// the role of the token with the project, its project membership. Job tokens
+	// cannot read their own details and must leave it unset.
+	CheckToken bool
+}
+
+// PreflightProblem is a project setting that would make the update fail, with the
+// change that fixes it
+type PreflightProblem struct {
+	Check   string
+	Message string
+	Hint    string
+}
+
+// String renders the problem with its hint
+func (p PreflightProblem) String() string {
+	return fmt.Sprintf("%s (%s)", p.Message, p.Hint)
+}
+
+// PreflightReport lists the problems found and the checks that could not run
+type PreflightReport struct {
+	Problems []PreflightProblem
+	// Skipped maps the checks whose settings could not be read to the reason
+	Skipped map[string]string
+}
+
+// add records a problem
+func (r *PreflightReport) add(check, message, hint string) {
+	r.Problems = append(r.Problems, PreflightProblem{Check: check, Message: message, Hint: hint})
+}
+
+// skip records a check that could not run
+func (r *PreflightReport) skip(check string, err error) {
+	r.Skipped[check] = err.Error()
+}
+
+// PreflightChecker verifies before any change that the token may push the update
+// branch, open the merge request and, with auto-merge, merge it
+type PreflightChecker struct {
+	api       PreflightAPI
+	projectID interface{}
+}
+
+// NewPreflightCheckerWithAPI creates a pre-flight checker on top of the given API implementation
+func NewPreflightCheckerWithAPI(api PreflightAPI, projectID interface{}) *PreflightChecker {
+	return &PreflightChecker{api: api, projectID: projectID}
+}
+
+// Check reads the token, the project, its protected branches and push rules and
+// reports every setting that would block the update. Settings the token cannot read
+// are reported as skipped checks rather than problems, since the update may still succeed.
+func (pc *PreflightChecker) Check(ctx context.Context, opts *PreflightOptions) *PreflightReport {
+	report, access := pc.checkAccess(ctx, opts)
+
+	protected, err := collectPages(ctx, MaxPageSize*MaxListPages, MaxPageSize,
+		func(page gitlab.ListOptions) ([]*gitlab.ProtectedBranch, *gitlab.Response, error) {
+			return pc.api.ListProtectedBranches(pc.projectID,
+				&gitlab.ListProtectedBranchesOptions{ListOptions: page}, gitlab.WithContext(ctx))
+		})
+	if err != nil {
+		report.skip(PreflightCheckProtectedBranch, err)
+	} else {
+		checkProtectedBranches(report, protected, access, opts)
+	}
+
+	rules, resp, err := pc.api.GetProjectPushRules(pc.projectID, gitlab.WithContext(ctx))
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		// Push rules are not configured or not available in this GitLab edition
+	case err != nil:
+		report.skip(PreflightCheckPushRules, err)
+	case rules != nil:
+		checkPushRules(report, rules, opts)
+	}
+
+	return report
+}
+
+// CheckAccess verifies only the token and its role in the project, which every
+// command writing to the project needs. Without a project ID only the token is checked.
+func (pc *PreflightChecker) CheckAccess(ctx context.Context, opts *PreflightOptions) *PreflightReport {
+	report, _ := pc.checkAccess(ctx, opts)
+	return report
+}
+
+// checkAccess runs the token and project checks and returns the role of the token in
+// the project, or NoPermissions when it is unknown
+func (pc *PreflightChecker) checkAccess(
+	ctx context.Context,
+	opts *PreflightOptions,
+) (*PreflightReport, gitlab.AccessLevelValue) {
+	report := &PreflightReport{Skipped: make(map[string]string)}
+
+	var token *gitlab.PersonalAccessToken
+	if opts.CheckToken {
+		token = pc.checkToken(ctx, report)
+	}
+	if pc.projectID == nil {
+		return report, gitlab.NoPermissions
+	}
+
+	project, _, err := pc.api.GetProject(pc.projectID, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		report.skip(PreflightCheckAccess, err)
+		return report, gitlab.NoPermissions
+	}
+	access := projectAccess(project)
+	if access == gitlab.NoPermissions && token != nil {
+		access = pc.memberAccess(ctx, report, token.UserID)
+	}
+	checkProject(report, project, access)
+	return report, access
+}
+
+// checkToken reports an inactive token and one without the api scope, and returns the
+// token for the membership lookup. Project and group access tokens are reported too.
+func (pc *PreflightChecker) checkToken(ctx context.Context, report *PreflightReport) *gitlab.PersonalAccessToken {
+	token, _, err := pc.api.GetSinglePersonalAccessToken(gitlab.WithContext(ctx))
+	if err != nil {
+		report.skip(PreflightCheckToken, err)
+		return nil
+	}
+
+	if !token.Active || token.Revoked {
+		report.add(PreflightCheckToken, fmt.Sprintf("token %s is revoked or expired", token.Name),
+			"create a new token with the api scope")
+	}
+	if !slices.Contains(token.Scopes, TokenScopeAPI) {
+		report.add(PreflightCheckToken,
+			fmt.Sprintf("token %s has the scopes %s; updates need the %s scope",
+				token.Name, strings.Join(token.Scopes, ", "), TokenScopeAPI),
+			"create a token with the api scope; read_api and write_repository cannot open merge requests")
+	}
+	return token
+}
+
+// memberAccess returns the role of a user in the project, including roles inherited
+// from its groups, and reports users that are not members. Administrators need no
+// membership, so their role stays unknown.
+func (pc *PreflightChecker) memberAccess(
+	ctx context.Context,
+	report *PreflightReport,
+	userID int,
+) gitlab.AccessLevelValue {
+	member, resp, err := pc.api.GetInheritedProjectMember(pc.projectID, userID, gitlab.WithContext(ctx))
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		user, _, err := pc.api.CurrentUser(gitlab.WithContext(ctx))
+		if err != nil {
+			report.skip(PreflightCheckAccess, err)
+		} else if !user.IsAdmin {
+			report.add(PreflightCheckAccess,
+				fmt.Sprintf("user %s of the token is not a member of the project", user.Username),
+				"add the user or bot of the token to the project with the Developer role")
+		}
+	case err != nil:
+		report.skip(PreflightCheckAccess, err)
+	default:
+		return member.AccessLevel
+	}
+	return gitlab.NoPermissions
+}
+
+// checkProject reports archived projects, disabled merge requests and roles below Developer
+func checkProject(report *PreflightReport, project *gitlab.Project, access gitlab.AccessLevelValue) {
+	if project.Archived {
+		report.add(PreflightCheckArchived, "the project is archived and read-only",
+			"unarchive it in Settings > General > Advanced")
+	}
+	if project.MergeRequestsAccessLevel == gitlab.DisabledAccessControl {
+		report.add(PreflightCheckMergeRequests, "merge requests are disabled in the project",
+			"enable them in Settings > General > Visibility, project features, permissions")
+	}
+	if access != gitlab.NoPermissions && access < gitlab.DeveloperPermissions {
+		report.add(PreflightCheckAccess,
+			fmt.Sprintf("the token has the %s role in the project; updates need Developer or higher",
+				accessLevelName(access)),
+			"give the user or bot of the token the Developer role in the project")
+	}
+}
+
+// checkProtectedBranches reports an update branch the token cannot push to and, with
+// auto-merge, a target branch it cannot merge into
+func checkProtectedBranches(
+	report *PreflightReport,
+	protected []*gitlab.ProtectedBranch,
+	access gitlab.AccessLevelValue,
+	opts *PreflightOptions,
+) {
+	for _, branch := range protected {
+		if opts.UpdateBranch != "" && MatchProtectedBranch(branch.Name, opts.UpdateBranch) &&
+			!accessAllows(branch.PushAccessLevels, access) {
+			report.add(PreflightCheckProtectedBranch,
+				fmt.Sprintf("update branch %s matches protected branch %s, which the token cannot push to",
+					opts.UpdateBranch, branch.Name),
+				fmt.Sprintf("choose a --branch-name outside %s or allow the role of the token to push to it",
+					branch.Name))
+		}
+		if opts.AutoMerge && MatchProtectedBranch(branch.Name, opts.TargetBranch) &&
+			!accessAllows(branch.MergeAccessLevels, access) {
+			report.add(PreflightCheckProtectedBranch,
+				fmt.Sprintf("the token cannot merge into protected branch %s, so --auto-merge would never merge",
+					opts.TargetBranch),
+				fmt.Sprintf("allow the role of the token to merge into %s or drop --auto-merge", branch.Name))
+		}
+	}
+}
+
+// checkPushRules reports push rules the update commit would break. Patterns that are
+// not valid Go regular expressions, such as Ruby-only syntax, are not checked.
+func checkPushRules(report *PreflightReport, rules *gitlab.ProjectPushRules, opts *PreflightOptions) {
+	if pattern := compileRule(rules.BranchNameRegex); pattern != nil && opts.UpdateBranch != "" &&
+		!pattern.MatchString(opts.UpdateBranch) {
+		report.add(PreflightCheckPushRules,
+			fmt.Sprintf("update branch %s does not match the push rule branch name pattern %q",
+				opts.UpdateBranch, rules.BranchNameRegex),
+			"choose a --branch-name matching the pattern")
+	}
+	if pattern := compileRule(rules.CommitMessageRegex); pattern != nil && !pattern.MatchString(opts.CommitMessage) {
+		report.add(PreflightCheckPushRules,
+			fmt.Sprintf("the commit message does not match the push rule pattern %q", rules.CommitMessageRegex),
+			"relax the commit message pattern in Settings > Repository > Push rules")
+	}
+	if pattern := compileRule(rules.CommitMessageNegativeRegex); pattern != nil &&
+		pattern.MatchString(opts.CommitMessage) {
+		report.add(PreflightCheckPushRules,
+			fmt.Sprintf("the commit message matches the rejected push rule pattern %q", rules.CommitMessageNegativeRegex),
+			"relax the rejected commit message pattern in Settings > Repository > Push rules")
+	}
+	if pattern := compileRule(rules.FileNameRegex); pattern != nil {
+		for _, filePath := range opts.FilePaths {
+			if pattern.MatchString(filePath) {
+				report.add(PreflightCheckPushRules,
+					fmt.Sprintf("push rules reject files matching %q, such as %s", rules.FileNameRegex, filePath),
+					"exempt the file from the prohibited file names push rule")
+			}
+		}
+	}
+	if rules.RejectUnsignedCommits && !opts.SignedCommits {
+		report.add(PreflightCheckPushRules, "push rules reject unsigned commits",
+			"sign the commits with --commit-backend=git and --gpg-key")
+	}
+}
+
+// compileRule compiles a push rule pattern, returning nil when it is empty or invalid
+func compileRule(expr string) *regexp.Regexp {
+	if expr == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return pattern
+}
+
+// MatchProtectedBranch reports whether a branch name matches a protected branch name,
+// where * matches any characters including slashes
+func MatchProtectedBranch(pattern, name string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == name
+	}
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	return regexp.MustCompile(expr).MatchString(name)
+}
+
+// accessAllows reports whether the access levels of a protected branch may allow a
+// role. Without listed levels, or with levels granted to specific users, groups or
+// deploy keys, the outcome is unknown and the branch is assumed to allow it.
+func accessAllows(levels []*gitlab.BranchAccessDescription, access gitlab.AccessLevelValue) bool {
+	if len(levels) == 0 {
+		return true
+	}
+	for _, level := range levels {
+		if level.UserID != 0 || level.GroupID != 0 || level.DeployKeyID != 0 {
+			return true
+		}
+		if level.AccessLevel != gitlab.NoPermissions &&
+			(access == gitlab.NoPermissions || access >= level.AccessLevel) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectAccess returns the role of the token in the project, the higher of its
+// direct and group membership, or NoPermissions when GitLab does not report it
+func projectAccess(project *gitlab.Project) gitlab.AccessLevelValue {
+	access := gitlab.NoPermissions
+	if project.Permissions == nil {
+		return access
+	}
+	if project.Permissions.ProjectAccess != nil {
+		access = project.Permissions.ProjectAccess.AccessLevel
+	}
+	if group := project.Permissions.GroupAccess; group != nil && group.AccessLevel > access {
+		access = group.AccessLevel
+	}
+	return access
+}
+
+// accessLevelName returns the role name of an access level
+func accessLevelName(access gitlab.AccessLevelValue) string {
+	switch access {
+	case gitlab.MinimalAccessPermissions:
+		return "Minimal Access"
+	case gitlab.GuestPermissions:
+		return "Guest"
+	case gitlab.ReporterPermissions:
+		return "Reporter"
+	case gitlab.DeveloperPermissions:
+		return "Developer"
+	case gitlab.MaintainerPermissions:
+		return "Maintainer"
+	case gitlab.OwnerPermissions:
+		return "Owner"
+	default:
+		return fmt.Sprintf("access level %d", access)
+	}
+}
@@ -0,0 +1,167 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+func TestPreflightChecker_Check(t *testing.T) {
+	options := func() *PreflightOptions {
+		return &PreflightOptions{
+			TargetBranch:  TestMainBranch,
+			UpdateBranch:  TestFakeUpdateBranch,
+			FilePaths:     []string{TestFakeFilePath},
+			CommitMessage: TestFakeCommitMessage,
+			CheckToken:    true,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		setup       func(server *gitlabtest.Server, projectID int)
+		opts        func(o *PreflightOptions)
+		wantChecks  []string
+		wantSkipped []string
+	}{
+		{name: "nothing blocks the update"},
+		{
+			name: "reporter role",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.SetAccessLevel(projectID, gitlab.ReporterPermissions)
+			},
+			wantChecks: []string{PreflightCheckAccess},
+		},
+		{
+			name: "token without the api scope",
+			setup: func(server *gitlabtest.Server, _ int) {
+				server.SetTokenScopes("read_api", "write_repository")
+			},
+			wantChecks: []string{PreflightCheckToken},
+		},
+		{
+			name: "token not checked",
+			setup: func(server *gitlabtest.Server, _ int) {
+				server.SetTokenScopes("read_api")
+			},
+			opts: func(o *PreflightOptions) { o.CheckToken = false },
+		},
+		{
+			name: "not a member",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.SetMemberAccess(projectID, gitlab.NoPermissions)
+			},
+			wantChecks: []string{PreflightCheckAccess},
+		},
+		{
+			name: "inherited reporter role",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.SetMemberAccess(projectID, gitlab.ReporterPermissions)
+			},
+			wantChecks: []string{PreflightCheckAccess},
+		},
+		{
+			name: "update branch protected",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.ProtectBranch(projectID, "update-tag/*", gitlab.NoPermissions, gitlab.MaintainerPermissions)
+			},
+			wantChecks: []string{PreflightCheckProtectedBranch},
+		},
+		{
+			name: "auto-merge without merge rights",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.SetAccessLevel(projectID, gitlab.DeveloperPermissions)
+				server.ProtectBranch(projectID, TestMainBranch, gitlab.MaintainerPermissions, gitlab.MaintainerPermissions)
+			},
+			opts:       func(o *PreflightOptions) { o.AutoMerge = true },
+			wantChecks: []string{PreflightCheckProtectedBranch},
+		},
+		{
+			name: "maintainer may merge",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.SetAccessLevel(projectID, gitlab.MaintainerPermissions)
+				server.ProtectBranch(projectID, TestMainBranch, gitlab.MaintainerPermissions, gitlab.MaintainerPermissions)
+			},
+			opts: func(o *PreflightOptions) { o.AutoMerge = true },
+		},
+		{
+			name: "push rules",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.SetPushRules(projectID, &gitlab.ProjectPushRules{
+					BranchNameRegex:       "^(feature|fix)/",
+					CommitMessageRegex:    "^JIRA-[0-9]+",
+					FileNameRegex:         `\.yaml$`,
+					RejectUnsignedCommits: true,
+				})
+			},
+			wantChecks: []string{PreflightCheckPushRules, PreflightCheckPushRules, PreflightCheckPushRules,
+				PreflightCheckPushRules},
+		},
+		{
+			name: "unreadable protected branches",
+			setup: func(server *gitlabtest.Server, projectID int) {
+				server.FailRequests(http.MethodGet, "/protected_branches", http.StatusForbidden)
+			},
+			wantSkipped: []string{PreflightCheckProtectedBranch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newFakeProject(t)
+			if tt.setup != nil {
+				tt.setup(server, projectID)
+			}
+			opts := options()
+			if tt.opts != nil {
+				tt.opts(opts)
+			}
+
+			report := NewPreflightCheckerWithAPI(NewAPIAdapter(server.Client()), projectID).
+				Check(context.Background(), opts)
+
+			var checks []string
+			for _, problem := range report.Problems {
+				checks = append(checks, problem.Check)
+				if problem.Message == "" || problem.Hint == "" {
+					t.Errorf("problem %+v lacks a message or hint", problem)
+				}
+			}
+			if !reflect.DeepEqual(checks, tt.wantChecks) {
+				t.Errorf("Check() problems = %v, want %v", report.Problems, tt.wantChecks)
+			}
+			var skipped []string
+			for check := range report.Skipped {
+				skipped = append(skipped, check)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("Check() skipped = %v, want %v", report.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestMatchProtectedBranch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "main", name: "main", want: true},
+		{pattern: "main", name: "main-2"},
+		{pattern: "release/*", name: "release/1.0", want: true},
+		{pattern: "*-stable", name: "team/1-stable", want: true},
+		{pattern: "release/*", name: "releases/1.0"},
+		{pattern: "v1.*", name: "v1x"},
+	}
+	for _, tt := range tests {
+		if got := MatchProtectedBranch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchProtectedBranch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
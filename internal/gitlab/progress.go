@@ -0,0 +1,12 @@
+package gitlab
+
+// ProgressFunc is called by the watchers after every check while they wait, with a
+// short description of the state such as "pipeline 42 running"
+type ProgressFunc func(status string)
+
+// report calls the function when it is set
+func (fn ProgressFunc) report(status string) {
+	if fn != nil {
+		fn(status)
+	}
+}
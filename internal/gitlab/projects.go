@@ -22,7 +22,7 @@ const (
 
 // ProjectManager handles GitLab project operations and resolution
 type ProjectManager struct {
-	client *gitlab.Client
+	api ProjectAPI
 }
 
 // ProjectInfo contains detailed project information
@@ -32,6 +32,7 @@ type ProjectInfo struct {
 	Path              string
 	PathWithNamespace string
 	WebURL            string
+	HTTPURLToRepo     string
 	DefaultBranch     string
 	Description       string
 	Visibility        string
@@ -43,8 +44,13 @@ type ProjectInfo struct {
 
 // NewProjectManager creates a new project manager
 func NewProjectManager(client *gitlab.Client) *ProjectManager {
+	return NewProjectManagerWithAPI(NewAPIAdapter(client))
+}
+
+// NewProjectManagerWithAPI creates a new project manager on top of the given API implementation
+func NewProjectManagerWithAPI(api ProjectAPI) *ProjectManager {
 	return &ProjectManager{
-		client: client,
+		api: api,
 	}
 }
 
@@ -93,7 +99,7 @@ func (pm *ProjectManager) ResolveProjectPath(ctx context.Context, projectPath st
 	// URL encode the path for API call
 	encodedPath := url.PathEscape(projectPath)
 
-	project, _, err := pm.client.Projects.GetProject(encodedPath, nil)
+	project, _, err := pm.api.GetProject(encodedPath, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		// Check if it's a "not found" error
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
@@ -111,7 +117,7 @@ func (pm *ProjectManager) GetProjectInfo(ctx context.Context, projectID int) (*P
 		return nil, errors.NewValidationError(fmt.Sprintf("project ID must be >= %d", MinProjectIDValue))
 	}
 
-	project, _, err := pm.client.Projects.GetProject(projectID, nil)
+	project, _, err := pm.api.GetProject(projectID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
 			return nil, errors.NewProjectNotFoundError(fmt.Sprintf("project with ID %d not found", projectID))
@@ -128,7 +134,7 @@ func (pm *ProjectManager) ValidateProjectExists(ctx context.Context, projectID i
 		return false, errors.NewValidationError(fmt.Sprintf("project ID must be >= %d", MinProjectIDValue))
 	}
 
-	_, _, err := pm.client.Projects.GetProject(projectID, nil)
+	_, _, err := pm.api.GetProject(projectID, nil, gitlab.WithContext(ctx))
 	if err != nil {
 		// Check if it's a "not found" error
 		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
@@ -147,15 +153,11 @@ func (pm *ProjectManager) ListUserProjects(ctx context.Context, maxResults int)
 	}
 
 	opts := &gitlab.ListProjectsOptions{
-		ListOptions: gitlab.ListOptions{
-			PerPage: maxResults,
-			Page:    1,
-		},
 		Membership: gitlab.Ptr(true),  // Only projects where user is a member
 		Simple:     gitlab.Ptr(false), // Get full project info
 	}
 
-	projects, _, err := pm.client.Projects.ListProjects(opts)
+	projects, err := pm.listProjects(ctx, opts, maxResults)
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to list user projects: %v", err))
 	}
@@ -179,15 +181,11 @@ func (pm *ProjectManager) SearchProjects(ctx context.Context, query string, maxR
 	}
 
 	opts := &gitlab.ListProjectsOptions{
-		ListOptions: gitlab.ListOptions{
-			PerPage: maxResults,
-			Page:    1,
-		},
 		Search: gitlab.Ptr(query),
 		Simple: gitlab.Ptr(false),
 	}
 
-	projects, _, err := pm.client.Projects.ListProjects(opts)
+	projects, err := pm.listProjects(ctx, opts, maxResults)
 	if err != nil {
 		return nil, errors.NewAPIError(fmt.Sprintf("failed to search projects with query '%s': %v", query, err))
 	}
@@ -200,6 +198,19 @@ func (pm *ProjectManager) SearchProjects(ctx context.Context, query string, maxR
 	return result, nil
 }
 
+// listProjects pages through the projects matching opts until maxResults were read
+func (pm *ProjectManager) listProjects(
+	ctx context.Context,
+	opts *gitlab.ListProjectsOptions,
+	maxResults int,
+) ([]*gitlab.Project, error) {
+	return collectPages(ctx, maxResults, maxResults,
+		func(page gitlab.ListOptions) ([]*gitlab.Project, *gitlab.Response, error) {
+			opts.ListOptions = page
+			return pm.api.ListProjects(opts, gitlab.WithContext(ctx))
+		})
+}
+
 // GetProjectDefaultBranch returns the default branch for a project
 func (pm *ProjectManager) GetProjectDefaultBranch(ctx context.Context, projectID int) (string, error) {
 	projectInfo, err := pm.GetProjectInfo(ctx, projectID)
@@ -222,6 +233,7 @@ func (pm *ProjectManager) convertToProjectInfo(project *gitlab.Project) *Project
 		Path:              project.Path,
 		PathWithNamespace: project.PathWithNamespace,
 		WebURL:            project.WebURL,
+		HTTPURLToRepo:     project.HTTPURLToRepo,
 		DefaultBranch:     project.DefaultBranch,
 		Description:       project.Description,
 		Visibility:        string(project.Visibility),
@@ -50,7 +50,7 @@ func TestNewProjectManager(t *testing.T) {
 		t.Fatal("NewProjectManager() should return non-nil manager")
 	}
 
-	if pm.client != client.GetGitLabClient() {
+	if adapter, ok := pm.api.(*APIAdapter); !ok || adapter.Client() != client.GetGitLabClient() {
 		t.Error("NewProjectManager() should set client correctly")
 	}
 }
@@ -62,7 +62,7 @@ func TestNewProjectManager_NilClient(t *testing.T) {
 		t.Fatal("NewProjectManager() should return non-nil manager even with nil client")
 	}
 
-	if pm.client != nil {
+	if adapter, ok := pm.api.(*APIAdapter); !ok || adapter.Client() != nil {
 		t.Error("NewProjectManager() should accept nil client")
 	}
 }
@@ -0,0 +1,198 @@
+package gitlab
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// ProxyDirect is the route logged for requests that bypass the proxy
+const ProxyDirect = "direct"
+
+// ProxyOptions configures the proxy GitLab requests go through. Explicit settings
+// are layered over the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables:
+// a proxy URL replaces both proxy variables and a no-proxy list replaces NO_PROXY.
+type ProxyOptions struct {
+	// URL is the proxy of every request, such as http://proxy.example.com:3128;
+	// the environment decides when empty
+	URL string
+	// NoProxy lists the hosts reached directly, in NO_PROXY syntax: host names that
+	// also match their subdomains, IP addresses, CIDR ranges, optional ports and *
+	NoProxy string
+	// Username and Password authenticate with the proxy, replacing credentials in its URL
+	Username string
+	Password string
+	// Logf reports the route chosen for each host the first time it is requested
+	Logf func(format string, args ...interface{})
+}
+
+// Enabled reports whether the options differ from the default environment proxy
+func (o ProxyOptions) Enabled() bool {
+	return o.URL != "" || o.NoProxy != "" || o.Username != "" || o.Logf != nil
+}
+
+// ProxyFunc returns the proxy selection of http.Transport for the options
+func (o ProxyOptions) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	var proxyURL *url.URL
+	if o.URL != "" {
+		var err error
+		if proxyURL, err = parseProxyURL(o.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	noProxy := o.NoProxy
+	if noProxy == "" {
+		noProxy = environment("NO_PROXY", "no_proxy")
+	}
+
+	var logged sync.Map
+	return func(req *http.Request) (*url.URL, error) {
+		route, err := o.route(req, proxyURL, noProxy)
+		if err != nil {
+			return nil, err
+		}
+		if o.Logf != nil {
+			if _, seen := logged.LoadOrStore(req.URL.Host, true); !seen {
+				via := ProxyDirect
+				if route != nil {
+					via = route.Redacted()
+				}
+				o.Logf("GitLab requests to %s go %s", req.URL.Host, via)
+			}
+		}
+		return route, nil
+	}, nil
+}
+
+// route returns the proxy of a request, or nil to connect directly
+func (o ProxyOptions) route(req *http.Request, proxyURL *url.URL, noProxy string) (*url.URL, error) {
+	if MatchNoProxy(noProxy, req.URL.Host) {
+		return nil, nil
+	}
+
+	route := proxyURL
+	if route == nil {
+		// Without an explicit proxy the environment applies, with the no-proxy list checked
+		// above; like http.ProxyFromEnvironment it never proxies loopback hosts
+		if isLoopback(req.URL.Hostname()) {
+			return nil, nil
+		}
+		env := environment("HTTPS_PROXY", "https_proxy")
+		if req.URL.Scheme == "http" {
+			env = environment("HTTP_PROXY", "http_proxy")
+		}
+		if env == "" {
+			return nil, nil
+		}
+		parsed, err := parseProxyURL(env)
+		if err != nil {
+			return nil, err
+		}
+		route = parsed
+	}
+
+	return o.withCredentials(route), nil
+}
+
+// withCredentials returns the proxy URL with the configured credentials, if any
+func (o ProxyOptions) withCredentials(route *url.URL) *url.URL {
+	if o.Username == "" {
+		return route
+	}
+	withUser := *route
+	withUser.User = url.UserPassword(o.Username, o.Password)
+	return &withUser
+}
+
+// AuthenticatedURL returns the explicit proxy URL with the configured credentials, or
+// an empty string when the environment decides, for tools such as git that read it
+func (o ProxyOptions) AuthenticatedURL() (string, error) {
+	if o.URL == "" {
+		return "", nil
+	}
+	proxyURL, err := parseProxyURL(o.URL)
+	if err != nil {
+		return "", err
+	}
+	return o.withCredentials(proxyURL).String(), nil
+}
+
+// parseProxyURL parses a proxy URL; like the proxy environment variables a URL
+// without a scheme means an HTTP proxy
+func parseProxyURL(value string) (*url.URL, error) {
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	proxyURL, err := url.Parse(value)
+	if err != nil || proxyURL.Host == "" {
+		return nil, errors.NewConfigError(fmt.Sprintf("invalid proxy URL %q", value))
+	}
+	return proxyURL, nil
+}
+
+// MatchNoProxy reports whether host, with an optional port, is listed in a
+// comma-separated NO_PROXY list
+func MatchNoProxy(noProxy, host string) bool {
+	hostname, port := host, ""
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		hostname, port = h, p
+	}
+	hostname = strings.ToLower(hostname)
+	ip := net.ParseIP(hostname)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		entryHost = strings.TrimPrefix(strings.TrimPrefix(entryHost, "*"), ".")
+		if hostname == entryHost || strings.HasSuffix(hostname, "."+entryHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback reports whether a host name is localhost or a loopback address
+func isLoopback(hostname string) bool {
+	if strings.EqualFold(hostname, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+// environment returns the first set environment variable of names
+func environment(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
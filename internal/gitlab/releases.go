@@ -0,0 +1,24 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// ReleaseNotes returns the description of the release of tag in a project. It
+// returns false without an error when the project has no release for the tag.
+func ReleaseNotes(ctx context.Context, api ReleaseAPI, project, tag string) (string, bool, error) {
+	release, resp, err := api.GetRelease(project, tag, gitlab.WithContext(ctx))
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		return "", false, nil
+	case err != nil:
+		return "", false, errors.NewAPIError(fmt.Sprintf("failed to get release %s of project %s: %v", tag, project, err))
+	}
+	return release.Description, true, nil
+}
@@ -0,0 +1,74 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// RenameHistoryDepth bounds how many commits of a missing file are inspected for a rename
+const RenameHistoryDepth = 10
+
+// FileRename describes a commit that moved a file to a new path
+type FileRename struct {
+	OldPath string
+	NewPath string
+	Commit  *gitlab.Commit
+}
+
+// FindRename looks through the recent history of a file missing on ref for the commit
+// that renamed it. It follows consecutive renames to the latest path and returns nil
+// when the file was deleted or never existed.
+func (fm *FileManager) FindRename(ctx context.Context, filePath, ref string) (*FileRename, error) {
+	var rename *FileRename
+	seen := map[string]bool{filePath: true}
+
+	for current := filePath; ; {
+		next, commit, err := fm.findRenameOf(ctx, current, ref)
+		if err != nil || next == "" || seen[next] {
+			return rename, err
+		}
+
+		rename = &FileRename{OldPath: filePath, NewPath: next, Commit: commit}
+		seen[next] = true
+
+		exists, err := fm.FileExists(ctx, next, ref)
+		if err != nil || exists {
+			return rename, err
+		}
+		current = next
+	}
+}
+
+// findRenameOf returns the path filePath was last renamed to on ref and the commit doing so
+func (fm *FileManager) findRenameOf(ctx context.Context, filePath, ref string) (string, *gitlab.Commit, error) {
+	commits, err := fm.GetFileHistory(ctx, filePath, ref, RenameHistoryDepth)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Commits are listed newest first, so the first change of the path decides
+	for _, commit := range commits {
+		diffs, _, err := fm.api.GetCommitDiff(fm.projectID, commit.ID, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			return "", nil, errors.NewAPIError(fmt.Sprintf("failed to get diff of commit %s: %v", commit.ShortID, err))
+		}
+
+		for _, diff := range diffs {
+			if diff.OldPath != filePath {
+				continue
+			}
+			if diff.RenamedFile && diff.NewPath != filePath {
+				return diff.NewPath, commit, nil
+			}
+			if diff.DeletedFile {
+				return "", nil, nil
+			}
+		}
+	}
+
+	return "", nil, nil
+}
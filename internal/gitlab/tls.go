@@ -0,0 +1,63 @@
+package gitlab
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// TLSOptions configures how the client verifies the GitLab instance and whether it
+// presents a client certificate, for self-hosted instances behind private CAs or mTLS
+type TLSOptions struct {
+	// CACertFile is a PEM bundle of CAs trusted in addition to the system ones
+	CACertFile string
+	// ClientCertFile and ClientKeyFile are the PEM certificate and key presented to
+	// instances requiring mutual TLS; both or neither must be set
+	ClientCertFile string
+	ClientKeyFile  string
+	// InsecureSkipVerify disables certificate verification; for testing only
+	InsecureSkipVerify bool
+}
+
+// Enabled reports whether any option differs from the default TLS settings
+func (o TLSOptions) Enabled() bool {
+	return o.CACertFile != "" || o.ClientCertFile != "" || o.ClientKeyFile != "" || o.InsecureSkipVerify
+}
+
+// Config returns the TLS client configuration of the options
+func (o TLSOptions) Config() (*tls.Config, error) {
+	if (o.ClientCertFile == "") != (o.ClientKeyFile == "") {
+		return nil, errors.NewConfigError("client certificate and client key must be set together")
+	}
+
+	// #nosec G402 -- verification is only skipped on explicit request
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: o.InsecureSkipVerify}
+
+	if o.CACertFile != "" {
+		pem, err := os.ReadFile(o.CACertFile)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("failed to read CA certificate: %v", err))
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.NewConfigError(fmt.Sprintf("no PEM certificates found in %s", o.CACertFile))
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if o.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("failed to load client certificate: %v", err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
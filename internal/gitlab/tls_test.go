@@ -0,0 +1,74 @@
+package gitlab
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeServerCA writes the certificate of a TLS test server as a PEM file
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write CA certificate: %v", err)
+	}
+	return path
+}
+
+func TestNewTransport_TLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	// Handshakes rejected by the client under test are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	caFile := writeServerCA(t, server)
+	notPEM := filepath.Join(t.TempDir(), "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        TLSOptions
+		wantErr     bool
+		wantRequest bool
+	}{
+		{name: "defaults reject the private CA"},
+		{name: "CA bundle", opts: TLSOptions{CACertFile: caFile}, wantRequest: true},
+		{name: "insecure", opts: TLSOptions{InsecureSkipVerify: true}, wantRequest: true},
+		{name: "missing CA bundle", opts: TLSOptions{CACertFile: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: true},
+		{name: "CA bundle without certificates", opts: TLSOptions{CACertFile: notPEM}, wantErr: true},
+		{name: "client key without certificate", opts: TLSOptions{ClientKeyFile: caFile}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTransport(TransportOptions{TLS: tt.opts})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTransport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (transport == nil) == tt.opts.Enabled() {
+				t.Errorf("NewTransport() = %v, want nil only for default options", transport)
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			if (err == nil) != tt.wantRequest {
+				t.Errorf("request error = %v, want success %v", err, tt.wantRequest)
+			}
+		})
+	}
+}
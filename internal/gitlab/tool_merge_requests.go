@@ -0,0 +1,92 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// DefaultToolMergeRequestsLimit bounds the merge requests FindToolMergeRequests inspects
+const DefaultToolMergeRequestsLimit = 500
+
+// ToolMergeRequest is a merge request opened by go-tag-updater with the metadata of
+// its description
+type ToolMergeRequest struct {
+	*gitlab.BasicMergeRequest
+	Metadata *identity.Metadata
+}
+
+// ToolMergeRequestFilter selects the merge requests FindToolMergeRequests returns;
+// empty fields match every merge request
+type ToolMergeRequestFilter struct {
+	// State is opened, closed, merged or all; all when empty
+	State        string
+	TargetBranch string
+	SourceBranch string
+	File         string
+	NewTag       string
+	RunID        string
+	Key          string
+	// Limit bounds the merge requests inspected, newest first; DefaultToolMergeRequestsLimit when zero
+	Limit int
+}
+
+// matches reports whether metadata satisfies the metadata fields of the filter
+func (f *ToolMergeRequestFilter) matches(metadata *identity.Metadata) bool {
+	return (f.File == "" || f.File == metadata.File) &&
+		(f.NewTag == "" || f.NewTag == metadata.NewTag) &&
+		(f.RunID == "" || f.RunID == metadata.RunID) &&
+		(f.Key == "" || f.Key == metadata.Key)
+}
+
+// FindToolMergeRequests lists the merge requests whose description carries the
+// metadata block of go-tag-updater, newest first, narrowed by the filter
+func (smr *SimpleMergeRequestManager) FindToolMergeRequests(
+	ctx context.Context,
+	filter *ToolMergeRequestFilter,
+) ([]*ToolMergeRequest, error) {
+	if filter == nil {
+		filter = &ToolMergeRequestFilter{}
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultToolMergeRequestsLimit
+	}
+
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		State:   gitlab.Ptr(StateAll),
+		OrderBy: gitlab.Ptr("created_at"),
+		Sort:    gitlab.Ptr("desc"),
+	}
+	if filter.State != "" {
+		opts.State = gitlab.Ptr(filter.State)
+	}
+	if filter.TargetBranch != "" {
+		opts.TargetBranch = gitlab.Ptr(filter.TargetBranch)
+	}
+	if filter.SourceBranch != "" {
+		opts.SourceBranch = gitlab.Ptr(filter.SourceBranch)
+	}
+
+	mrs, err := collectPages(ctx, limit, OpenMergeRequestsPageSize,
+		func(page gitlab.ListOptions) ([]*gitlab.BasicMergeRequest, *gitlab.Response, error) {
+			opts.ListOptions = page
+			return smr.api.ListProjectMergeRequests(smr.projectID, opts, gitlab.WithContext(ctx))
+		})
+	if err != nil {
+		return nil, errors.NewAPIError(fmt.Sprintf("failed to list merge requests: %v", err))
+	}
+
+	var found []*ToolMergeRequest
+	for _, mr := range mrs {
+		metadata, ok := identity.ParseMarker(mr.Description)
+		if ok && filter.matches(metadata) {
+			found = append(found, &ToolMergeRequest{BasicMergeRequest: mr, Metadata: metadata})
+		}
+	}
+	return found, nil
+}
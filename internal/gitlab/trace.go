@@ -0,0 +1,126 @@
+package gitlab
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const (
+	// TraceBodyLimit bounds the bytes of each request and response body that are logged
+	TraceBodyLimit = 4096
+	// traceRedacted replaces the values of secret fields in logged bodies
+	traceRedacted = "[REDACTED]"
+)
+
+// traceHeaders are the response headers logged with each request, by log field
+var traceHeaders = []struct {
+	field  string
+	header string
+}{
+	{field: "request_id", header: "X-Request-Id"},
+	{field: "ratelimit_limit", header: "RateLimit-Limit"},
+	{field: "ratelimit_remaining", header: "RateLimit-Remaining"},
+	{field: "ratelimit_reset", header: "RateLimit-Reset"},
+	{field: "retry_after", header: "Retry-After"},
+}
+
+// secretJSONField matches JSON string fields whose names suggest a secret, such as
+// "token" or "password", in logged bodies
+var secretJSONField = regexp.MustCompile(`(?i)("[a-z_]*(?:token|password|secret)[a-z_]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// TraceOptions configures the log entry written for each GitLab request
+type TraceOptions struct {
+	// Log writes one entry with the given fields; tracing is off when nil
+	Log func(fields map[string]interface{}, message string)
+	// Bodies adds the request and response bodies, up to TraceBodyLimit bytes each and
+	// with secret fields redacted
+	Bodies bool
+}
+
+// Enabled reports whether requests are traced
+func (o TraceOptions) Enabled() bool {
+	return o.Log != nil
+}
+
+// TracingTransport logs method, path, status, duration, request ID and rate-limit
+// headers of each request it sends
+type TracingTransport struct {
+	next http.RoundTripper
+	opts TraceOptions
+}
+
+// NewTracingTransport wraps next with request tracing
+func NewTracingTransport(next http.RoundTripper, opts TraceOptions) *TracingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &TracingTransport{next: next, opts: opts}
+}
+
+// RoundTrip sends the request and logs its outcome
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := map[string]interface{}{
+		"method": req.Method,
+		"path":   req.URL.RequestURI(),
+	}
+	if t.opts.Bodies && req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		fields["request_body"] = traceBody(body, int64(len(body)))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields["duration"] = time.Since(start)
+	if err != nil {
+		fields["error"] = err.Error()
+		t.opts.Log(fields, "GitLab request failed")
+		return nil, err
+	}
+
+	fields["status"] = resp.StatusCode
+	for _, header := range traceHeaders {
+		if value := resp.Header.Get(header.header); value != "" {
+			fields[header.field] = value
+		}
+	}
+	if t.opts.Bodies {
+		// Only the logged prefix is read ahead; the caller still reads the whole body
+		prefix, readErr := io.ReadAll(io.LimitReader(resp.Body, TraceBodyLimit+1))
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(prefix), resp.Body), Closer: resp.Body}
+		if readErr == nil {
+			fields["response_body"] = traceBody(prefix, resp.ContentLength)
+		}
+	}
+	t.opts.Log(fields, "GitLab request")
+	return resp, nil
+}
+
+// traceBody returns the loggable form of a body starting with body; size is its full
+// length, or -1 when unknown
+func traceBody(body []byte, size int64) string {
+	if len(body) <= TraceBodyLimit && (size < 0 || size <= int64(len(body))) {
+		return redactBody(body)
+	}
+	if len(body) > TraceBodyLimit {
+		body = body[:TraceBodyLimit]
+	}
+	if size < 0 {
+		return redactBody(body) + "... (truncated)"
+	}
+	return redactBody(body) + fmt.Sprintf("... (truncated, %d bytes)", size)
+}
+
+// redactBody replaces the values of secret JSON fields
+func redactBody(body []byte) string {
+	return secretJSONField.ReplaceAllString(string(body), `${1}"`+traceRedacted+`"`)
+}
@@ -0,0 +1,109 @@
+package gitlab
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracingTransport(t *testing.T) {
+	large := strings.Repeat("x", TraceBodyLimit*2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.Header().Set("RateLimit-Remaining", "599")
+		if r.URL.Path == "/large" {
+			w.Header().Set("Content-Length", fmt.Sprint(len(large)))
+			_, _ = io.WriteString(w, large)
+			return
+		}
+		_, _ = io.WriteString(w, `{"name":"ci","token":"glpat-secret-value","scopes":["api"]}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		bodies bool
+		path   string
+		body   string
+		check  func(t *testing.T, fields map[string]interface{})
+	}{
+		{
+			name: "headers only",
+			path: "/api/v4/projects/1?statistics=true",
+			check: func(t *testing.T, fields map[string]interface{}) {
+				if fields["path"] != "/api/v4/projects/1?statistics=true" || fields["status"] != http.StatusOK {
+					t.Errorf("path and status = %v %v", fields["path"], fields["status"])
+				}
+				if fields["request_id"] != "req-123" || fields["ratelimit_remaining"] != "599" {
+					t.Errorf("request ID and rate limit = %v %v", fields["request_id"], fields["ratelimit_remaining"])
+				}
+				if _, ok := fields["response_body"]; ok {
+					t.Error("body logged without --trace-http")
+				}
+			},
+		},
+		{
+			name:   "bodies redacted",
+			bodies: true,
+			path:   "/api/v4/user",
+			body:   `{"password": "hunter22", "branch":"main"}`,
+			check: func(t *testing.T, fields map[string]interface{}) {
+				if got := fields["request_body"]; got != `{"password": "[REDACTED]", "branch":"main"}` {
+					t.Errorf("request body = %v", got)
+				}
+				if got := fields["response_body"]; got != `{"name":"ci","token":"[REDACTED]","scopes":["api"]}` {
+					t.Errorf("response body = %v", got)
+				}
+			},
+		},
+		{
+			name:   "large body truncated",
+			bodies: true,
+			path:   "/large",
+			check: func(t *testing.T, fields map[string]interface{}) {
+				got, _ := fields["response_body"].(string)
+				if !strings.HasSuffix(got, "... (truncated, 8192 bytes)") || len(got) > TraceBodyLimit+30 {
+					t.Errorf("response body of %d bytes ends with %q", len(got), got[len(got)-30:])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged []map[string]interface{}
+			client := &http.Client{Transport: NewTracingTransport(nil, TraceOptions{
+				Log:    func(fields map[string]interface{}, _ string) { logged = append(logged, fields) },
+				Bodies: tt.bodies,
+			})}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, http.NoBody)
+			if tt.body != "" {
+				req, err = http.NewRequest(http.MethodPost, server.URL+tt.path, strings.NewReader(tt.body))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.path == "/large" && string(body) != large {
+				t.Errorf("caller read %d bytes, want %d", len(body), len(large))
+			}
+
+			if len(logged) != 1 {
+				t.Fatalf("logged %d entries, want 1", len(logged))
+			}
+			tt.check(t, logged[0])
+		})
+	}
+}
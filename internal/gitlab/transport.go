@@ -0,0 +1,58 @@
+package gitlab
+
+import (
+	"net/http"
+)
+
+// TransportOptions configures the HTTP transport of GitLab requests
+type TransportOptions struct {
+	TLS     TLSOptions
+	Proxy   ProxyOptions
+	Cache   CacheOptions
+	Trace   TraceOptions
+	Breaker BreakerOptions
+}
+
+// NewTransport returns a copy of the default transport using the options, wrapped
+// with tracing, caching and the circuit breaker when enabled, or nil when the options
+// keep the defaults so that callers fall back to the default transport
+func NewTransport(opts TransportOptions) (http.RoundTripper, error) {
+	if !opts.TLS.Enabled() && !opts.Proxy.Enabled() && !opts.Cache.Enabled() && !opts.Trace.Enabled() &&
+		!opts.Breaker.Enabled() {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.TLS.Enabled() {
+		tlsConfig, err := opts.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if opts.Proxy.Enabled() {
+		proxy, err := opts.Proxy.ProxyFunc()
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = proxy
+	}
+
+	// Tracing sits below the cache so that the logged requests are those sent to GitLab
+	var next http.RoundTripper = transport
+	if opts.Trace.Enabled() {
+		next = NewTracingTransport(next, opts.Trace)
+	}
+	if opts.Cache.Enabled() {
+		store, err := opts.Cache.Store()
+		if err != nil {
+			return nil, err
+		}
+		next = NewCachingTransport(next, store)
+	}
+	// The breaker sits above the cache, so responses revalidated from it count as successes
+	if opts.Breaker.Enabled() {
+		next = NewBreakerTransport(next, opts.Breaker)
+	}
+	return next, nil
+}
@@ -0,0 +1,142 @@
+// Package hooks runs the local commands configured to validate updated content and
+// to integrate runs with other tools.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DefaultTimeout bounds the run of one hook command
+	DefaultTimeout = 5 * time.Minute
+	// MaxOutputBytes bounds the output of a hook kept for logs and reports; the
+	// beginning is dropped since errors are usually printed last
+	MaxOutputBytes = 16 * 1024
+	// FilePlaceholder is replaced by the path of the file a validation command checks
+	FilePlaceholder = "{}"
+
+	// tempDirPattern names the directories holding the files of validation commands
+	tempDirPattern = "go-tag-updater-validate-"
+	// tempFilePermissions keeps the validated content private to the user
+	tempFilePermissions = 0o600
+	// truncatedPrefix marks output whose beginning was dropped
+	truncatedPrefix = "...\n"
+)
+
+// Result is the outcome of one hook command
+type Result struct {
+	Command  string
+	ExitCode int
+	Duration time.Duration
+	// Output is the combined standard output and error of the command
+	Output string
+}
+
+// Run runs a command through the shell, sh on Unix and cmd on Windows, with env
+// added to the environment of the process. The result is returned with the error
+// of a command that could not be run, failed or timed out.
+func Run(ctx context.Context, command string, env []string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, command)
+	cmd.Env = append(os.Environ(), env...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	started := time.Now()
+	err := cmd.Run()
+	result := &Result{
+		Command: command,
+		// ExitCode is -1 when the command could not be started
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Duration: time.Since(started),
+		Output:   truncateOutput(output.String()),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return result, nil
+	case ctx.Err() == context.DeadlineExceeded:
+		return result, errors.NewValidationError(fmt.Sprintf("command %q timed out after %v", command, DefaultTimeout))
+	case stderrors.As(err, &exitErr):
+		return result, errors.NewValidationErrorWithContext(
+			fmt.Sprintf("command %q failed with exit code %d", command, result.ExitCode), lastLine(result.Output))
+	default:
+		return result, errors.NewValidationError(fmt.Sprintf("failed to run command %q: %v", command, err))
+	}
+}
+
+// Validate writes content to a temporary file named like filePath, so tools can
+// tell its format from the extension, and runs command on it. FilePlaceholder in
+// the command is replaced by the path of the file, which is appended to the command
+// when it has no placeholder. The command must exit with 0 for the content to pass.
+func Validate(ctx context.Context, command, filePath, content string, env []string) (*Result, error) {
+	dir, err := os.MkdirTemp("", tempDirPattern)
+	if err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to create temporary directory: %v", err))
+	}
+	defer func() {
+		_ = os.RemoveAll(dir) // Ignore cleanup error, the content was validated
+	}()
+
+	tempPath := filepath.Join(dir, filepath.Base(filePath))
+	if err := os.WriteFile(tempPath, []byte(content), tempFilePermissions); err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to write temporary file: %v", err))
+	}
+
+	quoted := quoteArgument(tempPath)
+	expanded := strings.ReplaceAll(command, FilePlaceholder, quoted)
+	if !strings.Contains(command, FilePlaceholder) {
+		expanded = command + " " + quoted
+	}
+	result, err := Run(ctx, expanded, env)
+	if result != nil {
+		result.Command = command
+	}
+	return result, err
+}
+
+// shellCommand returns the command running a command line through the shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command) // #nosec G204 -- hook commands are set by the operator
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command) // #nosec G204 -- hook commands are set by the operator
+}
+
+// quoteArgument quotes a file path for the shell of shellCommand
+func quoteArgument(path string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + path + `"`
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+// truncateOutput keeps the end of output within MaxOutputBytes
+func truncateOutput(output string) string {
+	if len(output) <= MaxOutputBytes {
+		return output
+	}
+	return truncatedPrefix + output[len(output)-MaxOutputBytes:]
+}
+
+// lastLine returns the last non-empty line of output, usually the error of a
+// failed command
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,73 @@
+//go:build !windows
+
+package hooks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestRun(t *testing.T) {
+	result, err := Run(context.Background(), `echo "$NEW_TAG"`, []string{"NEW_TAG=v1.2.3"})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || strings.TrimSpace(result.Output) != "v1.2.3" {
+		t.Errorf("Run() = %+v, want the environment echoed with exit code 0", result)
+	}
+
+	result, err = Run(context.Background(), "echo checking; echo broken >&2; exit 3", nil)
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation || result.ExitCode != 3 {
+		t.Fatalf("Run() = %+v, %v, want exit code 3 and a validation error", result, err)
+	}
+	if !strings.Contains(result.Output, "checking") || !strings.Contains(err.Error(), "exit code 3") {
+		t.Errorf("Run() output = %q, error = %v, want both streams and the exit code", result.Output, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, "true", nil); err == nil {
+		t.Error("Run() with a canceled context succeeded, want an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	const content = "image:\n  tag: v1.2.3\n"
+	tests := []struct {
+		name    string
+		command string
+		wantErr bool
+	}{
+		{name: "placeholder", command: "grep -q v1.2.3 {}"},
+		{name: "path appended", command: "grep -q v1.2.3"},
+		{name: "keeps the file name", command: `case {} in *values.yaml) exit 0;; *) exit 1;; esac`},
+		{name: "failing check", command: "grep -q v9.9.9 {}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Validate(context.Background(), tt.command, "deploy/it's values.yaml", content, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Command != tt.command {
+				t.Errorf("Validate() command = %q, want the configured command %q", result.Command, tt.command)
+			}
+		})
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	output := strings.Repeat("a", MaxOutputBytes) + "error: last line"
+	got := truncateOutput(output)
+	if !strings.HasPrefix(got, truncatedPrefix) || !strings.HasSuffix(got, "error: last line") ||
+		len(got) != len(truncatedPrefix)+MaxOutputBytes {
+		t.Errorf("truncateOutput() kept %d bytes, want the last %d", len(got), MaxOutputBytes)
+	}
+	if got := truncateOutput("short"); got != "short" {
+		t.Errorf("truncateOutput() = %q, want short output unchanged", got)
+	}
+}
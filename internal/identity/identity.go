@@ -0,0 +1,146 @@
+// Package identity recognizes the commits and merge requests made by go-tag-updater,
+// so event-driven modes can skip them instead of reacting to their own changes
+package identity
+
+import (
+	"strings"
+)
+
+const (
+	// CommitTrailerKey is the git trailer added to every commit the tool makes
+	CommitTrailerKey = "Go-Tag-Updater-Run"
+	// MarkerPrefix starts the hidden marker embedded in merge request descriptions
+	MarkerPrefix = "<!-- go-tag-updater"
+	// DefaultLabel is the merge request label identifying the tool's merge requests
+	DefaultLabel = "go-tag-updater"
+)
+
+// Reasons reported by OwnEventReason
+const (
+	// ReasonAuthor means the event was caused by one of the tool's accounts
+	ReasonAuthor = "author"
+	// ReasonLabel means the merge request carries the tool's label
+	ReasonLabel = "label"
+	// ReasonMarker means the merge request description contains the tool's marker
+	ReasonMarker = "marker"
+	// ReasonCommitTrailer means a commit carries the tool's trailer
+	ReasonCommitTrailer = "commit_trailer"
+)
+
+// Identity describes how the tool's own changes are recognized
+type Identity struct {
+	// Usernames are the GitLab accounts the tool acts as, such as the token's bot user
+	Usernames []string
+	// Label identifies the tool's merge requests; empty disables label matching
+	Label string
+}
+
+// Event is the part of a GitLab push or merge request event used for attribution
+type Event struct {
+	AuthorUsername string
+	Labels         []string
+	Description    string
+	CommitMessages []string
+}
+
+// New creates an identity for the given accounts using the default label
+func New(usernames ...string) *Identity {
+	return &Identity{
+		Usernames: usernames,
+		Label:     DefaultLabel,
+	}
+}
+
+// CommitTrailer returns the trailer line recording the run that made a commit
+func CommitTrailer(runID string) string {
+	return CommitTrailerKey + ": " + runID
+}
+
+// WithCommitTrailer appends the run trailer to a commit message
+func WithCommitTrailer(message, runID string) string {
+	if runID == "" {
+		return message
+	}
+	return strings.TrimRight(message, "\n") + "\n\n" + CommitTrailer(runID)
+}
+
+// IsOwnEvent reports whether an event was caused by the tool itself. It errs on the
+// side of attribution to suppress loops; use OwnsMergeRequest before changing a
+// merge request.
+func (i *Identity) IsOwnEvent(event *Event) bool {
+	return i.OwnEventReason(event) != ""
+}
+
+// OwnsMergeRequest reports whether the merge request of an event was opened by the
+// tool, which merging, counting or readying it requires. A label alone is not enough
+// since anyone can add it: the description must carry the tool's marker or the
+// author must be one of the tool's accounts.
+func (i *Identity) OwnsMergeRequest(event *Event) bool {
+	if event == nil {
+		return false
+	}
+	if i.isOwnAccount(event.AuthorUsername) {
+		return true
+	}
+	_, ok := ParseMarker(event.Description)
+	return ok
+}
+
+// OwnEventReason returns why an event is attributed to the tool, or an empty string
+// when it was caused by someone else
+func (i *Identity) OwnEventReason(event *Event) string {
+	if event == nil {
+		return ""
+	}
+
+	if i.isOwnAccount(event.AuthorUsername) {
+		return ReasonAuthor
+	}
+
+	if i.Label != "" {
+		for _, label := range event.Labels {
+			if strings.EqualFold(label, i.Label) {
+				return ReasonLabel
+			}
+		}
+	}
+
+	if strings.Contains(event.Description, MarkerPrefix) {
+		return ReasonMarker
+	}
+
+	for _, message := range event.CommitMessages {
+		if hasCommitTrailer(message) {
+			return ReasonCommitTrailer
+		}
+	}
+
+	return ""
+}
+
+// isOwnAccount reports whether a username is one of the tool's accounts
+func (i *Identity) isOwnAccount(username string) bool {
+	for _, own := range i.Usernames {
+		if own != "" && strings.EqualFold(own, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommitTrailer reports whether a commit message carries the tool's trailer
+func hasCommitTrailer(message string) bool {
+	_, ok := CommitRunID(message)
+	return ok
+}
+
+// CommitRunID returns the run recorded in the trailer of a commit message, and
+// whether the message carries the tool's trailer at all
+func CommitRunID(message string) (string, bool) {
+	for _, line := range strings.Split(message, "\n") {
+		if runID, ok := strings.CutPrefix(strings.TrimSpace(line), CommitTrailerKey+":"); ok {
+			return strings.TrimSpace(runID), true
+		}
+	}
+	return "", false
+}
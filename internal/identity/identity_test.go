@@ -0,0 +1,125 @@
+package identity
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	TestBotUser = "tag-bot"
+	TestRunID   = "20250101T000000Z-abcdef12"
+	TestMessage = "Update tag to v1.2.3 in deploy.yaml"
+)
+
+func TestWithCommitTrailer(t *testing.T) {
+	want := TestMessage + "\n\n" + CommitTrailerKey + ": " + TestRunID
+	if got := WithCommitTrailer(TestMessage+"\n", TestRunID); got != want {
+		t.Errorf("WithCommitTrailer() = %q, want %q", got, want)
+	}
+	if got := WithCommitTrailer(TestMessage, ""); got != TestMessage {
+		t.Errorf("WithCommitTrailer() without run ID = %q, want message unchanged", got)
+	}
+}
+
+func TestCommitRunID(t *testing.T) {
+	runID, ok := CommitRunID(WithCommitTrailer(TestMessage, TestRunID))
+	if !ok || runID != TestRunID {
+		t.Errorf("CommitRunID() = %q, %v, want %q", runID, ok, TestRunID)
+	}
+	if runID, ok := CommitRunID(TestMessage); ok || runID != "" {
+		t.Errorf("CommitRunID() without trailer = %q, %v, want none", runID, ok)
+	}
+}
+
+func TestIdentity_OwnEventReason(t *testing.T) {
+	id := New(TestBotUser)
+
+	tests := []struct {
+		name  string
+		event *Event
+		want  string
+	}{
+		{name: "nil event", event: nil, want: ""},
+		{name: "human change", event: &Event{AuthorUsername: "alice", CommitMessages: []string{"Fix typo"}}, want: ""},
+		{name: "bot author", event: &Event{AuthorUsername: "Tag-Bot"}, want: ReasonAuthor},
+		{name: "tool label", event: &Event{AuthorUsername: "alice", Labels: []string{"deps", DefaultLabel}}, want: ReasonLabel},
+		{
+			name:  "description marker",
+			event: &Event{Description: "Automated\n\n" + MarkerPrefix + ` file="a.yaml" -->`},
+			want:  ReasonMarker,
+		},
+		{
+			name:  "commit trailer",
+			event: &Event{CommitMessages: []string{"Fix typo", WithCommitTrailer(TestMessage, TestRunID)}},
+			want:  ReasonCommitTrailer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := id.OwnEventReason(tt.event); got != tt.want {
+				t.Errorf("OwnEventReason() = %q, want %q", got, tt.want)
+			}
+			if id.IsOwnEvent(tt.event) != (tt.want != "") {
+				t.Errorf("IsOwnEvent() disagrees with reason %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentity_OwnsMergeRequest(t *testing.T) {
+	id := New(TestBotUser)
+
+	tests := []struct {
+		name  string
+		event *Event
+		want  bool
+	}{
+		{name: "nil event", event: nil},
+		{name: "tool label only", event: &Event{AuthorUsername: "alice", Labels: []string{DefaultLabel}}},
+		{name: "commit trailer only", event: &Event{CommitMessages: []string{WithCommitTrailer(TestMessage, TestRunID)}}},
+		{name: "bot author", event: &Event{AuthorUsername: "Tag-Bot"}, want: true},
+		{
+			name:  "description marker",
+			event: &Event{AuthorUsername: "alice", Description: (&Metadata{RunID: TestRunID}).Marker()},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := id.OwnsMergeRequest(tt.event); got != tt.want {
+				t.Errorf("OwnsMergeRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMarker(t *testing.T) {
+	metadata := &Metadata{
+		Version: "1.4.0",
+		RunID:   TestRunID,
+		File:    "deploy/values.yaml",
+		OldTag:  "v1.2.2",
+		NewTag:  "v1.2.3-->x",
+		Key:     "0123456789ab",
+	}
+	description := "Automated tag update\n\n" + metadata.Marker() + "\n\nEdited by a reviewer"
+
+	got, ok := ParseMarker(description)
+	if !ok || *got != *metadata {
+		t.Errorf("ParseMarker() = %+v, %v, want %+v", got, ok, metadata)
+	}
+	if strings.Count(description, markerEnd) != 1 {
+		t.Errorf("Marker() does not escape the comment end in values: %q", metadata.Marker())
+	}
+
+	legacy, ok := ParseMarker(MarkerPrefix + ` file="a.yaml" tag="v2" key="abc" -->`)
+	if !ok || legacy.File != "a.yaml" || legacy.NewTag != "v2" || legacy.Key != "abc" {
+		t.Errorf("ParseMarker() of the single-line marker = %+v, %v", legacy, ok)
+	}
+
+	if got, ok := ParseMarker("Bump the tag"); ok || got != nil {
+		t.Errorf("ParseMarker() without marker = %+v, %v, want none", got, ok)
+	}
+}
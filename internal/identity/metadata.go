@@ -0,0 +1,91 @@
+package identity
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// markerEnd closes the hidden marker
+const markerEnd = "-->"
+
+// legacyMarkerField matches the key="value" fields of the single-line marker written
+// by earlier versions
+var legacyMarkerField = regexp.MustCompile(`(\w+)=("(?:[^"\\]|\\.)*")`)
+
+// Metadata is the machine-readable block embedded as a hidden HTML comment in the
+// description of every merge request the tool opens, so later runs and commands can
+// find the merge requests of a file, a tag or a run
+type Metadata struct {
+	Version string `yaml:"version,omitempty"`
+	RunID   string `yaml:"run_id,omitempty"`
+	File    string `yaml:"file"`
+	OldTag  string `yaml:"old_tag,omitempty"`
+	NewTag  string `yaml:"new_tag"`
+	// Key is the idempotency key of the update
+	Key string `yaml:"key,omitempty"`
+}
+
+// Marker returns the hidden comment carrying the metadata as YAML
+func (m *Metadata) Marker() string {
+	var b strings.Builder
+	b.WriteString(MarkerPrefix + "\n")
+	for _, field := range []struct{ key, value string }{
+		{"version", m.Version},
+		{"run_id", m.RunID},
+		{"file", m.File},
+		{"old_tag", m.OldTag},
+		{"new_tag", m.NewTag},
+		{"key", m.Key},
+	} {
+		if field.value == "" {
+			continue
+		}
+		// Double-quoted values read back as YAML, with the comment end escaped
+		quoted := strings.ReplaceAll(strconv.Quote(field.value), markerEnd, `--\x3e`)
+		fmt.Fprintf(&b, "%s: %s\n", field.key, quoted)
+	}
+	b.WriteString(markerEnd)
+	return b.String()
+}
+
+// ParseMarker returns the metadata embedded in a merge request description, and
+// whether the description carries the tool's marker at all. The single-line marker
+// of earlier versions yields the file, the tag as NewTag and the key.
+func ParseMarker(description string) (*Metadata, bool) {
+	start := strings.Index(description, MarkerPrefix)
+	if start < 0 {
+		return nil, false
+	}
+	body := description[start+len(MarkerPrefix):]
+	if end := strings.Index(body, markerEnd); end >= 0 {
+		body = body[:end]
+	}
+
+	metadata := &Metadata{}
+	if strings.HasPrefix(body, "\n") {
+		if err := yaml.Unmarshal([]byte(body), metadata); err != nil {
+			return &Metadata{}, true
+		}
+		return metadata, true
+	}
+
+	for _, match := range legacyMarkerField.FindAllStringSubmatch(body, -1) {
+		value, err := strconv.Unquote(match[2])
+		if err != nil {
+			continue
+		}
+		switch match[1] {
+		case "file":
+			metadata.File = value
+		case "tag":
+			metadata.NewTag = value
+		case "key":
+			metadata.Key = value
+		}
+	}
+	return metadata, true
+}
@@ -0,0 +1,254 @@
+// Package journal records the remote side effects of each run so they can be inspected or undone.
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DefaultStateDirName is the directory under the user's home holding run journals
+	DefaultStateDirName = ".go-tag-updater"
+	// RunsDirName is the subdirectory containing one journal file per run
+	RunsDirName = "runs"
+	// JournalFileExtension is the file extension of journal entries
+	JournalFileExtension = ".json"
+	// JournalDirPermissions defines permissions for the journal directory
+	JournalDirPermissions = 0o700
+	// JournalFilePermissions defines permissions for journal files
+	JournalFilePermissions = 0o600
+	// RunIDTimestampFormat defines the timestamp portion of generated run IDs
+	RunIDTimestampFormat = "20060102T150405Z"
+	// RunIDRandomBytes defines the number of random bytes appended to run IDs
+	RunIDRandomBytes = 4
+
+	// StatusRunning marks a run that has started but not finished
+	StatusRunning = "running"
+	// StatusCompleted marks a run that finished successfully
+	StatusCompleted = "completed"
+	// StatusFailed marks a run that finished with an error
+	StatusFailed = "failed"
+	// StatusAborted marks a run whose side effects were undone by the abort command
+	StatusAborted = "aborted"
+
+	// MergeOutcomeEnabled marks a deferred merge whose auto-merge was enabled
+	MergeOutcomeEnabled = "enabled"
+	// MergeOutcomeSkipped marks a deferred merge whose merge request was no longer open
+	MergeOutcomeSkipped = "skipped"
+)
+
+// runIDPattern restricts run IDs to characters that are safe in file names
+var runIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Entry describes one run and the remote resources it created
+type Entry struct {
+	RunID            string    `json:"run_id"`
+	Status           string    `json:"status"`
+	GitLabURL        string    `json:"gitlab_url,omitempty"`
+	ProjectID        int       `json:"project_id"`
+	ProjectPath      string    `json:"project_path"`
+	FilePath         string    `json:"file_path"`
+	OldTag           string    `json:"old_tag,omitempty"`
+	NewTag           string    `json:"new_tag"`
+	TargetBranch     string    `json:"target_branch"`
+	CreatedBranches  []string  `json:"created_branches,omitempty"`
+	MergeRequestIIDs []int     `json:"merge_request_iids,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	StartedAt        time.Time `json:"started_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// DeferredMerge is set when auto-merge waits for working hours
+	DeferredMerge *DeferredMerge `json:"deferred_merge,omitempty"`
+}
+
+// DeferredMerge records an auto-merge that the merge-later command must enable
+type DeferredMerge struct {
+	MergeRequestIID    int        `json:"merge_request_iid"`
+	NotBefore          time.Time  `json:"not_before"`
+	Squash             bool       `json:"squash,omitempty"`
+	RemoveSourceBranch bool       `json:"remove_source_branch,omitempty"`
+	Outcome            string     `json:"outcome,omitempty"`
+	ResolvedAt         *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Pending reports whether the deferred merge still has to be processed
+func (d *DeferredMerge) Pending() bool {
+	return d != nil && d.ResolvedAt == nil
+}
+
+// Journal persists run entries as JSON files in a directory
+type Journal struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// New creates a journal stored in dir, or in the default state directory when dir is empty
+func New(dir string) (*Journal, error) {
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.NewFileSystemError(fmt.Sprintf("failed to determine home directory: %v", err))
+		}
+		dir = filepath.Join(home, DefaultStateDirName, RunsDirName)
+	}
+
+	return &Journal{dir: filepath.Clean(dir)}, nil
+}
+
+// Dir returns the directory holding journal entries
+func (j *Journal) Dir() string {
+	return j.dir
+}
+
+// NewRunID generates a unique, sortable run correlation ID
+func NewRunID() string {
+	suffix := make([]byte, RunIDRandomBytes)
+	if _, err := rand.Read(suffix); err != nil {
+		return time.Now().UTC().Format(RunIDTimestampFormat)
+	}
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format(RunIDTimestampFormat), hex.EncodeToString(suffix))
+}
+
+// Save writes the entry, replacing any previous state of the same run
+func (j *Journal) Save(entry *Entry) error {
+	if entry == nil {
+		return errors.NewValidationError("journal entry cannot be nil")
+	}
+
+	path, err := j.entryPath(entry.RunID)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(j.dir, JournalDirPermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to create journal directory: %v", err))
+	}
+
+	entry.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, JournalFilePermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write journal entry: %v", err))
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath) // Ignore cleanup error, return original rename error
+		return errors.NewFileSystemError(fmt.Sprintf("failed to store journal entry: %v", err))
+	}
+
+	return nil
+}
+
+// Load reads the entry of a run
+func (j *Journal) Load(runID string) (*Entry, error) {
+	path, err := j.entryPath(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	data, err := os.ReadFile(path) // #nosec G304 -- run ID validated by entryPath
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewFileNotFoundError(path)
+		}
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to read journal entry: %v", err))
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to decode journal entry %s: %v", runID, err))
+	}
+
+	return &entry, nil
+}
+
+// RecordBranch adds a created branch to the entry and persists it
+func (j *Journal) RecordBranch(entry *Entry, branchName string) error {
+	entry.CreatedBranches = append(entry.CreatedBranches, branchName)
+	return j.Save(entry)
+}
+
+// RecordMergeRequest adds an opened merge request to the entry and persists it
+func (j *Journal) RecordMergeRequest(entry *Entry, mrIID int) error {
+	entry.MergeRequestIIDs = append(entry.MergeRequestIIDs, mrIID)
+	return j.Save(entry)
+}
+
+// RecordDeferredMerge stores an auto-merge deferred to working hours and persists it
+func (j *Journal) RecordDeferredMerge(entry *Entry, deferred *DeferredMerge) error {
+	entry.DeferredMerge = deferred
+	return j.Save(entry)
+}
+
+// ResolveDeferredMerge records the outcome of a deferred merge and persists it
+func (j *Journal) ResolveDeferredMerge(entry *Entry, outcome string) error {
+	if entry.DeferredMerge == nil {
+		return errors.NewValidationErrorWithContext("run has no deferred merge", entry.RunID)
+	}
+
+	resolvedAt := time.Now().UTC()
+	entry.DeferredMerge.Outcome = outcome
+	entry.DeferredMerge.ResolvedAt = &resolvedAt
+	return j.Save(entry)
+}
+
+// List returns all run entries ordered by run ID, which sorts them by start time
+func (j *Journal) List() ([]*Entry, error) {
+	j.mu.Lock()
+	names, err := filepath.Glob(filepath.Join(j.dir, "*"+JournalFileExtension))
+	j.mu.Unlock()
+	if err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to list journal entries: %v", err))
+	}
+
+	sort.Strings(names)
+	entries := make([]*Entry, 0, len(names))
+	for _, name := range names {
+		entry, err := j.Load(strings.TrimSuffix(filepath.Base(name), JournalFileExtension))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Finish records the final status of a run
+func (j *Journal) Finish(entry *Entry, runErr error) error {
+	entry.Status = StatusCompleted
+	if runErr != nil {
+		entry.Status = StatusFailed
+		entry.Error = runErr.Error()
+	}
+	return j.Save(entry)
+}
+
+// entryPath returns the file path of a run entry after validating the run ID
+func (j *Journal) entryPath(runID string) (string, error) {
+	if !runIDPattern.MatchString(runID) {
+		return "", errors.NewValidationErrorWithContext("invalid run ID", runID)
+	}
+	return filepath.Join(j.dir, runID+JournalFileExtension), nil
+}
@@ -0,0 +1,139 @@
+package journal
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestRunID        = "20260101T120000Z-deadbeef"
+	TestProjectID    = 123
+	TestBranchName   = "update-tag/v1.2.3-abc123"
+	TestMergeRequest = 42
+)
+
+func TestJournal_SaveLoadRoundTrip(t *testing.T) {
+	j, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	entry := &Entry{RunID: TestRunID, Status: StatusRunning, ProjectID: TestProjectID}
+	if err := j.Save(entry); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	if err := j.RecordBranch(entry, TestBranchName); err != nil {
+		t.Fatalf("RecordBranch() unexpected error: %v", err)
+	}
+	if err := j.RecordMergeRequest(entry, TestMergeRequest); err != nil {
+		t.Fatalf("RecordMergeRequest() unexpected error: %v", err)
+	}
+	if err := j.Finish(entry, fmt.Errorf("boom")); err != nil {
+		t.Fatalf("Finish() unexpected error: %v", err)
+	}
+
+	loaded, err := j.Load(TestRunID)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if loaded.Status != StatusFailed || loaded.Error != "boom" {
+		t.Errorf("Load() status = %q, error = %q", loaded.Status, loaded.Error)
+	}
+	if len(loaded.CreatedBranches) != 1 || loaded.CreatedBranches[0] != TestBranchName {
+		t.Errorf("Load() branches = %v", loaded.CreatedBranches)
+	}
+	if len(loaded.MergeRequestIIDs) != 1 || loaded.MergeRequestIIDs[0] != TestMergeRequest {
+		t.Errorf("Load() merge requests = %v", loaded.MergeRequestIIDs)
+	}
+}
+
+func TestJournal_Load(t *testing.T) {
+	j, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		runID    string
+		wantCode int
+	}{
+		{name: "unknown run", runID: TestRunID, wantCode: errors.ErrCodeFileNotFound},
+		{name: "path traversal", runID: "../secret", wantCode: errors.ErrCodeValidation},
+		{name: "empty run", runID: "", wantCode: errors.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := j.Load(tt.runID)
+			if code := errors.GetErrorCode(err); code != tt.wantCode {
+				t.Errorf("Load(%q) error code = %d, want %d (%v)", tt.runID, code, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	first := NewRunID()
+	second := NewRunID()
+
+	if first == second {
+		t.Errorf("NewRunID() returned duplicate IDs: %s", first)
+	}
+	if !runIDPattern.MatchString(first) {
+		t.Errorf("NewRunID() = %q is not a valid run ID", first)
+	}
+}
+
+func TestJournal_ListAndDeferredMerge(t *testing.T) {
+	j, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	entries, err := j.List()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("List() on empty journal = %v, %v", entries, err)
+	}
+
+	later := &Entry{RunID: "20260102T120000Z-00000002", Status: StatusCompleted}
+	earlier := &Entry{RunID: TestRunID, Status: StatusCompleted}
+	for _, entry := range []*Entry{later, earlier} {
+		if err := j.Save(entry); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+	}
+
+	if err := j.ResolveDeferredMerge(earlier, MergeOutcomeEnabled); err == nil {
+		t.Error("ResolveDeferredMerge() without a deferred merge should fail")
+	}
+	if err := j.RecordDeferredMerge(earlier, &DeferredMerge{MergeRequestIID: TestMergeRequest}); err != nil {
+		t.Fatalf("RecordDeferredMerge() unexpected error: %v", err)
+	}
+
+	entries, err = j.List()
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].RunID != TestRunID || entries[1].RunID != later.RunID {
+		t.Fatalf("List() = %v, want runs in start order", entries)
+	}
+	if !entries[0].DeferredMerge.Pending() || entries[1].DeferredMerge.Pending() {
+		t.Errorf("Pending() = %v, %v; want true, false",
+			entries[0].DeferredMerge.Pending(), entries[1].DeferredMerge.Pending())
+	}
+
+	if err := j.ResolveDeferredMerge(entries[0], MergeOutcomeEnabled); err != nil {
+		t.Fatalf("ResolveDeferredMerge() unexpected error: %v", err)
+	}
+	loaded, err := j.Load(TestRunID)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if loaded.DeferredMerge.Pending() || loaded.DeferredMerge.Outcome != MergeOutcomeEnabled {
+		t.Errorf("Load() deferred merge = %+v, want resolved as enabled", loaded.DeferredMerge)
+	}
+}
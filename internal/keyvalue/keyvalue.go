@@ -0,0 +1,417 @@
+// Package keyvalue updates values in .env and Java properties files.
+//
+// Files are edited line by line: only the value of the updated entry changes, so
+// comments, blank lines, the order of entries and the quoting of the value are
+// preserved. Values continued over several lines are listed but cannot be updated.
+package keyvalue
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Format is a key=value file format
+type Format string
+
+const (
+	// FormatEnv is a dotenv file of KEY=value lines, optionally prefixed with export
+	FormatEnv Format = "env"
+	// FormatProperties is a Java properties file of key=value, key: value or key value lines
+	FormatProperties Format = "properties"
+)
+
+const (
+	// EnvFileName is the name of dotenv files, also used as prefix (.env.production)
+	// and extension (app.env)
+	EnvFileName = ".env"
+	// PropertiesExtension is the extension of Java properties files
+	PropertiesExtension = ".properties"
+	// ExportPrefix may start dotenv entries
+	ExportPrefix = "export "
+
+	// envComment starts dotenv comment lines and, after a space, inline comments
+	envComment = "#"
+	// envInlineComment starts an inline comment after an unquoted dotenv value
+	envInlineComment = " #"
+	// propertiesComments start properties comment lines
+	propertiesComments = "#!"
+	// propertiesSeparators end properties keys, besides whitespace
+	propertiesSeparators = "=:"
+	// whitespace separates properties keys from values
+	whitespace = " \t\f"
+	// escape starts escape sequences in properties files and double-quoted dotenv values
+	escape = '\\'
+	// doubleQuote and singleQuote quote dotenv values
+	doubleQuote = '"'
+	singleQuote = '\''
+)
+
+// tagKeySuffixes are the key endings DetectKey prefers, in order
+var tagKeySuffixes = []string{"tag", "version"}
+
+// Entry is one key and its value
+type Entry struct {
+	Key   string
+	Value string
+	// Line is the 1-based line of the key
+	Line int
+	// Continued is set for values spanning several lines, which cannot be updated
+	Continued bool
+
+	// start and end delimit the raw value on its line, including quotes
+	start, end int
+	quote      byte
+}
+
+// File is a parsed key=value file
+type File struct {
+	Format  Format
+	Entries []Entry
+
+	lines []string
+}
+
+// DetectFormat returns the key=value format of a file from its name: .env, .env.*
+// and *.env are dotenv files, *.properties are properties files
+func DetectFormat(filePath string) (Format, bool) {
+	name := strings.ToLower(path.Base(strings.ReplaceAll(filePath, "\\", "/")))
+	switch {
+	case name == EnvFileName || strings.HasPrefix(name, EnvFileName+".") || strings.HasSuffix(name, EnvFileName):
+		return FormatEnv, true
+	case strings.HasSuffix(name, PropertiesExtension):
+		return FormatProperties, true
+	default:
+		return "", false
+	}
+}
+
+// Parse parses content in the given format; lines that are neither entries,
+// comments nor blank are errors
+func Parse(content string, format Format) (*File, error) {
+	file := &File{Format: format, lines: strings.Split(content, "\n")}
+
+	for i := 0; i < len(file.lines); i++ {
+		line := strings.TrimSuffix(file.lines[i], "\r")
+		var (
+			entry *Entry
+			err   error
+		)
+		switch format {
+		case FormatEnv:
+			entry, err = parseEnvLine(line)
+		case FormatProperties:
+			entry, err = parsePropertiesLine(line)
+		default:
+			return nil, errors.NewValidationError(fmt.Sprintf("unsupported key=value format %q", format))
+		}
+		if err != nil {
+			return nil, errors.NewValidationError(fmt.Sprintf("line %d: %v", i+1, err))
+		}
+		if entry == nil {
+			continue
+		}
+
+		entry.Line = i + 1
+		file.Entries = append(file.Entries, *entry)
+
+		// Skip the continuation lines of a properties value ending in an unescaped backslash
+		for entry.Continued && i+1 < len(file.lines) && continues(strings.TrimSuffix(file.lines[i], "\r")) {
+			i++
+		}
+	}
+
+	return file, nil
+}
+
+// Lookup returns the entry of key; a missing key or a key defined more than once is an error
+func (f *File) Lookup(key string) (*Entry, error) {
+	var found *Entry
+	for i := range f.Entries {
+		if f.Entries[i].Key != key {
+			continue
+		}
+		if found != nil {
+			return nil, errors.NewValidationError(fmt.Sprintf("key %s is defined more than once, on lines %d and %d",
+				key, found.Line, f.Entries[i].Line))
+		}
+		found = &f.Entries[i]
+	}
+	if found == nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("key not found: %s", key))
+	}
+	return found, nil
+}
+
+// DetectKey returns the key an update uses when none is given: the first key ending
+// in tag, then the first ending in version, ignoring case
+func (f *File) DetectKey() (string, error) {
+	for _, suffix := range tagKeySuffixes {
+		for _, entry := range f.Entries {
+			if strings.HasSuffix(strings.ToLower(entry.Key), suffix) {
+				return entry.Key, nil
+			}
+		}
+	}
+	return "", errors.NewValidationError("no key ending in tag or version found")
+}
+
+// Set returns the content with the value of key replaced by value; every other byte
+// of the content is kept
+func (f *File) Set(key, value string) (string, error) {
+	entry, err := f.Lookup(key)
+	if err != nil {
+		return "", err
+	}
+	if entry.Continued {
+		return "", errors.NewValidationError(fmt.Sprintf("value of %s on line %d spans several lines", key, entry.Line))
+	}
+
+	raw, err := f.encodeValue(entry, value)
+	if err != nil {
+		return "", err
+	}
+
+	lines := append([]string(nil), f.lines...)
+	line := lines[entry.Line-1]
+	lines[entry.Line-1] = line[:entry.start] + raw + line[entry.end:]
+	return strings.Join(lines, "\n"), nil
+}
+
+// Diff returns the keys whose values differ between original and updated content. Any
+// other change, such as an added, removed or renamed entry or an edited comment, is an
+// error.
+func Diff(original, updated string, format Format) ([]string, error) {
+	before, err := Parse(original, format)
+	if err != nil {
+		return nil, err
+	}
+	after, err := Parse(updated, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(before.lines) != len(after.lines) || len(before.Entries) != len(after.Entries) {
+		return nil, errors.NewValidationError("change adds or removes lines")
+	}
+
+	entryLines := make(map[int]bool, len(before.Entries))
+	var changed []string
+	for i, entry := range before.Entries {
+		other := after.Entries[i]
+		if entry.Key != other.Key || entry.Line != other.Line {
+			return nil, errors.NewValidationError(fmt.Sprintf("change renames or moves key %s", entry.Key))
+		}
+		if before.outsideValue(&entry) != after.outsideValue(&other) {
+			return nil, errors.NewValidationError(fmt.Sprintf("change edits line %d outside of values", entry.Line))
+		}
+		entryLines[entry.Line] = true
+		if entry.Value != other.Value {
+			changed = append(changed, entry.Key)
+		}
+	}
+
+	for i, line := range before.lines {
+		if !entryLines[i+1] && line != after.lines[i] {
+			return nil, errors.NewValidationError(fmt.Sprintf("change edits line %d outside of values", i+1))
+		}
+	}
+	return changed, nil
+}
+
+// outsideValue returns the line of the entry without its value
+func (f *File) outsideValue(entry *Entry) string {
+	line := f.lines[entry.Line-1]
+	return line[:entry.start] + line[entry.end:]
+}
+
+// parseEnvLine parses a dotenv line; comments and blank lines return no entry
+func parseEnvLine(line string) (*Entry, error) {
+	trimmed := strings.TrimLeft(line, whitespace)
+	if trimmed == "" || strings.HasPrefix(trimmed, envComment) {
+		return nil, nil
+	}
+	offset := len(line) - len(trimmed)
+	if rest, found := strings.CutPrefix(trimmed, ExportPrefix); found {
+		offset += len(trimmed) - len(strings.TrimLeft(rest, whitespace))
+		trimmed = strings.TrimLeft(rest, whitespace)
+	}
+
+	key, _, found := strings.Cut(trimmed, "=")
+	key = strings.TrimRight(key, whitespace)
+	if !found || key == "" || strings.ContainsAny(key, whitespace) {
+		return nil, fmt.Errorf("expected KEY=value")
+	}
+
+	start := offset + strings.Index(trimmed, "=") + 1
+	start += len(line[start:]) - len(strings.TrimLeft(line[start:], whitespace))
+	entry := &Entry{Key: key, start: start}
+
+	value := line[start:]
+	if value != "" && (value[0] == doubleQuote || value[0] == singleQuote) {
+		closing := closingQuote(value)
+		if closing < 0 {
+			return nil, fmt.Errorf("unterminated quoted value of %s; multi-line values are not supported", key)
+		}
+		entry.quote = value[0]
+		entry.end = start + closing + 1
+		entry.Value = value[1:closing]
+		if entry.quote == doubleQuote {
+			entry.Value = unescapeDoubleQuoted(entry.Value)
+		}
+		return entry, nil
+	}
+
+	if comment := strings.Index(value, envInlineComment); comment >= 0 {
+		value = value[:comment]
+	}
+	value = strings.TrimRight(value, whitespace)
+	entry.end = start + len(value)
+	entry.Value = value
+	return entry, nil
+}
+
+// closingQuote returns the index of the quote closing the value starting with a quote,
+// or -1; backslashes escape double quotes
+func closingQuote(value string) int {
+	quote := value[0]
+	for i := 1; i < len(value); i++ {
+		switch {
+		case value[i] == escape && quote == doubleQuote:
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDoubleQuoted removes the backslashes of escaped characters
+func unescapeDoubleQuoted(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == escape && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// parsePropertiesLine parses a properties line; comments and blank lines return no entry
+func parsePropertiesLine(line string) (*Entry, error) {
+	trimmed := strings.TrimLeft(line, whitespace)
+	if trimmed == "" || strings.ContainsRune(propertiesComments, rune(trimmed[0])) {
+		return nil, nil
+	}
+	offset := len(line) - len(trimmed)
+
+	// The key ends at the first unescaped separator or whitespace
+	keyEnd := len(trimmed)
+	for i := 0; i < len(trimmed); i++ {
+		if trimmed[i] == escape {
+			i++
+			continue
+		}
+		if strings.ContainsRune(whitespace+propertiesSeparators, rune(trimmed[i])) {
+			keyEnd = i
+			break
+		}
+	}
+
+	// Whitespace, at most one separator and more whitespace lead to the value
+	start := keyEnd
+	start += len(trimmed[start:]) - len(strings.TrimLeft(trimmed[start:], whitespace))
+	if start < len(trimmed) && strings.ContainsRune(propertiesSeparators, rune(trimmed[start])) {
+		start++
+		start += len(trimmed[start:]) - len(strings.TrimLeft(trimmed[start:], whitespace))
+	}
+
+	raw := trimmed[start:]
+	entry := &Entry{
+		Key:       unescapeProperties(trimmed[:keyEnd]),
+		start:     offset + start,
+		end:       len(line),
+		Continued: continues(line),
+	}
+	if entry.Key == "" {
+		return nil, fmt.Errorf("expected key=value")
+	}
+	if entry.Continued {
+		raw = raw[:len(raw)-1]
+	}
+	entry.Value = unescapeProperties(raw)
+	return entry, nil
+}
+
+// continues reports whether a properties line ends in an unescaped backslash
+func continues(line string) bool {
+	backslashes := len(line) - len(strings.TrimRight(line, string(escape)))
+	return backslashes%2 == 1
+}
+
+// unescapeProperties resolves the escape sequences of a properties key or value
+func unescapeProperties(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != escape || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// encodeValue returns the raw text of value for the entry, keeping its quoting
+func (f *File) encodeValue(entry *Entry, value string) (string, error) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", errors.NewValidationError("value cannot contain line breaks")
+	}
+
+	if f.Format == FormatProperties {
+		var b strings.Builder
+		for i := 0; i < len(value); i++ {
+			if value[i] == escape || (i == 0 && strings.ContainsRune(whitespace, rune(value[i]))) {
+				b.WriteByte(escape)
+			}
+			b.WriteByte(value[i])
+		}
+		return b.String(), nil
+	}
+
+	switch entry.quote {
+	case singleQuote:
+		if strings.ContainsRune(value, singleQuote) {
+			return "", errors.NewValidationError(fmt.Sprintf("value of %s cannot contain a single quote", entry.Key))
+		}
+		return string(singleQuote) + value + string(singleQuote), nil
+	case doubleQuote:
+		return quoteDouble(value), nil
+	default:
+		// Unquoted values gain quotes when a shell sourcing the file would split or cut them
+		if value == "" || strings.ContainsAny(value, whitespace+"#\"'$`\\") {
+			return quoteDouble(value), nil
+		}
+		return value, nil
+	}
+}
+
+// quoteDouble double-quotes a dotenv value, escaping backslashes and quotes
+func quoteDouble(value string) string {
+	replacer := strings.NewReplacer(string(escape), `\\`, string(doubleQuote), `\"`)
+	return string(doubleQuote) + replacer.Replace(value) + string(doubleQuote)
+}
@@ -0,0 +1,220 @@
+package keyvalue
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	TestEnvContent = `# Deployment settings
+export APP_NAME=api
+IMAGE_TAG=v1.0.0 # bumped by CI
+
+SIDECAR_VERSION="1.4.0"
+QUOTED_TAG='v0.9.0'
+`
+	TestPropertiesContent = "# Service images\r\n" +
+		"! legacy comment\r\n" +
+		"app.name = api\r\n" +
+		"image.tag=v1.0.0\r\n" +
+		"jvm.options: -Xmx1g \\\r\n" +
+		"    -Xms512m\r\n" +
+		"agent.version 2.1\r\n"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filePath string
+		want     Format
+		ok       bool
+	}{
+		{filePath: ".env", want: FormatEnv, ok: true},
+		{filePath: "deploy/.env.production", want: FormatEnv, ok: true},
+		{filePath: "config/app.env", want: FormatEnv, ok: true},
+		{filePath: "src/main/resources/application.properties", want: FormatProperties, ok: true},
+		{filePath: "values.yaml"},
+		{filePath: "environment.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			got, ok := DetectFormat(tt.filePath)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("DetectFormat(%q) = %q, %v; want %q, %v", tt.filePath, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  Format
+		want    map[string]string
+	}{
+		{
+			name:    "env",
+			content: TestEnvContent,
+			format:  FormatEnv,
+			want: map[string]string{
+				"APP_NAME": "api", "IMAGE_TAG": "v1.0.0", "SIDECAR_VERSION": "1.4.0", "QUOTED_TAG": "v0.9.0",
+			},
+		},
+		{
+			name:    "properties",
+			content: TestPropertiesContent,
+			format:  FormatProperties,
+			want: map[string]string{
+				"app.name": "api", "image.tag": "v1.0.0", "jvm.options": "-Xmx1g ", "agent.version": "2.1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.content, tt.format)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if len(file.Entries) != len(tt.want) {
+				t.Fatalf("Parse() found %d entries, want %d: %+v", len(file.Entries), len(tt.want), file.Entries)
+			}
+			for _, entry := range file.Entries {
+				if want, ok := tt.want[entry.Key]; !ok || entry.Value != want {
+					t.Errorf("entry %s = %q, want %q", entry.Key, entry.Value, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Parse("IMAGE_TAG\n", FormatEnv); err == nil {
+		t.Error("Parse() should reject dotenv lines without a value")
+	}
+	if _, err := Parse("KEY=\"open\n", FormatEnv); err == nil {
+		t.Error("Parse() should reject unterminated quotes")
+	}
+}
+
+func TestFile_Set(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		format      Format
+		key         string
+		value       string
+		want        string
+		expectError bool
+	}{
+		{
+			name: "unquoted env value keeps inline comment", content: TestEnvContent, format: FormatEnv,
+			key: "IMAGE_TAG", value: "v1.2.3",
+			want: strings.Replace(TestEnvContent, "IMAGE_TAG=v1.0.0", "IMAGE_TAG=v1.2.3", 1),
+		},
+		{
+			name: "double-quoted env value", content: TestEnvContent, format: FormatEnv,
+			key: "SIDECAR_VERSION", value: "1.5.0",
+			want: strings.Replace(TestEnvContent, `"1.4.0"`, `"1.5.0"`, 1),
+		},
+		{
+			name: "single-quoted env value", content: TestEnvContent, format: FormatEnv,
+			key: "QUOTED_TAG", value: "v1.0.0",
+			want: strings.Replace(TestEnvContent, "'v0.9.0'", "'v1.0.0'", 1),
+		},
+		{
+			name: "exported env value", content: TestEnvContent, format: FormatEnv,
+			key: "APP_NAME", value: "api two",
+			want: strings.Replace(TestEnvContent, "APP_NAME=api", `APP_NAME="api two"`, 1),
+		},
+		{
+			name: "properties value keeps CRLF", content: TestPropertiesContent, format: FormatProperties,
+			key: "image.tag", value: "v1.2.3",
+			want: strings.Replace(TestPropertiesContent, "image.tag=v1.0.0", "image.tag=v1.2.3", 1),
+		},
+		{
+			name: "properties whitespace separator", content: TestPropertiesContent, format: FormatProperties,
+			key: "agent.version", value: "2.2",
+			want: strings.Replace(TestPropertiesContent, "agent.version 2.1", "agent.version 2.2", 1),
+		},
+		{
+			name: "continued properties value", content: TestPropertiesContent, format: FormatProperties,
+			key: "jvm.options", value: "-Xmx2g", expectError: true,
+		},
+		{
+			name: "missing key", content: TestEnvContent, format: FormatEnv,
+			key: "MISSING_TAG", value: "v1", expectError: true,
+		},
+		{
+			name: "duplicate key", content: "TAG=v1\nTAG=v2\n", format: FormatEnv,
+			key: "TAG", value: "v3", expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.content, tt.format)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			got, err := file.Set(tt.key, tt.value)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Set() expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Set() =\n%q\nwant\n%q", got, tt.want)
+			}
+
+			updated, err := Parse(got, tt.format)
+			if err != nil {
+				t.Fatalf("Parse() of the update unexpected error: %v", err)
+			}
+			if entry, err := updated.Lookup(tt.key); err != nil || entry.Value != tt.value {
+				t.Errorf("updated value of %s = %+v, %v; want %q", tt.key, entry, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestFile_DetectKey(t *testing.T) {
+	file, err := Parse(TestEnvContent, FormatEnv)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if key, err := file.DetectKey(); err != nil || key != "IMAGE_TAG" {
+		t.Errorf("DetectKey() = %q, %v; want IMAGE_TAG", key, err)
+	}
+
+	file, err = Parse("NAME=api\n", FormatEnv)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if _, err := file.DetectKey(); err == nil {
+		t.Error("DetectKey() expected error but got none")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	updated := strings.Replace(TestEnvContent, "v1.0.0", "v1.2.3", 1)
+	changed, err := Diff(TestEnvContent, updated, FormatEnv)
+	if err != nil || len(changed) != 1 || changed[0] != "IMAGE_TAG" {
+		t.Errorf("Diff() = %v, %v; want [IMAGE_TAG]", changed, err)
+	}
+
+	for name, content := range map[string]string{
+		"edited comment": strings.Replace(TestEnvContent, "# Deployment", "# Changed", 1),
+		"renamed key":    strings.Replace(TestEnvContent, "IMAGE_TAG", "OTHER_TAG", 1),
+		"added entry":    TestEnvContent + "EXTRA=1\n",
+		"inline comment": strings.Replace(TestEnvContent, "# bumped", "# pinned", 1),
+	} {
+		if _, err := Diff(TestEnvContent, content, FormatEnv); err == nil {
+			t.Errorf("Diff() of %s expected error but got none", name)
+		}
+	}
+}
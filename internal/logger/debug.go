@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/Gosayram/go-tag-updater/internal/terminal"
 )
 
 const (
@@ -99,6 +101,9 @@ type Config struct {
 	ReportCaller bool
 	Output       io.Writer
 	Component    string
+
+	// DisableColors turns off colored text output; it is forced on in non-interactive mode
+	DisableColors bool
 }
 
 // New creates a new logger instance with debug mode setting
@@ -153,7 +158,7 @@ func NewWithConfig(config *Config) *Logger {
 		logger.SetFormatter(&logrus.TextFormatter{
 			FullTimestamp:   DefaultTimestamp,
 			TimestampFormat: time.RFC3339,
-			DisableColors:   false,
+			DisableColors:   config.DisableColors || terminal.NonInteractiveForced(),
 			ForceColors:     false,
 		})
 	default:
@@ -165,6 +170,10 @@ func NewWithConfig(config *Config) *Logger {
 	// Set caller reporting
 	logger.SetReportCaller(config.ReportCaller)
 
+	// Scrub registered secrets from every entry
+	logger.AddHook(redactHook{})
+	logger.AddHook(timezoneHook{})
+
 	return &Logger{
 		logrus:       logger,
 		debug:        config.Debug,
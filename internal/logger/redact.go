@@ -0,0 +1,99 @@
+// Package logger provides structured logging functionality for go-tag-updater.
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RedactedPlaceholder replaces registered secrets in log output
+	RedactedPlaceholder = "[REDACTED]"
+	// MinSecretLength is the shortest value accepted as a secret; shorter values would
+	// redact unrelated text
+	MinSecretLength = 4
+)
+
+// secretRegistry holds the values scrubbed from every log entry
+var secretRegistry = struct {
+	sync.RWMutex
+	values []string
+}{}
+
+// RegisterSecret scrubs value from the messages and fields of all subsequent log
+// entries of every logger. Values shorter than MinSecretLength are ignored.
+func RegisterSecret(value string) {
+	if len(value) < MinSecretLength {
+		return
+	}
+
+	secretRegistry.Lock()
+	defer secretRegistry.Unlock()
+
+	for _, existing := range secretRegistry.values {
+		if existing == value {
+			return
+		}
+	}
+
+	// Longer secrets first, so a secret containing another one is fully replaced
+	secretRegistry.values = append(secretRegistry.values, value)
+	sort.Slice(secretRegistry.values, func(i, j int) bool {
+		return len(secretRegistry.values[i]) > len(secretRegistry.values[j])
+	})
+}
+
+// Redact replaces every registered secret in text with RedactedPlaceholder
+func Redact(text string) string {
+	secretRegistry.RLock()
+	defer secretRegistry.RUnlock()
+
+	for _, secret := range secretRegistry.values {
+		text = strings.ReplaceAll(text, secret, RedactedPlaceholder)
+	}
+	return text
+}
+
+// redactHook scrubs registered secrets from log entries before they are formatted
+type redactHook struct{}
+
+// Levels returns all levels so no entry escapes redaction
+func (redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts the message and every field value of the entry
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	for key, value := range entry.Data {
+		entry.Data[key] = redactValue(value)
+	}
+	return nil
+}
+
+// redactValue returns the value unchanged unless its text contains a secret, in which
+// case the redacted text is returned instead
+func redactValue(value interface{}) interface{} {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return Redact(v)
+	case error:
+		text = v.Error()
+	case fmt.Stringer:
+		text = v.String()
+	default:
+		text = fmt.Sprintf("%v", v)
+	}
+
+	if redacted := Redact(text); redacted != text {
+		return redacted
+	}
+	return value
+}
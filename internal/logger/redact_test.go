@@ -0,0 +1,79 @@
+// Package logger provides structured logging functionality for go-tag-updater.
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	TestSecretToken = "glpat-secret-token-value"
+	TestShortSecret = "abc"
+)
+
+// resetSecrets clears the secret registry after a test
+func resetSecrets(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		secretRegistry.Lock()
+		secretRegistry.values = nil
+		secretRegistry.Unlock()
+	})
+}
+
+func TestRedact(t *testing.T) {
+	resetSecrets(t)
+	RegisterSecret(TestSecretToken)
+	RegisterSecret(TestShortSecret)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "no secret", text: TestMessage, want: TestMessage},
+		{name: "secret in text", text: "token=" + TestSecretToken, want: "token=" + RedactedPlaceholder},
+		{name: "short values are not secrets", text: TestShortSecret, want: TestShortSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.text); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactHook(t *testing.T) {
+	resetSecrets(t)
+	RegisterSecret(TestSecretToken)
+
+	var buf bytes.Buffer
+	logger := NewWithConfig(&Config{
+		Level:     LevelDebug,
+		Format:    FormatJSON,
+		Output:    &buf,
+		Component: TestComponent,
+	})
+
+	logger.WithFields(map[string]interface{}{
+		"token":   TestSecretToken,
+		"headers": map[string]string{"PRIVATE-TOKEN": TestSecretToken},
+		"count":   TestMRID,
+	}).WithError(fmt.Errorf("request with %s failed", TestSecretToken)).
+		Debugf("using token %s", TestSecretToken)
+
+	output := buf.String()
+	if strings.Contains(output, TestSecretToken) {
+		t.Fatalf("log output leaks the secret: %s", output)
+	}
+	if strings.Count(output, RedactedPlaceholder) != 4 {
+		t.Errorf("log output should redact message and three fields: %s", output)
+	}
+	if !strings.Contains(output, fmt.Sprintf(`"count":%d`, TestMRID)) {
+		t.Errorf("log output should keep non-secret fields: %s", output)
+	}
+}
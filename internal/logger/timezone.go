@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/Gosayram/go-tag-updater/internal/clock"
+)
+
+// timezoneHook stamps log entries in the time zone of generated timestamps, UTC
+// unless overridden, so logs of runners in different zones correlate
+type timezoneHook struct{}
+
+// Levels returns all levels so every entry is stamped the same way
+func (timezoneHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire converts the entry time to the configured time zone
+func (timezoneHook) Fire(entry *logrus.Entry) error {
+	entry.Time = clock.In(entry.Time)
+	return nil
+}
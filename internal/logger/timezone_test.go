@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/clock"
+)
+
+func TestTimezoneHook(t *testing.T) {
+	tokyo, err := clock.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	t.Cleanup(func() { clock.SetLocation(nil) })
+
+	tests := []struct {
+		name       string
+		location   *time.Location
+		wantOffset string
+	}{
+		{name: "UTC by default", wantOffset: "Z"},
+		{name: "override", location: tokyo, wantOffset: "+09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.SetLocation(tt.location)
+
+			var buf bytes.Buffer
+			logger := NewWithConfig(&Config{
+				Level:     LevelInfo,
+				Format:    FormatJSON,
+				Output:    &buf,
+				Component: TestComponent,
+			})
+			logger.Info("stamped")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to decode log entry %q: %v", buf.String(), err)
+			}
+			timestamp, _ := entry["timestamp"].(string)
+			if !strings.HasSuffix(timestamp, tt.wantOffset) {
+				t.Errorf("timestamp = %q, want offset %s", timestamp, tt.wantOffset)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// ArgoCDAPIGroup is the API group of Argo CD resources
+	ArgoCDAPIGroup = "argoproj.io/"
+	// ArgoCDApplicationKind is the kind of Argo CD Applications
+	ArgoCDApplicationKind = "Application"
+	// ArgoCDRevisionField is the field holding the revision of a source
+	ArgoCDRevisionField = "targetRevision"
+)
+
+// argoCDTargets returns the targetRevision of spec.source and of every entry of
+// spec.sources of an Argo CD Application, limited to the sources of opts.RepoURL
+func argoCDTargets(document *yaml.Node, index int, opts Options) ([]Target, error) {
+	if !strings.HasPrefix(scalarValue(document, "apiVersion"), ArgoCDAPIGroup) ||
+		scalarValue(document, "kind") != ArgoCDApplicationKind {
+		return nil, nil
+	}
+	spec := mappingValue(document, "spec")
+
+	var targets []Target
+	if source := mappingValue(spec, "source"); source != nil {
+		target, err := argoCDSourceTarget(source, "spec.source", index, opts)
+		if err != nil {
+			return nil, err
+		}
+		targets = appendTarget(targets, target)
+	}
+
+	if sources := mappingValue(spec, "sources"); sources != nil && sources.Kind == yaml.SequenceNode {
+		for n, source := range sources.Content {
+			target, err := argoCDSourceTarget(source, fmt.Sprintf("spec.sources[%d]", n), index, opts)
+			if err != nil {
+				return nil, err
+			}
+			targets = appendTarget(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// argoCDSourceTarget returns the targetRevision of one source, or nil when the source
+// is of another repository
+func argoCDSourceTarget(source *yaml.Node, path string, index int, opts Options) (*Target, error) {
+	if opts.RepoURL != "" && !SameRepoURL(scalarValue(source, "repoURL"), opts.RepoURL) {
+		return nil, nil
+	}
+	if mappingValue(source, ArgoCDRevisionField) == nil {
+		return nil, errors.NewValidationErrorWithContext(
+			fmt.Sprintf("%s of the Argo CD Application in document %d has no %s", path, index, ArgoCDRevisionField),
+			"add the field, e.g. targetRevision: HEAD, before updating it")
+	}
+	return &Target{YAMLPath: path + "." + ArgoCDRevisionField, Document: index}, nil
+}
+
+// appendTarget appends target when it is set
+func appendTarget(targets []Target, target *Target) []Target {
+	if target == nil {
+		return targets
+	}
+	return append(targets, *target)
+}
+
+// SameRepoURL reports whether two repository URLs name the same repository,
+// ignoring case, the scheme, a trailing slash or .git suffix and the scp-like form
+// of SSH URLs, so https://gitlab.com/group/app.git matches git@gitlab.com:group/app
+func SameRepoURL(a, b string) bool {
+	return normalizeRepoURL(a) == normalizeRepoURL(b)
+}
+
+// normalizeRepoURL reduces a repository URL to host and path
+func normalizeRepoURL(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	if scheme := strings.Index(url, "://"); scheme >= 0 {
+		url = url[scheme+len("://"):]
+	} else if at := strings.Index(url, "@"); at >= 0 && strings.Contains(url[at:], ":") {
+		// scp-like SSH form: git@host:group/app
+		url = strings.Replace(url, ":", "/", 1)
+	}
+	if at := strings.Index(url, "@"); at >= 0 {
+		url = url[at+1:]
+	}
+	url = strings.TrimSuffix(url, "/")
+	return strings.TrimSuffix(url, ".git")
+}
@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	yamldoc "github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// imageDigestSeparator starts the digest of an image reference
+	imageDigestSeparator = "@"
+	// imagePathSeparator separates the registry and path segments of a repository
+	imagePathSeparator = "/"
+)
+
+// composeTargets returns the image of the service of a Compose file named by
+// opts.Service, or of every service with an image when no service is named.
+// Unnamed services built from source without an image, or with an image pinned by
+// digest, have no tag to update and are skipped.
+func composeTargets(document *yaml.Node, index int, opts Options) ([]Target, error) {
+	services := mappingValue(document, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var targets []Target
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		name, service := services.Content[i].Value, services.Content[i+1]
+		switch {
+		case opts.Service != "" && name != opts.Service:
+			continue
+		case opts.Service == "" && !hasImageTag(service):
+			continue
+		}
+		target, err := composeServiceTarget(service, name, index)
+		if err != nil {
+			return nil, err
+		}
+		targets = appendTarget(targets, target)
+	}
+	return targets, nil
+}
+
+// composeServiceTarget returns the image of one service
+func composeServiceTarget(service *yaml.Node, name string, index int) (*Target, error) {
+	image := mappingValue(service, "image")
+	if image == nil {
+		if mappingValue(service, "build") != nil {
+			return nil, errors.NewValidationErrorWithContext(
+				fmt.Sprintf("service %s is built from source and has no image to tag", name),
+				"add image: <repository> next to build so the built image is named")
+		}
+		return nil, errors.NewValidationError(fmt.Sprintf("service %s has no image", name))
+	}
+	if image.Kind != yaml.ScalarNode {
+		return nil, errors.NewValidationError(fmt.Sprintf("the image of service %s is not a string", name))
+	}
+	if strings.Contains(image.Value, imageDigestSeparator) {
+		return nil, errors.NewValidationErrorWithContext(
+			fmt.Sprintf("the image of service %s is pinned by digest: %s", name, image.Value),
+			"remove the digest to update the image by tag")
+	}
+	return &Target{YAMLPath: "services." + name + ".image", Document: index, ImageTag: true}, nil
+}
+
+// hasImageTag reports whether the service has an image that is not pinned by digest
+func hasImageTag(service *yaml.Node) bool {
+	image := mappingValue(service, "image")
+	return image != nil && image.Kind == yaml.ScalarNode && !strings.Contains(image.Value, imageDigestSeparator)
+}
+
+// SplitImage splits an image reference such as registry:5000/app:1.2 into its
+// repository and tag; the tag is empty when the reference has none
+func SplitImage(ref string) (repository, tag string) {
+	// A colon before the last path segment is the port of the registry
+	lastSegment := strings.LastIndex(ref, imagePathSeparator)
+	if colon := strings.LastIndex(ref, yamldoc.ImageTagSeparator); colon > lastSegment {
+		return ref[:colon], ref[colon+1:]
+	}
+	return ref, ""
+}
+
+// WithImageTag returns the image reference with its tag replaced by tag, or with
+// tag added when the reference has none
+func WithImageTag(ref, tag string) string {
+	repository, _ := SplitImage(ref)
+	return repository + yamldoc.ImageTagSeparator + tag
+}
@@ -0,0 +1,71 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// FluxHelmAPIGroup is the API group of Flux Helm resources
+	FluxHelmAPIGroup = "helm.toolkit.fluxcd.io/"
+	// FluxHelmReleaseKind is the kind of Flux HelmReleases
+	FluxHelmReleaseKind = "HelmRelease"
+
+	// FluxModeChart updates the chart version of a HelmRelease
+	FluxModeChart = "chart"
+	// FluxModeImage updates the image tag set in the values of a HelmRelease
+	FluxModeImage = "image"
+)
+
+// fluxFields are the fields of a HelmRelease updated in each mode
+var fluxFields = map[string][]string{
+	FluxModeChart: {"spec", "chart", "spec", "version"},
+	FluxModeImage: {"spec", "values", "image", "tag"},
+}
+
+// CheckFluxMode checks mode is a HelmRelease mode; empty stands for FluxModeChart
+func CheckFluxMode(mode string) error {
+	if _, ok := fluxFields[fluxMode(mode)]; !ok {
+		return errors.NewValidationError(fmt.Sprintf("unknown HelmRelease mode %q, expected %s or %s",
+			mode, FluxModeChart, FluxModeImage))
+	}
+	return nil
+}
+
+// fluxMode returns the mode, FluxModeChart when empty
+func fluxMode(mode string) string {
+	if mode == "" {
+		return FluxModeChart
+	}
+	return mode
+}
+
+// fluxHelmReleaseTargets returns the field of a Flux HelmRelease selected by the mode
+// of opts: the chart version, or the image tag of its values
+func fluxHelmReleaseTargets(document *yaml.Node, index int, opts Options) ([]Target, error) {
+	if !strings.HasPrefix(scalarValue(document, "apiVersion"), FluxHelmAPIGroup) ||
+		scalarValue(document, "kind") != FluxHelmReleaseKind {
+		return nil, nil
+	}
+	if err := CheckFluxMode(opts.FluxMode); err != nil {
+		return nil, err
+	}
+
+	path := fluxFields[fluxMode(opts.FluxMode)]
+	node := document
+	for _, key := range path {
+		node = mappingValue(node, key)
+	}
+	yamlPath := strings.Join(path, ".")
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return nil, errors.NewValidationErrorWithContext(
+			fmt.Sprintf("the HelmRelease %s in document %d has no %s", scalarValue(document, "metadata", "name"),
+				index, yamlPath),
+			"pick the other field with --flux-mode, or pass --yaml-path")
+	}
+	return []Target{{YAMLPath: yamlPath, Document: index}}, nil
+}
@@ -0,0 +1,156 @@
+// Package manifest locates the version field of well-known manifest kinds, such as
+// the target revision of an Argo CD Application, so a tag update can be pointed at a
+// kind of file instead of a YAML path.
+//
+// Each kind is an updater profile selected by name. Resolving a profile against the
+// content of a file returns the YAML path of the field and the document holding it.
+package manifest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	yamldoc "github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// ArgoCD updates the targetRevision of the source of an Argo CD Application
+	ArgoCD = "argocd"
+	// FluxHelmRelease updates the chart version or image tag of a Flux HelmRelease
+	FluxHelmRelease = "flux-helmrelease"
+	// Compose updates the image tag of a service of a Docker Compose file
+	Compose = "compose"
+)
+
+// Options narrow the field a profile picks when a file holds several candidates
+type Options struct {
+	// RepoURL selects the Argo CD source with this repository URL
+	RepoURL string
+	// FluxMode selects the HelmRelease field: FluxModeChart, the default, or FluxModeImage
+	FluxMode string
+	// Service selects the Compose service whose image is updated
+	Service string
+}
+
+// Target is the field a profile resolved to
+type Target struct {
+	// YAMLPath is the dotted path of the field, such as spec.source.targetRevision
+	YAMLPath string
+	// Document is the zero-based index of the document holding the field
+	Document int
+	// ImageTag marks a field holding an image reference, such as nginx:1.25, of
+	// which only the tag is replaced
+	ImageTag bool
+}
+
+// String describes the target for logs and errors
+func (t Target) String() string {
+	return fmt.Sprintf("%s in document %d", t.YAMLPath, t.Document)
+}
+
+// resolvers resolve the candidate fields of one document, by profile name
+var resolvers = map[string]func(document *yaml.Node, index int, opts Options) ([]Target, error){
+	ArgoCD:          argoCDTargets,
+	Compose:         composeTargets,
+	FluxHelmRelease: fluxHelmReleaseTargets,
+}
+
+// Names returns the names of the known profiles, sorted
+func Names() []string {
+	names := make([]string, 0, len(resolvers))
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsKnown reports whether name is a known profile
+func IsKnown(name string) bool {
+	_, ok := resolvers[name]
+	return ok
+}
+
+// Resolve returns the field the named profile updates in content. Exactly one field
+// must match across the documents of the content.
+func Resolve(name, content string, opts Options) (*Target, error) {
+	resolve, ok := resolvers[name]
+	if !ok {
+		return nil, errors.NewValidationError(fmt.Sprintf("unknown updater profile %q, expected one of %v",
+			name, Names()))
+	}
+
+	documents, err := yamldoc.DecodeDocuments(content)
+	if err != nil {
+		return nil, errors.NewInvalidYAMLError(fmt.Sprintf("failed to parse YAML: %v", err))
+	}
+
+	var targets []Target
+	for index, document := range documents {
+		found, err := resolve(document, index, opts)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, found...)
+	}
+
+	switch len(targets) {
+	case 0:
+		return nil, errors.NewValidationError(noTargetMessage(name, opts))
+	case 1:
+		return &targets[0], nil
+	default:
+		described := make([]string, 0, len(targets))
+		for _, target := range targets {
+			described = append(described, target.String())
+		}
+		return nil, errors.NewValidationErrorWithContext(
+			fmt.Sprintf("profile %s matches %d fields: %s", name, len(targets), strings.Join(described, ", ")),
+			"narrow the match with --repo-url or --service, or pass --yaml-path and --doc-selector")
+	}
+}
+
+// noTargetMessage explains why a profile found nothing to update
+func noTargetMessage(name string, opts Options) string {
+	if opts.Service != "" {
+		return fmt.Sprintf("profile %s found no service %s", name, opts.Service)
+	}
+	if opts.RepoURL != "" {
+		return fmt.Sprintf("profile %s found no field for repoURL %s", name, opts.RepoURL)
+	}
+	return fmt.Sprintf("profile %s found no field to update", name)
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the value of a scalar at the path below node, or ""
+func scalarValue(node *yaml.Node, path ...string) string {
+	for _, key := range path {
+		node = mappingValue(node, key)
+	}
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
@@ -0,0 +1,235 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestSingleSourceApp = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: api
+spec:
+  source:
+    repoURL: https://gitlab.example.com/platform/charts.git
+    chart: api
+    targetRevision: 1.2.3
+`
+	TestMultiSourceApp = `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: api
+spec:
+  sources:
+    - repoURL: https://charts.example.com
+      chart: api
+      targetRevision: 1.2.3
+    - repoURL: git@gitlab.example.com:platform/values.git
+      targetRevision: main
+      ref: values
+`
+)
+
+func TestResolve_ArgoCD(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		repoURL  string
+		want     string
+		document int
+		wantCode int
+	}{
+		{name: "single source", content: TestSingleSourceApp, want: "spec.source.targetRevision", document: 1},
+		{
+			name:     "single source by equivalent URL",
+			content:  TestSingleSourceApp,
+			repoURL:  "HTTPS://gitlab.example.com/platform/charts/",
+			want:     "spec.source.targetRevision",
+			document: 1,
+		},
+		{
+			name:    "multi-source by repoURL",
+			content: TestMultiSourceApp,
+			repoURL: "https://charts.example.com/",
+			want:    "spec.sources[0].targetRevision",
+		},
+		{
+			name:    "multi-source by SSH repoURL",
+			content: TestMultiSourceApp,
+			repoURL: "ssh://git@gitlab.example.com/platform/values",
+			want:    "spec.sources[1].targetRevision",
+		},
+		{name: "multi-source without repoURL", content: TestMultiSourceApp, wantCode: errors.ErrCodeValidation},
+		{
+			name:     "no matching source",
+			content:  TestSingleSourceApp,
+			repoURL:  "https://gitlab.example.com/other.git",
+			wantCode: errors.ErrCodeValidation,
+		},
+		{
+			name:     "source without targetRevision",
+			content:  "apiVersion: argoproj.io/v1alpha1\nkind: Application\nspec:\n  source:\n    path: app\n",
+			wantCode: errors.ErrCodeValidation,
+		},
+		{name: "invalid YAML", content: "kind: [Application\n", wantCode: errors.ErrCodeInvalidYAML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := Resolve(ArgoCD, tt.content, Options{RepoURL: tt.repoURL})
+			if tt.wantCode != 0 {
+				if code := errors.GetErrorCode(err); code != tt.wantCode {
+					t.Fatalf("Resolve() = %v, %v; want error code %d", target, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if target.YAMLPath != tt.want || target.Document != tt.document {
+				t.Errorf("Resolve() = %s, want %s in document %d", target, tt.want, tt.document)
+			}
+		})
+	}
+}
+
+func TestResolve_UnknownProfile(t *testing.T) {
+	_, err := Resolve("helmfile", TestSingleSourceApp, Options{})
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("Resolve() of an unknown profile = %v, want a validation error", err)
+	}
+}
+
+func TestResolve_FluxHelmRelease(t *testing.T) {
+	release := `apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: charts
+spec:
+  url: https://charts.example.com
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: api
+spec:
+  chart:
+    spec:
+      chart: api
+      version: 1.2.3
+  values:
+    image:
+      tag: v1.0.0
+`
+	tests := []struct {
+		name     string
+		content  string
+		mode     string
+		want     string
+		wantCode int
+	}{
+		{name: "chart by default", content: release, want: "spec.chart.spec.version"},
+		{name: "image", content: release, mode: FluxModeImage, want: "spec.values.image.tag"},
+		{name: "unknown mode", content: release, mode: "digest", wantCode: errors.ErrCodeValidation},
+		{
+			name:     "image without values",
+			content:  strings.Replace(release, "  values:\n    image:\n      tag: v1.0.0\n", "", 1),
+			mode:     FluxModeImage,
+			wantCode: errors.ErrCodeValidation,
+		},
+		{name: "no HelmRelease", content: TestSingleSourceApp, wantCode: errors.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := Resolve(FluxHelmRelease, tt.content, Options{FluxMode: tt.mode})
+			if tt.wantCode != 0 {
+				if code := errors.GetErrorCode(err); code != tt.wantCode {
+					t.Fatalf("Resolve() = %v, %v; want error code %d", target, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if target.YAMLPath != tt.want || target.Document != 1 {
+				t.Errorf("Resolve() = %s, want %s in document 1", target, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve_Compose(t *testing.T) {
+	compose := `services:
+  web:
+    build: ./web
+    image: registry.example.com:5000/web:1.2
+  worker:
+    build: ./worker
+  db:
+    image: postgres@sha256:0123abcd
+`
+	tests := []struct {
+		name     string
+		content  string
+		service  string
+		want     string
+		wantCode int
+	}{
+		{name: "only service with a tagged image", content: compose, want: "services.web.image"},
+		{name: "named service", content: compose, service: "web", want: "services.web.image"},
+		{name: "service built without image", content: compose, service: "worker", wantCode: errors.ErrCodeValidation},
+		{name: "image pinned by digest", content: compose, service: "db", wantCode: errors.ErrCodeValidation},
+		{name: "unknown service", content: compose, service: "cache", wantCode: errors.ErrCodeValidation},
+		{
+			name:     "several images without service",
+			content:  "services:\n  web:\n    image: web:1\n  db:\n    image: postgres:16\n",
+			wantCode: errors.ErrCodeValidation,
+		},
+		{name: "no services", content: TestSingleSourceApp, wantCode: errors.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := Resolve(Compose, tt.content, Options{Service: tt.service})
+			if tt.wantCode != 0 {
+				if code := errors.GetErrorCode(err); code != tt.wantCode {
+					t.Fatalf("Resolve() = %v, %v; want error code %d", target, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() unexpected error: %v", err)
+			}
+			if target.YAMLPath != tt.want || !target.ImageTag {
+				t.Errorf("Resolve() = %s, want the image %s", target, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithImageTag(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "nginx:1.25", want: "nginx:2.0"},
+		{ref: "nginx", want: "nginx:2.0"},
+		{ref: "registry.example.com:5000/team/web:1.2", want: "registry.example.com:5000/team/web:2.0"},
+		{ref: "registry.example.com:5000/team/web", want: "registry.example.com:5000/team/web:2.0"},
+	}
+
+	for _, tt := range tests {
+		if got := WithImageTag(tt.ref, "2.0"); got != tt.want {
+			t.Errorf("WithImageTag(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
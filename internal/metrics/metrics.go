@@ -0,0 +1,293 @@
+// Package metrics counts GitLab API operations and exports them in the Prometheus
+// text format, so long-running batch updates can be monitored through a Pushgateway.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// Namespace prefixes every exported metric name
+	Namespace = "go_tag_updater"
+	// DefaultJob is the Pushgateway job name used when none is configured
+	DefaultJob = "go-tag-updater"
+	// ContentTypeText is the content type of the Prometheus text exposition format
+	ContentTypeText = "text/plain; version=0.0.4"
+	// DefaultPushTimeout bounds how long pushing metrics may take
+	DefaultPushTimeout = 10 * time.Second
+	// statusTransportError labels requests that failed without an HTTP response
+	statusTransportError = "error"
+)
+
+// Values of the circuit breaker state gauge
+const (
+	CircuitClosed   = 0
+	CircuitOpen     = 1
+	CircuitHalfOpen = 2
+)
+
+// latencyBuckets are the upper bounds in seconds of the request duration histogram
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Default is the recorder instrumenting every GitLab client of the process
+var Default = NewRecorder()
+
+// requestKey identifies a request counter series
+type requestKey struct {
+	method string
+	status string
+}
+
+// Recorder collects API call counts, retries, rate-limit hits, cache hits and latencies
+type Recorder struct {
+	mu          sync.Mutex
+	requests    map[requestKey]uint64
+	retries     uint64
+	rateLimited uint64
+	cacheHits   uint64
+	// circuitState is the last state of a GitLab circuit breaker, CircuitClosed to
+	// CircuitHalfOpen
+	circuitState    int
+	circuitOpened   uint64
+	circuitRejected uint64
+	buckets         []uint64
+	latencySum      float64
+	latencyN        uint64
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		requests: make(map[requestKey]uint64),
+		buckets:  make([]uint64, len(latencyBuckets)),
+	}
+}
+
+// ObserveRequest records one HTTP attempt; status is 0 when no response was received
+func (r *Recorder) ObserveRequest(method string, status int, duration time.Duration) {
+	key := requestKey{method: method, status: statusTransportError}
+	if status > 0 {
+		key.status = strconv.Itoa(status)
+	}
+
+	seconds := duration.Seconds()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[key]++
+	if status == http.StatusTooManyRequests {
+		r.rateLimited++
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			r.buckets[i]++
+		}
+	}
+	r.latencySum += seconds
+	r.latencyN++
+}
+
+// ObserveRetry records that a request is being retried
+func (r *Recorder) ObserveRetry() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries++
+}
+
+// ObserveCacheHit records a response served from the HTTP cache after GitLab
+// confirmed it unchanged
+func (r *Recorder) ObserveCacheHit() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cacheHits++
+}
+
+// ObserveCircuitState records a state change of a circuit breaker, counting openings
+func (r *Recorder) ObserveCircuitState(state int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if state == CircuitOpen && r.circuitState != CircuitOpen {
+		r.circuitOpened++
+	}
+	r.circuitState = state
+}
+
+// ObserveCircuitRejected records a request rejected by an open circuit breaker
+func (r *Recorder) ObserveCircuitRejected() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.circuitRejected++
+}
+
+// Snapshot is the number of API operations recorded up to one moment
+type Snapshot struct {
+	Requests    uint64
+	Failed      uint64
+	Retries     uint64
+	RateLimited uint64
+	CacheHits   uint64
+}
+
+// Snapshot returns the operations recorded so far; subtract an earlier snapshot
+// to count the operations of one run
+func (r *Recorder) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := Snapshot{Retries: r.retries, RateLimited: r.rateLimited, CacheHits: r.cacheHits}
+	for key, count := range r.requests {
+		snapshot.Requests += count
+		if key.status == statusTransportError || strings.HasPrefix(key.status, "4") ||
+			strings.HasPrefix(key.status, "5") {
+			snapshot.Failed += count
+		}
+	}
+	return snapshot
+}
+
+// Sub returns the operations recorded between earlier and s
+func (s Snapshot) Sub(earlier Snapshot) Snapshot {
+	return Snapshot{
+		Requests:    s.Requests - earlier.Requests,
+		Failed:      s.Failed - earlier.Failed,
+		Retries:     s.Retries - earlier.Retries,
+		RateLimited: s.RateLimited - earlier.RateLimited,
+		CacheHits:   s.CacheHits - earlier.CacheHits,
+	}
+}
+
+// Transport wraps next so that every request attempt is recorded
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &instrumentedTransport{next: next, recorder: r}
+}
+
+// instrumentedTransport records each round trip made through it
+type instrumentedTransport struct {
+	next     http.RoundTripper
+	recorder *Recorder
+}
+
+// RoundTrip performs the request and records its status and duration
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	t.recorder.ObserveRequest(req.Method, status, time.Since(start))
+
+	return resp, err
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format
+func (r *Recorder) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	requests := Namespace + "_api_requests_total"
+	fmt.Fprintf(&buf, "# HELP %s GitLab API request attempts by method and status.\n", requests)
+	fmt.Fprintf(&buf, "# TYPE %s counter\n", requests)
+	keys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s{method=%q,status=%q} %d\n", requests, key.method, key.status, r.requests[key])
+	}
+
+	writeCounter(&buf, Namespace+"_api_retries_total", "GitLab API requests retried.", r.retries)
+	writeCounter(&buf, Namespace+"_api_rate_limited_total",
+		"GitLab API responses rejected by rate limiting (HTTP 429).", r.rateLimited)
+	writeCounter(&buf, Namespace+"_api_cache_hits_total",
+		"GitLab API responses served from the HTTP cache (HTTP 304).", r.cacheHits)
+	writeCounter(&buf, Namespace+"_api_circuit_opened_total",
+		"Times the GitLab circuit breaker opened after consecutive failures.", r.circuitOpened)
+	writeCounter(&buf, Namespace+"_api_circuit_rejected_total",
+		"GitLab API requests rejected without being sent while the circuit breaker was open.", r.circuitRejected)
+
+	circuit := Namespace + "_api_circuit_state"
+	fmt.Fprintf(&buf, "# HELP %s GitLab circuit breaker state: 0 closed, 1 open, 2 half-open.\n", circuit)
+	fmt.Fprintf(&buf, "# TYPE %s gauge\n", circuit)
+	fmt.Fprintf(&buf, "%s %d\n", circuit, r.circuitState)
+
+	duration := Namespace + "_api_request_duration_seconds"
+	fmt.Fprintf(&buf, "# HELP %s GitLab API request attempt latency.\n", duration)
+	fmt.Fprintf(&buf, "# TYPE %s histogram\n", duration)
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(&buf, "%s_bucket{le=%q} %d\n", duration, strconv.FormatFloat(bound, 'g', -1, 64), r.buckets[i])
+	}
+	fmt.Fprintf(&buf, "%s_bucket{le=\"+Inf\"} %d\n", duration, r.latencyN)
+	fmt.Fprintf(&buf, "%s_sum %s\n", duration, strconv.FormatFloat(r.latencySum, 'g', -1, 64))
+	fmt.Fprintf(&buf, "%s_count %d\n", duration, r.latencyN)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeCounter writes a single unlabeled counter
+func writeCounter(buf *bytes.Buffer, name, help string, value uint64) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s counter\n", name)
+	fmt.Fprintf(buf, "%s %d\n", name, value)
+}
+
+// Push replaces the metrics of job on a Prometheus Pushgateway at gatewayURL
+func (r *Recorder) Push(ctx context.Context, gatewayURL, job string) error {
+	if gatewayURL == "" {
+		return errors.NewConfigError("metrics push URL cannot be empty")
+	}
+	if job == "" {
+		job = DefaultJob
+	}
+
+	var body bytes.Buffer
+	if err := r.WriteText(&body); err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	endpoint := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return errors.NewConfigErrorWithCause("invalid metrics push URL "+gatewayURL, err)
+	}
+	req.Header.Set("Content-Type", ContentTypeText)
+
+	client := &http.Client{Timeout: DefaultPushTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.NewNetworkErrorWithCause("failed to push metrics", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.NewNetworkError(fmt.Sprintf("metrics push returned %s", resp.Status))
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	TestJob     = "nightly-bump"
+	TestLatency = 200 * time.Millisecond
+)
+
+func TestRecorder_Transport(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	client := &http.Client{Transport: recorder.Transport(nil)}
+
+	for _, code := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		status = code
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if _, err := client.Get("http://127.0.0.1:0"); err == nil {
+		t.Fatal("Get() to an invalid address should fail")
+	}
+	recorder.ObserveRetry()
+
+	var buf bytes.Buffer
+	if err := recorder.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() unexpected error: %v", err)
+	}
+
+	for _, line := range []string{
+		`go_tag_updater_api_requests_total{method="GET",status="200"} 2`,
+		`go_tag_updater_api_requests_total{method="GET",status="429"} 1`,
+		`go_tag_updater_api_requests_total{method="GET",status="error"} 1`,
+		`go_tag_updater_api_retries_total 1`,
+		`go_tag_updater_api_rate_limited_total 1`,
+		`go_tag_updater_api_request_duration_seconds_count 4`,
+		`# TYPE go_tag_updater_api_request_duration_seconds histogram`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("WriteText() output missing %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestRecorder_Snapshot(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.ObserveRequest(http.MethodGet, http.StatusOK, TestLatency)
+	start := recorder.Snapshot()
+
+	recorder.ObserveRequest(http.MethodGet, http.StatusNotModified, TestLatency)
+	recorder.ObserveRequest(http.MethodPost, http.StatusTooManyRequests, TestLatency)
+	recorder.ObserveRequest(http.MethodPost, 0, TestLatency)
+	recorder.ObserveRetry()
+	recorder.ObserveCacheHit()
+
+	got := recorder.Snapshot().Sub(start)
+	want := Snapshot{Requests: 3, Failed: 2, Retries: 1, RateLimited: 1, CacheHits: 1}
+	if got != want {
+		t.Errorf("Snapshot().Sub() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecorder_Circuit(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.ObserveCircuitState(CircuitOpen)
+	recorder.ObserveCircuitRejected()
+	recorder.ObserveCircuitState(CircuitHalfOpen)
+	recorder.ObserveCircuitState(CircuitOpen)
+	recorder.ObserveCircuitRejected()
+
+	var buf bytes.Buffer
+	if err := recorder.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() unexpected error: %v", err)
+	}
+	for _, line := range []string{
+		"go_tag_updater_api_circuit_opened_total 2\n",
+		"go_tag_updater_api_circuit_rejected_total 2\n",
+		"go_tag_updater_api_circuit_state 1\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("WriteText() output missing %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestRecorder_Histogram(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.ObserveRequest(http.MethodPut, http.StatusOK, TestLatency)
+
+	var buf bytes.Buffer
+	if err := recorder.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		bucket string
+		want   string
+	}{
+		{bucket: "0.1", want: "0"},
+		{bucket: "0.25", want: "1"},
+		{bucket: "+Inf", want: "1"},
+	}
+
+	for _, tt := range tests {
+		line := `go_tag_updater_api_request_duration_seconds_bucket{le="` + tt.bucket + `"} ` + tt.want + "\n"
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("WriteText() output missing %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestRecorder_Push(t *testing.T) {
+	var (
+		method, path, contentType string
+		body                      []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	recorder.ObserveRequest(http.MethodGet, http.StatusOK, TestLatency)
+
+	if err := recorder.Push(context.Background(), server.URL+"/", TestJob); err != nil {
+		t.Fatalf("Push() unexpected error: %v", err)
+	}
+
+	if method != http.MethodPut || path != "/metrics/job/"+TestJob || contentType != ContentTypeText {
+		t.Errorf("Push() sent %s %s (%s)", method, path, contentType)
+	}
+	if !bytes.Contains(body, []byte("go_tag_updater_api_requests_total")) {
+		t.Errorf("Push() body = %s", body)
+	}
+
+	if err := recorder.Push(context.Background(), "", TestJob); err == nil {
+		t.Error("Push() without URL should fail")
+	}
+}
@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DefaultSMTPPort is the SMTP submission port
+	DefaultSMTPPort = 587
+
+	// EmailTLSStartTLS upgrades the SMTP connection with STARTTLS, EmailTLSImplicit
+	// connects over TLS from the start and EmailTLSNone sends in plain text
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "tls"
+	EmailTLSNone     = "none"
+)
+
+// EmailOptions configures the SMTP server and recipients of email notifications
+type EmailOptions struct {
+	Host string
+	// Port is DefaultSMTPPort when zero
+	Port int
+	// TLS is one of EmailTLSStartTLS, EmailTLSImplicit or EmailTLSNone; EmailTLSStartTLS when empty
+	TLS  string
+	From string
+	To   []string
+	// Username and Password authenticate with PLAIN auth when the username is set
+	Username string
+	Password string
+}
+
+// EmailNotifier sends events as email through an SMTP server
+type EmailNotifier struct {
+	opts    EmailOptions
+	timeout time.Duration
+}
+
+// NewEmailNotifier validates the options and creates an email notifier
+func NewEmailNotifier(opts EmailOptions, timeout time.Duration) (*EmailNotifier, error) {
+	if opts.Port == 0 {
+		opts.Port = DefaultSMTPPort
+	}
+	if opts.TLS == "" {
+		opts.TLS = EmailTLSStartTLS
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	switch {
+	case opts.Host == "":
+		return nil, errors.NewValidationError("email notifications require an SMTP host")
+	case opts.From == "":
+		return nil, errors.NewValidationError("email notifications require a sender address")
+	case len(opts.To) == 0:
+		return nil, errors.NewValidationError("email notifications require at least one recipient")
+	case opts.TLS != EmailTLSStartTLS && opts.TLS != EmailTLSImplicit && opts.TLS != EmailTLSNone:
+		return nil, errors.NewValidationErrorWithContext(fmt.Sprintf("unknown email TLS mode %q", opts.TLS),
+			"use starttls, tls or none")
+	}
+	return &EmailNotifier{opts: opts, timeout: timeout}, nil
+}
+
+// Notify sends the event as a summary email, listing every updated file of batch runs
+func (n *EmailNotifier) Notify(ctx context.Context, event *Event) error {
+	if err := n.send(ctx, n.message(event)); err != nil {
+		return errors.NewNetworkErrorWithCause("failed to deliver email notification", err)
+	}
+	return nil
+}
+
+// message returns the email of the event with its headers
+func (n *EmailNotifier) message(event *Event) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.opts.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.opts.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(event.Title()))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+
+	lines := []string{event.Title(), ""}
+	fields := [][2]string{
+		{"Project", event.Project},
+		{"File", event.File},
+		{"Old tag", event.OldTag},
+		{"New tag", event.NewTag},
+		{"Branch", event.Branch},
+		{"Merge request", event.MergeRequestURL},
+		{"Run", event.RunID},
+	}
+	for _, field := range fields {
+		if field[1] != "" {
+			lines = append(lines, field[0]+": "+field[1])
+		}
+	}
+	if len(event.Files) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Updated files (%d):", len(event.Files)))
+		for _, file := range event.Files {
+			lines = append(lines, fmt.Sprintf("  %s: %d value(s)", file.Path, file.Changes))
+		}
+	}
+	if detail := event.Detail(); detail != "" {
+		lines = append(lines, "", detail)
+	}
+	for _, line := range lines {
+		b.WriteString(line + "\r\n")
+	}
+	return b.Bytes()
+}
+
+// headerValue returns text as the value of one header line: line breaks, which would
+// start other headers, become spaces and non-ASCII text is RFC 2047 encoded
+func headerValue(text string) string {
+	text = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(text)
+	return mime.QEncoding.Encode("utf-8", text)
+}
+
+// send delivers the message to every recipient in one SMTP session
+func (n *EmailNotifier) send(ctx context.Context, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(n.opts.Host, strconv.Itoa(n.opts.Port))
+	tlsConfig := &tls.Config{ServerName: n.opts.Host, MinVersion: tls.VersionTLS12}
+	var conn net.Conn
+	var err error
+	if n.opts.TLS == EmailTLSImplicit {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.opts.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if n.opts.TLS == EmailTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if n.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.opts.Username, n.opts.Password, n.opts.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.opts.From); err != nil {
+		return err
+	}
+	for _, recipient := range n.opts.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	stderrors "errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpSession is what the fake SMTP server received in one session
+type smtpSession struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// startSMTPServer accepts one plain text SMTP session and sends what it received
+func startSMTPServer(t *testing.T) (host string, port int, sessions <-chan smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var session smtpSession
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimSpace(line)
+			switch upper := strings.ToUpper(command); {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				session.from = strings.Trim(command[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				session.recipients = append(session.recipients, strings.Trim(command[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				session.data = data.String()
+				reply("250 OK")
+			case upper == "QUIT":
+				reply("221 Bye")
+				received <- session
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func TestNewEmailNotifier(t *testing.T) {
+	valid := EmailOptions{Host: "smtp.example.com", From: "bot@example.com", To: []string{"team@example.com"}}
+	notifier, err := NewEmailNotifier(valid, 0)
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() unexpected error: %v", err)
+	}
+	if notifier.opts.Port != DefaultSMTPPort || notifier.opts.TLS != EmailTLSStartTLS {
+		t.Errorf("defaults = port %d, TLS %q", notifier.opts.Port, notifier.opts.TLS)
+	}
+
+	invalid := []EmailOptions{
+		{From: "bot@example.com", To: []string{"team@example.com"}},
+		{Host: "smtp.example.com", To: []string{"team@example.com"}},
+		{Host: "smtp.example.com", From: "bot@example.com"},
+		{Host: "smtp.example.com", From: "bot@example.com", To: []string{"team@example.com"}, TLS: "ssl"},
+	}
+	for _, opts := range invalid {
+		if _, err := NewEmailNotifier(opts, 0); err == nil {
+			t.Errorf("NewEmailNotifier(%+v) succeeded, want a validation error", opts)
+		}
+	}
+}
+
+func TestEmailNotifier_Notify(t *testing.T) {
+	host, port, sessions := startSMTPServer(t)
+	notifier, err := NewEmailNotifier(EmailOptions{
+		Host: host,
+		Port: port,
+		TLS:  EmailTLSNone,
+		From: "bot@example.com",
+		To:   []string{"team@example.com", "ops@example.com"},
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() unexpected error: %v", err)
+	}
+
+	event := testEvent()
+	event.File = "envs/*/values.yaml"
+	event.Files = []FileChange{{Path: "envs/dev/values.yaml", Changes: 2}, {Path: "envs/prod/values.yaml", Changes: 1}}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+
+	session := <-sessions
+	if session.from != "bot@example.com" || len(session.recipients) != 2 {
+		t.Errorf("envelope = %s to %v", session.from, session.recipients)
+	}
+	for _, want := range []string{
+		"Subject: " + event.Title(),
+		"To: team@example.com, ops@example.com",
+		"Merge request: " + event.MergeRequestURL,
+		"Updated files (2):",
+		"envs/prod/values.yaml: 1 value(s)",
+	} {
+		if !strings.Contains(session.data, want) {
+			t.Errorf("email does not contain %q:\n%s", want, session.data)
+		}
+	}
+}
+
+func TestEmailNotifier_MessageHeaders(t *testing.T) {
+	notifier := &EmailNotifier{opts: EmailOptions{From: "bot@example.com", To: []string{"team@example.com"}}}
+
+	tests := []struct {
+		name        string
+		newTag      string
+		wantSubject string
+	}{
+		{name: "plain tag", newTag: "v1.2.3",
+			wantSubject: "Subject: Tag of values.yaml in platform/deploy updated from v1.0.0 to v1.2.3\r\n"},
+		{name: "tag with a line break", newTag: "v1.2.3\r\nBcc: attacker@example.com",
+			wantSubject: "to v1.2.3 Bcc: attacker@example.com\r\n"},
+		{name: "non-ASCII tag", newTag: "v1.2.3-é", wantSubject: "Subject: =?utf-8?q?Tag_of_values.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := testEvent()
+			event.NewTag = tt.newTag
+
+			headers, _, _ := strings.Cut(string(notifier.message(event)), "\r\n\r\n")
+			if !strings.Contains(headers, tt.wantSubject) {
+				t.Errorf("headers = %q, want %q", headers, tt.wantSubject)
+			}
+			for _, line := range strings.Split(headers, "\r\n") {
+				if strings.HasPrefix(line, "Bcc:") {
+					t.Errorf("headers = %q, want no injected header", headers)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailNotifier_NotifyWithoutStartTLS(t *testing.T) {
+	host, port, _ := startSMTPServer(t)
+	notifier, err := NewEmailNotifier(EmailOptions{
+		Host: host,
+		Port: port,
+		From: "bot@example.com",
+		To:   []string{"team@example.com"},
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() unexpected error: %v", err)
+	}
+
+	err = notifier.Notify(context.Background(), testEvent())
+	cause := stderrors.Unwrap(err)
+	if cause == nil || !strings.Contains(cause.Error(), "STARTTLS") {
+		t.Errorf("Notify() = %v (%v), want the missing STARTTLS support on %s",
+			err, cause, net.JoinHostPort(host, strconv.Itoa(port)))
+	}
+}
@@ -0,0 +1,170 @@
+// Package notify posts the outcome of tag update runs to chat channels, webhooks and
+// mailboxes: Slack and Microsoft Teams incoming webhooks, generic JSON webhooks and
+// email sent through an SMTP server.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DefaultTimeout bounds how long delivery to each webhook may take
+	DefaultTimeout = 10 * time.Second
+	// ContentTypeJSON is the content type of the posted messages
+	ContentTypeJSON = "application/json"
+)
+
+// Event describes the outcome of one run
+type Event struct {
+	Timestamp       time.Time `json:"timestamp"`
+	RunID           string    `json:"run_id,omitempty"`
+	Success         bool      `json:"success"`
+	Project         string    `json:"project"`
+	File            string    `json:"file"`
+	OldTag          string    `json:"old_tag,omitempty"`
+	NewTag          string    `json:"new_tag"`
+	Branch          string    `json:"branch,omitempty"`
+	MergeRequestIID int       `json:"mr_iid,omitempty"`
+	MergeRequestURL string    `json:"mr_url,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	// Files lists every file updated by a batch run over several files
+	Files []FileChange `json:"files,omitempty"`
+}
+
+// FileChange is one file updated by a batch run
+type FileChange struct {
+	Path    string `json:"path"`
+	Changes int    `json:"changes"`
+}
+
+// Title returns a one-line summary of the event
+func (e *Event) Title() string {
+	if !e.Success {
+		return fmt.Sprintf("Tag update to %s in %s of %s failed", e.NewTag, e.File, e.Project)
+	}
+	if e.OldTag != "" {
+		return fmt.Sprintf("Tag of %s in %s updated from %s to %s", e.File, e.Project, e.OldTag, e.NewTag)
+	}
+	return fmt.Sprintf("Tag of %s in %s updated to %s", e.File, e.Project, e.NewTag)
+}
+
+// Detail returns the error of a failed run or the result message of a successful one
+func (e *Event) Detail() string {
+	if !e.Success {
+		return e.Error
+	}
+	return e.Message
+}
+
+// Notifier delivers events to one destination
+type Notifier interface {
+	Notify(ctx context.Context, event *Event) error
+}
+
+// Options configures the destinations of notifications
+type Options struct {
+	// SlackWebhookURL is a Slack incoming webhook
+	SlackWebhookURL string
+	// TeamsWebhookURL is a Microsoft Teams incoming webhook
+	TeamsWebhookURL string
+	// WebhookURL receives every event as JSON
+	WebhookURL string
+	// Email sends every event as email when its host is set
+	Email EmailOptions
+	// Timeout bounds delivery to each webhook; DefaultTimeout when zero
+	Timeout time.Duration
+}
+
+// Dispatcher delivers events to every configured notifier
+type Dispatcher struct {
+	notifiers []Notifier
+}
+
+// New creates a dispatcher from options, or returns nil when no destination is configured
+func New(opts Options) (*Dispatcher, error) {
+	client := &http.Client{Timeout: opts.Timeout}
+	if opts.Timeout <= 0 {
+		client.Timeout = DefaultTimeout
+	}
+
+	var notifiers []Notifier
+	if opts.SlackWebhookURL != "" {
+		notifiers = append(notifiers, &SlackNotifier{webhookURL: opts.SlackWebhookURL, client: client})
+	}
+	if opts.TeamsWebhookURL != "" {
+		notifiers = append(notifiers, &TeamsNotifier{webhookURL: opts.TeamsWebhookURL, client: client})
+	}
+	if opts.WebhookURL != "" {
+		notifiers = append(notifiers, &WebhookNotifier{url: opts.WebhookURL, client: client})
+	}
+	if opts.Email.Host != "" {
+		email, err := NewEmailNotifier(opts.Email, client.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, email)
+	}
+
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return NewWithNotifiers(notifiers...), nil
+}
+
+// NewWithNotifiers creates a dispatcher delivering to the given notifiers
+func NewWithNotifiers(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: notifiers}
+}
+
+// Notify stamps the event and delivers it to every notifier. Delivery continues
+// after a failing notifier and all failures are returned together.
+func (d *Dispatcher) Notify(ctx context.Context, event *Event) error {
+	if event == nil {
+		return errors.NewValidationError("notification event cannot be nil")
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	var errs []error
+	for _, notifier := range d.notifiers {
+		if err := notifier.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// postJSON posts body as JSON; any non-2xx response is an error
+func postJSON(ctx context.Context, client *http.Client, url, destination string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s notification: %w", destination, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return errors.NewConfigErrorWithCause("invalid "+destination+" webhook URL", err)
+	}
+	req.Header.Set("Content-Type", ContentTypeJSON)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.NewNetworkErrorWithCause("failed to deliver "+destination+" notification", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.NewNetworkError(fmt.Sprintf("%s webhook returned %s", destination, resp.Status))
+	}
+	return nil
+}
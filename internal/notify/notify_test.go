@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recorder is a webhook endpoint recording the bodies posted to it
+type recorder struct {
+	mu     sync.Mutex
+	bodies []string
+	status int
+}
+
+func newRecorder(t *testing.T, status int) (*recorder, *httptest.Server) {
+	t.Helper()
+	rec := &recorder{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != ContentTypeJSON {
+			t.Errorf("Content-Type = %q, want %q", r.Header.Get("Content-Type"), ContentTypeJSON)
+		}
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.bodies = append(rec.bodies, string(body))
+		rec.mu.Unlock()
+		w.WriteHeader(rec.status)
+	}))
+	t.Cleanup(server.Close)
+	return rec, server
+}
+
+func testEvent() *Event {
+	return &Event{
+		Success:         true,
+		Project:         "platform/deploy",
+		File:            "values.yaml",
+		OldTag:          "v1.0.0",
+		NewTag:          "v1.1.0",
+		MergeRequestIID: 7,
+		MergeRequestURL: "https://gitlab.example.com/platform/deploy/-/merge_requests/7",
+	}
+}
+
+func TestNew(t *testing.T) {
+	if d, err := New(Options{}); d != nil || err != nil {
+		t.Errorf("New() without destinations = %v, %v, want nil", d, err)
+	}
+	d, err := New(Options{SlackWebhookURL: "https://hooks.slack.com/x", WebhookURL: "https://example.com/hook"})
+	if err != nil || d == nil || len(d.notifiers) != 2 {
+		t.Fatalf("New() = %v, %v, want two notifiers", d, err)
+	}
+	if _, err := New(Options{Email: EmailOptions{Host: "smtp.example.com", From: "bot@example.com"}}); err == nil {
+		t.Error("New() with email notifications without recipients succeeded")
+	}
+}
+
+func TestDispatcher_Notify(t *testing.T) {
+	slack, slackServer := newRecorder(t, http.StatusOK)
+	teams, teamsServer := newRecorder(t, http.StatusOK)
+	webhook, webhookServer := newRecorder(t, http.StatusNoContent)
+
+	d, err := New(Options{
+		SlackWebhookURL: slackServer.URL,
+		TeamsWebhookURL: teamsServer.URL,
+		WebhookURL:      webhookServer.URL,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := d.Notify(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+
+	var message slackMessage
+	if err := json.Unmarshal([]byte(slack.bodies[0]), &message); err != nil {
+		t.Fatalf("invalid Slack payload: %v", err)
+	}
+	for _, want := range []string{"from v1.0.0 to v1.1.0", "|Merge request !7>"} {
+		if !strings.Contains(message.Text, want) {
+			t.Errorf("Slack text = %q, want it to contain %q", message.Text, want)
+		}
+	}
+
+	var card teamsCard
+	if err := json.Unmarshal([]byte(teams.bodies[0]), &card); err != nil {
+		t.Fatalf("invalid Teams payload: %v", err)
+	}
+	if card.Type != "MessageCard" || card.ThemeColor != teamsColorSuccess ||
+		!strings.Contains(card.Text, "[Merge request !7]") {
+		t.Errorf("Teams card = %+v", card)
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(webhook.bodies[0]), &event); err != nil {
+		t.Fatalf("invalid webhook payload: %v", err)
+	}
+	if event.NewTag != "v1.1.0" || event.MergeRequestIID != 7 || event.Timestamp.IsZero() {
+		t.Errorf("webhook event = %+v", event)
+	}
+}
+
+func TestDispatcher_NotifyFailure(t *testing.T) {
+	teams, teamsServer := newRecorder(t, http.StatusOK)
+	_, failingServer := newRecorder(t, http.StatusInternalServerError)
+
+	d, err := New(Options{TeamsWebhookURL: teamsServer.URL, WebhookURL: failingServer.URL})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	event := &Event{Project: "platform/deploy", File: "values.yaml", NewTag: "v1.1.0", Error: "file not found"}
+	err = d.Notify(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("Notify() = %v, want the webhook failure", err)
+	}
+
+	if len(teams.bodies) != 1 {
+		t.Fatalf("Teams received %d notifications, want delivery to continue after a failure", len(teams.bodies))
+	}
+	var card teamsCard
+	if err := json.Unmarshal([]byte(teams.bodies[0]), &card); err != nil {
+		t.Fatalf("invalid Teams payload: %v", err)
+	}
+	if card.ThemeColor != teamsColorFailure || !strings.Contains(card.Title, "failed") || card.Text != "file not found" {
+		t.Errorf("Teams card of a failure = %+v", card)
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// teamsColorSuccess and teamsColorFailure are the theme colors of Teams cards
+	teamsColorSuccess = "2EB67D"
+	teamsColorFailure = "D00000"
+)
+
+// SlackNotifier posts events to a Slack incoming webhook
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify posts the event as a Slack message linking the merge request
+func (n *SlackNotifier) Notify(ctx context.Context, event *Event) error {
+	text := "*" + event.Title() + "*"
+	if event.MergeRequestURL != "" {
+		text += fmt.Sprintf("\n<%s|Merge request !%d>", event.MergeRequestURL, event.MergeRequestIID)
+	}
+	if detail := event.Detail(); detail != "" {
+		text += "\n" + detail
+	}
+	return postJSON(ctx, n.client, n.webhookURL, "Slack", &slackMessage{Text: text})
+}
+
+// TeamsNotifier posts events to a Microsoft Teams incoming webhook
+type TeamsNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// teamsCard is the MessageCard payload of a Teams incoming webhook
+type teamsCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor"`
+	Title      string `json:"title"`
+	Text       string `json:"text,omitempty"`
+}
+
+// Notify posts the event as a Teams card linking the merge request
+func (n *TeamsNotifier) Notify(ctx context.Context, event *Event) error {
+	card := &teamsCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    event.Title(),
+		ThemeColor: teamsColorSuccess,
+		Title:      event.Title(),
+		Text:       event.Detail(),
+	}
+	if !event.Success {
+		card.ThemeColor = teamsColorFailure
+	}
+	if event.MergeRequestURL != "" {
+		card.Text += fmt.Sprintf("\n\n[Merge request !%d](%s)", event.MergeRequestIID, event.MergeRequestURL)
+	}
+	return postJSON(ctx, n.client, n.webhookURL, "Teams", card)
+}
+
+// WebhookNotifier posts events as JSON to a generic webhook
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Notify posts the event as JSON
+func (n *WebhookNotifier) Notify(ctx context.Context, event *Event) error {
+	return postJSON(ctx, n.client, n.url, "notification", event)
+}
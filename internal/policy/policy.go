@@ -0,0 +1,279 @@
+// Package policy provides change-scope enforcement for go-tag-updater.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	yamldoc "github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// GlobSeparator separates segments of repository file globs
+	GlobSeparator = "/"
+	// RecursiveWildcard matches zero or more path segments
+	RecursiveWildcard = "**"
+	// SegmentWildcard matches exactly one YAML path segment
+	SegmentWildcard = "*"
+	// IndexWildcard matches any sequence index in a YAML path
+	IndexWildcard = "[*]"
+	// YAMLPathSeparator separates mapping keys in YAML path expressions
+	YAMLPathSeparator = "."
+	// IndexOpen marks the beginning of a sequence index segment
+	IndexOpen = "["
+)
+
+// Policy restricts which files and which YAML values may be modified
+type Policy struct {
+	enabled      bool
+	allowedFiles []string
+	allowedPaths [][]string
+}
+
+// New creates a policy; when enabled, at least one file glob and one YAML path are required
+func New(enabled bool, allowedFiles, allowedPaths []string) (*Policy, error) {
+	p := &Policy{enabled: enabled}
+	if !enabled {
+		return p, nil
+	}
+
+	for _, glob := range allowedFiles {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			continue
+		}
+		if _, err := path.Match(strings.ReplaceAll(glob, RecursiveWildcard, SegmentWildcard), ""); err != nil {
+			return nil, errors.NewConfigError(fmt.Sprintf("invalid allowed file glob %q: %v", glob, err))
+		}
+		p.allowedFiles = append(p.allowedFiles, glob)
+	}
+
+	for _, yamlPath := range allowedPaths {
+		segments := SplitYAMLPath(yamlPath)
+		if len(segments) == 0 {
+			continue
+		}
+		p.allowedPaths = append(p.allowedPaths, segments)
+	}
+
+	if len(p.allowedFiles) == 0 {
+		return nil, errors.NewConfigError("least-privilege mode requires at least one allowed file glob")
+	}
+	if len(p.allowedPaths) == 0 {
+		return nil, errors.NewConfigError("least-privilege mode requires at least one allowed YAML path")
+	}
+
+	return p, nil
+}
+
+// Enabled reports whether the policy is enforced
+func (p *Policy) Enabled() bool {
+	return p != nil && p.enabled
+}
+
+// CheckFile verifies that the repository file may be modified
+func (p *Policy) CheckFile(filePath string) error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	cleanPath := strings.TrimPrefix(path.Clean(strings.ReplaceAll(filePath, "\\", GlobSeparator)), GlobSeparator)
+	for _, glob := range p.allowedFiles {
+		if MatchGlob(glob, cleanPath) {
+			return nil
+		}
+	}
+
+	return errors.NewPolicyErrorWithContext("file is outside the allowed scope", filePath)
+}
+
+// CheckTagPath verifies that the scalar at the given YAML path may be modified
+func (p *Policy) CheckTagPath(segments []string) error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	for _, allowed := range p.allowedPaths {
+		if matchSegments(allowed, segments, matchYAMLSegment) {
+			return nil
+		}
+	}
+
+	return errors.NewPolicyErrorWithContext("YAML path is outside the allowed scope", FormatYAMLPath(segments))
+}
+
+// CheckChange compares original and updated YAML and refuses anything beyond
+// scalar value changes at allowed paths
+func (p *Policy) CheckChange(original, updated string) error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	originalDocuments, err := yamldoc.DecodeDocuments(original)
+	if err != nil {
+		return errors.NewPolicyError(fmt.Sprintf("cannot verify change scope of original content: %v", err))
+	}
+	updatedDocuments, err := yamldoc.DecodeDocuments(updated)
+	if err != nil {
+		return errors.NewPolicyError(fmt.Sprintf("cannot verify change scope of updated content: %v", err))
+	}
+	if len(originalDocuments) != len(updatedDocuments) {
+		return errors.NewPolicyError(fmt.Sprintf("change alters the number of YAML documents from %d to %d",
+			len(originalDocuments), len(updatedDocuments)))
+	}
+
+	var changed [][]string
+	for i := range originalDocuments {
+		if err := diffNodes(originalDocuments[i], updatedDocuments[i], nil, &changed); err != nil {
+			return err
+		}
+	}
+
+	for _, segments := range changed {
+		if err := p.CheckTagPath(segments); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffNodes walks two YAML trees in parallel collecting changed scalar paths
+func diffNodes(a, b *yaml.Node, segments []string, changed *[][]string) error {
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return errors.NewPolicyErrorWithContext("change alters YAML structure", FormatYAMLPath(segments))
+	}
+
+	switch a.Kind {
+	case yaml.DocumentNode:
+		for i := range a.Content {
+			if err := diffNodes(a.Content[i], b.Content[i], segments, changed); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i := range a.Content {
+			if err := diffNodes(a.Content[i], b.Content[i], appendSegment(segments, fmt.Sprintf("[%d]", i)), changed); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			if a.Content[i].Value != b.Content[i].Value {
+				return errors.NewPolicyErrorWithContext("change renames a YAML key", FormatYAMLPath(segments))
+			}
+			childPath := appendSegment(segments, a.Content[i].Value)
+			if err := diffNodes(a.Content[i+1], b.Content[i+1], childPath, changed); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if a.Value != b.Value {
+			*changed = append(*changed, segments)
+		}
+	case yaml.AliasNode:
+		if a.Value != b.Value {
+			return errors.NewPolicyErrorWithContext("change retargets a YAML alias", FormatYAMLPath(segments))
+		}
+	}
+
+	return nil
+}
+
+// MatchGlob reports whether a slash-separated path matches a glob where
+// "**" matches any number of directories
+func MatchGlob(glob, name string) bool {
+	return matchSegments(strings.Split(glob, GlobSeparator), strings.Split(name, GlobSeparator), matchFileSegment)
+}
+
+// SplitYAMLPath splits an expression such as "spec.containers[0].image" into segments
+func SplitYAMLPath(expr string) []string {
+	var segments []string
+	for _, part := range strings.Split(strings.TrimSpace(expr), YAMLPathSeparator) {
+		for part != "" {
+			idx := strings.Index(part, IndexOpen)
+			switch {
+			case idx < 0:
+				segments = append(segments, part)
+				part = ""
+			case idx > 0:
+				segments = append(segments, part[:idx])
+				part = part[idx:]
+			default:
+				end := strings.Index(part, "]")
+				if end < 0 {
+					segments = append(segments, part)
+					part = ""
+					continue
+				}
+				segments = append(segments, part[:end+1])
+				part = part[end+1:]
+			}
+		}
+	}
+	return segments
+}
+
+// FormatYAMLPath renders path segments in the same notation accepted by SplitYAMLPath
+func FormatYAMLPath(segments []string) string {
+	var builder strings.Builder
+	for i, segment := range segments {
+		if i > 0 && !strings.HasPrefix(segment, IndexOpen) {
+			builder.WriteString(YAMLPathSeparator)
+		}
+		builder.WriteString(segment)
+	}
+	return builder.String()
+}
+
+// matchSegments matches pattern segments against name segments with "**" support
+func matchSegments(pattern, name []string, match func(p, s string) bool) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == RecursiveWildcard {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:], match) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(name) == 0 || !match(pattern[0], name[0]) {
+		return false
+	}
+
+	return matchSegments(pattern[1:], name[1:], match)
+}
+
+// matchFileSegment matches a single file path segment using shell glob rules
+func matchFileSegment(pattern, segment string) bool {
+	matched, err := path.Match(pattern, segment)
+	return err == nil && matched
+}
+
+// matchYAMLSegment matches a single YAML path segment
+func matchYAMLSegment(pattern, segment string) bool {
+	isIndex := strings.HasPrefix(segment, IndexOpen)
+	switch pattern {
+	case SegmentWildcard:
+		return !isIndex
+	case IndexWildcard:
+		return isIndex
+	default:
+		return pattern == segment
+	}
+}
+
+// appendSegment returns a copy of segments with segment appended
+func appendSegment(segments []string, segment string) []string {
+	result := make([]string, len(segments), len(segments)+1)
+	copy(result, segments)
+	return append(result, segment)
+}
@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestOriginalYAML = `
+image:
+  repository: test/repo
+  tag: v1.0.0
+replicas: 2
+`
+	TestTagChangedYAML = `
+image:
+  repository: test/repo
+  tag: v1.2.3
+replicas: 2
+`
+	TestReplicasChangedYAML = `
+image:
+  repository: test/repo
+  tag: v1.0.0
+replicas: 5
+`
+	TestKeyAddedYAML = `
+image:
+  repository: test/repo
+  tag: v1.0.0
+  pullPolicy: Always
+replicas: 2
+`
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name         string
+		enabled      bool
+		allowedFiles []string
+		allowedPaths []string
+		expectError  bool
+	}{
+		{name: "disabled without scope", enabled: false},
+		{name: "enabled with scope", enabled: true, allowedFiles: []string{"k8s/**/*.yaml"}, allowedPaths: []string{"image.tag"}},
+		{name: "enabled without files", enabled: true, allowedPaths: []string{"image.tag"}, expectError: true},
+		{name: "enabled without paths", enabled: true, allowedFiles: []string{"*.yaml"}, expectError: true},
+		{name: "invalid glob", enabled: true, allowedFiles: []string{"[.yaml"}, allowedPaths: []string{"tag"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.enabled, tt.allowedFiles, tt.allowedPaths)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("New() expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if p.Enabled() != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", p.Enabled(), tt.enabled)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckFile(t *testing.T) {
+	p, err := New(true, []string{"k8s/**/*.yaml", "values.yml"}, []string{"image.tag"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		filePath    string
+		expectError bool
+	}{
+		{filePath: "k8s/deployment.yaml"},
+		{filePath: "k8s/prod/eu/deployment.yaml"},
+		{filePath: "/values.yml"},
+		{filePath: "charts/values.yml", expectError: true},
+		{filePath: ".gitlab-ci.yml", expectError: true},
+		{filePath: "k8s/secret.json", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			err := p.CheckFile(tt.filePath)
+			if tt.expectError {
+				if errors.GetErrorCode(err) != errors.ErrCodePolicyViolation {
+					t.Errorf("CheckFile(%q) expected policy violation, got %v", tt.filePath, err)
+				}
+			} else if err != nil {
+				t.Errorf("CheckFile(%q) unexpected error: %v", tt.filePath, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckChange(t *testing.T) {
+	p, err := New(true, []string{"**"}, []string{"image.tag", "spec.containers[*].image"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		updated     string
+		expectError bool
+	}{
+		{name: "allowed scalar change", updated: TestTagChangedYAML},
+		{name: "no change", updated: TestOriginalYAML},
+		{name: "scalar outside scope", updated: TestReplicasChangedYAML, expectError: true},
+		{name: "structural change", updated: TestKeyAddedYAML, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.CheckChange(TestOriginalYAML, tt.updated)
+			if tt.expectError && err == nil {
+				t.Errorf("CheckChange() expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("CheckChange() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckChangeDocuments(t *testing.T) {
+	p, err := New(true, []string{"**"}, []string{"image.tag"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	const original = TestOriginalYAML + "---\nreplicas: 3\n"
+	tests := []struct {
+		name        string
+		updated     string
+		expectError bool
+	}{
+		{name: "allowed change in first document", updated: TestTagChangedYAML + "---\nreplicas: 3\n"},
+		{name: "scalar outside scope in second document", updated: TestOriginalYAML + "---\nreplicas: 4\n",
+			expectError: true},
+		{name: "document removed", updated: TestTagChangedYAML, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.CheckChange(original, tt.updated)
+			if tt.expectError && err == nil {
+				t.Errorf("CheckChange() expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("CheckChange() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Disabled(t *testing.T) {
+	p, err := New(false, nil, nil)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if err := p.CheckFile("anything.yaml"); err != nil {
+		t.Errorf("CheckFile() should allow everything when disabled, got %v", err)
+	}
+	if err := p.CheckChange(TestOriginalYAML, TestKeyAddedYAML); err != nil {
+		t.Errorf("CheckChange() should allow everything when disabled, got %v", err)
+	}
+}
+
+func TestSplitAndFormatYAMLPath(t *testing.T) {
+	tests := []struct {
+		expr     string
+		segments []string
+	}{
+		{expr: "image.tag", segments: []string{"image", "tag"}},
+		{expr: "spec.containers[0].image", segments: []string{"spec", "containers", "[0]", "image"}},
+		{expr: "items[*][1]", segments: []string{"items", "[*]", "[1]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			segments := SplitYAMLPath(tt.expr)
+			if len(segments) != len(tt.segments) {
+				t.Fatalf("SplitYAMLPath(%q) = %v, want %v", tt.expr, segments, tt.segments)
+			}
+			for i := range segments {
+				if segments[i] != tt.segments[i] {
+					t.Errorf("SplitYAMLPath(%q)[%d] = %q, want %q", tt.expr, i, segments[i], tt.segments[i])
+				}
+			}
+			if formatted := FormatYAMLPath(segments); formatted != tt.expr {
+				t.Errorf("FormatYAMLPath(%v) = %q, want %q", segments, formatted, tt.expr)
+			}
+		})
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob     string
+		name     string
+		expected bool
+	}{
+		{glob: "**", name: "a/b/c.yaml", expected: true},
+		{glob: "envs/**/values.yaml", name: "envs/values.yaml", expected: true},
+		{glob: "envs/**/values.yaml", name: "envs/prod/eu/values.yaml", expected: true},
+		{glob: "envs/*/values.yaml", name: "envs/prod/eu/values.yaml", expected: false},
+		{glob: "*.yaml", name: "dir/file.yaml", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+"_"+tt.name, func(t *testing.T) {
+			if got := MatchGlob(tt.glob, tt.name); got != tt.expected {
+				t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.glob, tt.name, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package progress reports the progress of long operations such as waiting for a
+// pipeline: a spinner or percentage line on an interactive terminal, periodic log
+// lines otherwise
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/terminal"
+)
+
+const (
+	// DefaultLogInterval is the minimum time between two progress log lines
+	DefaultLogInterval = 30 * time.Second
+	// PercentScale converts a fraction of the total into a percentage
+	PercentScale = 100
+
+	// spinnerFrames are drawn in turn, one per update, on an interactive terminal
+	spinnerFrames = `|/-\`
+	// clearLine returns to the start of the line and erases it
+	clearLine = "\r\033[K"
+)
+
+// Reporter starts progress tasks writing to a terminal or to the log
+type Reporter struct {
+	out         io.Writer
+	log         *logger.Logger
+	interactive bool
+	logInterval time.Duration
+	now         func() time.Time
+}
+
+// Task is one long operation reported by a Reporter. A nil task ignores every call,
+// so callers need not check whether progress is reported at all.
+type Task struct {
+	mu        sync.Mutex
+	reporter  *Reporter
+	operation string
+	total     int
+	started   time.Time
+	lastLog   time.Time
+	frame     int
+}
+
+// New creates a reporter drawing on out when interactive is set and logging to log
+// otherwise
+func New(out io.Writer, log *logger.Logger, interactive bool) *Reporter {
+	return &Reporter{
+		out:         out,
+		log:         log,
+		interactive: interactive,
+		logInterval: DefaultLogInterval,
+		now:         time.Now,
+	}
+}
+
+// NewDefault creates a reporter drawing on standard error when it is an interactive
+// terminal and logging to log otherwise
+func NewDefault(log *logger.Logger) *Reporter {
+	return New(os.Stderr, log, terminal.IsInteractive(os.Stderr))
+}
+
+// SetLogInterval overrides the minimum time between two progress log lines
+func (r *Reporter) SetLogInterval(interval time.Duration) {
+	if interval > 0 {
+		r.logInterval = interval
+	}
+}
+
+// Start begins reporting an operation. A positive total reports the progress as a
+// count and percentage; zero reports only the status and elapsed time.
+func (r *Reporter) Start(operation string, total int) *Task {
+	if r == nil {
+		return nil
+	}
+
+	now := r.now()
+	task := &Task{reporter: r, operation: operation, total: total, started: now, lastLog: now}
+	if !r.interactive && r.log != nil {
+		r.log.WithFields(map[string]interface{}{
+			"operation": operation,
+			"total":     total,
+		}).Info(operation + " started")
+	}
+	return task
+}
+
+// Update reports that done of the total items are finished and describes the current
+// state. Terminals redraw the line on every update; the log gets at most one line per
+// log interval.
+func (t *Task) Update(done int, status string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.reporter
+	now := r.now()
+	if r.interactive {
+		frame := spinnerFrames[t.frame%len(spinnerFrames)]
+		t.frame++
+		fmt.Fprintf(r.out, "%s%c %s", clearLine, frame, t.describe(done, status, now))
+		return
+	}
+
+	if r.log == nil || now.Sub(t.lastLog) < r.logInterval {
+		return
+	}
+	t.lastLog = now
+	fields := map[string]interface{}{
+		"operation": t.operation,
+		"status":    status,
+		"elapsed":   now.Sub(t.started).Round(time.Second).String(),
+	}
+	if t.total > 0 {
+		fields["done"] = done
+		fields["total"] = t.total
+		fields["percent"] = done * PercentScale / t.total
+	}
+	r.log.WithFields(fields).Info(t.operation + " in progress")
+}
+
+// Finish ends the operation, reporting err when it failed
+func (t *Task) Finish(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.reporter
+	elapsed := r.now().Sub(t.started).Round(time.Second)
+	if r.interactive {
+		outcome := "done"
+		if err != nil {
+			outcome = "failed: " + err.Error()
+		}
+		fmt.Fprintf(r.out, "%s%s %s after %s\n", clearLine, t.operation, outcome, elapsed)
+		return
+	}
+
+	if r.log == nil {
+		return
+	}
+	entry := r.log.WithFields(map[string]interface{}{
+		"operation": t.operation,
+		"elapsed":   elapsed.String(),
+	})
+	if err != nil {
+		entry.WithError(err).Warn(t.operation + " failed")
+		return
+	}
+	entry.Info(t.operation + " finished")
+}
+
+// describe renders the progress line drawn on a terminal
+func (t *Task) describe(done int, status string, now time.Time) string {
+	parts := []string{t.operation}
+	if t.total > 0 {
+		parts = append(parts, fmt.Sprintf("[%d/%d %d%%]", done, t.total, done*PercentScale/t.total))
+	}
+	if status != "" {
+		parts = append(parts, status)
+	}
+	parts = append(parts, fmt.Sprintf("(%s)", now.Sub(t.started).Round(time.Second)))
+	return strings.Join(parts, " ")
+}
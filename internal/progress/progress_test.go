@@ -0,0 +1,80 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+const (
+	TestOperation = "Waiting for pipeline"
+	TestInterval  = 30 * time.Second
+)
+
+// newTestReporter returns a reporter whose clock advances only through the returned function
+func newTestReporter(out *bytes.Buffer, log *logger.Logger, interactive bool) (*Reporter, func(time.Duration)) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	reporter := New(out, log, interactive)
+	reporter.now = func() time.Time { return now }
+	return reporter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTask_Interactive(t *testing.T) {
+	var out bytes.Buffer
+	reporter, advance := newTestReporter(&out, nil, true)
+
+	task := reporter.Start(TestOperation, 4)
+	advance(2 * time.Second)
+	task.Update(1, "pipeline 7 running")
+	task.Update(2, "pipeline 7 running")
+	task.Finish(nil)
+
+	output := out.String()
+	for _, want := range []string{
+		"| " + TestOperation + " [1/4 25%] pipeline 7 running (2s)",
+		"/ " + TestOperation + " [2/4 50%]",
+		TestOperation + " done after 2s\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output %q does not contain %q", output, want)
+		}
+	}
+}
+
+func TestTask_Log(t *testing.T) {
+	var out, logs bytes.Buffer
+	log := logger.NewWithConfig(&logger.Config{Level: logger.LevelInfo, Format: logger.FormatJSON, Output: &logs})
+	reporter, advance := newTestReporter(&out, log, false)
+	reporter.SetLogInterval(TestInterval)
+
+	task := reporter.Start(TestOperation, 0)
+	advance(TestInterval / 2)
+	task.Update(0, "too early")
+	advance(TestInterval)
+	task.Update(0, "pipeline 7 running")
+	task.Finish(errors.New("timeout"))
+
+	if out.Len() != 0 {
+		t.Errorf("log mode wrote to the terminal: %q", out.String())
+	}
+	output := logs.String()
+	if strings.Contains(output, "too early") {
+		t.Errorf("update within the log interval was logged: %s", output)
+	}
+	for _, want := range []string{" started", `"status":"pipeline 7 running"`, `"elapsed":"45s"`, " failed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log output does not contain %q: %s", want, output)
+		}
+	}
+}
+
+func TestTask_Nil(t *testing.T) {
+	var reporter *Reporter
+	task := reporter.Start(TestOperation, 1)
+	task.Update(1, "ignored")
+	task.Finish(nil)
+}
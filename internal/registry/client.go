@@ -0,0 +1,306 @@
+// Package registry lists image tags through the Docker Registry HTTP API v2 and
+// watches registries for new tags that satisfy a version constraint.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DockerHubRegistry is the registry of image names without a registry host
+	DockerHubRegistry = "docker.io"
+	// DockerHubAPIURL is the API endpoint of Docker Hub
+	DockerHubAPIURL = "https://registry-1.docker.io"
+	// dockerHubLibrary is the namespace of official Docker Hub images such as nginx
+	dockerHubLibrary = "library/"
+
+	// DefaultTimeout bounds every registry request
+	DefaultTimeout = 30 * time.Second
+	// TagsPageSize is the number of tags requested per page
+	TagsPageSize = 100
+	// MaxTagPages bounds how many pages of tags are read from a repository
+	MaxTagPages = 50
+	// maxResponseSize bounds the size of registry responses
+	maxResponseSize = 8 << 20
+
+	// bearerScheme is the challenge scheme of token authentication
+	bearerScheme = "Bearer"
+	// basicScheme is the challenge scheme of basic authentication
+	basicScheme = "Basic"
+)
+
+// Reference is an image repository within a registry
+type Reference struct {
+	// Registry is the registry host such as ghcr.io or registry.example.com:5000
+	Registry string
+	// Repository is the repository path such as library/nginx or team/app
+	Repository string
+}
+
+// ParseReference splits an image name like nginx, team/app or
+// registry.example.com/team/app into registry and repository. Names without a
+// registry host refer to Docker Hub. The name must not carry a tag or digest.
+func ParseReference(image string) (*Reference, error) {
+	if image == "" {
+		return nil, errors.NewValidationError("image is required")
+	}
+	if strings.Contains(image, "@") || strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		return nil, errors.NewValidationError(fmt.Sprintf("image %q must not include a tag or digest", image))
+	}
+
+	first, rest, hasSlash := strings.Cut(image, "/")
+	if hasSlash && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return &Reference{Registry: first, Repository: rest}, nil
+	}
+
+	repository := image
+	if !hasSlash {
+		repository = dockerHubLibrary + image
+	}
+	return &Reference{Registry: DockerHubRegistry, Repository: repository}, nil
+}
+
+// APIURL returns the base URL of the registry API
+func (r *Reference) APIURL() string {
+	if r.Registry == DockerHubRegistry {
+		return DockerHubAPIURL
+	}
+	return "https://" + r.Registry
+}
+
+// String returns the image name with its registry
+func (r *Reference) String() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// Credentials authenticate against a registry; anonymous access is used when empty
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client lists tags of image repositories
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient creates a registry client; a client with DefaultTimeout is used when
+// httpClient is nil
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Client{httpClient: httpClient}
+}
+
+// tagsResponse is the body of the tags list endpoint
+type tagsResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// tokenResponse is the body of a token endpoint; registries use either field
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+// ListTags returns every tag of a repository. apiURL is the registry API base URL,
+// such as Reference.APIURL. Token and basic authentication challenges are answered
+// with the credentials, and the result is read page by page.
+func (c *Client) ListTags(ctx context.Context, apiURL, repository string, creds Credentials) ([]string, error) {
+	base, err := url.Parse(strings.TrimSuffix(apiURL, "/"))
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid registry URL %q: %v", apiURL, err))
+	}
+
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", base, repository, TagsPageSize)
+	var tags []string
+	authorization := ""
+
+	for page := 0; next != "" && page < MaxTagPages; page++ {
+		resp, err := c.get(ctx, next, authorization)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && authorization == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			_ = resp.Body.Close()
+			if authorization, err = c.authorize(ctx, challenge, creds); err != nil {
+				return nil, err
+			}
+			if resp, err = c.get(ctx, next, authorization); err != nil {
+				return nil, err
+			}
+		}
+
+		var body tagsResponse
+		err = decodeResponse(resp, &body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", repository, err)
+		}
+		tags = append(tags, body.Tags...)
+
+		next, err = nextPage(base, resp.Header.Get("Link"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// get sends a GET request with an optional Authorization header
+func (c *Client) get(ctx context.Context, target, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid registry request: %v", err))
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.NewNetworkErrorWithCause("registry request failed", err)
+	}
+	return resp, nil
+}
+
+// authorize answers an authentication challenge and returns the Authorization header to use
+func (c *Client) authorize(ctx context.Context, challenge string, creds Credentials) (string, error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch {
+	case strings.EqualFold(scheme, bearerScheme) && params["realm"] != "":
+		token, err := c.fetchToken(ctx, params, creds)
+		if err != nil {
+			return "", err
+		}
+		return bearerScheme + " " + token, nil
+	case strings.EqualFold(scheme, basicScheme) && creds.Username != "":
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(creds.Username, creds.Password)
+		return req.Header.Get("Authorization"), nil
+	default:
+		return "", errors.NewAuthError("registry requires authentication: configure registry credentials")
+	}
+}
+
+// fetchToken requests a pull token from the realm of a bearer challenge
+func (c *Client) fetchToken(ctx context.Context, params map[string]string, creds Credentials) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", errors.NewAPIError(fmt.Sprintf("invalid token realm %q: %v", params["realm"], err))
+	}
+
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), http.NoBody)
+	if err != nil {
+		return "", errors.NewValidationError(fmt.Sprintf("invalid token request: %v", err))
+	}
+	if creds.Username != "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", errors.NewNetworkErrorWithCause("registry token request failed", err)
+	}
+
+	var body tokenResponse
+	if err := decodeResponse(resp, &body); err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.NewAuthError("registry token endpoint returned no token")
+}
+
+// decodeResponse closes the response and decodes a successful JSON body into out
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return errors.NewNetworkErrorWithCause("failed to read registry response", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errors.NewAuthError(fmt.Sprintf("registry denied access: %d", resp.StatusCode))
+	case resp.StatusCode != http.StatusOK:
+		return errors.NewAPIError(fmt.Sprintf("registry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return errors.NewAPIError(fmt.Sprintf("invalid registry response: %v", err))
+	}
+	return nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry" into scheme and parameters
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(rest, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+			_, rest, _ = strings.Cut(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		if key != "" {
+			params[key] = strings.TrimSpace(value)
+		}
+	}
+	return scheme, params
+}
+
+// nextPage returns the URL of the next page from a Link header, or "" on the last page
+func nextPage(base *url.URL, link string) (string, error) {
+	if link == "" || !strings.Contains(link, `rel="next"`) {
+		return "", nil
+	}
+
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start {
+		return "", errors.NewAPIError(fmt.Sprintf("invalid Link header %q", link))
+	}
+
+	target, err := base.Parse(link[start+1 : end])
+	if err != nil {
+		return "", errors.NewAPIError(fmt.Sprintf("invalid Link header %q: %v", link, err))
+	}
+	return target.String(), nil
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	TestRepository = "team/app"
+	TestUsername   = "robot"
+	TestPassword   = "robot-password"
+	TestToken      = "pull-token"
+)
+
+// newTestRegistry serves the tags of TestRepository in pages of two behind token
+// authentication; the token endpoint requires the test credentials when auth is set
+func newTestRegistry(t *testing.T, tags []string, auth bool) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if auth && (!ok || user != TestUsername || password != TestPassword) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:"+TestRepository+":pull" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{Token: TestToken})
+	})
+	mux.HandleFunc("GET /v2/"+TestRepository+"/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+TestToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="test-registry",scope="repository:%s:pull"`, server.URL, TestRepository))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, tag := range tags {
+				if tag == last {
+					start = i + 1
+				}
+			}
+		}
+		end := min(start+2, len(tags))
+		if end < len(tags) {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=2&last=%s>; rel="next"`, TestRepository, tags[end-1]))
+		}
+		_ = json.NewEncoder(w).Encode(tagsResponse{Name: TestRepository, Tags: tags[start:end]})
+	})
+
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image   string
+		want    Reference
+		wantURL string
+		wantErr bool
+	}{
+		{image: "nginx", want: Reference{Registry: DockerHubRegistry, Repository: "library/nginx"},
+			wantURL: DockerHubAPIURL},
+		{image: "team/app", want: Reference{Registry: DockerHubRegistry, Repository: "team/app"},
+			wantURL: DockerHubAPIURL},
+		{image: "ghcr.io/team/app", want: Reference{Registry: "ghcr.io", Repository: "team/app"},
+			wantURL: "https://ghcr.io"},
+		{image: "localhost:5000/app", want: Reference{Registry: "localhost:5000", Repository: "app"},
+			wantURL: "https://localhost:5000"},
+		{image: "nginx:1.25", wantErr: true},
+		{image: "ghcr.io/team/app@sha256:abc", wantErr: true},
+		{image: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := ParseReference(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference(%q) error = %v, wantErr %v", tt.image, err, tt.wantErr)
+			}
+			if err == nil && (*got != tt.want || got.APIURL() != tt.wantURL) {
+				t.Errorf("ParseReference(%q) = %+v (%s), want %+v (%s)", tt.image, got, got.APIURL(), tt.want, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestClient_ListTags(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.1.0", "v1.2.0", "latest", "v2.0.0-rc.1"}
+	server := newTestRegistry(t, tags, true)
+	client := NewClient(server.Client())
+
+	got, err := client.ListTags(context.Background(), server.URL, TestRepository,
+		Credentials{Username: TestUsername, Password: TestPassword})
+	if err != nil {
+		t.Fatalf("ListTags() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, tags) {
+		t.Errorf("ListTags() = %v, want %v", got, tags)
+	}
+
+	_, err = client.ListTags(context.Background(), server.URL, TestRepository, Credentials{})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("ListTags() without credentials error = %v, want a denied token request", err)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry",` +
+		`scope="repository:team/app:pull,push"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry",
+		"scope":   "repository:team/app:pull,push",
+	}
+	if scheme != "Bearer" || !reflect.DeepEqual(params, want) {
+		t.Errorf("parseChallenge() = %q %v, want Bearer %v", scheme, params, want)
+	}
+}
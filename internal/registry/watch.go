@@ -0,0 +1,320 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/progress"
+	"github.com/Gosayram/go-tag-updater/internal/semver"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Watch maps an image repository to the YAML field its newest matching tag is written to
+type Watch struct {
+	// Image is the repository to poll, such as nginx or registry.example.com/team/app
+	Image string `yaml:"image"`
+	// RegistryURL overrides the registry API URL derived from Image
+	RegistryURL string `yaml:"registry_url"`
+	// Constraint selects the tags to propose, such as "^1.4" or ">=1.2.0, <2.0.0";
+	// every release matches when empty
+	Constraint string `yaml:"constraint"`
+
+	ProjectID    string `yaml:"project_id"`
+	File         string `yaml:"file"`
+	YAMLPath     string `yaml:"yaml_path"`
+	TargetBranch string `yaml:"target_branch"`
+
+	// UsernameEnv and PasswordEnv name the environment variables holding registry
+	// credentials; the registry is accessed anonymously when unset
+	UsernameEnv string `yaml:"username_env"`
+	PasswordEnv string `yaml:"password_env"`
+
+	reference  *Reference
+	constraint *semver.Constraint
+}
+
+// watchesFile is the layout of a watch configuration file
+type watchesFile struct {
+	Watches []Watch `yaml:"watches"`
+}
+
+// LoadWatches reads and validates a watch configuration file
+func LoadWatches(filePath string) ([]Watch, error) {
+	data, err := os.ReadFile(filePath) // #nosec G304 -- the watch file is chosen by the operator
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewFileNotFoundError(filePath)
+		}
+		return nil, errors.NewConfigErrorWithCause("failed to read watches file "+filePath, err)
+	}
+
+	var file watchesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.NewConfigErrorWithCause("failed to parse watches file "+filePath, err)
+	}
+
+	for i := range file.Watches {
+		if err := file.Watches[i].compile(); err != nil {
+			return nil, errors.NewConfigErrorWithCause(fmt.Sprintf("invalid watch %d in %s", i+1, filePath), err)
+		}
+	}
+	return file.Watches, nil
+}
+
+// compile validates the watch and parses its image and constraint
+func (w *Watch) compile() error {
+	if w.Image == "" || w.ProjectID == "" || w.File == "" {
+		return errors.NewValidationError("image, project_id and file are required")
+	}
+
+	reference, err := ParseReference(w.Image)
+	if err != nil {
+		return err
+	}
+	constraint, err := semver.ParseConstraint(w.Constraint)
+	if err != nil {
+		return err
+	}
+
+	w.reference = reference
+	w.constraint = constraint
+	return nil
+}
+
+// apiURL returns the registry API URL of the watched image
+func (w *Watch) apiURL() string {
+	if w.RegistryURL != "" {
+		return w.RegistryURL
+	}
+	return w.reference.APIURL()
+}
+
+// credentials reads the registry credentials from the environment
+func (w *Watch) credentials() Credentials {
+	var creds Credentials
+	if w.UsernameEnv != "" {
+		creds.Username = os.Getenv(w.UsernameEnv)
+	}
+	if w.PasswordEnv != "" {
+		creds.Password = os.Getenv(w.PasswordEnv)
+	}
+	return creds
+}
+
+// Runner executes one tag update; workflow.RunUpdate is used when none is given
+type Runner func(ctx context.Context, cfg *config.CLIConfig, log *logger.Logger) (*workflow.SimpleUpdateResult, error)
+
+// TagReader returns the tag a file currently holds; workflow.CurrentTag is used when none is given
+type TagReader func(ctx context.Context, cfg *config.CLIConfig) (string, error)
+
+// WatcherOptions configures a Watcher
+type WatcherOptions struct {
+	// Base holds the GitLab connection and update behavior shared by every update
+	Base *config.CLIConfig
+	// Watches lists the polled images
+	Watches []Watch
+	// Interval is the time between two polls
+	Interval time.Duration
+
+	Client     *Client
+	Runner     Runner
+	CurrentTag TagReader
+}
+
+// PollResult reports what one poll did for a watch
+type PollResult struct {
+	Image      string
+	ProjectID  string
+	File       string
+	LatestTag  string
+	CurrentTag string
+	// Updated is set when an update ran for LatestTag
+	Updated bool
+	Result  *workflow.SimpleUpdateResult
+	Err     error
+}
+
+// Watcher periodically polls registries and runs a tag update when a tag newer
+// than the one in the file satisfies the constraint of a watch
+type Watcher struct {
+	opts     WatcherOptions
+	logger   *logger.Logger
+	progress *progress.Reporter
+
+	// proposed holds the latest tag handled per watch, so unchanged registries are
+	// not checked against GitLab again on every poll
+	proposed map[int]string
+}
+
+// NewWatcher validates the options and creates a watcher
+func NewWatcher(opts WatcherOptions, log *logger.Logger) (*Watcher, error) {
+	if opts.Base == nil {
+		return nil, errors.NewValidationError("base configuration is required")
+	}
+	if len(opts.Watches) == 0 {
+		return nil, errors.NewValidationError("at least one watch is required")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = config.DefaultRegistryWatchInterval
+	}
+	if opts.Client == nil {
+		opts.Client = NewClient(nil)
+	}
+	if opts.Runner == nil {
+		opts.Runner = workflow.RunUpdate
+	}
+	if opts.CurrentTag == nil {
+		opts.CurrentTag = workflow.CurrentTag
+	}
+
+	opts.Watches = append([]Watch(nil), opts.Watches...)
+	for i := range opts.Watches {
+		if err := opts.Watches[i].compile(); err != nil {
+			return nil, fmt.Errorf("invalid watch %d: %w", i+1, err)
+		}
+	}
+
+	return &Watcher{opts: opts, logger: log, progress: progress.NewDefault(log), proposed: make(map[int]string)}, nil
+}
+
+// Run polls every interval until the context is canceled
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Registry watch stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every watch once, running updates one after another
+func (w *Watcher) Poll(ctx context.Context) []PollResult {
+	results := make([]PollResult, 0, len(w.opts.Watches))
+	task := w.progress.Start("Polling watched images", len(w.opts.Watches))
+	for i := range w.opts.Watches {
+		if ctx.Err() != nil {
+			break
+		}
+		task.Update(i, w.opts.Watches[i].reference.String())
+		results = append(results, w.poll(ctx, i))
+	}
+	task.Finish(ctx.Err())
+	return results
+}
+
+// poll checks one watch for a newer matching tag and runs its update
+func (w *Watcher) poll(ctx context.Context, index int) PollResult {
+	watch := &w.opts.Watches[index]
+	result := PollResult{Image: watch.reference.String(), ProjectID: watch.ProjectID, File: watch.File}
+	watchLog := w.logger.WithFields(map[string]interface{}{
+		"image":      result.Image,
+		"project_id": watch.ProjectID,
+		"file_path":  watch.File,
+	})
+
+	tags, err := w.opts.Client.ListTags(ctx, watch.apiURL(), watch.reference.Repository, watch.credentials())
+	if err != nil {
+		watchLog.WithError(err).Error("Failed to list registry tags")
+		result.Err = err
+		return result
+	}
+
+	latest, ok := semver.Latest(tags, watch.constraint)
+	if !ok {
+		watchLog.WithField("constraint", watch.Constraint).Debug("No tag satisfies the constraint")
+		return result
+	}
+	result.LatestTag = latest
+	watchLog = watchLog.WithField("latest_tag", latest)
+
+	if w.proposed[index] == latest {
+		watchLog.Debug("Latest tag already handled")
+		return result
+	}
+
+	cfg, err := w.updateConfig(watch, latest)
+	if err != nil {
+		watchLog.WithError(err).Error("Failed to transform the latest tag")
+		result.Err = err
+		return result
+	}
+	current, err := w.opts.CurrentTag(ctx, cfg)
+	if err != nil {
+		watchLog.WithError(err).Error("Failed to read the current tag")
+		result.Err = err
+		return result
+	}
+	result.CurrentTag = current
+	watchLog = watchLog.WithField("current_tag", current)
+
+	if current == cfg.NewTag || !isNewer(latest, current) {
+		watchLog.Debug("File already uses the latest tag or a newer one")
+		w.proposed[index] = latest
+		return result
+	}
+
+	watchLog.WithField("run_id", cfg.RunID).Info("New tag found, running update")
+	updateResult, err := w.opts.Runner(ctx, cfg, w.logger)
+	result.Result = updateResult
+	result.Updated = err == nil
+	if err != nil {
+		watchLog.WithError(err).Error("Tag update failed; retrying on the next poll")
+		result.Err = err
+		return result
+	}
+
+	w.proposed[index] = latest
+	if updateResult != nil {
+		watchLog.Info(updateResult.Message)
+	}
+	return result
+}
+
+// updateConfig returns the configuration of the update proposing tag for a watch,
+// with the tag rewritten by the tag transforms
+func (w *Watcher) updateConfig(watch *Watch, tag string) (*config.CLIConfig, error) {
+	cfg := *w.opts.Base
+	cfg.ProjectID = watch.ProjectID
+	cfg.FilePath = watch.File
+	cfg.YAMLPath = watch.YAMLPath
+	cfg.BranchName = ""
+	cfg.RunID = journal.NewRunID()
+	// Restarts and repeated polls reuse the merge request opened for the same tag
+	cfg.UpdateExistingMR = true
+	if watch.TargetBranch != "" {
+		cfg.TargetBranch = watch.TargetBranch
+	}
+	if err := cfg.SetNewTag(tag); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// isNewer reports whether latest should replace current; a current value that is
+// not a version, such as "latest", is replaced by a pinned version
+func isNewer(latest, current string) bool {
+	latestVersion, err := semver.Parse(latest)
+	if err != nil {
+		return false
+	}
+	currentVersion, err := semver.Parse(current)
+	if err != nil {
+		return current != latest
+	}
+	return latestVersion.Compare(currentVersion) > 0
+}
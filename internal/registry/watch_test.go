@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestProjectID = "group/deploy"
+	TestFilePath  = "values.yaml"
+	TestYAMLPath  = "image.tag"
+)
+
+func TestWatcher_Poll(t *testing.T) {
+	t.Setenv("TEST_REGISTRY_USER", TestUsername)
+	t.Setenv("TEST_REGISTRY_PASSWORD", TestPassword)
+	server := newTestRegistry(t, []string{"v1.1.0", "v1.3.0", "v1.2.0", "v2.0.0", "latest"}, true)
+
+	current := "v1.1.0"
+	var runErr error
+	var runs []config.CLIConfig
+
+	watcher, err := NewWatcher(WatcherOptions{
+		Base: &config.CLIConfig{GitLabToken: "token", TargetBranch: "main", BranchName: "fixed"},
+		Watches: []Watch{{
+			Image:       "registry.example.com/" + TestRepository,
+			RegistryURL: server.URL,
+			Constraint:  "^1.0",
+			ProjectID:   TestProjectID,
+			File:        TestFilePath,
+			YAMLPath:    TestYAMLPath,
+			UsernameEnv: "TEST_REGISTRY_USER",
+			PasswordEnv: "TEST_REGISTRY_PASSWORD",
+		}},
+		Client: NewClient(server.Client()),
+		Runner: func(_ context.Context, cfg *config.CLIConfig, _ *logger.Logger) (*workflow.SimpleUpdateResult, error) {
+			runs = append(runs, *cfg)
+			return &workflow.SimpleUpdateResult{Success: runErr == nil}, runErr
+		},
+		CurrentTag: func(_ context.Context, _ *config.CLIConfig) (string, error) {
+			return current, nil
+		},
+	}, logger.New(false))
+	if err != nil {
+		t.Fatalf("NewWatcher() unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	// A failed update is retried on the next poll
+	runErr = errors.NewAPIError("gitlab unavailable")
+	if results := watcher.Poll(ctx); len(results) != 1 || results[0].Err == nil || len(runs) != 1 {
+		t.Fatalf("Poll() = %+v, runs %d, want one failed update", results, len(runs))
+	}
+
+	runErr = nil
+	results := watcher.Poll(ctx)
+	if len(results) != 1 || !results[0].Updated || results[0].LatestTag != "v1.3.0" || results[0].CurrentTag != current {
+		t.Fatalf("Poll() = %+v, want an update to v1.3.0", results)
+	}
+	run := runs[len(runs)-1]
+	if run.ProjectID != TestProjectID || run.FilePath != TestFilePath || run.YAMLPath != TestYAMLPath ||
+		run.NewTag != "v1.3.0" || run.BranchName != "" || run.RunID == "" || !run.UpdateExistingMR {
+		t.Errorf("update config = %+v", run)
+	}
+
+	// The same latest tag is not handled twice
+	if results := watcher.Poll(ctx); results[0].Updated || len(runs) != 2 {
+		t.Errorf("Poll() = %+v, runs %d, want no second update", results, len(runs))
+	}
+}
+
+func TestWatcher_SkipsOlderTags(t *testing.T) {
+	server := newTestRegistry(t, []string{"1.2.0", "1.4.0"}, false)
+
+	for _, current := range []string{"1.4.0", "1.5.0"} {
+		watcher, err := NewWatcher(WatcherOptions{
+			Base: &config.CLIConfig{},
+			Watches: []Watch{{Image: "registry.example.com/" + TestRepository, RegistryURL: server.URL,
+				ProjectID: TestProjectID, File: TestFilePath}},
+			Client: NewClient(server.Client()),
+			Runner: func(context.Context, *config.CLIConfig, *logger.Logger) (*workflow.SimpleUpdateResult, error) {
+				t.Errorf("update ran although the file uses %s", current)
+				return nil, nil
+			},
+			CurrentTag: func(context.Context, *config.CLIConfig) (string, error) { return current, nil },
+		}, logger.New(false))
+		if err != nil {
+			t.Fatalf("NewWatcher() unexpected error: %v", err)
+		}
+		if results := watcher.Poll(context.Background()); results[0].Err != nil || results[0].Updated {
+			t.Errorf("Poll() = %+v, want nothing to do", results)
+		}
+	}
+}
+
+func TestLoadWatches(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: "watches:\n  - image: nginx\n    constraint: '~1.25'\n" +
+			"    project_id: group/deploy\n    file: values.yaml\n"},
+		{name: "missing file", content: "watches:\n  - image: nginx\n    project_id: group/deploy\n", wantErr: true},
+		{name: "invalid constraint", content: "watches:\n  - image: nginx\n    constraint: '>=latest'\n" +
+			"    project_id: group/deploy\n    file: values.yaml\n", wantErr: true},
+		{name: "image with tag", content: "watches:\n  - image: nginx:1.25\n" +
+			"    project_id: group/deploy\n    file: values.yaml\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("Failed to write watches: %v", err)
+			}
+
+			watches, err := LoadWatches(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadWatches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (len(watches) != 1 || watches[0].reference.Repository != "library/nginx") {
+				t.Errorf("LoadWatches() = %+v", watches)
+			}
+		})
+	}
+
+	if _, err := LoadWatches(filepath.Join(dir, "missing.yaml")); errors.GetErrorCode(err) != errors.ErrCodeFileNotFound {
+		t.Errorf("LoadWatches() for a missing file error = %v, want file not found", err)
+	}
+}
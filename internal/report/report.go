@@ -0,0 +1,284 @@
+// Package report writes a detailed report of one tag update run, in JSON or Markdown,
+// to be attached to a CI job as an artifact.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// FormatJSON and FormatMarkdown are the report formats, chosen by file extension
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+
+	// FilePermissions lets CI runners read the report as an artifact
+	FilePermissions = 0o644
+
+	// minCodeFence is the shortest Markdown code fence
+	minCodeFence = 3
+)
+
+// Report describes one run
+type Report struct {
+	RunID      string        `json:"run_id,omitempty"`
+	Success    bool          `json:"success"`
+	Skipped    bool          `json:"skipped,omitempty"`
+	DryRun     bool          `json:"dry_run,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration_ns"`
+
+	Inputs       Inputs        `json:"inputs"`
+	Project      *Project      `json:"project,omitempty"`
+	Branch       *Link         `json:"branch,omitempty"`
+	CommitURL    string        `json:"commit_url,omitempty"`
+	MergeRequest *MergeRequest `json:"merge_request,omitempty"`
+	Diffs        []FileDiff    `json:"diffs,omitempty"`
+	Validations  []Validation  `json:"validations,omitempty"`
+	Steps        []Step        `json:"steps,omitempty"`
+	APICalls     APICalls      `json:"api_calls"`
+}
+
+// Inputs are the settings the run was started with
+type Inputs struct {
+	ProjectID    string `json:"project_id"`
+	FilePath     string `json:"file_path,omitempty"`
+	RepoPathGlob string `json:"repo_path_glob,omitempty"`
+	YAMLPath     string `json:"yaml_path,omitempty"`
+	Updater      string `json:"updater,omitempty"`
+	OldTag       string `json:"old_tag,omitempty"`
+	NewTag       string `json:"new_tag"`
+	TargetBranch string `json:"target_branch"`
+	AutoMerge    bool   `json:"auto_merge,omitempty"`
+}
+
+// Project is the GitLab project the project ID input resolved to
+type Project struct {
+	ID int `json:"id"`
+}
+
+// Link names a GitLab object and links it in the GitLab UI
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+// MergeRequest is the merge request the run opened or reused
+type MergeRequest struct {
+	IID   int    `json:"iid"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url"`
+	State string `json:"state,omitempty"`
+}
+
+// FileDiff is the change made to one file as a unified diff
+type FileDiff struct {
+	Path    string `json:"path"`
+	Changes int    `json:"changes,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// Validation is the outcome of one --validate-cmd command on an updated file
+type Validation struct {
+	Command  string        `json:"command"`
+	Path     string        `json:"path"`
+	Passed   bool          `json:"passed"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"duration_ns"`
+	Output   string        `json:"output,omitempty"`
+}
+
+// Step is the outcome of one workflow step
+type Step struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// APICalls counts the GitLab API requests of the run
+type APICalls struct {
+	Requests    uint64 `json:"requests"`
+	Failed      uint64 `json:"failed"`
+	Retries     uint64 `json:"retries"`
+	RateLimited uint64 `json:"rate_limited"`
+	CacheHits   uint64 `json:"cache_hits"`
+}
+
+// FormatOf returns the format of a report file from its extension
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	}
+	return "", errors.NewValidationErrorWithContext(
+		fmt.Sprintf("unsupported report file %s", path), "use a .json or .md file")
+}
+
+// Write writes the report to path in the format of its extension
+func Write(path string, r *Report) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if format == FormatJSON {
+		data, err = json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		data = append(data, '\n')
+	} else {
+		data = []byte(Markdown(r))
+	}
+
+	if err := os.WriteFile(path, data, FilePermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write report %s: %v", path, err))
+	}
+	return nil
+}
+
+// Markdown renders the report as a Markdown document
+func Markdown(r *Report) string {
+	var b strings.Builder
+	status := "Succeeded"
+	switch {
+	case !r.Success:
+		status = "Failed"
+	case r.Skipped:
+		status = "Skipped"
+	case r.DryRun:
+		status = "Dry run"
+	}
+	fmt.Fprintf(&b, "# Tag update report\n\n**%s** in %s", status, r.Duration.Round(time.Millisecond))
+	if r.RunID != "" {
+		fmt.Fprintf(&b, " (run `%s`)", r.RunID)
+	}
+	b.WriteString("\n\n")
+	if r.Message != "" {
+		b.WriteString(r.Message + "\n\n")
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n\n", r.Error)
+	}
+
+	b.WriteString("## Inputs\n\n| Setting | Value |\n| --- | --- |\n")
+	project := r.Inputs.ProjectID
+	if r.Project != nil {
+		project = fmt.Sprintf("%s (ID %d)", r.Inputs.ProjectID, r.Project.ID)
+	}
+	rows := [][2]string{
+		{"Project", project},
+		{"File", r.Inputs.FilePath},
+		{"Files matching", r.Inputs.RepoPathGlob},
+		{"YAML path", r.Inputs.YAMLPath},
+		{"Updater profile", r.Inputs.Updater},
+		{"Old tag", r.Inputs.OldTag},
+		{"New tag", r.Inputs.NewTag},
+		{"Target branch", r.Inputs.TargetBranch},
+	}
+	for _, row := range rows {
+		if row[1] != "" {
+			fmt.Fprintf(&b, "| %s | %s |\n", row[0], row[1])
+		}
+	}
+
+	if r.Branch != nil || r.MergeRequest != nil || r.CommitURL != "" {
+		b.WriteString("\n## Links\n\n")
+		if r.Branch != nil {
+			fmt.Fprintf(&b, "- Branch: %s\n", markdownLink(r.Branch.Name, r.Branch.URL))
+		}
+		if r.CommitURL != "" {
+			fmt.Fprintf(&b, "- Commit: %s\n", r.CommitURL)
+		}
+		if mr := r.MergeRequest; mr != nil {
+			fmt.Fprintf(&b, "- Merge request: %s", markdownLink(fmt.Sprintf("!%d", mr.IID), mr.URL))
+			if mr.Title != "" {
+				fmt.Fprintf(&b, " %s", mr.Title)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	for _, diff := range r.Diffs {
+		fmt.Fprintf(&b, "\n## Changes to %s\n\n", diff.Path)
+		if diff.Diff == "" {
+			fmt.Fprintf(&b, "%d value(s) replaced\n", diff.Changes)
+			continue
+		}
+		fence := codeFence(diff.Diff)
+		fmt.Fprintf(&b, "%sdiff\n%s", fence, diff.Diff)
+		if !strings.HasSuffix(diff.Diff, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(fence + "\n")
+	}
+
+	if len(r.Validations) > 0 {
+		b.WriteString("\n## Validation\n")
+		for _, validation := range r.Validations {
+			status := "passed"
+			if !validation.Passed {
+				status = fmt.Sprintf("failed with exit code %d", validation.ExitCode)
+			}
+			fmt.Fprintf(&b, "\n`%s` on %s %s in %s\n", validation.Command, validation.Path, status,
+				validation.Duration.Round(time.Millisecond))
+			if validation.Output == "" {
+				continue
+			}
+			fence := codeFence(validation.Output)
+			fmt.Fprintf(&b, "\n%s\n%s", fence, validation.Output)
+			if !strings.HasSuffix(validation.Output, "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString(fence + "\n")
+		}
+	}
+
+	if len(r.Steps) > 0 {
+		b.WriteString("\n## Steps\n\n| Step | Status | Duration | Error |\n| --- | --- | --- | --- |\n")
+		for _, step := range r.Steps {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+				step.Name, step.Status, step.Duration.Round(time.Millisecond), step.Error)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n## GitLab API\n\n| Requests | Failed | Retries | Rate limited | Cache hits |\n"+
+		"| --- | --- | --- | --- | --- |\n| %d | %d | %d | %d | %d |\n",
+		r.APICalls.Requests, r.APICalls.Failed, r.APICalls.Retries, r.APICalls.RateLimited, r.APICalls.CacheHits)
+	return b.String()
+}
+
+// markdownLink links name to url, or returns name when there is no URL
+func markdownLink(name, url string) string {
+	if url == "" {
+		return name
+	}
+	return fmt.Sprintf("[%s](%s)", name, url)
+}
+
+// codeFence returns a fence longer than any backtick run in content
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(minCodeFence, longest+1))
+}
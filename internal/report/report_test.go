@@ -0,0 +1,98 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func testReport() *Report {
+	started := time.Date(2026, time.January, 2, 15, 4, 5, 0, time.UTC)
+	return &Report{
+		RunID:      "20260102T150405Z-1a2b3c4d",
+		Success:    true,
+		Message:    "Merge request !42 created",
+		StartedAt:  started,
+		FinishedAt: started.Add(1500 * time.Millisecond),
+		Duration:   1500 * time.Millisecond,
+		Inputs: Inputs{
+			ProjectID:    "group/project",
+			FilePath:     "values.yaml",
+			NewTag:       "v1.2.3",
+			TargetBranch: "main",
+		},
+		Project:      &Project{ID: 7},
+		Branch:       &Link{Name: "update-tag/v1.2.3", URL: "https://gitlab.example.com/-/tree/update-tag/v1.2.3"},
+		MergeRequest: &MergeRequest{IID: 42, Title: "Update tag", URL: "https://gitlab.example.com/-/merge_requests/42"},
+		Diffs:        []FileDiff{{Path: "values.yaml", Diff: "-tag: v1.0.0\n+tag: v1.2.3\n```\n"}},
+		Validations: []Validation{{
+			Command:  "kubeconform -strict {}",
+			Path:     "values.yaml",
+			Passed:   true,
+			Duration: 300 * time.Millisecond,
+			Output:   "values.yaml - Deployment web is valid",
+		}},
+		Steps: []Step{
+			{Name: "validate", Duration: 120 * time.Millisecond, Status: "succeeded"},
+			{Name: "update", Duration: time.Second, Status: "succeeded"},
+		},
+		APICalls: APICalls{Requests: 9, Retries: 1},
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"report.json":     FormatJSON,
+		"out/REPORT.MD":   FormatMarkdown,
+		"report.markdown": FormatMarkdown,
+	}
+	for path, want := range tests {
+		if got, err := FormatOf(path); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if _, err := FormatOf("report.txt"); errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("FormatOf(report.txt) = %v, want a validation error", err)
+	}
+}
+
+func TestWrite_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := Write(path, testReport()); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() unexpected error: %v", err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON report: %v", err)
+	}
+	if got.MergeRequest.IID != 42 || len(got.Steps) != 2 || got.APICalls.Requests != 9 {
+		t.Errorf("report = %+v", got)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	markdown := Markdown(testReport())
+	for _, want := range []string{
+		"**Succeeded** in 1.5s (run `20260102T150405Z-1a2b3c4d`)",
+		"| Project | group/project (ID 7) |",
+		"- Merge request: [!42](https://gitlab.example.com/-/merge_requests/42) Update tag",
+		"````diff\n-tag: v1.0.0\n+tag: v1.2.3\n```\n````\n",
+		"`kubeconform -strict {}` on values.yaml passed in 300ms\n\n```\nvalues.yaml - Deployment web is valid\n```\n",
+		"| update | succeeded | 1s |  |",
+		"| 9 | 0 | 1 | 0 | 0 |",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown() does not contain %q:\n%s", want, markdown)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package schedule describes recurring working hours, used to defer actions such as
+// auto-merge until someone is around to react to them.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// ClockFormat is the layout of the start and end times of working hours
+	ClockFormat = "15:04"
+	// DaysPerWeek is the number of weekdays a window may cover
+	DaysPerWeek = 7
+	// dayRangeSeparator separates the first and last day of a day range
+	dayRangeSeparator = "-"
+	// listSeparator separates days or day ranges
+	listSeparator = ","
+)
+
+// weekdays maps three-letter day names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// WorkingHours is a weekly recurring window such as "Mon-Fri 09:00-17:00" in a time zone
+type WorkingHours struct {
+	days     [DaysPerWeek]bool
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+	spec     string
+}
+
+// Parse parses a window of the form "<days> <start>-<end>", for example
+// "Mon-Fri 09:00-17:00" or "Mon,Wed,Fri 10:00-16:00", in the named IANA time
+// zone; an empty timezone means UTC. An empty spec returns nil: no restriction.
+func Parse(spec, timezone string) (*WorkingHours, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, errors.NewConfigErrorWithCause("invalid time zone "+timezone, err)
+		}
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 2 {
+		return nil, errors.NewConfigError(fmt.Sprintf(
+			"invalid working hours %q: expected \"<days> <HH:MM>-<HH:MM>\", e.g. \"Mon-Fri 09:00-17:00\"", spec))
+	}
+
+	hours := &WorkingHours{location: location, spec: spec}
+	if err := hours.parseDays(fields[0]); err != nil {
+		return nil, err
+	}
+	if err := hours.parseClock(fields[1]); err != nil {
+		return nil, err
+	}
+
+	return hours, nil
+}
+
+// parseDays parses a list of days and day ranges
+func (w *WorkingHours) parseDays(days string) error {
+	for _, part := range strings.Split(days, listSeparator) {
+		first, last, isRange := strings.Cut(part, dayRangeSeparator)
+		from, ok := weekdays[strings.ToLower(first)]
+		if !ok {
+			return errors.NewConfigError(fmt.Sprintf("invalid working hours day %q in %q", first, w.spec))
+		}
+
+		to := from
+		if isRange {
+			if to, ok = weekdays[strings.ToLower(last)]; !ok {
+				return errors.NewConfigError(fmt.Sprintf("invalid working hours day %q in %q", last, w.spec))
+			}
+		}
+
+		for day := from; ; day = (day + 1) % DaysPerWeek {
+			w.days[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseClock parses the start and end time of the window
+func (w *WorkingHours) parseClock(clock string) error {
+	startText, endText, ok := strings.Cut(clock, dayRangeSeparator)
+	if !ok {
+		return errors.NewConfigError(fmt.Sprintf("invalid working hours time range %q in %q", clock, w.spec))
+	}
+
+	start, err := time.Parse(ClockFormat, startText)
+	if err != nil {
+		return errors.NewConfigErrorWithCause(fmt.Sprintf("invalid working hours start in %q", w.spec), err)
+	}
+	end, err := time.Parse(ClockFormat, endText)
+	if err != nil {
+		return errors.NewConfigErrorWithCause(fmt.Sprintf("invalid working hours end in %q", w.spec), err)
+	}
+
+	w.start = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+	w.end = time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+	if w.end <= w.start {
+		return errors.NewConfigError(fmt.Sprintf("working hours must end after they start: %q", w.spec))
+	}
+
+	return nil
+}
+
+// String returns the window as it was configured
+func (w *WorkingHours) String() string {
+	return w.spec + " " + w.location.String()
+}
+
+// Contains reports whether t falls within the working hours
+func (w *WorkingHours) Contains(t time.Time) bool {
+	local := t.In(w.location)
+	if !w.days[local.Weekday()] {
+		return false
+	}
+
+	offset := local.Sub(midnight(local))
+	return offset >= w.start && offset < w.end
+}
+
+// Next returns t when it falls within the working hours, otherwise the start of the
+// next working period
+func (w *WorkingHours) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	local := t.In(w.location)
+	day := midnight(local)
+	for i := 0; i <= DaysPerWeek; i++ {
+		if w.days[day.Weekday()] {
+			start := day.Add(w.start)
+			if !start.Before(local) {
+				return start
+			}
+		}
+		day = midnight(day.AddDate(0, 0, 1))
+	}
+
+	return t
+}
+
+// midnight returns the start of the day of t in its location
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+const (
+	TestWorkweek = "Mon-Fri 09:00-17:00"
+	TestTimezone = "Europe/Berlin"
+)
+
+// berlin returns a wall clock time in the test time zone
+func berlin(t *testing.T, value string) time.Time {
+	t.Helper()
+	location, err := time.LoadLocation(TestTimezone)
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	parsed, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+	if err != nil {
+		t.Fatalf("invalid test time %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		timezone string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "empty", spec: "", wantNil: true},
+		{name: "workweek", spec: TestWorkweek, timezone: TestTimezone},
+		{name: "day list", spec: "mon,wed,Fri 10:00-16:00"},
+		{name: "wrapping range", spec: "Fri-Mon 08:00-12:00"},
+		{name: "unknown day", spec: "Mon-Fry 09:00-17:00", wantErr: true},
+		{name: "missing times", spec: "Mon-Fri", wantErr: true},
+		{name: "bad clock", spec: "Mon-Fri 9am-5pm", wantErr: true},
+		{name: "end before start", spec: "Mon-Fri 17:00-09:00", wantErr: true},
+		{name: "unknown timezone", spec: TestWorkweek, timezone: "Mars/Olympus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours, err := Parse(tt.spec, tt.timezone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (hours == nil) != tt.wantNil {
+				t.Errorf("Parse() = %v, want nil %v", hours, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestWorkingHours_ContainsAndNext(t *testing.T) {
+	hours, err := Parse(TestWorkweek, TestTimezone)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		at           string
+		wantContains bool
+		wantNext     string
+	}{
+		{name: "during hours", at: "2026-10-14 10:30", wantContains: true, wantNext: "2026-10-14 10:30"},
+		{name: "before opening", at: "2026-10-14 07:00", wantNext: "2026-10-14 09:00"},
+		{name: "at closing", at: "2026-10-14 17:00", wantNext: "2026-10-15 09:00"},
+		{name: "friday evening", at: "2026-10-16 18:00", wantNext: "2026-10-19 09:00"},
+		{name: "sunday", at: "2026-10-18 12:00", wantNext: "2026-10-19 09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := berlin(t, tt.at)
+			if got := hours.Contains(at); got != tt.wantContains {
+				t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.wantContains)
+			}
+			if got, want := hours.Next(at), berlin(t, tt.wantNext); !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.at, got, want)
+			}
+			if got := hours.Next(at.UTC()); !hours.Contains(got) {
+				t.Errorf("Next(%s) = %s is outside the working hours", tt.at, got)
+			}
+		})
+	}
+}
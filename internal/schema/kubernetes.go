@@ -0,0 +1,355 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	apperrors "github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Kubernetes names the minimal structural check of Kubernetes manifests in
+// --validate-schema
+const Kubernetes = "k8s"
+
+// kubernetesListKind is the kind of v1 lists whose items are resources themselves
+const kubernetesListKind = "List"
+
+// Schemas of the values shared by the Kubernetes resources
+var (
+	anySchema      = &Schema{Type: TypeAny}
+	stringSchema   = &Schema{Type: TypeString}
+	integerSchema  = &Schema{Type: TypeInteger}
+	booleanSchema  = &Schema{Type: TypeBoolean}
+	stringMap      = &Schema{Type: TypeObject, Values: stringSchema}
+	stringList     = &Schema{Type: TypeArray, Items: stringSchema}
+	quantityMap    = &Schema{Type: TypeObject, Values: &Schema{Type: TypeQuantity}}
+	protocolSchema = &Schema{Type: TypeString, Enum: []string{"TCP", "UDP", "SCTP"}}
+
+	objectMetaSchema = &Schema{Type: TypeObject, Properties: fields(map[string]*Schema{
+		"name":         stringSchema,
+		"generateName": stringSchema,
+		"namespace":    stringSchema,
+		"labels":       stringMap,
+		"annotations":  stringMap,
+	}, "selfLink", "uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+		"deletionGracePeriodSeconds", "ownerReferences", "finalizers", "managedFields")}
+
+	labelSelectorSchema = &Schema{Type: TypeObject, Properties: map[string]*Schema{
+		"matchLabels": stringMap,
+		"matchExpressions": {Type: TypeArray, Items: &Schema{
+			Type:     TypeObject,
+			Required: []string{"key", "operator"},
+			Properties: map[string]*Schema{
+				"key":      stringSchema,
+				"operator": {Type: TypeString, Enum: []string{"In", "NotIn", "Exists", "DoesNotExist"}},
+				"values":   stringList,
+			},
+		}},
+	}}
+
+	containerSchema = &Schema{
+		Type:     TypeObject,
+		Required: []string{"name"},
+		Properties: fields(map[string]*Schema{
+			"name":            stringSchema,
+			"image":           stringSchema,
+			"imagePullPolicy": {Type: TypeString, Enum: []string{"Always", "IfNotPresent", "Never"}},
+			"command":         stringList,
+			"args":            stringList,
+			"workingDir":      stringSchema,
+			"env": {Type: TypeArray, Items: &Schema{
+				Type:       TypeObject,
+				Required:   []string{"name"},
+				Properties: fields(map[string]*Schema{"name": stringSchema, "value": stringSchema}, "valueFrom"),
+			}},
+			"ports": {Type: TypeArray, Items: &Schema{
+				Type:     TypeObject,
+				Required: []string{"containerPort"},
+				Properties: fields(map[string]*Schema{
+					"name":          stringSchema,
+					"containerPort": integerSchema,
+					"hostPort":      integerSchema,
+					"protocol":      protocolSchema,
+				}, "hostIP"),
+			}},
+			"resources": {Type: TypeObject, Properties: fields(map[string]*Schema{
+				"limits":   quantityMap,
+				"requests": quantityMap,
+			}, "claims")},
+		}, "envFrom", "resizePolicy", "restartPolicy", "volumeMounts", "volumeDevices", "livenessProbe",
+			"readinessProbe", "startupProbe", "lifecycle", "terminationMessagePath", "terminationMessagePolicy",
+			"securityContext", "stdin", "stdinOnce", "tty"),
+	}
+
+	podSpecSchema = &Schema{
+		Type:     TypeObject,
+		Required: []string{"containers"},
+		Properties: fields(map[string]*Schema{
+			"containers":         {Type: TypeArray, Items: containerSchema},
+			"initContainers":     {Type: TypeArray, Items: containerSchema},
+			"restartPolicy":      {Type: TypeString, Enum: []string{"Always", "OnFailure", "Never"}},
+			"serviceAccountName": stringSchema,
+			"nodeSelector":       stringMap,
+			"hostNetwork":        booleanSchema,
+			"imagePullSecrets": {Type: TypeArray, Items: &Schema{
+				Type:       TypeObject,
+				Properties: map[string]*Schema{"name": stringSchema},
+			}},
+		}, "volumes", "ephemeralContainers", "terminationGracePeriodSeconds", "activeDeadlineSeconds",
+			"dnsPolicy", "serviceAccount", "automountServiceAccountToken", "nodeName", "hostPID", "hostIPC",
+			"shareProcessNamespace", "securityContext", "hostname", "subdomain", "affinity", "schedulerName",
+			"tolerations", "hostAliases", "priorityClassName", "priority", "dnsConfig", "readinessGates",
+			"runtimeClassName", "enableServiceLinks", "preemptionPolicy", "overhead", "topologySpreadConstraints",
+			"setHostnameAsFQDN", "os", "hostUsers", "schedulingGates", "resourceClaims", "resources"),
+	}
+
+	podTemplateSchema = &Schema{
+		Type:       TypeObject,
+		Properties: map[string]*Schema{"metadata": objectMetaSchema, "spec": podSpecSchema},
+	}
+
+	jobSpecSchema = &Schema{
+		Type:     TypeObject,
+		Required: []string{"template"},
+		Properties: fields(map[string]*Schema{
+			"template":     podTemplateSchema,
+			"selector":     labelSelectorSchema,
+			"backoffLimit": integerSchema,
+			"completions":  integerSchema,
+			"parallelism":  integerSchema,
+			"suspend":      booleanSchema,
+		}, "activeDeadlineSeconds", "podFailurePolicy", "successPolicy", "backoffLimitPerIndex",
+			"maxFailedIndexes", "manualSelector", "ttlSecondsAfterFinished", "completionMode",
+			"podReplacementPolicy", "managedBy"),
+	}
+)
+
+// kubernetesSchemas holds the schemas of the built-in resources, keyed by apiVersion
+// and kind. They list every field of the resources so misspelled fields are caught,
+// but only check the values of the fields a tag update can break; they are a minimal
+// structural check, not the full OpenAPI schemas. Other resources, such as custom
+// resources, are not validated.
+var kubernetesSchemas = map[string]*Schema{
+	"v1/Pod": resourceSchema(podSpecSchema),
+	"v1/ReplicationController": resourceSchema(&Schema{Type: TypeObject, Properties: map[string]*Schema{
+		"replicas":        integerSchema,
+		"minReadySeconds": integerSchema,
+		"selector":        stringMap,
+		"template":        podTemplateSchema,
+	}}),
+	"v1/Service": resourceSchema(&Schema{Type: TypeObject, Properties: fields(map[string]*Schema{
+		"type":     {Type: TypeString, Enum: []string{"ClusterIP", "NodePort", "LoadBalancer", "ExternalName"}},
+		"selector": stringMap,
+		"ports": {Type: TypeArray, Items: &Schema{
+			Type:     TypeObject,
+			Required: []string{"port"},
+			Properties: fields(map[string]*Schema{
+				"name":       stringSchema,
+				"port":       integerSchema,
+				"targetPort": {Type: TypeIntOrString},
+				"nodePort":   integerSchema,
+				"protocol":   protocolSchema,
+			}, "appProtocol"),
+		}},
+	}, "clusterIP", "clusterIPs", "externalIPs", "sessionAffinity", "loadBalancerIP", "loadBalancerSourceRanges",
+		"externalName", "externalTrafficPolicy", "healthCheckNodePort", "publishNotReadyAddresses",
+		"sessionAffinityConfig", "ipFamilies", "ipFamilyPolicy", "allocateLoadBalancerNodePorts",
+		"loadBalancerClass", "internalTrafficPolicy", "trafficDistribution")}),
+	"v1/ConfigMap": {Type: TypeObject, Properties: typeFields(map[string]*Schema{
+		"metadata":   objectMetaSchema,
+		"data":       stringMap,
+		"binaryData": stringMap,
+		"immutable":  booleanSchema,
+	})},
+	"v1/Secret": {Type: TypeObject, Properties: typeFields(map[string]*Schema{
+		"metadata":   objectMetaSchema,
+		"type":       stringSchema,
+		"data":       stringMap,
+		"stringData": stringMap,
+		"immutable":  booleanSchema,
+	})},
+	"apps/v1/Deployment": resourceSchema(workloadSpec(map[string]*Schema{"replicas": integerSchema},
+		"strategy", "minReadySeconds", "revisionHistoryLimit", "paused", "progressDeadlineSeconds")),
+	"apps/v1/ReplicaSet": resourceSchema(workloadSpec(map[string]*Schema{"replicas": integerSchema},
+		"minReadySeconds")),
+	"apps/v1/DaemonSet": resourceSchema(workloadSpec(nil,
+		"updateStrategy", "minReadySeconds", "revisionHistoryLimit")),
+	"apps/v1/StatefulSet": resourceSchema(workloadSpec(
+		map[string]*Schema{"replicas": integerSchema, "serviceName": stringSchema},
+		"volumeClaimTemplates", "podManagementPolicy", "updateStrategy", "revisionHistoryLimit",
+		"minReadySeconds", "persistentVolumeClaimRetentionPolicy", "ordinals")),
+	"batch/v1/Job": resourceSchema(jobSpecSchema),
+	"batch/v1/CronJob": resourceSchema(&Schema{
+		Type:     TypeObject,
+		Required: []string{"schedule", "jobTemplate"},
+		Properties: fields(map[string]*Schema{
+			"schedule": stringSchema,
+			"suspend":  booleanSchema,
+			"jobTemplate": {
+				Type:       TypeObject,
+				Required:   []string{"spec"},
+				Properties: map[string]*Schema{"metadata": objectMetaSchema, "spec": jobSpecSchema},
+			},
+		}, "timeZone", "startingDeadlineSeconds", "concurrencyPolicy", "successfulJobsHistoryLimit",
+			"failedJobsHistoryLimit"),
+	}),
+}
+
+// resourceSchema returns the schema of a resource with the given spec
+func resourceSchema(spec *Schema) *Schema {
+	return &Schema{
+		Type:       TypeObject,
+		Required:   []string{"spec"},
+		Properties: typeFields(map[string]*Schema{"metadata": objectMetaSchema, "spec": spec, "status": anySchema}),
+	}
+}
+
+// typeFields adds the apiVersion and kind every resource has to its fields
+func typeFields(properties map[string]*Schema) map[string]*Schema {
+	return fields(properties, "apiVersion", "kind")
+}
+
+// fields returns the properties of an object whose values are checked, plus the
+// names of the other fields it may have, accepted with any value
+func fields(checked map[string]*Schema, other ...string) map[string]*Schema {
+	properties := make(map[string]*Schema, len(checked)+len(other))
+	for name, property := range checked {
+		properties[name] = property
+	}
+	for _, name := range other {
+		properties[name] = anySchema
+	}
+	return properties
+}
+
+// workloadSpec returns the spec of a workload managing pods from a template, with
+// the checked and other fields of its kind
+func workloadSpec(extra map[string]*Schema, other ...string) *Schema {
+	properties := fields(extra, other...)
+	properties["selector"] = labelSelectorSchema
+	properties["template"] = podTemplateSchema
+	return &Schema{Type: TypeObject, Required: []string{"selector", "template"}, Properties: properties}
+}
+
+// Names returns the schemas --validate-schema accepts
+func Names() []string {
+	return []string{Kubernetes}
+}
+
+// Validate checks content against the named schemas and returns a validation error
+// listing every violation
+func Validate(name, content string) error {
+	var (
+		violations []Violation
+		err        error
+	)
+	switch name {
+	case Kubernetes:
+		violations, err = ValidateKubernetes(content)
+	default:
+		return apperrors.NewValidationError(fmt.Sprintf("unknown schema %q: expected %s",
+			name, strings.Join(Names(), ", ")))
+	}
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(violations))
+	for i, violation := range violations {
+		messages[i] = violation.String()
+	}
+	return apperrors.NewValidationErrorWithContext(
+		fmt.Sprintf("updated content does not match the %s schema: %s", name, strings.Join(messages, "; ")),
+		"fix the manifest or run without --validate-schema")
+}
+
+// ValidateKubernetes checks the structure of every document of content against the
+// built-in Kubernetes schemas. Each document must have an apiVersion and a kind;
+// documents of other kinds, like custom resources, are accepted as is.
+func ValidateKubernetes(content string) ([]Violation, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	var violations []Violation
+	for index := 0; ; index++ {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperrors.NewValidationError(fmt.Sprintf("failed to parse document %d: %v", index, err))
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		v := &validator{document: index}
+		v.validateResource(document.Content[0], "")
+		violations = append(violations, v.violations...)
+	}
+	sortViolations(violations)
+	return violations, nil
+}
+
+// validateResource checks a resource against the schema of its apiVersion and kind
+func (v *validator) validateResource(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.add(node, path, "expected a Kubernetes resource, got "+describeNode(node))
+		return
+	}
+	apiVersion, kind := scalarField(node, "apiVersion"), scalarField(node, "kind")
+	if apiVersion == "" || kind == "" {
+		v.add(node, path, "not a Kubernetes resource: apiVersion and kind are required")
+		return
+	}
+
+	resource := kind
+	if name := scalarField(mappingField(node, "metadata"), "name"); name != "" {
+		resource += "/" + name
+	}
+	if path == "" {
+		v.resource = resource
+	}
+
+	if apiVersion == "v1" && kind == kubernetesListKind {
+		items := mappingField(node, "items")
+		if items == nil || items.Kind != yaml.SequenceNode {
+			v.add(node, joinPath(path, "items"), "expected the items of the list")
+			return
+		}
+		for i, item := range items.Content {
+			v.validateResource(item, fmt.Sprintf("%s[%d]", joinPath(path, "items"), i))
+		}
+		return
+	}
+	if s, ok := kubernetesSchemas[apiVersion+"/"+kind]; ok {
+		v.validate(node, s, path)
+	}
+}
+
+// mappingField returns the value of a field of a mapping node, or nil
+func mappingField(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarField returns the value of a scalar field of a mapping node, or ""
+func scalarField(node *yaml.Node, key string) string {
+	value := mappingField(node, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return value.Value
+}
@@ -0,0 +1,130 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+        - name: web
+          image: registry.example.com/web:v1.2.3
+          ports:
+            - containerPort: 8080
+---
+apiVersion: example.com/v1
+kind: Widget
+spec:
+  anything: [1, 2]
+`
+)
+
+func TestValidateKubernetes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "valid resources and unknown kinds", content: TestDeployment},
+		{
+			name:    "tag written as a number",
+			content: strings.Replace(TestDeployment, "image: registry.example.com/web:v1.2.3", "image: 1.10", 1),
+			want:    []string{"document 0 (Deployment/web) spec.template.spec.containers[0].image line 17: expected string"},
+		},
+		{
+			name: "tag written as a mapping",
+			content: strings.Replace(TestDeployment, "image: registry.example.com/web:v1.2.3",
+				"image:\n            tag: v1.2.3", 1),
+			want: []string{"containers[0].image line 18: expected string, got object"},
+		},
+		{
+			name: "missing required field and bad enum",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n" +
+				"spec:\n  type: Internal\n  ports:\n    - name: http\n",
+			want: []string{
+				`spec.type line 6: value "Internal" is not one of`,
+				`spec.ports[0] line 8: missing required field "port"`,
+			},
+		},
+		{
+			name: "misspelled container field",
+			content: strings.Replace(TestDeployment, "image: registry.example.com/web:v1.2.3",
+				"image: registry.example.com/web:v1.2.3\n          imagePullPolcy: Always", 1),
+			want: []string{`spec.template.spec.containers[0].imagePullPolcy line 18: unknown field "imagePullPolcy"`},
+		},
+		{
+			name:    "misspelled resource field",
+			content: strings.Replace(TestDeployment, "metadata:\n  name: web", "metdata:\n  name: web", 1),
+			want:    []string{`document 0 (Deployment) metdata line 3: unknown field "metdata"`},
+		},
+		{
+			name: "fields without checked values",
+			content: strings.Replace(TestDeployment, "          ports:",
+				"          volumeMounts:\n            - name: data\n              mountPath: /data\n"+
+					"          livenessProbe:\n            httpGet: {path: /healthz, port: 8080}\n          ports:", 1) +
+				"---\napiVersion: v1\nkind: Service\nmetadata: {name: web}\n" +
+				"spec:\n  clusterIP: None\n  ports: [{port: 80, appProtocol: http}]\nstatus: {}\n",
+		},
+		{
+			name:    "not a resource",
+			content: "image:\n  tag: v1.2.3\n",
+			want:    []string{"document 0 line 1: not a Kubernetes resource"},
+		},
+		{
+			name:    "list items",
+			content: "apiVersion: v1\nkind: List\nitems:\n  - apiVersion: batch/v1\n    kind: Job\n    spec: {}\n",
+			want:    []string{`items[0].spec line 6: missing required field "template"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := ValidateKubernetes(tt.content)
+			if err != nil {
+				t.Fatalf("ValidateKubernetes() unexpected error: %v", err)
+			}
+			if len(violations) != len(tt.want) {
+				t.Fatalf("ValidateKubernetes() = %v, want %d violations", violations, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := violations[i].String(); !strings.Contains(got, want) {
+					t.Errorf("violation %d = %q, want it to contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(Kubernetes, TestDeployment); err != nil {
+		t.Errorf("Validate() unexpected error: %v", err)
+	}
+
+	err := Validate(Kubernetes, strings.Replace(TestDeployment, "replicas: 2", "replicas: two", 1))
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation || !strings.Contains(err.Error(), "spec.replicas") {
+		t.Errorf("Validate() error = %v, want a validation error naming spec.replicas", err)
+	}
+
+	if err := Validate("openapi", TestDeployment); err == nil {
+		t.Error("Validate() of an unknown schema succeeded, want an error")
+	}
+	if err := Validate(Kubernetes, "a: [\n"); err == nil {
+		t.Error("Validate() of invalid YAML succeeded, want an error")
+	}
+}
@@ -0,0 +1,263 @@
+// Package schema runs a minimal structural check of updated manifests against the
+// schemas of their format, catching mistakes like misspelled fields or mistyped
+// values before they are committed.
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Type is the type of a schema value
+type Type int
+
+// Value types, following the OpenAPI types of the Kubernetes schemas
+const (
+	// TypeAny accepts any value
+	TypeAny Type = iota
+	TypeObject
+	TypeArray
+	TypeString
+	TypeInteger
+	TypeBoolean
+	// TypeIntOrString accepts an integer or a string, like container port references
+	TypeIntOrString
+	// TypeQuantity accepts a number or a string, like resource limits
+	TypeQuantity
+)
+
+// YAML tags of scalar values
+const (
+	tagString = "!!str"
+	tagInt    = "!!int"
+	tagFloat  = "!!float"
+	tagBool   = "!!bool"
+	tagNull   = "!!null"
+)
+
+// String returns the name of the type used in violations
+func (t Type) String() string {
+	switch t {
+	case TypeObject:
+		return "object"
+	case TypeArray:
+		return "array"
+	case TypeString:
+		return "string"
+	case TypeInteger:
+		return "integer"
+	case TypeBoolean:
+		return "boolean"
+	case TypeIntOrString:
+		return "integer or string"
+	case TypeQuantity:
+		return "quantity"
+	default:
+		return "any"
+	}
+}
+
+// Schema describes the values allowed at one place of a document. An object with
+// Properties only accepts the listed fields, so a misspelled field is reported;
+// fields whose values are not checked are listed with TypeAny.
+type Schema struct {
+	Type Type
+	// Properties are the known fields of an object
+	Properties map[string]*Schema
+	// Required lists the fields an object must have
+	Required []string
+	// Values is the schema of every value of an object used as a map, like labels
+	Values *Schema
+	// Items is the schema of the entries of an array
+	Items *Schema
+	// Enum lists the allowed values of a string
+	Enum []string
+}
+
+// Violation is one place of a document that does not match its schema
+type Violation struct {
+	// Document is the index of the YAML document, starting at 0
+	Document int
+	// Resource names the resource of the document, such as Deployment/web
+	Resource string
+	// Path is the dotted path of the value, such as spec.replicas
+	Path    string
+	Line    int
+	Message string
+}
+
+// String formats the violation for logs and errors
+func (v Violation) String() string {
+	location := fmt.Sprintf("document %d", v.Document)
+	if v.Resource != "" {
+		location += " (" + v.Resource + ")"
+	}
+	if v.Path != "" {
+		location += " " + v.Path
+	}
+	if v.Line > 0 {
+		location += fmt.Sprintf(" line %d", v.Line)
+	}
+	return location + ": " + v.Message
+}
+
+// validator collects the violations of one document
+type validator struct {
+	document   int
+	resource   string
+	violations []Violation
+}
+
+// validate checks a node against a schema
+func (v *validator) validate(node *yaml.Node, s *Schema, path string) {
+	if s == nil || node == nil || s.Type == TypeAny {
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	// A null value is the same as an omitted optional field
+	if node.Kind == yaml.ScalarNode && node.Tag == tagNull {
+		return
+	}
+
+	switch s.Type {
+	case TypeObject:
+		v.validateObject(node, s, path)
+	case TypeArray:
+		if node.Kind != yaml.SequenceNode {
+			v.mismatch(node, s.Type, path)
+			return
+		}
+		for i, item := range node.Content {
+			v.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	default:
+		v.validateScalar(node, s, path)
+	}
+}
+
+// validateObject checks the required and known fields of an object
+func (v *validator) validateObject(node *yaml.Node, s *Schema, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.mismatch(node, s.Type, path)
+		return
+	}
+
+	present := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		present[key] = true
+		fieldPath := joinPath(path, key)
+		if field, ok := s.Properties[key]; ok {
+			v.validate(value, field, fieldPath)
+		} else if s.Values != nil {
+			v.validate(value, s.Values, fieldPath)
+		} else if len(s.Properties) > 0 {
+			v.add(node.Content[i], fieldPath, fmt.Sprintf("unknown field %q", key))
+		}
+	}
+	for _, field := range s.Required {
+		if !present[field] {
+			v.add(node, path, fmt.Sprintf("missing required field %q", field))
+		}
+	}
+}
+
+// validateScalar checks the type and allowed values of a scalar
+func (v *validator) validateScalar(node *yaml.Node, s *Schema, path string) {
+	if node.Kind != yaml.ScalarNode {
+		v.mismatch(node, s.Type, path)
+		return
+	}
+
+	var ok bool
+	switch s.Type {
+	case TypeString:
+		ok = node.Tag == tagString
+	case TypeInteger:
+		ok = node.Tag == tagInt
+	case TypeBoolean:
+		ok = node.Tag == tagBool
+	case TypeIntOrString:
+		ok = node.Tag == tagInt || node.Tag == tagString
+	case TypeQuantity:
+		ok = node.Tag == tagInt || node.Tag == tagFloat || node.Tag == tagString
+	}
+	if !ok {
+		v.mismatch(node, s.Type, path)
+		return
+	}
+
+	if len(s.Enum) > 0 && !contains(s.Enum, node.Value) {
+		v.add(node, path, fmt.Sprintf("value %q is not one of %s", node.Value, strings.Join(s.Enum, ", ")))
+	}
+}
+
+// mismatch records a value of the wrong type
+func (v *validator) mismatch(node *yaml.Node, expected Type, path string) {
+	v.add(node, path, fmt.Sprintf("expected %s, got %s", expected, describeNode(node)))
+}
+
+// add records a violation at a node
+func (v *validator) add(node *yaml.Node, path, message string) {
+	v.violations = append(v.violations, Violation{
+		Document: v.document,
+		Resource: v.resource,
+		Path:     path,
+		Line:     node.Line,
+		Message:  message,
+	})
+}
+
+// describeNode names the type of a YAML value
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case tagString:
+		return fmt.Sprintf("string %q", node.Value)
+	case tagInt:
+		return "integer " + node.Value
+	case tagFloat:
+		return "number " + node.Value
+	case tagBool:
+		return "boolean " + node.Value
+	}
+	return node.Tag + " " + node.Value
+}
+
+// joinPath appends a field to a dotted path
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sortViolations orders violations by document and line
+func sortViolations(violations []Violation) {
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Document != violations[j].Document {
+			return violations[i].Document < violations[j].Document
+		}
+		return violations[i].Line < violations[j].Line
+	})
+}
@@ -0,0 +1,114 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Bump levels a policy can allow, from the smallest to the largest change
+const (
+	BumpPatch = "patch"
+	BumpMinor = "minor"
+	BumpMajor = "major"
+)
+
+// bumpLevels orders the bump levels; a pre-release change of the same release
+// counts as a patch bump
+var bumpLevels = map[string]int{BumpPatch: 3, BumpMinor: 2, BumpMajor: 1}
+
+// Policy restricts which tags may replace the current one
+type Policy struct {
+	// Allow is the largest permitted bump; any bump is permitted when empty
+	Allow string
+	// Prefix must start every new tag, such as "v"; the rest must be a version
+	Prefix string
+	// AllowDowngrade permits tags lower than the current one
+	AllowDowngrade bool
+}
+
+// NewPolicy validates the bump level and creates a policy
+func NewPolicy(allow, prefix string, allowDowngrade bool) (*Policy, error) {
+	allow = strings.ToLower(strings.TrimSpace(allow))
+	if _, ok := bumpLevels[allow]; allow != "" && !ok {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid bump level %q: use %s, %s or %s",
+			allow, BumpPatch, BumpMinor, BumpMajor))
+	}
+	return &Policy{Allow: allow, Prefix: prefix, AllowDowngrade: allowDowngrade}, nil
+}
+
+// requiresVersion reports whether new tags must be versions
+func (p *Policy) requiresVersion() bool {
+	return p.Allow != "" || p.Prefix != ""
+}
+
+// CheckTag validates the format of a new tag on its own
+func (p *Policy) CheckTag(tag string) error {
+	_, err := p.parseTag(tag)
+	return err
+}
+
+// parseTag checks the prefix of a new tag and parses it; it returns nil without an
+// error for a tag that is not a version when the policy does not require one
+func (p *Policy) parseTag(tag string) (*Version, error) {
+	if p.Prefix != "" && !strings.HasPrefix(tag, p.Prefix) {
+		return nil, errors.NewValidationError(fmt.Sprintf("tag %q must start with %q", tag, p.Prefix))
+	}
+
+	v, err := Parse(strings.TrimPrefix(tag, p.Prefix))
+	if err != nil {
+		if p.requiresVersion() {
+			return nil, errors.NewValidationError(fmt.Sprintf("tag %q is not a semantic version", tag))
+		}
+		return nil, nil
+	}
+	return v, nil
+}
+
+// Check validates that next may replace current. Tags that are not versions are
+// only checked when the policy requires versions, and a current value that is not
+// a version, such as "latest", may be replaced by any valid tag.
+func (p *Policy) Check(current, next string) error {
+	nextVersion, err := p.parseTag(next)
+	if err != nil || nextVersion == nil {
+		return err
+	}
+
+	currentVersion, err := Parse(strings.TrimPrefix(current, p.Prefix))
+	if current == "" || err != nil {
+		return nil
+	}
+
+	if nextVersion.Compare(currentVersion) < 0 {
+		if p.AllowDowngrade {
+			return nil
+		}
+		return errors.NewValidationError(fmt.Sprintf(
+			"tag %s is lower than the current tag %s: use --allow-downgrade to downgrade", next, current))
+	}
+
+	if p.Allow != "" {
+		if bump := Bump(currentVersion, nextVersion); bump != "" && bumpLevels[bump] < bumpLevels[p.Allow] {
+			return errors.NewValidationError(fmt.Sprintf(
+				"update from %s to %s is a %s bump; --allow=%s permits at most %s bumps",
+				current, next, bump, p.Allow, p.Allow))
+		}
+	}
+	return nil
+}
+
+// Bump returns the largest component that differs between two versions, or "" when
+// they are equal; versions of the same release differing in pre-release are a patch bump
+func Bump(from, to *Version) string {
+	switch {
+	case from.Major != to.Major:
+		return BumpMajor
+	case from.Minor != to.Minor:
+		return BumpMinor
+	case from.Patch != to.Patch || from.Prerelease != to.Prerelease:
+		return BumpPatch
+	default:
+		return ""
+	}
+}
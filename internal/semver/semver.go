@@ -0,0 +1,356 @@
+// Package semver parses semantic version tags and matches them against version
+// constraints such as ">=1.2.0, <2.0.0" or "^1.4", as used to pick image tags.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// versionPrefix is the optional prefix of version tags such as v1.2.3
+	versionPrefix = "v"
+	// prereleaseSeparator starts the pre-release part of a version
+	prereleaseSeparator = "-"
+	// buildSeparator starts the build metadata of a version, which is ignored
+	buildSeparator = "+"
+	// maxComponents is the number of numeric components of a version
+	maxComponents = 3
+	// alternativeSeparator separates alternative comparator groups of a constraint
+	alternativeSeparator = "||"
+)
+
+// Version is a parsed semantic version
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+
+	// Original is the tag the version was parsed from
+	Original string
+
+	// precision is the number of numeric components given, 1 to 3
+	precision int
+}
+
+// Parse parses a version tag like 1.2.3, v1.2.3-rc.1 or 1.2; missing minor and
+// patch components are zero. Build metadata after + is ignored.
+func Parse(tag string) (*Version, error) {
+	value := strings.TrimPrefix(strings.TrimPrefix(tag, versionPrefix), strings.ToUpper(versionPrefix))
+	value, _, _ = strings.Cut(value, buildSeparator)
+	value, prerelease, hasPrerelease := strings.Cut(value, prereleaseSeparator)
+	if hasPrerelease && prerelease == "" {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid version %q: empty pre-release", tag))
+	}
+
+	parts := strings.Split(value, ".")
+	if value == "" || len(parts) > maxComponents {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid version %q", tag))
+	}
+
+	var numbers [maxComponents]int
+	for i, part := range parts {
+		number, err := parseNumber(part)
+		if err != nil {
+			return nil, errors.NewValidationError(fmt.Sprintf("invalid version %q: %v", tag, err))
+		}
+		numbers[i] = number
+	}
+
+	return &Version{
+		Major:      numbers[0],
+		Minor:      numbers[1],
+		Patch:      numbers[2],
+		Prerelease: prerelease,
+		Original:   tag,
+		precision:  len(parts),
+	}, nil
+}
+
+// parseNumber parses a numeric version component without sign or leading zeros
+func parseNumber(part string) (int, error) {
+	if part == "" || strings.TrimLeft(part, "0123456789") != "" {
+		return 0, fmt.Errorf("component %q is not a number", part)
+	}
+	if len(part) > 1 && part[0] == '0' {
+		return 0, fmt.Errorf("component %q has a leading zero", part)
+	}
+	return strconv.Atoi(part)
+}
+
+// String returns the version as major.minor.patch[-prerelease]
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += prereleaseSeparator + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or higher than other.
+// A pre-release is lower than the release it precedes.
+func (v *Version) Compare(other *Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if c := compareInts(pair[0], pair[1]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	default:
+		return comparePrerelease(v.Prerelease, other.Prerelease)
+	}
+}
+
+// comparePrerelease compares dot-separated pre-release identifiers; numeric
+// identifiers compare numerically and are lower than alphanumeric ones
+func comparePrerelease(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNumber, aErr := strconv.Atoi(aParts[i])
+		bNumber, bErr := strconv.Atoi(bParts[i])
+
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = compareInts(aNumber, bNumber)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(aParts[i], bParts[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aParts), len(bParts))
+}
+
+// compareInts returns -1, 0 or 1 when a is lower than, equal to or higher than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// bump returns the lowest release above every version sharing v's first
+// components; component 1 is major, 2 minor and 3 patch
+func (v *Version) bump(component int) *Version {
+	switch component {
+	case 1:
+		return &Version{Major: v.Major + 1, precision: maxComponents}
+	case 2:
+		return &Version{Major: v.Major, Minor: v.Minor + 1, precision: maxComponents}
+	default:
+		return &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1, precision: maxComponents}
+	}
+}
+
+// bound is a single comparison a version must pass
+type bound struct {
+	op      string
+	version *Version
+}
+
+// matches reports whether v passes the comparison
+func (b bound) matches(v *Version) bool {
+	c := v.Compare(b.version)
+	switch b.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case "!=":
+		return c != 0
+	default:
+		return c == 0
+	}
+}
+
+// Constraint is a set of alternative comparator groups; a version satisfies the
+// constraint when it passes every comparison of at least one group
+type Constraint struct {
+	groups   [][]bound
+	original string
+}
+
+// constraintOperators lists the comparator operators, longest first for prefix matching
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+// ParseConstraint parses a constraint such as ">=1.2.0, <2.0.0", "~1.4" or
+// "^1.0 || ^2.0". Comparators of a group are separated by commas or spaces. An
+// empty constraint matches every release.
+func ParseConstraint(constraint string) (*Constraint, error) {
+	c := &Constraint{original: constraint}
+	if strings.TrimSpace(constraint) == "" {
+		c.groups = [][]bound{{}}
+		return c, nil
+	}
+
+	for _, alternative := range strings.Split(constraint, alternativeSeparator) {
+		group, err := parseGroup(alternative)
+		if err != nil {
+			return nil, errors.NewValidationError(fmt.Sprintf("invalid version constraint %q: %v", constraint, err))
+		}
+		c.groups = append(c.groups, group)
+	}
+	return c, nil
+}
+
+// parseGroup parses the comparators of one alternative
+func parseGroup(alternative string) ([]bound, error) {
+	fields := strings.FieldsFunc(alternative, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty alternative")
+	}
+
+	var group []bound
+	for i := 0; i < len(fields); i++ {
+		op, value := splitOperator(fields[i])
+		// Allow a space between operator and version, as in ">= 1.2"
+		if value == "" && op != "" && i+1 < len(fields) {
+			i++
+			value = fields[i]
+		}
+
+		bounds, err := comparatorBounds(op, value)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, bounds...)
+	}
+	return group, nil
+}
+
+// splitOperator splits a comparator into its operator and version
+func splitOperator(comparator string) (op, value string) {
+	for _, candidate := range constraintOperators {
+		if strings.HasPrefix(comparator, candidate) {
+			return candidate, comparator[len(candidate):]
+		}
+	}
+	return "", comparator
+}
+
+// comparatorBounds turns a comparator into the comparisons it stands for
+func comparatorBounds(op, value string) ([]bound, error) {
+	v, err := Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case ">", ">=", "<", "<=", "!=":
+		return []bound{{op: op, version: v}}, nil
+	case "~":
+		// ~1.2.3 and ~1.2 allow patch updates, ~1 allows minor updates
+		return rangeBounds(v, minInt(v.precision, 2)), nil
+	case "^":
+		// ^1.2.3 allows updates that keep the first non-zero component
+		switch {
+		case v.Major > 0 || v.precision == 1:
+			return rangeBounds(v, 1), nil
+		case v.Minor > 0 || v.precision == 2:
+			return rangeBounds(v, 2), nil
+		default:
+			return rangeBounds(v, maxComponents), nil
+		}
+	default:
+		if v.precision == maxComponents {
+			return []bound{{op: "=", version: v}}, nil
+		}
+		// A partial version such as 1.2 stands for every 1.2.x release
+		return rangeBounds(v, v.precision), nil
+	}
+}
+
+// rangeBounds returns the range from v up to the next change of the given component
+func rangeBounds(v *Version, component int) []bound {
+	return []bound{{op: ">=", version: v}, {op: "<", version: v.bump(component)}}
+}
+
+// minInt returns the smaller of two integers
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// String returns the constraint as given
+func (c *Constraint) String() string {
+	return c.original
+}
+
+// Check reports whether v satisfies the constraint. Pre-releases only satisfy a
+// group that names a pre-release itself, so "^1.2" never picks 1.3.0-rc.1.
+func (c *Constraint) Check(v *Version) bool {
+	for _, group := range c.groups {
+		if v.Prerelease != "" && !namesPrerelease(group) {
+			continue
+		}
+
+		matched := true
+		for _, b := range group {
+			if !b.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// namesPrerelease reports whether a comparator of the group has a pre-release version
+func namesPrerelease(group []bound) bool {
+	for _, b := range group {
+		if b.version.Prerelease != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the highest of tags satisfying the constraint; tags that are not
+// versions are skipped. It reports false when no tag satisfies the constraint.
+func Latest(tags []string, constraint *Constraint) (string, bool) {
+	var best *Version
+	for _, tag := range tags {
+		v, err := Parse(tag)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		if best == nil || v.Compare(best) > 0 {
+			best = v
+		}
+	}
+
+	if best == nil {
+		return "", false
+	}
+	return best.Original, true
+}
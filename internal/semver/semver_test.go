@@ -0,0 +1,154 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "1.2.3", want: "1.2.3"},
+		{tag: "v1.2.3", want: "1.2.3"},
+		{tag: "1.2", want: "1.2.0"},
+		{tag: "2", want: "2.0.0"},
+		{tag: "1.2.3-rc.1+build.5", want: "1.2.3-rc.1"},
+		{tag: "latest", wantErr: true},
+		{tag: "1.02.3", wantErr: true},
+		{tag: "1.2.3.4", wantErr: true},
+		{tag: "1.2.3-", wantErr: true},
+		{tag: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			v, err := Parse(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			}
+			if err == nil && v.String() != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.tag, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11",
+		"1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "2.0.0"}
+
+	for i := 0; i+1 < len(ordered); i++ {
+		lower, _ := Parse(ordered[i])
+		higher, _ := Parse(ordered[i+1])
+		if lower.Compare(higher) != -1 || higher.Compare(lower) != 1 || lower.Compare(lower) != 0 {
+			t.Errorf("Compare(%s, %s) does not order them", ordered[i], ordered[i+1])
+		}
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{constraint: "", match: []string{"0.0.1", "9.9.9"}, noMatch: []string{"1.0.0-rc.1"}},
+		{constraint: ">=1.2.0, <2.0.0", match: []string{"1.2.0", "1.9.9"}, noMatch: []string{"1.1.9", "2.0.0", "2.0.0-rc.1"}},
+		{constraint: ">= 1.2 < 2", match: []string{"1.5.0"}, noMatch: []string{"2.1.0"}},
+		{constraint: "~1.4.2", match: []string{"1.4.2", "1.4.9"}, noMatch: []string{"1.5.0", "1.4.1"}},
+		{constraint: "~1", match: []string{"1.9.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: "^1.4", match: []string{"1.4.0", "1.99.0"}, noMatch: []string{"2.0.0", "1.3.9"}},
+		{constraint: "^0.3.1", match: []string{"0.3.5"}, noMatch: []string{"0.4.0"}},
+		{constraint: "^0.0.3", match: []string{"0.0.3"}, noMatch: []string{"0.0.4"}},
+		{constraint: "1.2", match: []string{"1.2.7"}, noMatch: []string{"1.3.0"}},
+		{constraint: "1.2.3", match: []string{"v1.2.3"}, noMatch: []string{"1.2.4"}},
+		{constraint: "^1.0 || ^3.0", match: []string{"1.5.0", "3.1.0"}, noMatch: []string{"2.0.0"}},
+		{constraint: ">=2.0.0-rc.1", match: []string{"2.0.0-rc.2", "2.1.0"}, noMatch: []string{"2.0.0-beta.1"}},
+		{constraint: ">=1.0.0, !=1.3.0", match: []string{"1.2.0"}, noMatch: []string{"1.3.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint(%q) unexpected error: %v", tt.constraint, err)
+			}
+			for _, tag := range tt.match {
+				if v, _ := Parse(tag); !c.Check(v) {
+					t.Errorf("%q should match %s", tt.constraint, tag)
+				}
+			}
+			for _, tag := range tt.noMatch {
+				if v, _ := Parse(tag); c.Check(v) {
+					t.Errorf("%q should not match %s", tt.constraint, tag)
+				}
+			}
+		})
+	}
+
+	for _, invalid := range []string{">=", "^latest", "1.2 ||", ">=1.x"} {
+		if _, err := ParseConstraint(invalid); err == nil {
+			t.Errorf("ParseConstraint(%q) expected an error", invalid)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	constraint, err := ParseConstraint("^1.2")
+	if err != nil {
+		t.Fatalf("ParseConstraint() unexpected error: %v", err)
+	}
+
+	tags := []string{"latest", "v1.2.0", "v1.10.1", "v1.9.0", "v1.11.0-rc.1", "v2.0.0", "main-abc123"}
+	if got, ok := Latest(tags, constraint); !ok || got != "v1.10.1" {
+		t.Errorf("Latest() = %q, %v, want v1.10.1", got, ok)
+	}
+	if got, ok := Latest([]string{"latest"}, constraint); ok {
+		t.Errorf("Latest() = %q, want no match", got)
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name           string
+		allow          string
+		prefix         string
+		allowDowngrade bool
+		current        string
+		next           string
+		wantErr        bool
+	}{
+		{name: "any upgrade", current: "v1.2.3", next: "v3.0.0"},
+		{name: "downgrade", current: "v1.2.3", next: "v1.2.2", wantErr: true},
+		{name: "allowed downgrade", allowDowngrade: true, current: "v1.2.3", next: "v1.0.0"},
+		{name: "release after its pre-release", current: "1.3.0-rc.1", next: "1.3.0"},
+		{name: "pre-release of the current release", current: "1.3.0", next: "1.3.0-rc.1", wantErr: true},
+		{name: "patch within patch", allow: BumpPatch, current: "1.2.3", next: "1.2.9"},
+		{name: "minor within patch", allow: BumpPatch, current: "1.2.3", next: "1.3.0", wantErr: true},
+		{name: "minor within minor", allow: BumpMinor, current: "1.2.3", next: "1.3.0"},
+		{name: "major within minor", allow: BumpMinor, current: "1.2.3", next: "2.0.0", wantErr: true},
+		{name: "major within major", allow: BumpMajor, current: "1.2.3", next: "2.0.0"},
+		{name: "unversioned tags", current: "latest", next: "stable"},
+		{name: "unversioned tag with policy", allow: BumpMajor, current: "1.2.3", next: "stable", wantErr: true},
+		{name: "unversioned current tag", allow: BumpPatch, current: "latest", next: "2.0.0"},
+		{name: "prefix", prefix: "v", current: "v1.2.3", next: "v1.2.4"},
+		{name: "missing prefix", prefix: "v", current: "v1.2.3", next: "1.2.4", wantErr: true},
+		{name: "custom prefix", prefix: "release-", allow: BumpMinor, current: "release-1.2.3", next: "release-1.3.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPolicy(tt.allow, tt.prefix, tt.allowDowngrade)
+			if err != nil {
+				t.Fatalf("NewPolicy() unexpected error: %v", err)
+			}
+			if err := policy.Check(tt.current, tt.next); (err != nil) != tt.wantErr {
+				t.Errorf("Check(%q, %q) error = %v, wantErr %v", tt.current, tt.next, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewPolicy("huge", "", false); err == nil {
+		t.Error("NewPolicy() expected an error for an unknown bump level")
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+const (
+	// GitLabEventPath receives GitLab project webhooks: push, tag push and merge request events
+	GitLabEventPath = "/gitlab"
+	// GitLabTokenHeader carries the secret token configured on a GitLab webhook
+	GitLabTokenHeader = "X-Gitlab-Token"
+
+	// tagRefPrefix starts the ref of a pushed tag
+	tagRefPrefix = "refs/tags/"
+)
+
+// GitLabEventResponse reports what a GitLab event started, or why it was skipped
+type GitLabEventResponse struct {
+	Event string `json:"event"`
+	// SkippedReason is set when the event was caused by go-tag-updater itself
+	SkippedReason string `json:"skipped_reason,omitempty"`
+	RegistryResponse
+}
+
+// handleGitLabEvent receives a GitLab project webhook. Events caused by the tool's
+// own commits and merge requests are skipped so that its changes never trigger it
+// again; a tag push of anyone else starts the routes matching the project path.
+func (s *Server) handleGitLabEvent(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(GitLabTokenHeader)
+	if token == "" {
+		token = r.Header.Get(WebhookTokenHeader)
+	}
+	if !s.checkSecret(w, token) {
+		return
+	}
+
+	body, err := readBody(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	eventType := gitlab.HookEventType(r)
+	payload, err := gitlab.ParseWebhook(eventType, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid GitLab event: "+err.Error())
+		return
+	}
+
+	event, pushes := gitLabEvent(payload)
+	response := GitLabEventResponse{
+		Event:            string(eventType),
+		RegistryResponse: RegistryResponse{Pushes: []ImagePush{}, Jobs: []UpdateResponse{}},
+	}
+	eventLog := s.logger.WithField("event", string(eventType))
+
+	if reason := s.identity.OwnEventReason(event); reason != "" {
+		eventLog.WithFields(map[string]interface{}{
+			"reason": reason,
+			"author": event.AuthorUsername,
+		}).Info("Skipping event caused by go-tag-updater")
+		response.SkippedReason = reason
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if len(pushes) > 0 {
+		response.RegistryResponse = s.startRouteJobs(pushes)
+	}
+	eventLog.WithFields(map[string]interface{}{
+		"pushes": len(pushes),
+		"jobs":   len(response.Jobs),
+	}).Info("GitLab event received")
+
+	writeJSON(w, routeJobsStatus(response.RegistryResponse), response)
+}
+
+// gitLabEvent returns the attribution of a GitLab event and the tags it pushed;
+// event types other than push, tag push and merge request carry neither
+func gitLabEvent(payload interface{}) (*identity.Event, []ImagePush) {
+	switch event := payload.(type) {
+	case *gitlab.PushEvent:
+		own := &identity.Event{AuthorUsername: event.UserUsername}
+		for _, commit := range event.Commits {
+			own.CommitMessages = append(own.CommitMessages, commit.Message)
+		}
+		return own, nil
+	case *gitlab.TagEvent:
+		own := &identity.Event{AuthorUsername: event.UserUsername, CommitMessages: []string{event.Message}}
+		for _, commit := range event.Commits {
+			own.CommitMessages = append(own.CommitMessages, commit.Message)
+		}
+		// A deleted tag has no commit to write
+		tag, ok := strings.CutPrefix(event.Ref, tagRefPrefix)
+		if !ok || tag == "" || strings.Trim(event.After, "0") == "" {
+			return own, nil
+		}
+		return own, []ImagePush{{Repository: event.Project.PathWithNamespace, Tag: tag}}
+	case *gitlab.MergeEvent:
+		own := &identity.Event{
+			Description:    event.ObjectAttributes.Description,
+			CommitMessages: []string{event.ObjectAttributes.LastCommit.Message},
+		}
+		if event.User != nil {
+			own.AuthorUsername = event.User.Username
+		}
+		for _, label := range event.ObjectAttributes.Labels {
+			if label != nil {
+				own.Labels = append(own.Labels, label.Title)
+			}
+		}
+		return own, nil
+	}
+	return &identity.Event{}, nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+const (
+	TestBotUsername      = "tag-bot"
+	TestMergeRequestHook = `{"object_kind": "merge_request", "user": {"username": "alice"},
+		"object_attributes": {"description": "Bump", "labels": [{"title": "go-tag-updater"}]}}`
+)
+
+// sendGitLabEvent posts a GitLab webhook event with the secret token and decodes the response
+func sendGitLabEvent(t *testing.T, url, secret string, eventType gitlab.EventType, body string) (int, GitLabEventResponse) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url+GitLabEventPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("X-Gitlab-Event", string(eventType))
+	req.Header.Set(GitLabTokenHeader, secret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", GitLabEventPath, err)
+	}
+	defer resp.Body.Close()
+
+	var response GitLabEventResponse
+	if resp.StatusCode < http.StatusBadRequest {
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, response
+}
+
+// tagPushHook returns a tag push event of team/app by author with one commit
+func tagPushHook(author, commitMessage string) string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"object_kind":   "tag_push",
+		"ref":           "refs/tags/" + TestNewTag,
+		"after":         "82b3d5ae55f7080f1e6022629cdb57bfae7cccc7",
+		"user_username": author,
+		"project":       map[string]string{"path_with_namespace": "team/app"},
+		"commits":       []map[string]string{{"message": commitMessage}},
+	})
+	return string(body)
+}
+
+func TestServer_GitLabEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		eventType  gitlab.EventType
+		body       string
+		wantReason string
+		wantJobs   int
+	}{
+		{name: "tag push starts the matching route", eventType: gitlab.EventTypeTagPush,
+			body: tagPushHook("alice", "Release v1.2.3"), wantJobs: 1},
+		{name: "tag push by the bot", eventType: gitlab.EventTypeTagPush,
+			body: tagPushHook(TestBotUsername, "Release v1.2.3"), wantReason: identity.ReasonAuthor},
+		{name: "tag of a commit with the run trailer", eventType: gitlab.EventTypeTagPush,
+			body:       tagPushHook("alice", identity.WithCommitTrailer("Update tag", "run-1")),
+			wantReason: identity.ReasonCommitTrailer},
+		{name: "merge request with the tool's label", eventType: gitlab.EventTypeMergeRequest,
+			body: TestMergeRequestHook, wantReason: identity.ReasonLabel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, httpServer, runs := newTestServer(t, nil,
+				Route{Repository: "team/app", ProjectID: "group/deploy", File: TestFilePath})
+			srv.identity = identity.New(TestBotUsername)
+
+			status, response := sendGitLabEvent(t, httpServer.URL, TestSecret, tt.eventType, tt.body)
+			if status >= http.StatusBadRequest {
+				t.Fatalf("POST %s = %d, want success", GitLabEventPath, status)
+			}
+			srv.Wait()
+
+			if response.SkippedReason != tt.wantReason {
+				t.Errorf("skipped_reason = %q, want %q", response.SkippedReason, tt.wantReason)
+			}
+			if len(response.Jobs) != tt.wantJobs || len(*runs) != tt.wantJobs {
+				t.Fatalf("jobs = %+v, runs = %d, want %d", response.Jobs, len(*runs), tt.wantJobs)
+			}
+			if tt.wantJobs > 0 && ((*runs)[0].ProjectID != "group/deploy" || (*runs)[0].NewTag != TestNewTag) {
+				t.Errorf("run = %+v, want %s of group/deploy", (*runs)[0], TestNewTag)
+			}
+		})
+	}
+
+	_, httpServer, _ := newTestServer(t, nil)
+	if status, _ := sendGitLabEvent(t, httpServer.URL, "wrong", gitlab.EventTypeTagPush,
+		tagPushHook("alice", "Release")); status != http.StatusUnauthorized {
+		t.Errorf("POST %s with a wrong secret = %d, want 401", GitLabEventPath, status)
+	}
+}
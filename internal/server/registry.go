@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// RegistryDockerHub selects the Docker Hub webhook payload format
+	RegistryDockerHub = "dockerhub"
+	// RegistryHarbor selects the Harbor webhook payload format
+	RegistryHarbor = "harbor"
+	// RegistryGitLab selects the container registry notification format used by GitLab
+	RegistryGitLab = "gitlab"
+
+	// harborPushEvent is the Harbor event type of a pushed artifact
+	harborPushEvent = "PUSH_ARTIFACT"
+	// registryPushAction is the notification action of a pushed manifest
+	registryPushAction = "push"
+)
+
+// ImagePush is a tag pushed to an image repository
+type ImagePush struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+}
+
+// RegistryResponse lists the jobs started for the pushes of a registry event
+type RegistryResponse struct {
+	Pushes []ImagePush       `json:"pushes"`
+	Jobs   []UpdateResponse  `json:"jobs"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// dockerHubPayload is the webhook payload Docker Hub sends on a push
+type dockerHubPayload struct {
+	PushData struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		RepoName string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+// harborPayload is the webhook payload Harbor sends on artifact events
+type harborPayload struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Tag string `json:"tag"`
+		} `json:"resources"`
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+// registryNotification is the notification envelope of the Docker distribution
+// registry, which the GitLab container registry sends
+type registryNotification struct {
+	Events []struct {
+		Action string `json:"action"`
+		Target struct {
+			Repository string `json:"repository"`
+			Tag        string `json:"tag"`
+		} `json:"target"`
+	} `json:"events"`
+}
+
+// ParseRegistryPayload extracts the pushed tags from a registry webhook payload;
+// events other than tag pushes are ignored
+func ParseRegistryPayload(source string, body []byte) ([]ImagePush, error) {
+	var pushes []ImagePush
+
+	switch source {
+	case RegistryDockerHub:
+		var payload dockerHubPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.NewValidationError("invalid Docker Hub payload: " + err.Error())
+		}
+		pushes = append(pushes, ImagePush{Repository: payload.Repository.RepoName, Tag: payload.PushData.Tag})
+	case RegistryHarbor:
+		var payload harborPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.NewValidationError("invalid Harbor payload: " + err.Error())
+		}
+		if payload.Type != harborPushEvent {
+			return nil, nil
+		}
+		for _, resource := range payload.EventData.Resources {
+			pushes = append(pushes, ImagePush{Repository: payload.EventData.Repository.RepoFullName, Tag: resource.Tag})
+		}
+	case RegistryGitLab:
+		var payload registryNotification
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, errors.NewValidationError("invalid registry notification: " + err.Error())
+		}
+		for _, event := range payload.Events {
+			if event.Action == registryPushAction {
+				pushes = append(pushes, ImagePush{Repository: event.Target.Repository, Tag: event.Target.Tag})
+			}
+		}
+	default:
+		return nil, errors.NewValidationError(fmt.Sprintf("unsupported registry %q: use %s, %s or %s",
+			source, RegistryDockerHub, RegistryHarbor, RegistryGitLab))
+	}
+
+	// Pushes by digest only carry no tag to write
+	tagged := pushes[:0]
+	for _, push := range pushes {
+		if push.Repository != "" && push.Tag != "" {
+			tagged = append(tagged, push)
+		}
+	}
+	return tagged, nil
+}
+
+// handleRegistry starts an update job for every route matching the pushed tags
+func (s *Server) handleRegistry(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, true) {
+		return
+	}
+
+	body, err := readBody(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pushes, err := ParseRegistryPayload(r.PathValue("source"), body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := s.startRouteJobs(pushes)
+	s.logger.WithFields(map[string]interface{}{
+		"registry": r.PathValue("source"),
+		"pushes":   len(pushes),
+		"jobs":     len(response.Jobs),
+	}).Info("Registry event received")
+
+	writeJSON(w, routeJobsStatus(response), response)
+}
+
+// startRouteJobs starts an update job for every route matching each pushed tag and
+// records why a job could not be started by push and project
+func (s *Server) startRouteJobs(pushes []ImagePush) RegistryResponse {
+	response := RegistryResponse{Pushes: pushes, Jobs: []UpdateResponse{}}
+	for _, push := range pushes {
+		for i := range s.opts.Routes {
+			route := &s.opts.Routes[i]
+			if !route.Matches(push.Repository, push.Tag) {
+				continue
+			}
+
+			cfg, err := s.jobConfig(route.updateRequest(push.Tag))
+			var job UpdateResponse
+			if err == nil {
+				job, err = s.startJob(cfg)
+			}
+			if err != nil {
+				if response.Errors == nil {
+					response.Errors = make(map[string]string)
+				}
+				response.Errors[fmt.Sprintf("%s:%s -> %s", push.Repository, push.Tag, route.ProjectID)] = err.Error()
+				continue
+			}
+			response.Jobs = append(response.Jobs, job)
+		}
+	}
+	return response
+}
+
+// routeJobsStatus is the response status of an event that started response.Jobs
+func routeJobsStatus(response RegistryResponse) int {
+	if len(response.Jobs) > 0 {
+		return http.StatusAccepted
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	TestDockerHubPayload = `{"push_data": {"tag": "v1.2.3"}, "repository": {"repo_name": "team/app"}}`
+	TestHarborPayload    = `{"type": "PUSH_ARTIFACT", "event_data": {"resources": [{"tag": "v1.2.3"}],
+		"repository": {"name": "app", "namespace": "team", "repo_full_name": "team/app"}}}`
+	TestGitLabPayload = `{"events": [
+		{"action": "push", "target": {"repository": "group/project/app", "tag": "v1.2.3"}},
+		{"action": "push", "target": {"repository": "group/project/app", "digest": "sha256:abc"}},
+		{"action": "pull", "target": {"repository": "group/project/app", "tag": "v1.0.0"}}]}`
+)
+
+func TestParseRegistryPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		body    string
+		want    []ImagePush
+		wantErr bool
+	}{
+		{name: "docker hub", source: RegistryDockerHub, body: TestDockerHubPayload,
+			want: []ImagePush{{Repository: "team/app", Tag: "v1.2.3"}}},
+		{name: "harbor", source: RegistryHarbor, body: TestHarborPayload,
+			want: []ImagePush{{Repository: "team/app", Tag: "v1.2.3"}}},
+		{name: "harbor delete ignored", source: RegistryHarbor, body: `{"type": "DELETE_ARTIFACT"}`},
+		{name: "gitlab registry", source: RegistryGitLab, body: TestGitLabPayload,
+			want: []ImagePush{{Repository: "group/project/app", Tag: "v1.2.3"}}},
+		{name: "unknown registry", source: "quay", body: `{}`, wantErr: true},
+		{name: "malformed payload", source: RegistryDockerHub, body: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRegistryPayload(tt.source, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRegistryPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ParseRegistryPayload() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRoutes(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "routes.yaml")
+	content := "routes:\n  - repository: team/*\n    project_id: group/deploy\n    file: values.yaml\n" +
+		"    yaml_path: image.tag\n    tags: '^v\\d+'\n"
+	if err := os.WriteFile(valid, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+
+	routes, err := LoadRoutes(valid)
+	if err != nil {
+		t.Fatalf("LoadRoutes() unexpected error: %v", err)
+	}
+	if len(routes) != 1 || !routes[0].Matches("team/app", "v1.2.3") || routes[0].Matches("team/app", "latest") ||
+		routes[0].Matches("other/app", "v1.2.3") {
+		t.Errorf("LoadRoutes() = %+v, want a route for team/* v-tags", routes)
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("routes:\n  - repository: team/app\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write routes: %v", err)
+	}
+	if _, err := LoadRoutes(invalid); err == nil {
+		t.Error("LoadRoutes() expected an error for a route without project and file")
+	}
+}
+
+func TestServer_Registry(t *testing.T) {
+	routes := []Route{
+		{Repository: "team/app", ProjectID: "group/deploy", File: "values.yaml", YAMLPath: "image.tag"},
+		{Repository: "team/*", ProjectID: "group/staging", File: "staging.yaml", Tags: `^v\d+`},
+		{Repository: "other/app", ProjectID: "group/other", File: "values.yaml"},
+	}
+	srv, httpServer, runs := newTestServer(t, nil, routes...)
+
+	var response RegistryResponse
+	url := httpServer.URL + RegistryPath + RegistryDockerHub + "?" + WebhookTokenParam + "=" + TestSecret
+	status := do(t, http.MethodPost, url, "", rawJSON(TestDockerHubPayload), &response)
+	if status != http.StatusAccepted || len(response.Jobs) != 2 {
+		t.Fatalf("POST registry = %d %+v, want 202 with two jobs", status, response)
+	}
+
+	srv.Wait()
+	projects := map[string]string{}
+	for _, run := range *runs {
+		projects[run.ProjectID] = run.FilePath
+		if run.NewTag != "v1.2.3" {
+			t.Errorf("run tag = %q, want v1.2.3", run.NewTag)
+		}
+	}
+	if !reflect.DeepEqual(projects, map[string]string{"group/deploy": "values.yaml", "group/staging": "staging.yaml"}) {
+		t.Errorf("runs = %+v, want the two matching routes", *runs)
+	}
+
+	status = do(t, http.MethodPost, httpServer.URL+RegistryPath+RegistryDockerHub, "", rawJSON(TestDockerHubPayload), nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("POST registry without secret = %d, want 401", status)
+	}
+}
+
+// rawJSON is a request body that is sent as is
+type rawJSON string
+
+// MarshalJSON returns the raw JSON
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+func TestServer_TagTransforms(t *testing.T) {
+	const wantTag = "v1.2.3-alpine"
+	srv, httpServer, runs := newTestServer(t, nil,
+		Route{Repository: "team/app", ProjectID: "group/deploy", File: TestFilePath})
+	srv.opts.Base.TagTransforms = []string{"suffix=-alpine"}
+
+	status := do(t, http.MethodPost, httpServer.URL+UpdatePath, TestSecret,
+		UpdateRequest{ProjectID: TestProjectID, File: TestFilePath, NewTag: TestNewTag}, nil)
+	if status != http.StatusAccepted {
+		t.Fatalf("POST %s = %d, want 202", UpdatePath, status)
+	}
+	url := httpServer.URL + RegistryPath + RegistryDockerHub + "?" + WebhookTokenParam + "=" + TestSecret
+	if status := do(t, http.MethodPost, url, "", rawJSON(TestDockerHubPayload), nil); status != http.StatusAccepted {
+		t.Fatalf("POST registry = %d, want 202", status)
+	}
+
+	srv.Wait()
+	if len(*runs) != 2 {
+		t.Fatalf("runs = %d, want 2", len(*runs))
+	}
+	for _, run := range *runs {
+		if run.NewTag != wantTag {
+			t.Errorf("run tag of %s = %q, want %q", run.ProjectID, run.NewTag, wantTag)
+		}
+	}
+	if srv.opts.Base.NewTag != "" {
+		t.Errorf("base tag = %q, want the jobs to leave it unset", srv.opts.Base.NewTag)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Route maps the pushes of a container image repository to a tag update
+type Route struct {
+	// Repository is the image repository as reported by the registry, such as
+	// "team/app"; path.Match patterns like "team/*" are allowed
+	Repository   string `yaml:"repository"`
+	ProjectID    string `yaml:"project_id"`
+	File         string `yaml:"file"`
+	YAMLPath     string `yaml:"yaml_path"`
+	TargetBranch string `yaml:"target_branch"`
+	TokenRef     string `yaml:"token_ref"`
+	// Tags is a regular expression pushed tags must match; every tag matches when empty
+	Tags string `yaml:"tags"`
+
+	tags *regexp.Regexp
+}
+
+// routesFile is the layout of a routing configuration file
+type routesFile struct {
+	Routes []Route `yaml:"routes"`
+}
+
+// LoadRoutes reads and validates a routing configuration file
+func LoadRoutes(filePath string) ([]Route, error) {
+	data, err := os.ReadFile(filePath) // #nosec G304 -- the routing file is chosen by the operator
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewFileNotFoundError(filePath)
+		}
+		return nil, errors.NewConfigErrorWithCause("failed to read routes file "+filePath, err)
+	}
+
+	var file routesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.NewConfigErrorWithCause("failed to parse routes file "+filePath, err)
+	}
+
+	for i := range file.Routes {
+		if err := file.Routes[i].compile(); err != nil {
+			return nil, errors.NewConfigErrorWithCause(fmt.Sprintf("invalid route %d in %s", i+1, filePath), err)
+		}
+	}
+	return file.Routes, nil
+}
+
+// compile validates the route and prepares its tag filter
+func (r *Route) compile() error {
+	if r.Repository == "" || r.ProjectID == "" || r.File == "" {
+		return errors.NewValidationError("repository, project_id and file are required")
+	}
+	if _, err := path.Match(r.Repository, ""); err != nil {
+		return fmt.Errorf("invalid repository pattern %q: %w", r.Repository, err)
+	}
+	if r.Tags != "" {
+		tags, err := regexp.Compile(r.Tags)
+		if err != nil {
+			return fmt.Errorf("invalid tags pattern %q: %w", r.Tags, err)
+		}
+		r.tags = tags
+	}
+	return nil
+}
+
+// Matches reports whether a push of tag to repository triggers the route
+func (r *Route) Matches(repository, tag string) bool {
+	if matched, err := path.Match(r.Repository, repository); err != nil || !matched {
+		return false
+	}
+	return r.tags == nil || r.tags.MatchString(tag)
+}
+
+// updateRequest returns the update request the route makes for a pushed tag
+func (r *Route) updateRequest(tag string) *UpdateRequest {
+	return &UpdateRequest{
+		ProjectID:    r.ProjectID,
+		File:         r.File,
+		NewTag:       tag,
+		YAMLPath:     r.YAMLPath,
+		TargetBranch: r.TargetBranch,
+		TokenRef:     r.TokenRef,
+	}
+}
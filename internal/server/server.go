@@ -0,0 +1,548 @@
+// Package server runs tag updates triggered over HTTP, so that registries and CI
+// systems can bump tags through webhooks
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// UpdatePath accepts POST requests that start a tag update job
+	UpdatePath = "/update"
+	// JobsPath prefixes the status endpoint of every job; it requires the webhook secret too
+	JobsPath = "/jobs/"
+	// HealthPath reports that the server is up
+	HealthPath = "/healthz"
+	// RegistryPath prefixes the endpoints receiving container registry push events
+	RegistryPath = "/registry/"
+	// WebhookTokenHeader carries the shared webhook secret of every update request
+	WebhookTokenHeader = "X-Webhook-Token"
+	// WebhookTokenParam carries the webhook secret for registries that cannot send headers
+	WebhookTokenParam = "token"
+
+	// DefaultListenAddress is the address the server listens on when none is configured
+	DefaultListenAddress = ":8080"
+	// DefaultMaxConcurrentJobs bounds how many updates run at the same time
+	DefaultMaxConcurrentJobs = 2
+	// DefaultMaxQueuedJobs bounds how many accepted updates wait for a free job slot;
+	// further requests are rejected until the queue drains
+	DefaultMaxQueuedJobs = 100
+	// ShutdownGracePeriod is how long running jobs may finish once the server shuts
+	// down before their context is canceled
+	ShutdownGracePeriod = 30 * time.Second
+	// MaxRequestBodySize bounds the size of an update request
+	MaxRequestBodySize = 1 << 20
+	// MaxRetainedJobs bounds how many jobs are kept for status queries; the oldest
+	// finished jobs are forgotten first
+	MaxRetainedJobs = 1000
+	// ReadHeaderTimeout bounds how long a client may take to send request headers
+	ReadHeaderTimeout = 10 * time.Second
+)
+
+// JobStatus is the state of an update job
+type JobStatus string
+
+const (
+	// JobQueued waits for a free job slot
+	JobQueued JobStatus = "queued"
+	// JobRunning is executing the update workflow
+	JobRunning JobStatus = "running"
+	// JobSucceeded finished the update, or skipped it because the file already had the tag
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed finished with an error
+	JobFailed JobStatus = "failed"
+)
+
+// UpdateRequest is the body of a POST to UpdatePath
+type UpdateRequest struct {
+	ProjectID    string `json:"project_id"`
+	File         string `json:"file"`
+	NewTag       string `json:"new_tag"`
+	YAMLPath     string `json:"yaml_path,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+	// TokenRef names a configured token; the server's own token is used when empty
+	TokenRef string `json:"token_ref,omitempty"`
+}
+
+// UpdateResponse is returned when an update job was accepted
+type UpdateResponse struct {
+	JobID     string `json:"job_id"`
+	StatusURL string `json:"status_url"`
+}
+
+// Job is the state of an update job as reported by its status endpoint
+type Job struct {
+	ID              string     `json:"id"`
+	Status          JobStatus  `json:"status"`
+	ProjectID       string     `json:"project_id"`
+	File            string     `json:"file"`
+	NewTag          string     `json:"new_tag"`
+	Message         string     `json:"message,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	ExitCode        int        `json:"exit_code,omitempty"`
+	BranchName      string     `json:"branch,omitempty"`
+	MergeRequestURL string     `json:"merge_request_url,omitempty"`
+	Skipped         bool       `json:"skipped,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// Runner executes one tag update; workflow.RunUpdate is used when none is given
+type Runner func(ctx context.Context, cfg *config.CLIConfig, log *logger.Logger) (*workflow.SimpleUpdateResult, error)
+
+// Options configures a Server
+type Options struct {
+	// Base holds the settings shared by every job; requests only set the target of the update
+	Base *config.CLIConfig
+	// WebhookSecret must be sent in WebhookTokenHeader by every update request
+	WebhookSecret string
+	// Tokens maps token refs to the environment variables holding the GitLab tokens
+	Tokens map[string]string
+	// MaxConcurrentJobs bounds how many updates run at the same time
+	MaxConcurrentJobs int
+	// MaxQueuedJobs bounds how many accepted updates wait for a free job slot
+	MaxQueuedJobs int
+	// Routes map container image repositories to the files their tags are written to
+	Routes []Route
+	// BotUsernames are the GitLab accounts the tool acts as; GitLab events they
+	// caused are skipped
+	BotUsernames []string
+	// Runner executes the updates
+	Runner Runner
+}
+
+// Server accepts update requests and runs them as asynchronous jobs on a fixed
+// number of workers
+type Server struct {
+	opts     Options
+	logger   *logger.Logger
+	identity *identity.Identity
+	queue    chan queuedJob
+	wg       sync.WaitGroup
+
+	// ctx is the context of every job, canceled when Shutdown gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	closing bool
+	jobs    map[string]*Job
+	order   []string
+}
+
+// queuedJob is an accepted job waiting for a worker
+type queuedJob struct {
+	id  string
+	cfg *config.CLIConfig
+}
+
+// errShuttingDown rejects jobs once the server shuts down
+var errShuttingDown = errors.NewNetworkError("server is shutting down")
+
+// New creates a server; a webhook secret is required so that only trusted callers
+// can open merge requests
+func New(opts Options, log *logger.Logger) (*Server, error) {
+	if opts.Base == nil || log == nil {
+		return nil, errors.NewValidationError("base config and logger are required")
+	}
+	if opts.WebhookSecret == "" {
+		return nil, errors.NewValidationError("webhook secret is required")
+	}
+	if opts.MaxConcurrentJobs <= 0 {
+		opts.MaxConcurrentJobs = DefaultMaxConcurrentJobs
+	}
+	if opts.MaxQueuedJobs <= 0 {
+		opts.MaxQueuedJobs = DefaultMaxQueuedJobs
+	}
+	if opts.Runner == nil {
+		opts.Runner = workflow.RunUpdate
+	}
+
+	opts.Routes = append([]Route(nil), opts.Routes...)
+	for i := range opts.Routes {
+		if err := opts.Routes[i].compile(); err != nil {
+			return nil, fmt.Errorf("invalid route %d: %w", i+1, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		opts:     opts,
+		logger:   log,
+		identity: identity.New(opts.BotUsernames...),
+		queue:    make(chan queuedJob, opts.MaxQueuedJobs),
+		ctx:      ctx,
+		cancel:   cancel,
+		jobs:     make(map[string]*Job),
+	}
+	for i := 0; i < opts.MaxConcurrentJobs; i++ {
+		go s.work()
+	}
+	return s, nil
+}
+
+// Handler returns the HTTP handler serving the update, event, job status and health endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+UpdatePath, s.handleUpdate)
+	mux.HandleFunc("POST "+RegistryPath+"{source}", s.handleRegistry)
+	mux.HandleFunc("POST "+GitLabEventPath, s.handleGitLabEvent)
+	mux.HandleFunc("GET "+JobsPath+"{id}", s.handleJob)
+	mux.HandleFunc("GET "+HealthPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// ListenAndServe serves on addr until ctx is canceled, then stops accepting requests
+// and shuts down the jobs, giving the running ones ShutdownGracePeriod to finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = DefaultListenAddress
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: ReadHeaderTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	s.logger.WithField("listen", addr).Info("Serving tag update webhooks")
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Shutting down; waiting for running jobs")
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), ReadHeaderTimeout)
+	defer cancelHTTP()
+	err := httpServer.Shutdown(httpCtx)
+
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), ShutdownGracePeriod)
+	defer cancelJobs()
+	s.Shutdown(jobsCtx)
+	return err
+}
+
+// Shutdown stops accepting jobs and fails the queued ones. Running jobs may finish
+// until ctx is done; then their context is canceled and Shutdown waits for them to
+// return.
+func (s *Server) Shutdown(ctx context.Context) {
+	s.mu.Lock()
+	if !s.closing {
+		s.closing = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn("Running jobs outlasted the shutdown grace period; canceling them")
+	}
+	s.cancel()
+	<-done
+}
+
+// Wait blocks until every accepted job has finished
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Job returns a copy of the state of a job
+func (s *Server) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// handleUpdate validates an update request and starts its job
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, false) {
+		return
+	}
+
+	var req UpdateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	cfg, err := s.jobConfig(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := s.startJob(cfg)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// startJob records a job for cfg and queues it for a worker. It fails when the
+// queue is full or the server shuts down.
+func (s *Server) startJob(cfg *config.CLIConfig) (UpdateResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing {
+		return UpdateResponse{}, errShuttingDown
+	}
+	s.wg.Add(1)
+	select {
+	case s.queue <- queuedJob{id: cfg.RunID, cfg: cfg}:
+	default:
+		s.wg.Done()
+		return UpdateResponse{}, errors.NewNetworkError(
+			fmt.Sprintf("job queue is full: %d updates are waiting, retry later", cap(s.queue)))
+	}
+	job := s.addJob(cfg)
+
+	return UpdateResponse{JobID: job.ID, StatusURL: JobsPath + job.ID}, nil
+}
+
+// handleJob reports the state of a job
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r, false) {
+		return
+	}
+
+	job, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// authorize checks the webhook secret of a request and rejects it when it does not match;
+// allowParam also accepts the secret as a query parameter
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, allowParam bool) bool {
+	token := r.Header.Get(WebhookTokenHeader)
+	if token == "" && allowParam {
+		token = r.URL.Query().Get(WebhookTokenParam)
+	}
+	return s.checkSecret(w, token)
+}
+
+// checkSecret rejects a request whose token does not match the webhook secret
+func (s *Server) checkSecret(w http.ResponseWriter, token string) bool {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.WebhookSecret)) != 1 {
+		writeError(w, http.StatusUnauthorized, "missing or invalid "+WebhookTokenHeader)
+		return false
+	}
+	return true
+}
+
+// jobConfig derives the configuration of a job from the base configuration
+func (s *Server) jobConfig(req *UpdateRequest) (*config.CLIConfig, error) {
+	if req.ProjectID == "" || req.File == "" || req.NewTag == "" {
+		return nil, errors.NewValidationError("project_id, file and new_tag are required")
+	}
+
+	token, err := s.resolveToken(req.TokenRef)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *s.opts.Base
+	cfg.ProjectID = req.ProjectID
+	cfg.FilePath = req.File
+	cfg.YAMLPath = req.YAMLPath
+	cfg.GitLabToken = token
+	cfg.BranchName = ""
+	cfg.RunID = journal.NewRunID()
+	if req.TargetBranch != "" {
+		cfg.TargetBranch = req.TargetBranch
+	}
+	if err := cfg.SetNewTag(req.NewTag); err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid new_tag %q: %v", req.NewTag, err))
+	}
+	return &cfg, nil
+}
+
+// resolveToken returns the GitLab token a request refers to; tokens are never
+// accepted in the request itself
+func (s *Server) resolveToken(ref string) (string, error) {
+	if ref == "" {
+		if s.opts.Base.GitLabToken == "" {
+			return "", errors.NewValidationError("token_ref is required: the server has no default token")
+		}
+		return s.opts.Base.GitLabToken, nil
+	}
+
+	envName, ok := s.opts.Tokens[ref]
+	if !ok {
+		return "", errors.NewValidationError(fmt.Sprintf("unknown token_ref %q", ref))
+	}
+	token := os.Getenv(envName)
+	if token == "" {
+		return "", errors.NewValidationError(fmt.Sprintf("token_ref %q refers to an unset variable", ref))
+	}
+
+	logger.RegisterSecret(token)
+	return token, nil
+}
+
+// addJob records a queued job, forgetting the oldest finished jobs beyond the retention
+// limit; the caller holds the lock
+func (s *Server) addJob(cfg *config.CLIConfig) *Job {
+	job := &Job{
+		ID:        cfg.RunID,
+		Status:    JobQueued,
+		ProjectID: cfg.ProjectID,
+		File:      cfg.FilePath,
+		NewTag:    cfg.NewTag,
+		CreatedAt: time.Now().UTC(),
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+
+	for i := 0; len(s.jobs) > MaxRetainedJobs && i < len(s.order); {
+		old := s.jobs[s.order[i]]
+		if old.Status == JobSucceeded || old.Status == JobFailed {
+			delete(s.jobs, old.ID)
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+
+	return job
+}
+
+// work runs queued jobs one at a time until the queue is closed by Shutdown
+func (s *Server) work() {
+	for queued := range s.queue {
+		s.runJob(queued.id, queued.cfg)
+	}
+}
+
+// runJob executes the update of a job under the server context; jobs still queued
+// when the server shuts down fail without running
+func (s *Server) runJob(id string, cfg *config.CLIConfig) {
+	defer s.wg.Done()
+
+	s.mu.Lock()
+	closing := s.closing
+	s.mu.Unlock()
+	if closing {
+		s.updateJob(id, func(job *Job) {
+			finished := time.Now().UTC()
+			job.FinishedAt = &finished
+			job.Status = JobFailed
+			job.Error = errShuttingDown.Error()
+			job.ExitCode = errors.ExitCode(errShuttingDown)
+		})
+		return
+	}
+
+	s.updateJob(id, func(job *Job) {
+		started := time.Now().UTC()
+		job.Status = JobRunning
+		job.StartedAt = &started
+	})
+
+	// The job ID is the run ID the workflow logs, so job lines correlate with run lines
+	jobLog := s.logger.WithField("job_id", id)
+	jobLog.WithFields(map[string]interface{}{
+		"project_id": cfg.ProjectID,
+		"file_path":  cfg.FilePath,
+		"new_tag":    cfg.NewTag,
+	}).Info("Starting update job")
+
+	result, err := s.opts.Runner(s.ctx, cfg, s.logger)
+
+	s.updateJob(id, func(job *Job) {
+		finished := time.Now().UTC()
+		job.FinishedAt = &finished
+		job.Status = JobSucceeded
+		if result != nil {
+			job.Message = result.Message
+			job.BranchName = result.BranchName
+			job.Skipped = result.Skipped
+			if result.MergeRequest != nil {
+				job.MergeRequestURL = result.MergeRequest.WebURL
+			}
+		}
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			job.ExitCode = errors.ExitCode(err)
+		}
+	})
+
+	if err != nil {
+		jobLog.WithError(err).Error("Update job failed")
+		return
+	}
+	jobLog.Info("Update job finished")
+}
+
+// updateJob applies change to a job under the lock
+func (s *Server) updateJob(id string, change func(*Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		change(job)
+	}
+}
+
+// readBody reads a request body of bounded size
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	return body, nil
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,261 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	TestSecret       = "webhook-secret"
+	TestBaseToken    = "base-token"
+	TestTokenRef     = "team-a"
+	TestTokenEnv     = "GO_TAG_UPDATER_TEST_TEAM_A_TOKEN"
+	TestTeamToken    = "team-a-token"
+	TestProjectID    = "group/project"
+	TestFilePath     = "values.yaml"
+	TestNewTag       = "v1.2.3"
+	TestMergeRequest = "https://gitlab.example.com/group/project/-/merge_requests/1"
+)
+
+// newTestServer starts a server whose runner records the configuration of every job
+func newTestServer(t *testing.T, runErr error, routes ...Route) (*Server, *httptest.Server, *[]config.CLIConfig) {
+	t.Helper()
+
+	var runs []config.CLIConfig
+	runner := func(_ context.Context, cfg *config.CLIConfig, _ *logger.Logger) (*workflow.SimpleUpdateResult, error) {
+		runs = append(runs, *cfg)
+		result := &workflow.SimpleUpdateResult{RunID: cfg.RunID, Message: "done"}
+		if runErr == nil {
+			result.Success = true
+			result.MergeRequest = &gitlab.MergeRequest{}
+			result.MergeRequest.WebURL = TestMergeRequest
+		}
+		return result, runErr
+	}
+
+	srv, err := New(Options{
+		Base:              &config.CLIConfig{GitLabToken: TestBaseToken, TargetBranch: "main"},
+		WebhookSecret:     TestSecret,
+		Tokens:            map[string]string{TestTokenRef: TestTokenEnv},
+		MaxConcurrentJobs: 1,
+		Routes:            routes,
+		Runner:            runner,
+	}, logger.New(false))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	httpServer := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		httpServer.Close()
+		srv.Shutdown(context.Background())
+	})
+
+	// Tests wait for the jobs before reading runs, so it needs no lock
+	return srv, httpServer, &runs
+}
+
+// do sends a request with the webhook secret and decodes the JSON response into out
+func do(t *testing.T, method, url, secret string, body interface{}, out interface{}) int {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if secret != "" {
+		req.Header.Set(WebhookTokenHeader, secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_Update(t *testing.T) {
+	t.Setenv(TestTokenEnv, TestTeamToken)
+
+	tests := []struct {
+		name       string
+		tokenRef   string
+		runErr     error
+		wantToken  string
+		wantStatus JobStatus
+		wantExit   int
+	}{
+		{name: "default token", wantToken: TestBaseToken, wantStatus: JobSucceeded},
+		{name: "token ref", tokenRef: TestTokenRef, wantToken: TestTeamToken, wantStatus: JobSucceeded},
+		{name: "failed update", runErr: errors.NewMergeConflictError("drift"), wantToken: TestBaseToken,
+			wantStatus: JobFailed, wantExit: errors.ExitCodeConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, httpServer, runs := newTestServer(t, tt.runErr)
+
+			var accepted UpdateResponse
+			status := do(t, http.MethodPost, httpServer.URL+UpdatePath, TestSecret, UpdateRequest{
+				ProjectID: TestProjectID,
+				File:      TestFilePath,
+				NewTag:    TestNewTag,
+				TokenRef:  tt.tokenRef,
+			}, &accepted)
+			if status != http.StatusAccepted || accepted.JobID == "" || accepted.StatusURL != JobsPath+accepted.JobID {
+				t.Fatalf("POST %s = %d %+v, want 202 with a job", UpdatePath, status, accepted)
+			}
+
+			srv.Wait()
+			var job Job
+			status = do(t, http.MethodGet, httpServer.URL+accepted.StatusURL, TestSecret, nil, &job)
+			if status != http.StatusOK {
+				t.Fatalf("GET %s = %d, want 200", accepted.StatusURL, status)
+			}
+
+			if job.Status != tt.wantStatus || job.ExitCode != tt.wantExit || job.FinishedAt == nil {
+				t.Errorf("job = %+v, want status %s and exit code %d", job, tt.wantStatus, tt.wantExit)
+			}
+			if tt.runErr == nil && job.MergeRequestURL != TestMergeRequest {
+				t.Errorf("job merge request URL = %q, want %q", job.MergeRequestURL, TestMergeRequest)
+			}
+
+			if len(*runs) != 1 {
+				t.Fatalf("runs = %d, want 1", len(*runs))
+			}
+			run := (*runs)[0]
+			if run.GitLabToken != tt.wantToken || run.RunID != accepted.JobID || run.ProjectID != TestProjectID ||
+				run.FilePath != TestFilePath || run.NewTag != TestNewTag || run.TargetBranch != "main" {
+				t.Errorf("run config = %+v", run)
+			}
+		})
+	}
+}
+
+func TestServer_RejectsRequests(t *testing.T) {
+	_, httpServer, runs := newTestServer(t, nil)
+	valid := UpdateRequest{ProjectID: TestProjectID, File: TestFilePath, NewTag: TestNewTag}
+
+	tests := []struct {
+		name       string
+		secret     string
+		body       interface{}
+		wantStatus int
+	}{
+		{name: "missing secret", body: valid, wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", secret: "nope", body: valid, wantStatus: http.StatusUnauthorized},
+		{name: "missing tag", secret: TestSecret, body: UpdateRequest{ProjectID: TestProjectID, File: TestFilePath},
+			wantStatus: http.StatusBadRequest},
+		{name: "unknown token ref", secret: TestSecret,
+			body:       UpdateRequest{ProjectID: TestProjectID, File: TestFilePath, NewTag: TestNewTag, TokenRef: "other"},
+			wantStatus: http.StatusBadRequest},
+		{name: "token in body", secret: TestSecret, body: map[string]string{"project_id": TestProjectID,
+			"file": TestFilePath, "new_tag": TestNewTag, "token": "secret"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := do(t, http.MethodPost, httpServer.URL+UpdatePath, tt.secret, tt.body, nil)
+			if status != tt.wantStatus {
+				t.Errorf("POST %s = %d, want %d", UpdatePath, status, tt.wantStatus)
+			}
+		})
+	}
+
+	status := do(t, http.MethodGet, httpServer.URL+JobsPath+"missing", TestSecret, nil, nil)
+	if status != http.StatusNotFound {
+		t.Errorf("GET unknown job = %d, want 404", status)
+	}
+	if len(*runs) != 0 {
+		t.Errorf("runs = %d, want none", len(*runs))
+	}
+}
+
+func TestServer_QueueAndShutdown(t *testing.T) {
+	started := make(chan struct{}, 1)
+	runner := func(ctx context.Context, _ *config.CLIConfig, _ *logger.Logger) (*workflow.SimpleUpdateResult, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	srv, err := New(Options{
+		Base:              &config.CLIConfig{GitLabToken: TestBaseToken},
+		WebhookSecret:     TestSecret,
+		MaxConcurrentJobs: 1,
+		MaxQueuedJobs:     1,
+		Runner:            runner,
+	}, logger.New(false))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	httpServer := httptest.NewServer(srv.Handler())
+	defer httpServer.Close()
+
+	post := func() (int, UpdateResponse) {
+		var accepted UpdateResponse
+		status := do(t, http.MethodPost, httpServer.URL+UpdatePath, TestSecret,
+			UpdateRequest{ProjectID: TestProjectID, File: TestFilePath, NewTag: TestNewTag}, &accepted)
+		return status, accepted
+	}
+
+	status, running := post()
+	if status != http.StatusAccepted {
+		t.Fatalf("first POST = %d, want 202", status)
+	}
+	<-started
+	status, queued := post()
+	if status != http.StatusAccepted {
+		t.Fatalf("second POST = %d, want 202 while a queue slot is free", status)
+	}
+	if status, _ := post(); status != http.StatusServiceUnavailable {
+		t.Errorf("POST with a full queue = %d, want 503", status)
+	}
+
+	// The running job outlasts the grace period and is canceled; the queued one never runs
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	srv.Shutdown(ctx)
+
+	if job, _ := srv.Job(running.JobID); job.Status != JobFailed || !strings.Contains(job.Error, "canceled") {
+		t.Errorf("running job = %+v, want failed by the canceled context", job)
+	}
+	if job, _ := srv.Job(queued.JobID); job.Status != JobFailed || job.StartedAt != nil {
+		t.Errorf("queued job = %+v, want failed without starting", job)
+	}
+	if status, _ := post(); status != http.StatusServiceUnavailable {
+		t.Errorf("POST after shutdown = %d, want 503", status)
+	}
+}
+
+func TestNew_RequiresSecret(t *testing.T) {
+	if _, err := New(Options{Base: &config.CLIConfig{}}, logger.New(false)); err == nil {
+		t.Error("New() expected an error without a webhook secret")
+	}
+}
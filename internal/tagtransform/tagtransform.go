@@ -0,0 +1,126 @@
+// Package tagtransform rewrites the new tag before it is written, so registries
+// publishing 1.2.3 can feed repositories expecting v1.2.3 and the other way round.
+//
+// Transforms are given as specs such as strip-v, add-v, prefix=foo-, suffix=-alpine
+// or template={{.Major}}.{{.Minor}}, and apply in the order given.
+package tagtransform
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Gosayram/go-tag-updater/internal/semver"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// StripV removes a leading v from the tag
+	StripV = "strip-v"
+	// AddV adds a leading v to the tag unless it has one
+	AddV = "add-v"
+	// Prefix adds its value before the tag
+	Prefix = "prefix"
+	// Suffix adds its value after the tag
+	Suffix = "suffix"
+	// Template renders its value, a Go template, with the fields of TemplateData
+	Template = "template"
+
+	// versionPrefix is the prefix StripV and AddV remove and add
+	versionPrefix = "v"
+	// valueSeparator separates the name of a transform from its value
+	valueSeparator = "="
+)
+
+// TemplateData is the data a template transform is rendered with
+type TemplateData struct {
+	// Tag is the tag as transformed so far
+	Tag string
+	// Version is the tag without a leading v
+	Version string
+	// Major, Minor and Patch are the components of the tag when it is a version
+	Major int
+	Minor int
+	Patch int
+}
+
+// step transforms a tag
+type step func(tag string) (string, error)
+
+// Chain is a sequence of transforms applied in order
+type Chain []step
+
+// Parse parses transform specs into a chain; no specs make a chain returning the tag
+// unchanged
+func Parse(specs []string) (Chain, error) {
+	chain := make(Chain, 0, len(specs))
+	for _, spec := range specs {
+		s, err := parseStep(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, s)
+	}
+	return chain, nil
+}
+
+// parseStep parses one transform spec
+func parseStep(spec string) (step, error) {
+	name, value, hasValue := strings.Cut(spec, valueSeparator)
+	switch {
+	case name == StripV && !hasValue:
+		return func(tag string) (string, error) {
+			return strings.TrimPrefix(tag, versionPrefix), nil
+		}, nil
+	case name == AddV && !hasValue:
+		return func(tag string) (string, error) {
+			if strings.HasPrefix(tag, versionPrefix) {
+				return tag, nil
+			}
+			return versionPrefix + tag, nil
+		}, nil
+	case name == Prefix && value != "":
+		return func(tag string) (string, error) { return value + tag, nil }, nil
+	case name == Suffix && value != "":
+		return func(tag string) (string, error) { return tag + value, nil }, nil
+	case name == Template && value != "":
+		return parseTemplate(value)
+	}
+	return nil, errors.NewValidationError(fmt.Sprintf(
+		"invalid tag transform %q, expected %s, %s, %s=<text>, %s=<text> or %s=<go template>",
+		spec, StripV, AddV, Prefix, Suffix, Template))
+}
+
+// parseTemplate parses a template transform
+func parseTemplate(text string) (step, error) {
+	tmpl, err := template.New(Template).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid tag transform template %q: %v", text, err))
+	}
+	return func(tag string) (string, error) {
+		data := TemplateData{Tag: tag, Version: strings.TrimPrefix(tag, versionPrefix)}
+		if v, err := semver.Parse(tag); err == nil {
+			data.Major, data.Minor, data.Patch = v.Major, v.Minor, v.Patch
+		}
+
+		var out strings.Builder
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", errors.NewValidationError(fmt.Sprintf("tag transform template %q failed: %v", text, err))
+		}
+		return out.String(), nil
+	}, nil
+}
+
+// Apply runs the transforms of the chain on tag; the result must not be empty
+func (c Chain) Apply(tag string) (string, error) {
+	for _, s := range c {
+		var err error
+		if tag, err = s(tag); err != nil {
+			return "", err
+		}
+	}
+	if tag == "" {
+		return "", errors.NewValidationError("tag transforms produced an empty tag")
+	}
+	return tag, nil
+}
@@ -0,0 +1,64 @@
+package tagtransform
+
+import (
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestChain_Apply(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		tag   string
+		want  string
+	}{
+		{name: "no transforms", tag: "1.2.3", want: "1.2.3"},
+		{name: "strip-v", specs: []string{StripV}, tag: "v1.2.3", want: "1.2.3"},
+		{name: "strip-v without v", specs: []string{StripV}, tag: "1.2.3", want: "1.2.3"},
+		{name: "add-v", specs: []string{AddV}, tag: "1.2.3", want: "v1.2.3"},
+		{name: "add-v with v", specs: []string{AddV}, tag: "v1.2.3", want: "v1.2.3"},
+		{name: "prefix and suffix", specs: []string{"prefix=api-", "suffix=-alpine"}, tag: "1.2.3", want: "api-1.2.3-alpine"},
+		{name: "chained in order", specs: []string{StripV, "prefix=release-"}, tag: "v2.0.0", want: "release-2.0.0"},
+		{name: "template", specs: []string{"template={{.Major}}.{{.Minor}}"}, tag: "v1.4.2", want: "1.4"},
+		{name: "template of a non-version", specs: []string{"template=build-{{.Version}}"}, tag: "main", want: "build-main"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := Parse(tt.specs)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			got, err := chain.Apply(tt.tag)
+			if err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"upper", "prefix=", "strip-v=1", "template={{.Tag", "template={{.Missing}}"} {
+		chain, err := Parse([]string{spec})
+		if err == nil {
+			_, err = chain.Apply("1.2.3")
+		}
+		if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+			t.Errorf("spec %q: error = %v, want a validation error", spec, err)
+		}
+	}
+}
+
+func TestChain_ApplyEmptyResult(t *testing.T) {
+	chain, err := Parse([]string{StripV})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if _, err := chain.Apply("v"); err == nil {
+		t.Error("Apply() accepted a transform producing an empty tag")
+	}
+}
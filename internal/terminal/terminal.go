@@ -0,0 +1,41 @@
+// Package terminal decides whether go-tag-updater may behave interactively
+package terminal
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	// EnvCI is set to true by GitLab CI, GitHub Actions and most other CI runners
+	EnvCI = "CI"
+	// EnvNonInteractive forces non-interactive behavior outside of CI
+	EnvNonInteractive = "GO_TAG_UPDATER_NON_INTERACTIVE"
+)
+
+// NonInteractiveForced reports whether the environment disables prompts, spinners
+// and colors regardless of the attached output
+func NonInteractiveForced() bool {
+	return envEnabled(EnvNonInteractive) || envEnabled(EnvCI)
+}
+
+// IsInteractive reports whether prompts, spinners and colors may be used on f: the
+// file must be a terminal and non-interactive behavior must not be forced
+func IsInteractive(f *os.File) bool {
+	if f == nil || NonInteractiveForced() {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// envEnabled reports whether an environment variable holds a true boolean value
+func envEnabled(name string) bool {
+	enabled, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && enabled
+}
@@ -0,0 +1,51 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNonInteractiveForced(t *testing.T) {
+	tests := []struct {
+		name           string
+		ci             string
+		nonInteractive string
+		want           bool
+	}{
+		{name: "local shell", want: false},
+		{name: "CI runner", ci: "true", want: true},
+		{name: "CI disabled", ci: "false", want: false},
+		{name: "forced by tool variable", nonInteractive: "1", want: true},
+		{name: "invalid value", nonInteractive: "maybe", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvCI, tt.ci)
+			t.Setenv(EnvNonInteractive, tt.nonInteractive)
+
+			if got := NonInteractiveForced(); got != tt.want {
+				t.Errorf("NonInteractiveForced() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInteractive(t *testing.T) {
+	t.Setenv(EnvCI, "")
+	t.Setenv(EnvNonInteractive, "")
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "output.log"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	if IsInteractive(file) {
+		t.Error("IsInteractive() = true for a regular file")
+	}
+	if IsInteractive(nil) {
+		t.Error("IsInteractive() = true for nil")
+	}
+}
@@ -0,0 +1,164 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// AbortNoteFormat defines the note left on merge requests closed by the abort command
+	AbortNoteFormat = "Closed by go-tag-updater: run `%s` was aborted. The source branch will be deleted."
+)
+
+// AbortResult contains the results of an abort operation
+type AbortResult struct {
+	RunID                string
+	ClosedMergeRequests  []int
+	SkippedMergeRequests []int
+	DeletedBranches      []string
+	Message              string
+}
+
+// AbortRun undoes the remote side effects recorded for a run: open merge requests
+// are closed with an explanatory note and created branches are deleted. Merge
+// requests that were already merged or closed, and their branches, are left alone.
+func AbortRun(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	j *journal.Journal,
+	runID string,
+	log *logger.Logger,
+) (*AbortResult, error) {
+	if cfg == nil || j == nil || log == nil {
+		return nil, errors.NewValidationError("config, journal and logger are required")
+	}
+
+	entry, err := j.Load(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run %s: %w", runID, err)
+	}
+
+	result := &AbortResult{RunID: entry.RunID}
+	if entry.Status == journal.StatusAborted {
+		result.Message = fmt.Sprintf("Run %s was already aborted", entry.RunID)
+		return result, nil
+	}
+
+	baseURL := cfg.GitLabURL
+	if baseURL == "" {
+		baseURL = entry.GitLabURL
+	}
+
+	client, err := newGitLabClient(cfg, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	mrManager := gitlabapi.NewSimpleMergeRequestManager(client.GetGitLabClient(), entry.ProjectID)
+	branchMgr := gitlabapi.NewBranchManager(client.GetGitLabClient(), entry.ProjectID)
+	runLog := log.WithField("run_id", entry.RunID)
+
+	keepBranches, err := closeRunMergeRequests(ctx, mrManager, entry, result, cfg.DryRun, runLog)
+	if err != nil {
+		return result, err
+	}
+
+	if err := deleteRunBranches(ctx, branchMgr, entry, result, keepBranches, cfg.DryRun, runLog); err != nil {
+		return result, err
+	}
+
+	if cfg.DryRun {
+		result.Message = fmt.Sprintf("Dry run completed. Would close %d merge request(s) and delete %d branch(es)",
+			len(result.ClosedMergeRequests), len(result.DeletedBranches))
+		return result, nil
+	}
+
+	entry.Status = journal.StatusAborted
+	if err := j.Save(entry); err != nil {
+		return result, fmt.Errorf("failed to mark run %s as aborted: %w", entry.RunID, err)
+	}
+
+	result.Message = fmt.Sprintf("Run %s aborted. Closed %d merge request(s), deleted %d branch(es)",
+		entry.RunID, len(result.ClosedMergeRequests), len(result.DeletedBranches))
+	return result, nil
+}
+
+// closeRunMergeRequests closes the still open merge requests of a run and returns
+// the source branches of merge requests that must be kept
+func closeRunMergeRequests(
+	ctx context.Context,
+	mrManager *gitlabapi.SimpleMergeRequestManager,
+	entry *journal.Entry,
+	result *AbortResult,
+	dryRun bool,
+	runLog *logrus.Entry,
+) (map[string]bool, error) {
+	keepBranches := make(map[string]bool)
+	for _, mrIID := range entry.MergeRequestIIDs {
+		mr, err := mrManager.GetMergeRequest(ctx, mrIID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect merge request %d: %w", mrIID, err)
+		}
+
+		if mr.State != gitlabapi.StateOpened {
+			keepBranches[mr.SourceBranch] = true
+			result.SkippedMergeRequests = append(result.SkippedMergeRequests, mrIID)
+			runLog.WithFields(map[string]interface{}{
+				"mr_id": mrIID,
+				"state": mr.State,
+			}).Warn("Merge request is no longer open, leaving it untouched")
+			continue
+		}
+
+		if dryRun {
+			runLog.WithField("mr_id", mrIID).Info("Dry run mode: would close merge request")
+		} else if _, err := mrManager.CloseMergeRequest(ctx, mrIID, fmt.Sprintf(AbortNoteFormat, entry.RunID)); err != nil {
+			return nil, fmt.Errorf("failed to close merge request %d: %w", mrIID, err)
+		}
+		result.ClosedMergeRequests = append(result.ClosedMergeRequests, mrIID)
+	}
+
+	return keepBranches, nil
+}
+
+// deleteRunBranches deletes the branches created by a run unless they must be kept
+func deleteRunBranches(
+	ctx context.Context,
+	branchMgr *gitlabapi.BranchManager,
+	entry *journal.Entry,
+	result *AbortResult,
+	keepBranches map[string]bool,
+	dryRun bool,
+	runLog *logrus.Entry,
+) error {
+	for _, branchName := range entry.CreatedBranches {
+		if keepBranches[branchName] {
+			continue
+		}
+
+		exists, err := branchMgr.BranchExists(ctx, branchName)
+		if err != nil {
+			return fmt.Errorf("failed to check branch %s: %w", branchName, err)
+		}
+		if !exists {
+			continue
+		}
+
+		if dryRun {
+			runLog.WithField("branch_name", branchName).Info("Dry run mode: would delete branch")
+		} else if err := branchMgr.DeleteBranch(ctx, branchName); err != nil {
+			return fmt.Errorf("failed to delete branch %s: %w", branchName, err)
+		}
+		result.DeletedBranches = append(result.DeletedBranches, branchName)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+// ApprovalsLeftFormat is appended to the result message while approvals are missing
+const ApprovalsLeftFormat = ". %d approval(s) still required"
+
+// SetApproverAPI sets the API authenticated with --approver-token, used to approve
+// the merge requests of the run
+func (stu *SimpleTagUpdater) SetApproverAPI(api gitlabapi.ApprovalAPI) {
+	stu.approvals.SetApprover(api)
+}
+
+// initializeApprover creates the client of --approver-token with the transport of the
+// run. The token is registered as a secret here too, so runs whose config was not
+// loaded by a command never log it.
+func (stu *SimpleTagUpdater) initializeApprover(transport http.RoundTripper) error {
+	if stu.config.ApproverToken == "" {
+		return nil
+	}
+	logger.RegisterSecret(stu.config.ApproverToken)
+	client, err := gitlabapi.NewClientWithTransport(stu.config.ApproverToken, stu.config.GitLabURL,
+		gitlabapi.AuthModePAT, transport)
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab client of the approver token: %w", err)
+	}
+	stu.SetApproverAPI(gitlabapi.NewAPIAdapter(client.GetGitLabClient()))
+	return nil
+}
+
+// reviewApprovals approves the merge request with --approver-token and records the
+// approvals it still needs in the result, with --check-approvals or an approver
+// token. Failures are reported as warnings since the merge request itself was created.
+func (stu *SimpleTagUpdater) reviewApprovals(ctx context.Context, result *SimpleUpdateResult, mr *gitlab.MergeRequest) {
+	if stu.config.ApproverToken == "" && !stu.config.CheckApprovals {
+		return
+	}
+
+	var status *gitlabapi.ApprovalStatus
+	var err error
+	if stu.config.ApproverToken != "" {
+		if status, err = stu.approvals.Approve(ctx, mr.IID); err != nil {
+			stu.logger.WithError(err).WithField("mr_id", mr.IID).Warn("Failed to approve merge request")
+		} else {
+			stu.logger.WithField("mr_id", mr.IID).Info("Merge request approved with the approver token")
+		}
+	}
+	if status == nil {
+		if status, err = stu.approvals.Status(ctx, mr.IID); err != nil {
+			stu.logger.WithError(err).WithField("mr_id", mr.IID).Warn("Failed to read merge request approvals")
+			return
+		}
+	}
+
+	result.Approvals = status
+	stu.logger.WithFields(map[string]interface{}{
+		"mr_id":              mr.IID,
+		"approvals_required": status.Required,
+		"approvals_left":     status.Left,
+		"rules_left":         status.RulesLeft,
+		"approved_by":        status.ApprovedBy,
+	}).Info("Merge request approvals")
+}
+
+// approvalsNote returns the note of the result message on missing approvals
+func approvalsNote(status *gitlabapi.ApprovalStatus) string {
+	if status == nil || status.Left == 0 {
+		return ""
+	}
+	return fmt.Sprintf(ApprovalsLeftFormat, status.Left)
+}
@@ -0,0 +1,74 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_Approvals(t *testing.T) {
+	tests := []struct {
+		name          string
+		approverToken string
+		wantLeft      int
+		wantApprovers []string
+	}{
+		{name: "check only", wantLeft: 2},
+		{name: "approver token", approverToken: "bot-token", wantLeft: 1, wantApprovers: []string{"approver-bot"}},
+		{name: "author token cannot approve", approverToken: gitlabtest.DefaultToken, wantLeft: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.SetApprovalRule(projectID, "Platform team", 2)
+			server.AddApprover("bot-token", "approver-bot")
+
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:       TestFilePath,
+				NewTag:         TestNewTag,
+				TargetBranch:   TestTargetBranch,
+				BranchName:     TestBranchName,
+				ApproverToken:  tt.approverToken,
+				CheckApprovals: true,
+			})
+			if tt.approverToken != "" {
+				updater.SetApproverAPI(gitlabapi.NewAPIAdapter(server.ClientWithToken(tt.approverToken)))
+			}
+
+			result, err := updater.Execute(context.Background())
+			if err != nil || result.Approvals == nil {
+				t.Fatalf("Execute() = %+v, %v, want the approvals of the merge request", result, err)
+			}
+			if result.Approvals.Left != tt.wantLeft || !reflect.DeepEqual(result.Approvals.ApprovedBy, tt.wantApprovers) {
+				t.Errorf("approvals = %+v, want %d left, approved by %v", result.Approvals, tt.wantLeft, tt.wantApprovers)
+			}
+			if !reflect.DeepEqual(result.Approvals.RulesLeft, []string{"Platform team"}) {
+				t.Errorf("rules left = %v, want the platform team rule", result.Approvals.RulesLeft)
+			}
+			if !strings.Contains(result.Message, fmt.Sprintf(ApprovalsLeftFormat, tt.wantLeft)) {
+				t.Errorf("message = %q, want the approvals still required", result.Message)
+			}
+		})
+	}
+}
+
+func TestSimpleTagUpdater_InitializeApproverRegistersSecret(t *testing.T) {
+	const approverToken = "approver-secret-token"
+	updater := newTestUpdater(t, gitlabtest.NewServer(t), 0, &config.CLIConfig{ApproverToken: approverToken})
+
+	if err := updater.initializeApprover(http.DefaultTransport); err != nil {
+		t.Fatalf("initializeApprover() unexpected error: %v", err)
+	}
+	if redacted := logger.Redact("token " + approverToken); strings.Contains(redacted, approverToken) {
+		t.Errorf("Redact() = %q, want the approver token scrubbed", redacted)
+	}
+}
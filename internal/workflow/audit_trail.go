@@ -0,0 +1,38 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/Gosayram/go-tag-updater/internal/audit"
+)
+
+// SetAuditTrail enables an audit record of every tag update this run performs
+func (stu *SimpleTagUpdater) SetAuditTrail(trail *audit.Trail) {
+	stu.auditTrail = trail
+}
+
+// recordAudit writes the audit record of a successful run; dry runs and skipped
+// updates change nothing and are not recorded, and audit failures never fail the update
+func (stu *SimpleTagUpdater) recordAudit(ctx context.Context, result *SimpleUpdateResult, runErr error) {
+	if stu.auditTrail == nil || runErr != nil || result == nil ||
+		!result.Success || result.Skipped || stu.config.DryRun {
+		return
+	}
+
+	record := &audit.Record{
+		RunID:   stu.runID,
+		Project: stu.config.ProjectID,
+		File:    stu.config.FilePath,
+		OldTag:  stu.oldTag,
+		NewTag:  stu.config.NewTag,
+		Branch:  result.BranchName,
+		Actor:   audit.DefaultActor(),
+	}
+	if result.MergeRequest != nil {
+		record.MergeRequestIID = result.MergeRequest.IID
+	}
+
+	if err := stu.auditTrail.Record(ctx, record); err != nil {
+		stu.logger.WithError(err).WithField("run_id", stu.runID).Warn("Failed to write audit record")
+	}
+}
@@ -0,0 +1,72 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/audit"
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+func TestSimpleTagUpdater_ExecuteRecordsAudit(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		dryRun      bool
+		wantRecords int
+	}{
+		{name: "update", content: TestYAMLContent, wantRecords: 1},
+		{name: "dry run", content: TestYAMLContent, dryRun: true},
+		{name: "tag already set", content: TestYAMLContentUpdated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+			t.Setenv(audit.EnvGitLabUserLogin, "release-bot")
+
+			server := gitlabtest.NewServer(t)
+			projectID := server.AddProject(TestProjectID)
+			server.SetFile(projectID, TestTargetBranch, TestFilePath, tt.content)
+
+			cfg := &config.CLIConfig{
+				ProjectID:    TestProjectID,
+				GitLabToken:  TestGitLabToken,
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+				DryRun:       tt.dryRun,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+			updater.SetAuditTrail(audit.NewTrailWithSinks(nil, audit.NewFileSink(auditPath)))
+
+			if _, err := updater.Execute(context.Background()); err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+
+			data, _ := os.ReadFile(auditPath)
+			records := strings.Count(string(data), "\n")
+			if records != tt.wantRecords {
+				t.Fatalf("audit file has %d records, want %d: %s", records, tt.wantRecords, data)
+			}
+			if tt.wantRecords == 0 {
+				return
+			}
+
+			for _, field := range []string{`"old_tag":"v1.0.0"`, `"new_tag":"v1.2.3"`,
+				`"branch":"update-tag/v1.2.3"`, `"mr_iid":`, `"actor":"release-bot"`} {
+				if !strings.Contains(string(data), field) {
+					t.Errorf("audit record %s missing %s", data, field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+)
+
+const (
+	// AutoMergeTimeout is how long enabling merge when pipeline succeeds is retried
+	// while GitLab refuses it because the merge request has no pipeline yet
+	AutoMergeTimeout = 2 * time.Minute
+	// AutoMergeRetryInterval is how often enabling it is retried
+	AutoMergeRetryInterval = 5 * time.Second
+)
+
+// enableAutoMerge schedules the merge request to merge when its pipeline succeeds.
+// GitLab answers 405 Method Not Allowed until CI created the head pipeline of a new
+// merge request, so the call is retried until autoMergeTimeout elapses. The error
+// returned fails the run: the merge request exists but will not merge by itself.
+func (stu *SimpleTagUpdater) enableAutoMerge(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	mrOpts *gitlabapi.SimpleMergeRequestOptions,
+) error {
+	iid := result.MergeRequest.IID
+	mrLog := stu.logger.WithField("mr_id", iid)
+	deadline := stu.now().Add(stu.autoMergeTimeout)
+
+	for attempt := 1; ; attempt++ {
+		mr, err := stu.mrManager.SetMergeWhenPipelineSucceeds(ctx, iid, mrOpts)
+		if err == nil {
+			result.MergeRequest = mr
+			result.AutoMergeEnabled = true
+			mrLog.WithField("attempts", attempt).Info("Merge when pipeline succeeds enabled")
+			return nil
+		}
+
+		wait := minDuration(stu.autoMergeInterval, deadline.Sub(stu.now()))
+		if gitlabapi.ResponseStatus(err) != http.StatusMethodNotAllowed || wait <= 0 {
+			mrLog.WithError(err).WithField("attempts", attempt).Error("Failed to enable merge when pipeline succeeds")
+			return fmt.Errorf("MR !%d was created, but merge when pipeline succeeds could not be enabled: %w", iid, err)
+		}
+		mrLog.WithField("retry_in", wait.String()).Debug("Merge request has no pipeline yet, retrying auto-merge")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("MR !%d was created, but enabling merge when pipeline succeeds was interrupted: %w",
+				iid, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
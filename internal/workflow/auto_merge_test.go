@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_AutoMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		// refusals is how many times GitLab answers 405 before the pipeline exists; -1 never stops
+		refusals    int
+		wantEnabled bool
+	}{
+		{name: "pipeline already created", wantEnabled: true},
+		{name: "pipeline created after the merge request", refusals: 2, wantEnabled: true},
+		{name: "no pipeline within the timeout", refusals: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			switch {
+			case tt.refusals < 0:
+				server.FailRequests(http.MethodPut, "/merge", http.StatusMethodNotAllowed)
+			case tt.refusals > 0:
+				server.FailRequestsTimes(http.MethodPut, "/merge", http.StatusMethodNotAllowed, tt.refusals)
+			}
+
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+				AutoMerge:    true,
+			})
+			updater.autoMergeTimeout = 50 * time.Millisecond
+			updater.autoMergeInterval = time.Millisecond
+
+			result, err := updater.Execute(context.Background())
+			if tt.wantEnabled && err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if !tt.wantEnabled && (err == nil || errors.ExitCode(err) == errors.ExitCodeSuccess) {
+				t.Fatalf("Execute() error = %v, want a failure with a non-zero exit code", err)
+			}
+
+			mrs := server.MergeRequests(projectID)
+			if len(mrs) != 1 || result.MergeRequest == nil {
+				t.Fatalf("merge requests = %d, result MR %+v, want the merge request created", len(mrs), result.MergeRequest)
+			}
+			if mrs[0].MergeWhenPipelineSucceeds != tt.wantEnabled || result.AutoMergeEnabled != tt.wantEnabled ||
+				result.Success != tt.wantEnabled {
+				t.Errorf("auto-merge = %v, result enabled = %v, success = %v, want %v",
+					mrs[0].MergeWhenPipelineSucceeds, result.AutoMergeEnabled, result.Success, tt.wantEnabled)
+			}
+		})
+	}
+}
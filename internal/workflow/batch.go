@@ -0,0 +1,544 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"sync"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	goyaml "gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/internal/audit"
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+	"github.com/Gosayram/go-tag-updater/internal/progress"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Stages of the batch pipeline, in order
+const (
+	BatchStageResolve      = "resolve"
+	BatchStageFetch        = "fetch"
+	BatchStageUpdate       = "update"
+	BatchStageCommit       = "commit"
+	BatchStageMergeRequest = "merge_request"
+
+	// DefaultBatchWorkers bounds the concurrent jobs of each stage of a batch run
+	DefaultBatchWorkers = 4
+)
+
+// BatchTarget is one file of one project updated by a batch run
+type BatchTarget struct {
+	ProjectID    string `yaml:"project_id"`
+	File         string `yaml:"file"`
+	YAMLPath     string `yaml:"yaml_path"`
+	TargetBranch string `yaml:"target_branch"`
+	// NewTag overrides the new tag of the batch run for this target
+	NewTag string `yaml:"new_tag"`
+}
+
+// batchTargetsFile is the layout of a batch targets file
+type batchTargetsFile struct {
+	Targets []BatchTarget `yaml:"targets"`
+}
+
+// LoadBatchTargets reads and validates a batch targets file
+func LoadBatchTargets(filePath string) ([]BatchTarget, error) {
+	data, err := os.ReadFile(filePath) // #nosec G304 -- the targets file is chosen by the operator
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NewFileNotFoundError(filePath)
+		}
+		return nil, errors.NewConfigErrorWithCause("failed to read targets file "+filePath, err)
+	}
+
+	var file batchTargetsFile
+	if err := goyaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.NewConfigErrorWithCause("failed to parse targets file "+filePath, err)
+	}
+	if len(file.Targets) == 0 {
+		return nil, errors.NewConfigError("targets file " + filePath + " lists no targets")
+	}
+	for i, target := range file.Targets {
+		if target.ProjectID == "" || target.File == "" {
+			return nil, errors.NewConfigError(fmt.Sprintf("target %d in %s: project_id and file are required",
+				i+1, filePath))
+		}
+	}
+	return file.Targets, nil
+}
+
+// BatchOptions configures a batch run
+type BatchOptions struct {
+	// Base holds the GitLab connection and update behavior shared by every target
+	Base    *config.CLIConfig
+	Targets []BatchTarget
+	// Workers bounds the concurrent jobs of each stage; DefaultBatchWorkers when zero
+	Workers int
+	// Client sends the requests of every target; created from Base when nil
+	Client *gitlab.Client
+	// StateFile records the outcome of each target as it finishes; disabled when empty
+	StateFile string
+	// Resume skips the targets StateFile records as completed and retries the others
+	Resume bool
+}
+
+// BatchTargetResult is the outcome of one target of a batch run; Stage names the
+// stage that failed with Err. Resumed targets were completed by a previous run and
+// have no Result.
+type BatchTargetResult struct {
+	Target  BatchTarget
+	Result  *SimpleUpdateResult
+	Stage   string
+	Err     error
+	Resumed bool
+}
+
+// BatchResult is the outcome of every target of a batch run, in the order of the targets
+type BatchResult struct {
+	Targets   []BatchTargetResult
+	Succeeded int
+	Skipped   int
+	Failed    int
+	Resumed   int
+}
+
+// Err returns the failures of every target together, or nil when all succeeded
+func (r *BatchResult) Err() error {
+	var errs []error
+	for _, target := range r.Targets {
+		if target.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %s failed: %w",
+				target.Target.ProjectID, target.Target.File, target.Stage, target.Err))
+		}
+	}
+	return stderrors.Join(errs...)
+}
+
+// batchJob carries one target through the stages of the pipeline
+type batchJob struct {
+	index   int
+	target  BatchTarget
+	cfg     *config.CLIConfig
+	updater *SimpleTagUpdater
+	result  *SimpleUpdateResult
+
+	content    string
+	newContent string
+	branchName string
+
+	// done is set when the update finished before the last stage: nothing to
+	// change, a dry run or a merge request opened by a previous run
+	done  bool
+	stage string
+	err   error
+	// resumed is set for targets completed by a previous run, which are not run again
+	resumed bool
+}
+
+// batchStage runs one step of every job with a bounded number of workers
+type batchStage struct {
+	name    string
+	workers int
+	run     func(ctx context.Context, job *batchJob) error
+}
+
+// batchRunner holds what the jobs of a batch run share
+type batchRunner struct {
+	opts       BatchOptions
+	logger     *logger.Logger
+	client     *gitlab.Client
+	api        gitlabapi.API
+	journal    *journal.Journal
+	auditTrail *audit.Trail
+	notifier   *notify.Dispatcher
+	state      *BatchState
+}
+
+// RunBatch updates every target through a pipeline of stages: project resolution,
+// content fetch, YAML update, commit and merge request creation. Each stage works on
+// several targets at a time with at most Workers of them in flight, so slow projects
+// do not hold up the others. A target failing at one stage skips the remaining
+// stages; the failures of all targets are returned together. With a StateFile, the
+// outcome of each target is saved as soon as it finishes, so a run interrupted or
+// partly failed can be resumed without repeating the completed targets. The
+// notification destinations of Base are notified of every target like a single run.
+func RunBatch(ctx context.Context, opts BatchOptions, log *logger.Logger) (*BatchResult, error) {
+	if opts.Base == nil {
+		return nil, errors.NewValidationError("base configuration is required")
+	}
+	if len(opts.Targets) == 0 {
+		return nil, errors.NewValidationError("at least one target is required")
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultBatchWorkers
+	}
+	if opts.Resume && opts.StateFile == "" {
+		return nil, errors.NewValidationError("resuming a batch run requires a state file")
+	}
+	if opts.Base.ReportFile != "" {
+		return nil, errors.NewValidationErrorWithContext("batch runs do not write a run report",
+			"remove --report-file; the batch result lists the outcome of every target")
+	}
+
+	runner := &batchRunner{opts: opts, logger: log, client: opts.Client}
+	if opts.StateFile != "" {
+		runner.state = &BatchState{Targets: make(map[string]*BatchTargetState)}
+	}
+	if opts.Resume {
+		state, err := LoadBatchState(opts.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		runner.state = state
+	}
+	if runner.client == nil {
+		client, err := newGitLabClient(opts.Base, opts.Base.GitLabURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+		}
+		if err := client.IsHealthy(ctx); err != nil {
+			return nil, fmt.Errorf("GitLab health check failed: %w", err)
+		}
+		runner.client = client.GetGitLabClient()
+	}
+	runner.api = gitlabapi.NewAPIAdapter(runner.client)
+
+	runJournal, err := journal.New(opts.Base.StateDir)
+	if err != nil {
+		log.WithError(err).Warn("Run journal disabled; the updates of this batch cannot be aborted later")
+	} else {
+		runner.journal = runJournal
+	}
+	if runner.auditTrail, err = audit.NewTrail(audit.Options{
+		FilePath:   opts.Base.AuditFile,
+		Endpoint:   opts.Base.AuditEndpoint,
+		SigningKey: opts.Base.AuditSigningKey,
+		Timeout:    opts.Base.AuditTimeout,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to configure audit trail: %w", err)
+	}
+	if runner.notifier, err = newNotifier(opts.Base); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*batchJob, len(opts.Targets))
+	var pending []*batchJob
+	for i, target := range opts.Targets {
+		cfg, err := runner.targetConfig(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid new tag of target %d (%s %s): %w", i+1, target.ProjectID, target.File, err)
+		}
+		jobs[i] = &batchJob{index: i, target: target, cfg: cfg}
+		if opts.Resume && runner.state.Completed(jobs[i].cfg) {
+			jobs[i].resumed = true
+			continue
+		}
+		pending = append(pending, jobs[i])
+	}
+
+	log.WithFields(map[string]interface{}{
+		"targets":   len(jobs),
+		"resumed":   len(jobs) - len(pending),
+		"workers":   opts.Workers,
+		"operation": "batch_start",
+	}).Info("Starting batch tag update")
+
+	task := progress.NewDefault(log).Start("Updating batch targets", len(pending))
+	finished := 0
+	runBatchPipeline(ctx, pending, runner.stages(), func(job *batchJob) {
+		runner.finish(ctx, job)
+		finished++
+		task.Update(finished, job.target.ProjectID+" "+job.target.File)
+	})
+
+	result := &BatchResult{Targets: make([]BatchTargetResult, len(jobs))}
+	for _, job := range jobs {
+		result.Targets[job.index] = BatchTargetResult{
+			Target:  job.target,
+			Result:  job.result,
+			Stage:   job.stage,
+			Err:     job.err,
+			Resumed: job.resumed,
+		}
+		switch {
+		case job.resumed:
+			result.Resumed++
+		case job.err != nil:
+			result.Failed++
+		case job.result != nil && job.result.Skipped:
+			result.Skipped++
+		default:
+			result.Succeeded++
+		}
+	}
+	task.Finish(result.Err())
+
+	log.WithFields(map[string]interface{}{
+		"succeeded": result.Succeeded,
+		"skipped":   result.Skipped,
+		"failed":    result.Failed,
+		"resumed":   result.Resumed,
+		"operation": "batch_complete",
+	}).Info("Batch tag update finished")
+	return result, result.Err()
+}
+
+// targetConfig returns the configuration of the update of one target
+func (r *batchRunner) targetConfig(target BatchTarget) (*config.CLIConfig, error) {
+	cfg := *r.opts.Base
+	cfg.ProjectID = target.ProjectID
+	cfg.FilePath = target.File
+	cfg.RepoPathGlob = ""
+	cfg.BranchName = ""
+	cfg.RunID = journal.NewRunID()
+	if target.YAMLPath != "" {
+		cfg.YAMLPath = target.YAMLPath
+	}
+	if target.TargetBranch != "" {
+		cfg.TargetBranch = target.TargetBranch
+	}
+	if target.NewTag != "" {
+		if err := cfg.SetNewTag(target.NewTag); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// stages returns the stages of the pipeline. The YAML update only works on fetched
+// content, so it gets no more workers than the stages waiting on GitLab.
+func (r *batchRunner) stages() []batchStage {
+	return []batchStage{
+		{name: BatchStageResolve, workers: r.opts.Workers, run: r.resolve},
+		{name: BatchStageFetch, workers: r.opts.Workers, run: r.fetch},
+		{name: BatchStageUpdate, workers: r.opts.Workers, run: r.update},
+		{name: BatchStageCommit, workers: r.opts.Workers, run: r.commit},
+		{name: BatchStageMergeRequest, workers: r.opts.Workers, run: r.openMergeRequest},
+	}
+}
+
+// resolve creates the updater of the target and resolves its project
+func (r *batchRunner) resolve(ctx context.Context, job *batchJob) error {
+	updater, err := NewSimpleTagUpdater(job.cfg, r.logger)
+	if err != nil {
+		return err
+	}
+	projectID, err := gitlabapi.NewProjectManager(r.client).ResolveProjectIdentifier(ctx, job.cfg.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project ID %s: %w", job.cfg.ProjectID, err)
+	}
+
+	updater.InitializeWithAPI(r.api, projectID)
+	if r.journal != nil {
+		updater.SetJournal(r.journal)
+	}
+	if r.auditTrail != nil {
+		updater.SetAuditTrail(r.auditTrail)
+	}
+	if r.notifier != nil {
+		updater.SetNotifier(r.notifier)
+	}
+	job.updater = updater
+	job.result = &SimpleUpdateResult{RunID: updater.runID}
+	updater.startJournal()
+	return nil
+}
+
+// fetch reads the current content of the file
+func (r *batchRunner) fetch(ctx context.Context, job *batchJob) error {
+	err := job.updater.ensureTargetBranch(ctx, job.result)
+	if err == nil {
+		job.content, err = job.updater.fetchContent(ctx)
+	}
+	return err
+}
+
+// update writes the new tag into the content and runs the checks of the update
+func (r *batchRunner) update(ctx context.Context, job *batchJob) error {
+	updater, result := job.updater, job.result
+	newContent, err := updater.updateFetchedContent(ctx, job.content)
+	result.RenamedFrom = updater.renamedFrom
+	result.EncodeDiagnostics = updater.encodeDiagnostics
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		job.result, job.done = updater.handleNoChanges(result), true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job.newContent = newContent
+
+	skipped, err := updater.checkMinInterval(ctx, result)
+	if err != nil || skipped {
+		job.done = skipped
+		return err
+	}
+	updater.idempotencyKey = computeIdempotencyKey(updater.projectID, job.cfg.TargetBranch, job.cfg.FilePath,
+		updater.tagPath, job.cfg.NewTag)
+	if job.cfg.UpdateExistingMR {
+		existing, findErr := updater.findExistingMergeRequest(ctx)
+		if findErr != nil || existing != nil {
+			job.done = existing != nil
+			return r.reuse(ctx, job, existing, findErr)
+		}
+	}
+	if err := updater.runChecks(ctx); err != nil {
+		return err
+	}
+
+	if job.branchName, err = updater.prepareBranchName(ctx); err != nil {
+		return err
+	}
+	result.BranchName = job.branchName
+	if job.cfg.CheckFileConflicts {
+		if err := updater.applyConflictPolicy(ctx, result, job.branchName); err != nil {
+			return err
+		}
+	}
+
+	if job.cfg.DryRun {
+		result.Commit = updater.previewCommit(ctx, job.branchName, newContent)
+		job.result, job.done = updater.handleDryRun(result, newContent), true
+	}
+	return nil
+}
+
+// commit commits the new content to the update branch, converging on the merge
+// request of a previous run for the same update
+func (r *batchRunner) commit(ctx context.Context, job *batchJob) error {
+	branchName, existing, err := job.updater.commitUpdate(ctx, job.result, job.newContent, job.branchName)
+	job.branchName = branchName
+	if err != nil || existing == nil {
+		return err
+	}
+	job.done = true
+	return r.reuse(ctx, job, existing, nil)
+}
+
+// reuse updates the merge request a previous run opened for the same update
+func (r *batchRunner) reuse(ctx context.Context, job *batchJob, existing *gitlab.BasicMergeRequest, err error) error {
+	if err != nil {
+		return err
+	}
+	job.result, err = job.updater.reuseMergeRequest(ctx, job.result, existing, job.newContent)
+	return r.gate(ctx, job, err)
+}
+
+// openMergeRequest opens the merge request of the committed update
+func (r *batchRunner) openMergeRequest(ctx context.Context, job *batchJob) error {
+	var err error
+	job.result, err = job.updater.openMergeRequest(ctx, job.result, job.newContent, job.branchName)
+	return r.gate(ctx, job, err)
+}
+
+// gate optionally resolves conflicts and waits for the pipeline of the merge request
+func (r *batchRunner) gate(ctx context.Context, job *batchJob, err error) error {
+	job.result, err = job.updater.gateOnConflicts(ctx, job.result, err)
+	job.result, err = job.updater.gateOnPipeline(ctx, job.result, err)
+	return err
+}
+
+// finish records the outcome of a job that left the pipeline in the run journal and
+// the audit trail and notifies of it, removing the branch of an update that failed
+// before its merge request was opened
+func (r *batchRunner) finish(ctx context.Context, job *batchJob) {
+	fields := map[string]interface{}{
+		"project_id": job.target.ProjectID,
+		"file_path":  job.target.File,
+	}
+	if job.updater != nil {
+		job.updater.removeOrphanBranch(ctx, job.result, job.err)
+		job.updater.closeCheckout()
+		job.updater.finishJournal(job.err)
+		job.updater.recordAudit(ctx, job.result, job.err)
+		job.updater.notifyRun(ctx, job.result, job.err)
+		fields["run_id"] = job.updater.runID
+	} else if job.err != nil {
+		notifyFailure(ctx, r.notifier, job.cfg, job.err, r.logger)
+	}
+	r.saveState(job)
+
+	if job.err != nil {
+		r.logger.WithError(job.err).WithFields(fields).WithField("stage", job.stage).Error("Batch target failed")
+		return
+	}
+	if job.result != nil {
+		r.logger.WithFields(fields).Info(job.result.Message)
+	}
+}
+
+// saveState records the outcome of a job in the state file. Dry runs change nothing,
+// so they leave the state as it was.
+func (r *batchRunner) saveState(job *batchJob) {
+	if r.state == nil || job.cfg.DryRun {
+		return
+	}
+	r.state.record(job)
+	if err := r.state.Save(r.opts.StateFile); err != nil {
+		r.logger.WithError(err).WithField("state_file", r.opts.StateFile).
+			Warn("Failed to save batch state; a resumed run may repeat this target")
+	}
+}
+
+// runBatchPipeline passes every job through the stages in order. Each stage runs its
+// own pool of workers, so a job can be committed while later jobs are still being
+// fetched. A job that failed or finished early passes the remaining stages untouched;
+// once the context is canceled, jobs that did not finish fail with its error. done is
+// called from a single goroutine for every job leaving the last stage.
+func runBatchPipeline(ctx context.Context, jobs []*batchJob, stages []batchStage, done func(*batchJob)) {
+	queued := make(chan *batchJob)
+	go func() {
+		defer close(queued)
+		for _, job := range jobs {
+			queued <- job
+		}
+	}()
+
+	out := queued
+	for _, stage := range stages {
+		out = startBatchStage(ctx, stage, out)
+	}
+	for job := range out {
+		done(job)
+	}
+}
+
+// startBatchStage starts the workers of a stage and returns the channel of the jobs
+// leaving it, closed once every job has left
+func startBatchStage(ctx context.Context, stage batchStage, in <-chan *batchJob) chan *batchJob {
+	out := make(chan *batchJob)
+	workers := max(stage.workers, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range in {
+				if job.err == nil && !job.done {
+					err := ctx.Err()
+					if err == nil {
+						err = stage.run(ctx, job)
+					}
+					if err != nil {
+						job.stage, job.err = stage.name, err
+					}
+				}
+				out <- job
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
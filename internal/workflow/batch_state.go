@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Statuses of the targets recorded in a batch state file
+const (
+	BatchTargetCompleted = "completed"
+	BatchTargetFailed    = "failed"
+
+	// BatchStateFileSuffix is appended to the targets file to name its default state file
+	BatchStateFileSuffix = ".state.json"
+)
+
+// BatchState records the progress of a batch run, so an interrupted or partly failed
+// run can be resumed
+type BatchState struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	// Targets holds the state of each target, keyed by batchTargetKey
+	Targets map[string]*BatchTargetState `json:"targets"`
+}
+
+// BatchTargetState is the outcome of the last attempt at one target
+type BatchTargetState struct {
+	ProjectID       string    `json:"project_id"`
+	File            string    `json:"file"`
+	NewTag          string    `json:"new_tag"`
+	Status          string    `json:"status"`
+	Stage           string    `json:"stage,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	RunID           string    `json:"run_id,omitempty"`
+	MergeRequestURL string    `json:"merge_request_url,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// DefaultBatchStateFile returns the state file of a targets file: the targets file
+// name followed by BatchStateFileSuffix
+func DefaultBatchStateFile(targetsFile string) string {
+	return targetsFile + BatchStateFileSuffix
+}
+
+// LoadBatchState reads a batch state file; a missing file yields an empty state
+func LoadBatchState(filePath string) (*BatchState, error) {
+	state := &BatchState{Targets: make(map[string]*BatchTargetState)}
+	data, err := os.ReadFile(filePath) // #nosec G304 -- the state file is chosen by the operator
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to read batch state file: %v", err))
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to decode batch state file %s: %v", filePath, err))
+	}
+	if state.Targets == nil {
+		state.Targets = make(map[string]*BatchTargetState)
+	}
+	return state, nil
+}
+
+// Save writes the state to a file, replacing it atomically so an interrupted run
+// never leaves a truncated state behind
+func (s *BatchState) Save(filePath string) error {
+	if dir := filepath.Dir(filePath); dir != "." {
+		if err := os.MkdirAll(dir, journal.JournalDirPermissions); err != nil {
+			return errors.NewFileSystemError(fmt.Sprintf("failed to create batch state directory: %v", err))
+		}
+	}
+
+	s.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch state: %w", err)
+	}
+
+	tempPath := filePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, journal.JournalFilePermissions); err != nil {
+		return errors.NewFileSystemError(fmt.Sprintf("failed to write batch state file: %v", err))
+	}
+	if err := os.Rename(tempPath, filePath); err != nil {
+		_ = os.Remove(tempPath) // Ignore cleanup error, return original rename error
+		return errors.NewFileSystemError(fmt.Sprintf("failed to store batch state file: %v", err))
+	}
+	return nil
+}
+
+// Completed reports whether a previous attempt completed the update of a target
+func (s *BatchState) Completed(cfg *config.CLIConfig) bool {
+	target, ok := s.Targets[batchTargetKey(cfg)]
+	return ok && target.Status == BatchTargetCompleted
+}
+
+// record stores the outcome of the job of a target
+func (s *BatchState) record(job *batchJob) {
+	target := &BatchTargetState{
+		ProjectID: job.cfg.ProjectID,
+		File:      job.cfg.FilePath,
+		NewTag:    job.cfg.NewTag,
+		Status:    BatchTargetCompleted,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if job.updater != nil {
+		target.RunID = job.updater.runID
+	}
+	if job.err != nil {
+		target.Status, target.Stage, target.Error = BatchTargetFailed, job.stage, job.err.Error()
+	} else if job.result != nil && job.result.MergeRequest != nil {
+		target.MergeRequestURL = job.result.MergeRequest.WebURL
+	}
+	s.Targets[batchTargetKey(job.cfg)] = target
+}
+
+// batchTargetKey identifies the update of a target in the state file. The new tag
+// is part of it, so a run for another tag does not skip targets completed for the
+// previous one.
+func batchTargetKey(cfg *config.CLIConfig) string {
+	return strings.Join([]string{cfg.ProjectID, cfg.FilePath, cfg.YAMLPath, cfg.TargetBranch, cfg.NewTag}, "|")
+}
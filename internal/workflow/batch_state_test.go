@@ -0,0 +1,24 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadBatchState(t *testing.T) {
+	dir := t.TempDir()
+	state, err := LoadBatchState(filepath.Join(dir, "missing.json"))
+	if err != nil || len(state.Targets) != 0 {
+		t.Errorf("LoadBatchState(missing) = %+v, %v, want an empty state", state, err)
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte("{"), 0o600); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+	if _, err := LoadBatchState(broken); err == nil || !strings.Contains(err.Error(), "failed to decode") {
+		t.Errorf("LoadBatchState(broken) error = %v, want a decoding error", err)
+	}
+}
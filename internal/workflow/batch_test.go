@@ -0,0 +1,266 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestLoadBatchTargets(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write targets file: %v", err)
+		}
+		return path
+	}
+
+	targets, err := LoadBatchTargets(write("targets.yaml",
+		"targets:\n  - project_id: group/a\n    file: values.yaml\n    new_tag: v2.0.0\n  - project_id: group/b\n"+
+			"    file: deploy.yaml\n    yaml_path: .image.tag\n"))
+	if err != nil {
+		t.Fatalf("LoadBatchTargets() unexpected error: %v", err)
+	}
+	want := []BatchTarget{
+		{ProjectID: "group/a", File: "values.yaml", NewTag: "v2.0.0"},
+		{ProjectID: "group/b", File: "deploy.yaml", YAMLPath: ".image.tag"},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("LoadBatchTargets() = %+v, want %+v", targets, want)
+	}
+
+	for name, content := range map[string]string{
+		"empty.yaml":   "targets: []\n",
+		"no-file.yaml": "targets:\n  - project_id: group/a\n",
+		"broken.yaml":  "targets: [\n",
+	} {
+		if _, err := LoadBatchTargets(write(name, content)); errors.GetErrorCode(err) != errors.ErrCodeConfiguration {
+			t.Errorf("LoadBatchTargets(%s) error = %v, want a configuration error", name, err)
+		}
+	}
+	_, err = LoadBatchTargets(filepath.Join(dir, "missing.yaml"))
+	if errors.GetErrorCode(err) != errors.ErrCodeFileNotFound {
+		t.Errorf("LoadBatchTargets(missing) error = %v, want file not found", err)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	const projects = 6
+
+	server := gitlabtest.NewServer(t)
+	var targets []BatchTarget
+	projectIDs := make([]int, projects)
+	for i := range projectIDs {
+		name := fmt.Sprintf("group/project-%d", i)
+		projectID := server.AddProject(name)
+		projectIDs[i] = projectID
+		content := TestYAMLContent
+		if i == 1 {
+			content = strings.Replace(content, "v1.0.0", TestNewTag, 1)
+		}
+		server.SetFile(projectID, TestTargetBranch, TestFilePath, content)
+		targets = append(targets, BatchTarget{ProjectID: name, File: TestFilePath})
+	}
+	targets = append(targets, BatchTarget{ProjectID: "group/project-0", File: "missing.yaml"})
+
+	base := &config.CLIConfig{
+		GitLabToken:  TestGitLabToken,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		StateDir:     t.TempDir(),
+	}
+	result, err := RunBatch(context.Background(), BatchOptions{
+		Base:    base,
+		Targets: targets,
+		Workers: 2,
+		Client:  server.Client(),
+	}, logger.New(false))
+	if err == nil || !strings.Contains(err.Error(), "group/project-0 missing.yaml: fetch failed") {
+		t.Errorf("RunBatch() error = %v, want the fetch failure of the missing file", err)
+	}
+
+	if result.Succeeded != projects-1 || result.Skipped != 1 || result.Failed != 1 {
+		t.Errorf("RunBatch() succeeded = %d, skipped = %d, failed = %d, want %d, 1, 1",
+			result.Succeeded, result.Skipped, result.Failed, projects-1)
+	}
+	for i, target := range result.Targets {
+		if target.Target != targets[i] {
+			t.Errorf("RunBatch() target %d = %+v, want %+v", i, target.Target, targets[i])
+		}
+	}
+	if failed := result.Targets[projects]; failed.Stage != BatchStageFetch ||
+		errors.GetErrorCode(failed.Err) != errors.ErrCodeFileNotFound {
+		t.Errorf("RunBatch() failed target stage = %q, error = %v", failed.Stage, failed.Err)
+	}
+
+	for i, projectID := range projectIDs {
+		mrs := server.MergeRequests(projectID)
+		if i == 1 {
+			if len(mrs) != 0 {
+				t.Errorf("project %d merge requests = %d, want none for an unchanged file", i, len(mrs))
+			}
+		} else if len(mrs) != 1 || !strings.Contains(mrs[0].Title, TestNewTag) {
+			t.Errorf("project %d merge requests = %+v, want one updating to %s", i, mrs, TestNewTag)
+		}
+	}
+}
+
+func TestRunBatch_Canceled(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	server.AddProject(TestProjectID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := RunBatch(ctx, BatchOptions{
+		Base:    &config.CLIConfig{GitLabToken: TestGitLabToken, NewTag: TestNewTag, StateDir: t.TempDir()},
+		Targets: []BatchTarget{{ProjectID: TestProjectID, File: TestFilePath}},
+		Client:  server.Client(),
+	}, logger.New(false))
+	if !stderrors.Is(err, context.Canceled) || result.Failed != 1 || result.Targets[0].Stage != BatchStageResolve {
+		t.Errorf("RunBatch() = %+v, %v, want the target to fail at resolve with the context error", result, err)
+	}
+}
+
+func TestRunBatch_Resume(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	first := server.AddProject("group/first")
+	second := server.AddProject("group/second")
+	server.SetFile(first, TestTargetBranch, TestFilePath, TestYAMLContent)
+
+	stateFile := filepath.Join(t.TempDir(), "targets.yaml"+BatchStateFileSuffix)
+	opts := BatchOptions{
+		Base: &config.CLIConfig{
+			GitLabToken:  TestGitLabToken,
+			NewTag:       TestNewTag,
+			TargetBranch: TestTargetBranch,
+			StateDir:     t.TempDir(),
+		},
+		Targets: []BatchTarget{
+			{ProjectID: "group/first", File: TestFilePath},
+			{ProjectID: "group/second", File: TestFilePath},
+		},
+		Client:    server.Client(),
+		StateFile: stateFile,
+	}
+
+	result, err := RunBatch(context.Background(), opts, logger.New(false))
+	if err == nil || result.Succeeded != 1 || result.Failed != 1 {
+		t.Fatalf("RunBatch() = %+v, %v, want the second target to fail", result, err)
+	}
+	state, err := LoadBatchState(stateFile)
+	if err != nil || len(state.Targets) != 2 {
+		t.Fatalf("LoadBatchState() = %+v, %v, want both targets recorded", state, err)
+	}
+	for _, target := range state.Targets {
+		if target.ProjectID == "group/first" && (target.Status != BatchTargetCompleted || target.MergeRequestURL == "") {
+			t.Errorf("first target state = %+v, want completed with its merge request", target)
+		}
+		if target.ProjectID == "group/second" && (target.Status != BatchTargetFailed || target.Stage != BatchStageFetch) {
+			t.Errorf("second target state = %+v, want failed at fetch", target)
+		}
+	}
+
+	server.SetFile(second, TestTargetBranch, TestFilePath, TestYAMLContent)
+	opts.Resume = true
+	result, err = RunBatch(context.Background(), opts, logger.New(false))
+	if err != nil || result.Resumed != 1 || result.Succeeded != 1 || !result.Targets[0].Resumed {
+		t.Fatalf("RunBatch(resume) = %+v, %v, want the first target resumed and the second updated", result, err)
+	}
+	if mrs := server.MergeRequests(first); len(mrs) != 1 {
+		t.Errorf("first project merge requests = %d, want the one of the first run only", len(mrs))
+	}
+	if mrs := server.MergeRequests(second); len(mrs) != 1 {
+		t.Errorf("second project merge requests = %d, want one from the resumed run", len(mrs))
+	}
+
+	state, err = LoadBatchState(stateFile)
+	if err != nil {
+		t.Fatalf("LoadBatchState() unexpected error: %v", err)
+	}
+	for _, target := range state.Targets {
+		if target.Status != BatchTargetCompleted {
+			t.Errorf("target state after resume = %+v, want completed", target)
+		}
+	}
+
+	opts.StateFile = ""
+	_, err = RunBatch(context.Background(), opts, logger.New(false))
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("RunBatch(resume without state file) error = %v, want a validation error", err)
+	}
+}
+
+func TestRunBatch_Notifications(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, TestYAMLContent)
+
+	var mu sync.Mutex
+	var events []notify.Event
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode notification: %v", err)
+		}
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	t.Cleanup(webhook.Close)
+
+	base := &config.CLIConfig{
+		GitLabToken:      TestGitLabToken,
+		NewTag:           TestNewTag,
+		TargetBranch:     TestTargetBranch,
+		StateDir:         t.TempDir(),
+		NotifyWebhookURL: webhook.URL,
+	}
+	_, err := RunBatch(context.Background(), BatchOptions{
+		Base: base,
+		Targets: []BatchTarget{
+			{ProjectID: TestProjectID, File: TestFilePath},
+			{ProjectID: TestProjectID, File: "missing.yaml"},
+		},
+		Client: server.Client(),
+	}, logger.New(false))
+	if err == nil {
+		t.Fatal("RunBatch() succeeded, want the failure of the missing file")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	outcomes := map[string]bool{}
+	for _, event := range events {
+		outcomes[event.File] = event.Success
+	}
+	if want := map[string]bool{TestFilePath: true, "missing.yaml": false}; !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("notified outcomes = %v, want %v", outcomes, want)
+	}
+
+	base.ReportFile = filepath.Join(t.TempDir(), "report.json")
+	_, err = RunBatch(context.Background(), BatchOptions{
+		Base:    base,
+		Targets: []BatchTarget{{ProjectID: TestProjectID, File: TestFilePath}},
+		Client:  server.Client(),
+	}, logger.New(false))
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("RunBatch(report file) error = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// createOrReuseBranch creates the update branch. When the name is taken it reuses the
+// branch if a prior run of the same update committed to it, otherwise it retries with
+// alternative names up to gitlabapi.MaxBranchCreateAttempts times. The returned flag
+// reports whether an existing branch was reused. Reused branches are not recorded in
+// the run journal, so aborting the run never deletes them.
+func (stu *SimpleTagUpdater) createOrReuseBranch(
+	ctx context.Context,
+	baseName string,
+) (*gitlabapi.BranchInfo, bool, error) {
+	for attempt := 0; attempt < gitlabapi.MaxBranchCreateAttempts; attempt++ {
+		branchName := stu.branchMgr.AlternativeBranchName(baseName, attempt)
+
+		created, err := stu.branchMgr.CreateBranch(ctx, branchName, stu.sourceRef())
+		if err == nil {
+			stu.createdBranch = branchName
+			stu.recordBranch(branchName)
+			stu.logger.WithFields(map[string]interface{}{
+				"branch_name":   branchName,
+				"branch_url":    created.WebURL,
+				"source_branch": stu.sourceRef(),
+			}).Info("Branch created successfully")
+			return created, false, nil
+		}
+
+		if !gitlabapi.IsBranchExistsError(err) {
+			stu.logger.WithError(err).WithFields(map[string]interface{}{
+				"branch_name":   branchName,
+				"source_branch": stu.sourceRef(),
+			}).Error("Failed to create branch")
+			return nil, false, fmt.Errorf("failed to create branch %s: %w", branchName, err)
+		}
+
+		existing, getErr := stu.branchMgr.GetBranch(ctx, branchName)
+		if getErr != nil {
+			// The name was rejected for another reason, such as an invalid source ref
+			return nil, false, fmt.Errorf("failed to create branch %s: %w", branchName, err)
+		}
+
+		if stu.isReusableBranch(existing) {
+			stu.logger.WithFields(map[string]interface{}{
+				"branch_name":     branchName,
+				"branch_url":      existing.WebURL,
+				"idempotency_key": stu.idempotencyKey,
+			}).Info("Reusing branch left by a previous run of the same update")
+			return existing, true, nil
+		}
+
+		stu.logger.WithFields(map[string]interface{}{
+			"branch_name": branchName,
+			"attempt":     attempt + 1,
+		}).Warn("Branch name already taken by unrelated changes, trying an alternative name")
+	}
+
+	return nil, false, errors.NewGitOperationError(fmt.Sprintf(
+		"no free branch name for %s after %d attempts; delete stale branches or pass --branch-name",
+		baseName, gitlabapi.MaxBranchCreateAttempts))
+}
+
+// isReusableBranch reports whether an existing branch was left by a prior run of the
+// same update: its head must be the tool's update commit, with the title of this
+// update and the run trailer. A branch at any other commit, even the target branch
+// head, may belong to someone else.
+func (stu *SimpleTagUpdater) isReusableBranch(branch *gitlabapi.BranchInfo) bool {
+	if branch.Commit == nil || branch.Commit.Title != stu.mergeRequestTitle() {
+		return false
+	}
+	_, ok := identity.CommitRunID(branch.Commit.Message)
+	return ok
+}
+
+// commitContent writes the updated file to the branch, skipping the commit when a
+// reused branch already carries the content
+func (stu *SimpleTagUpdater) commitContent(ctx context.Context, branchName, newContent string, reused bool) error {
+	if reused {
+		current, err := stu.fileManager.GetFileContent(ctx, stu.config.FilePath, branchName)
+		if err != nil {
+			return fmt.Errorf("failed to get file content from branch %s: %w", branchName, err)
+		}
+		if current == newContent {
+			return nil
+		}
+	}
+
+	if err := stu.commitFile(ctx, branchName, newContent, reused); err != nil {
+		// Try to cleanup a branch created by this run on failure
+		if !reused {
+			_ = stu.branchMgr.DeleteBranch(ctx, branchName)
+		}
+		stu.logger.WithError(err).WithFields(map[string]interface{}{
+			"file_path":   stu.config.FilePath,
+			"branch_name": branchName,
+		}).Error("Failed to update file")
+		return fmt.Errorf("failed to update file: %w", err)
+	}
+
+	return nil
+}
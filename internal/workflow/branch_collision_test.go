@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+)
+
+func TestSimpleTagUpdater_BranchCollision(t *testing.T) {
+	tests := []struct {
+		name string
+		// commitMessage is the message of a commit on the taken branch; empty leaves it at the target head
+		commitMessage func(title string) string
+		wantReused    bool
+	}{
+		{name: "branch at the target head"},
+		{name: "manual commit with the update title", commitMessage: func(title string) string { return title }},
+		{
+			name:          "update commit of an earlier run",
+			commitMessage: func(title string) string { return identity.WithCommitTrailer(title, "earlier-run") },
+			wantReused:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.AddBranch(projectID, TestBranchName, TestTargetBranch, false)
+
+			runJournal, err := journal.New(t.TempDir())
+			if err != nil {
+				t.Fatalf("journal.New() unexpected error: %v", err)
+			}
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+			})
+			updater.SetJournal(runJournal)
+			updater.concurrentRunWindow = time.Millisecond
+
+			if tt.commitMessage != nil {
+				_, _, err := server.Client().RepositoryFiles.UpdateFile(projectID, TestFilePath, &gitlab.UpdateFileOptions{
+					Branch:        gitlab.Ptr(TestBranchName),
+					Content:       gitlab.Ptr(TestYAMLContentUpdated),
+					CommitMessage: gitlab.Ptr(tt.commitMessage(updater.mergeRequestTitle())),
+				})
+				if err != nil {
+					t.Fatalf("Failed to commit to the taken branch: %v", err)
+				}
+			}
+
+			result, err := updater.Execute(context.Background())
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if reused := result.BranchName == TestBranchName; reused != tt.wantReused {
+				t.Errorf("Execute() branch = %s, reused = %v, want %v", result.BranchName, reused, tt.wantReused)
+			}
+
+			entry, err := runJournal.Load(result.RunID)
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if slices.Contains(entry.CreatedBranches, TestBranchName) {
+				t.Errorf("journal branches = %v, want %s left out so abort keeps it", entry.CreatedBranches, TestBranchName)
+			}
+			if !tt.wantReused && !slices.Contains(entry.CreatedBranches, result.BranchName) {
+				t.Errorf("journal branches = %v, want the created %s", entry.CreatedBranches, result.BranchName)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// CleanupMaxBranches bounds how many update branches one cleanup inspects
+	CleanupMaxBranches = 1000
+)
+
+// CleanupResult contains the results of a branch cleanup
+type CleanupResult struct {
+	DeletedBranches []string
+	// KeptBranches lists the update branches left alone with the reason for each
+	KeptBranches map[string]string
+	Message      string
+}
+
+// CleanupOptions selects the branches a cleanup deletes
+type CleanupOptions struct {
+	// OlderThan keeps branches whose head commit is more recent; every branch
+	// qualifies when zero
+	OlderThan time.Duration
+	// Now is the time branch ages are measured against
+	Now time.Time
+}
+
+// CleanupBranches deletes the update branches of a project whose merge requests
+// were all merged or closed. Branches without merge requests, with an open one,
+// protected branches and branches more recent than OlderThan are kept.
+func CleanupBranches(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	opts CleanupOptions,
+	log *logger.Logger,
+) (*CleanupResult, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("project ID is required")
+	}
+	if opts.OlderThan < 0 {
+		return nil, errors.NewValidationError("older-than cannot be negative")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	projectID, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+
+	branchMgr := gitlabapi.NewBranchManager(client.GetGitLabClient(), projectID)
+	mrManager := gitlabapi.NewSimpleMergeRequestManager(client.GetGitLabClient(), projectID)
+
+	branches, err := branchMgr.ListBranches(ctx, gitlabapi.UpdateBranchPrefix, CleanupMaxBranches)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CleanupResult{KeptBranches: make(map[string]string)}
+	for _, branch := range branches {
+		// The search matches anywhere in the name
+		if !strings.HasPrefix(branch.Name, gitlabapi.UpdateBranchPrefix) {
+			continue
+		}
+
+		branchLog := log.WithField("branch_name", branch.Name)
+		reason, err := keepBranchReason(ctx, mrManager, branch, opts)
+		if err != nil {
+			return result, err
+		}
+		if reason != "" {
+			result.KeptBranches[branch.Name] = reason
+			branchLog.WithField("reason", reason).Debug("Keeping branch")
+			continue
+		}
+
+		if err := deleteStaleBranch(ctx, branchMgr, branch.Name, cfg.DryRun, branchLog); err != nil {
+			return result, err
+		}
+		result.DeletedBranches = append(result.DeletedBranches, branch.Name)
+	}
+
+	result.Message = fmt.Sprintf("Deleted %d stale branch(es), kept %d",
+		len(result.DeletedBranches), len(result.KeptBranches))
+	if cfg.DryRun {
+		result.Message = fmt.Sprintf("Dry run completed. Would delete %d stale branch(es), keep %d",
+			len(result.DeletedBranches), len(result.KeptBranches))
+	}
+	return result, nil
+}
+
+// keepBranchReason returns why an update branch must be kept, or "" when it is stale
+func keepBranchReason(
+	ctx context.Context,
+	mrManager *gitlabapi.SimpleMergeRequestManager,
+	branch *gitlabapi.BranchInfo,
+	opts CleanupOptions,
+) (string, error) {
+	if branch.Protected || branch.Default {
+		return "protected", nil
+	}
+
+	if opts.OlderThan > 0 && branch.Commit != nil && branch.Commit.CommittedDate != nil &&
+		opts.Now.Sub(*branch.Commit.CommittedDate) < opts.OlderThan {
+		return "recent", nil
+	}
+
+	mrs, err := mrManager.ListMergeRequestsBySourceBranch(ctx, branch.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(mrs) == 0 {
+		return "no merge request", nil
+	}
+	for _, mr := range mrs {
+		if mr.State != gitlabapi.StateMerged && mr.State != gitlabapi.StateClosed {
+			return fmt.Sprintf("merge request !%d is %s", mr.IID, mr.State), nil
+		}
+	}
+	return "", nil
+}
+
+// deleteStaleBranch deletes a branch, or only logs it in dry-run mode
+func deleteStaleBranch(
+	ctx context.Context,
+	branchMgr *gitlabapi.BranchManager,
+	branchName string,
+	dryRun bool,
+	branchLog *logrus.Entry,
+) error {
+	if dryRun {
+		branchLog.Info("Dry run mode: would delete branch")
+		return nil
+	}
+
+	if err := branchMgr.DeleteBranch(ctx, branchName); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branchName, err)
+	}
+	branchLog.Info("Stale branch deleted")
+	return nil
+}
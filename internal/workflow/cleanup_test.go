@@ -0,0 +1,102 @@
+package workflow
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestCleanupBranches(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	client := server.Client()
+
+	// state is the merge request state of each branch, "" for none
+	branches := map[string]string{
+		"update-tag/merged":     gitlabapi.StateMerged,
+		"update-tag/closed":     gitlabapi.StateClosed,
+		"update-tag/open":       gitlabapi.StateOpened,
+		"update-tag/orphan":     "",
+		"feature/update-tag/v1": gitlabapi.StateMerged,
+	}
+	for name, state := range branches {
+		server.AddBranch(projectID, name, TestTargetBranch, false)
+		if state == "" {
+			continue
+		}
+		mr, _, err := client.MergeRequests.CreateMergeRequest(projectID, &gitlab.CreateMergeRequestOptions{
+			Title:        gitlab.Ptr("Update " + name),
+			SourceBranch: gitlab.Ptr(name),
+			TargetBranch: gitlab.Ptr(TestTargetBranch),
+		})
+		if err != nil {
+			t.Fatalf("failed to create merge request: %v", err)
+		}
+		switch state {
+		case gitlabapi.StateMerged:
+			_, _, err = client.MergeRequests.AcceptMergeRequest(projectID, mr.IID, nil)
+		case gitlabapi.StateClosed:
+			_, _, err = client.MergeRequests.UpdateMergeRequest(projectID, mr.IID,
+				&gitlab.UpdateMergeRequestOptions{StateEvent: gitlab.Ptr("close")})
+		}
+		if err != nil {
+			t.Fatalf("failed to move merge request to %s: %v", state, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		opts        CleanupOptions
+		wantDeleted []string
+	}{
+		{name: "recent branches kept", opts: CleanupOptions{OlderThan: time.Hour, Now: time.Now()}},
+		{
+			name:        "dry run",
+			dryRun:      true,
+			wantDeleted: []string{"update-tag/closed", "update-tag/merged"},
+		},
+		{
+			name:        "old branches deleted",
+			opts:        CleanupOptions{OlderThan: time.Hour, Now: time.Now().Add(2 * time.Hour)},
+			wantDeleted: []string{"update-tag/closed", "update-tag/merged"},
+		},
+		{name: "nothing left"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.CLIConfig{
+				ProjectID:   TestProjectID,
+				GitLabToken: TestGitLabToken,
+				GitLabURL:   server.URL(),
+				DryRun:      tt.dryRun,
+			}
+
+			result, err := CleanupBranches(context.Background(), cfg, tt.opts, logger.New(false))
+			if err != nil {
+				t.Fatalf("CleanupBranches() unexpected error: %v", err)
+			}
+			sort.Strings(result.DeletedBranches)
+			if strings.Join(result.DeletedBranches, ",") != strings.Join(tt.wantDeleted, ",") {
+				t.Errorf("CleanupBranches() deleted %v, want %v", result.DeletedBranches, tt.wantDeleted)
+			}
+		})
+	}
+
+	for name := range branches {
+		stale := name == "update-tag/merged" || name == "update-tag/closed"
+		if exists := server.BranchExists(projectID, name); exists == stale {
+			t.Errorf("branch %s exists = %v, want %v", name, exists, !stale)
+		}
+	}
+}
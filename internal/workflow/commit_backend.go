@@ -0,0 +1,200 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitbackend"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// validateCommitBackend checks the configured commit backend and its signing key.
+// The git backend is refused up front when the git binary is missing, rather than
+// after the update branch was created.
+func validateCommitBackend(commitBackend, gpgKey string) error {
+	switch commitBackend {
+	case "", config.CommitBackendAPI:
+		if gpgKey != "" {
+			return errors.NewConfigError(fmt.Sprintf(
+				"the GitLab API cannot sign commits: --gpg-key requires --commit-backend=%s", config.CommitBackendGit))
+		}
+		return nil
+	case config.CommitBackendGit:
+		_, err := gitbackend.LookGit()
+		return err
+	default:
+		return errors.NewConfigError(fmt.Sprintf("invalid commit backend %q: expected %s or %s",
+			commitBackend, config.CommitBackendAPI, config.CommitBackendGit))
+	}
+}
+
+// commitFile commits the updated file to the branch with the configured commit backend
+func (stu *SimpleTagUpdater) commitFile(ctx context.Context, branchName, newContent string, reused bool) error {
+	if stu.config.CommitBackend == config.CommitBackendGit {
+		return stu.commitWithGit(ctx, branchName, newContent, reused)
+	}
+
+	_, err := stu.commits.CommitFiles(ctx, &gitlabapi.CommitOptions{
+		Branch:        branchName,
+		CommitMessage: stu.commitMessage(),
+		Changes:       stu.commitChanges(newContent),
+	})
+	return err
+}
+
+// prefetchFiles fetches existence and content of the files from the source ref. The
+// git backend reads them from a shallow clone, which is kept to commit the update
+// from; all missing files are reported together in one file-not-found error.
+func (stu *SimpleTagUpdater) prefetchFiles(
+	ctx context.Context,
+	filePaths []string,
+) (map[string]*gitlabapi.PrefetchedFile, error) {
+	if stu.config.CommitBackend != config.CommitBackendGit {
+		return stu.fileManager.PrefetchFiles(ctx, filePaths, stu.sourceRef(), gitlabapi.DefaultPrefetchConcurrency)
+	}
+
+	backend, err := stu.gitCommitBackend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Every fetch starts from the latest source ref, such as when a merge request is recreated
+	stu.closeCheckout()
+	if stu.checkout, err = backend.Clone(ctx, stu.sourceRef()); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*gitlabapi.PrefetchedFile, len(filePaths))
+	var missing []string
+	for _, filePath := range filePaths {
+		content, err := stu.checkout.ReadFile(filePath)
+		if errors.GetErrorCode(err) == errors.ErrCodeFileNotFound {
+			files[filePath] = &gitlabapi.PrefetchedFile{FilePath: filePath}
+			missing = append(missing, filePath)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[filePath] = &gitlabapi.PrefetchedFile{FilePath: filePath, Exists: true, Content: string(content)}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return files, errors.NewAppError(errors.ErrCodeFileNotFound, errors.CategoryFile,
+			fmt.Sprintf("%d file(s) not found in %s: %s", len(missing), stu.sourceRef(), strings.Join(missing, ", ")))
+	}
+	return files, nil
+}
+
+// commitWithGit commits the updated file to the branch from a local clone, signed
+// with the configured GPG key. A new branch gets the commit on top of the source ref
+// clone the file was read from; a reused branch is cloned itself.
+func (stu *SimpleTagUpdater) commitWithGit(ctx context.Context, branchName, newContent string, reused bool) error {
+	backend, err := stu.gitCommitBackend(ctx)
+	if err != nil {
+		return err
+	}
+
+	var commitID string
+	if stu.checkout != nil && !reused {
+		commitID, err = stu.commitCheckout(ctx, stu.checkout, branchName, newContent)
+	} else {
+		commitID, err = stu.commitBranchClone(ctx, backend, branchName, newContent)
+	}
+	if err != nil {
+		return err
+	}
+
+	stu.logger.WithFields(map[string]interface{}{
+		"branch_name": branchName,
+		"commit_id":   commitID,
+		"signed":      stu.config.GPGKey != "",
+	}).Debug("Commit pushed from local clone")
+	return nil
+}
+
+// commitCheckout commits the updated files in a clone and pushes them to the branch
+func (stu *SimpleTagUpdater) commitCheckout(
+	ctx context.Context,
+	checkout *gitbackend.Checkout,
+	branchName, newContent string,
+) (string, error) {
+	for _, change := range stu.commitChanges(newContent) {
+		if err := checkout.WriteFile(ctx, change.FilePath, []byte(change.Content)); err != nil {
+			return "", err
+		}
+	}
+	commitID, err := checkout.Commit(ctx, stu.commitMessage())
+	if err != nil {
+		return "", err
+	}
+	if err := checkout.Push(ctx, branchName); err != nil {
+		return "", err
+	}
+	return commitID, nil
+}
+
+// commitBranchClone commits the updated files on top of the branch in a fresh clone of it
+func (stu *SimpleTagUpdater) commitBranchClone(
+	ctx context.Context,
+	backend *gitbackend.Backend,
+	branchName, newContent string,
+) (string, error) {
+	checkout, err := backend.Clone(ctx, branchName)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = checkout.Close() }()
+	return stu.commitCheckout(ctx, checkout, branchName, newContent)
+}
+
+// gitCommitBackend returns the git backend of the project, creating it on first use
+func (stu *SimpleTagUpdater) gitCommitBackend(ctx context.Context) (*gitbackend.Backend, error) {
+	if stu.gitBackend != nil {
+		return stu.gitBackend, nil
+	}
+
+	projects := gitlabapi.NewProjectManagerWithAPI(stu.api)
+	project, err := projects.GetProjectInfo(ctx, stu.projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyURL, err := TransportOptions(stu.config).Proxy.AuthenticatedURL()
+	if err != nil {
+		return nil, err
+	}
+	username := gitbackend.DefaultUsername
+	if gitlabapi.AuthMode(stu.config.AuthMode) == gitlabapi.AuthModeJob {
+		username = gitbackend.JobTokenUsername
+	}
+	stu.gitBackend, err = gitbackend.New(gitbackend.Options{
+		RemoteURL:  project.HTTPURLToRepo,
+		Username:   username,
+		Token:      stu.config.GitLabToken,
+		SigningKey: stu.config.GPGKey,
+
+		CACertFile:            stu.config.CACert,
+		ClientCertFile:        stu.config.ClientCert,
+		ClientKeyFile:         stu.config.ClientKey,
+		InsecureSkipTLSVerify: stu.config.InsecureSkipTLSVerify,
+		ProxyURL:              proxyURL,
+		NoProxy:               stu.config.NoProxy,
+	})
+	return stu.gitBackend, err
+}
+
+// closeCheckout removes the clone of the source ref, if any
+func (stu *SimpleTagUpdater) closeCheckout() {
+	if stu.checkout == nil {
+		return
+	}
+	if err := stu.checkout.Close(); err != nil {
+		stu.logger.WithError(err).Warn("Failed to remove the local clone")
+	}
+	stu.checkout = nil
+}
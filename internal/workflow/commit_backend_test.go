@@ -0,0 +1,112 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestValidateCommitBackend(t *testing.T) {
+	tests := []struct {
+		backend string
+		gpgKey  string
+		wantErr bool
+	}{
+		{backend: ""},
+		{backend: config.CommitBackendAPI},
+		{backend: config.CommitBackendGit},
+		{backend: config.CommitBackendGit, gpgKey: "ABCDEF0123456789"},
+		{backend: config.CommitBackendAPI, gpgKey: "ABCDEF0123456789", wantErr: true},
+		{backend: "svn", wantErr: true},
+	}
+
+	// The git backend is refused where no git binary is installed
+	_, gitErr := exec.LookPath("git")
+	for _, tt := range tests {
+		if tt.backend == config.CommitBackendGit {
+			tt.wantErr = gitErr != nil
+		}
+		err := validateCommitBackend(tt.backend, tt.gpgKey)
+		if tt.wantErr && errors.GetErrorCode(err) != errors.ErrCodeConfiguration {
+			t.Errorf("validateCommitBackend(%q, %q) = %v, want a configuration error", tt.backend, tt.gpgKey, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("validateCommitBackend(%q, %q) unexpected error: %v", tt.backend, tt.gpgKey, err)
+		}
+	}
+}
+
+func TestSimpleTagUpdater_GitCommitBackend(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not installed")
+	}
+
+	// A bare repository stands in for the project repository
+	const rawContent = "# Größe\nimage:\n  tag: v1.0.0\n"
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	work := filepath.Join(root, "work")
+	runGit := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, output)
+		}
+		return string(output)
+	}
+	runGit(root, "init", "--quiet", "--bare", remote)
+	runGit(root, "init", "--quiet", work)
+	if err := os.WriteFile(filepath.Join(work, TestFilePath), []byte(rawContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runGit(work, "add", ".")
+	runGit(work, "commit", "--quiet", "--no-gpg-sign", "--message", "Initial commit")
+	runGit(work, "push", "--quiet", remote, "HEAD:refs/heads/"+TestTargetBranch)
+
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetRepositoryURL(projectID, "file://"+remote)
+
+	cfg := &config.CLIConfig{
+		ProjectID:     TestProjectID,
+		GitLabToken:   TestGitLabToken,
+		FilePath:      TestFilePath,
+		NewTag:        TestNewTag,
+		TargetBranch:  TestTargetBranch,
+		BranchName:    TestBranchName,
+		CommitBackend: config.CommitBackendGit,
+	}
+	updater := newTestUpdater(t, server, projectID, cfg)
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success || result.MergeRequest == nil {
+		t.Fatalf("Execute() = %+v, want a merge request", result)
+	}
+
+	want := strings.Replace(rawContent, TestOldTag, TestNewTag, 1)
+	if got := runGit(remote, "show", result.BranchName+":"+TestFilePath); got != want {
+		t.Errorf("pushed file = %q, want %q", got, want)
+	}
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "/repository/files/") || strings.HasSuffix(request, "/repository/commits") {
+			t.Errorf("git backend used the files API: %s", request)
+		}
+	}
+	if updater.checkout != nil {
+		t.Error("Execute() should remove the local clone")
+	}
+}
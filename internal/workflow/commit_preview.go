@@ -0,0 +1,120 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// CommitPatchExtension defines the extension of the dry run commit artifact
+	CommitPatchExtension = ".patch"
+	// patchFromLine starts a patch like git format-patch does; a dry run cannot know the commit ID
+	patchFromLine = "From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001"
+)
+
+// CommitPreview is the commit a dry run would make
+type CommitPreview struct {
+	Branch   string
+	Message  string
+	FilePath string
+	Diff     string
+
+	// BaseRef is the ref the branch would be created from and BaseCommit its head
+	// commit, empty when it could not be read
+	BaseRef    string
+	BaseCommit string
+
+	// CarriedCommits and CarriedFiles are the commits and files on BaseRef that the
+	// target branch lacks; the merge request would propose them as well
+	CarriedCommits []string
+	CarriedFiles   []string
+}
+
+// Patch renders the commit in the layout of git format-patch; git apply applies it
+func (p *CommitPreview) Patch() string {
+	subject, body, _ := strings.Cut(p.Message, "\n")
+
+	var b strings.Builder
+	b.WriteString(patchFromLine + "\n")
+	fmt.Fprintf(&b, "Subject: [PATCH] %s\n\n", subject)
+	if body = strings.TrimSpace(body); body != "" {
+		b.WriteString(body + "\n\n")
+	}
+
+	// Text between the separator and the diff is a note that git ignores
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "Branch: %s\n", p.Branch)
+	base := p.BaseRef
+	if p.BaseCommit != "" {
+		base += " at " + p.BaseCommit
+	}
+	fmt.Fprintf(&b, "Base: %s\n", base)
+	if len(p.CarriedCommits) > 0 {
+		fmt.Fprintf(&b, "The merge request also carries these commits of %s:\n", p.BaseRef)
+		for _, commit := range p.CarriedCommits {
+			fmt.Fprintf(&b, "  %s\n", commit)
+		}
+	}
+	if len(p.CarriedFiles) > 0 {
+		fmt.Fprintf(&b, "Files changed by them: %s\n", strings.Join(p.CarriedFiles, ", "))
+	}
+
+	fmt.Fprintf(&b, "\ndiff --git a/%s b/%s\n", p.FilePath, p.FilePath)
+	b.WriteString(p.Diff)
+	return b.String()
+}
+
+// previewCommit describes the commit the update would make on branchName. The base
+// commit and the changes the base ref carries over the target branch are read from
+// GitLab; failures to read them are logged and leave those fields empty.
+func (stu *SimpleTagUpdater) previewCommit(ctx context.Context, branchName, newContent string) *CommitPreview {
+	preview := &CommitPreview{
+		Branch:   branchName,
+		Message:  stu.commitMessage(),
+		FilePath: stu.config.FilePath,
+		Diff: diff.Unified("a/"+stu.config.FilePath, "b/"+stu.config.FilePath,
+			stu.originalContent, newContent, diff.DefaultContextLines),
+		BaseRef: stu.sourceRef(),
+	}
+
+	head, err := stu.fileManager.HeadCommit(ctx, preview.BaseRef)
+	if err != nil {
+		stu.logger.WithError(err).WithField("ref", preview.BaseRef).Warn("Failed to read the base commit")
+	} else {
+		preview.BaseCommit = head.ID
+	}
+
+	// The update starts from the target branch itself, or from the ref a missing
+	// target branch would be created from, so nothing else is carried
+	if preview.BaseRef == stu.config.TargetBranch || stu.bootstrapRef != "" {
+		return preview
+	}
+
+	compare, err := stu.fileManager.CompareRefs(ctx, stu.config.TargetBranch, preview.BaseRef)
+	if err != nil {
+		stu.logger.WithError(err).WithField("ref", preview.BaseRef).
+			Warn("Failed to compare the source ref with the target branch")
+		return preview
+	}
+	for _, commit := range compare.Commits {
+		preview.CarriedCommits = append(preview.CarriedCommits, commit.ShortID+" "+commit.Title)
+	}
+	for _, change := range compare.Diffs {
+		preview.CarriedFiles = append(preview.CarriedFiles, change.NewPath)
+	}
+	return preview
+}
+
+// writeCommitPatch writes the patch of a commit preview next to the preview artifact
+func writeCommitPatch(previewPath string, commit *CommitPreview) (string, error) {
+	patchPath := previewPath + CommitPatchExtension
+	if err := os.WriteFile(patchPath, []byte(commit.Patch()), TempFilePermissions); err != nil {
+		return "", errors.NewFileSystemError(fmt.Sprintf("failed to write commit artifact: %v", err))
+	}
+	return patchPath, nil
+}
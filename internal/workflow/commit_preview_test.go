@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+)
+
+func TestSimpleTagUpdater_DryRunCommitPreview(t *testing.T) {
+	const (
+		sourceRef   = "release"
+		carriedFile = "NOTES.md"
+	)
+
+	server, projectID := newTestProject(t)
+	server.AddBranch(projectID, sourceRef, TestTargetBranch, false)
+	server.SetFile(projectID, sourceRef, carriedFile, "release notes\n")
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		SourceRef:    sourceRef,
+		DryRun:       true,
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	defer os.RemoveAll(filepath.Dir(result.PreviewPath))
+
+	commit := result.Commit
+	if commit == nil {
+		t.Fatal("Execute() returned no commit preview")
+	}
+	if commit.Branch != TestBranchName || commit.Message != updater.commitMessage() ||
+		commit.BaseRef != sourceRef || commit.BaseCommit == "" {
+		t.Errorf("commit preview = %+v", commit)
+	}
+	if len(commit.CarriedCommits) != 1 || len(commit.CarriedFiles) != 1 || commit.CarriedFiles[0] != carriedFile {
+		t.Errorf("carried commits = %v, files = %v, want the commit adding %s", commit.CarriedCommits,
+			commit.CarriedFiles, carriedFile)
+	}
+
+	patch, err := os.ReadFile(result.PatchPath)
+	if err != nil {
+		t.Fatalf("failed to read commit artifact: %v", err)
+	}
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	for _, want := range []string{"Subject: [PATCH] " + subject, "Branch: " + TestBranchName,
+		"diff --git a/" + TestFilePath, "+  tag: " + TestNewTag} {
+		if !strings.Contains(string(patch), want) {
+			t.Errorf("commit artifact does not contain %q:\n%s", want, patch)
+		}
+	}
+
+	if server.BranchExists(projectID, TestBranchName) || len(server.MergeRequests(projectID)) != 0 {
+		t.Error("Execute() changed the project in dry run mode")
+	}
+}
@@ -0,0 +1,111 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/clock"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+)
+
+const (
+	// ConcurrentRunGraceWindow is how long after its update commit a branch without a
+	// merge request is assumed to belong to another runner still working on it
+	ConcurrentRunGraceWindow = 2 * time.Minute
+	// ConcurrentRunCheckInterval is how often the merge request of such a runner is looked up
+	ConcurrentRunCheckInterval = 5 * time.Second
+)
+
+// awaitConcurrentRun handles a reused update branch that has no merge request yet.
+// When the branch head is the update commit of this same update and younger than the
+// grace window, another runner is mid-flight between committing and opening its merge
+// request. Its merge request is awaited and returned for adoption instead of racing
+// it with a second one. nil is returned when there is no such runner, or when it did
+// not open a merge request within the window and is presumed dead.
+func (stu *SimpleTagUpdater) awaitConcurrentRun(
+	ctx context.Context,
+	branch *gitlabapi.BranchInfo,
+) (*gitlab.BasicMergeRequest, error) {
+	if branch.Commit == nil || branch.Commit.Title != stu.mergeRequestTitle() {
+		return nil, nil
+	}
+
+	committed := branch.Commit.CommittedDate
+	if committed == nil {
+		committed = branch.Commit.CreatedAt
+	}
+	if committed == nil {
+		return nil, nil
+	}
+
+	deadline := committed.Add(stu.concurrentRunWindow)
+	if !stu.now().Before(deadline) {
+		return nil, nil
+	}
+
+	runLog := stu.logger.WithFields(map[string]interface{}{
+		"branch_name":     branch.Name,
+		"commit":          branch.Commit.ShortID,
+		"idempotency_key": stu.idempotencyKey,
+		"wait_until":      clock.In(deadline).Format(time.RFC3339),
+	})
+	runLog.Info("Another run of the same update is in flight, waiting for its merge request")
+
+	report, finish := stu.startProgress(ProgressConcurrentRun)
+	for {
+		mr, err := stu.findMergeRequestForBranch(ctx, branch.Name)
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		if mr != nil && stu.isSameUpdate(mr) {
+			finish(nil)
+			runLog.WithField("mr_id", mr.IID).Info("Adopting the merge request of the concurrent run")
+			return mr, nil
+		}
+
+		wait := minDuration(stu.concurrentRunInterval, deadline.Sub(stu.now()))
+		if wait <= 0 {
+			finish(nil)
+			runLog.Warn("Concurrent run opened no merge request in time, continuing with this run")
+			return nil, nil
+		}
+		report(fmt.Sprintf("no merge request on %s yet", branch.Name))
+
+		select {
+		case <-ctx.Done():
+			finish(ctx.Err())
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// adoptAfterCreateFailure looks for a merge request another runner opened for the
+// branch after creating one failed, such as when both runs raced to open it
+func (stu *SimpleTagUpdater) adoptAfterCreateFailure(
+	ctx context.Context,
+	branchName string,
+) *gitlab.BasicMergeRequest {
+	mr, err := stu.findMergeRequestForBranch(ctx, branchName)
+	if err != nil || mr == nil || !stu.isSameUpdate(mr) {
+		return nil
+	}
+
+	stu.logger.WithFields(map[string]interface{}{
+		"branch_name": branchName,
+		"mr_id":       mr.IID,
+	}).Info("Merge request was opened by a concurrent run of the same update, adopting it")
+	return mr
+}
+
+// minDuration returns the shorter of two durations
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
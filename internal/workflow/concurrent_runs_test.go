@@ -0,0 +1,88 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+func TestSimpleTagUpdater_ConcurrentRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		openMR bool
+	}{
+		{name: "adopts the merge request of the other run", openMR: true},
+		{name: "continues when the other run opens none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			cfg := &config.CLIConfig{
+				ProjectID:    TestProjectID,
+				GitLabToken:  TestGitLabToken,
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+			}
+			updater := newTestUpdater(t, server, projectID, cfg)
+			updater.concurrentRunWindow = time.Second
+			updater.concurrentRunInterval = time.Millisecond
+			if !tt.openMR {
+				updater.concurrentRunWindow = 20 * time.Millisecond
+			}
+
+			// Another runner created the branch and committed, but has not opened its merge request yet
+			client := server.Client()
+			server.AddBranch(projectID, TestBranchName, TestTargetBranch, false)
+			_, _, err := client.RepositoryFiles.UpdateFile(projectID, TestFilePath, &gitlab.UpdateFileOptions{
+				Branch:        gitlab.Ptr(TestBranchName),
+				Content:       gitlab.Ptr(TestYAMLContentUpdated),
+				CommitMessage: gitlab.Ptr(identity.WithCommitTrailer(updater.mergeRequestTitle(), "other-run")),
+			})
+			if err != nil {
+				t.Fatalf("Failed to commit as the other run: %v", err)
+			}
+
+			opened := make(chan error, 1)
+			go func() {
+				if !tt.openMR {
+					opened <- nil
+					return
+				}
+				time.Sleep(20 * time.Millisecond)
+				_, _, err := client.MergeRequests.CreateMergeRequest(projectID, &gitlab.CreateMergeRequestOptions{
+					Title:        gitlab.Ptr(updater.mergeRequestTitle()),
+					Description:  gitlab.Ptr(updater.mergeRequestDescription(TestBranchName)),
+					SourceBranch: gitlab.Ptr(TestBranchName),
+					TargetBranch: gitlab.Ptr(TestTargetBranch),
+				})
+				opened <- err
+			}()
+
+			result, err := updater.Execute(context.Background())
+			if openErr := <-opened; openErr != nil {
+				t.Fatalf("Failed to open the merge request of the other run: %v", openErr)
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+
+			mrs := server.MergeRequests(projectID)
+			if len(mrs) != 1 || result.MergeRequest == nil || result.MergeRequest.IID != mrs[0].IID {
+				t.Errorf("merge requests = %d, result MR %+v, want exactly one", len(mrs), result.MergeRequest)
+			}
+			if adopted := strings.HasPrefix(result.Message, "Reused"); adopted != tt.openMR {
+				t.Errorf("Execute() message = %q, adopted = %v, want %v", result.Message, adopted, tt.openMR)
+			}
+		})
+	}
+}
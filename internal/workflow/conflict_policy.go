@@ -0,0 +1,136 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// validateConflictPolicy checks the configured reaction to conflicting merge requests
+func validateConflictPolicy(conflictPolicy string) error {
+	switch conflictPolicy {
+	case "", config.ConflictPolicyFail, config.ConflictPolicyWait, config.ConflictPolicyForce, config.ConflictPolicyQueue:
+		return nil
+	default:
+		return errors.NewConfigError(fmt.Sprintf("invalid conflict policy %q: expected %s, %s, %s or %s",
+			conflictPolicy, config.ConflictPolicyFail, config.ConflictPolicyWait,
+			config.ConflictPolicyForce, config.ConflictPolicyQueue))
+	}
+}
+
+// applyConflictPolicy looks for other open merge requests to the target branch that
+// change the same file and reacts as the conflict policy says: fail the run, wait for
+// them to close, proceed anyway, or queue the merge request until they close. Merge
+// requests from branchName belong to this same update and are ignored. It runs only
+// with same-file conflict detection enabled.
+func (stu *SimpleTagUpdater) applyConflictPolicy(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	branchName string,
+) error {
+	conflicts, err := stu.findConflictingMergeRequests(ctx, branchName)
+	if err != nil || conflicts.TotalConflicts == 0 {
+		return err
+	}
+
+	iids := make([]string, 0, conflicts.TotalConflicts)
+	for _, mr := range conflicts.ConflictingMRs {
+		result.ConflictingMergeRequests = append(result.ConflictingMergeRequests, mr.IID)
+		iids = append(iids, fmt.Sprintf("!%d", mr.IID))
+	}
+	conflictLog := stu.logger.WithFields(map[string]interface{}{
+		"file_path":       stu.config.FilePath,
+		"conflicting_mrs": strings.Join(iids, ", "),
+		"conflict_policy": stu.config.ConflictPolicy,
+	})
+
+	switch stu.config.ConflictPolicy {
+	case config.ConflictPolicyForce:
+		conflictLog.Warn("Other open merge requests change the same file; proceeding anyway")
+		return nil
+	case config.ConflictPolicyQueue:
+		if stu.config.DryRun {
+			conflictLog.Info("Dry run mode: would open the merge request once the conflicting ones close")
+			return nil
+		}
+		conflictLog.Info("Merge request queued until the conflicting ones close")
+		stu.queuedConflicts = conflicts
+		return nil
+	case config.ConflictPolicyWait:
+		if stu.config.DryRun {
+			conflictLog.Info("Dry run mode: would wait for the conflicting merge requests to close")
+			return nil
+		}
+		return stu.waitForConflicts(ctx, conflicts, conflictLog)
+	default:
+		conflictLog.Error("Other open merge requests change the same file")
+		return errors.NewMergeConflictError(fmt.Sprintf(
+			"open merge requests %s also change %s; merge or close them first, or pass --conflict-policy=%s, %s or %s",
+			strings.Join(iids, ", "), stu.config.FilePath,
+			config.ConflictPolicyWait, config.ConflictPolicyQueue, config.ConflictPolicyForce))
+	}
+}
+
+// findConflictingMergeRequests returns the open merge requests of other branches that
+// change the updated file
+func (stu *SimpleTagUpdater) findConflictingMergeRequests(
+	ctx context.Context,
+	branchName string,
+) (*gitlabapi.ConflictInfo, error) {
+	info, err := stu.conflicts.CheckForConflicts(ctx, branchName, stu.config.TargetBranch, stu.config.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for conflicting merge requests: %w", err)
+	}
+
+	conflicts := &gitlabapi.ConflictInfo{}
+	for _, mr := range info.ConflictingMRs {
+		if mr.ConflictType == gitlabapi.ConflictTypeSameFile && mr.SourceBranch != branchName {
+			conflicts.ConflictingMRs = append(conflicts.ConflictingMRs, mr)
+		}
+	}
+	conflicts.TotalConflicts = len(conflicts.ConflictingMRs)
+	return conflicts, nil
+}
+
+// awaitQueuedConflicts waits for the merge requests the queue policy postponed this
+// merge request for; it returns at once when nothing was queued
+func (stu *SimpleTagUpdater) awaitQueuedConflicts(ctx context.Context, branchName string) error {
+	if stu.queuedConflicts == nil {
+		return nil
+	}
+	conflicts := stu.queuedConflicts
+	stu.queuedConflicts = nil
+
+	return stu.waitForConflicts(ctx, conflicts, stu.logger.WithField("branch_name", branchName))
+}
+
+// waitForConflicts waits up to the conflict timeout for conflicting merge requests to close
+func (stu *SimpleTagUpdater) waitForConflicts(
+	ctx context.Context,
+	conflicts *gitlabapi.ConflictInfo,
+	conflictLog *logrus.Entry,
+) error {
+	conflictLog.WithField("timeout", stu.config.ConflictTimeout.String()).
+		Info("Waiting for conflicting merge requests to close")
+
+	report, finish := stu.startProgress(ProgressConflicts)
+	stu.conflicts.SetProgress(report)
+	err := stu.conflicts.WaitForConflictsToResolve(ctx, conflicts, stu.config.ConflictTimeout)
+	finish(err)
+	if ctx.Err() != nil {
+		return fmt.Errorf("stopped waiting for conflicting merge requests: %w", ctx.Err())
+	}
+	if err != nil {
+		conflictLog.WithError(err).Error("Conflicting merge requests did not close")
+		return errors.NewMergeConflictError(fmt.Sprintf("conflicting merge requests did not close: %v", err))
+	}
+
+	conflictLog.Info("Conflicting merge requests closed")
+	return nil
+}
@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// conflictClosingHook closes a merge request when the update phase starts, after the
+// conflict policy looked for conflicting merge requests
+type conflictClosingHook struct {
+	mrManager *gitlabapi.SimpleMergeRequestManager
+	mrIID     int
+}
+
+func (h *conflictClosingHook) OnPhaseStart(ctx context.Context, phase Phase) {
+	if phase == PhaseUpdate {
+		_, _ = h.mrManager.CloseMergeRequest(ctx, h.mrIID, "")
+	}
+}
+
+func (h *conflictClosingHook) OnPhaseEnd(context.Context, Phase, error) {}
+
+func TestSimpleTagUpdater_ConflictPolicy(t *testing.T) {
+	const otherBranch = "update-tag/v1.1.0"
+
+	tests := []struct {
+		name          string
+		policy        string
+		disabled      bool
+		closeConflict bool
+		keepBranch    bool
+		wantErr       bool
+		wantBranch    bool
+		wantMRs       int
+	}{
+		{name: "fail by default", wantErr: true, wantMRs: 1},
+		{name: "detection disabled", disabled: true, wantBranch: true, wantMRs: 2},
+		{name: "force", policy: config.ConflictPolicyForce, wantBranch: true, wantMRs: 2},
+		{name: "wait times out", policy: config.ConflictPolicyWait, wantErr: true, wantMRs: 1},
+		{name: "queue times out", policy: config.ConflictPolicyQueue, wantErr: true, wantMRs: 1},
+		{name: "queue times out keeping the branch", policy: config.ConflictPolicyQueue, keepBranch: true,
+			wantErr: true, wantBranch: true, wantMRs: 1},
+		{name: "queue opens after the conflict closed", policy: config.ConflictPolicyQueue, closeConflict: true,
+			wantBranch: true, wantMRs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.AddBranch(projectID, otherBranch, TestTargetBranch, false)
+			server.SetFile(projectID, otherBranch, TestFilePath, strings.Replace(TestYAMLContent, TestOldTag, "v1.1.0", 1))
+
+			mrManager := gitlabapi.NewSimpleMergeRequestManager(server.Client(), projectID)
+			conflicting, err := mrManager.CreateMergeRequest(context.Background(), &gitlabapi.SimpleMergeRequestOptions{
+				Title:        "Update tag to v1.1.0",
+				SourceBranch: otherBranch,
+				TargetBranch: TestTargetBranch,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create conflicting merge request: %v", err)
+			}
+
+			cfg := &config.CLIConfig{
+				ProjectID:          TestProjectID,
+				GitLabToken:        TestGitLabToken,
+				FilePath:           TestFilePath,
+				NewTag:             TestNewTag,
+				TargetBranch:       TestTargetBranch,
+				BranchName:         TestBranchName,
+				ConflictPolicy:     tt.policy,
+				CheckFileConflicts: !tt.disabled,
+				ConflictTimeout:    20 * time.Millisecond,
+
+				KeepBranchOnFailure: tt.keepBranch,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+			updater.conflicts.SetInterval(time.Millisecond)
+			if tt.closeConflict {
+				updater.AddPhaseHook(&conflictClosingHook{mrManager: mrManager, mrIID: conflicting.IID})
+			}
+
+			result, err := updater.Execute(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && errors.ExitCode(err) != errors.ExitCodeConflict {
+				t.Errorf("Execute() exit code = %d, want %d", errors.ExitCode(err), errors.ExitCodeConflict)
+			}
+
+			wantConflicts := []int{conflicting.IID}
+			if tt.disabled {
+				wantConflicts = nil
+			}
+			if fmt.Sprint(result.ConflictingMergeRequests) != fmt.Sprint(wantConflicts) {
+				t.Errorf("ConflictingMergeRequests = %v, want %v", result.ConflictingMergeRequests, wantConflicts)
+			}
+			if got := server.BranchExists(projectID, TestBranchName); got != tt.wantBranch {
+				t.Errorf("branch exists = %v, want %v", got, tt.wantBranch)
+			}
+			if got := len(server.MergeRequests(projectID)); got != tt.wantMRs {
+				t.Errorf("merge requests = %d, want %d", got, tt.wantMRs)
+			}
+		})
+	}
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+	for _, policy := range []string{"", config.ConflictPolicyFail, config.ConflictPolicyWait,
+		config.ConflictPolicyForce, config.ConflictPolicyQueue} {
+		if err := validateConflictPolicy(policy); err != nil {
+			t.Errorf("validateConflictPolicy(%q) unexpected error: %v", policy, err)
+		}
+	}
+	if err := validateConflictPolicy("retry"); errors.GetErrorCode(err) != errors.ErrCodeConfiguration {
+		t.Errorf("validateConflictPolicy(retry) = %v, want a configuration error", err)
+	}
+}
@@ -0,0 +1,234 @@
+package workflow
+
+import (
+	"context"
+	"crypto/tls"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+)
+
+// Doctor checks, in the order they run
+const (
+	DoctorCheckConnect = "connect"
+	DoctorCheckTLS     = "tls"
+	DoctorCheckToken   = "token"
+	DoctorCheckProject = "project"
+	DoctorCheckFile    = "file"
+	DoctorCheckYAML    = "yaml"
+)
+
+const (
+	// DoctorConnectTimeout bounds the request that checks connectivity and TLS
+	DoctorConnectTimeout = 10 * time.Second
+	// DoctorCertExpiryWarning is how long before its expiry the certificate of the
+	// instance is reported as a warning
+	DoctorCertExpiryWarning = 14 * 24 * time.Hour
+	// doctorDateFormat formats certificate expiry dates
+	doctorDateFormat = "2006-01-02"
+)
+
+// doctor holds the state the checks hand on to each other
+type doctor struct {
+	cfg     *config.CLIConfig
+	baseURL string
+	now     time.Time
+
+	// Outcome of the TLS handshake of the connectivity check
+	tlsState *tls.ConnectionState
+	tlsErr   error
+
+	client    *gitlabapi.Client
+	projectID int
+	ref       string
+	content   string
+}
+
+// RunDoctor diagnoses the environment an update runs in: connectivity to the GitLab
+// instance, its TLS certificate, the token, the project, the file and whether it
+// parses. Each check is reported as PASS, WARN, FAIL or SKIP; checks after a failure
+// are skipped, as are the project and file checks when they are not configured.
+func RunDoctor(ctx context.Context, cfg *config.CLIConfig) *config.ValidationReport {
+	d := &doctor{cfg: cfg, baseURL: cfg.GitLabURL, now: time.Now()}
+	if d.baseURL == "" {
+		d.baseURL = gitlabapi.DefaultGitLabURL
+	}
+
+	checks := []struct {
+		name string
+		run  func(context.Context) (string, string)
+	}{
+		{DoctorCheckConnect, d.connect},
+		{DoctorCheckTLS, d.checkTLS},
+		{DoctorCheckToken, d.checkToken},
+		{DoctorCheckProject, d.checkProject},
+		{DoctorCheckFile, d.checkFile},
+		{DoctorCheckYAML, d.checkYAML},
+	}
+
+	report := &config.ValidationReport{}
+	failed := false
+	for _, check := range checks {
+		if failed {
+			report.Add(check.name, config.CheckSkip, "skipped after a failed check")
+			continue
+		}
+		status, detail := check.run(ctx)
+		report.Add(check.name, status, detail)
+		failed = status == config.CheckFail
+	}
+	return report
+}
+
+// connect requests the instance URL. A certificate that does not verify still proves
+// the instance reachable; the TLS check reports it.
+func (d *doctor) connect(ctx context.Context) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, DoctorConnectTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL, http.NoBody)
+	if err != nil {
+		return config.CheckFail, fmt.Sprintf("invalid GitLab URL %s: %v", d.baseURL, err)
+	}
+	transport, err := gitlabapi.NewTransport(TransportOptions(d.cfg))
+	if err != nil {
+		return config.CheckFail, err.Error()
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	var certErr *tls.CertificateVerificationError
+	if stderrors.As(err, &certErr) {
+		d.tlsErr = certErr
+		return config.CheckPass, "reached " + req.URL.Host
+	}
+	if err != nil {
+		return config.CheckFail, fmt.Sprintf("cannot reach %s: %v", d.baseURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	d.tlsState = resp.TLS
+	return config.CheckPass, fmt.Sprintf("%s responded %s", d.baseURL, resp.Status)
+}
+
+// checkTLS reports a certificate that does not verify or expires soon, and warns
+// about plain HTTP, which sends the token unencrypted
+func (d *doctor) checkTLS(_ context.Context) (string, string) {
+	if d.tlsErr != nil {
+		return config.CheckFail, fmt.Sprintf("%v; pass the CA of the instance with --ca-cert", d.tlsErr)
+	}
+	if d.tlsState == nil || len(d.tlsState.PeerCertificates) == 0 {
+		return config.CheckWarn, "plain HTTP: the token is sent unencrypted"
+	}
+	if d.cfg.InsecureSkipTLSVerify {
+		return config.CheckWarn, "certificate verification is disabled by --insecure-skip-tls-verify"
+	}
+
+	cert := d.tlsState.PeerCertificates[0]
+	detail := fmt.Sprintf("%s certificate for %s issued by %s, valid until %s",
+		tls.VersionName(d.tlsState.Version), cert.Subject.CommonName, cert.Issuer.CommonName,
+		cert.NotAfter.Format(doctorDateFormat))
+	if cert.NotAfter.Sub(d.now) < DoctorCertExpiryWarning {
+		return config.CheckWarn, detail + "; renew it soon"
+	}
+	return config.CheckPass, detail
+}
+
+// checkToken verifies the token and its scopes like config validate --online does
+func (d *doctor) checkToken(ctx context.Context) (string, string) {
+	cfg := *d.cfg
+	cfg.GitLabURL = d.baseURL
+	cfg.ProjectID = ""
+	detail, err := CheckToken(ctx, &cfg)
+	if err != nil {
+		return config.CheckFail, err.Error()
+	}
+
+	if d.client, err = newGitLabClient(d.cfg, d.baseURL); err != nil {
+		return config.CheckFail, fmt.Sprintf("failed to create GitLab client: %v", err)
+	}
+	return config.CheckPass, detail
+}
+
+// checkProject resolves the project and checks the role of the token in it
+func (d *doctor) checkProject(ctx context.Context) (string, string) {
+	if d.cfg.ProjectID == "" {
+		return config.CheckSkip, "no project configured"
+	}
+
+	var err error
+	if d.projectID, err = d.client.ResolveProjectID(ctx, d.cfg.ProjectID); err != nil {
+		return config.CheckFail, fmt.Sprintf("failed to resolve project %s: %v", d.cfg.ProjectID, err)
+	}
+	api := gitlabapi.NewAPIAdapter(d.client.GetGitLabClient())
+	project, _, err := api.GetProject(d.projectID, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return config.CheckFail, fmt.Sprintf("failed to read project %s: %v", d.cfg.ProjectID, err)
+	}
+
+	// The token itself was checked before; only its role in the project is reported here
+	report := gitlabapi.NewPreflightCheckerWithAPI(api, d.projectID).CheckAccess(ctx, &gitlabapi.PreflightOptions{
+		CheckToken: gitlabapi.AuthMode(d.cfg.AuthMode) != gitlabapi.AuthModeJob,
+	})
+	problems := make([]string, 0, len(report.Problems))
+	for _, problem := range report.Problems {
+		if problem.Check != gitlabapi.PreflightCheckToken {
+			problems = append(problems, problem.String())
+		}
+	}
+	if len(problems) > 0 {
+		return config.CheckFail, strings.Join(problems, "; ")
+	}
+
+	d.ref = d.cfg.TargetBranch
+	if d.ref == "" {
+		d.ref = project.DefaultBranch
+	}
+	return config.CheckPass, fmt.Sprintf("%s (ID %d), default branch %s", project.PathWithNamespace, project.ID,
+		project.DefaultBranch)
+}
+
+// checkFile reads the file from the target branch, or the default branch when no
+// target branch is configured
+func (d *doctor) checkFile(ctx context.Context) (string, string) {
+	if d.cfg.FilePath == "" || d.projectID == 0 {
+		return config.CheckSkip, "no project and file configured"
+	}
+
+	fileManager := gitlabapi.NewFileManager(d.client.GetGitLabClient(), d.projectID)
+	content, err := fileManager.GetFileContent(ctx, d.cfg.FilePath, d.ref)
+	if err != nil {
+		return config.CheckFail, fmt.Sprintf("failed to read %s at %s: %v", d.cfg.FilePath, d.ref, err)
+	}
+	d.content = content
+	return config.CheckPass, fmt.Sprintf("%s at %s, %d bytes", d.cfg.FilePath, d.ref, len(content))
+}
+
+// checkYAML parses the file and reads the tag an update would change
+func (d *doctor) checkYAML(_ context.Context) (string, string) {
+	if d.content == "" {
+		return config.CheckSkip, "no file read"
+	}
+
+	docSelector, err := yaml.ParseDocumentSelector(d.cfg.DocSelector)
+	if err != nil {
+		return config.CheckFail, fmt.Sprintf("invalid document selector: %v", err)
+	}
+	field := tagField{filePath: d.cfg.FilePath, yamlPath: d.cfg.YAMLPath, docSelector: docSelector}
+	tag, err := field.value(d.content)
+	if err != nil {
+		return config.CheckFail, err.Error()
+	}
+
+	yamlPath := d.cfg.YAMLPath
+	if yamlPath == "" {
+		yamlPath = "detected tag field"
+	}
+	return config.CheckPass, fmt.Sprintf("%s is %s", yamlPath, tag)
+}
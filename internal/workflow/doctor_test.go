@@ -0,0 +1,79 @@
+package workflow
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+func TestRunDoctor(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, gitlabtest.DefaultBranch, TestFilePath, TestYAMLContent)
+	// A server with a self-signed certificate; its handshake errors are expected
+	tlsServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsServer.StartTLS()
+	t.Cleanup(tlsServer.Close)
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.CLIConfig)
+		want   []string
+	}{
+		{
+			name: "plain HTTP instance",
+			want: []string{config.CheckPass, config.CheckWarn, config.CheckPass, config.CheckPass, config.CheckPass,
+				config.CheckPass},
+		},
+		{
+			name:   "no project",
+			modify: func(cfg *config.CLIConfig) { cfg.ProjectID = "" },
+			want: []string{config.CheckPass, config.CheckWarn, config.CheckPass, config.CheckSkip, config.CheckSkip,
+				config.CheckSkip},
+		},
+		{
+			name:   "missing file",
+			modify: func(cfg *config.CLIConfig) { cfg.FilePath = "missing.yaml" },
+			want: []string{config.CheckPass, config.CheckWarn, config.CheckPass, config.CheckPass, config.CheckFail,
+				config.CheckSkip},
+		},
+		{
+			name:   "untrusted certificate",
+			modify: func(cfg *config.CLIConfig) { cfg.GitLabURL = tlsServer.URL },
+			want: []string{config.CheckPass, config.CheckFail, config.CheckSkip, config.CheckSkip, config.CheckSkip,
+				config.CheckSkip},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.CLIConfig{
+				ProjectID:   TestProjectID,
+				FilePath:    TestFilePath,
+				GitLabToken: TestGitLabToken,
+				AuthMode:    config.AuthModePAT,
+				GitLabURL:   server.URL(),
+			}
+			if tt.modify != nil {
+				tt.modify(cfg)
+			}
+
+			report := RunDoctor(context.Background(), cfg)
+			got := make([]string, 0, len(report.Checks))
+			for _, check := range report.Checks {
+				got = append(got, check.Status)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("RunDoctor() = %+v, want statuses %v", report.Checks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package workflow
+
+import (
+	stderrors "errors"
+
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+)
+
+// encodeDiagnostics returns the diagnostic bundle of an update whose YAML encoding
+// failed, whether a retry recovered or the update failed, or nil
+func encodeDiagnostics(result *yaml.UpdateResult, err error) *yaml.EncodeDiagnostics {
+	var encodeErr *yaml.EncodeError
+	if stderrors.As(err, &encodeErr) {
+		return encodeErr.Diagnostics
+	}
+	if result != nil {
+		return result.EncodeDiagnostics
+	}
+	return nil
+}
+
+// logEncodeDiagnostics writes the diagnostic bundle to the debug log, so users can
+// attach it to a bug report, and warns when a retry recovered from the failure
+func logEncodeDiagnostics(log *logger.Logger, filePath string, diagnostics *yaml.EncodeDiagnostics) {
+	if diagnostics == nil {
+		return
+	}
+
+	entry := log.WithField("file_path", filePath)
+	entry.WithField("encode_diagnostics", diagnostics.String()).Debug("YAML encoder diagnostic bundle")
+	if diagnostics.Recovered {
+		entry.WithField("attempts", diagnostics.Attempts).
+			Warn("YAML encoder failed; the document was re-encoded with normalized styles, review the diff")
+	}
+}
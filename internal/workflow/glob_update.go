@@ -0,0 +1,276 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// FileChangeCount is the number of values a glob update replaced in one file
+type FileChangeCount struct {
+	FilePath string
+	Changes  int
+}
+
+// ExecuteGlob replaces the old tag with the new one in every file of the target
+// branch matching --repo-path-glob, committing all changed files at once and
+// proposing them through a single merge request. Files that are not valid YAML
+// are skipped with a warning.
+func (stu *SimpleTagUpdater) ExecuteGlob(ctx context.Context) (*SimpleUpdateResult, error) {
+	result := &SimpleUpdateResult{RunID: stu.runID}
+	if err := validateGlobUpdate(stu.config.RepoPathGlob, stu.config.OldTag, stu.config.NewTag); err != nil {
+		return result, err
+	}
+	if err := stu.tagPolicy.Check(stu.config.OldTag, stu.config.NewTag); err != nil {
+		return result, err
+	}
+	stu.oldTag = stu.config.OldTag
+
+	stu.logger.WithFields(map[string]interface{}{
+		"repo_path_glob": stu.config.RepoPathGlob,
+		"old_tag":        stu.config.OldTag,
+		"new_tag":        stu.config.NewTag,
+		"project_id":     stu.config.ProjectID,
+		"run_id":         stu.runID,
+		"operation":      "glob_update_start",
+	}).Info("Starting tag update of matching files")
+
+	stu.startJournal()
+	result, err := stu.runGlob(ctx, result)
+	result.Steps = stu.steps
+	stu.removeOrphanBranch(ctx, result, err)
+	stu.finishJournal(err)
+	stu.notifyRun(ctx, result, err)
+	return result, err
+}
+
+// validateGlobUpdate checks the settings of a glob update
+func validateGlobUpdate(glob, oldTag, newTag string) error {
+	switch {
+	case glob == "":
+		return errors.NewValidationError("repo-path-glob is required")
+	case oldTag == "":
+		return errors.NewValidationError("old-tag is required with repo-path-glob")
+	case newTag == "":
+		return errors.NewValidationError("new-tag is required")
+	case oldTag == newTag:
+		return errors.NewValidationError("old-tag and new-tag are the same")
+	}
+	return nil
+}
+
+// runGlob finds the changes, then commits them and opens the merge request
+func (stu *SimpleTagUpdater) runGlob(ctx context.Context, result *SimpleUpdateResult) (*SimpleUpdateResult, error) {
+	endPhase := stu.beginPhase(ctx, PhaseValidate)
+	changes, err := stu.collectGlobChanges(ctx, result)
+	endPhase(err)
+	if err != nil {
+		return result, err
+	}
+	replaced := 0
+	for _, count := range result.FileChanges {
+		replaced += count.Changes
+	}
+	if len(changes) == 0 {
+		result.Success = true
+		result.Skipped = true
+		result.Message = fmt.Sprintf("No file matching %s references %s", stu.config.RepoPathGlob, stu.config.OldTag)
+		return result, nil
+	}
+
+	stu.idempotencyKey = computeIdempotencyKey(stu.projectID, stu.config.TargetBranch, stu.config.RepoPathGlob,
+		[]string{stu.config.OldTag}, stu.config.NewTag)
+	endPhase = stu.beginPhase(ctx, PhaseChecks)
+	err = stu.loadReleaseNotes(ctx)
+	endPhase(err)
+	if err != nil {
+		return result, err
+	}
+	branchName := stu.updateBranchName()
+	result.BranchName = branchName
+
+	if stu.config.DryRun {
+		result.Success = true
+		result.Message = fmt.Sprintf("Dry run completed. Would replace %d value(s) in %d file(s)",
+			replaced, len(changes))
+		return result, nil
+	}
+
+	endPhase = stu.beginPhase(ctx, PhaseUpdate)
+	result, err = stu.executeGlobUpdate(ctx, result, changes, replaced, branchName)
+	endPhase(err)
+	return result, err
+}
+
+// executeGlobUpdate commits the changes of a glob update to its branch and opens
+// the merge request
+func (stu *SimpleTagUpdater) executeGlobUpdate(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	changes []gitlabapi.FileChange,
+	replaced int,
+	branchName string,
+) (*SimpleUpdateResult, error) {
+	if err := stu.runPreUpdateHooks(ctx, result); err != nil {
+		return result, err
+	}
+	branch, reused, err := stu.createOrReuseBranch(ctx, branchName)
+	if err != nil {
+		return result, err
+	}
+	branchName = branch.Name
+	result.BranchName = branchName
+	result.BranchURL = branch.WebURL
+
+	if reused {
+		existing, findErr := stu.findMergeRequestForBranch(ctx, branchName)
+		if findErr != nil {
+			return result, findErr
+		}
+		if existing != nil {
+			result.Success = true
+			result.Message = fmt.Sprintf("Merge request !%d already makes this update", existing.IID)
+			return result, nil
+		}
+	}
+
+	// A reused branch may already carry the commit of an interrupted run
+	if !reused || branch.Commit == nil || branch.Commit.Title != stu.mergeRequestTitle() {
+		_, err = stu.commits.CommitFiles(ctx, &gitlabapi.CommitOptions{
+			Branch:        branchName,
+			CommitMessage: stu.commitMessage(),
+			Changes:       changes,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to commit %d file(s): %w", len(changes), err)
+		}
+	}
+	stu.reportCommit(ctx, result, branchName)
+	stu.runPostHooks(ctx, HookPostCommit, result)
+	result.FileUpdated = true
+
+	mrOpts := &gitlabapi.SimpleMergeRequestOptions{
+		Title:        stu.mergeRequestTitle(),
+		Description:  stu.globMergeRequestDescription(result.FileChanges, branchName),
+		SourceBranch: branchName,
+		TargetBranch: stu.config.TargetBranch,
+
+		Squash:                    stu.config.Squash,
+		RemoveSourceBranch:        stu.config.RemoveSourceBranch,
+		MergeWhenPipelineSucceeds: stu.config.AutoMerge,
+		Draft:                     stu.config.QuietRollout,
+		Labels:                    []string{identity.DefaultLabel},
+	}
+	mr, err := stu.mrManager.CreateMergeRequest(ctx, mrOpts)
+	if err != nil {
+		return result, fmt.Errorf("failed to create merge request: %w", err)
+	}
+	result.MergeRequest = mr
+	stu.recordMergeRequest(mr.IID)
+	stu.logger.WithFields(map[string]interface{}{
+		"mr_id":       mr.IID,
+		"mr_url":      mr.WebURL,
+		"branch_name": branchName,
+	}).Info("Merge request created successfully")
+	stu.commentOnIssues(ctx, mr)
+	stu.reviewApprovals(ctx, result, mr)
+	stu.runPostHooks(ctx, HookPostMR, result)
+
+	var autoMergeErr error
+	if mrOpts.MergeWhenPipelineSucceeds {
+		autoMergeErr = stu.enableAutoMerge(ctx, result, mrOpts)
+	}
+
+	result.Success = autoMergeErr == nil
+	result.Message = fmt.Sprintf("Tag update completed successfully. MR: !%d replaces %d value(s) in %d file(s)",
+		mr.IID, replaced, len(changes)) + approvalsNote(result.Approvals)
+	return result, autoMergeErr
+}
+
+// collectGlobChanges reads every file matching the glob on the target branch and
+// returns the updated content of the files referencing the old tag, recording the
+// number of replaced values of each in the result
+func (stu *SimpleTagUpdater) collectGlobChanges(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+) ([]gitlabapi.FileChange, error) {
+	files, err := stu.fileManager.ListFiles(ctx, stu.config.TargetBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []gitlabapi.FileChange
+	matched := 0
+	for _, filePath := range files {
+		if !policy.MatchGlob(stu.config.RepoPathGlob, filePath) {
+			continue
+		}
+		matched++
+
+		fileLog := stu.logger.WithField("file_path", filePath)
+		content, err := stu.fileManager.GetFileContent(ctx, filePath, stu.config.TargetBranch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file content of %s: %w", filePath, err)
+		}
+
+		updated, count, err := yaml.ReplaceTagValues(content, stu.config.OldTag, stu.config.NewTag)
+		if err != nil {
+			fileLog.WithError(err).Warn("Skipping file that cannot be updated")
+			continue
+		}
+		if count == 0 {
+			continue
+		}
+
+		if err := stu.policy.CheckFile(filePath); err != nil {
+			return nil, err
+		}
+		if err := stu.policy.CheckChange(content, updated); err != nil {
+			return nil, err
+		}
+		if err := checkSchema(stu.config.ValidateSchema, filePath, updated); err != nil {
+			return nil, err
+		}
+		if err := stu.runValidateCommands(ctx, filePath, updated); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, gitlabapi.FileChange{FilePath: filePath, Content: updated})
+		result.FileChanges = append(result.FileChanges, FileChangeCount{FilePath: filePath, Changes: count})
+		stu.fileDiffs = append(stu.fileDiffs, report.FileDiff{
+			Path:    filePath,
+			Changes: count,
+			Diff:    diff.Unified("a/"+filePath, "b/"+filePath, content, updated, diff.DefaultContextLines),
+		})
+		fileLog.WithField("changes", count).Info("Tag references found")
+	}
+
+	stu.logger.WithFields(map[string]interface{}{
+		"repo_path_glob": stu.config.RepoPathGlob,
+		"matched_files":  matched,
+		"changed_files":  len(changes),
+	}).Info("Matching files scanned")
+	return changes, nil
+}
+
+// globMergeRequestDescription returns the merge request description of a glob update
+// with the values replaced in each file
+func (stu *SimpleTagUpdater) globMergeRequestDescription(counts []FileChangeCount, branchName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Automated tag update from %s to %s\n\nFiles matching `%s`:\n\n",
+		stu.config.OldTag, stu.config.NewTag, stu.config.RepoPathGlob)
+	b.WriteString("| File | Changes |\n| --- | --- |\n")
+	for _, count := range counts {
+		fmt.Fprintf(&b, "| %s | %d |\n", count.FilePath, count.Changes)
+	}
+	fmt.Fprintf(&b, "\nBranch: %s\n\n", branchName)
+	return b.String() + stu.issueReferencesSection() + stu.releaseNotesSection() + stu.mergeRequestMarker()
+}
@@ -0,0 +1,97 @@
+package workflow
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+func TestSimpleTagUpdater_ExecuteGlob(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, "environments/prod/api/values.yaml",
+		"image:\n  repository: registry.example.com/api\n  tag: v1.0.0\n")
+	server.SetFile(projectID, TestTargetBranch, "environments/prod/jobs.yaml",
+		"jobs:\n  - image: registry.example.com/worker:v1.0.0\n  - image: registry.example.com/cron:v1.0.0\n")
+	server.SetFile(projectID, TestTargetBranch, "environments/prod/other.yaml", "image:\n  tag: v2.0.0\n")
+	server.SetFile(projectID, TestTargetBranch, "environments/prod/chart/templates/deploy.yaml", "tag: {{ .tag\n")
+	server.SetFile(projectID, TestTargetBranch, "environments/staging/values.yaml", "tag: v1.0.0\n")
+
+	newUpdater := func(t *testing.T, dryRun bool) *SimpleTagUpdater {
+		t.Helper()
+		return newTestUpdater(t, server, projectID, &config.CLIConfig{
+			RepoPathGlob: "environments/prod/**/*.yaml",
+			OldTag:       "v1.0.0",
+			NewTag:       TestNewTag,
+			TargetBranch: TestTargetBranch,
+			BranchName:   TestBranchName,
+			DryRun:       dryRun,
+		})
+	}
+	want := []FileChangeCount{
+		{FilePath: "environments/prod/api/values.yaml", Changes: 1},
+		{FilePath: "environments/prod/jobs.yaml", Changes: 2},
+	}
+
+	result, err := newUpdater(t, true).ExecuteGlob(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteGlob() dry run unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.FileChanges, want) || server.BranchExists(projectID, TestBranchName) {
+		t.Errorf("dry run changes = %+v, want %+v without a branch", result.FileChanges, want)
+	}
+
+	result, err = newUpdater(t, false).ExecuteGlob(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteGlob() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result.FileChanges, want) || result.MergeRequest == nil {
+		t.Fatalf("changes = %+v, merge request %v; want %+v and a merge request", result.FileChanges,
+			result.MergeRequest, want)
+	}
+
+	jobs, _ := server.File(projectID, TestBranchName, "environments/prod/jobs.yaml")
+	if strings.Count(jobs, ":"+TestNewTag) != 2 {
+		t.Errorf("jobs.yaml = %q, want both images updated", jobs)
+	}
+	staging, _ := server.File(projectID, TestBranchName, "environments/staging/values.yaml")
+	if staging != "tag: v1.0.0\n" {
+		t.Errorf("file outside the glob changed: %q", staging)
+	}
+
+	commits := 0
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, "POST ") && strings.HasSuffix(request, "/repository/commits") {
+			commits++
+		}
+	}
+	if commits != 1 {
+		t.Errorf("made %d commits, want 1", commits)
+	}
+
+	mrs := server.MergeRequests(projectID)
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "| environments/prod/jobs.yaml | 2 |") {
+		t.Errorf("merge requests = %+v, want one listing the changes per file", mrs)
+	}
+	metadata, ok := identity.ParseMarker(mrs[0].Description)
+	if !ok || metadata.File != "environments/prod/**/*.yaml" || metadata.OldTag != "v1.0.0" {
+		t.Errorf("metadata = %+v, want the glob and the old tag", metadata)
+	}
+	if !slices.Contains(mrs[0].Labels, identity.DefaultLabel) {
+		t.Errorf("labels = %v, want %s", mrs[0].Labels, identity.DefaultLabel)
+	}
+
+	var steps []Phase
+	for _, step := range result.Steps {
+		steps = append(steps, step.Name)
+	}
+	if !reflect.DeepEqual(steps, []Phase{PhaseValidate, PhaseChecks, PhaseUpdate}) {
+		t.Errorf("steps = %v, want validate, checks and update", steps)
+	}
+}
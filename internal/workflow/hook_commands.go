@@ -0,0 +1,120 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/Gosayram/go-tag-updater/internal/hooks"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+// Hook points running the configured local commands
+const (
+	// HookPreUpdate runs before the update branch is created or changed; a failing
+	// command stops the update. A target branch created by --create-target-branch
+	// already exists by then, since the file is read from it.
+	HookPreUpdate = "pre-update"
+	// HookPostCommit runs after the updated content was committed
+	HookPostCommit = "post-commit"
+	// HookPostMR runs after the merge request was opened or refreshed
+	HookPostMR = "post-mr"
+)
+
+// hookCommands returns the commands configured for a hook point
+func (stu *SimpleTagUpdater) hookCommands(point string) []string {
+	switch point {
+	case HookPreUpdate:
+		return stu.config.PreUpdateCommands
+	case HookPostCommit:
+		return stu.config.PostCommitCommands
+	case HookPostMR:
+		return stu.config.PostMRCommands
+	}
+	return nil
+}
+
+// runPreUpdateHooks runs the pre-update commands once per run, before the update
+// branch, commit or merge request is changed, and returns the error of the first
+// failing one. ensureTargetBranch runs earlier, so a missing target branch is
+// created before the hooks.
+func (stu *SimpleTagUpdater) runPreUpdateHooks(ctx context.Context, result *SimpleUpdateResult) error {
+	if stu.preUpdateHooksRan {
+		return nil
+	}
+	stu.preUpdateHooksRan = true
+	return stu.runHookCommands(ctx, HookPreUpdate, result)
+}
+
+// runPostHooks runs the commands of a hook point after a change. Failures are only
+// logged as warnings since the change itself succeeded.
+func (stu *SimpleTagUpdater) runPostHooks(ctx context.Context, point string, result *SimpleUpdateResult) {
+	_ = stu.runHookCommands(ctx, point, result) // Failures are logged, the change is done
+}
+
+// runHookCommands runs the commands of a hook point in order with the environment
+// describing the run, stopping at the first failing one. Dry runs change nothing,
+// so they run no hooks.
+func (stu *SimpleTagUpdater) runHookCommands(ctx context.Context, point string, result *SimpleUpdateResult) error {
+	commands := stu.hookCommands(point)
+	if len(commands) == 0 {
+		return nil
+	}
+	if stu.config.DryRun {
+		stu.logger.WithField("hook", point).Debug("Dry run mode: skipping hook commands")
+		return nil
+	}
+
+	env := stu.hookEnv(point, result)
+	for _, command := range commands {
+		hookLog := stu.logger.WithFields(map[string]interface{}{
+			"hook":         point,
+			"hook_command": command,
+		})
+		output, err := hooks.Run(ctx, command, env)
+		if output != nil {
+			hookLog = hookLog.WithFields(map[string]interface{}{
+				"exit_code": output.ExitCode,
+				"output":    logger.Redact(output.Output),
+				"duration":  output.Duration.String(),
+			})
+		}
+		if err != nil && point == HookPreUpdate {
+			hookLog.WithError(err).Error("Hook command failed, stopping the update")
+			return err
+		}
+		if err != nil {
+			hookLog.WithError(err).Warn("Hook command failed")
+			return err
+		}
+		hookLog.Info("Hook command completed")
+	}
+	return nil
+}
+
+// hookEnv returns the environment variables describing the run to hook commands
+func (stu *SimpleTagUpdater) hookEnv(point string, result *SimpleUpdateResult) []string {
+	file, oldTag := stu.config.FilePath, stu.oldTag
+	if stu.config.RepoPathGlob != "" {
+		file, oldTag = stu.config.RepoPathGlob, stu.config.OldTag
+	}
+	mrURL := ""
+	if result != nil && result.MergeRequest != nil {
+		mrURL = result.MergeRequest.WebURL
+	}
+	branchName, commitURL := "", ""
+	if result != nil {
+		branchName, commitURL = result.BranchName, result.CommitURL
+	}
+
+	return []string{
+		"HOOK=" + point,
+		"RUN_ID=" + stu.runID,
+		"PROJECT=" + stu.config.ProjectID,
+		"FILE=" + file,
+		"OLD_TAG=" + oldTag,
+		"NEW_TAG=" + stu.config.NewTag,
+		"TARGET_BRANCH=" + stu.config.TargetBranch,
+		"BRANCH=" + branchName,
+		"COMMIT_URL=" + commitURL,
+		"MR_URL=" + mrURL,
+	}
+}
@@ -0,0 +1,65 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSimpleTagUpdater_HookCommands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	t.Run("runs every hook point with the run environment", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "hooks.log")
+		record := `echo "$HOOK $PROJECT $FILE $OLD_TAG $NEW_TAG $MR_URL" >> '` + out + `'`
+		server, projectID := newTestProject(t)
+		cfg := testConfig()
+		cfg.PreUpdateCommands = []string{record}
+		cfg.PostCommitCommands = []string{"exit 1", record}
+		cfg.PostMRCommands = []string{record}
+		updater := newTestUpdater(t, server, projectID, cfg)
+
+		result, err := updater.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("Execute() unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("hooks did not run: %v", err)
+		}
+		want := fmt.Sprintf("pre-update %[1]s %[2]s %[3]s %[4]s \npost-mr %[1]s %[2]s %[3]s %[4]s %[5]s\n",
+			TestProjectID, TestFilePath, TestOldTag, TestNewTag, result.MergeRequest.WebURL)
+		if string(data) != want {
+			t.Errorf("hook log = %q, want %q: a failing post-commit command stops its hook point only", data, want)
+		}
+	})
+
+	t.Run("failing pre-update command stops the update", func(t *testing.T) {
+		server, projectID := newTestProject(t)
+		cfg := testConfig()
+		cfg.PreUpdateCommands = []string{"exit 2"}
+		updater := newTestUpdater(t, server, projectID, cfg)
+
+		if _, err := updater.Execute(context.Background()); err == nil {
+			t.Fatal("Execute() succeeded, want the failing pre-update command to stop the update")
+		}
+		if server.BranchExists(projectID, TestBranchName) || len(server.MergeRequests(projectID)) != 0 {
+			t.Error("Execute() changed the project after a failing pre-update command")
+		}
+	})
+
+	t.Run("dry run runs no hooks", func(t *testing.T) {
+		server, projectID := newTestProject(t)
+		cfg := testConfig()
+		cfg.DryRun, cfg.PreUpdateCommands = true, []string{"exit 2"}
+		updater := newTestUpdater(t, server, projectID, cfg)
+		if _, err := updater.Execute(context.Background()); err != nil {
+			t.Errorf("Execute() unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Phase names a step of the tag update workflow reported to phase hooks
+type Phase string
+
+const (
+	// PhaseInitialize creates the GitLab client and resolves the project
+	PhaseInitialize Phase = "initialize"
+	// PhaseValidate fetches the file and computes the updated content
+	PhaseValidate Phase = "validate"
+	// PhaseReuse looks for and refreshes an open merge request for the same update
+	PhaseReuse Phase = "reuse"
+	// PhaseChecks enforces the open merge request limit, source drift and pre-flight checks
+	PhaseChecks Phase = "checks"
+	// PhaseBranch chooses the name of the update branch
+	PhaseBranch Phase = "branch"
+	// PhaseConflicts applies the conflict policy to other merge requests changing the same file
+	PhaseConflicts Phase = "conflicts"
+	// PhaseUpdate creates the branch, commits the file and opens the merge request
+	PhaseUpdate Phase = "update"
+	// PhaseMergeability re-checks a merge request with merge conflicts until they are resolved
+	PhaseMergeability Phase = "mergeability"
+	// PhasePipeline waits for the merge request pipeline
+	PhasePipeline Phase = "pipeline"
+)
+
+// Step outcomes recorded in SimpleUpdateResult.Steps
+const (
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+)
+
+// StepResult is the timing and outcome of one workflow step; Error is set when the
+// step failed
+type StepResult struct {
+	Name     Phase
+	Duration time.Duration
+	Status   string
+	Error    string
+}
+
+// PhaseHook is notified when a workflow phase starts and ends; err is the error
+// that ended the phase, or nil when it completed
+type PhaseHook interface {
+	OnPhaseStart(ctx context.Context, phase Phase)
+	OnPhaseEnd(ctx context.Context, phase Phase, err error)
+}
+
+// AddPhaseHook registers a hook notified of every phase of the run
+func (stu *SimpleTagUpdater) AddPhaseHook(hook PhaseHook) {
+	stu.phaseHooks = append(stu.phaseHooks, hook)
+}
+
+// SetTransport sets the HTTP transport the GitLab client created by Initialize sends
+// its requests through; nil uses the default transport
+func (stu *SimpleTagUpdater) SetTransport(transport http.RoundTripper) {
+	stu.transport = transport
+}
+
+// beginPhase notifies the phase hooks that a phase starts and returns the function
+// that records the step and notifies them of its end
+func (stu *SimpleTagUpdater) beginPhase(ctx context.Context, phase Phase) func(error) {
+	for _, hook := range stu.phaseHooks {
+		hook.OnPhaseStart(ctx, phase)
+	}
+
+	started := time.Now()
+	return func(err error) {
+		step := StepResult{Name: phase, Duration: time.Since(started), Status: StepSucceeded}
+		if err != nil {
+			step.Status = StepFailed
+			step.Error = err.Error()
+		}
+		stu.steps = append(stu.steps, step)
+
+		for _, hook := range stu.phaseHooks {
+			hook.OnPhaseEnd(ctx, phase, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+func TestSimpleTagUpdater_Steps(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantSteps  []Phase
+		wantFailed Phase
+	}{
+		{
+			name:      "merge request created",
+			content:   TestYAMLContent,
+			wantSteps: []Phase{PhaseValidate, PhaseChecks, PhaseBranch, PhaseUpdate},
+		},
+		{name: "invalid file", content: "image: [broken\n", wantSteps: []Phase{PhaseValidate}, wantFailed: PhaseValidate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gitlabtest.NewServer(t)
+			projectID := server.AddProject(TestProjectID)
+			server.SetFile(projectID, TestTargetBranch, TestFilePath, tt.content)
+
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+			})
+
+			result, _ := updater.Execute(context.Background())
+			var names []Phase
+			for _, step := range result.Steps {
+				names = append(names, step.Name)
+				failed := step.Name == tt.wantFailed
+				if failed != (step.Status == StepFailed) || failed != (step.Error != "") {
+					t.Errorf("step %+v, want failed %v", step, failed)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantSteps) {
+				t.Errorf("steps = %v, want %v", names, tt.wantSteps)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package workflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	// IdempotencyKeyLength defines the number of hex characters kept from the key digest
+	IdempotencyKeyLength = 12
+	// idempotencyKeySeparator separates the key inputs before hashing
+	idempotencyKeySeparator = "\x00"
+)
+
+// computeIdempotencyKey derives a stable key from everything that defines an update,
+// so the same request always maps to the same branch and merge request. The target
+// branch is part of it, so updates of the same file for different target branches
+// get their own branch and merge request.
+func computeIdempotencyKey(projectID int, targetBranch, filePath string, tagPath []string, newTag string) string {
+	digest := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.Itoa(projectID),
+		targetBranch,
+		filePath,
+		strings.Join(tagPath, "."),
+		newTag,
+	}, idempotencyKeySeparator)))
+
+	return hex.EncodeToString(digest[:])[:IdempotencyKeyLength]
+}
+
+// findMergeRequestForBranch returns the open merge request whose source branch is
+// branchName, left by a previous run with the same key, or nil when there is none
+func (stu *SimpleTagUpdater) findMergeRequestForBranch(
+	ctx context.Context,
+	branchName string,
+) (*gitlab.BasicMergeRequest, error) {
+	mrs, err := stu.mrManager.ListOpenMergeRequests(ctx, stu.config.TargetBranch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up merge requests for branch %s: %w", branchName, err)
+	}
+
+	for _, mr := range mrs {
+		if mr.SourceBranch == branchName {
+			stu.logger.WithFields(map[string]interface{}{
+				"branch_name":     branchName,
+				"mr_id":           mr.IID,
+				"idempotency_key": stu.idempotencyKey,
+			}).Info("Branch already exists with an open merge request, converging on it")
+			return mr, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,31 @@
+package workflow
+
+import (
+	"testing"
+)
+
+func TestComputeIdempotencyKey(t *testing.T) {
+	tagPath := []string{"image", "tag"}
+	key := computeIdempotencyKey(123, TestTargetBranch, TestFilePath, tagPath, TestNewTag)
+
+	if len(key) != IdempotencyKeyLength {
+		t.Errorf("computeIdempotencyKey() length = %d, want %d", len(key), IdempotencyKeyLength)
+	}
+
+	if again := computeIdempotencyKey(123, TestTargetBranch, TestFilePath, tagPath, TestNewTag); again != key {
+		t.Errorf("computeIdempotencyKey() not deterministic: %q != %q", again, key)
+	}
+
+	variants := map[string]string{
+		"project":       computeIdempotencyKey(124, TestTargetBranch, TestFilePath, tagPath, TestNewTag),
+		"target branch": computeIdempotencyKey(123, "release", TestFilePath, tagPath, TestNewTag),
+		"file":          computeIdempotencyKey(123, TestTargetBranch, "other.yaml", tagPath, TestNewTag),
+		"tag path":      computeIdempotencyKey(123, TestTargetBranch, TestFilePath, []string{"version"}, TestNewTag),
+		"new tag":       computeIdempotencyKey(123, TestTargetBranch, TestFilePath, tagPath, TestOldTag),
+	}
+	for name, variant := range variants {
+		if variant == key {
+			t.Errorf("computeIdempotencyKey() should change when %s changes", name)
+		}
+	}
+}
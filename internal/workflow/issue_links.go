@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+)
+
+// issueLinks are the issues a merge request closes when merged and the ones it
+// only relates to
+type issueLinks struct {
+	closes  []int
+	related []int
+}
+
+// parseIssueLinks parses the issue numbers of --closes-issues and --related-issues
+func parseIssueLinks(closes, related []string) (*issueLinks, error) {
+	closing, err := gitlabapi.ParseIssueIIDs(closes)
+	if err != nil {
+		return nil, err
+	}
+	relating, err := gitlabapi.ParseIssueIIDs(related)
+	if err != nil {
+		return nil, err
+	}
+	return &issueLinks{closes: closing, related: relating}, nil
+}
+
+// all returns every linked issue
+func (l *issueLinks) all() []int {
+	return append(append([]int{}, l.closes...), l.related...)
+}
+
+// issueReferencesSection returns the merge request description lines referencing the
+// linked issues, so GitLab closes the closing ones on merge
+func (stu *SimpleTagUpdater) issueReferencesSection() string {
+	references := gitlabapi.IssueReferences(stu.issueLinks.closes, stu.issueLinks.related)
+	if references == "" {
+		return ""
+	}
+	return references + "\n"
+}
+
+// commentOnIssues links the merge request from the linked issues with
+// --comment-issues; failures are reported as warnings since the merge request itself
+// was created
+func (stu *SimpleTagUpdater) commentOnIssues(ctx context.Context, mr *gitlab.MergeRequest) {
+	iids := stu.issueLinks.all()
+	if !stu.config.CommentIssues || len(iids) == 0 {
+		return
+	}
+
+	if err := stu.issues.CommentMergeRequest(ctx, iids, mr); err != nil {
+		stu.logger.WithError(err).WithField("mr_id", mr.IID).Warn("Failed to comment on linked issues")
+		return
+	}
+	stu.logger.WithFields(map[string]interface{}{
+		"mr_id":  mr.IID,
+		"issues": iids,
+	}).Info("Linked issues commented with the merge request")
+}
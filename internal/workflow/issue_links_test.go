@@ -0,0 +1,47 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_IssueLinks(t *testing.T) {
+	server, projectID := newTestProject(t)
+	server.AddIssue(projectID, 12)
+	server.AddIssue(projectID, 34)
+
+	updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+		FilePath:      TestFilePath,
+		NewTag:        TestNewTag,
+		TargetBranch:  TestTargetBranch,
+		BranchName:    TestBranchName,
+		ClosesIssues:  []string{"12"},
+		RelatedIssues: []string{"#34", "56"},
+		CommentIssues: true,
+	})
+
+	result, err := updater.Execute(context.Background())
+	if err != nil || result.MergeRequest == nil {
+		t.Fatalf("Execute() = %+v, %v, want a merge request despite the missing issue", result, err)
+	}
+	description := result.MergeRequest.Description
+	if !strings.Contains(description, "Closes #12\nRelated to #34, #56\n") {
+		t.Errorf("description = %q, want the issue references", description)
+	}
+	for _, iid := range []int{12, 34} {
+		notes := server.IssueNotes(projectID, iid)
+		if len(notes) != 1 || !strings.Contains(notes[0], result.MergeRequest.WebURL) {
+			t.Errorf("notes of issue #%d = %q, want a link to the merge request", iid, notes)
+		}
+	}
+
+	_, err = NewSimpleTagUpdater(&config.CLIConfig{ClosesIssues: []string{"twelve"}}, logger.New(false))
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("NewSimpleTagUpdater() with an invalid issue = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,150 @@
+package workflow
+
+import (
+	stderrors "errors"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	"github.com/Gosayram/go-tag-updater/internal/keyvalue"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/semver"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// keyValueUpdate is the result of setting the tag key of a .env or properties file
+type keyValueUpdate struct {
+	Content  string
+	Key      string
+	OldValue string
+}
+
+// keyValueKey returns the key --yaml-path names, or the detected tag key when it is empty
+func keyValueKey(file *keyvalue.File, yamlPath string) (string, error) {
+	if yamlPath != "" {
+		return yamlPath, nil
+	}
+	return file.DetectKey()
+}
+
+// updateKeyValue sets the key to newTag. It returns yaml.ErrNoChanges together with the
+// update when the key already has the value, like the YAML updater.
+func updateKeyValue(content string, format keyvalue.Format, yamlPath, newTag string) (*keyValueUpdate, error) {
+	file, err := keyvalue.Parse(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s file: %w", format, err)
+	}
+	key, err := keyValueKey(file, yamlPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to auto-detect tag key: %w", err)
+	}
+	entry, err := file.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &keyValueUpdate{Content: content, Key: key, OldValue: entry.Value}
+	if entry.Value == newTag {
+		return update, yaml.ErrNoChanges
+	}
+	if update.Content, err = file.Set(key, newTag); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// checkKeyValueChange refuses anything but value changes of allowed keys; keys are
+// matched against the allowed paths like YAML paths, so image.tag allows the
+// properties key image.tag and IMAGE_TAG the dotenv key IMAGE_TAG
+func checkKeyValueChange(changePolicy *policy.Policy, format keyvalue.Format, original, updated string) error {
+	if !changePolicy.Enabled() {
+		return nil
+	}
+
+	changed, err := keyvalue.Diff(original, updated, format)
+	if err != nil {
+		return errors.NewPolicyError(fmt.Sprintf("cannot verify change scope: %v", err))
+	}
+	for _, key := range changed {
+		if err := changePolicy.CheckTagPath(policy.SplitYAMLPath(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateKeyValueContent updates the tag key of the configured .env or properties file
+func (stu *SimpleTagUpdater) updateKeyValueContent(content string, format keyvalue.Format) (string, error) {
+	update, err := updateKeyValue(content, format, stu.yamlPath, stu.config.NewTag)
+	if update != nil {
+		stu.tagPath = policy.SplitYAMLPath(update.Key)
+		stu.oldTag = update.OldValue
+	}
+	if err != nil {
+		return "", err
+	}
+	return update.Content, nil
+}
+
+// updateLocalKeyValueFile is UpdateLocalFile for .env and properties files
+func updateLocalKeyValueFile(
+	cfg *config.CLIConfig,
+	log *logger.Logger,
+	format keyvalue.Format,
+	changePolicy *policy.Policy,
+	tagPolicy *semver.Policy,
+) (*LocalUpdateResult, error) {
+	updater := newLocalUpdater(cfg)
+	content, err := updater.ReadContent(cfg.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", cfg.FilePath, err)
+	}
+
+	result := &LocalUpdateResult{FilePath: cfg.FilePath, NewTag: cfg.NewTag}
+	update, err := updateKeyValue(content, format, cfg.YAMLPath, cfg.NewTag)
+	if update != nil {
+		result.TagPath = policy.SplitYAMLPath(update.Key)
+		result.OldTag = update.OldValue
+	}
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		result.Skipped = true
+		result.Message = fmt.Sprintf("No changes: %s already uses tag %s", cfg.FilePath, cfg.NewTag)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyValueChange(changePolicy, format, content, update.Content); err != nil {
+		return nil, err
+	}
+	if err := tagPolicy.Check(update.OldValue, cfg.NewTag); err != nil {
+		return nil, err
+	}
+
+	result.Diff = diff.Unified("a/"+cfg.FilePath, "b/"+cfg.FilePath, content, update.Content,
+		diff.DefaultContextLines)
+	fileLog := log.WithField("file_path", cfg.FilePath)
+	if cfg.DryRun {
+		fileLog.WithField("diff_preview", result.Diff).Info("Dry run mode: would update local file")
+		result.Message = fmt.Sprintf("Dry run completed. Would change %s from %s to %s",
+			update.Key, result.OldTag, result.NewTag)
+		return result, nil
+	}
+
+	if result.BackupPath, err = updater.WriteContent(cfg.FilePath, content, update.Content, cfg.Backup); err != nil {
+		return nil, err
+	}
+	if result.BackupPath != "" {
+		if err := updater.CleanupOldBackups(cfg.FilePath); err != nil {
+			fileLog.WithError(err).Warn("Failed to remove old backups")
+		}
+	}
+
+	result.Message = fmt.Sprintf("Updated %s in %s from %s to %s", update.Key, cfg.FilePath, result.OldTag, result.NewTag)
+	if result.BackupPath != "" {
+		result.Message += fmt.Sprintf("; restore with --restore-backup=%s", result.BackupPath)
+	}
+	return result, nil
+}
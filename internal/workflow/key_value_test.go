@@ -0,0 +1,46 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+func TestSimpleTagUpdater_KeyValueFile(t *testing.T) {
+	const (
+		envPath    = "deploy/.env.production"
+		envContent = "# Release settings\nIMAGE_TAG=v1.0.0 # bumped by CI\nREPLICAS=2\n"
+	)
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, envPath, envContent)
+
+	cfg := &config.CLIConfig{
+		ProjectID:      TestProjectID,
+		GitLabToken:    TestGitLabToken,
+		FilePath:       envPath,
+		NewTag:         "v1.2.3",
+		TargetBranch:   TestTargetBranch,
+		BranchName:     TestBranchName,
+		LeastPrivilege: true,
+		AllowedFiles:   []string{"deploy/.env.*"},
+		AllowedPaths:   []string{"IMAGE_TAG"},
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+
+	if _, err := updater.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	want := strings.Replace(envContent, "v1.0.0", "v1.2.3", 1)
+	if content, ok := server.File(projectID, TestBranchName, envPath); !ok || content != want {
+		t.Errorf("branch content = %q, want %q", content, want)
+	}
+	if updater.oldTag != "v1.0.0" {
+		t.Errorf("oldTag = %q, want v1.0.0", updater.oldTag)
+	}
+}
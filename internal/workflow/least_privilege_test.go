@@ -0,0 +1,84 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_LeastPrivilegeRefusal(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowedFiles []string
+		allowedPaths []string
+		yamlPath     string
+		wantRefused  bool
+	}{
+		{name: "allowed file and path", allowedFiles: []string{"*.yaml"}, allowedPaths: []string{"image.tag"}},
+		{name: "file outside the allowed globs", allowedFiles: []string{"deploy/*.yaml"},
+			allowedPaths: []string{"image.tag"}, wantRefused: true},
+		{name: "path outside the allowed paths", allowedFiles: []string{"*.yaml"},
+			allowedPaths: []string{"image.tag"}, yamlPath: "version", wantRefused: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			cfg := testConfig()
+			cfg.LeastPrivilege = true
+			cfg.AllowedFiles = tt.allowedFiles
+			cfg.AllowedPaths = tt.allowedPaths
+			cfg.YAMLPath = tt.yamlPath
+
+			_, err := newTestUpdater(t, server, projectID, cfg).Execute(context.Background())
+			if !tt.wantRefused {
+				if err != nil {
+					t.Fatalf("Execute() unexpected error: %v", err)
+				}
+				return
+			}
+
+			if code := errors.GetErrorCode(err); code != errors.ErrCodePolicyViolation {
+				t.Fatalf("Execute() error = %v (code %d), want a policy violation", err, code)
+			}
+			if server.BranchExists(projectID, TestBranchName) {
+				t.Error("refused update created the update branch")
+			}
+			if mrs := server.MergeRequests(projectID); len(mrs) != 0 {
+				t.Errorf("refused update opened merge requests: %v", mrs)
+			}
+		})
+	}
+}
+
+func TestUpdateLocalFile_LeastPrivilegeRefusal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(path, []byte(TestYAMLContent), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	cfg := &config.CLIConfig{
+		FilePath:       path,
+		NewTag:         TestNewTag,
+		YAMLPath:       "version",
+		LeastPrivilege: true,
+		AllowedFiles:   []string{"**/values.yaml"},
+		AllowedPaths:   []string{"image.tag"},
+	}
+
+	_, err := UpdateLocalFile(context.Background(), cfg, logger.New(false))
+	if code := errors.GetErrorCode(err); code != errors.ErrCodePolicyViolation {
+		t.Fatalf("UpdateLocalFile() error = %v (code %d), want a policy violation", err, code)
+	}
+	if !strings.Contains(err.Error(), "version") {
+		t.Errorf("UpdateLocalFile() error = %v, want it to name the refused path", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != TestYAMLContent {
+		t.Errorf("refused update changed the file: %q", content)
+	}
+}
@@ -0,0 +1,24 @@
+package workflow
+
+import (
+	"context"
+)
+
+// reportCommit looks up the commit just pushed to the branch and logs its URL so a
+// failure in a later step can be inspected in the GitLab UI. Lookup failures only
+// produce a warning since the commit itself succeeded.
+func (stu *SimpleTagUpdater) reportCommit(ctx context.Context, result *SimpleUpdateResult, branchName string) {
+	branch, err := stu.branchMgr.GetBranch(ctx, branchName)
+	if err != nil || branch.Commit == nil {
+		stu.logger.WithError(err).WithField("branch_name", branchName).
+			Warn("Could not look up the pushed commit")
+		return
+	}
+
+	result.CommitURL = branch.Commit.WebURL
+	stu.logger.WithFields(map[string]interface{}{
+		"branch_name": branchName,
+		"commit_id":   branch.Commit.ShortID,
+		"commit_url":  branch.Commit.WebURL,
+	}).Info("Commit pushed")
+}
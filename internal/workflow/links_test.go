@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+func TestSimpleTagUpdater_ExecuteReportsLinks(t *testing.T) {
+	server, projectID := newTestProject(t)
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	if !strings.HasSuffix(result.BranchURL, "/-/tree/"+TestBranchName) {
+		t.Errorf("Execute() branch URL = %q", result.BranchURL)
+	}
+	if !strings.Contains(result.CommitURL, "/-/commit/") {
+		t.Errorf("Execute() commit URL = %q", result.CommitURL)
+	}
+	if content, _ := server.File(projectID, TestBranchName, TestFilePath); !strings.Contains(content, TestNewTag) {
+		t.Errorf("branch content = %q, want new tag", content)
+	}
+
+	history, err := gitlabapi.NewFileManager(server.Client(), projectID).
+		GetFileHistory(context.Background(), TestFilePath, TestBranchName, 1)
+	if err != nil || len(history) != 1 || !strings.HasSuffix(history[0].Message, identity.CommitTrailer(result.RunID)) {
+		t.Errorf("commit history = %v, %v, want a commit with the run trailer", history, err)
+	}
+	if mrs := server.MergeRequests(projectID); len(mrs) != 1 || !slices.Contains(mrs[0].Labels, identity.DefaultLabel) {
+		t.Errorf("merge requests = %+v, want one labeled %s", mrs, identity.DefaultLabel)
+	}
+}
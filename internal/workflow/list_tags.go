@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// ListTags fetches the configured file at ref from GitLab and parses it; the result
+// lists the tag fields the parser detects, in document order
+func ListTags(ctx context.Context, cfg *config.CLIConfig, ref string) (*yaml.ParseResult, error) {
+	content, err := fetchFileContent(ctx, cfg, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.NewParser().ParseContent(content)
+}
+
+// CurrentTag returns the value the configured file holds on the target branch at
+// the YAML path, or at the auto-detected tag field when no path is configured; the
+// path names the key of .env and properties files
+func CurrentTag(ctx context.Context, cfg *config.CLIConfig) (string, error) {
+	if cfg == nil {
+		return "", errors.NewValidationError("config is required")
+	}
+	docSelector, err := yaml.ParseDocumentSelector(cfg.DocSelector)
+	if err != nil {
+		return "", fmt.Errorf("invalid document selector: %w", err)
+	}
+
+	content, err := fetchFileContent(ctx, cfg, cfg.TargetBranch)
+	if err != nil {
+		return "", err
+	}
+
+	field := tagField{filePath: cfg.FilePath, yamlPath: cfg.YAMLPath, docSelector: docSelector}
+	return field.value(content)
+}
+
+// fetchFileContent reads the configured file at ref from GitLab
+func fetchFileContent(ctx context.Context, cfg *config.CLIConfig, ref string) (string, error) {
+	if cfg == nil {
+		return "", errors.NewValidationError("config is required")
+	}
+	if cfg.ProjectID == "" || cfg.FilePath == "" {
+		return "", errors.NewValidationError("project ID and file path are required")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	projectID, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+
+	fileManager := gitlabapi.NewFileManager(client.GetGitLabClient(), projectID)
+	content, err := fileManager.GetFileContent(ctx, cfg.FilePath, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s at %s: %w", cfg.FilePath, ref, err)
+	}
+	return content, nil
+}
+
+// tagValue returns the value of a parsed file at the YAML path, or at the
+// auto-detected tag field when the path is empty, in the selected document
+func tagValue(parsed *yaml.ParseResult, yamlPath string, docSelector *yaml.DocumentSelector) (string, error) {
+	if err := parsed.SelectDocument(docSelector); err != nil {
+		return "", err
+	}
+
+	tagPath := policy.SplitYAMLPath(yamlPath)
+	if len(tagPath) == 0 {
+		var err error
+		if tagPath, err = yaml.DetectTagPath(parsed); err != nil {
+			return "", err
+		}
+	}
+	return yaml.NewParser().GetTagValue(parsed, tagPath)
+}
+
+// ListLocalTags reads a YAML file from disk and parses it like ListTags
+func ListLocalTags(filePath string) (*yaml.ParseResult, error) {
+	if filePath == "" {
+		return nil, errors.NewValidationError("file path is required")
+	}
+
+	content, err := os.ReadFile(filepath.Clean(filePath))
+	if os.IsNotExist(err) {
+		return nil, errors.NewFileNotFoundError(filePath)
+	}
+	if err != nil {
+		return nil, errors.NewFileSystemError(fmt.Sprintf("failed to read %s: %v", filePath, err))
+	}
+
+	return yaml.NewParser().ParseContent(string(content))
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+)
+
+func TestListTags(t *testing.T) {
+	server, _ := newTestProject(t)
+
+	localPath := filepath.Join(t.TempDir(), TestFilePath)
+	if err := os.WriteFile(localPath, []byte(TestYAMLContent), TempFilePermissions); err != nil {
+		t.Fatalf("failed to write local file: %v", err)
+	}
+
+	cfg := &config.CLIConfig{
+		ProjectID:   TestProjectID,
+		GitLabToken: TestGitLabToken,
+		GitLabURL:   server.URL(),
+		FilePath:    TestFilePath,
+	}
+
+	tests := []struct {
+		name string
+		list func() (*yaml.ParseResult, error)
+	}{
+		{name: "gitlab", list: func() (*yaml.ParseResult, error) {
+			return ListTags(context.Background(), cfg, TestTargetBranch)
+		}},
+		{name: "local", list: func() (*yaml.ParseResult, error) { return ListLocalTags(localPath) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := tt.list()
+			if err != nil {
+				t.Fatalf("list unexpected error: %v", err)
+			}
+
+			var found bool
+			for _, location := range parseResult.TagLocations {
+				found = found || (strings.Join(location.Path, ".") == "image.tag" &&
+					location.Value == TestOldTag && location.Line > 0)
+			}
+			if !found {
+				t.Errorf("tag locations = %+v, want image.tag = %s", parseResult.TagLocations, TestOldTag)
+			}
+		})
+	}
+
+	if _, err := ListTags(context.Background(), cfg, "missing-branch"); err == nil {
+		t.Error("ListTags() expected error for a missing ref")
+	}
+	if _, err := ListLocalTags(filepath.Join(t.TempDir(), TestFilePath)); err == nil {
+		t.Error("ListLocalTags() expected error for a missing file")
+	}
+}
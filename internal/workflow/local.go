@@ -0,0 +1,189 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	"github.com/Gosayram/go-tag-updater/internal/keyvalue"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// LocalUpdateResult contains the results of a tag update of a file on disk
+type LocalUpdateResult struct {
+	FilePath   string
+	TagPath    []string
+	OldTag     string
+	NewTag     string
+	BackupPath string
+	Diff       string
+	Skipped    bool
+	Message    string
+	// Validations holds the outcome of each --validate-cmd command run on the
+	// updated content
+	Validations []report.Validation
+}
+
+// UpdateLocalFile updates the tag in a YAML file on disk without contacting GitLab.
+// The change is computed and checked against validation and the least-privilege
+// policy before the file is written, so a rejected update never touches it. The
+// validation commands run under ctx, so a timeout or an interrupt stops them; when
+// one rejects the content the result is returned with the error to report them.
+func UpdateLocalFile(ctx context.Context, cfg *config.CLIConfig, log *logger.Logger) (*LocalUpdateResult, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.FilePath == "" || cfg.NewTag == "" {
+		return nil, errors.NewValidationError("file path and new tag are required")
+	}
+
+	changePolicy, err := policy.New(cfg.LeastPrivilege, cfg.AllowedFiles, cfg.AllowedPaths)
+	if err != nil {
+		return nil, fmt.Errorf("invalid change policy: %w", err)
+	}
+	if err := changePolicy.CheckFile(cfg.FilePath); err != nil {
+		return nil, err
+	}
+	tagPolicy, err := newTagPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSchemaName(cfg.ValidateSchema); err != nil {
+		return nil, err
+	}
+	if format, ok := keyvalue.DetectFormat(cfg.FilePath); ok {
+		return updateLocalKeyValueFile(cfg, log, format, changePolicy, tagPolicy)
+	}
+	docSelector, err := yaml.ParseDocumentSelector(cfg.DocSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document selector: %w", err)
+	}
+
+	updater := newLocalUpdater(cfg)
+	request := &yaml.UpdateRequest{
+		FilePath:      cfg.FilePath,
+		NewTagValue:   cfg.NewTag,
+		TagPath:       policy.SplitYAMLPath(cfg.YAMLPath),
+		CreateBackup:  cfg.Backup,
+		ValidateAfter: true,
+		FallbackRaw:   cfg.FallbackRaw,
+
+		ResolveAnchors:   cfg.ResolveAnchors,
+		DocumentSelector: docSelector,
+	}
+
+	result := &LocalUpdateResult{FilePath: cfg.FilePath, NewTag: cfg.NewTag}
+	fileLog := log.WithField("file_path", cfg.FilePath)
+
+	preview, err := updater.PreviewUpdate(request)
+	logEncodeDiagnostics(log, cfg.FilePath, encodeDiagnostics(preview, err))
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		result.TagPath = preview.TagPath
+		result.OldTag = preview.OldTagValue
+		result.Skipped = true
+		result.Message = fmt.Sprintf("No changes: %s already uses tag %s", cfg.FilePath, cfg.NewTag)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if preview.ValidationError != nil {
+		return nil, fmt.Errorf("updated content is not valid YAML: %w", preview.ValidationError)
+	}
+	if err := changePolicy.CheckChange(preview.OriginalContent, preview.UpdatedContent); err != nil {
+		return nil, err
+	}
+	if err := checkTagPolicy(tagPolicy, tagField{filePath: cfg.FilePath, yamlPath: cfg.YAMLPath, docSelector: docSelector},
+		preview.OriginalContent, cfg.NewTag); err != nil {
+		return nil, err
+	}
+	if err := checkSchema(cfg.ValidateSchema, cfg.FilePath, preview.UpdatedContent); err != nil {
+		return nil, err
+	}
+	result.Validations, err = runValidateCommands(ctx, cfg.ValidateCommands, cfg.FilePath,
+		preview.UpdatedContent, log)
+	if err != nil {
+		return result, err
+	}
+
+	result.TagPath = preview.TagPath
+	result.OldTag = preview.OldTagValue
+	result.Diff = diff.Unified("a/"+cfg.FilePath, "b/"+cfg.FilePath,
+		preview.OriginalContent, preview.UpdatedContent, diff.DefaultContextLines)
+	if len(preview.SharedAnchors) > 0 {
+		fileLog.WithField("shared_anchors", describeAnchors(preview.SharedAnchors)).
+			Warn("Updated tag is shared through anchors, the new value applies to every alias")
+	}
+
+	if cfg.DryRun {
+		fileLog.WithField("diff_preview", result.Diff).Info("Dry run mode: would update local file")
+		result.Message = fmt.Sprintf("Dry run completed. Would change %s from %s to %s",
+			policy.FormatYAMLPath(result.TagPath), result.OldTag, result.NewTag)
+		return result, nil
+	}
+
+	written, err := updater.UpdateTagInFile(request)
+	if err != nil {
+		return nil, err
+	}
+	result.BackupPath = written.BackupPath
+
+	if result.BackupPath != "" {
+		if err := updater.CleanupOldBackups(cfg.FilePath); err != nil {
+			fileLog.WithError(err).Warn("Failed to remove old backups")
+		}
+	}
+
+	result.Message = fmt.Sprintf("Updated %s in %s from %s to %s",
+		policy.FormatYAMLPath(result.TagPath), cfg.FilePath, result.OldTag, result.NewTag)
+	if result.BackupPath != "" {
+		result.Message += fmt.Sprintf("; restore with --restore-backup=%s", result.BackupPath)
+	}
+	return result, nil
+}
+
+// newLocalUpdater creates the updater of local mode, keeping backups in the backup
+// directory under the configured retention
+func newLocalUpdater(cfg *config.CLIConfig) *yaml.Updater {
+	updater := yaml.NewUpdaterWithOptions(cfg.BackupDir, true, true)
+	updater.SetBackupRetention(localBackupRetention(cfg))
+	return updater
+}
+
+// localBackupRetention returns the backup retention of --backup-keep and --backup-max-age
+func localBackupRetention(cfg *config.CLIConfig) yaml.BackupRetention {
+	return yaml.BackupRetention{MaxCount: cfg.BackupKeep, MaxAge: cfg.BackupMaxAge}
+}
+
+// PruneLocalBackups removes the backups of a local file beyond --backup-keep and older
+// than --backup-max-age and returns their paths; a dry run only lists them
+func PruneLocalBackups(cfg *config.CLIConfig, log *logger.Logger) ([]string, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.FilePath == "" {
+		return nil, errors.NewValidationError("file path is required")
+	}
+
+	removed, err := yaml.PruneBackups(cfg.BackupDir, cfg.FilePath, localBackupRetention(cfg), cfg.DryRun)
+	for _, path := range removed {
+		log.WithFields(map[string]interface{}{
+			"file_path":   cfg.FilePath,
+			"backup_path": path,
+			"dry_run":     cfg.DryRun,
+		}).Info("Pruned backup")
+	}
+	return removed, err
+}
+
+// RestoreLocalFile rolls a local file back to the content of a backup written by
+// UpdateLocalFile
+func RestoreLocalFile(filePath, backupPath string) error {
+	return yaml.NewUpdater().RollbackFromBackup(filePath, backupPath)
+}
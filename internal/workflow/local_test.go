@@ -0,0 +1,159 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestUpdateLocalFile(t *testing.T) {
+	writeFile := func(t *testing.T) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "values.yaml")
+		if err := os.WriteFile(path, []byte(TestYAMLContent), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		return path
+	}
+
+	t.Run("update with backup and restore", func(t *testing.T) {
+		path := writeFile(t)
+		cfg := &config.CLIConfig{FilePath: path, NewTag: TestNewTag, Backup: true}
+
+		result, err := UpdateLocalFile(context.Background(), cfg, logger.New(false))
+		if err != nil {
+			t.Fatalf("UpdateLocalFile() unexpected error: %v", err)
+		}
+		if result.OldTag != TestOldTag || result.BackupPath == "" {
+			t.Fatalf("result = %+v, want old tag %s and a backup", result, TestOldTag)
+		}
+
+		content, _ := os.ReadFile(path)
+		if !strings.Contains(string(content), "tag: "+TestNewTag) {
+			t.Errorf("file content = %q, want new tag", content)
+		}
+
+		if err := RestoreLocalFile(path, result.BackupPath); err != nil {
+			t.Fatalf("RestoreLocalFile() unexpected error: %v", err)
+		}
+		content, _ = os.ReadFile(path)
+		if string(content) != TestYAMLContent {
+			t.Errorf("restored content = %q, want original", content)
+		}
+	})
+
+	t.Run("dry run leaves the file untouched", func(t *testing.T) {
+		path := writeFile(t)
+		cfg := &config.CLIConfig{FilePath: path, NewTag: TestNewTag, DryRun: true}
+
+		result, err := UpdateLocalFile(context.Background(), cfg, logger.New(false))
+		if err != nil {
+			t.Fatalf("UpdateLocalFile() unexpected error: %v", err)
+		}
+		if result.Diff == "" {
+			t.Error("expected a diff in dry run")
+		}
+		content, _ := os.ReadFile(path)
+		if string(content) != TestYAMLContent {
+			t.Errorf("file content = %q, want unchanged", content)
+		}
+	})
+
+	t.Run("properties file", func(t *testing.T) {
+		const properties = "# Images\nimage.tag = v1.0.0\nimage.repository = app\n"
+		path := filepath.Join(t.TempDir(), "application.properties")
+		if err := os.WriteFile(path, []byte(properties), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		cfg := &config.CLIConfig{FilePath: path, NewTag: "v1.2.3", YAMLPath: "image.tag"}
+
+		result, err := UpdateLocalFile(context.Background(), cfg, logger.New(false))
+		if err != nil {
+			t.Fatalf("UpdateLocalFile() unexpected error: %v", err)
+		}
+		if result.OldTag != "v1.0.0" {
+			t.Errorf("OldTag = %q, want v1.0.0", result.OldTag)
+		}
+		content, _ := os.ReadFile(path)
+		if want := strings.Replace(properties, "v1.0.0", "v1.2.3", 1); string(content) != want {
+			t.Errorf("file content = %q, want %q", content, want)
+		}
+	})
+
+	t.Run("same tag is skipped", func(t *testing.T) {
+		path := writeFile(t)
+		cfg := &config.CLIConfig{FilePath: path, NewTag: TestOldTag}
+
+		result, err := UpdateLocalFile(context.Background(), cfg, logger.New(false))
+		if err != nil {
+			t.Fatalf("UpdateLocalFile() unexpected error: %v", err)
+		}
+		if !result.Skipped {
+			t.Errorf("result = %+v, want skipped", result)
+		}
+	})
+
+	t.Run("validation commands are reported", func(t *testing.T) {
+		if _, err := exec.LookPath("sh"); err != nil {
+			t.Skip("sh not installed")
+		}
+		path := writeFile(t)
+		cfg := &config.CLIConfig{
+			FilePath:         path,
+			NewTag:           TestNewTag,
+			ValidateCommands: []string{"grep -q 'tag: " + TestNewTag + "' {}", "echo 'tag not pinned' >&2; exit 1"},
+		}
+
+		result, err := UpdateLocalFile(context.Background(), cfg, logger.New(false))
+		if err == nil {
+			t.Fatal("UpdateLocalFile() succeeded, want the failing validation command to stop the update")
+		}
+		if result == nil || len(result.Validations) != 2 || !result.Validations[0].Passed ||
+			result.Validations[1].Passed || !strings.Contains(result.Validations[1].Output, "tag not pinned") {
+			t.Errorf("result = %+v, want both commands reported", result)
+		}
+		content, _ := os.ReadFile(path)
+		if string(content) != TestYAMLContent {
+			t.Errorf("file content = %q, want unchanged", content)
+		}
+	})
+
+	t.Run("validation commands stop with the context", func(t *testing.T) {
+		if _, err := exec.LookPath("sh"); err != nil {
+			t.Skip("sh not installed")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cfg := &config.CLIConfig{FilePath: writeFile(t), NewTag: TestNewTag, ValidateCommands: []string{"true"}}
+
+		if _, err := UpdateLocalFile(ctx, cfg, logger.New(false)); err == nil {
+			t.Error("UpdateLocalFile() succeeded, want the canceled context to stop the validation command")
+		}
+	})
+
+	t.Run("tag policy is checked before validation commands", func(t *testing.T) {
+		if _, err := exec.LookPath("sh"); err != nil {
+			t.Skip("sh not installed")
+		}
+		marker := filepath.Join(t.TempDir(), "ran")
+		cfg := &config.CLIConfig{
+			FilePath:         writeFile(t),
+			NewTag:           TestNewTag,
+			AllowBump:        "patch",
+			ValidateCommands: []string{"touch '" + marker + "'"},
+		}
+
+		if _, err := UpdateLocalFile(context.Background(), cfg, logger.New(false)); err == nil {
+			t.Fatal("UpdateLocalFile() accepted a minor bump under --allow-bump=patch")
+		}
+		if _, err := os.Stat(marker); err == nil {
+			t.Error("validation command ran on an update the tag policy rejects")
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// ApprovalCheckInterval defines how often the approvals of a merge request are polled
+	ApprovalCheckInterval = 15 * time.Second
+	// DefaultApprovalTimeout defines how long to wait for approvals when no timeout is given
+	DefaultApprovalTimeout = 30 * time.Minute
+)
+
+// MergeOptions selects the merge request to merge and what to wait for first
+type MergeOptions struct {
+	MergeRequestIID int
+	// WaitPipeline waits for the head pipeline to succeed before merging
+	WaitPipeline    bool
+	PipelineTimeout time.Duration
+	// WaitApprovals waits until every approval rule is satisfied before merging
+	WaitApprovals   bool
+	ApprovalTimeout time.Duration
+	// KeepBranch keeps the source branch after the merge
+	KeepBranch bool
+	Squash     bool
+	// Interval overrides how often the pipeline and approvals are polled
+	Interval time.Duration
+}
+
+// MergeResult contains the results of merging a merge request
+type MergeResult struct {
+	MergeRequest  *gitlab.MergeRequest
+	Pipeline      *gitlabapi.PipelineResult
+	Approvals     *gitlabapi.ApprovalStatus
+	BranchDeleted bool
+	Message       string
+}
+
+// merger merges one merge request of the configured project
+type merger struct {
+	cfg       *config.CLIConfig
+	opts      MergeOptions
+	logger    *logrus.Entry
+	mrManager *gitlabapi.SimpleMergeRequestManager
+	approvals *gitlabapi.ApprovalManager
+	pipelines *gitlabapi.PipelineWatcher
+	branches  *gitlabapi.BranchManager
+}
+
+// MergeMergeRequest merges a merge request opened by go-tag-updater, so that CI can
+// create merge requests in one job and merge them in a later one. It optionally
+// waits for the head pipeline to succeed and for the approvals first, approving with
+// --approver-token when set, and deletes the source branch afterwards. Merge requests
+// that are not open, are drafts or were not opened by go-tag-updater are refused.
+func MergeMergeRequest(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	opts MergeOptions,
+	log *logger.Logger,
+) (*MergeResult, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("project ID is required")
+	}
+	if opts.MergeRequestIID <= 0 {
+		return nil, errors.NewValidationError("merge request IID must be positive")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	projectID, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+
+	m := newMerger(cfg, opts, gitlabapi.NewAPIAdapter(client.GetGitLabClient()), projectID, log)
+	if cfg.ApproverToken != "" {
+		approverCfg := *cfg
+		approverCfg.GitLabToken, approverCfg.AuthMode = cfg.ApproverToken, string(gitlabapi.AuthModePAT)
+		approver, err := newGitLabClient(&approverCfg, cfg.GitLabURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GitLab client of the approver token: %w", err)
+		}
+		m.approvals.SetApprover(gitlabapi.NewAPIAdapter(approver.GetGitLabClient()))
+	}
+	return m.merge(ctx)
+}
+
+// newMerger creates a merger on top of the given API implementation
+func newMerger(
+	cfg *config.CLIConfig,
+	opts MergeOptions,
+	api gitlabapi.API,
+	projectID int,
+	log *logger.Logger,
+) *merger {
+	m := &merger{
+		cfg:       cfg,
+		opts:      opts,
+		logger:    log.WithField("mr_id", opts.MergeRequestIID),
+		mrManager: gitlabapi.NewSimpleMergeRequestManagerWithAPI(api, projectID),
+		approvals: gitlabapi.NewApprovalManagerWithAPI(api, projectID),
+		pipelines: gitlabapi.NewPipelineWatcherWithAPI(api, projectID),
+		branches:  gitlabapi.NewBranchManagerWithAPI(api, projectID),
+	}
+	m.pipelines.SetInterval(opts.Interval)
+	return m
+}
+
+// merge checks the merge request, waits for its pipeline and approvals and merges it
+func (m *merger) merge(ctx context.Context) (*MergeResult, error) {
+	iid := m.opts.MergeRequestIID
+	mr, err := m.mrManager.GetMergeRequest(ctx, iid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMergeable(mr); err != nil {
+		return nil, err
+	}
+
+	result := &MergeResult{MergeRequest: mr}
+	if m.opts.WaitPipeline {
+		m.logger.WithField("timeout", m.opts.PipelineTimeout.String()).Info("Waiting for merge request pipeline")
+		result.Pipeline, err = m.pipelines.WaitForMergeRequestPipeline(ctx, iid, m.opts.PipelineTimeout)
+		if err != nil {
+			return result, fmt.Errorf("pipeline gate failed for MR !%d: %w", iid, err)
+		}
+		if result.Pipeline.Skipped() {
+			m.logger.WithField("pipeline_id", result.Pipeline.PipelineID).
+				Warn("Merge request pipeline was skipped, GitLab decides whether it may be merged")
+		}
+	}
+	if m.opts.WaitApprovals {
+		if result.Approvals, err = m.waitForApprovals(ctx); err != nil {
+			return result, err
+		}
+	}
+
+	if m.cfg.DryRun {
+		m.logger.Info("Dry run mode: would merge merge request")
+		result.Message = fmt.Sprintf("Dry run completed. Would merge MR !%d (%s) into %s",
+			iid, mr.SourceBranch, mr.TargetBranch)
+		return result, nil
+	}
+
+	merged, err := m.mrManager.MergeMergeRequest(ctx, iid, &gitlabapi.SimpleMergeRequestOptions{
+		Squash:             m.opts.Squash,
+		RemoveSourceBranch: !m.opts.KeepBranch,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.MergeRequest = merged
+	m.logger.WithFields(map[string]interface{}{
+		"source_branch": merged.SourceBranch,
+		"target_branch": merged.TargetBranch,
+		"merge_commit":  merged.MergeCommitSHA,
+	}).Info("Merge request merged")
+
+	result.Message = fmt.Sprintf("Merged MR !%d into %s", iid, merged.TargetBranch)
+	if !m.opts.KeepBranch {
+		result.BranchDeleted = m.deleteSourceBranch(ctx, merged.SourceBranch)
+		if result.BranchDeleted {
+			result.Message += fmt.Sprintf(" and deleted branch %s", merged.SourceBranch)
+		}
+	}
+	return result, nil
+}
+
+// checkMergeable refuses merge requests go-tag-updater must not merge
+func checkMergeable(mr *gitlab.MergeRequest) error {
+	tool := identity.New()
+	if !tool.OwnsMergeRequest(&identity.Event{Description: mr.Description}) {
+		return errors.NewValidationError(fmt.Sprintf("MR !%d was not opened by go-tag-updater", mr.IID))
+	}
+	if mr.State != gitlabapi.StateOpened {
+		return errors.NewValidationError(fmt.Sprintf("MR !%d is %s, only open merge requests can be merged",
+			mr.IID, mr.State))
+	}
+	if mr.Draft {
+		return errors.NewValidationError(fmt.Sprintf("MR !%d is a draft: run 'go-tag-updater ready' first", mr.IID))
+	}
+	return nil
+}
+
+// waitForApprovals approves the merge request with --approver-token when set, then
+// polls its approvals until every rule is satisfied or the timeout elapses
+func (m *merger) waitForApprovals(ctx context.Context) (*gitlabapi.ApprovalStatus, error) {
+	iid := m.opts.MergeRequestIID
+	if m.cfg.ApproverToken != "" {
+		if _, err := m.approvals.Approve(ctx, iid); err != nil {
+			m.logger.WithError(err).Warn("Failed to approve merge request")
+		}
+	}
+
+	timeout, interval := m.opts.ApprovalTimeout, m.opts.Interval
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	if interval <= 0 {
+		interval = ApprovalCheckInterval
+	}
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := m.approvals.Status(ctx, iid)
+		if err != nil {
+			return nil, err
+		}
+		if status.Approved {
+			m.logger.WithField("approved_by", status.ApprovedBy).Info("Merge request approved")
+			return status, nil
+		}
+		m.logger.WithFields(map[string]interface{}{
+			"approvals_left": status.Left,
+			"rules_left":     status.RulesLeft,
+		}).Info("Waiting for merge request approvals")
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-deadline:
+			return status, errors.NewValidationError(fmt.Sprintf(
+				"timeout waiting for approvals of MR !%d after %v (%d approval(s) left)", iid, timeout, status.Left))
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteSourceBranch deletes the source branch unless GitLab already removed it with
+// the merge. Failures are reported as warnings since the merge itself succeeded.
+func (m *merger) deleteSourceBranch(ctx context.Context, branchName string) bool {
+	exists, err := m.branches.BranchExists(ctx, branchName)
+	if err == nil && exists {
+		err = m.branches.DeleteBranch(ctx, branchName)
+	}
+	if err != nil {
+		m.logger.WithError(err).WithField("branch_name", branchName).Warn("Failed to delete source branch")
+		return false
+	}
+	return true
+}
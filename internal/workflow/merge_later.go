@@ -0,0 +1,194 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/clock"
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/progress"
+	"github.com/Gosayram/go-tag-updater/internal/schedule"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// MergeDeferredFormat is appended to the run message when auto-merge waits for working hours
+	MergeDeferredFormat = "; auto-merge deferred until %s, run 'go-tag-updater merge-later' to enable it"
+)
+
+// MergeLaterResult contains the results of processing deferred merges
+type MergeLaterResult struct {
+	EnabledRuns []string
+	SkippedRuns []string
+	WaitingRuns []string
+	Message     string
+}
+
+// deferAutoMerge records the auto-merge for later when the run happens outside the
+// configured working hours or opened a draft, and reports whether it was deferred
+func (stu *SimpleTagUpdater) deferAutoMerge(result *SimpleUpdateResult, mrOpts *gitlabapi.SimpleMergeRequestOptions) bool {
+	now := stu.now()
+	outsideWindow := stu.mergeWindow != nil && !stu.mergeWindow.Contains(now)
+	if !outsideWindow && !mrOpts.Draft {
+		return false
+	}
+
+	notBefore := now.UTC()
+	if outsideWindow {
+		notBefore = stu.mergeWindow.Next(now).UTC()
+	}
+
+	mrLog := stu.logger.WithFields(map[string]interface{}{
+		"mr_id":      result.MergeRequest.IID,
+		"draft":      mrOpts.Draft,
+		"not_before": clock.In(notBefore).Format(time.RFC3339),
+	})
+	if stu.mergeWindow != nil {
+		mrLog = mrLog.WithField("merge_window", stu.mergeWindow.String())
+	}
+
+	if stu.runEntry == nil {
+		mrLog.Warn("Auto-merge must wait but there is no run journal to defer to; auto-merge was not enabled")
+		return true
+	}
+
+	err := stu.journal.RecordDeferredMerge(stu.runEntry, &journal.DeferredMerge{
+		MergeRequestIID:    result.MergeRequest.IID,
+		NotBefore:          notBefore,
+		Squash:             mrOpts.Squash,
+		RemoveSourceBranch: mrOpts.RemoveSourceBranch,
+	})
+	if err != nil {
+		mrLog.WithError(err).Warn("Failed to record deferred auto-merge; auto-merge was not enabled")
+		return true
+	}
+
+	result.MergeDeferredUntil = notBefore
+	mrLog.Info("Auto-merge deferred until the merge request is ready and within working hours")
+	return true
+}
+
+// ProcessDeferredMerges enables the auto-merges that runs deferred to working hours
+// once their time has come. Nothing is enabled outside the configured working hours
+// or while the merge request is a draft, and merge requests that are no longer open
+// are skipped. Failures of single runs do
+// not stop the others and are returned together.
+func ProcessDeferredMerges(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	j *journal.Journal,
+	now time.Time,
+	log *logger.Logger,
+) (*MergeLaterResult, error) {
+	if cfg == nil || j == nil || log == nil {
+		return nil, errors.NewValidationError("config, journal and logger are required")
+	}
+
+	window, err := schedule.Parse(cfg.MergeWindow, cfg.MergeTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := j.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	result := &MergeLaterResult{}
+	var errs []error
+	task := progress.NewDefault(log).Start(ProgressMergeLater, len(entries))
+	for i, entry := range entries {
+		task.Update(i, entry.RunID)
+		if !entry.DeferredMerge.Pending() || entry.Status == journal.StatusAborted {
+			continue
+		}
+
+		if now.Before(entry.DeferredMerge.NotBefore) || (window != nil && !window.Contains(now)) {
+			result.WaitingRuns = append(result.WaitingRuns, entry.RunID)
+			continue
+		}
+
+		if err := enableDeferredMerge(ctx, cfg, j, entry, result, log); err != nil {
+			errs = append(errs, fmt.Errorf("run %s: %w", entry.RunID, err))
+		}
+	}
+
+	task.Finish(stderrors.Join(errs...))
+
+	result.Message = fmt.Sprintf("Enabled auto-merge for %d run(s), skipped %d, %d still waiting",
+		len(result.EnabledRuns), len(result.SkippedRuns), len(result.WaitingRuns))
+	if cfg.DryRun {
+		result.Message = fmt.Sprintf("Dry run completed. Would enable auto-merge for %d run(s), skip %d, %d still waiting",
+			len(result.EnabledRuns), len(result.SkippedRuns), len(result.WaitingRuns))
+	}
+
+	return result, stderrors.Join(errs...)
+}
+
+// enableDeferredMerge enables auto-merge for the merge request of one run
+func enableDeferredMerge(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	j *journal.Journal,
+	entry *journal.Entry,
+	result *MergeLaterResult,
+	log *logger.Logger,
+) error {
+	deferred := entry.DeferredMerge
+	runLog := log.WithFields(map[string]interface{}{
+		"run_id": entry.RunID,
+		"mr_id":  deferred.MergeRequestIID,
+	})
+
+	baseURL := cfg.GitLabURL
+	if baseURL == "" {
+		baseURL = entry.GitLabURL
+	}
+
+	client, err := newGitLabClient(cfg, baseURL)
+	if err != nil {
+		return fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	mrManager := gitlabapi.NewSimpleMergeRequestManager(client.GetGitLabClient(), entry.ProjectID)
+	mr, err := mrManager.GetMergeRequest(ctx, deferred.MergeRequestIID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect merge request %d: %w", deferred.MergeRequestIID, err)
+	}
+
+	outcome := journal.MergeOutcomeEnabled
+	switch {
+	case mr.State == gitlabapi.StateOpened && mr.Draft:
+		result.WaitingRuns = append(result.WaitingRuns, entry.RunID)
+		runLog.Info("Merge request is still a draft, deferred auto-merge keeps waiting")
+		return nil
+	case mr.State != gitlabapi.StateOpened:
+		outcome = journal.MergeOutcomeSkipped
+		result.SkippedRuns = append(result.SkippedRuns, entry.RunID)
+		runLog.WithField("state", mr.State).Warn("Merge request is no longer open, skipping deferred auto-merge")
+	case cfg.DryRun:
+		result.EnabledRuns = append(result.EnabledRuns, entry.RunID)
+		runLog.Info("Dry run mode: would enable merge when pipeline succeeds")
+		return nil
+	default:
+		if _, err := mrManager.SetMergeWhenPipelineSucceeds(ctx, deferred.MergeRequestIID,
+			&gitlabapi.SimpleMergeRequestOptions{
+				Squash:             deferred.Squash,
+				RemoveSourceBranch: deferred.RemoveSourceBranch,
+			}); err != nil {
+			return err
+		}
+		result.EnabledRuns = append(result.EnabledRuns, entry.RunID)
+		runLog.Info("Deferred merge when pipeline succeeds enabled")
+	}
+
+	if cfg.DryRun {
+		return nil
+	}
+	return j.ResolveDeferredMerge(entry, outcome)
+}
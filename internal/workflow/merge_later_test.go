@@ -0,0 +1,80 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_DeferredAutoMerge(t *testing.T) {
+	server, projectID := newTestProject(t)
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		GitLabURL:    server.URL(),
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		AutoMerge:    true,
+		MergeWindow:  "Mon-Fri 09:00-17:00",
+	}
+
+	runJournal, err := journal.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("journal.New() unexpected error: %v", err)
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+	updater.SetJournal(runJournal)
+	saturday := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	updater.now = func() time.Time { return saturday }
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	monday := time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)
+	if !result.MergeDeferredUntil.Equal(monday) || !strings.Contains(result.Message, "merge-later") {
+		t.Errorf("Execute() deferred until %s with message %q, want %s", result.MergeDeferredUntil, result.Message, monday)
+	}
+	if mrs := server.MergeRequests(projectID); len(mrs) != 1 || mrs[0].MergeWhenPipelineSucceeds {
+		t.Fatalf("merge requests = %+v, want one without auto-merge", mrs)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantEnabled int
+		wantWaiting int
+	}{
+		{name: "before not-before", now: saturday.Add(time.Hour), wantWaiting: 1},
+		{name: "outside working hours", now: monday.Add(-time.Minute + 24*time.Hour), wantWaiting: 1},
+		{name: "within working hours", now: monday.Add(time.Hour), wantEnabled: 1},
+		{name: "already resolved", now: monday.Add(2 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			later, err := ProcessDeferredMerges(context.Background(), cfg, runJournal, tt.now, logger.New(false))
+			if err != nil {
+				t.Fatalf("ProcessDeferredMerges() unexpected error: %v", err)
+			}
+			if len(later.EnabledRuns) != tt.wantEnabled || len(later.WaitingRuns) != tt.wantWaiting {
+				t.Errorf("ProcessDeferredMerges() = %+v, want %d enabled and %d waiting",
+					later, tt.wantEnabled, tt.wantWaiting)
+			}
+		})
+	}
+
+	if mrs := server.MergeRequests(projectID); len(mrs) != 1 || !mrs[0].MergeWhenPipelineSucceeds {
+		t.Errorf("merge requests = %+v, want auto-merge enabled", mrs)
+	}
+}
@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestMergeMergeRequest(t *testing.T) {
+	newServer := func(t *testing.T) (*gitlabtest.Server, int, int) {
+		server, projectID := newTestProject(t)
+		updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+			FilePath:     TestFilePath,
+			NewTag:       TestNewTag,
+			TargetBranch: TestTargetBranch,
+			BranchName:   TestBranchName,
+		})
+		result, err := updater.Execute(context.Background())
+		if err != nil {
+			t.Fatalf("Execute() unexpected error: %v", err)
+		}
+		return server, projectID, result.MergeRequest.IID
+	}
+	cfg := &config.CLIConfig{ProjectID: TestProjectID, GitLabToken: TestGitLabToken}
+
+	t.Run("waits for approvals and deletes the branch", func(t *testing.T) {
+		server, projectID, iid := newServer(t)
+		server.SetApprovalRule(projectID, "Platform team", 1)
+		server.AddApprover("bot-token", "approver-bot")
+
+		m := newMerger(&config.CLIConfig{ApproverToken: "bot-token"},
+			MergeOptions{MergeRequestIID: iid, WaitApprovals: true, Interval: time.Millisecond},
+			gitlabapi.NewAPIAdapter(server.Client()), projectID, logger.New(false))
+		m.approvals.SetApprover(gitlabapi.NewAPIAdapter(server.ClientWithToken("bot-token")))
+
+		result, err := m.merge(context.Background())
+		if err != nil {
+			t.Fatalf("merge() unexpected error: %v", err)
+		}
+		if result.MergeRequest.State != "merged" || !result.BranchDeleted || result.Approvals == nil ||
+			!result.Approvals.Approved {
+			t.Errorf("merge() = %+v, want an approved, merged merge request without its branch", result)
+		}
+		if server.BranchExists(projectID, TestBranchName) {
+			t.Error("source branch still exists after the merge")
+		}
+		if content, _ := server.File(projectID, TestTargetBranch, TestFilePath); !strings.Contains(content, TestNewTag) {
+			t.Errorf("target branch content = %q, want the new tag", content)
+		}
+	})
+
+	t.Run("approval timeout", func(t *testing.T) {
+		server, projectID, iid := newServer(t)
+		server.SetApprovalRule(projectID, "Platform team", 1)
+
+		m := newMerger(cfg, MergeOptions{
+			MergeRequestIID: iid,
+			WaitApprovals:   true,
+			ApprovalTimeout: 20 * time.Millisecond,
+			Interval:        5 * time.Millisecond,
+		}, gitlabapi.NewAPIAdapter(server.Client()), projectID, logger.New(false))
+		if _, err := m.merge(context.Background()); err == nil || !strings.Contains(err.Error(), "1 approval(s) left") {
+			t.Errorf("merge() error = %v, want an approval timeout", err)
+		}
+		if mrs := server.MergeRequests(projectID); mrs[0].State != "opened" {
+			t.Errorf("merge request state = %s, want it left open", mrs[0].State)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		server, projectID, iid := newServer(t)
+		m := newMerger(&config.CLIConfig{DryRun: true}, MergeOptions{MergeRequestIID: iid},
+			gitlabapi.NewAPIAdapter(server.Client()), projectID, logger.New(false))
+		result, err := m.merge(context.Background())
+		if err != nil || !strings.HasPrefix(result.Message, "Dry run completed") {
+			t.Errorf("merge() = %+v, %v, want a dry run", result, err)
+		}
+		if !server.BranchExists(projectID, TestBranchName) || server.MergeRequests(projectID)[0].State != "opened" {
+			t.Error("dry run changed the merge request")
+		}
+	})
+
+	t.Run("refuses foreign merge requests", func(t *testing.T) {
+		server, projectID, _ := newServer(t)
+		server.AddBranch(projectID, "manual-change", TestTargetBranch, false)
+		// Anyone can add the tool's label; it does not make the merge request the tool's
+		foreign, _, err := server.Client().MergeRequests.CreateMergeRequest(projectID, &gitlab.CreateMergeRequestOptions{
+			Title:        gitlab.Ptr("Manual change"),
+			Labels:       &gitlab.LabelOptions{identity.DefaultLabel},
+			SourceBranch: gitlab.Ptr("manual-change"),
+			TargetBranch: gitlab.Ptr(TestTargetBranch),
+		})
+		if err != nil {
+			t.Fatalf("Failed to create merge request: %v", err)
+		}
+
+		m := newMerger(cfg, MergeOptions{MergeRequestIID: foreign.IID},
+			gitlabapi.NewAPIAdapter(server.Client()), projectID, logger.New(false))
+		if _, err := m.merge(context.Background()); errors.GetErrorCode(err) != errors.ErrCodeValidation {
+			t.Errorf("merge() error = %v, want a validation error", err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// MaxConflictRecreateAttempts bounds how often one run replaces a conflicting
+	// merge request with --recreate-on-conflict
+	MaxConflictRecreateAttempts = 2
+	// RecreateNoteFormat defines the note left on merge requests closed because of conflicts
+	RecreateNoteFormat = "Closed by go-tag-updater: this merge request has merge conflicts with %s. " +
+		"The update is re-applied on a new branch from the latest %s."
+)
+
+// gateOnConflicts re-checks the merge request when --watch-conflicts is set. GitLab
+// may report merge conflicts once the target branch moves; such a merge request is
+// labeled needs-rebase, rebased with --auto-rebase, and conflicts that outlast the
+// timeout turn into an error of the run. With --recreate-on-conflict, conflicts that
+// remain are resolved by replacing the merge request instead of waiting.
+func (stu *SimpleTagUpdater) gateOnConflicts(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	err error,
+) (*SimpleUpdateResult, error) {
+	if err != nil || !stu.config.WatchConflicts || stu.config.DryRun || result.MergeRequest == nil {
+		return result, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		endPhase := stu.beginPhase(ctx, PhaseMergeability)
+		mrIID := result.MergeRequest.IID
+		mrLog := stu.logger.WithField("mr_id", mrIID)
+
+		var mergeability *gitlabapi.MergeabilityResult
+		report, finish := stu.startProgress(ProgressMergeability)
+		stu.mergeability.SetProgress(report)
+		mergeability, err = stu.mergeability.WaitForMergeable(ctx, mrIID, stu.config.ConflictTimeout,
+			stu.config.AutoRebase)
+		finish(err)
+		result.Mergeability = mergeability
+		endPhase(err)
+
+		if err != nil && stu.config.RecreateOnConflict && attempt < MaxConflictRecreateAttempts &&
+			errors.GetErrorCode(err) == errors.ErrCodeMergeConflict {
+			mrLog.WithError(err).Warn("Merge request has conflicts, recreating it from the latest target branch")
+			if result, err = stu.recreateOnConflict(ctx, result); err != nil || result.MergeRequest == nil {
+				return result, err
+			}
+			continue
+		}
+		if err != nil {
+			result.Success = false
+			mrLog.WithError(err).Error("Merge request conflicts were not resolved")
+			return result, fmt.Errorf("merge conflicts of MR !%d not resolved: %w", mrIID, err)
+		}
+
+		if mergeability.HadConflicts {
+			mrLog.WithFields(map[string]interface{}{
+				"label":            gitlabapi.NeedsRebaseLabel,
+				"rebase_requested": mergeability.RebaseRequested,
+				"checks":           mergeability.Checks,
+			}).Info("Merge request conflicts resolved")
+			result.Message += "; merge conflicts resolved"
+		}
+		return result, nil
+	}
+}
+
+// recreateOnConflict closes a conflicting merge request, deletes its branch and
+// re-applies the update on a new branch from the latest source ref. The run is
+// skipped when the tag reached the target branch in the meantime.
+func (stu *SimpleTagUpdater) recreateOnConflict(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+) (*SimpleUpdateResult, error) {
+	mrIID := result.MergeRequest.IID
+	note := fmt.Sprintf(RecreateNoteFormat, stu.config.TargetBranch, stu.sourceRef())
+	if _, err := stu.mrManager.CloseMergeRequest(ctx, mrIID, note); err != nil {
+		return result, fmt.Errorf("failed to close conflicting merge request %d: %w", mrIID, err)
+	}
+	if err := stu.branchMgr.DeleteBranch(ctx, result.BranchName); err != nil {
+		return result, fmt.Errorf("failed to delete branch %s: %w", result.BranchName, err)
+	}
+	result.RecreatedMergeRequests = append(result.RecreatedMergeRequests, mrIID)
+	result.MergeRequest = nil
+	result.FileUpdated = false
+	result.BranchURL = ""
+	result.CommitURL = ""
+
+	stu.logger.WithFields(map[string]interface{}{
+		"mr_id":       mrIID,
+		"branch_name": result.BranchName,
+	}).Info("Conflicting merge request closed")
+
+	endPhase := stu.beginPhase(ctx, PhaseUpdate)
+	newContent, err := stu.validateAndUpdateContent(ctx)
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		endPhase(nil)
+		return stu.handleNoChanges(result), nil
+	}
+	if err != nil {
+		endPhase(err)
+		return result, err
+	}
+
+	branchName, err := stu.prepareBranchName(ctx)
+	if err != nil {
+		endPhase(err)
+		return result, err
+	}
+	result.BranchName = branchName
+
+	result, err = stu.executeUpdate(ctx, result, newContent, branchName)
+	endPhase(err)
+	if err != nil {
+		return result, err
+	}
+	result.Message += fmt.Sprintf("; replaces conflicting MR !%d", mrIID)
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package workflow
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_WatchConflicts(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoRebase bool
+		wantErr    bool
+	}{
+		{name: "auto rebase resolves conflicts", autoRebase: true},
+		{name: "conflicts outlast the timeout", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.SetConflicts(projectID, true, false)
+
+			cfg := &config.CLIConfig{
+				ProjectID:       TestProjectID,
+				GitLabToken:     TestGitLabToken,
+				FilePath:        TestFilePath,
+				NewTag:          TestNewTag,
+				TargetBranch:    TestTargetBranch,
+				BranchName:      TestBranchName,
+				WatchConflicts:  true,
+				AutoRebase:      tt.autoRebase,
+				ConflictTimeout: 50 * time.Millisecond,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+			updater.mergeability.SetInterval(time.Millisecond)
+
+			result, err := updater.Execute(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && errors.ExitCode(err) != errors.ExitCodeConflict {
+				t.Errorf("Execute() exit code = %d, want %d", errors.ExitCode(err), errors.ExitCodeConflict)
+			}
+			if result.Mergeability == nil || !result.Mergeability.HadConflicts || result.Success == tt.wantErr {
+				t.Errorf("Execute() = %+v, mergeability %+v", result, result.Mergeability)
+			}
+
+			mr := server.MergeRequests(projectID)[0]
+			hasLabel := slices.Contains(mr.Labels, gitlabapi.NeedsRebaseLabel)
+			if hasLabel != tt.wantErr {
+				t.Errorf("labels = %v, want needs-rebase only while conflicts remain", mr.Labels)
+			}
+		})
+	}
+}
+
+// conflictClearingHook stops simulating merge conflicts once the first mergeability
+// check failed, as if the target branch had settled before the merge request is recreated
+type conflictClearingHook struct {
+	server    *gitlabtest.Server
+	projectID int
+}
+
+func (h *conflictClearingHook) OnPhaseStart(context.Context, Phase) {}
+
+func (h *conflictClearingHook) OnPhaseEnd(_ context.Context, phase Phase, err error) {
+	if phase == PhaseMergeability && err != nil {
+		h.server.SetConflicts(h.projectID, false, false)
+	}
+}
+
+func TestSimpleTagUpdater_RecreateOnConflict(t *testing.T) {
+	server, projectID := newTestProject(t)
+	server.SetConflicts(projectID, true, true)
+
+	cfg := &config.CLIConfig{
+		ProjectID:          TestProjectID,
+		GitLabToken:        TestGitLabToken,
+		FilePath:           TestFilePath,
+		NewTag:             TestNewTag,
+		TargetBranch:       TestTargetBranch,
+		BranchName:         TestBranchName,
+		WatchConflicts:     true,
+		AutoRebase:         true,
+		RecreateOnConflict: true,
+		ConflictTimeout:    time.Minute,
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+	updater.mergeability.SetInterval(time.Millisecond)
+	updater.AddPhaseHook(&conflictClearingHook{server: server, projectID: projectID})
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.Success || len(result.RecreatedMergeRequests) != 1 || result.RecreatedMergeRequests[0] != 1 {
+		t.Fatalf("Execute() = %+v, want one recreated merge request", result)
+	}
+	if result.MergeRequest == nil || result.MergeRequest.IID != 2 {
+		t.Fatalf("MergeRequest = %+v, want the replacement !2", result.MergeRequest)
+	}
+
+	mrs := server.MergeRequests(projectID)
+	if len(mrs) != 2 || mrs[0].State != gitlabapi.StateClosed || mrs[1].State != gitlabapi.StateOpened {
+		t.Fatalf("merge requests = %+v, want the conflicting one closed and its replacement open", mrs)
+	}
+	if notes := server.Notes(projectID, 1); len(notes) != 1 || !strings.Contains(notes[0], "re-applied") {
+		t.Errorf("notes = %v, want the recreate note", notes)
+	}
+	if !server.BranchExists(projectID, TestBranchName) {
+		t.Errorf("branch %s was not recreated", TestBranchName)
+	}
+	if rebases := server.Rebases(projectID, 1); rebases != 1 {
+		t.Errorf("rebases = %d, want one rebase before recreating", rebases)
+	}
+}
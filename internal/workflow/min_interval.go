@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// MinIntervalHistoryDepth bounds the commits of the file read to find its last
+	// non-merge commit
+	MinIntervalHistoryDepth = 5
+)
+
+// validateRecentUpdate checks the configured reaction to a recent update by the tool
+func validateRecentUpdate(minInterval time.Duration, onRecentUpdate string) error {
+	if minInterval < 0 {
+		return errors.NewConfigError("min-interval cannot be negative")
+	}
+	switch onRecentUpdate {
+	case "", config.RecentUpdateSkip, config.RecentUpdateWarn:
+		return nil
+	default:
+		return errors.NewConfigError(fmt.Sprintf("invalid recent update action %q: expected %s or %s",
+			onRecentUpdate, config.RecentUpdateSkip, config.RecentUpdateWarn))
+	}
+}
+
+// checkMinInterval guards against update loops between automation systems. When the
+// last commit to the file on the source ref carries the tool's trailer and is more
+// recent than --min-interval, the update is skipped, or only warned about when
+// configured so. It reports whether the update was skipped.
+func (stu *SimpleTagUpdater) checkMinInterval(ctx context.Context, result *SimpleUpdateResult) (bool, error) {
+	if stu.config.MinInterval <= 0 {
+		return false, nil
+	}
+
+	commits, err := stu.fileManager.GetFileHistory(ctx, stu.config.FilePath, stu.sourceRef(),
+		MinIntervalHistoryDepth)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the history of %s: %w", stu.config.FilePath, err)
+	}
+
+	for _, commit := range commits {
+		// Merge commits only bring in the change; the commit that made it follows
+		if len(commit.ParentIDs) > 1 {
+			continue
+		}
+
+		runID, own := identity.CommitRunID(commit.Message)
+		if !own || commit.CommittedDate == nil {
+			return false, nil
+		}
+		age := stu.now().Sub(*commit.CommittedDate)
+		if age >= stu.config.MinInterval {
+			return false, nil
+		}
+
+		recentLog := stu.logger.WithFields(map[string]interface{}{
+			"file_path":    stu.config.FilePath,
+			"commit_id":    commit.ShortID,
+			"last_run_id":  runID,
+			"age":          age.Round(time.Second).String(),
+			"min_interval": stu.config.MinInterval.String(),
+		})
+		if stu.config.OnRecentUpdate == config.RecentUpdateWarn {
+			recentLog.Warn("File was updated by go-tag-updater within the minimum interval; updating anyway")
+			return false, nil
+		}
+
+		recentLog.Info("File was updated by go-tag-updater within the minimum interval, skipping update")
+		result.Success = true
+		result.Skipped = true
+		result.Message = fmt.Sprintf("Skipped: %s was updated by go-tag-updater run %s %s ago, within --min-interval %s",
+			stu.config.FilePath, runID, age.Round(time.Second), stu.config.MinInterval)
+		return true, nil
+	}
+	return false, nil
+}
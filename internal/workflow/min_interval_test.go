@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+func TestSimpleTagUpdater_MinInterval(t *testing.T) {
+	const lastRunID = "20250101T000000Z-abcdef12"
+
+	tests := []struct {
+		name           string
+		minInterval    time.Duration
+		onRecentUpdate string
+		humanCommit    bool
+		later          time.Duration
+		wantSkipped    bool
+	}{
+		{name: "disabled"},
+		{name: "recent update skipped", minInterval: time.Hour, wantSkipped: true},
+		{name: "recent update warned", minInterval: time.Hour, onRecentUpdate: config.RecentUpdateWarn},
+		{name: "update older than the interval", minInterval: time.Hour, later: 2 * time.Hour},
+		{name: "last commit by someone else", minInterval: time.Hour, humanCommit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gitlabtest.NewServer(t)
+			projectID := server.AddProject(TestProjectID)
+			fileManager := gitlabapi.NewFileManager(server.Client(), projectID)
+			if _, err := fileManager.UpdateFile(context.Background(), TestFilePath, &gitlabapi.FileUpdateOptions{
+				Branch:        TestTargetBranch,
+				Content:       TestYAMLContent,
+				CommitMessage: identity.WithCommitTrailer("Update tag to "+TestOldTag, lastRunID),
+			}); err != nil {
+				t.Fatalf("Failed to commit the previous update: %v", err)
+			}
+			if tt.humanCommit {
+				server.SetFile(projectID, TestTargetBranch, TestFilePath, TestYAMLContent+"# reviewed\n")
+			}
+
+			cfg := &config.CLIConfig{
+				ProjectID:      TestProjectID,
+				GitLabToken:    TestGitLabToken,
+				FilePath:       TestFilePath,
+				NewTag:         TestNewTag,
+				TargetBranch:   TestTargetBranch,
+				BranchName:     TestBranchName,
+				MinInterval:    tt.minInterval,
+				OnRecentUpdate: tt.onRecentUpdate,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+			updater.now = func() time.Time { return time.Now().Add(tt.later) }
+
+			result, err := updater.Execute(context.Background())
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Skipped != tt.wantSkipped || !result.Success {
+				t.Fatalf("Execute() = %+v, want skipped %v", result, tt.wantSkipped)
+			}
+			if tt.wantSkipped && !strings.Contains(result.Message, lastRunID) {
+				t.Errorf("Message = %q, want the run that updated the file", result.Message)
+			}
+			wantMRs := 1
+			if tt.wantSkipped {
+				wantMRs = 0
+			}
+			if got := len(server.MergeRequests(projectID)); got != wantMRs {
+				t.Errorf("merge requests = %d, want %d", got, wantMRs)
+			}
+		})
+	}
+}
+
+func TestValidateRecentUpdate(t *testing.T) {
+	tests := []struct {
+		name           string
+		minInterval    time.Duration
+		onRecentUpdate string
+		wantErr        bool
+	}{
+		{name: "defaults"},
+		{name: "skip", minInterval: time.Minute, onRecentUpdate: config.RecentUpdateSkip},
+		{name: "warn", minInterval: time.Minute, onRecentUpdate: config.RecentUpdateWarn},
+		{name: "negative interval", minInterval: -time.Minute, wantErr: true},
+		{name: "unknown action", onRecentUpdate: "fail", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRecentUpdate(tt.minInterval, tt.onRecentUpdate); (err != nil) != tt.wantErr {
+				t.Errorf("validateRecentUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
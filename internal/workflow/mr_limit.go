@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// checkOpenMergeRequestLimit refuses to open another merge request when the project
+// already has the configured number of open merge requests created by the tool.
+// A merge request for this same update does not count, so retried runs can converge.
+func (stu *SimpleTagUpdater) checkOpenMergeRequestLimit(ctx context.Context) error {
+	if stu.config.MaxOpenMRs <= 0 {
+		return nil
+	}
+
+	mrs, err := stu.mrManager.ListOpenMergeRequests(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to count open automation merge requests: %w", err)
+	}
+
+	tool := identity.New()
+	var open []string
+	for _, mr := range mrs {
+		if stu.isSameUpdate(mr) {
+			continue
+		}
+		if tool.OwnsMergeRequest(&identity.Event{Description: mr.Description}) {
+			open = append(open, fmt.Sprintf("!%d", mr.IID))
+		}
+	}
+
+	if len(open) < stu.config.MaxOpenMRs {
+		return nil
+	}
+
+	stu.logger.WithFields(map[string]interface{}{
+		"open_mrs":     len(open),
+		"max_open_mrs": stu.config.MaxOpenMRs,
+	}).Error("Open merge request limit reached")
+
+	return errors.NewPolicyErrorWithContext(fmt.Sprintf(
+		"project already has %d open merge requests created by go-tag-updater (limit %d); "+
+			"merge or close some of them before opening more",
+		len(open), stu.config.MaxOpenMRs), strings.Join(open, ", "))
+}
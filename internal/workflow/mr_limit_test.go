@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_ExecuteOpenMergeRequestLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxOpenMRs int
+		toolMRs    int
+		// manualMRs are opened after the tool's, so they are listed first
+		manualMRs int
+		wantErr   bool
+	}{
+		{name: "no limit", toolMRs: 2, manualMRs: 1},
+		{name: "below limit", maxOpenMRs: 3, toolMRs: 2, manualMRs: 1},
+		{name: "limit reached", maxOpenMRs: 2, toolMRs: 2, manualMRs: 1, wantErr: true},
+		{
+			name:       "limit reached beyond the first page",
+			maxOpenMRs: 2,
+			toolMRs:    2,
+			manualMRs:  gitlabapi.OpenMergeRequestsPageSize,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			mrManager := gitlabapi.NewSimpleMergeRequestManager(server.Client(), projectID)
+			for i := 0; i < tt.toolMRs+tt.manualMRs; i++ {
+				description := "Manual change"
+				if i < tt.toolMRs {
+					description = identity.MarkerPrefix + " file=\"other.yaml\" -->"
+				}
+				branch := fmt.Sprintf("feature-%d", i)
+				server.AddBranch(projectID, branch, TestTargetBranch, false)
+				if _, err := mrManager.CreateMergeRequest(context.Background(), &gitlabapi.SimpleMergeRequestOptions{
+					Title:        branch,
+					Description:  description,
+					SourceBranch: branch,
+					TargetBranch: TestTargetBranch,
+				}); err != nil {
+					t.Fatalf("CreateMergeRequest() unexpected error: %v", err)
+				}
+			}
+
+			cfg := &config.CLIConfig{
+				ProjectID:    TestProjectID,
+				GitLabToken:  TestGitLabToken,
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+				MaxOpenMRs:   tt.maxOpenMRs,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			_, err := updater.Execute(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if code := errors.GetErrorCode(err); code != errors.ErrCodePolicyViolation {
+					t.Errorf("Execute() error code = %d, want %d", code, errors.ErrCodePolicyViolation)
+				}
+				if server.BranchExists(projectID, TestBranchName) {
+					t.Error("Execute() should not create a branch when the limit is reached")
+				}
+			}
+		})
+	}
+}
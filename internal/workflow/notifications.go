@@ -0,0 +1,97 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+)
+
+// newNotifier creates the dispatcher of the notification destinations of cfg; it is
+// nil when none is configured
+func newNotifier(cfg *config.CLIConfig) (*notify.Dispatcher, error) {
+	notifier, err := notify.New(notify.Options{
+		SlackWebhookURL: cfg.NotifySlackWebhook,
+		TeamsWebhookURL: cfg.NotifyTeamsWebhook,
+		WebhookURL:      cfg.NotifyWebhookURL,
+		Email: notify.EmailOptions{
+			Host:     cfg.NotifyEmailHost,
+			Port:     cfg.NotifyEmailPort,
+			TLS:      cfg.NotifyEmailTLS,
+			From:     cfg.NotifyEmailFrom,
+			To:       cfg.NotifyEmailTo,
+			Username: cfg.NotifyEmailUsername,
+			Password: cfg.NotifyEmailPassword,
+		},
+		Timeout: cfg.NotifyTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure notifications: %w", err)
+	}
+	return notifier, nil
+}
+
+// SetNotifier enables notifications of the outcome of this run
+func (stu *SimpleTagUpdater) SetNotifier(notifier *notify.Dispatcher) {
+	stu.notifier = notifier
+}
+
+// notifyRun notifies of the outcome of the run. Dry runs and updates skipped because
+// there is nothing to change are not notified, and notification failures never fail
+// the update.
+func (stu *SimpleTagUpdater) notifyRun(ctx context.Context, result *SimpleUpdateResult, runErr error) {
+	if stu.notifier == nil || stu.config.DryRun || (runErr == nil && (result == nil || result.Skipped)) {
+		return
+	}
+
+	event := newRunEvent(stu.config, runErr)
+	event.RunID = stu.runID
+	event.File = stu.targetFiles()
+	event.OldTag = stu.oldTag
+	if result != nil {
+		event.Branch = result.BranchName
+		event.Message = result.Message
+		for _, count := range result.FileChanges {
+			event.Files = append(event.Files, notify.FileChange{Path: count.FilePath, Changes: count.Changes})
+		}
+		if result.MergeRequest != nil {
+			event.MergeRequestIID = result.MergeRequest.IID
+			event.MergeRequestURL = result.MergeRequest.WebURL
+		}
+	}
+
+	if err := stu.notifier.Notify(ctx, event); err != nil {
+		stu.logger.WithError(err).WithField("run_id", stu.runID).Warn("Failed to send run notification")
+	}
+}
+
+// newRunEvent creates the notification event of a run of cfg failing with runErr, or
+// succeeding when runErr is nil
+func newRunEvent(cfg *config.CLIConfig, runErr error) *notify.Event {
+	event := &notify.Event{
+		Success: runErr == nil,
+		Project: cfg.ProjectID,
+		File:    cfg.FilePath,
+		NewTag:  cfg.NewTag,
+	}
+	if cfg.RepoPathGlob != "" {
+		event.File = cfg.RepoPathGlob
+	}
+	if runErr != nil {
+		event.Error = runErr.Error()
+	}
+	return event
+}
+
+// notifyFailure notifies of a run that failed before its workflow started
+func notifyFailure(ctx context.Context, notifier *notify.Dispatcher, cfg *config.CLIConfig, runErr error,
+	log *logger.Logger) {
+	if notifier == nil || cfg.DryRun {
+		return
+	}
+	if err := notifier.Notify(ctx, newRunEvent(cfg, runErr)); err != nil {
+		log.WithError(err).Warn("Failed to send run notification")
+	}
+}
@@ -0,0 +1,72 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+)
+
+// eventRecorder is a notifier recording the events it receives
+type eventRecorder struct {
+	events []*notify.Event
+}
+
+func (r *eventRecorder) Notify(_ context.Context, event *notify.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestSimpleTagUpdater_Notifications(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		dryRun      bool
+		wantEvents  int
+		wantSuccess bool
+	}{
+		{name: "merge request created", content: TestYAMLContent, wantEvents: 1, wantSuccess: true},
+		{name: "dry run", content: TestYAMLContent, dryRun: true},
+		{name: "failure", content: "version: [broken\n", wantEvents: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gitlabtest.NewServer(t)
+			projectID := server.AddProject(TestProjectID)
+			server.SetFile(projectID, TestTargetBranch, TestFilePath, tt.content)
+
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				BranchName:   TestBranchName,
+				DryRun:       tt.dryRun,
+			})
+			recorder := &eventRecorder{}
+			updater.SetNotifier(notify.NewWithNotifiers(recorder))
+
+			result, err := updater.Execute(context.Background())
+			if len(recorder.events) != tt.wantEvents {
+				t.Fatalf("received %d notifications, want %d", len(recorder.events), tt.wantEvents)
+			}
+			if tt.wantEvents == 0 {
+				return
+			}
+
+			event := recorder.events[0]
+			if event.Success != tt.wantSuccess || event.NewTag != TestNewTag || event.Project != TestProjectID {
+				t.Errorf("event = %+v, want success %v", event, tt.wantSuccess)
+			}
+			if tt.wantSuccess {
+				if err != nil || event.MergeRequestIID != result.MergeRequest.IID || event.MergeRequestURL == "" {
+					t.Errorf("event = %+v, want the merge request of %+v", event, result)
+				}
+			} else if event.Error == "" {
+				t.Errorf("event = %+v, want the error of the run", event)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// OriginalBackupSuffix is appended to the file path of the original file committed
+	// with --original-backup=file
+	OriginalBackupSuffix = ".orig"
+	// OriginalBackupMaxDescriptionBytes bounds the original content embedded in the merge
+	// request description; larger files get the diff restoring them instead
+	OriginalBackupMaxDescriptionBytes = 64 * 1024
+	// minCodeFence is the shortest Markdown code fence
+	minCodeFence = 3
+)
+
+// validateOriginalBackup checks where the original file is kept
+func validateOriginalBackup(originalBackup string) error {
+	switch originalBackup {
+	case "", config.OriginalBackupNone, config.OriginalBackupDescription, config.OriginalBackupFile:
+		return nil
+	default:
+		return errors.NewConfigError(fmt.Sprintf("invalid original backup %q: expected %s, %s or %s", originalBackup,
+			config.OriginalBackupNone, config.OriginalBackupDescription, config.OriginalBackupFile))
+	}
+}
+
+// originalBackupPath returns the path the original file is committed to next to the file
+func originalBackupPath(filePath string) string {
+	return filePath + OriginalBackupSuffix
+}
+
+// checkOriginalBackupPolicy applies the least-privilege file scope to the original file
+// committed next to the updated one
+func (stu *SimpleTagUpdater) checkOriginalBackupPolicy() error {
+	if stu.config.OriginalBackup != config.OriginalBackupFile {
+		return nil
+	}
+	return stu.policy.CheckFile(originalBackupPath(stu.config.FilePath))
+}
+
+// commitChanges returns the files the update commits: the updated file and, with
+// --original-backup=file, its original content next to it
+func (stu *SimpleTagUpdater) commitChanges(newContent string) []gitlabapi.FileChange {
+	changes := []gitlabapi.FileChange{{FilePath: stu.config.FilePath, Content: newContent}}
+	if stu.config.OriginalBackup == config.OriginalBackupFile && stu.originalContent != "" {
+		changes = append(changes, gitlabapi.FileChange{
+			FilePath: originalBackupPath(stu.config.FilePath),
+			Content:  stu.originalContent,
+		})
+	}
+	return changes
+}
+
+// originalBackupSection returns the collapsed section of the merge request description
+// holding the original file with --original-backup=description, or the diff restoring
+// it when the file is too large
+func (stu *SimpleTagUpdater) originalBackupSection() string {
+	if stu.config.OriginalBackup != config.OriginalBackupDescription || stu.originalContent == "" {
+		return ""
+	}
+
+	filePath := stu.config.FilePath
+	summary := fmt.Sprintf("Original %s before this update", path.Base(filePath))
+	language := "yaml"
+	content := stu.originalContent
+	if len(content) > OriginalBackupMaxDescriptionBytes {
+		content = diff.Unified("a/"+filePath, "b/"+filePath, stu.updatedContent, stu.originalContent,
+			diff.DefaultContextLines)
+		summary = fmt.Sprintf("Patch restoring the original %s", path.Base(filePath))
+		language = "diff"
+		if len(content) > OriginalBackupMaxDescriptionBytes {
+			return fmt.Sprintf("> The original %s is too large to include; restore it from the target branch history.\n\n",
+				filePath)
+		}
+	}
+
+	fence := codeFence(content)
+	return fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s%s\n%s\n%s\n\n</details>\n\n",
+		summary, fence, language, strings.TrimSuffix(content, "\n"), fence)
+}
+
+// codeFence returns a Markdown code fence longer than any run of backticks in content
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(minCodeFence, longest+1))
+}
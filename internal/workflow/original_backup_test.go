@@ -0,0 +1,79 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_OriginalBackup(t *testing.T) {
+	tests := []struct {
+		name            string
+		originalBackup  string
+		wantFile        bool
+		wantDescription bool
+	}{
+		{name: "none", originalBackup: config.OriginalBackupNone},
+		{name: "description", originalBackup: config.OriginalBackupDescription, wantDescription: true},
+		{name: "file", originalBackup: config.OriginalBackupFile, wantFile: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			cfg := &config.CLIConfig{
+				ProjectID:      TestProjectID,
+				GitLabToken:    TestGitLabToken,
+				FilePath:       TestFilePath,
+				NewTag:         TestNewTag,
+				TargetBranch:   TestTargetBranch,
+				BranchName:     TestBranchName,
+				OriginalBackup: tt.originalBackup,
+			}
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			result, err := updater.Execute(context.Background())
+			if err != nil || result.MergeRequest == nil {
+				t.Fatalf("Execute() = %+v, %v, want a merge request", result, err)
+			}
+
+			original, exists := server.File(projectID, TestBranchName, TestFilePath+OriginalBackupSuffix)
+			if exists != tt.wantFile || (tt.wantFile && original != TestYAMLContent) {
+				t.Errorf("original file = %q, exists %v, want exists %v", original, exists, tt.wantFile)
+			}
+			description := result.MergeRequest.Description
+			hasSection := strings.Contains(description, "<details>") && strings.Contains(description, TestYAMLContent)
+			if hasSection != tt.wantDescription {
+				t.Errorf("description = %q, want original section %v", description, tt.wantDescription)
+			}
+		})
+	}
+
+	invalid := &config.CLIConfig{
+		ProjectID:      TestProjectID,
+		GitLabToken:    TestGitLabToken,
+		FilePath:       TestFilePath,
+		NewTag:         TestNewTag,
+		OriginalBackup: "artifact",
+	}
+	if _, err := NewSimpleTagUpdater(invalid, logger.New(false)); err == nil {
+		t.Error("NewSimpleTagUpdater() expected an error for an unknown original backup")
+	}
+}
+
+func TestCodeFence(t *testing.T) {
+	tests := map[string]string{
+		"image: nginx":           "```",
+		"run: `date`":            "```",
+		"script: |\n  ```\n  ``": "````",
+	}
+	for content, want := range tests {
+		if got := codeFence(content); got != want {
+			t.Errorf("codeFence(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
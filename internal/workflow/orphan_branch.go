@@ -0,0 +1,36 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+)
+
+// OrphanBranchCleanupTimeout bounds the removal of the branch of a failed run; it runs
+// even when an interrupt or --timeout canceled the run itself
+const OrphanBranchCleanupTimeout = 30 * time.Second
+
+// removeOrphanBranch deletes the branch this run created when the run failed or was
+// interrupted before a merge request was opened for it, so no orphan branch is left
+// behind. Reused branches and --keep-branch-on-failure keep it.
+func (stu *SimpleTagUpdater) removeOrphanBranch(ctx context.Context, result *SimpleUpdateResult, runErr error) {
+	if runErr == nil || stu.createdBranch == "" || result.MergeRequest != nil || stu.config.KeepBranchOnFailure {
+		return
+	}
+
+	branchLog := stu.logger.WithField("branch_name", stu.createdBranch)
+	if stderrors.Is(runErr, context.Canceled) || stderrors.Is(runErr, context.DeadlineExceeded) {
+		branchLog.Warn("Run interrupted before its merge request was opened, removing its branch")
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), OrphanBranchCleanupTimeout)
+	defer cancel()
+	if err := stu.branchMgr.DeleteBranch(cleanupCtx, stu.createdBranch); err != nil {
+		branchLog.WithError(err).Warn("Failed to remove the branch of the failed run; " +
+			"run 'go-tag-updater abort' to remove it")
+		return
+	}
+
+	result.BranchURL = ""
+	branchLog.Info("Removed the branch of the failed run; pass --keep-branch-on-failure to keep it")
+}
@@ -0,0 +1,84 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+)
+
+func TestSimpleTagUpdater_RemoveOrphanBranch(t *testing.T) {
+	const otherBranch = "update-tag/v1.1.0"
+
+	tests := []struct {
+		name       string
+		interrupt  bool
+		keepBranch bool
+		wantBranch bool
+	}{
+		{name: "merge request creation fails"},
+		{name: "merge request creation fails keeping the branch", keepBranch: true, wantBranch: true},
+		{name: "interrupted while queued", interrupt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			cfg := &config.CLIConfig{
+				ProjectID:           TestProjectID,
+				GitLabToken:         TestGitLabToken,
+				FilePath:            TestFilePath,
+				NewTag:              TestNewTag,
+				TargetBranch:        TestTargetBranch,
+				BranchName:          TestBranchName,
+				KeepBranchOnFailure: tt.keepBranch,
+			}
+			ctx := context.Background()
+			if tt.interrupt {
+				// The queue policy waits on the conflicting merge request until the run is canceled
+				server.AddBranch(projectID, otherBranch, TestTargetBranch, false)
+				server.SetFile(projectID, otherBranch, TestFilePath, strings.Replace(TestYAMLContent, TestOldTag, "v1.1.0", 1))
+				mrManager := gitlabapi.NewSimpleMergeRequestManager(server.Client(), projectID)
+				if _, err := mrManager.CreateMergeRequest(ctx, &gitlabapi.SimpleMergeRequestOptions{
+					Title:        "Update tag to v1.1.0",
+					SourceBranch: otherBranch,
+					TargetBranch: TestTargetBranch,
+				}); err != nil {
+					t.Fatalf("Failed to create conflicting merge request: %v", err)
+				}
+				cfg.ConflictPolicy = config.ConflictPolicyQueue
+				cfg.CheckFileConflicts = true
+				cfg.ConflictTimeout = time.Minute
+
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+				defer cancel()
+			} else {
+				server.FailRequests(http.MethodPost, "/merge_requests", http.StatusInternalServerError)
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+			updater.conflicts.SetInterval(time.Millisecond)
+
+			result, err := updater.Execute(ctx)
+			if err == nil {
+				t.Fatal("Execute() expected error")
+			}
+			if tt.interrupt && !stderrors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Execute() error = %v, want the deadline of the run", err)
+			}
+			if got := server.BranchExists(projectID, TestBranchName); got != tt.wantBranch {
+				t.Errorf("branch exists = %v, want %v", got, tt.wantBranch)
+			}
+			if got := result.BranchURL != ""; got != tt.wantBranch {
+				t.Errorf("BranchURL = %q, want it only for a kept branch", result.BranchURL)
+			}
+		})
+	}
+}
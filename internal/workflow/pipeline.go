@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+)
+
+// gateOnPipeline waits for the merge request pipeline when --wait-pipeline is set
+// and turns a failed or timed out pipeline into an error of the run. A skipped
+// pipeline ran nothing to gate on, so it is reported without failing the run.
+func (stu *SimpleTagUpdater) gateOnPipeline(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	err error,
+) (*SimpleUpdateResult, error) {
+	if err != nil || !stu.config.WaitPipeline || stu.config.DryRun || result.MergeRequest == nil {
+		return result, err
+	}
+
+	endPhase := stu.beginPhase(ctx, PhasePipeline)
+	mrIID := result.MergeRequest.IID
+	stu.logger.WithFields(map[string]interface{}{
+		"mr_id":   mrIID,
+		"timeout": stu.config.PipelineTimeout.String(),
+	}).Info("Waiting for merge request pipeline")
+
+	report, finish := stu.startProgress(ProgressPipeline)
+	stu.pipelineWatcher.SetProgress(report)
+	pipeline, err := stu.pipelineWatcher.WaitForMergeRequestPipeline(ctx, mrIID, stu.config.PipelineTimeout)
+	finish(err)
+	result.Pipeline = pipeline
+	endPhase(err)
+	if err != nil {
+		result.Success = false
+		stu.logger.WithError(err).WithField("mr_id", mrIID).Error("Merge request pipeline did not succeed")
+		return result, fmt.Errorf("pipeline gate failed for MR !%d: %w", mrIID, err)
+	}
+
+	pipelineLog := stu.logger.WithFields(map[string]interface{}{
+		"mr_id":       mrIID,
+		"pipeline_id": pipeline.PipelineID,
+		"status":      pipeline.Status,
+	})
+	if pipeline.Skipped() {
+		pipelineLog.Warn("Merge request pipeline was skipped")
+		result.Message = fmt.Sprintf("%s. Pipeline #%d was skipped", result.Message, pipeline.PipelineID)
+		return result, nil
+	}
+	pipelineLog.Info("Merge request pipeline succeeded")
+
+	result.Message = fmt.Sprintf("%s. Pipeline #%d passed", result.Message, pipeline.PipelineID)
+	return result, nil
+}
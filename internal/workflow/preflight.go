@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// runPreflight checks the project permissions, protected branches and push rules
+// before the update branch is created, so that a blocked update fails with a hint
+// instead of leaving a branch and commit behind. Checks the token cannot read are
+// logged and skipped.
+func (stu *SimpleTagUpdater) runPreflight(ctx context.Context) error {
+	if stu.config.SkipPreflight {
+		return nil
+	}
+
+	filePaths := []string{stu.config.FilePath}
+	if stu.config.OriginalBackup == config.OriginalBackupFile {
+		filePaths = append(filePaths, originalBackupPath(stu.config.FilePath))
+	}
+	report := gitlabapi.NewPreflightCheckerWithAPI(stu.api, stu.projectID).Check(ctx, &gitlabapi.PreflightOptions{
+		TargetBranch:  stu.config.TargetBranch,
+		UpdateBranch:  stu.updateBranchName(),
+		FilePaths:     filePaths,
+		CommitMessage: stu.commitMessage(),
+		AutoMerge:     stu.config.AutoMerge,
+		SignedCommits: stu.config.CommitBackend == config.CommitBackendGit && stu.config.GPGKey != "",
+		CheckToken:    gitlabapi.AuthMode(stu.config.AuthMode) != gitlabapi.AuthModeJob,
+	})
+
+	for check, reason := range report.Skipped {
+		stu.logger.WithFields(map[string]interface{}{
+			"check":  check,
+			"reason": reason,
+		}).Debug("Pre-flight check skipped")
+	}
+	if len(report.Problems) == 0 {
+		return nil
+	}
+
+	for _, problem := range report.Problems {
+		stu.logger.WithFields(map[string]interface{}{
+			"check": problem.Check,
+			"hint":  problem.Hint,
+		}).Error("Pre-flight check failed: " + problem.Message)
+	}
+	return errors.NewAuthError(fmt.Sprintf("pre-flight checks failed: %s; pass --skip-preflight to try anyway",
+		preflightProblems(report)))
+}
+
+// preflightProblems joins the problems of a pre-flight report with their hints
+func preflightProblems(report *gitlabapi.PreflightReport) string {
+	problems := make([]string, 0, len(report.Problems))
+	for _, problem := range report.Problems {
+		problems = append(problems, problem.String())
+	}
+	return strings.Join(problems, "; ")
+}
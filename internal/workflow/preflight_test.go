@@ -0,0 +1,48 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_Preflight(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip %v", skip), func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.ProtectBranch(projectID, "update-*", gitlab.NoPermissions, gitlab.MaintainerPermissions)
+
+			cfg := &config.CLIConfig{
+				ProjectID:     TestProjectID,
+				GitLabToken:   TestGitLabToken,
+				FilePath:      TestFilePath,
+				NewTag:        TestNewTag,
+				TargetBranch:  TestTargetBranch,
+				BranchName:    TestBranchName,
+				SkipPreflight: skip,
+			}
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			_, err := updater.Execute(context.Background())
+			if skip {
+				// The fake does not enforce protection, so the skipped check lets the update through
+				if err != nil {
+					t.Errorf("Execute() with skipped pre-flight checks unexpected error: %v", err)
+				}
+				return
+			}
+			if errors.ExitCode(err) != errors.ExitCodeAuth || !strings.Contains(err.Error(), "--branch-name") {
+				t.Errorf("Execute() error = %v, want a pre-flight failure with a hint", err)
+			}
+			if server.BranchExists(projectID, TestBranchName) {
+				t.Error("Execute() created the branch despite the failed pre-flight check")
+			}
+		})
+	}
+}
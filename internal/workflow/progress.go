@@ -0,0 +1,29 @@
+package workflow
+
+import (
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/progress"
+)
+
+// Operations reported while the workflow waits
+const (
+	ProgressPipeline      = "Waiting for merge request pipeline"
+	ProgressConflicts     = "Waiting for conflicting merge requests"
+	ProgressMergeability  = "Waiting for merge conflicts to be resolved"
+	ProgressConcurrentRun = "Waiting for concurrent run"
+	ProgressMarkReady     = "Marking drafts ready"
+	ProgressMergeLater    = "Enabling deferred auto-merges"
+)
+
+// SetProgress sets the reporter of the waits of the run, such as for the pipeline;
+// nil disables progress reporting
+func (stu *SimpleTagUpdater) SetProgress(reporter *progress.Reporter) {
+	stu.progress = reporter
+}
+
+// startProgress begins reporting a wait and returns the function a watcher tells its
+// status to, together with the function ending the report
+func (stu *SimpleTagUpdater) startProgress(operation string) (gitlabapi.ProgressFunc, func(error)) {
+	task := stu.progress.Start(operation, 0)
+	return func(status string) { task.Update(0, status) }, task.Finish
+}
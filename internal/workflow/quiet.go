@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sort"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/progress"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// QuietRolloutNote is appended to the run message when the merge request was opened as a draft
+	QuietRolloutNote = "; opened as a draft, run 'go-tag-updater ready' to request reviews"
+)
+
+// ReadyResult contains the results of marking quiet rollout drafts as ready
+type ReadyResult struct {
+	ReadyMergeRequests   []int
+	PendingMergeRequests []int
+	Message              string
+}
+
+// MarkDraftsReady marks the draft merge requests opened by quiet rollouts in the
+// configured project as ready for review, oldest first. A positive batchSize limits
+// how many are converted, so reviewers are notified in manageable batches.
+func MarkDraftsReady(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	batchSize int,
+	log *logger.Logger,
+) (*ReadyResult, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("project ID is required")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+
+	projectID, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+
+	mrManager := gitlabapi.NewSimpleMergeRequestManager(client.GetGitLabClient(), projectID)
+	mrs, err := mrManager.ListOpenMergeRequests(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open merge requests: %w", err)
+	}
+
+	tool := identity.New()
+	var drafts []int
+	for _, mr := range mrs {
+		if mr.Draft && tool.OwnsMergeRequest(&identity.Event{Description: mr.Description}) {
+			drafts = append(drafts, mr.IID)
+		}
+	}
+	sort.Ints(drafts)
+
+	result := &ReadyResult{}
+	if batchSize > 0 && len(drafts) > batchSize {
+		result.PendingMergeRequests = drafts[batchSize:]
+		drafts = drafts[:batchSize]
+	}
+
+	var errs []error
+	task := progress.NewDefault(log).Start(ProgressMarkReady, len(drafts))
+	for i, iid := range drafts {
+		task.Update(i, fmt.Sprintf("!%d", iid))
+		mrLog := log.WithField("mr_id", iid)
+		if cfg.DryRun {
+			result.ReadyMergeRequests = append(result.ReadyMergeRequests, iid)
+			mrLog.Info("Dry run mode: would mark merge request as ready")
+			continue
+		}
+
+		if _, err := mrManager.MarkReady(ctx, iid); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.ReadyMergeRequests = append(result.ReadyMergeRequests, iid)
+		mrLog.Info("Merge request marked as ready")
+	}
+
+	task.Finish(stderrors.Join(errs...))
+
+	result.Message = fmt.Sprintf("Marked %d merge request(s) as ready, %d draft(s) left for later batches",
+		len(result.ReadyMergeRequests), len(result.PendingMergeRequests))
+	if cfg.DryRun {
+		result.Message = fmt.Sprintf("Dry run completed. Would mark %d merge request(s) as ready, %d draft(s) left",
+			len(result.ReadyMergeRequests), len(result.PendingMergeRequests))
+	}
+
+	return result, stderrors.Join(errs...)
+}
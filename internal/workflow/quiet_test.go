@@ -0,0 +1,71 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_QuietRollout(t *testing.T) {
+	server, projectID := newTestProject(t)
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		GitLabURL:    server.URL(),
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		AutoMerge:    true,
+		QuietRollout: true,
+	}
+
+	runJournal, err := journal.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("journal.New() unexpected error: %v", err)
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+	updater.SetJournal(runJournal)
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Message, QuietRolloutNote) || result.MergeDeferredUntil.IsZero() {
+		t.Errorf("Execute() message = %q, want a deferred draft", result.Message)
+	}
+
+	mrs := server.MergeRequests(projectID)
+	if len(mrs) != 1 || !mrs[0].Draft || mrs[0].MergeWhenPipelineSucceeds {
+		t.Fatalf("merge requests = %+v, want one draft without auto-merge", mrs)
+	}
+
+	later, err := ProcessDeferredMerges(context.Background(), cfg, runJournal, time.Now(), logger.New(false))
+	if err != nil || len(later.WaitingRuns) != 1 {
+		t.Fatalf("ProcessDeferredMerges() = %+v, %v, want the draft to keep waiting", later, err)
+	}
+
+	ready, err := MarkDraftsReady(context.Background(), cfg, 1, logger.New(false))
+	if err != nil {
+		t.Fatalf("MarkDraftsReady() unexpected error: %v", err)
+	}
+	if len(ready.ReadyMergeRequests) != 1 || ready.ReadyMergeRequests[0] != mrs[0].IID {
+		t.Errorf("MarkDraftsReady() = %+v, want !%d ready", ready, mrs[0].IID)
+	}
+
+	later, err = ProcessDeferredMerges(context.Background(), cfg, runJournal, time.Now(), logger.New(false))
+	if err != nil || len(later.EnabledRuns) != 1 {
+		t.Fatalf("ProcessDeferredMerges() = %+v, %v, want auto-merge enabled", later, err)
+	}
+
+	if mrs := server.MergeRequests(projectID); len(mrs) != 1 || mrs[0].Draft || !mrs[0].MergeWhenPipelineSucceeds {
+		t.Errorf("merge requests = %+v, want a ready merge request with auto-merge", mrs)
+	}
+}
@@ -0,0 +1,71 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// ReleaseNotesMaxDescriptionBytes bounds the release notes embedded in the merge
+	// request description; longer notes are truncated
+	ReleaseNotesMaxDescriptionBytes = 32 * 1024
+	// releaseNotesTruncated ends release notes cut to ReleaseNotesMaxDescriptionBytes
+	releaseNotesTruncated = "\n\n_Release notes truncated._"
+)
+
+// validateReleaseNotes checks at most one source of release notes is configured
+func validateReleaseNotes(file, project string) error {
+	if file != "" && project != "" {
+		return errors.NewValidationError("release-notes-file and release-notes-project cannot be combined")
+	}
+	return nil
+}
+
+// loadReleaseNotes reads the release notes of the new tag from --release-notes-file,
+// or from the GitLab release of the tag in --release-notes-project. A missing or
+// unreadable release only loses the notes, so it is logged instead of failing the run.
+func (stu *SimpleTagUpdater) loadReleaseNotes(ctx context.Context) error {
+	switch {
+	case stu.config.ReleaseNotesFile != "":
+		notes, err := os.ReadFile(filepath.Clean(stu.config.ReleaseNotesFile))
+		if err != nil {
+			return errors.NewConfigErrorWithCause("failed to read release notes file "+stu.config.ReleaseNotesFile, err)
+		}
+		stu.releaseNotes = string(notes)
+	case stu.config.ReleaseNotesProject != "":
+		notes, found, err := gitlabapi.ReleaseNotes(ctx, stu.api, stu.config.ReleaseNotesProject, stu.config.NewTag)
+		fields := map[string]interface{}{"project": stu.config.ReleaseNotesProject, "tag": stu.config.NewTag}
+		switch {
+		case err != nil:
+			stu.logger.WithError(err).WithFields(fields).
+				Warn("Failed to read release notes, opening the merge request without them")
+			return nil
+		case !found:
+			stu.logger.WithFields(fields).
+				Warn("No release for the new tag, opening the merge request without release notes")
+			return nil
+		}
+		stu.releaseNotes = notes
+	}
+	return nil
+}
+
+// releaseNotesSection returns the collapsed section of the merge request description
+// holding the release notes of the new tag
+func (stu *SimpleTagUpdater) releaseNotesSection() string {
+	notes := strings.TrimSpace(stu.releaseNotes)
+	if notes == "" {
+		return ""
+	}
+	if len(notes) > ReleaseNotesMaxDescriptionBytes {
+		notes = truncateUTF8(notes, ReleaseNotesMaxDescriptionBytes) + releaseNotesTruncated
+	}
+	return fmt.Sprintf("<details>\n<summary>Release notes for %s</summary>\n\n%s\n\n</details>\n\n",
+		stu.config.NewTag, notes)
+}
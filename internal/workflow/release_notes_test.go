@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_ReleaseNotes(t *testing.T) {
+	notesFile := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	if err := os.WriteFile(notesFile, []byte("- Faster startup\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write release notes: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		file      string
+		project   string
+		wantNotes string
+	}{
+		{name: "file", file: notesFile, wantNotes: "- Faster startup"},
+		{name: "GitLab release", project: "mygroup/api", wantNotes: "- Fixed the login redirect"},
+		{name: "missing release", project: "mygroup/worker"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.AddRelease(server.AddProject("mygroup/api"), TestNewTag, "- Fixed the login redirect")
+			server.AddProject("mygroup/worker")
+
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:            TestFilePath,
+				NewTag:              TestNewTag,
+				TargetBranch:        TestTargetBranch,
+				BranchName:          TestBranchName,
+				ReleaseNotesFile:    tt.file,
+				ReleaseNotesProject: tt.project,
+			})
+
+			result, err := updater.Execute(context.Background())
+			if err != nil || result.MergeRequest == nil {
+				t.Fatalf("Execute() = %+v, %v, want a merge request", result, err)
+			}
+			description := result.MergeRequest.Description
+			hasSection := strings.Contains(description, "<summary>Release notes for "+TestNewTag+"</summary>")
+			if hasSection != (tt.wantNotes != "") || !strings.Contains(description, tt.wantNotes) {
+				t.Errorf("description = %q, want release notes %q", description, tt.wantNotes)
+			}
+		})
+	}
+
+	_, err := NewSimpleTagUpdater(&config.CLIConfig{ReleaseNotesFile: notesFile, ReleaseNotesProject: "mygroup/api"},
+		logger.New(false))
+	if errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("NewSimpleTagUpdater() with both release notes sources = %v, want a validation error", err)
+	}
+}
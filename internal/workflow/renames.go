@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"context"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// followRename inspects the history of a file missing on the source ref for a rename.
+// With --follow-renames the update moves on to the new path; otherwise the returned
+// error names it. notFound is returned unchanged when the file was not renamed.
+func (stu *SimpleTagUpdater) followRename(
+	ctx context.Context,
+	notFound error,
+) (map[string]*gitlabapi.PrefetchedFile, error) {
+	renameLog := stu.logger.WithFields(map[string]interface{}{
+		"file_path": stu.config.FilePath,
+		"branch":    stu.sourceRef(),
+	})
+
+	rename, err := stu.fileManager.FindRename(ctx, stu.config.FilePath, stu.sourceRef())
+	if err != nil {
+		renameLog.WithError(err).Warn("Failed to inspect file history for renames")
+		return nil, notFound
+	}
+	if rename == nil {
+		return nil, notFound
+	}
+
+	renameLog = renameLog.WithFields(map[string]interface{}{
+		"new_path": rename.NewPath,
+		"commit":   rename.Commit.ShortID,
+	})
+
+	if !stu.config.FollowRenames {
+		renameLog.Info("File was renamed; update --file or pass --follow-renames")
+		return nil, errors.NewFileRenamedError(rename.OldPath, rename.NewPath, rename.Commit.ShortID)
+	}
+
+	if err := stu.policy.CheckFile(rename.NewPath); err != nil {
+		renameLog.WithError(err).Error("Renamed file rejected by least-privilege policy")
+		return nil, err
+	}
+
+	renameLog.Warn("File was renamed, following it to its new path")
+	stu.renamedFrom = stu.config.FilePath
+	stu.config.FilePath = rename.NewPath
+
+	return stu.prefetchFiles(ctx, []string{rename.NewPath})
+}
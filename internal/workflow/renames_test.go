@@ -0,0 +1,59 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_FollowRenames(t *testing.T) {
+	const movedPath = "apps/app/values.yaml"
+
+	tests := []struct {
+		name   string
+		follow bool
+	}{
+		{name: "reports the new path"},
+		{name: "follows the rename", follow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.RenameFile(projectID, TestTargetBranch, TestFilePath, movedPath)
+
+			cfg := &config.CLIConfig{
+				ProjectID:     TestProjectID,
+				GitLabToken:   TestGitLabToken,
+				FilePath:      TestFilePath,
+				NewTag:        TestNewTag,
+				TargetBranch:  TestTargetBranch,
+				BranchName:    TestBranchName,
+				FollowRenames: tt.follow,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			result, err := updater.Execute(context.Background())
+			if !tt.follow {
+				if errors.GetErrorCode(err) != errors.ErrCodeFileNotFound || !strings.Contains(err.Error(), movedPath) {
+					t.Errorf("Execute() error = %v, want a file not found error naming %s", err, movedPath)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if result.RenamedFrom != TestFilePath {
+				t.Errorf("Execute() renamed from = %q, want %q", result.RenamedFrom, TestFilePath)
+			}
+			if content, ok := server.File(projectID, TestBranchName, movedPath); !ok || !strings.Contains(content, TestNewTag) {
+				t.Errorf("file %s on update branch = %q, want the new tag", movedPath, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+)
+
+// findExistingMergeRequest looks for an open merge request that already updates
+// the same file to the same tag, identified by its description marker
+func (stu *SimpleTagUpdater) findExistingMergeRequest(ctx context.Context) (*gitlab.BasicMergeRequest, error) {
+	mrs, err := stu.mrManager.ListOpenMergeRequests(ctx, stu.config.TargetBranch)
+	if err != nil {
+		stu.logger.WithError(err).WithField("target_branch", stu.config.TargetBranch).
+			Error("Failed to list open merge requests")
+		return nil, fmt.Errorf("failed to look up existing merge requests: %w", err)
+	}
+
+	for _, mr := range mrs {
+		if stu.isSameUpdate(mr) {
+			stu.logger.WithFields(map[string]interface{}{
+				"mr_id":         mr.IID,
+				"mr_url":        mr.WebURL,
+				"source_branch": mr.SourceBranch,
+			}).Info("Found existing merge request for the same tag update")
+			return mr, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// isSameUpdate reports whether a merge request was created by this tool for the same
+// file and tag, whichever run created it. Only the description marker counts: a
+// merge request a person opened with the same title is never taken over.
+func (stu *SimpleTagUpdater) isSameUpdate(mr *gitlab.BasicMergeRequest) bool {
+	if mr == nil {
+		return false
+	}
+
+	metadata, ok := identity.ParseMarker(mr.Description)
+	if !ok {
+		return false
+	}
+	want := stu.mergeRequestMetadata()
+	return metadata.File == want.File && metadata.NewTag == want.NewTag && metadata.Key == want.Key
+}
+
+// reuseMergeRequest brings an existing merge request up to date instead of creating a duplicate
+func (stu *SimpleTagUpdater) reuseMergeRequest(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	existing *gitlab.BasicMergeRequest,
+	newContent string,
+) (*SimpleUpdateResult, error) {
+	result.BranchName = existing.SourceBranch
+
+	if stu.config.DryRun {
+		stu.logger.WithFields(map[string]interface{}{
+			"operation":   "dry_run",
+			"mr_id":       existing.IID,
+			"branch_name": existing.SourceBranch,
+		}).Info("Dry run mode: would reuse existing merge request")
+
+		result.Success = true
+		result.Message = fmt.Sprintf("Dry run completed successfully. Would reuse MR: !%d", existing.IID)
+		return result, nil
+	}
+	if err := stu.runPreUpdateHooks(ctx, result); err != nil {
+		return result, err
+	}
+
+	currentContent, err := stu.fileManager.GetFileContent(ctx, stu.config.FilePath, existing.SourceBranch)
+	if err != nil {
+		return result, fmt.Errorf("failed to get file content from branch %s: %w", existing.SourceBranch, err)
+	}
+
+	if currentContent != newContent {
+		updateOpts := &gitlabapi.FileUpdateOptions{
+			Branch:        existing.SourceBranch,
+			CommitMessage: stu.commitMessage(),
+			Content:       newContent,
+		}
+
+		if _, err := stu.fileManager.UpdateFileContent(ctx, stu.config.FilePath, updateOpts); err != nil {
+			return result, fmt.Errorf("failed to update file on existing branch %s: %w", existing.SourceBranch, err)
+		}
+
+		result.FileUpdated = true
+		stu.logger.WithFields(map[string]interface{}{
+			"file_path":   stu.config.FilePath,
+			"branch_name": existing.SourceBranch,
+		}).Info("File refreshed on existing merge request branch")
+		stu.reportCommit(ctx, result, existing.SourceBranch)
+		stu.runPostHooks(ctx, HookPostCommit, result)
+	}
+
+	mr, err := stu.mrManager.UpdateMergeRequest(ctx, existing.IID, &gitlabapi.SimpleMergeRequestOptions{
+		Title:       stu.mergeRequestTitle(),
+		Description: stu.mergeRequestDescription(existing.SourceBranch),
+		Draft:       existing.Draft,
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to update existing merge request: %w", err)
+	}
+
+	result.MergeRequest = mr
+	result.Success = true
+	result.Message = fmt.Sprintf("Reused existing merge request. MR: !%d", mr.IID)
+
+	stu.logger.WithFields(map[string]interface{}{
+		"mr_id":       mr.IID,
+		"mr_url":      mr.WebURL,
+		"branch_name": existing.SourceBranch,
+	}).Info("Existing merge request reused")
+	stu.runPostHooks(ctx, HookPostMR, result)
+
+	return result, nil
+}
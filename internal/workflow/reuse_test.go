@@ -0,0 +1,128 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+const TestManualBranch = "manual/bump-tag"
+
+func TestSimpleTagUpdater_UpdateExistingMR(t *testing.T) {
+	tests := []struct {
+		name       string
+		ownMR      bool
+		wantMRs    int
+		wantReused bool
+	}{
+		{name: "merge request of an earlier run", ownMR: true, wantMRs: 1, wantReused: true},
+		{name: "manual merge request with the same title", wantMRs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			newUpdater := func() *SimpleTagUpdater {
+				cfg := testConfig()
+				cfg.UpdateExistingMR = true
+				return newTestUpdater(t, server, projectID, cfg)
+			}
+
+			if tt.ownMR {
+				if _, err := newUpdater().Execute(context.Background()); err != nil {
+					t.Fatalf("first Execute() unexpected error: %v", err)
+				}
+			} else {
+				// A person opened a merge request with the title the tool would use, without its marker
+				server.AddBranch(projectID, TestManualBranch, TestTargetBranch, false)
+				_, _, err := server.Client().MergeRequests.CreateMergeRequest(projectID, &gitlab.CreateMergeRequestOptions{
+					Title:        gitlab.Ptr(newUpdater().mergeRequestTitle()),
+					SourceBranch: gitlab.Ptr(TestManualBranch),
+					TargetBranch: gitlab.Ptr(TestTargetBranch),
+				})
+				if err != nil {
+					t.Fatalf("Failed to open the manual merge request: %v", err)
+				}
+			}
+
+			result, err := newUpdater().Execute(context.Background())
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if mrs := server.MergeRequests(projectID); len(mrs) != tt.wantMRs {
+				t.Errorf("merge requests = %d, want %d", len(mrs), tt.wantMRs)
+			}
+			if reused := strings.HasPrefix(result.Message, "Reused"); reused != tt.wantReused {
+				t.Errorf("Execute() message = %q, reused = %v, want %v", result.Message, reused, tt.wantReused)
+			}
+			if !tt.wantReused && result.BranchName == TestManualBranch {
+				t.Errorf("Execute() took over the manual branch %s", TestManualBranch)
+			}
+		})
+	}
+}
+
+func TestSimpleTagUpdater_IsSameUpdate(t *testing.T) {
+	log := logger.New(false)
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+	}
+
+	updater, err := NewSimpleTagUpdater(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create updater: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mr       *gitlab.BasicMergeRequest
+		expected bool
+	}{
+		{
+			name:     "nil merge request",
+			mr:       nil,
+			expected: false,
+		},
+		{
+			name:     "description marker",
+			mr:       &gitlab.BasicMergeRequest{Title: "Bump", Description: updater.mergeRequestDescription(TestBranchName)},
+			expected: true,
+		},
+		{
+			name: "marker of an earlier version",
+			mr: &gitlab.BasicMergeRequest{Title: "Bump", Description: fmt.Sprintf("%s file=%q tag=%q key=%q -->",
+				identity.MarkerPrefix, TestFilePath, TestNewTag, updater.idempotencyKey)},
+			expected: true,
+		},
+		{
+			name:     "matching title without marker",
+			mr:       &gitlab.BasicMergeRequest{Title: updater.mergeRequestTitle()},
+			expected: false,
+		},
+		{
+			name:     "different tag",
+			mr:       &gitlab.BasicMergeRequest{Title: "Update tag to v9.9.9 in " + TestFilePath},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updater.isSameUpdate(tt.mr); got != tt.expected {
+				t.Errorf("isSameUpdate() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
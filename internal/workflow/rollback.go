@@ -0,0 +1,100 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// RollbackConfig returns the configuration of an update that restores the tag a
+// completed run replaced. The rollback is an ordinary update with its own branch,
+// merge request and journal entry; cfg supplies the token and merge request options.
+func RollbackConfig(cfg *config.CLIConfig, entry *journal.Entry) (*config.CLIConfig, error) {
+	if cfg == nil || entry == nil {
+		return nil, errors.NewValidationError("config and run entry are required")
+	}
+
+	if entry.Status != journal.StatusCompleted {
+		return nil, errors.NewValidationErrorWithContext(fmt.Sprintf(
+			"run %s has status %s; only completed runs can be rolled back, use abort for the others",
+			entry.RunID, entry.Status), entry.RunID)
+	}
+	if entry.OldTag == "" {
+		return nil, errors.NewValidationErrorWithContext(fmt.Sprintf(
+			"run %s did not record the tag it replaced", entry.RunID), entry.RunID)
+	}
+
+	rollback := *cfg
+	rollback.ProjectID = entry.ProjectPath
+	if rollback.ProjectID == "" {
+		rollback.ProjectID = strconv.Itoa(entry.ProjectID)
+	}
+	if rollback.GitLabURL == "" {
+		rollback.GitLabURL = entry.GitLabURL
+	}
+	rollback.FilePath = entry.FilePath
+	rollback.NewTag = entry.OldTag
+	// Restoring the replaced tag is a downgrade whenever the run was an upgrade
+	rollback.AllowDowngrade = true
+	rollback.TargetBranch = entry.TargetBranch
+	rollback.BranchName = ""
+	rollback.RunID = ""
+
+	return &rollback, nil
+}
+
+// FindRunEntry rebuilds the journal entry of a run from the metadata block of the
+// merge request it opened in cfg.ProjectID, for runs whose journal is not at hand,
+// such as runs of another CI job. The run counts as completed once its merge request
+// was merged.
+func FindRunEntry(ctx context.Context, cfg *config.CLIConfig, runID string) (*journal.Entry, error) {
+	if cfg == nil || runID == "" {
+		return nil, errors.NewValidationError("config and run ID are required")
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("project ID is required to find the merge request of a run")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	projectID, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+
+	mrManager := gitlabapi.NewSimpleMergeRequestManager(client.GetGitLabClient(), projectID)
+	mrs, err := mrManager.FindToolMergeRequests(ctx, &gitlabapi.ToolMergeRequestFilter{RunID: runID})
+	if err != nil {
+		return nil, err
+	}
+	if len(mrs) == 0 {
+		return nil, errors.NewValidationErrorWithContext(fmt.Sprintf(
+			"no merge request of run %s found in %s", runID, cfg.ProjectID), runID)
+	}
+
+	mr := mrs[0]
+	entry := &journal.Entry{
+		RunID:            runID,
+		Status:           journal.StatusRunning,
+		GitLabURL:        cfg.GitLabURL,
+		ProjectID:        projectID,
+		ProjectPath:      cfg.ProjectID,
+		FilePath:         mr.Metadata.File,
+		OldTag:           mr.Metadata.OldTag,
+		NewTag:           mr.Metadata.NewTag,
+		TargetBranch:     mr.TargetBranch,
+		CreatedBranches:  []string{mr.SourceBranch},
+		MergeRequestIIDs: []int{mr.IID},
+	}
+	if mr.State == gitlabapi.StateMerged {
+		entry.Status = journal.StatusCompleted
+	}
+	return entry, nil
+}
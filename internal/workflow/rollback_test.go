@@ -0,0 +1,102 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+)
+
+func TestSimpleTagUpdater_RollbackRestoresOldTag(t *testing.T) {
+	server, projectID := newTestProject(t)
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		GitLabURL:    server.URL(),
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+	}
+
+	runJournal, err := journal.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("journal.New() unexpected error: %v", err)
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+	updater.SetJournal(runJournal)
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	entry, err := runJournal.Load(result.RunID)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	rollback, err := RollbackConfig(&config.CLIConfig{GitLabToken: TestGitLabToken}, entry)
+	if err != nil {
+		t.Fatalf("RollbackConfig() unexpected error: %v", err)
+	}
+	if rollback.NewTag != TestOldTag || rollback.FilePath != TestFilePath ||
+		rollback.ProjectID != TestProjectID || rollback.TargetBranch != TestTargetBranch ||
+		rollback.GitLabURL != server.URL() {
+		t.Errorf("RollbackConfig() = %+v, want an update back to %s", rollback, TestOldTag)
+	}
+
+	entry.Status = journal.StatusFailed
+	if _, err := RollbackConfig(cfg, entry); err == nil {
+		t.Error("RollbackConfig() expected error for a run that did not complete")
+	}
+}
+
+func TestFindRunEntry(t *testing.T) {
+	server, projectID := newTestProject(t)
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		GitLabURL:    server.URL(),
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+	}
+	updater := newTestUpdater(t, server, projectID, cfg)
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	mrs := server.MergeRequests(projectID)
+	if len(mrs) != 1 {
+		t.Fatalf("got %d merge requests, want 1", len(mrs))
+	}
+	metadata, ok := identity.ParseMarker(mrs[0].Description)
+	if !ok || metadata.RunID != result.RunID || metadata.OldTag != TestOldTag || metadata.NewTag != TestNewTag ||
+		metadata.File != TestFilePath || metadata.Version == "" {
+		t.Errorf("ParseMarker() = %+v, %v, want the metadata of run %s", metadata, ok, result.RunID)
+	}
+
+	entry, err := FindRunEntry(context.Background(), cfg, result.RunID)
+	if err != nil {
+		t.Fatalf("FindRunEntry() unexpected error: %v", err)
+	}
+	if entry.OldTag != TestOldTag || entry.FilePath != TestFilePath || entry.TargetBranch != TestTargetBranch ||
+		entry.Status != journal.StatusRunning {
+		t.Errorf("FindRunEntry() = %+v, want the open run replacing %s", entry, TestOldTag)
+	}
+	// Its merge request is still open, so the run cannot be rolled back yet
+	if _, err := RollbackConfig(cfg, entry); err == nil {
+		t.Error("RollbackConfig() expected error for a run whose merge request is open")
+	}
+
+	if _, err := FindRunEntry(context.Background(), cfg, "20250101T000000Z-00000000"); err == nil {
+		t.Error("FindRunEntry() expected error for an unknown run")
+	}
+}
@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/internal/audit"
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+// RunUpdate creates and initializes a tag updater for cfg and executes it, recording
+// the run in the run journal and in the audit trail configured in cfg, notifying
+// the configured destinations of its outcome and writing the report requested with
+// --report-file. The result is nil when the run failed before the workflow started.
+// With --repo-path-glob every matching file is updated instead of one file.
+func RunUpdate(ctx context.Context, cfg *config.CLIConfig, log *logger.Logger) (*SimpleUpdateResult, error) {
+	reporter, err := newRunReporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier, err := newNotifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	updater, err := NewSimpleTagUpdater(cfg, log)
+	if err != nil {
+		err = fmt.Errorf("failed to create tag updater: %w", err)
+		notifyFailure(ctx, notifier, cfg, err, log)
+		reporter.write(nil, nil, err, log)
+		return nil, err
+	}
+
+	if err := updater.Initialize(ctx); err != nil {
+		err = fmt.Errorf("failed to initialize tag updater: %w", err)
+		notifyFailure(ctx, notifier, cfg, err, log)
+		reporter.write(updater, nil, err, log)
+		return nil, err
+	}
+	if notifier != nil {
+		updater.SetNotifier(notifier)
+	}
+
+	runJournal, err := journal.New(cfg.StateDir)
+	if err != nil {
+		log.WithError(err).Warn("Run journal disabled; this run cannot be aborted later")
+	} else {
+		updater.SetJournal(runJournal)
+	}
+
+	auditTrail, err := audit.NewTrail(audit.Options{
+		FilePath:   cfg.AuditFile,
+		Endpoint:   cfg.AuditEndpoint,
+		SigningKey: cfg.AuditSigningKey,
+		Timeout:    cfg.AuditTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure audit trail: %w", err)
+	}
+	if auditTrail != nil {
+		updater.SetAuditTrail(auditTrail)
+	}
+
+	execute := updater.Execute
+	if cfg.RepoPathGlob != "" {
+		execute = updater.ExecuteGlob
+	}
+	result, err := execute(ctx)
+	reporter.write(updater, result, err, log)
+	if cleanupErr := updater.Cleanup(); cleanupErr != nil {
+		log.WithError(cleanupErr).Warn("Cleanup failed")
+	}
+	return result, err
+}
@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+)
+
+// startJournal records the beginning of a run; journal failures never fail the update
+func (stu *SimpleTagUpdater) startJournal() {
+	if stu.journal == nil || stu.config.DryRun {
+		return
+	}
+
+	stu.runEntry = &journal.Entry{
+		RunID:        stu.runID,
+		Status:       journal.StatusRunning,
+		GitLabURL:    stu.config.GitLabURL,
+		ProjectID:    stu.projectID,
+		ProjectPath:  stu.config.ProjectID,
+		FilePath:     stu.targetFiles(),
+		NewTag:       stu.config.NewTag,
+		TargetBranch: stu.config.TargetBranch,
+		StartedAt:    time.Now().UTC(),
+	}
+
+	if err := stu.journal.Save(stu.runEntry); err != nil {
+		stu.logger.WithError(err).WithField("run_id", stu.runID).Warn("Failed to record run in journal")
+	}
+}
+
+// recordBranch stores a created branch in the run journal
+func (stu *SimpleTagUpdater) recordBranch(branchName string) {
+	if stu.runEntry == nil {
+		return
+	}
+
+	if err := stu.journal.RecordBranch(stu.runEntry, branchName); err != nil {
+		stu.logger.WithError(err).WithField("branch_name", branchName).Warn("Failed to record branch in journal")
+	}
+}
+
+// recordMergeRequest stores an opened merge request in the run journal
+func (stu *SimpleTagUpdater) recordMergeRequest(mrIID int) {
+	if stu.runEntry == nil {
+		return
+	}
+
+	if err := stu.journal.RecordMergeRequest(stu.runEntry, mrIID); err != nil {
+		stu.logger.WithError(err).WithField("mr_id", mrIID).Warn("Failed to record merge request in journal")
+	}
+}
+
+// finishJournal records the final status of the run and the tag it replaced
+func (stu *SimpleTagUpdater) finishJournal(runErr error) {
+	if stu.runEntry == nil {
+		return
+	}
+
+	stu.runEntry.OldTag = stu.oldTag
+	if err := stu.journal.Finish(stu.runEntry, runErr); err != nil {
+		stu.logger.WithError(err).WithField("run_id", stu.runID).Warn("Failed to finalize run journal")
+	}
+}
@@ -0,0 +1,136 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/metrics"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+)
+
+// runReporter collects the duration and API calls of a run for --report-file
+type runReporter struct {
+	path      string
+	cfg       *config.CLIConfig
+	startedAt time.Time
+	apiStart  metrics.Snapshot
+}
+
+// newRunReporter validates the report file of cfg and starts timing the run; it
+// returns nil when no report is requested
+func newRunReporter(cfg *config.CLIConfig) (*runReporter, error) {
+	if cfg.ReportFile == "" {
+		return nil, nil
+	}
+	if _, err := report.FormatOf(cfg.ReportFile); err != nil {
+		return nil, err
+	}
+	return &runReporter{
+		path:      cfg.ReportFile,
+		cfg:       cfg,
+		startedAt: time.Now().UTC(),
+		apiStart:  metrics.Default.Snapshot(),
+	}, nil
+}
+
+// write writes the report of the run; updater and result are nil when the run
+// failed before the workflow started. A report that cannot be written is only
+// logged, since the update itself is already done.
+func (r *runReporter) write(updater *SimpleTagUpdater, result *SimpleUpdateResult, runErr error, log *logger.Logger) {
+	if r == nil {
+		return
+	}
+
+	finishedAt := time.Now().UTC()
+	api := metrics.Default.Snapshot().Sub(r.apiStart)
+	rep := &report.Report{
+		Success:    runErr == nil && result != nil && result.Success,
+		DryRun:     r.cfg.DryRun,
+		StartedAt:  r.startedAt,
+		FinishedAt: finishedAt,
+		Duration:   finishedAt.Sub(r.startedAt),
+		Inputs: report.Inputs{
+			ProjectID:    r.cfg.ProjectID,
+			FilePath:     r.cfg.FilePath,
+			RepoPathGlob: r.cfg.RepoPathGlob,
+			YAMLPath:     r.cfg.YAMLPath,
+			Updater:      r.cfg.Updater,
+			OldTag:       r.cfg.OldTag,
+			NewTag:       r.cfg.NewTag,
+			TargetBranch: r.cfg.TargetBranch,
+			AutoMerge:    r.cfg.AutoMerge,
+		},
+		APICalls: report.APICalls{
+			Requests:    api.Requests,
+			Failed:      api.Failed,
+			Retries:     api.Retries,
+			RateLimited: api.RateLimited,
+			CacheHits:   api.CacheHits,
+		},
+	}
+	if runErr != nil {
+		rep.Error = runErr.Error()
+	}
+
+	if updater != nil {
+		rep.RunID = updater.runID
+		if updater.projectID != 0 {
+			rep.Project = &report.Project{ID: updater.projectID}
+		}
+		rep.Diffs = updater.reportDiffs()
+		rep.Validations = updater.validations
+		for _, step := range updater.steps {
+			rep.Steps = append(rep.Steps, report.Step{
+				Name:     string(step.Name),
+				Duration: step.Duration,
+				Status:   step.Status,
+				Error:    step.Error,
+			})
+		}
+	}
+	if result != nil {
+		addResultToReport(rep, result)
+	}
+
+	if err := report.Write(r.path, rep); err != nil {
+		log.WithError(err).WithField("report_file", r.path).Warn("Failed to write run report")
+		return
+	}
+	log.WithField("report_file", r.path).Info("Run report written")
+}
+
+// addResultToReport adds the outcome and the links of a run to its report
+func addResultToReport(rep *report.Report, result *SimpleUpdateResult) {
+	rep.RunID = result.RunID
+	rep.Skipped = result.Skipped
+	rep.Message = result.Message
+	rep.CommitURL = result.CommitURL
+	if result.BranchName != "" {
+		rep.Branch = &report.Link{Name: result.BranchName, URL: result.BranchURL}
+	}
+	if mr := result.MergeRequest; mr != nil {
+		rep.MergeRequest = &report.MergeRequest{IID: mr.IID, Title: mr.Title, URL: mr.WebURL, State: mr.State}
+	}
+	if len(rep.Diffs) == 0 {
+		for _, count := range result.FileChanges {
+			rep.Diffs = append(rep.Diffs, report.FileDiff{Path: count.FilePath, Changes: count.Changes})
+		}
+	}
+}
+
+// reportDiffs returns the changes of the run to each file
+func (stu *SimpleTagUpdater) reportDiffs() []report.FileDiff {
+	if len(stu.fileDiffs) > 0 {
+		return stu.fileDiffs
+	}
+	if stu.originalContent == "" || stu.updatedContent == "" || stu.updatedContent == stu.originalContent {
+		return nil
+	}
+	return []report.FileDiff{{
+		Path: stu.config.FilePath,
+		Diff: diff.Unified("a/"+stu.config.FilePath, "b/"+stu.config.FilePath,
+			stu.originalContent, stu.updatedContent, diff.DefaultContextLines),
+	}}
+}
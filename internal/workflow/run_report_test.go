@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestRunReporter(t *testing.T) {
+	server, projectID := newTestProject(t)
+
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		ReportFile:   filepath.Join(t.TempDir(), "report.json"),
+	}
+	reporter, err := newRunReporter(cfg)
+	if err != nil {
+		t.Fatalf("newRunReporter() unexpected error: %v", err)
+	}
+
+	updater := newTestUpdater(t, server, projectID, cfg)
+
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	reporter.write(updater, result, err, logger.New(false))
+
+	data, err := os.ReadFile(cfg.ReportFile)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	var rep report.Report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if !rep.Success || rep.RunID != result.RunID || rep.Project == nil || rep.Project.ID != projectID {
+		t.Errorf("report = %+v, want the successful run in project %d", rep, projectID)
+	}
+	if rep.MergeRequest == nil || rep.MergeRequest.IID != result.MergeRequest.IID {
+		t.Errorf("report merge request = %+v, want !%d", rep.MergeRequest, result.MergeRequest.IID)
+	}
+	if len(rep.Diffs) != 1 || !strings.Contains(rep.Diffs[0].Diff, "+  tag: "+TestNewTag) {
+		t.Errorf("report diffs = %+v, want the change of %s", rep.Diffs, TestFilePath)
+	}
+	if len(rep.Steps) == 0 || rep.Steps[0].Name != string(PhaseValidate) || rep.Steps[0].Status != StepSucceeded {
+		t.Errorf("report steps = %+v, want the validate step first", rep.Steps)
+	}
+
+	cfg.ReportFile = "report.txt"
+	if _, err := newRunReporter(cfg); errors.GetErrorCode(err) != errors.ErrCodeValidation {
+		t.Errorf("newRunReporter() with a .txt report = %v, want a validation error", err)
+	}
+}
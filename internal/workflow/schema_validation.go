@@ -0,0 +1,40 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Gosayram/go-tag-updater/internal/keyvalue"
+	"github.com/Gosayram/go-tag-updater/internal/schema"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// validateSchemaName checks the schemas selected with --validate-schema
+func validateSchemaName(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, known := range schema.Names() {
+		if name == known {
+			return nil
+		}
+	}
+	return errors.NewConfigError(fmt.Sprintf("invalid schema %q: expected %s",
+		name, strings.Join(schema.Names(), ", ")))
+}
+
+// checkSchema validates the updated content of a YAML file against the schemas
+// selected with --validate-schema, so a rewrite breaking the structure of a
+// manifest is never committed. .env and properties files have no schema.
+func checkSchema(name, filePath, content string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := keyvalue.DetectFormat(filePath); ok {
+		return nil
+	}
+	if err := schema.Validate(name, content); err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	return nil
+}
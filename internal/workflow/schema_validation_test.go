@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_ValidateSchema(t *testing.T) {
+	const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      containers:
+        - name: web
+          image: registry.example.com/web:v1.0.0
+          imagePullPolicy: IfNotPresent
+`
+	tests := []struct {
+		name     string
+		yamlPath string
+		wantErr  bool
+	}{
+		{name: "valid manifest", yamlPath: "spec.template.spec.containers[0].image"},
+		{name: "path breaking the schema", yamlPath: "spec.template.spec.containers[0].imagePullPolicy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gitlabtest.NewServer(t)
+			projectID := server.AddProject(TestProjectID)
+			server.SetFile(projectID, TestTargetBranch, "deploy/web.yaml", deployment)
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:       "deploy/web.yaml",
+				YAMLPath:       tt.yamlPath,
+				NewTag:         "registry.example.com/web:v1.2.3",
+				TargetBranch:   TestTargetBranch,
+				BranchName:     TestBranchName,
+				ValidateSchema: "k8s",
+			})
+
+			_, err := updater.Execute(context.Background())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "imagePullPolicy") {
+					t.Errorf("Execute() error = %v, want a schema violation at imagePullPolicy", err)
+				}
+				if server.BranchExists(projectID, TestBranchName) {
+					t.Error("Execute() created the update branch of content breaking the schema")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+		})
+	}
+
+	if _, err := NewSimpleTagUpdater(&config.CLIConfig{
+		ProjectID:      TestProjectID,
+		GitLabToken:    TestGitLabToken,
+		FilePath:       TestFilePath,
+		NewTag:         TestNewTag,
+		ValidateSchema: "openapi",
+	}, logger.New(false)); err == nil {
+		t.Error("NewSimpleTagUpdater() accepted an unknown schema")
+	}
+}
@@ -0,0 +1,320 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// SelfTestDir is the directory of the sandbox project holding self-test files
+	SelfTestDir = "go-tag-updater-selftest"
+	// SelfTestYAMLPath is the tag field of the self-test file
+	SelfTestYAMLPath = "image.tag"
+	// SelfTestOldTag is the tag the self-test file is created with
+	SelfTestOldTag = "selftest-initial"
+	// SelfTestMergeTimeout bounds the wait for GitLab to report the merge request mergeable
+	SelfTestMergeTimeout = 2 * time.Minute
+	// SelfTestMergeInterval defines how often mergeability is checked before merging
+	SelfTestMergeInterval = 5 * time.Second
+	// selfTestFileFormat is the content of the self-test file
+	selfTestFileFormat = "# Created by go-tag-updater selftest run %s; safe to delete\nimage:\n  tag: %s\n"
+)
+
+// Self-test phases, in the order they run
+const (
+	SelfTestPhaseConnect    = "connect"
+	SelfTestPhaseCreateFile = "create-file"
+	SelfTestPhaseUpdate     = "update"
+	SelfTestPhaseMerge      = "merge"
+	SelfTestPhaseVerify     = "verify"
+	SelfTestPhaseCleanup    = "cleanup"
+)
+
+// SelfTestPhase reports the outcome of one self-test phase
+type SelfTestPhase struct {
+	Name     string
+	Passed   bool
+	Skipped  bool
+	Duration time.Duration
+	Detail   string
+	Err      error
+}
+
+// SelfTestResult reports a self-test run against a sandbox project
+type SelfTestResult struct {
+	RunID        string
+	ProjectID    string
+	TargetBranch string
+	FilePath     string
+	NewTag       string
+	Phases       []SelfTestPhase
+	Passed       bool
+}
+
+// selfTest holds the state shared by the phases of a self-test
+type selfTest struct {
+	cfg    *config.CLIConfig
+	log    *logger.Logger
+	result *SelfTestResult
+
+	projectID    int
+	fileManager  *gitlabapi.FileManager
+	branchMgr    *gitlabapi.BranchManager
+	mrManager    *gitlabapi.SimpleMergeRequestManager
+	mergeability *gitlabapi.MergeabilityWatcher
+
+	fileCreated  bool
+	update       *SimpleUpdateResult
+	mergeRequest int
+}
+
+// RunSelfTest performs a full update cycle against a sandbox project: it creates a
+// YAML file on the target branch, updates its tag through a merge request, merges
+// it, verifies the merged content and removes what it created. Every phase is
+// reported; phases after a failure are skipped except cleanup, which always runs.
+// The sandbox project must allow the token to push to and merge into its target branch.
+func RunSelfTest(ctx context.Context, cfg *config.CLIConfig, log *logger.Logger) (*SelfTestResult, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("sandbox project is required")
+	}
+	if cfg.DryRun {
+		return nil, errors.NewValidationError(
+			"selftest makes real changes to the sandbox project; --dry-run is not supported")
+	}
+
+	runID := cfg.RunID
+	if runID == "" {
+		runID = journal.NewRunID()
+	}
+	st := &selfTest{
+		cfg: cfg,
+		log: log,
+		result: &SelfTestResult{
+			RunID:        runID,
+			ProjectID:    cfg.ProjectID,
+			TargetBranch: cfg.TargetBranch,
+			FilePath:     path.Join(SelfTestDir, runID+".yaml"),
+			NewTag:       "selftest-" + runID,
+		},
+	}
+	return st.run(ctx)
+}
+
+// run executes the phases in order and reports the first failure
+func (st *selfTest) run(ctx context.Context) (*SelfTestResult, error) {
+	phases := []struct {
+		name string
+		run  func(context.Context) (string, error)
+	}{
+		{SelfTestPhaseConnect, st.connect},
+		{SelfTestPhaseCreateFile, st.createFile},
+		{SelfTestPhaseUpdate, st.runUpdate},
+		{SelfTestPhaseMerge, st.merge},
+		{SelfTestPhaseVerify, st.verify},
+	}
+
+	var failure error
+	for _, phase := range phases {
+		if failure != nil {
+			st.record(SelfTestPhase{Name: phase.name, Skipped: true})
+			continue
+		}
+		if err := st.runPhase(ctx, phase.name, phase.run); err != nil {
+			failure = fmt.Errorf("self-test phase %s failed: %w", phase.name, err)
+		}
+	}
+
+	if err := st.runPhase(ctx, SelfTestPhaseCleanup, st.cleanup); err != nil && failure == nil {
+		failure = fmt.Errorf("self-test phase %s failed: %w", SelfTestPhaseCleanup, err)
+	}
+
+	st.result.Passed = failure == nil
+	return st.result, failure
+}
+
+// runPhase times and records one phase
+func (st *selfTest) runPhase(ctx context.Context, name string, run func(context.Context) (string, error)) error {
+	start := time.Now()
+	detail, err := run(ctx)
+	phase := SelfTestPhase{Name: name, Passed: err == nil, Duration: time.Since(start), Detail: detail, Err: err}
+	st.record(phase)
+
+	phaseLog := st.log.WithFields(map[string]interface{}{
+		"run_id":   st.result.RunID,
+		"phase":    name,
+		"duration": phase.Duration.String(),
+	})
+	if err != nil {
+		phaseLog.WithError(err).Error("Self-test phase failed")
+	} else {
+		phaseLog.WithField("detail", detail).Info("Self-test phase passed")
+	}
+	return err
+}
+
+// record appends a phase to the result
+func (st *selfTest) record(phase SelfTestPhase) {
+	st.result.Phases = append(st.result.Phases, phase)
+}
+
+// connect resolves the sandbox project and its target branch and checks the token
+func (st *selfTest) connect(ctx context.Context) (string, error) {
+	client, err := newGitLabClient(st.cfg, st.cfg.GitLabURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	if err := client.IsHealthy(ctx); err != nil {
+		return "", fmt.Errorf("GitLab health check failed: %w", err)
+	}
+
+	if st.projectID, err = client.ResolveProjectID(ctx, st.cfg.ProjectID); err != nil {
+		return "", fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+	if st.result.TargetBranch == "" {
+		projects := gitlabapi.NewProjectManager(client.GetGitLabClient())
+		if st.result.TargetBranch, err = projects.GetProjectDefaultBranch(ctx, st.projectID); err != nil {
+			return "", err
+		}
+	}
+
+	api := gitlabapi.NewAPIAdapter(client.GetGitLabClient())
+	st.fileManager = gitlabapi.NewFileManagerWithAPI(api, st.projectID)
+	st.branchMgr = gitlabapi.NewBranchManagerWithAPI(api, st.projectID)
+	st.mrManager = gitlabapi.NewSimpleMergeRequestManagerWithAPI(api, st.projectID)
+	st.mergeability = gitlabapi.NewMergeabilityWatcherWithAPI(api, st.projectID)
+	st.mergeability.SetInterval(SelfTestMergeInterval)
+
+	return fmt.Sprintf("project %d, target branch %s", st.projectID, st.result.TargetBranch), nil
+}
+
+// createFile commits the self-test file to the target branch
+func (st *selfTest) createFile(ctx context.Context) (string, error) {
+	_, err := st.fileManager.UpdateFile(ctx, st.result.FilePath, &gitlabapi.FileUpdateOptions{
+		Branch:        st.result.TargetBranch,
+		Content:       fmt.Sprintf(selfTestFileFormat, st.result.RunID, SelfTestOldTag),
+		CommitMessage: "Add go-tag-updater self-test file " + st.result.FilePath,
+	})
+	if err != nil {
+		return "", err
+	}
+	st.fileCreated = true
+	return st.result.FilePath, nil
+}
+
+// runUpdate runs the regular update workflow on the self-test file
+func (st *selfTest) runUpdate(ctx context.Context) (string, error) {
+	cfg := *st.cfg
+	cfg.FilePath = st.result.FilePath
+	cfg.NewTag = st.result.NewTag
+	cfg.YAMLPath = SelfTestYAMLPath
+	cfg.TargetBranch = st.result.TargetBranch
+	cfg.RunID = st.result.RunID
+	cfg.BranchName = ""
+	cfg.SourceRef = ""
+	cfg.RemoveSourceBranch = true
+	// The self-test merges the merge request itself and checks plain tags only
+	cfg.AutoMerge = false
+	cfg.QuietRollout = false
+	cfg.UpdateExistingMR = false
+	cfg.AllowBump = ""
+	cfg.TagPrefix = ""
+
+	result, err := RunUpdate(ctx, &cfg, st.log)
+	// A failed update may still have created its branch, which cleanup removes
+	st.update = result
+	if err != nil {
+		return "", err
+	}
+	if result.MergeRequest == nil {
+		return "", errors.NewGitOperationError("update did not open a merge request: " + result.Message)
+	}
+	st.mergeRequest = result.MergeRequest.IID
+	return fmt.Sprintf("merge request !%d from %s", result.MergeRequest.IID, result.BranchName), nil
+}
+
+// merge waits until GitLab reports the merge request mergeable and merges it
+func (st *selfTest) merge(ctx context.Context) (string, error) {
+	if _, err := st.mergeability.WaitForMergeable(ctx, st.mergeRequest, SelfTestMergeTimeout, false); err != nil {
+		return "", err
+	}
+
+	mr, err := st.mrManager.MergeMergeRequest(ctx, st.mergeRequest,
+		&gitlabapi.SimpleMergeRequestOptions{RemoveSourceBranch: true})
+	if err != nil {
+		return "", err
+	}
+	if mr.State != gitlabapi.StateMerged {
+		return "", errors.NewGitOperationError(fmt.Sprintf("merge request !%d is %s after merging", mr.IID, mr.State))
+	}
+	return fmt.Sprintf("merge request !%d merged", mr.IID), nil
+}
+
+// verify reads the merged file back from the target branch
+func (st *selfTest) verify(ctx context.Context) (string, error) {
+	cfg := *st.cfg
+	cfg.FilePath = st.result.FilePath
+	cfg.YAMLPath = SelfTestYAMLPath
+	cfg.TargetBranch = st.result.TargetBranch
+
+	tag, err := CurrentTag(ctx, &cfg)
+	if err != nil {
+		return "", err
+	}
+	if tag != st.result.NewTag {
+		return "", errors.NewValidationError(fmt.Sprintf("%s holds %s on %s, want %s",
+			st.result.FilePath, tag, st.result.TargetBranch, st.result.NewTag))
+	}
+	return fmt.Sprintf("%s is %s on %s", SelfTestYAMLPath, tag, st.result.TargetBranch), nil
+}
+
+// cleanup closes a merge request left open, deletes the update branch and removes
+// the self-test file from the target branch
+func (st *selfTest) cleanup(ctx context.Context) (string, error) {
+	if st.fileManager == nil {
+		return "nothing to clean up", nil
+	}
+
+	if st.mergeRequest > 0 {
+		mr, err := st.mrManager.GetMergeRequest(ctx, st.mergeRequest)
+		if err != nil {
+			return "", err
+		}
+		if mr.State == gitlabapi.StateOpened {
+			if _, err := st.mrManager.CloseMergeRequest(ctx, mr.IID,
+				"Closed by go-tag-updater selftest run `"+st.result.RunID+"`."); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if st.update != nil && st.update.BranchName != "" {
+		exists, err := st.branchMgr.BranchExists(ctx, st.update.BranchName)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			if err := st.branchMgr.DeleteBranch(ctx, st.update.BranchName); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if st.fileCreated {
+		if err := st.fileManager.DeleteFile(ctx, st.result.FilePath, st.result.TargetBranch,
+			"Remove go-tag-updater self-test file "+st.result.FilePath); err != nil {
+			return "", err
+		}
+	}
+	return "self-test file, branch and merge request removed", nil
+}
@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       string
+		wantPassed []string
+		wantFailed string
+	}{
+		{
+			name: "full cycle",
+			wantPassed: []string{SelfTestPhaseConnect, SelfTestPhaseCreateFile, SelfTestPhaseUpdate,
+				SelfTestPhaseMerge, SelfTestPhaseVerify, SelfTestPhaseCleanup},
+		},
+		{
+			name:       "merge refused",
+			fail:       "/merge_requests/1/merge",
+			wantPassed: []string{SelfTestPhaseConnect, SelfTestPhaseCreateFile, SelfTestPhaseUpdate, SelfTestPhaseCleanup},
+			wantFailed: SelfTestPhaseMerge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := gitlabtest.NewServer(t)
+			projectID := server.AddProject(TestProjectID)
+			if tt.fail != "" {
+				server.FailRequests(http.MethodPut, tt.fail, http.StatusMethodNotAllowed)
+			}
+
+			cfg := &config.CLIConfig{
+				ProjectID:   TestProjectID,
+				GitLabToken: TestGitLabToken,
+				GitLabURL:   server.URL(),
+				StateDir:    t.TempDir(),
+			}
+			result, err := RunSelfTest(context.Background(), cfg, logger.New(false))
+			if (err != nil) != (tt.wantFailed != "") {
+				t.Fatalf("RunSelfTest() error = %v, want failure in %q", err, tt.wantFailed)
+			}
+
+			var passed []string
+			for _, phase := range result.Phases {
+				if phase.Passed {
+					passed = append(passed, phase.Name)
+				} else if !phase.Skipped && phase.Name != tt.wantFailed {
+					t.Errorf("phase %s failed: %v", phase.Name, phase.Err)
+				}
+			}
+			if strings.Join(passed, ",") != strings.Join(tt.wantPassed, ",") {
+				t.Errorf("passed phases = %v, want %v", passed, tt.wantPassed)
+			}
+
+			if _, ok := server.File(projectID, gitlabtest.DefaultBranch, result.FilePath); ok {
+				t.Errorf("self-test file %s was not removed", result.FilePath)
+			}
+			for _, mr := range server.MergeRequests(projectID) {
+				if mr.State == gitlabapi.StateOpened || server.BranchExists(projectID, mr.SourceBranch) {
+					t.Errorf("merge request !%d left %s with branch %s", mr.IID, mr.State, mr.SourceBranch)
+				}
+			}
+		})
+	}
+}
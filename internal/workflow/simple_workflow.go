@@ -3,14 +3,36 @@ package workflow
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 
+	"github.com/Gosayram/go-tag-updater/internal/audit"
+	"github.com/Gosayram/go-tag-updater/internal/clock"
 	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	"github.com/Gosayram/go-tag-updater/internal/gitbackend"
 	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/journal"
+	"github.com/Gosayram/go-tag-updater/internal/keyvalue"
 	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/manifest"
+	"github.com/Gosayram/go-tag-updater/internal/notify"
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/progress"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+	"github.com/Gosayram/go-tag-updater/internal/schedule"
+	"github.com/Gosayram/go-tag-updater/internal/semver"
+	"github.com/Gosayram/go-tag-updater/internal/version"
 	"github.com/Gosayram/go-tag-updater/internal/yaml"
 	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
@@ -20,26 +42,149 @@ const (
 	PreviewContentMaxLength = 500
 	// TempFilePermissions defines permissions for temporary files
 	TempFilePermissions = 0o600
+	// DryRunArtifactsPattern defines the temporary directory pattern for dry run artifacts
+	DryRunArtifactsPattern = "go-tag-updater-dry-run-*"
+	// DiffArtifactExtension defines the extension of the dry run diff artifact
+	DiffArtifactExtension = ".diff"
+	// RawFallbackWarningFormat warns reviewers that the tag line was edited without a YAML round-trip
+	RawFallbackWarningFormat = "> **Warning:** %s contains YAML constructs that cannot be round-tripped " +
+		"safely (%s), so only the tag line was replaced as text. Review the change carefully."
+	// SharedAnchorsWarningFormat warns reviewers that the updated tag is shared through aliases
+	SharedAnchorsWarningFormat = "> **Warning:** the tag updated in %s is shared through %s, " +
+		"so the new value also applies wherever it is aliased."
 )
 
 // SimpleTagUpdater handles basic tag update workflow
 type SimpleTagUpdater struct {
-	config       *config.CLIConfig
-	logger       *logger.Logger
-	gitlabClient *gitlabapi.Client
-	fileManager  *gitlabapi.FileManager
-	branchMgr    *gitlabapi.BranchManager
-	mrManager    *gitlabapi.SimpleMergeRequestManager
-	projectID    int
+	config          *config.CLIConfig
+	logger          *logger.Logger
+	gitlabClient    *gitlabapi.Client
+	api             gitlabapi.API
+	fileManager     *gitlabapi.FileManager
+	commits         *gitlabapi.CommitManager
+	gitBackend      *gitbackend.Backend
+	checkout        *gitbackend.Checkout
+	branchMgr       *gitlabapi.BranchManager
+	mrManager       *gitlabapi.SimpleMergeRequestManager
+	issues          *gitlabapi.IssuesManager
+	approvals       *gitlabapi.ApprovalManager
+	pipelineWatcher *gitlabapi.PipelineWatcher
+	mergeability    *gitlabapi.MergeabilityWatcher
+	conflicts       *gitlabapi.ConflictDetector
+	policy          *policy.Policy
+	tagPolicy       *semver.Policy
+	docSelector     *yaml.DocumentSelector
+	issueLinks      *issueLinks
+	yamlPath        string
+	imageRef        string
+	mergeWindow     *schedule.WorkingHours
+	now             func() time.Time
+	journal         *journal.Journal
+	auditTrail      *audit.Trail
+	notifier        *notify.Dispatcher
+	runEntry        *journal.Entry
+	runID           string
+	projectID       int
+	bootstrapRef    string
+	renamedFrom     string
+	phaseHooks      []PhaseHook
+	steps           []StepResult
+	progress        *progress.Reporter
+	transport       http.RoundTripper
+
+	// Waiting for concurrent runs of the same update
+	concurrentRunWindow   time.Duration
+	concurrentRunInterval time.Duration
+
+	// Retrying auto-merge until the merge request has a pipeline
+	autoMergeTimeout  time.Duration
+	autoMergeInterval time.Duration
+
+	// Populated once the content has been updated
+	originalContent string
+	updatedContent  string
+	oldTag          string
+	tagPath         []string
+	idempotencyKey  string
+	rawFallback     []string
+	sharedAnchors   []string
+	releaseNotes    string
+
+	// fileDiffs are the changes of a glob update to each file, for the run report
+	fileDiffs []report.FileDiff
+	// validations are the outcomes of the --validate-cmd commands, for the run report
+	validations []report.Validation
+	// preUpdateHooksRan is set once the pre-update hook commands ran
+	preUpdateHooksRan bool
+
+	// encodeDiagnostics is captured when the YAML encoder failed on the file
+	encodeDiagnostics *yaml.EncodeDiagnostics
+
+	// queuedConflicts are the merge requests the queue conflict policy waits for
+	// before opening the merge request
+	queuedConflicts *gitlabapi.ConflictInfo
+
+	// createdBranch is the branch this run created, removed again when the run fails
+	// before opening its merge request
+	createdBranch string
 }
 
 // SimpleUpdateResult contains the results of the update operation
 type SimpleUpdateResult struct {
 	Success      bool
+	RunID        string
 	BranchName   string
 	MergeRequest *gitlab.MergeRequest
+	Pipeline     *gitlabapi.PipelineResult
+	Mergeability *gitlabapi.MergeabilityResult
 	FileUpdated  bool
+	Skipped      bool
 	Message      string
+
+	// Steps lists the timing and outcome of each workflow step, in order
+	Steps []StepResult
+
+	// TargetBranchCreated is set when the run created the missing target branch
+	TargetBranchCreated bool
+
+	// RenamedFrom is the configured file path when the update followed a rename
+	RenamedFrom string
+
+	// EncodeDiagnostics describes the file when the YAML encoder failed on it, for bug reports
+	EncodeDiagnostics *yaml.EncodeDiagnostics
+
+	// Links to the partial state in the GitLab UI, set as soon as each step completes
+	BranchURL string
+	CommitURL string
+
+	// Dry run artifacts with the full updated content, its unified diff and the
+	// commit that would be made as a patch
+	PreviewPath string
+	DiffPath    string
+	PatchPath   string
+
+	// Commit describes the commit a dry run would make
+	Commit *CommitPreview
+
+	// AutoMergeEnabled is set once the merge request merges when its pipeline succeeds
+	AutoMergeEnabled bool
+
+	// MergeDeferredUntil is set when auto-merge waits for the configured working hours
+	MergeDeferredUntil time.Time
+
+	// ConflictingMergeRequests lists the other open merge requests changing the same file
+	ConflictingMergeRequests []int
+
+	// RecreatedMergeRequests lists the conflicting merge requests closed and replaced
+	// with --recreate-on-conflict, oldest first
+	RecreatedMergeRequests []int
+
+	// FileChanges counts the values replaced in each file by a --repo-path-glob update
+	FileChanges []FileChangeCount
+
+	// Approvals holds the approvals of the merge request with --check-approvals or
+	// --approver-token
+	Approvals *gitlabapi.ApprovalStatus
 }
 
 // NewSimpleTagUpdater creates a new simple tag updater
@@ -51,16 +196,114 @@ func NewSimpleTagUpdater(cfg *config.CLIConfig, log *logger.Logger) (*SimpleTagU
 		return nil, errors.NewValidationError("logger cannot be nil")
 	}
 
+	changePolicy, err := policy.New(cfg.LeastPrivilege, cfg.AllowedFiles, cfg.AllowedPaths)
+	if err != nil {
+		return nil, fmt.Errorf("invalid change policy: %w", err)
+	}
+
+	tagPolicy, err := newTagPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	docSelector, err := yaml.ParseDocumentSelector(cfg.DocSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document selector: %w", err)
+	}
+
+	mergeWindow, err := schedule.Parse(cfg.MergeWindow, cfg.MergeTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge window: %w", err)
+	}
+
+	if err := validateSourceDrift(cfg.OnSourceDrift); err != nil {
+		return nil, err
+	}
+
+	if err := validateConflictPolicy(cfg.ConflictPolicy); err != nil {
+		return nil, err
+	}
+
+	if err := validateCommitBackend(cfg.CommitBackend, cfg.GPGKey); err != nil {
+		return nil, err
+	}
+	if err := validateRecentUpdate(cfg.MinInterval, cfg.OnRecentUpdate); err != nil {
+		return nil, err
+	}
+	if err := validateOriginalBackup(cfg.OriginalBackup); err != nil {
+		return nil, err
+	}
+	if err := validateSchemaName(cfg.ValidateSchema); err != nil {
+		return nil, err
+	}
+
+	if err := validateTargetBranchCreation(cfg.CreateTargetBranch, cfg.TargetBranchFrom, cfg.TargetBranch); err != nil {
+		return nil, err
+	}
+	if err := validateUpdaterProfile(cfg.Updater, cfg.FluxMode); err != nil {
+		return nil, err
+	}
+	if err := validateReleaseNotes(cfg.ReleaseNotesFile, cfg.ReleaseNotesProject); err != nil {
+		return nil, err
+	}
+	links, err := parseIssueLinks(cfg.ClosesIssues, cfg.RelatedIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := cfg.RunID
+	if runID == "" {
+		runID = journal.NewRunID()
+	}
+
 	return &SimpleTagUpdater{
-		config: cfg,
-		logger: log,
+		config:      cfg,
+		logger:      log,
+		policy:      changePolicy,
+		tagPolicy:   tagPolicy,
+		docSelector: docSelector,
+		issueLinks:  links,
+		yamlPath:    cfg.YAMLPath,
+		mergeWindow: mergeWindow,
+		now:         time.Now,
+		runID:       runID,
+		progress:    progress.NewDefault(log),
+
+		concurrentRunWindow:   ConcurrentRunGraceWindow,
+		concurrentRunInterval: ConcurrentRunCheckInterval,
+		autoMergeTimeout:      AutoMergeTimeout,
+		autoMergeInterval:     AutoMergeRetryInterval,
 	}, nil
 }
 
+// SetJournal enables recording of created branches and merge requests for later abort
+func (stu *SimpleTagUpdater) SetJournal(j *journal.Journal) {
+	stu.journal = j
+}
+
+// RunID returns the correlation ID of this run
+func (stu *SimpleTagUpdater) RunID() string {
+	return stu.runID
+}
+
 // Initialize sets up the GitLab client and managers
-func (stu *SimpleTagUpdater) Initialize(_ context.Context) error {
-	// Create GitLab client
-	client, err := gitlabapi.NewClient(stu.config.GitLabToken, stu.config.GitLabURL)
+func (stu *SimpleTagUpdater) Initialize(ctx context.Context) (err error) {
+	endPhase := stu.beginPhase(ctx, PhaseInitialize)
+	defer func() { endPhase(err) }()
+
+	// Create GitLab client; a transport set by an embedding application brings its own
+	// TLS and proxy settings
+	transport := stu.transport
+	if transport == nil {
+		if transport, err = gitlabapi.NewTransport(TransportOptions(stu.config)); err != nil {
+			return err
+		}
+	}
+	if stu.config.InsecureSkipTLSVerify {
+		stu.logger.Warn("TLS certificate verification of GitLab is disabled")
+	}
+	client, err := gitlabapi.NewClientWithTransport(stu.config.GitLabToken, stu.config.GitLabURL,
+		gitlabapi.AuthMode(stu.config.AuthMode), transport)
 	if err != nil {
 		return fmt.Errorf("failed to create GitLab client: %w", err)
 	}
@@ -68,7 +311,7 @@ func (stu *SimpleTagUpdater) Initialize(_ context.Context) error {
 	stu.gitlabClient = client
 
 	// Resolve project ID
-	stu.projectID, err = stu.gitlabClient.ResolveProjectID(stu.config.ProjectID)
+	stu.projectID, err = stu.gitlabClient.ResolveProjectID(ctx, stu.config.ProjectID)
 	if err != nil {
 		return fmt.Errorf("failed to resolve project ID %s: %w", stu.config.ProjectID, err)
 	}
@@ -77,12 +320,13 @@ func (stu *SimpleTagUpdater) Initialize(_ context.Context) error {
 		Info("Project ID resolved successfully")
 
 	// Initialize managers
-	stu.fileManager = gitlabapi.NewFileManager(client.GetGitLabClient(), stu.projectID)
-	stu.branchMgr = gitlabapi.NewBranchManager(client.GetGitLabClient(), stu.projectID)
-	stu.mrManager = gitlabapi.NewSimpleMergeRequestManager(client.GetGitLabClient(), stu.projectID)
+	stu.InitializeWithAPI(gitlabapi.NewAPIAdapter(client.GetGitLabClient()), stu.projectID)
+	if err := stu.initializeApprover(transport); err != nil {
+		return err
+	}
 
 	// Health check
-	if err := stu.gitlabClient.IsHealthy(); err != nil {
+	if err := stu.gitlabClient.IsHealthy(ctx); err != nil {
 		return fmt.Errorf("GitLab health check failed: %w", err)
 	}
 
@@ -90,82 +334,319 @@ func (stu *SimpleTagUpdater) Initialize(_ context.Context) error {
 	return nil
 }
 
+// newGitLabClient creates a GitLab client sending the token as the configured auth mode
+// over the configured TLS settings
+func newGitLabClient(cfg *config.CLIConfig, baseURL string) (*gitlabapi.Client, error) {
+	transport, err := gitlabapi.NewTransport(TransportOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return gitlabapi.NewClientWithTransport(cfg.GitLabToken, baseURL, gitlabapi.AuthMode(cfg.AuthMode), transport)
+}
+
+// TransportOptions returns the TLS, proxy, HTTP cache and circuit breaker settings of
+// the configuration; with --debug the route of each GitLab host and every request are
+// logged, with --trace-http including their bodies
+func TransportOptions(cfg *config.CLIConfig) gitlabapi.TransportOptions {
+	breakerLog := logger.New(cfg.Debug)
+	opts := gitlabapi.TransportOptions{
+		TLS: gitlabapi.TLSOptions{
+			CACertFile:         cfg.CACert,
+			ClientCertFile:     cfg.ClientCert,
+			ClientKeyFile:      cfg.ClientKey,
+			InsecureSkipVerify: cfg.InsecureSkipTLSVerify,
+		},
+		Proxy: gitlabapi.ProxyOptions{
+			URL:      cfg.Proxy,
+			NoProxy:  cfg.NoProxy,
+			Username: cfg.ProxyUsername,
+			Password: cfg.ProxyPassword,
+		},
+		Cache: gitlabapi.CacheOptions{Mode: cfg.HTTPCache, Dir: cfg.HTTPCacheDir},
+		Breaker: gitlabapi.BreakerOptions{
+			Threshold: cfg.CircuitBreakerThreshold,
+			CoolDown:  cfg.CircuitBreakerCoolDown,
+			Log: func(fields map[string]interface{}, message string) {
+				breakerLog.WithFields(fields).Warn(message)
+			},
+		},
+	}
+	if cfg.Debug || cfg.TraceHTTP {
+		log := logger.New(true)
+		opts.Proxy.Logf = log.Debugf
+		opts.Trace = gitlabapi.TraceOptions{
+			Log: func(fields map[string]interface{}, message string) {
+				log.WithFields(fields).Debug(message)
+			},
+			Bodies: cfg.TraceHTTP,
+		}
+	}
+	return opts
+}
+
+// InitializeWithAPI sets up the managers on top of the given API implementation
+// for an already resolved project, skipping client creation and health checks
+func (stu *SimpleTagUpdater) InitializeWithAPI(api gitlabapi.API, projectID int) {
+	stu.projectID = projectID
+	stu.api = api
+	stu.fileManager = gitlabapi.NewFileManagerWithAPI(api, projectID)
+	stu.commits = gitlabapi.NewCommitManagerWithAPI(api, projectID)
+	stu.branchMgr = gitlabapi.NewBranchManagerWithAPI(api, projectID)
+	stu.mrManager = gitlabapi.NewSimpleMergeRequestManagerWithAPI(api, projectID)
+	stu.issues = gitlabapi.NewIssuesManagerWithAPI(api, projectID)
+	stu.approvals = gitlabapi.NewApprovalManagerWithAPI(api, projectID)
+	stu.pipelineWatcher = gitlabapi.NewPipelineWatcherWithAPI(api, projectID)
+	stu.mergeability = gitlabapi.NewMergeabilityWatcherWithAPI(api, projectID)
+	stu.mergeability.SetStopOnConflict(stu.config.RecreateOnConflict)
+	stu.conflicts = gitlabapi.NewConflictDetectorWithAPI(api, projectID)
+	stu.conflicts.SetFileCheck(stu.config.CheckFileConflicts)
+}
+
 // Execute runs the basic tag update workflow
 func (stu *SimpleTagUpdater) Execute(ctx context.Context) (*SimpleUpdateResult, error) {
 	result := &SimpleUpdateResult{
 		Success: false,
+		RunID:   stu.runID,
 	}
 
 	stu.logger.WithFields(map[string]interface{}{
 		"file_path":  stu.config.FilePath,
 		"new_tag":    stu.config.NewTag,
 		"project_id": stu.config.ProjectID,
+		"run_id":     stu.runID,
 		"operation":  "tag_update_start",
 	}).Info("Starting tag update workflow")
 
-	// Step 1: Validate file and get content
-	newContent, err := stu.validateAndUpdateContent(ctx)
+	stu.startJournal()
+	result, err := stu.run(ctx, result)
+	result.Steps = stu.steps
+	stu.removeOrphanBranch(ctx, result, err)
+	stu.closeCheckout()
+	stu.finishJournal(err)
+	stu.recordAudit(ctx, result, err)
+	stu.notifyRun(ctx, result, err)
+
+	return result, err
+}
+
+// run executes the workflow steps
+func (stu *SimpleTagUpdater) run(ctx context.Context, result *SimpleUpdateResult) (*SimpleUpdateResult, error) {
+	// Step 1: Create a missing target branch if requested, then validate file and get content
+	endPhase := stu.beginPhase(ctx, PhaseValidate)
+	newContent, err := "", stu.ensureTargetBranch(ctx, result)
+	if err == nil {
+		newContent, err = stu.validateAndUpdateContent(ctx)
+	}
+	result.RenamedFrom = stu.renamedFrom
+	result.EncodeDiagnostics = stu.encodeDiagnostics
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		endPhase(nil)
+		return stu.handleNoChanges(result), nil
+	}
+	skipped := false
+	if err == nil {
+		// Skip files go-tag-updater itself updated within --min-interval
+		skipped, err = stu.checkMinInterval(ctx, result)
+	}
+	endPhase(err)
+	if err != nil || skipped {
+		return result, err
+	}
+	stu.idempotencyKey = computeIdempotencyKey(stu.projectID, stu.config.TargetBranch, stu.config.FilePath,
+		stu.tagPath, stu.config.NewTag)
+
+	// Step 2: Reuse an existing merge request for the same update if requested
+	if stu.config.UpdateExistingMR {
+		endPhase = stu.beginPhase(ctx, PhaseReuse)
+		existing, findErr := stu.findExistingMergeRequest(ctx)
+		if findErr != nil {
+			endPhase(findErr)
+			return result, findErr
+		}
+		if existing != nil {
+			result, err = stu.reuseMergeRequest(ctx, result, existing, newContent)
+			endPhase(err)
+			result, err = stu.gateOnConflicts(ctx, result, err)
+			return stu.gateOnPipeline(ctx, result, err)
+		}
+		endPhase(nil)
+	}
+
+	// Steps 3 and 4: Check the limits, the source branch and the project settings
+	endPhase = stu.beginPhase(ctx, PhaseChecks)
+	err = stu.runChecks(ctx)
+	endPhase(err)
 	if err != nil {
 		return result, err
 	}
 
-	// Step 2: Generate unique branch name
+	// Step 5: Generate unique branch name
+	endPhase = stu.beginPhase(ctx, PhaseBranch)
 	branchName, err := stu.prepareBranchName(ctx)
+	endPhase(err)
 	if err != nil {
 		return result, err
 	}
 	result.BranchName = branchName
 
-	// Step 3: Handle dry run
+	// Step 6: Apply the conflict policy to other merge requests changing the same file
+	if stu.config.CheckFileConflicts {
+		endPhase = stu.beginPhase(ctx, PhaseConflicts)
+		err = stu.applyConflictPolicy(ctx, result, branchName)
+		endPhase(err)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// Step 7: Handle dry run
 	if stu.config.DryRun {
+		result.Commit = stu.previewCommit(ctx, branchName, newContent)
 		return stu.handleDryRun(result, newContent), nil
 	}
 
-	// Step 4: Execute actual update
-	return stu.executeUpdate(ctx, result, newContent, branchName)
+	// Step 8: Execute actual update, then optionally resolve conflicts and wait for the pipeline
+	endPhase = stu.beginPhase(ctx, PhaseUpdate)
+	result, err = stu.executeUpdate(ctx, result, newContent, branchName)
+	endPhase(err)
+	result, err = stu.gateOnConflicts(ctx, result, err)
+	return stu.gateOnPipeline(ctx, result, err)
+}
+
+// handleNoChanges reports an update that is skipped because the file already has the tag
+func (stu *SimpleTagUpdater) handleNoChanges(result *SimpleUpdateResult) *SimpleUpdateResult {
+	stu.logger.WithFields(map[string]interface{}{
+		"file_path": stu.config.FilePath,
+		"new_tag":   stu.config.NewTag,
+		"branch":    stu.sourceRef(),
+	}).Info("File already has the requested tag, skipping update")
+
+	result.Success = true
+	result.Skipped = true
+	result.Message = fmt.Sprintf("No changes: %s already uses tag %s on %s",
+		stu.config.FilePath, stu.config.NewTag, stu.sourceRef())
+	return result
+}
+
+// runChecks fails the update before any change when it would flood reviewers,
+// revert changes made on the target branch or be blocked by the project settings,
+// then loads the release notes of the new tag
+func (stu *SimpleTagUpdater) runChecks(ctx context.Context) error {
+	// Refuse to flood reviewers with more automation merge requests
+	err := stu.checkOpenMergeRequestLimit(ctx)
+
+	// Refuse to revert unrelated changes made on the target branch
+	if err == nil {
+		err = stu.checkSourceDrift(ctx)
+	}
+
+	// Then fail before any change when project settings would block the update
+	if err == nil {
+		err = stu.runPreflight(ctx)
+	}
+	if err == nil {
+		err = stu.loadReleaseNotes(ctx)
+	}
+	return err
 }
 
 // validateAndUpdateContent validates the file exists and updates its content
 func (stu *SimpleTagUpdater) validateAndUpdateContent(ctx context.Context) (string, error) {
-	// Check if file exists
-	exists, err := stu.fileManager.FileExists(ctx, stu.config.FilePath, stu.config.TargetBranch)
+	content, err := stu.fetchContent(ctx)
 	if err != nil {
+		return "", err
+	}
+	return stu.updateFetchedContent(ctx, content)
+}
+
+// fetchContent checks the file against the least-privilege policy and fetches its
+// current content from the source branch
+func (stu *SimpleTagUpdater) fetchContent(ctx context.Context) (string, error) {
+	// Enforce least-privilege file scope before touching the repository
+	if err := stu.policy.CheckFile(stu.config.FilePath); err != nil {
+		stu.logger.WithError(err).WithField("file_path", stu.config.FilePath).
+			Error("File rejected by least-privilege policy")
+		return "", err
+	}
+	if err := stu.checkOriginalBackupPolicy(); err != nil {
+		return "", err
+	}
+
+	// Fetch existence and content of the target files before any mutation
+	files, err := stu.prefetchFiles(ctx, []string{stu.config.FilePath})
+	if errors.GetErrorCode(err) == errors.ErrCodeFileNotFound {
+		files, err = stu.followRename(ctx, err)
+	}
+	if errors.GetErrorCode(err) == errors.ErrCodeFileNotFound {
 		stu.logger.WithError(err).WithFields(map[string]interface{}{
 			"file_path": stu.config.FilePath,
-			"branch":    stu.config.TargetBranch,
-		}).Error("Failed to check file existence")
-		return "", fmt.Errorf("failed to check if file exists: %w", err)
+			"branch":    stu.sourceRef(),
+		}).Error("File does not exist in source branch")
+		return "", err
 	}
-
-	if !exists {
-		stu.logger.WithFields(map[string]interface{}{
+	if err != nil {
+		stu.logger.WithError(err).WithFields(map[string]interface{}{
 			"file_path": stu.config.FilePath,
-			"branch":    stu.config.TargetBranch,
-		}).Error("File does not exist in target branch")
-		return "", fmt.Errorf("file %s does not exist in branch %s", stu.config.FilePath, stu.config.TargetBranch)
+			"branch":    stu.sourceRef(),
+		}).Error("Failed to fetch file")
+		return "", fmt.Errorf("failed to fetch file content: %w", err)
 	}
 
 	stu.logger.WithFields(map[string]interface{}{
 		"file_path": stu.config.FilePath,
-		"branch":    stu.config.TargetBranch,
-	}).Info("File exists in target branch")
+		"branch":    stu.sourceRef(),
+	}).Info("File exists in source branch")
 
-	// Get current file content
-	content, err := stu.fileManager.GetFileContent(ctx, stu.config.FilePath, stu.config.TargetBranch)
-	if err != nil {
+	return files[stu.config.FilePath].Content, nil
+}
+
+// updateFetchedContent writes the new tag into the fetched content after checking
+// it against the version and least-privilege policies
+func (stu *SimpleTagUpdater) updateFetchedContent(ctx context.Context, content string) (string, error) {
+	// Let the updater profile of the manifest kind pick the field to update
+	if err := stu.applyUpdaterProfile(content); err != nil {
 		stu.logger.WithError(err).WithField("file_path", stu.config.FilePath).
-			Error("Failed to get file content")
-		return "", fmt.Errorf("failed to get file content: %w", err)
+			Error("Updater profile found no field to update")
+		return "", err
 	}
 
-	// Update YAML content
-	newContent, err := stu.updateYAMLContent(content)
+	// Refuse downgrades and bumps beyond the semantic version policy
+	if err := checkTagPolicy(stu.tagPolicy, stu.tagField(), content, stu.config.NewTag); err != nil {
+		stu.logger.WithError(err).WithField("file_path", stu.config.FilePath).
+			Error("New tag rejected by version policy")
+		return "", err
+	}
+
+	// Update the content with the engine of the file format
+	newContent, err := stu.updateContent(content)
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		return "", err
+	}
 	if err != nil {
 		stu.logger.WithError(err).WithField("file_path", stu.config.FilePath).
 			Error("Failed to update YAML content")
 		return "", fmt.Errorf("failed to update YAML content: %w", err)
 	}
 
+	stu.originalContent = content
+
+	// Refuse anything broader than scalar changes at allowed paths
+	if err := stu.checkChange(content, newContent); err != nil {
+		stu.logger.WithError(err).WithField("file_path", stu.config.FilePath).
+			Error("Change rejected by least-privilege policy")
+		return "", err
+	}
+
+	// Refuse content breaking the schema of the manifest
+	if err := checkSchema(stu.config.ValidateSchema, stu.config.FilePath, newContent); err != nil {
+		stu.logger.WithError(err).WithField("file_path", stu.config.FilePath).
+			Error("Updated content rejected by schema validation")
+		return "", err
+	}
+	if err := stu.runValidateCommands(ctx, stu.config.FilePath, newContent); err != nil {
+		return "", err
+	}
+
 	stu.logger.WithFields(map[string]interface{}{
 		"file_path": stu.config.FilePath,
 		"new_tag":   stu.config.NewTag,
@@ -174,37 +655,68 @@ func (stu *SimpleTagUpdater) validateAndUpdateContent(ctx context.Context) (stri
 	return newContent, nil
 }
 
-// updateYAMLContent updates YAML content using the proper parser
-func (stu *SimpleTagUpdater) updateYAMLContent(content string) (string, error) {
-	yamlUpdater := yaml.NewUpdater()
+// tagField returns the tag field of the configured file
+func (stu *SimpleTagUpdater) tagField() tagField {
+	return tagField{
+		filePath:    stu.config.FilePath,
+		yamlPath:    stu.yamlPath,
+		docSelector: stu.docSelector,
+		imageTag:    stu.imageRef != "",
+	}
+}
 
-	// Create temporary file with content for validation
-	tempFile, err := stu.createTempFileWithContent(content)
-	if err != nil {
-		return "", fmt.Errorf("failed to create temporary file: %w", err)
+// newFieldValue returns the value written to the tag field: the new tag, or the
+// image reference carrying it when the field holds a whole image
+func (stu *SimpleTagUpdater) newFieldValue() string {
+	if stu.imageRef != "" {
+		return stu.imageRef
 	}
-	defer func() {
-		if removeErr := os.Remove(tempFile); removeErr != nil {
-			stu.logger.WithError(removeErr).WithField("temp_file", tempFile).
-				Warn("Failed to remove temporary file")
-		}
-	}()
+	return stu.config.NewTag
+}
+
+// updateContent updates the tag of the content, as a .env or properties file when
+// the file name says so and as YAML otherwise
+func (stu *SimpleTagUpdater) updateContent(content string) (string, error) {
+	if format, ok := keyvalue.DetectFormat(stu.config.FilePath); ok {
+		return stu.updateKeyValueContent(content, format)
+	}
+	return stu.updateYAMLContent(content)
+}
+
+// checkChange applies the least-privilege policy to the change of the content
+func (stu *SimpleTagUpdater) checkChange(original, updated string) error {
+	if format, ok := keyvalue.DetectFormat(stu.config.FilePath); ok {
+		return checkKeyValueChange(stu.policy, format, original, updated)
+	}
+	return stu.policy.CheckChange(original, updated)
+}
 
+// updateYAMLContent updates YAML content in memory using the proper parser
+func (stu *SimpleTagUpdater) updateYAMLContent(content string) (string, error) {
 	// Validate the existing YAML
-	if validationErr := yamlUpdater.ValidateFile(tempFile); validationErr != nil {
+	if validationErr := yaml.NewParser().ValidateYAML(content); validationErr != nil {
 		return "", fmt.Errorf("invalid YAML in source file: %w", validationErr)
 	}
 
 	// Update the content
 	request := &yaml.UpdateRequest{
-		FilePath:      tempFile,
-		NewTagValue:   stu.config.NewTag,
-		CreateBackup:  false,
+		FilePath:      stu.config.FilePath,
+		NewTagValue:   stu.newFieldValue(),
+		TagPath:       policy.SplitYAMLPath(stu.yamlPath),
 		ValidateAfter: true,
-		DryRun:        true, // We only want the updated content, not to write it
+		FallbackRaw:   stu.config.FallbackRaw,
+
+		ResolveAnchors:   stu.config.ResolveAnchors,
+		DocumentSelector: stu.docSelector,
 	}
 
-	result, err := yamlUpdater.UpdateTagInFile(request)
+	result, err := yaml.NewUpdater().UpdateContent(content, request)
+	stu.encodeDiagnostics = encodeDiagnostics(result, err)
+	logEncodeDiagnostics(stu.logger, stu.config.FilePath, stu.encodeDiagnostics)
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		stu.tagPath = result.TagPath
+		return "", err
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to update YAML tag: %w", err)
 	}
@@ -213,40 +725,44 @@ func (stu *SimpleTagUpdater) updateYAMLContent(content string) (string, error) {
 		return "", fmt.Errorf("YAML update was not successful")
 	}
 
-	newContent := result.UpdatedContent
-
-	return newContent, nil
-}
+	stu.tagPath = result.TagPath
+	stu.oldTag = result.OldTagValue
+	if stu.imageRef != "" {
+		_, stu.oldTag = manifest.SplitImage(result.OldTagValue)
+	}
 
-// createTempFileWithContent creates a temporary file with given content
-func (stu *SimpleTagUpdater) createTempFileWithContent(content string) (string, error) {
-	// Create temp file in current directory to avoid YAML validator path restrictions
-	tempFile, err := os.CreateTemp(".", "go-tag-updater-*.yaml")
-	if err != nil {
-		return "", err
+	if result.RawFallback {
+		stu.rawFallback = result.UnsafeConstructs
+		stu.logger.WithFields(map[string]interface{}{
+			"file_path":         stu.config.FilePath,
+			"unsafe_constructs": result.UnsafeConstructs,
+		}).Warn("YAML round-trip is unsafe, replaced the tag line as raw text")
 	}
-	defer tempFile.Close()
 
-	if _, err := tempFile.WriteString(content); err != nil {
-		_ = os.Remove(tempFile.Name()) // Ignore cleanup error, return original write error
-		return "", err
+	if len(result.SharedAnchors) > 0 {
+		stu.sharedAnchors = describeAnchors(result.SharedAnchors)
+		stu.logger.WithFields(map[string]interface{}{
+			"file_path":      stu.config.FilePath,
+			"shared_anchors": stu.sharedAnchors,
+		}).Warn("Updated tag is shared through anchors, the new value applies to every alias")
 	}
 
-	return tempFile.Name(), nil
+	return result.UpdatedContent, nil
 }
 
-// prepareBranchName generates a unique branch name
-func (stu *SimpleTagUpdater) prepareBranchName(ctx context.Context) (string, error) {
-	var branchName string
-	if stu.config.BranchName != "" {
-		branchName = stu.config.BranchName
-	} else {
-		var err error
-		branchName, err = stu.branchMgr.GenerateUniqueBranchName(ctx, "update-tag", stu.config.NewTag)
-		if err != nil {
-			return "", fmt.Errorf("failed to generate branch name: %w", err)
-		}
+// describeAnchors describes the anchors an updated tag is shared through
+func describeAnchors(uses []yaml.AnchorUse) []string {
+	described := make([]string, 0, len(uses))
+	for _, use := range uses {
+		described = append(described, use.String())
 	}
+	return described
+}
+
+// prepareBranchName returns the configured branch name or a deterministic one derived
+// from the idempotency key, so retried runs converge on the same branch
+func (stu *SimpleTagUpdater) prepareBranchName(_ context.Context) (string, error) {
+	branchName := stu.updateBranchName()
 
 	stu.logger.WithFields(map[string]interface{}{
 		"branch_name":    branchName,
@@ -256,62 +772,157 @@ func (stu *SimpleTagUpdater) prepareBranchName(ctx context.Context) (string, err
 	return branchName, nil
 }
 
-// handleDryRun handles dry run mode
+// updateBranchName returns the configured branch name or the one derived from the
+// idempotency key
+func (stu *SimpleTagUpdater) updateBranchName() string {
+	if stu.config.BranchName != "" {
+		return stu.config.BranchName
+	}
+	return stu.branchMgr.GenerateIdempotentBranchName(gitlabapi.UpdateBranchPrefix, stu.config.NewTag, stu.idempotencyKey)
+}
+
+// handleDryRun handles dry run mode: the console preview is bounded while the full
+// updated content and unified diff are written to temporary artifacts
 func (stu *SimpleTagUpdater) handleDryRun(result *SimpleUpdateResult, newContent string) *SimpleUpdateResult {
-	maxLen := minInt(PreviewContentMaxLength, len(newContent))
+	stu.updatedContent = newContent
+	changes := diff.Unified("a/"+stu.config.FilePath, "b/"+stu.config.FilePath,
+		stu.originalContent, newContent, diff.DefaultContextLines)
+	preview := truncateUTF8(changes, PreviewContentMaxLength)
 
 	stu.logger.WithFields(map[string]interface{}{
 		"operation":      "dry_run",
 		"branch_name":    result.BranchName,
 		"content_length": len(newContent),
-		"preview_length": maxLen,
+		"preview_length": len(preview),
 	}).Info("Dry run mode: would create branch and update file")
 
-	stu.logger.WithField("content_preview", newContent[:maxLen]).Debug("Content preview")
+	stu.logger.WithField("diff_preview", preview).Info("Diff preview")
+
+	if result.Commit != nil {
+		stu.logger.WithFields(map[string]interface{}{
+			"branch_name":     result.Commit.Branch,
+			"base_ref":        result.Commit.BaseRef,
+			"base_commit":     result.Commit.BaseCommit,
+			"commit_message":  result.Commit.Message,
+			"carried_commits": result.Commit.CarriedCommits,
+		}).Info("Dry run mode: would commit")
+	}
+
+	previewPath, diffPath, err := stu.writeDryRunArtifacts(newContent, changes)
+	if err != nil {
+		stu.logger.WithError(err).Warn("Failed to write dry run artifacts")
+	} else {
+		result.PreviewPath = previewPath
+		result.DiffPath = diffPath
+		if result.Commit != nil {
+			if result.PatchPath, err = writeCommitPatch(previewPath, result.Commit); err != nil {
+				stu.logger.WithError(err).Warn("Failed to write dry run commit artifact")
+			}
+		}
+		stu.logger.WithFields(map[string]interface{}{
+			"preview_path": previewPath,
+			"diff_path":    diffPath,
+			"patch_path":   result.PatchPath,
+		}).Info("Dry run artifacts written")
+	}
 
 	result.Success = true
 	result.Message = "Dry run completed successfully"
+	if result.DiffPath != "" {
+		result.Message = fmt.Sprintf("%s. Full content: %s, diff: %s", result.Message, previewPath, diffPath)
+	}
+	if result.PatchPath != "" {
+		result.Message += fmt.Sprintf(", commit: %s", result.PatchPath)
+	}
+	if stu.bootstrapRef != "" {
+		result.Message += fmt.Sprintf(". Would create target branch %s from %s",
+			stu.config.TargetBranch, stu.bootstrapRef)
+	}
 	return result
 }
 
+// writeDryRunArtifacts writes the updated content and diff to a new temporary directory
+func (stu *SimpleTagUpdater) writeDryRunArtifacts(newContent, changes string) (previewPath, diffPath string, err error) {
+	dir, err := os.MkdirTemp("", DryRunArtifactsPattern)
+	if err != nil {
+		return "", "", errors.NewFileSystemError(fmt.Sprintf("failed to create artifacts directory: %v", err))
+	}
+
+	previewPath = filepath.Join(dir, path.Base(stu.config.FilePath))
+	if err := os.WriteFile(previewPath, []byte(newContent), TempFilePermissions); err != nil {
+		return "", "", errors.NewFileSystemError(fmt.Sprintf("failed to write preview artifact: %v", err))
+	}
+
+	diffPath = previewPath + DiffArtifactExtension
+	if err := os.WriteFile(diffPath, []byte(changes), TempFilePermissions); err != nil {
+		return "", "", errors.NewFileSystemError(fmt.Sprintf("failed to write diff artifact: %v", err))
+	}
+
+	return previewPath, diffPath, nil
+}
+
 // executeUpdate performs the actual update operations
 func (stu *SimpleTagUpdater) executeUpdate(
 	ctx context.Context,
 	result *SimpleUpdateResult,
 	newContent, branchName string,
 ) (*SimpleUpdateResult, error) {
-	// Create branch
-	_, err := stu.branchMgr.CreateBranch(ctx, branchName, stu.config.TargetBranch)
+	branchName, existing, err := stu.commitUpdate(ctx, result, newContent, branchName)
 	if err != nil {
-		stu.logger.WithError(err).WithFields(map[string]interface{}{
-			"branch_name":   branchName,
-			"source_branch": stu.config.TargetBranch,
-		}).Error("Failed to create branch")
-		return result, fmt.Errorf("failed to create branch %s: %w", branchName, err)
+		return result, err
 	}
+	if existing != nil {
+		return stu.reuseMergeRequest(ctx, result, existing, newContent)
+	}
+	return stu.openMergeRequest(ctx, result, newContent, branchName)
+}
 
-	stu.logger.WithFields(map[string]interface{}{
-		"branch_name":   branchName,
-		"source_branch": stu.config.TargetBranch,
-	}).Info("Branch created successfully")
-
-	// Update file with new content
-	updateOpts := &gitlabapi.FileUpdateOptions{
-		Branch:        branchName,
-		CommitMessage: fmt.Sprintf("Update tag to %s in %s", stu.config.NewTag, stu.config.FilePath),
-		Content:       newContent,
+// commitUpdate creates the update branch and commits the new content to it,
+// returning the name of the branch. When a previous or concurrent run already
+// opened a merge request for the branch, that merge request is returned instead
+// and nothing is committed.
+func (stu *SimpleTagUpdater) commitUpdate(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	newContent, branchName string,
+) (string, *gitlab.BasicMergeRequest, error) {
+	stu.updatedContent = newContent
+	if err := stu.runPreUpdateHooks(ctx, result); err != nil {
+		return branchName, nil, err
 	}
 
-	_, err = stu.fileManager.UpdateFileContent(ctx, stu.config.FilePath, updateOpts)
+	// Create the branch, healing name collisions with branches of other updates
+	branch, reused, err := stu.createOrReuseBranch(ctx, branchName)
 	if err != nil {
-		// Try to cleanup branch on failure
-		_ = stu.branchMgr.DeleteBranch(ctx, branchName)
-		stu.logger.WithError(err).WithFields(map[string]interface{}{
-			"file_path":   stu.config.FilePath,
-			"branch_name": branchName,
-		}).Error("Failed to update file, branch cleaned up")
-		return result, fmt.Errorf("failed to update file: %w", err)
+		return branchName, nil, err
 	}
+	branchName = branch.Name
+	result.BranchName = branchName
+	result.BranchURL = branch.WebURL
+
+	// Converge on a previous run that already opened a merge request for this branch
+	if reused {
+		existing, findErr := stu.findMergeRequestForBranch(ctx, branchName)
+		if findErr != nil {
+			return branchName, nil, findErr
+		}
+		if existing == nil {
+			existing, findErr = stu.awaitConcurrentRun(ctx, branch)
+			if findErr != nil {
+				return branchName, nil, findErr
+			}
+		}
+		if existing != nil {
+			return branchName, existing, nil
+		}
+	}
+
+	// Update file with new content
+	if err := stu.commitContent(ctx, branchName, newContent, reused); err != nil {
+		return branchName, nil, err
+	}
+	stu.reportCommit(ctx, result, branchName)
+	stu.runPostHooks(ctx, HookPostCommit, result)
 
 	result.FileUpdated = true
 	stu.logger.WithFields(map[string]interface{}{
@@ -319,19 +930,40 @@ func (stu *SimpleTagUpdater) executeUpdate(
 		"branch_name": branchName,
 		"new_tag":     stu.config.NewTag,
 	}).Info("File updated successfully")
+	return branchName, nil, nil
+}
+
+// openMergeRequest opens the merge request of the committed update branch, adopting
+// the merge request of a concurrent run when creating it fails
+func (stu *SimpleTagUpdater) openMergeRequest(
+	ctx context.Context,
+	result *SimpleUpdateResult,
+	newContent, branchName string,
+) (*SimpleUpdateResult, error) {
+	// With the queue conflict policy, the merge request waits for the conflicting ones
+	if err := stu.awaitQueuedConflicts(ctx, branchName); err != nil {
+		return result, err
+	}
 
 	// Create merge request
-	mrDescription := fmt.Sprintf("Automated tag update to %s\n\nFile: %s\nBranch: %s",
-		stu.config.NewTag, stu.config.FilePath, branchName)
 	mrOpts := &gitlabapi.SimpleMergeRequestOptions{
-		Title:        fmt.Sprintf("Update tag to %s in %s", stu.config.NewTag, stu.config.FilePath),
-		Description:  mrDescription,
+		Title:        stu.mergeRequestTitle(),
+		Description:  stu.mergeRequestDescription(branchName),
 		SourceBranch: branchName,
 		TargetBranch: stu.config.TargetBranch,
+
+		Squash:                    stu.config.Squash,
+		RemoveSourceBranch:        stu.config.RemoveSourceBranch,
+		MergeWhenPipelineSucceeds: stu.config.AutoMerge,
+		Draft:                     stu.config.QuietRollout,
+		Labels:                    []string{identity.DefaultLabel},
 	}
 
 	mr, err := stu.mrManager.CreateMergeRequest(ctx, mrOpts)
 	if err != nil {
+		if existing := stu.adoptAfterCreateFailure(ctx, branchName); existing != nil {
+			return stu.reuseMergeRequest(ctx, result, existing, newContent)
+		}
 		stu.logger.WithError(err).WithFields(map[string]interface{}{
 			"branch_name":   branchName,
 			"target_branch": stu.config.TargetBranch,
@@ -340,15 +972,85 @@ func (stu *SimpleTagUpdater) executeUpdate(
 	}
 
 	result.MergeRequest = mr
+	stu.recordMergeRequest(mr.IID)
 	stu.logger.WithFields(map[string]interface{}{
 		"mr_id":       mr.IID,
 		"mr_url":      mr.WebURL,
 		"branch_name": branchName,
 	}).Info("Merge request created successfully")
+	stu.commentOnIssues(ctx, mr)
+	stu.reviewApprovals(ctx, result, mr)
+	stu.runPostHooks(ctx, HookPostMR, result)
 
-	result.Success = true
+	var autoMergeErr error
+	if mrOpts.MergeWhenPipelineSucceeds && !stu.deferAutoMerge(result, mrOpts) {
+		autoMergeErr = stu.enableAutoMerge(ctx, result, mrOpts)
+	}
+
+	result.Success = autoMergeErr == nil
 	result.Message = fmt.Sprintf("Tag update completed successfully. MR: !%d", mr.IID)
-	return result, nil
+	if result.TargetBranchCreated {
+		result.Message += fmt.Sprintf(" against new target branch %s", stu.config.TargetBranch)
+	}
+	if !result.MergeDeferredUntil.IsZero() {
+		result.Message += fmt.Sprintf(MergeDeferredFormat, clock.In(result.MergeDeferredUntil).Format(time.RFC3339))
+	}
+	if mrOpts.Draft {
+		result.Message += QuietRolloutNote
+	}
+	result.Message += approvalsNote(result.Approvals)
+	return result, autoMergeErr
+}
+
+// mergeRequestTitle returns the title used for commits and merge requests
+func (stu *SimpleTagUpdater) mergeRequestTitle() string {
+	return fmt.Sprintf("Update tag to %s in %s", stu.config.NewTag, stu.targetFiles())
+}
+
+// targetFiles returns the file the update changes, or the glob of a glob update
+func (stu *SimpleTagUpdater) targetFiles() string {
+	if stu.config.RepoPathGlob != "" {
+		return stu.config.RepoPathGlob
+	}
+	return stu.config.FilePath
+}
+
+// commitMessage returns the commit message with the trailer identifying this run
+func (stu *SimpleTagUpdater) commitMessage() string {
+	return identity.WithCommitTrailer(stu.mergeRequestTitle(), stu.runID)
+}
+
+// mergeRequestDescription returns the merge request description including the tool marker
+func (stu *SimpleTagUpdater) mergeRequestDescription(branchName string) string {
+	description := fmt.Sprintf("Automated tag update to %s\n\nFile: %s\nBranch: %s\n\n",
+		stu.config.NewTag, stu.config.FilePath, branchName)
+	description += stu.issueReferencesSection()
+	if len(stu.rawFallback) > 0 {
+		description += fmt.Sprintf(RawFallbackWarningFormat, stu.config.FilePath,
+			strings.Join(stu.rawFallback, ", ")) + "\n\n"
+	}
+	if len(stu.sharedAnchors) > 0 {
+		description += fmt.Sprintf(SharedAnchorsWarningFormat, stu.config.FilePath,
+			strings.Join(stu.sharedAnchors, ", ")) + "\n\n"
+	}
+	return description + stu.releaseNotesSection() + stu.originalBackupSection() + stu.mergeRequestMarker()
+}
+
+// mergeRequestMarker returns the hidden metadata block identifying this run and update
+func (stu *SimpleTagUpdater) mergeRequestMarker() string {
+	return stu.mergeRequestMetadata().Marker()
+}
+
+// mergeRequestMetadata returns the metadata recorded in the merge request description
+func (stu *SimpleTagUpdater) mergeRequestMetadata() *identity.Metadata {
+	return &identity.Metadata{
+		Version: version.Version,
+		RunID:   stu.runID,
+		File:    stu.targetFiles(),
+		OldTag:  stu.oldTag,
+		NewTag:  stu.config.NewTag,
+		Key:     stu.idempotencyKey,
+	}
 }
 
 // Cleanup performs cleanup operations
@@ -364,3 +1066,15 @@ func minInt(a, b int) int {
 	}
 	return b
 }
+
+// truncateUTF8 cuts s to at most maxBytes bytes without splitting a multi-byte character
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
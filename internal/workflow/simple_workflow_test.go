@@ -2,13 +2,22 @@ package workflow
 
 import (
 	"context"
+	"encoding/base64"
+	stderrors "errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
 
 	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
 	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
 const (
@@ -48,6 +57,47 @@ invalid yaml content: [
 `
 )
 
+// newTestProject returns a fake GitLab server with the test project holding
+// TestYAMLContent at TestFilePath on the target branch
+func newTestProject(t *testing.T) (*gitlabtest.Server, int) {
+	t.Helper()
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, TestYAMLContent)
+	return server, projectID
+}
+
+// testConfig returns the configuration updating TestFilePath to TestNewTag on the
+// update branch TestBranchName
+func testConfig() *config.CLIConfig {
+	return &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+	}
+}
+
+// newTestUpdater creates an updater of cfg working on a project of the fake GitLab
+// server. An empty project ID and token default to the test project.
+func newTestUpdater(t *testing.T, server *gitlabtest.Server, projectID int, cfg *config.CLIConfig) *SimpleTagUpdater {
+	t.Helper()
+	if cfg.ProjectID == "" {
+		cfg.ProjectID = TestProjectID
+	}
+	if cfg.GitLabToken == "" {
+		cfg.GitLabToken = TestGitLabToken
+	}
+	updater, err := NewSimpleTagUpdater(cfg, logger.New(false))
+	if err != nil {
+		t.Fatalf("Failed to create updater: %v", err)
+	}
+	updater.InitializeWithAPI(gitlabapi.NewAPIAdapter(server.Client()), projectID)
+	return updater
+}
+
 func TestNewSimpleTagUpdater(t *testing.T) {
 	log := logger.New(false)
 
@@ -242,13 +292,13 @@ description: "No tags here"
 	}
 }
 
-func TestCreateTempFileWithContent(t *testing.T) {
+func TestUpdateYAMLContent_NoChanges(t *testing.T) {
 	log := logger.New(false)
 	cfg := &config.CLIConfig{
 		ProjectID:    TestProjectID,
 		GitLabToken:  TestGitLabToken,
 		FilePath:     TestFilePath,
-		NewTag:       TestNewTag,
+		NewTag:       TestOldTag,
 		TargetBranch: TestTargetBranch,
 	}
 
@@ -257,60 +307,14 @@ func TestCreateTempFileWithContent(t *testing.T) {
 		t.Fatalf("Failed to create updater: %v", err)
 	}
 
-	testContent := TestYAMLContent
-
-	tempFile2, tempErr := updater.createTempFileWithContent(testContent)
-	if tempErr != nil {
-		t.Fatalf("createTempFileWithContent() failed: %v", tempErr)
-	}
-	tempFile := tempFile2
-
-	// Check that file was created
-	if tempFile == "" {
-		t.Error("createTempFileWithContent() should return non-empty path")
+	if _, err := updater.updateYAMLContent(TestYAMLContent); !stderrors.Is(err, yaml.ErrNoChanges) {
+		t.Fatalf("updateYAMLContent() error = %v, want ErrNoChanges", err)
 	}
 
-	// Check that file exists
-	if _, err := os.Stat(tempFile); os.IsNotExist(err) {
-		t.Errorf("createTempFileWithContent() file should exist at %s", tempFile)
+	result := updater.handleNoChanges(&SimpleUpdateResult{})
+	if !result.Success || !result.Skipped {
+		t.Errorf("handleNoChanges() = %+v, want successful skipped result", result)
 	}
-
-	// Check content
-	content, err := os.ReadFile(tempFile)
-	if err != nil {
-		t.Errorf("createTempFileWithContent() failed to read created file: %v", err)
-	}
-
-	if string(content) != testContent {
-		t.Errorf("createTempFileWithContent() content mismatch")
-	}
-
-	// Check permissions (cross-platform)
-	info, err := os.Stat(tempFile)
-	if err != nil {
-		t.Errorf("createTempFileWithContent() failed to stat file: %v", err)
-	}
-
-	// On Windows, file permissions work differently than Unix
-	// We just verify that the file is readable and writable by the owner
-	actualPerms := info.Mode().Perm()
-	if actualPerms == 0 {
-		t.Error("createTempFileWithContent() file should have some permissions set")
-	}
-
-	// Verify that the file is at least readable and writable
-	// On Unix: should be 0o600 (owner read/write only)
-	// On Windows: might be 0o666 (read/write for all) due to filesystem differences
-	if actualPerms&0o600 != 0o600 {
-		t.Errorf("createTempFileWithContent() file should be readable and writable by owner, got %o", actualPerms)
-	}
-
-	// Cleanup
-	defer func() {
-		if removeErr := os.Remove(tempFile); removeErr != nil {
-			t.Logf("Failed to clean up temp file: %v", removeErr)
-		}
-	}()
 }
 
 func TestMinInt(t *testing.T) {
@@ -362,6 +366,30 @@ func TestMinInt(t *testing.T) {
 	}
 }
 
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		maxBytes int
+		expected string
+	}{
+		{name: "shorter than the limit", s: "tag", maxBytes: 5, expected: "tag"},
+		{name: "ascii cut", s: "image: v1.2.3", maxBytes: 5, expected: "image"},
+		{name: "cut inside a character", s: "tag: ü", maxBytes: 6, expected: "tag: "},
+		{name: "cut after a character", s: "üü", maxBytes: 2, expected: "ü"},
+		{name: "cut inside the first character", s: "日本", maxBytes: 2, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := truncateUTF8(tt.s, tt.maxBytes)
+			if result != tt.expected || !utf8.ValidString(result) {
+				t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.s, tt.maxBytes, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestSimpleTagUpdater_HandleDryRun(t *testing.T) {
 	log := logger.New(false)
 	cfg := &config.CLIConfig{
@@ -397,6 +425,52 @@ func TestSimpleTagUpdater_HandleDryRun(t *testing.T) {
 	}
 }
 
+func TestSimpleTagUpdater_HandleDryRunArtifacts(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	log := logger.New(false)
+	cfg := &config.CLIConfig{
+		ProjectID:    TestProjectID,
+		GitLabToken:  TestGitLabToken,
+		FilePath:     "k8s/" + TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		DryRun:       true,
+	}
+
+	updater, err := NewSimpleTagUpdater(cfg, log)
+	if err != nil {
+		t.Fatalf("Failed to create updater: %v", err)
+	}
+	updater.originalContent = TestYAMLContent
+
+	result := updater.handleDryRun(&SimpleUpdateResult{BranchName: TestBranchName}, TestYAMLContentUpdated)
+
+	if filepath.Base(result.PreviewPath) != TestFilePath {
+		t.Errorf("handleDryRun() preview path = %q, want file named %q", result.PreviewPath, TestFilePath)
+	}
+
+	preview, err := os.ReadFile(result.PreviewPath)
+	if err != nil {
+		t.Fatalf("Failed to read preview artifact: %v", err)
+	}
+	if string(preview) != TestYAMLContentUpdated {
+		t.Errorf("preview artifact should contain the full updated content, got %q", preview)
+	}
+
+	changes, err := os.ReadFile(result.DiffPath)
+	if err != nil {
+		t.Fatalf("Failed to read diff artifact: %v", err)
+	}
+	if !strings.Contains(string(changes), "-  tag: "+TestOldTag) || !strings.Contains(string(changes), "+  tag: "+TestNewTag) {
+		t.Errorf("diff artifact should contain the tag change, got:\n%s", changes)
+	}
+
+	if !strings.Contains(result.Message, result.DiffPath) {
+		t.Errorf("handleDryRun() message should mention the diff path, got %q", result.Message)
+	}
+}
+
 func TestSimpleTagUpdater_PrepareBranchName(t *testing.T) {
 	log := logger.New(false)
 
@@ -482,8 +556,8 @@ func TestSimpleTagUpdater_Cleanup(t *testing.T) {
 	}
 }
 
-func TestTempFileCleanup(t *testing.T) {
-	// Test that temp files can be cleaned up properly
+// Benchmark tests for performance requirements from IDEA.md
+func BenchmarkUpdateYAMLContent(b *testing.B) {
 	log := logger.New(false)
 	cfg := &config.CLIConfig{
 		ProjectID:    TestProjectID,
@@ -495,33 +569,23 @@ func TestTempFileCleanup(t *testing.T) {
 
 	updater, err := NewSimpleTagUpdater(cfg, log)
 	if err != nil {
-		t.Fatalf("Failed to create updater: %v", err)
-	}
-
-	testContent := "test content"
-	tempFile, tempErr := updater.createTempFileWithContent(testContent)
-	if tempErr != nil {
-		t.Fatalf("createTempFileWithContent() failed: %v", tempErr)
+		b.Fatalf("Failed to create updater: %v", err)
 	}
 
-	// Verify file exists
-	if _, err := os.Stat(tempFile); os.IsNotExist(err) {
-		t.Error("Temp file should exist after creation")
-	}
+	content := TestYAMLContent
 
-	// Clean up
-	err = os.Remove(tempFile)
-	if err != nil {
-		t.Errorf("Failed to remove temp file: %v", err)
+	for i := 0; i < b.N; i++ {
+		_, _ = updater.updateYAMLContent(content)
 	}
+}
 
-	// Verify file is gone
-	if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
-		t.Error("Temp file should not exist after cleanup")
+func BenchmarkMinInt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = minInt(i, i+1)
 	}
 }
 
-func TestTempFileLocation(t *testing.T) {
+func BenchmarkNewSimpleTagUpdater(b *testing.B) {
 	log := logger.New(false)
 	cfg := &config.CLIConfig{
 		ProjectID:    TestProjectID,
@@ -531,121 +595,220 @@ func TestTempFileLocation(t *testing.T) {
 		TargetBranch: TestTargetBranch,
 	}
 
-	updater, err := NewSimpleTagUpdater(cfg, log)
-	if err != nil {
-		t.Fatalf("Failed to create updater: %v", err)
+	for i := 0; i < b.N; i++ {
+		_, _ = NewSimpleTagUpdater(cfg, log)
 	}
+}
 
-	testContent := "test content"
-	tempFile, err := updater.createTempFileWithContent(testContent)
-	if err != nil {
-		t.Fatalf("createTempFileWithContent() failed: %v", err)
-	}
-	defer func() {
-		if removeErr := os.Remove(tempFile); removeErr != nil {
-			t.Logf("Failed to clean up temp file: %v", removeErr)
-		}
-	}()
+// mockFileAPI serves repository files from memory; any other API call panics on the nil embedded API
+type mockFileAPI struct {
+	gitlabapi.API
+	files map[string]string
+}
 
-	// Check that temp file is in current directory (not system temp)
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get current directory: %v", err)
-	}
-	expectedDir := filepath.Clean(currentDir)
+func (m *mockFileAPI) GetFile(
+	_ interface{},
+	fileName string,
+	_ *gitlab.GetFileOptions,
+	_ ...gitlab.RequestOptionFunc,
+) (*gitlab.File, *gitlab.Response, error) {
+	content, ok := m.files[fileName]
+	if !ok {
+		return nil, nil, stderrors.New("404 File Not Found")
+	}
+	return &gitlab.File{FilePath: fileName, Content: base64.StdEncoding.EncodeToString([]byte(content))}, nil, nil
+}
 
-	// Get absolute path of temp file for comparison
-	absTempFile, err := filepath.Abs(tempFile)
-	if err != nil {
-		t.Fatalf("Failed to get absolute path of temp file: %v", err)
+// ListCommits reports no history, so missing files were never renamed
+func (m *mockFileAPI) ListCommits(
+	_ interface{},
+	_ *gitlab.ListCommitsOptions,
+	_ ...gitlab.RequestOptionFunc,
+) ([]*gitlab.Commit, *gitlab.Response, error) {
+	return nil, nil, nil
+}
+
+// GetProject reports no project settings, so the pre-flight checks are skipped
+func (m *mockFileAPI) GetProject(
+	_ interface{},
+	_ *gitlab.GetProjectOptions,
+	_ ...gitlab.RequestOptionFunc,
+) (*gitlab.Project, *gitlab.Response, error) {
+	return nil, nil, errors.NewAPIError("project settings are not served")
+}
+
+// ListProtectedBranches reports no protected branches
+func (m *mockFileAPI) ListProtectedBranches(
+	_ interface{},
+	_ *gitlab.ListProtectedBranchesOptions,
+	_ ...gitlab.RequestOptionFunc,
+) ([]*gitlab.ProtectedBranch, *gitlab.Response, error) {
+	return nil, nil, nil
+}
+
+// GetSinglePersonalAccessToken reports no token details, so the token check is skipped
+func (m *mockFileAPI) GetSinglePersonalAccessToken(
+	_ ...gitlab.RequestOptionFunc,
+) (*gitlab.PersonalAccessToken, *gitlab.Response, error) {
+	return nil, nil, errors.NewAPIError("token details are not served")
+}
+
+// GetProjectPushRules reports no push rules
+func (m *mockFileAPI) GetProjectPushRules(
+	_ interface{},
+	_ ...gitlab.RequestOptionFunc,
+) (*gitlab.ProjectPushRules, *gitlab.Response, error) {
+	return nil, nil, nil
+}
+
+func TestSimpleTagUpdater_ExecuteWithMockAPI(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	// Like in a read-only container, nothing can be written to the working directory
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+	if err := os.Remove(workDir); err != nil {
+		t.Skipf("cannot remove the working directory on this platform: %v", err)
 	}
-	actualDir := filepath.Clean(filepath.Dir(absTempFile))
 
-	if actualDir != expectedDir {
-		t.Errorf("Temp file created in %q, expected %q", actualDir, expectedDir)
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantErr     bool
+		wantSkipped bool
+	}{
+		{name: "dry run update", files: map[string]string{TestFilePath: TestYAMLContent}},
+		{name: "tag already set", files: map[string]string{TestFilePath: TestYAMLContentUpdated}, wantSkipped: true},
+		{name: "missing file", files: map[string]string{}, wantErr: true},
 	}
 
-	// Check filename pattern - should start with "go-tag-updater-" and end with ".yaml"
-	actualName := filepath.Base(tempFile)
-	expectedPrefix := "go-tag-updater-"
-	expectedSuffix := ".yaml"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.CLIConfig{
+				ProjectID:    TestProjectID,
+				GitLabToken:  TestGitLabToken,
+				FilePath:     TestFilePath,
+				NewTag:       TestNewTag,
+				TargetBranch: TestTargetBranch,
+				DryRun:       true,
+			}
 
-	if !strings.HasPrefix(actualName, expectedPrefix) {
-		t.Errorf("Temp file name = %q, should start with %q", actualName, expectedPrefix)
-	}
+			updater, err := NewSimpleTagUpdater(cfg, logger.New(false))
+			if err != nil {
+				t.Fatalf("Failed to create updater: %v", err)
+			}
+			updater.InitializeWithAPI(&mockFileAPI{files: tt.files}, 1)
 
-	if !strings.HasSuffix(actualName, expectedSuffix) {
-		t.Errorf("Temp file name = %q, should end with %q", actualName, expectedSuffix)
+			result, err := updater.Execute(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !result.Success || result.Skipped != tt.wantSkipped {
+				t.Errorf("Execute() success = %v, skipped = %v, want skipped %v",
+					result.Success, result.Skipped, tt.wantSkipped)
+			}
+		})
 	}
 }
 
-// Benchmark tests for performance requirements from IDEA.md
-func BenchmarkUpdateYAMLContent(b *testing.B) {
-	log := logger.New(false)
+func TestSimpleTagUpdater_ExecuteFallbackRaw(t *testing.T) {
+	anchored := "registry: &registry registry.example.com\napp:\n  registry: *registry\n  image:\n    tag: v1.0.0\n"
+
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, anchored)
+
 	cfg := &config.CLIConfig{
 		ProjectID:    TestProjectID,
 		GitLabToken:  TestGitLabToken,
 		FilePath:     TestFilePath,
 		NewTag:       TestNewTag,
 		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		FallbackRaw:  true,
 	}
 
-	updater, err := NewSimpleTagUpdater(cfg, log)
-	if err != nil {
-		b.Fatalf("Failed to create updater: %v", err)
-	}
+	updater := newTestUpdater(t, server, projectID, cfg)
 
-	content := TestYAMLContent
+	if _, err := updater.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
 
-	for i := 0; i < b.N; i++ {
-		_, _ = updater.updateYAMLContent(content)
+	content, _ := server.File(projectID, TestBranchName, TestFilePath)
+	if content != strings.Replace(anchored, "v1.0.0", TestNewTag, 1) {
+		t.Errorf("branch content = %q, want only the tag line replaced", content)
 	}
-}
 
-func BenchmarkMinInt(b *testing.B) {
-	for i := 0; i < b.N; i++ {
-		_ = minInt(i, i+1)
+	mrs := server.MergeRequests(projectID)
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "**Warning:**") ||
+		!strings.Contains(mrs[0].Description, "alias *registry at line 3") {
+		t.Errorf("merge requests = %+v, want one with a raw fallback warning", mrs)
 	}
 }
 
-func BenchmarkCreateTempFileWithContent(b *testing.B) {
-	log := logger.New(false)
+func TestSimpleTagUpdater_ExecuteResolveAnchors(t *testing.T) {
+	anchored := "base: &base\n  image:\n    tag: v1.0.0\napp:\n  <<: *base\n"
+
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, anchored)
+
 	cfg := &config.CLIConfig{
 		ProjectID:    TestProjectID,
 		GitLabToken:  TestGitLabToken,
 		FilePath:     TestFilePath,
+		YAMLPath:     "base.image.tag",
 		NewTag:       TestNewTag,
 		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
 	}
 
-	updater, err := NewSimpleTagUpdater(cfg, log)
-	if err != nil {
-		b.Fatalf("Failed to create updater: %v", err)
+	updater := newTestUpdater(t, server, projectID, cfg)
+	if _, err := updater.Execute(context.Background()); err == nil {
+		t.Fatal("Execute() should refuse a tag shared through an anchor without --resolve-anchors")
 	}
 
-	content := TestYAMLContent
+	cfg.ResolveAnchors = true
+	updater = newTestUpdater(t, server, projectID, cfg)
+	if _, err := updater.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
 
-	for i := 0; i < b.N; i++ {
-		tempFile, err := updater.createTempFileWithContent(content)
-		if err != nil {
-			continue
-		}
-		// Clean up immediately to avoid filling disk
-		_ = os.Remove(tempFile)
+	content, _ := server.File(projectID, TestBranchName, TestFilePath)
+	if content != strings.Replace(anchored, "v1.0.0", TestNewTag, 1) {
+		t.Errorf("branch content = %q, want the anchored tag updated", content)
+	}
+
+	mrs := server.MergeRequests(projectID)
+	if len(mrs) != 1 || !strings.Contains(mrs[0].Description, "anchor &base at line 1 aliased at line 5") {
+		t.Errorf("merge requests = %+v, want one with a shared anchor warning", mrs)
 	}
 }
 
-func BenchmarkNewSimpleTagUpdater(b *testing.B) {
-	log := logger.New(false)
+func TestSimpleTagUpdater_ExplicitYAMLPath(t *testing.T) {
+	server, projectID := newTestProject(t)
+
 	cfg := &config.CLIConfig{
 		ProjectID:    TestProjectID,
 		GitLabToken:  TestGitLabToken,
 		FilePath:     TestFilePath,
-		NewTag:       TestNewTag,
+		NewTag:       "2.0.0",
+		YAMLPath:     "version",
 		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
 	}
 
-	for i := 0; i < b.N; i++ {
-		_, _ = NewSimpleTagUpdater(cfg, log)
+	updater := newTestUpdater(t, server, projectID, cfg)
+
+	if _, err := updater.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	content, ok := server.File(projectID, TestBranchName, TestFilePath)
+	if !ok || !strings.Contains(content, "version: 2.0.0") || !strings.Contains(content, "tag: "+TestOldTag) {
+		t.Errorf("branch content = %q, want only version updated", content)
 	}
 }
@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/diff"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// validateSourceDrift checks the configured reaction to source drift
+func validateSourceDrift(onSourceDrift string) error {
+	switch onSourceDrift {
+	case "", config.SourceDriftRefuse, config.SourceDriftWarn:
+		return nil
+	default:
+		return errors.NewConfigError(fmt.Sprintf("invalid source drift action %q: expected %s or %s",
+			onSourceDrift, config.SourceDriftRefuse, config.SourceDriftWarn))
+	}
+}
+
+// sourceRef returns the ref the update branch starts from
+func (stu *SimpleTagUpdater) sourceRef() string {
+	if stu.config.SourceRef != "" {
+		return stu.config.SourceRef
+	}
+	if stu.bootstrapRef != "" {
+		return stu.bootstrapRef
+	}
+	return stu.config.TargetBranch
+}
+
+// checkSourceDrift compares the file at the source ref with the target branch before
+// any branch is created. A difference means the merge request would also revert or
+// reapply unrelated changes to the file, so the update is refused unless configured
+// to only warn.
+func (stu *SimpleTagUpdater) checkSourceDrift(ctx context.Context) error {
+	sourceRef := stu.sourceRef()
+	if sourceRef == stu.config.TargetBranch || stu.bootstrapRef != "" {
+		// A target branch a dry run would create has nothing to drift from
+		return nil
+	}
+
+	driftLog := stu.logger.WithFields(map[string]interface{}{
+		"file_path":     stu.config.FilePath,
+		"source_ref":    sourceRef,
+		"target_branch": stu.config.TargetBranch,
+	})
+
+	files, err := stu.fileManager.PrefetchFiles(ctx, []string{stu.config.FilePath}, stu.config.TargetBranch,
+		gitlabapi.DefaultPrefetchConcurrency)
+	if err != nil && errors.GetErrorCode(err) != errors.ErrCodeFileNotFound {
+		return fmt.Errorf("failed to read %s on target branch %s: %w",
+			stu.config.FilePath, stu.config.TargetBranch, err)
+	}
+
+	var targetContent string
+	if err == nil {
+		targetContent = files[stu.config.FilePath].Content
+	}
+
+	if err == nil && targetContent == stu.originalContent {
+		driftLog.Debug("File is identical on source ref and target branch")
+		return nil
+	}
+
+	changes := "file does not exist on the target branch"
+	if err == nil {
+		changes = diff.Unified("a/"+stu.config.FilePath+"@"+stu.config.TargetBranch,
+			"b/"+stu.config.FilePath+"@"+sourceRef, targetContent, stu.originalContent, diff.DefaultContextLines)
+	}
+	driftLog = driftLog.WithField("drift_preview", truncateUTF8(changes, PreviewContentMaxLength))
+
+	if stu.config.OnSourceDrift == config.SourceDriftWarn {
+		driftLog.Warn("File differs between source ref and target branch; the merge request will carry the difference")
+		return nil
+	}
+
+	driftLog.Error("File differs between source ref and target branch")
+	return errors.NewMergeConflictError(fmt.Sprintf(
+		"%s differs between source ref %s and target branch %s; the merge request would revert or "+
+			"reapply those changes. Update the source ref or pass --on-source-drift=%s",
+		stu.config.FilePath, sourceRef, stu.config.TargetBranch, config.SourceDriftWarn))
+}
@@ -0,0 +1,65 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_SourceDrift(t *testing.T) {
+	const sourceRef = "release"
+
+	tests := []struct {
+		name          string
+		sourceContent string
+		onSourceDrift string
+		wantErr       bool
+	}{
+		{name: "identical file", sourceContent: TestYAMLContent},
+		{name: "drift refused", sourceContent: TestYAMLContent + "replicas: 2\n", wantErr: true},
+		{name: "drift warned", sourceContent: TestYAMLContent + "replicas: 2\n", onSourceDrift: config.SourceDriftWarn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			server.AddBranch(projectID, sourceRef, TestTargetBranch, false)
+			server.SetFile(projectID, sourceRef, TestFilePath, tt.sourceContent)
+
+			cfg := &config.CLIConfig{
+				ProjectID:     TestProjectID,
+				GitLabToken:   TestGitLabToken,
+				FilePath:      TestFilePath,
+				NewTag:        TestNewTag,
+				TargetBranch:  TestTargetBranch,
+				BranchName:    TestBranchName,
+				SourceRef:     sourceRef,
+				OnSourceDrift: tt.onSourceDrift,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			_, err := updater.Execute(context.Background())
+			if tt.wantErr {
+				if errors.ExitCode(err) != errors.ExitCodeConflict {
+					t.Fatalf("Execute() error = %v, want a conflict", err)
+				}
+				if server.BranchExists(projectID, TestBranchName) {
+					t.Error("Execute() created a branch despite source drift")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+
+			want := strings.Replace(tt.sourceContent, TestOldTag, TestNewTag, 1)
+			if content, ok := server.File(projectID, TestBranchName, TestFilePath); !ok || strings.TrimSpace(content) != strings.TrimSpace(want) {
+				t.Errorf("branch content = %q, want the updated source ref content %q", content, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/identity"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// MergeRequestStatus reports where an update merge request stands
+type MergeRequestStatus struct {
+	ProjectID    string `json:"project_id"`
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	WebURL       string `json:"web_url"`
+	State        string `json:"state"`
+	Draft        bool   `json:"draft"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	// OwnMergeRequest reports whether go-tag-updater opened the merge request
+	OwnMergeRequest bool   `json:"own_merge_request"`
+	RunID           string `json:"run_id,omitempty"`
+	File            string `json:"file,omitempty"`
+	OldTag          string `json:"old_tag,omitempty"`
+	NewTag          string `json:"new_tag,omitempty"`
+
+	Pipeline            *PipelineStatus  `json:"pipeline,omitempty"`
+	Approvals           *ApprovalsStatus `json:"approvals,omitempty"`
+	HasConflicts        bool             `json:"has_conflicts"`
+	DetailedMergeStatus string           `json:"detailed_merge_status,omitempty"`
+
+	// FileRef is the branch the file was read from: the target branch once merged,
+	// the source branch otherwise
+	FileRef string `json:"file_ref,omitempty"`
+	// TagPresent reports whether the file still references the new tag; nil when
+	// the file was not checked
+	TagPresent *bool  `json:"tag_present,omitempty"`
+	FileError  string `json:"file_error,omitempty"`
+}
+
+// PipelineStatus is the head pipeline of a merge request
+type PipelineStatus struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	WebURL string `json:"web_url,omitempty"`
+}
+
+// ApprovalsStatus is the approval state of a merge request
+type ApprovalsStatus struct {
+	Approved   bool     `json:"approved"`
+	Required   int      `json:"required"`
+	Left       int      `json:"left"`
+	RulesLeft  []string `json:"rules_left,omitempty"`
+	ApprovedBy []string `json:"approved_by,omitempty"`
+}
+
+// statusReader collects the status of merge requests of one project
+type statusReader struct {
+	projectID string
+	logger    *logrus.Entry
+	mrManager *gitlabapi.SimpleMergeRequestManager
+	approvals *gitlabapi.ApprovalManager
+	files     *gitlabapi.FileManager
+}
+
+// GetMergeRequestStatus reports the state of an update merge request, found by its
+// IID or by the run ID recorded in its description marker: its state, head pipeline,
+// approvals and conflicts, and whether the updated file still references the new tag.
+// The approvals and the file are checked best-effort; their failures are reported in
+// the status rather than returned.
+func GetMergeRequestStatus(
+	ctx context.Context,
+	cfg *config.CLIConfig,
+	iid int,
+	runID string,
+	log *logger.Logger,
+) (*MergeRequestStatus, error) {
+	if cfg == nil || log == nil {
+		return nil, errors.NewValidationError("config and logger are required")
+	}
+	if cfg.ProjectID == "" {
+		return nil, errors.NewValidationError("project ID is required")
+	}
+	if (iid > 0) == (runID != "") {
+		return nil, errors.NewValidationError("either a merge request IID or a run ID is required")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	projectID, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project ID: %w", err)
+	}
+
+	reader := newStatusReader(cfg.ProjectID, gitlabapi.NewAPIAdapter(client.GetGitLabClient()), projectID, log)
+	return reader.status(ctx, iid, runID)
+}
+
+// newStatusReader creates a status reader on top of the given API implementation
+func newStatusReader(project string, api gitlabapi.API, projectID int, log *logger.Logger) *statusReader {
+	return &statusReader{
+		projectID: project,
+		logger:    log.WithField("project_id", project),
+		mrManager: gitlabapi.NewSimpleMergeRequestManagerWithAPI(api, projectID),
+		approvals: gitlabapi.NewApprovalManagerWithAPI(api, projectID),
+		files:     gitlabapi.NewFileManagerWithAPI(api, projectID),
+	}
+}
+
+// status finds the merge request and collects its status
+func (r *statusReader) status(ctx context.Context, iid int, runID string) (*MergeRequestStatus, error) {
+	if runID != "" {
+		mrs, err := r.mrManager.FindToolMergeRequests(ctx, &gitlabapi.ToolMergeRequestFilter{RunID: runID})
+		if err != nil {
+			return nil, err
+		}
+		if len(mrs) == 0 {
+			return nil, errors.NewValidationErrorWithContext(fmt.Sprintf(
+				"no merge request of run %s found in %s", runID, r.projectID), runID)
+		}
+		iid = mrs[0].IID
+	}
+
+	mr, err := r.mrManager.GetMergeRequest(ctx, iid)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MergeRequestStatus{
+		ProjectID:           r.projectID,
+		IID:                 mr.IID,
+		Title:               mr.Title,
+		WebURL:              mr.WebURL,
+		State:               mr.State,
+		Draft:               mr.Draft,
+		SourceBranch:        mr.SourceBranch,
+		TargetBranch:        mr.TargetBranch,
+		OwnMergeRequest:     identity.New().OwnsMergeRequest(&identity.Event{Description: mr.Description}),
+		HasConflicts:        mr.HasConflicts,
+		DetailedMergeStatus: mr.DetailedMergeStatus,
+	}
+	if metadata, ok := identity.ParseMarker(mr.Description); ok {
+		status.RunID, status.File = metadata.RunID, metadata.File
+		status.OldTag, status.NewTag = metadata.OldTag, metadata.NewTag
+	}
+	if mr.HeadPipeline != nil {
+		status.Pipeline = &PipelineStatus{
+			ID:     mr.HeadPipeline.ID,
+			Status: mr.HeadPipeline.Status,
+			WebURL: mr.HeadPipeline.WebURL,
+		}
+	}
+
+	if approvals, err := r.approvals.Status(ctx, mr.IID); err != nil {
+		r.logger.WithError(err).Debug("Failed to get merge request approvals")
+	} else {
+		status.Approvals = &ApprovalsStatus{
+			Approved:   approvals.Approved,
+			Required:   approvals.Required,
+			Left:       approvals.Left,
+			RulesLeft:  approvals.RulesLeft,
+			ApprovedBy: approvals.ApprovedBy,
+		}
+	}
+
+	r.checkFile(ctx, mr, status)
+	return status, nil
+}
+
+// checkFile reports whether the updated file still references the new tag. Merge
+// requests of glob updates and without a marker name no single file and are skipped.
+func (r *statusReader) checkFile(ctx context.Context, mr *gitlab.MergeRequest, status *MergeRequestStatus) {
+	if status.File == "" || status.NewTag == "" || strings.ContainsAny(status.File, "*?[") {
+		return
+	}
+
+	status.FileRef = mr.SourceBranch
+	if mr.State == gitlabapi.StateMerged {
+		status.FileRef = mr.TargetBranch
+	}
+	content, err := r.files.GetFileContent(ctx, status.File, status.FileRef)
+	if err != nil {
+		status.FileError = err.Error()
+		return
+	}
+	_, count, err := yaml.ReplaceTagValues(content, status.NewTag, status.NewTag)
+	if err != nil {
+		status.FileError = err.Error()
+		return
+	}
+	present := count > 0
+	status.TagPresent = &present
+}
@@ -0,0 +1,57 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestStatusReader(t *testing.T) {
+	server, projectID := newTestProject(t)
+	server.SetApprovalRule(projectID, "Platform team", 1)
+	updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+		FilePath:     TestFilePath,
+		NewTag:       TestNewTag,
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+	})
+	result, err := updater.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	reader := newStatusReader(TestProjectID, gitlabapi.NewAPIAdapter(server.Client()), projectID, logger.New(false))
+
+	byIID, err := reader.status(context.Background(), result.MergeRequest.IID, "")
+	if err != nil {
+		t.Fatalf("status() by IID unexpected error: %v", err)
+	}
+	if byIID.State != gitlabapi.StateOpened || !byIID.OwnMergeRequest || byIID.RunID != updater.RunID() ||
+		byIID.NewTag != TestNewTag || byIID.FileRef != TestBranchName {
+		t.Errorf("status() = %+v, want the open merge request of the run", byIID)
+	}
+	if byIID.TagPresent == nil || !*byIID.TagPresent {
+		t.Errorf("status().TagPresent = %v, want true", byIID.TagPresent)
+	}
+	if byIID.Approvals == nil || byIID.Approvals.Approved || byIID.Approvals.Left != 1 {
+		t.Errorf("status().Approvals = %+v, want one approval left", byIID.Approvals)
+	}
+
+	server.SetFile(projectID, TestBranchName, TestFilePath, TestYAMLContent)
+	byRun, err := reader.status(context.Background(), 0, updater.RunID())
+	if err != nil {
+		t.Fatalf("status() by run unexpected error: %v", err)
+	}
+	if byRun.IID != result.MergeRequest.IID {
+		t.Errorf("status().IID = %d, want %d", byRun.IID, result.MergeRequest.IID)
+	}
+	if byRun.TagPresent == nil || *byRun.TagPresent {
+		t.Errorf("status().TagPresent = %v, want false once the tag was reverted", byRun.TagPresent)
+	}
+
+	if _, err := reader.status(context.Background(), 0, "unknown-run"); err == nil {
+		t.Error("status() of an unknown run succeeded, want an error")
+	}
+}
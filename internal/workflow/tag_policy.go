@@ -0,0 +1,73 @@
+package workflow
+
+import (
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/keyvalue"
+	"github.com/Gosayram/go-tag-updater/internal/manifest"
+	"github.com/Gosayram/go-tag-updater/internal/semver"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+)
+
+// tagField locates the tag of a file: a YAML path in the selected document, or a key
+// of a .env or properties file. An empty path stands for the auto-detected field.
+// With imageTag set the field holds an image reference and its tag is the value.
+type tagField struct {
+	filePath    string
+	yamlPath    string
+	docSelector *yaml.DocumentSelector
+	imageTag    bool
+}
+
+// value returns the current tag content holds
+func (f tagField) value(content string) (string, error) {
+	if format, ok := keyvalue.DetectFormat(f.filePath); ok {
+		file, err := keyvalue.Parse(content, format)
+		if err != nil {
+			return "", err
+		}
+		key, err := keyValueKey(file, f.yamlPath)
+		if err != nil {
+			return "", err
+		}
+		entry, err := file.Lookup(key)
+		if err != nil {
+			return "", err
+		}
+		return entry.Value, nil
+	}
+
+	parsed, err := yaml.NewParser().ParseContent(content)
+	if err != nil {
+		return "", err
+	}
+	value, err := tagValue(parsed, f.yamlPath, f.docSelector)
+	if err != nil || !f.imageTag {
+		return value, err
+	}
+	_, tag := manifest.SplitImage(value)
+	return tag, nil
+}
+
+// newTagPolicy creates the semantic version policy of cfg and checks the format of
+// the new tag against it, before anything is read from the repository
+func newTagPolicy(cfg *config.CLIConfig) (*semver.Policy, error) {
+	tagPolicy, err := semver.NewPolicy(cfg.AllowBump, cfg.TagPrefix, cfg.AllowDowngrade)
+	if err != nil {
+		return nil, err
+	}
+	if err := tagPolicy.CheckTag(cfg.NewTag); err != nil {
+		return nil, err
+	}
+	return tagPolicy, nil
+}
+
+// checkTagPolicy compares the new tag with the tag content currently holds. When the
+// current tag cannot be read the update itself reports why, so only the format of
+// the new tag is checked.
+func checkTagPolicy(tagPolicy *semver.Policy, field tagField, content, newTag string) error {
+	current, err := field.value(content)
+	if err != nil {
+		return tagPolicy.CheckTag(newTag)
+	}
+	return tagPolicy.Check(current, newTag)
+}
@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestSimpleTagUpdater_TagPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		newTag         string
+		allow          string
+		allowDowngrade bool
+		wantErr        bool
+	}{
+		{name: "upgrade", newTag: TestNewTag},
+		{name: "downgrade", newTag: "v0.9.0", wantErr: true},
+		{name: "allowed downgrade", newTag: "v0.9.0", allowDowngrade: true},
+		{name: "bump beyond the policy", newTag: "v2.0.0", allow: "minor", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			cfg := &config.CLIConfig{
+				ProjectID:      TestProjectID,
+				GitLabToken:    TestGitLabToken,
+				FilePath:       TestFilePath,
+				NewTag:         tt.newTag,
+				TargetBranch:   TestTargetBranch,
+				AllowBump:      tt.allow,
+				AllowDowngrade: tt.allowDowngrade,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			_, err := updater.Execute(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && errors.GetErrorCode(err) != errors.ErrCodeValidation {
+				t.Errorf("Execute() error code = %d, want a validation error", errors.GetErrorCode(err))
+			}
+			wantMRs := 1
+			if tt.wantErr {
+				wantMRs = 0
+			}
+			if mrs := server.MergeRequests(projectID); len(mrs) != wantMRs {
+				t.Errorf("merge requests = %d, want %d", len(mrs), wantMRs)
+			}
+		})
+	}
+
+	cfg := &config.CLIConfig{NewTag: "1.2.3", TagPrefix: "v"}
+	if _, err := NewSimpleTagUpdater(cfg, logger.New(false)); err == nil {
+		t.Error("NewSimpleTagUpdater() expected an error for a tag without the required prefix")
+	}
+}
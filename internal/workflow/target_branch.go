@@ -0,0 +1,60 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// validateTargetBranchCreation checks the ref a missing target branch is created from
+func validateTargetBranchCreation(createTargetBranch bool, from, targetBranch string) error {
+	if !createTargetBranch {
+		return nil
+	}
+	if from == "" {
+		return errors.NewValidationError("--from is required with --create-target-branch")
+	}
+	if from == targetBranch {
+		return errors.NewValidationError(fmt.Sprintf("--from must differ from the target branch %s", targetBranch))
+	}
+	return nil
+}
+
+// ensureTargetBranch creates the target branch from --from when it does not exist yet
+// and --create-target-branch is set, so a new release branch and the merge request
+// against it are made in one run. A dry run only reads the file from --from instead.
+func (stu *SimpleTagUpdater) ensureTargetBranch(ctx context.Context, result *SimpleUpdateResult) error {
+	if !stu.config.CreateTargetBranch {
+		return nil
+	}
+
+	exists, err := stu.branchMgr.BranchExists(ctx, stu.config.TargetBranch)
+	if err != nil {
+		return fmt.Errorf("failed to check target branch %s: %w", stu.config.TargetBranch, err)
+	}
+	if exists {
+		return nil
+	}
+
+	branchLog := stu.logger.WithFields(map[string]interface{}{
+		"target_branch": stu.config.TargetBranch,
+		"from":          stu.config.TargetBranchFrom,
+	})
+
+	if stu.config.DryRun {
+		stu.bootstrapRef = stu.config.TargetBranchFrom
+		branchLog.Info("DRY RUN: Target branch does not exist and would be created")
+		return nil
+	}
+
+	if _, err := stu.branchMgr.CreateBranch(ctx, stu.config.TargetBranch, stu.config.TargetBranchFrom); err != nil {
+		branchLog.WithError(err).Error("Failed to create target branch")
+		return fmt.Errorf("failed to create target branch %s from %s: %w",
+			stu.config.TargetBranch, stu.config.TargetBranchFrom, err)
+	}
+
+	result.TargetBranchCreated = true
+	branchLog.Info("Target branch created")
+	return nil
+}
@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_CreateTargetBranch(t *testing.T) {
+	const releaseBranch = "release/2.0"
+
+	tests := []struct {
+		name        string
+		create      bool
+		dryRun      bool
+		wantErr     bool
+		wantCreated bool
+	}{
+		{name: "creates missing target branch", create: true, wantCreated: true},
+		{name: "dry run reads from the base", create: true, dryRun: true},
+		{name: "missing target branch without the option", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+
+			cfg := &config.CLIConfig{
+				ProjectID:          TestProjectID,
+				GitLabToken:        TestGitLabToken,
+				FilePath:           TestFilePath,
+				NewTag:             TestNewTag,
+				TargetBranch:       releaseBranch,
+				BranchName:         TestBranchName,
+				CreateTargetBranch: tt.create,
+				TargetBranchFrom:   TestTargetBranch,
+				DryRun:             tt.dryRun,
+			}
+
+			updater := newTestUpdater(t, server, projectID, cfg)
+
+			result, err := updater.Execute(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if result.TargetBranchCreated != tt.wantCreated || server.BranchExists(projectID, releaseBranch) != tt.wantCreated {
+				t.Errorf("Execute() created target branch = %v, want %v", result.TargetBranchCreated, tt.wantCreated)
+			}
+			if tt.wantCreated {
+				mrs := server.MergeRequests(projectID)
+				if len(mrs) != 1 || mrs[0].TargetBranch != releaseBranch {
+					t.Errorf("merge requests = %+v, want one against %s", mrs, releaseBranch)
+				}
+			}
+		})
+	}
+
+	if _, err := NewSimpleTagUpdater(&config.CLIConfig{TargetBranch: releaseBranch, CreateTargetBranch: true},
+		logger.New(false)); err == nil {
+		t.Error("NewSimpleTagUpdater() expected an error without --from")
+	}
+}
@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// CheckToken verifies the configured token against GitLab with the same health check
+// an update starts with, and describes the instance and auth mode it authenticated with.
+// Personal, project and group access tokens must have the api scope and, when a
+// project is configured, at least the Developer role in it.
+func CheckToken(ctx context.Context, cfg *config.CLIConfig) (string, error) {
+	if cfg.GitLabToken == "" {
+		return "", errors.NewValidationError("no GitLab token is configured")
+	}
+
+	client, err := newGitLabClient(cfg, cfg.GitLabURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	if err := client.IsHealthy(ctx); err != nil {
+		return "", fmt.Errorf("GitLab health check failed: %w", err)
+	}
+	if err := checkTokenAccess(ctx, cfg, client); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s token from %s accepted by %s", cfg.AuthMode, cfg.TokenSource,
+		client.GetGitLabClient().BaseURL()), nil
+}
+
+// checkTokenAccess runs the token and project role pre-flight checks
+func checkTokenAccess(ctx context.Context, cfg *config.CLIConfig, client *gitlabapi.Client) error {
+	var projectID interface{}
+	if cfg.ProjectID != "" {
+		id, err := client.ResolveProjectID(ctx, cfg.ProjectID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve project %s: %w", cfg.ProjectID, err)
+		}
+		projectID = id
+	}
+
+	report := gitlabapi.NewPreflightCheckerWithAPI(gitlabapi.NewAPIAdapter(client.GetGitLabClient()), projectID).
+		CheckAccess(ctx, &gitlabapi.PreflightOptions{
+			CheckToken: gitlabapi.AuthMode(cfg.AuthMode) != gitlabapi.AuthModeJob,
+		})
+	if len(report.Problems) > 0 {
+		return errors.NewAuthError("token check failed: " + preflightProblems(report))
+	}
+	return nil
+}
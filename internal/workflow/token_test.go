@@ -0,0 +1,44 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+func TestCheckToken(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	cfg := &config.CLIConfig{
+		GitLabToken: TestGitLabToken,
+		TokenSource: config.TokenSourceFlag,
+		AuthMode:    config.AuthModePAT,
+		GitLabURL:   server.URL(),
+	}
+
+	detail, err := CheckToken(context.Background(), cfg)
+	if err != nil || !strings.Contains(detail, server.URL()) {
+		t.Errorf("CheckToken() = %q, %v; want the instance URL", detail, err)
+	}
+
+	cfg.ProjectID = fmt.Sprint(server.AddProject(TestProjectID))
+	server.SetTokenScopes("read_api")
+	if _, err := CheckToken(context.Background(), cfg); errors.ExitCode(err) != errors.ExitCodeAuth {
+		t.Errorf("CheckToken() error = %v, want an auth error for a token without the api scope", err)
+	}
+
+	server.FailRequests(http.MethodGet, "/user", http.StatusUnauthorized)
+	if _, err := CheckToken(context.Background(), cfg); err == nil {
+		t.Error("CheckToken() should fail when GitLab rejects the token")
+	}
+
+	cfg.GitLabToken = ""
+	if _, err := CheckToken(context.Background(), cfg); err == nil {
+		t.Error("CheckToken() should fail without a token")
+	}
+}
@@ -0,0 +1,64 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Gosayram/go-tag-updater/internal/manifest"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// validateUpdaterProfile checks the updater profile is known, and the HelmRelease
+// mode of the Flux profile
+func validateUpdaterProfile(name, fluxMode string) error {
+	switch {
+	case name == "":
+		return nil
+	case !manifest.IsKnown(name):
+		return errors.NewValidationError(fmt.Sprintf("unknown updater profile %q, expected one of %v",
+			name, manifest.Names()))
+	case name == manifest.FluxHelmRelease:
+		return manifest.CheckFluxMode(fluxMode)
+	}
+	return nil
+}
+
+// applyUpdaterProfile resolves the field the configured updater profile updates in
+// content and selects its document. An explicit --yaml-path takes precedence.
+func (stu *SimpleTagUpdater) applyUpdaterProfile(content string) error {
+	if stu.config.Updater == "" || stu.config.YAMLPath != "" {
+		return nil
+	}
+
+	target, err := manifest.Resolve(stu.config.Updater, content, manifest.Options{
+		RepoURL:  stu.config.RepoURL,
+		FluxMode: stu.config.FluxMode,
+		Service:  stu.config.Service,
+	})
+	if err != nil {
+		return err
+	}
+	docSelector, err := yaml.ParseDocumentSelector(strconv.Itoa(target.Document))
+	if err != nil {
+		return err
+	}
+
+	stu.yamlPath = target.YAMLPath
+	stu.docSelector = docSelector
+	if target.ImageTag {
+		// Keep the repository of the image and replace its tag only
+		image, err := stu.tagField().value(content)
+		if err != nil {
+			return err
+		}
+		stu.imageRef = manifest.WithImageTag(image, stu.config.NewTag)
+	}
+	stu.logger.WithFields(map[string]interface{}{
+		"file_path": stu.config.FilePath,
+		"profile":   stu.config.Updater,
+		"yaml_path": target.YAMLPath,
+		"document":  target.Document,
+	}).Info("Updater profile selected the field to update")
+	return nil
+}
@@ -0,0 +1,84 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+)
+
+func TestSimpleTagUpdater_ExecuteArgoCDProfile(t *testing.T) {
+	application := `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: api
+spec:
+  sources:
+    - repoURL: https://charts.example.com
+      chart: api
+      targetRevision: 1.2.3 # chart version
+    - repoURL: https://gitlab.example.com/platform/values.git
+      targetRevision: main
+      ref: values
+`
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, application)
+
+	updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+		FilePath:     TestFilePath,
+		NewTag:       "1.3.0",
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		Updater:      "argocd",
+		RepoURL:      "https://charts.example.com/",
+	})
+
+	if _, err := updater.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	content, _ := server.File(projectID, TestBranchName, TestFilePath)
+	if content != strings.Replace(application, "1.2.3", "1.3.0", 1) {
+		t.Errorf("branch content = %q, want only the chart source revision updated", content)
+	}
+
+	if _, err := NewSimpleTagUpdater(&config.CLIConfig{Updater: "helmfile"}, logger.New(false)); err == nil {
+		t.Error("NewSimpleTagUpdater() accepted an unknown updater profile")
+	}
+}
+
+func TestSimpleTagUpdater_ExecuteComposeProfile(t *testing.T) {
+	compose := `services:
+  web:
+    build: ./web
+    image: registry.example.com:5000/web:1.2 # pinned
+  db:
+    image: postgres:16
+`
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, compose)
+
+	updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+		FilePath:     TestFilePath,
+		NewTag:       "1.3",
+		TargetBranch: TestTargetBranch,
+		BranchName:   TestBranchName,
+		Updater:      "compose",
+		Service:      "web",
+	})
+
+	if _, err := updater.Execute(context.Background()); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	content, _ := server.File(projectID, TestBranchName, TestFilePath)
+	if want := strings.Replace(compose, "web:1.2", "web:1.3", 1); content != want {
+		t.Errorf("branch content = %q, want %q", content, want)
+	}
+	if updater.oldTag != "1.2" {
+		t.Errorf("old tag = %q, want the tag of the image only", updater.oldTag)
+	}
+}
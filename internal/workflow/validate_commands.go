@@ -0,0 +1,54 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/Gosayram/go-tag-updater/internal/hooks"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/report"
+)
+
+// runValidateCommands runs the --validate-cmd commands on the updated content of a
+// file, keeping their outcomes for the run report
+func (stu *SimpleTagUpdater) runValidateCommands(ctx context.Context, filePath, content string) error {
+	validations, err := runValidateCommands(ctx, stu.config.ValidateCommands, filePath, content, stu.logger)
+	stu.validations = append(stu.validations, validations...)
+	return err
+}
+
+// runValidateCommands runs validation commands on the updated content of a file in
+// order, stopping at the first failing one, and returns the outcome and output of
+// each command run
+func runValidateCommands(
+	ctx context.Context,
+	commands []string,
+	filePath, content string,
+	log *logger.Logger,
+) ([]report.Validation, error) {
+	var validations []report.Validation
+	for _, command := range commands {
+		commandLog := log.WithFields(map[string]interface{}{
+			"file_path":    filePath,
+			"validate_cmd": command,
+		})
+		result, err := hooks.Validate(ctx, command, filePath, content, nil)
+		if result != nil {
+			output := logger.Redact(result.Output)
+			validations = append(validations, report.Validation{
+				Command:  command,
+				Path:     filePath,
+				Passed:   err == nil,
+				ExitCode: result.ExitCode,
+				Duration: result.Duration,
+				Output:   output,
+			})
+			commandLog = commandLog.WithField("output", output)
+		}
+		if err != nil {
+			commandLog.WithError(err).Error("Updated content rejected by validation command")
+			return validations, err
+		}
+		commandLog.Debug("Validation command passed")
+	}
+	return validations, nil
+}
@@ -0,0 +1,61 @@
+package workflow
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+)
+
+func TestSimpleTagUpdater_ValidateCommands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+
+	tests := []struct {
+		name     string
+		commands []string
+		wantErr  bool
+	}{
+		{name: "passing commands", commands: []string{"grep -q 'tag: " + TestNewTag + "' {}", "echo checked {}"}},
+		{name: "failing command", commands: []string{"echo 'tag not pinned' >&2; exit 1", "true"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, projectID := newTestProject(t)
+			updater := newTestUpdater(t, server, projectID, &config.CLIConfig{
+				FilePath:         TestFilePath,
+				NewTag:           TestNewTag,
+				TargetBranch:     TestTargetBranch,
+				BranchName:       TestBranchName,
+				ValidateCommands: tt.commands,
+			})
+
+			_, err := updater.Execute(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Execute() succeeded, want the failing validation command to stop the update")
+				}
+				if server.BranchExists(projectID, TestBranchName) {
+					t.Error("Execute() created the update branch of content rejected by a validation command")
+				}
+				if len(updater.validations) != 1 || updater.validations[0].Passed ||
+					!strings.Contains(updater.validations[0].Output, "tag not pinned") {
+					t.Errorf("validations = %+v, want only the failed command with its output", updater.validations)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if len(updater.validations) != 2 || !updater.validations[1].Passed ||
+				!strings.Contains(updater.validations[1].Output, filepath.Base(TestFilePath)) {
+				t.Errorf("validations = %+v, want both commands passed on a copy of the file", updater.validations)
+			}
+		})
+	}
+}
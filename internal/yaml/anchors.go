@@ -0,0 +1,145 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// AnchorUse is an anchor holding the tag that aliases elsewhere in the document refer
+// to; changing the tag changes the value seen at every one of those aliases
+type AnchorUse struct {
+	Anchor     string
+	Line       int
+	AliasLines []int
+}
+
+// String describes the anchor and the lines of its aliases
+func (u AnchorUse) String() string {
+	lines := make([]string, 0, len(u.AliasLines))
+	for _, line := range u.AliasLines {
+		lines = append(lines, fmt.Sprint(line))
+	}
+	label := "line"
+	if len(lines) > 1 {
+		label = "lines"
+	}
+	return fmt.Sprintf("anchor &%s at line %d aliased at %s %s", u.Anchor, u.Line, label, strings.Join(lines, ", "))
+}
+
+// SharedAnchors returns the anchors of the tag node and of the nodes enclosing it that
+// aliases or merge keys refer to, outermost first
+func (r *ParseResult) SharedAnchors(location *TagLocation) []AnchorUse {
+	if location == nil || location.Node == nil || location.Document >= len(r.Documents) {
+		return nil
+	}
+	document := r.Documents[location.Document]
+
+	aliasLines := make(map[*yaml.Node][]int)
+	collectAliasLines(document, aliasLines)
+	if len(aliasLines) == 0 {
+		return nil
+	}
+
+	var uses []AnchorUse
+	for _, node := range nodePath(document, location.Node) {
+		if lines := aliasLines[node]; node.Anchor != "" && len(lines) > 0 {
+			uses = append(uses, AnchorUse{Anchor: node.Anchor, Line: node.Line, AliasLines: lines})
+		}
+	}
+	return uses
+}
+
+// collectAliasLines maps every anchored node to the lines of the aliases referring to it
+func collectAliasLines(node *yaml.Node, aliasLines map[*yaml.Node][]int) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		aliasLines[node.Alias] = append(aliasLines[node.Alias], node.Line)
+	}
+	for _, child := range node.Content {
+		collectAliasLines(child, aliasLines)
+	}
+}
+
+// nodePath returns the nodes from root down to target, both included, without
+// following aliases; it is empty when target is not below root
+func nodePath(root, target *yaml.Node) []*yaml.Node {
+	if root == nil {
+		return nil
+	}
+	if root == target {
+		return []*yaml.Node{root}
+	}
+	for _, child := range root.Content {
+		if path := nodePath(child, target); path != nil {
+			return append([]*yaml.Node{root}, path...)
+		}
+	}
+	return nil
+}
+
+// checkSharedAnchors refuses to update a tag aliases refer to unless the options
+// resolve anchors
+func checkSharedAnchors(parseResult *ParseResult, location *TagLocation, options *UpdateOptions) error {
+	uses := parseResult.SharedAnchors(location)
+	if len(uses) == 0 || options.ResolveAnchors {
+		return nil
+	}
+
+	described := make([]string, 0, len(uses))
+	for _, use := range uses {
+		described = append(described, use.String())
+	}
+	return errors.NewValidationError(fmt.Sprintf(
+		"tag at path %v is shared through %s; updating it changes every alias too, "+
+			"pass --resolve-anchors to update it anyway", location.Path, strings.Join(described, "; ")))
+}
+
+// anchorSignature lists the anchors and aliases of the documents in document order
+func anchorSignature(documents []*yaml.Node) []string {
+	var signature []string
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node == nil {
+			return
+		}
+		if node.Anchor != "" {
+			signature = append(signature, "&"+node.Anchor)
+		}
+		if node.Kind == yaml.AliasNode {
+			signature = append(signature, "*"+node.Value)
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	for _, document := range documents {
+		walk(document)
+	}
+	return signature
+}
+
+// verifyAnchors checks the updated content keeps every anchor and alias of the
+// original documents, so re-encoding neither expanded nor dropped any of them
+func verifyAnchors(original []*yaml.Node, updated string) error {
+	want := anchorSignature(original)
+	if len(want) == 0 {
+		return nil
+	}
+
+	documents, err := DecodeDocuments(updated)
+	if err != nil {
+		return errors.NewInvalidYAMLError(fmt.Sprintf("updated YAML is invalid: %v", err))
+	}
+	if got := anchorSignature(documents); strings.Join(got, " ") != strings.Join(want, " ") {
+		return errors.NewInvalidYAMLError(fmt.Sprintf(
+			"re-encoding changed anchors and aliases from [%s] to [%s]",
+			strings.Join(want, " "), strings.Join(got, " ")))
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	TestAnchoredTagYAML = `base: &base
+  image:
+    tag: &tag old-tag
+app:
+  <<: *base
+sidecar:
+  tag: *tag
+other:
+  tag: old-tag
+`
+	TestUnrelatedAnchorYAML = `defaults: &res
+  cpu: 100m
+resources: *res
+image:
+  tag: old-tag
+`
+)
+
+func TestParseResult_SharedAnchors(t *testing.T) {
+	tests := []struct {
+		name string
+		path []string
+		want []string
+	}{
+		{
+			name: "anchored tag inside anchored mapping",
+			path: []string{"base", "image", "tag"},
+			want: []string{"anchor &base at line 1 aliased at line 5", "anchor &tag at line 3 aliased at line 7"},
+		},
+		{name: "unshared tag", path: []string{"other", "tag"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			parseResult, err := parser.ParseContent(TestAnchoredTagYAML)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+			location, err := parser.locateTag(parseResult, tt.path)
+			if err != nil {
+				t.Fatalf("locateTag() unexpected error: %v", err)
+			}
+
+			uses := parseResult.SharedAnchors(location)
+			got := make([]string, 0, len(uses))
+			for _, use := range uses {
+				got = append(got, use.String())
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("SharedAnchors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_UpdateTagSharedAnchor(t *testing.T) {
+	parser := NewParser()
+	parseResult, err := parser.ParseContent(TestAnchoredTagYAML)
+	if err != nil {
+		t.Fatalf("ParseContent() unexpected error: %v", err)
+	}
+
+	options := &UpdateOptions{TagPath: []string{"base", "image", "tag"}, NewValue: TestNewTag}
+	if _, err := parser.UpdateTag(parseResult, options); err == nil {
+		t.Error("UpdateTag() should refuse an anchored tag without ResolveAnchors")
+	}
+	if _, err := parser.UpdateTagRaw(parseResult, options); err == nil {
+		t.Error("UpdateTagRaw() should refuse an anchored tag without ResolveAnchors")
+	}
+
+	options.ResolveAnchors = true
+	updated, err := parser.UpdateTagRaw(parseResult, options)
+	if err != nil {
+		t.Fatalf("UpdateTagRaw() unexpected error: %v", err)
+	}
+	if want := strings.Replace(TestAnchoredTagYAML, "&tag old-tag", "&tag "+TestNewTag, 1); updated != want {
+		t.Errorf("UpdateTagRaw() =\n%s\nwant\n%s", updated, want)
+	}
+}
+
+func TestUpdater_UpdateTagInFileResolveAnchors(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), TestValidPath)
+	if err := os.WriteFile(testFile, []byte(TestAnchoredTagYAML), DefaultFilePermissions); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	updater := NewUpdater()
+
+	request := &UpdateRequest{
+		FilePath:       testFile,
+		NewTagValue:    TestNewTag,
+		TagPath:        []string{"base", "image", "tag"},
+		DryRun:         true,
+		ResolveAnchors: true,
+	}
+
+	result, err := updater.UpdateTagInFile(request)
+	if err != nil {
+		t.Fatalf("UpdateTagInFile() unexpected error: %v", err)
+	}
+	if result.RawFallback {
+		t.Error("UpdateTagInFile() used the raw fallback, want re-encoding")
+	}
+	if len(result.SharedAnchors) != 2 {
+		t.Errorf("SharedAnchors = %+v, want &base and &tag", result.SharedAnchors)
+	}
+	if want := strings.Replace(TestAnchoredTagYAML, "&tag old-tag", "&tag "+TestNewTag, 1); result.UpdatedContent != want {
+		t.Errorf("UpdatedContent =\n%s\nwant\n%s", result.UpdatedContent, want)
+	}
+}
+
+func TestVerifyAnchors(t *testing.T) {
+	documents, err := DecodeDocuments(TestSharedAliasYAML)
+	if err != nil {
+		t.Fatalf("DecodeDocuments() unexpected error: %v", err)
+	}
+
+	if err := verifyAnchors(documents, TestSharedAliasYAML); err != nil {
+		t.Errorf("verifyAnchors() of unchanged content unexpected error: %v", err)
+	}
+
+	expanded := strings.Replace(TestSharedAliasYAML, "*registry", "registry.example.com", 1)
+	if err := verifyAnchors(documents, expanded); err == nil {
+		t.Error("verifyAnchors() should reject an expanded alias")
+	}
+}
+
+func TestUpdater_UpdateTagInFileUnrelatedAnchor(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), TestValidPath)
+	if err := os.WriteFile(testFile, []byte(TestUnrelatedAnchorYAML), DefaultFilePermissions); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	updater := NewUpdater()
+
+	result, err := updater.UpdateTagInFile(&UpdateRequest{
+		FilePath:    testFile,
+		NewTagValue: TestNewTag,
+		TagPath:     []string{"image", "tag"},
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("UpdateTagInFile() unexpected error: %v", err)
+	}
+	if result.RawFallback || len(result.SharedAnchors) != 0 {
+		t.Errorf("UpdateTagInFile() result = %+v, want re-encoding without shared anchors", result)
+	}
+	if want := strings.Replace(TestUnrelatedAnchorYAML, "tag: old-tag", "tag: "+TestNewTag, 1); result.UpdatedContent != want {
+		t.Errorf("UpdatedContent =\n%s\nwant\n%s", result.UpdatedContent, want)
+	}
+}
+
+func TestUpdater_UpdateTagInFileSharedTagRefused(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), TestValidPath)
+	if err := os.WriteFile(testFile, []byte(TestAnchoredTagYAML), DefaultFilePermissions); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	_, err := NewUpdater().UpdateTagInFile(&UpdateRequest{
+		FilePath:    testFile,
+		NewTagValue: TestNewTag,
+		TagPath:     []string{"base", "image", "tag"},
+		DryRun:      true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "--resolve-anchors") {
+		t.Errorf("UpdateTagInFile() error = %v, want refusal of the shared tag", err)
+	}
+}
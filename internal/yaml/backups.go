@@ -0,0 +1,124 @@
+package yaml
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/clock"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// BackupRetention decides which backups of a file are kept
+type BackupRetention struct {
+	// MaxCount keeps at most this many of the newest backups; 0 keeps every backup
+	MaxCount int
+	// MaxAge removes backups modified longer ago; 0 keeps backups of any age
+	MaxAge time.Duration
+}
+
+// DefaultBackupRetention keeps the MaxBackupFiles newest backups of any age
+func DefaultBackupRetention() BackupRetention {
+	return BackupRetention{MaxCount: MaxBackupFiles}
+}
+
+// Validate rejects negative limits
+func (r BackupRetention) Validate() error {
+	if r.MaxCount < 0 {
+		return errors.NewValidationError(fmt.Sprintf("backup count %d cannot be negative", r.MaxCount))
+	}
+	if r.MaxAge < 0 {
+		return errors.NewValidationError(fmt.Sprintf("backup max age %s cannot be negative", r.MaxAge))
+	}
+	return nil
+}
+
+// BackupFile is a backup of a file written before an update
+type BackupFile struct {
+	Path    string
+	ModTime time.Time
+}
+
+// ListBackups returns the backups of filePath, newest first. Backups live in backupDir,
+// or next to the file when backupDir is empty.
+func ListBackups(backupDir, filePath string) ([]BackupFile, error) {
+	if filePath == "" {
+		return nil, errors.NewValidationError("file path cannot be empty")
+	}
+	if backupDir == "" {
+		backupDir = filepath.Dir(filePath)
+	}
+
+	pattern := filepath.Join(backupDir, fmt.Sprintf("%s.*.backup", filepath.Base(filePath)))
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backup files: %w", err)
+	}
+
+	backups := make([]BackupFile, 0, len(matches))
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to inspect backup file %s: %w", match, err)
+		}
+		if info.Mode().IsRegular() {
+			backups = append(backups, BackupFile{Path: match, ModTime: info.ModTime()})
+		}
+	}
+
+	// Names break ties so that backups written within the timestamp resolution keep
+	// the order of their timestamped names
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].ModTime.Equal(backups[j].ModTime) {
+			return backups[i].ModTime.After(backups[j].ModTime)
+		}
+		return backups[i].Path > backups[j].Path
+	})
+	return backups, nil
+}
+
+// SelectExpiredBackups returns the backups, listed newest first, that the retention
+// does not keep: those beyond MaxCount and those older than MaxAge at now
+func SelectExpiredBackups(backups []BackupFile, retention BackupRetention, now time.Time) []BackupFile {
+	var expired []BackupFile
+	for i, backup := range backups {
+		tooMany := retention.MaxCount > 0 && i >= retention.MaxCount
+		tooOld := retention.MaxAge > 0 && now.Sub(backup.ModTime) > retention.MaxAge
+		if tooMany || tooOld {
+			expired = append(expired, backup)
+		}
+	}
+	return expired
+}
+
+// PruneBackups removes the backups of filePath the retention does not keep and returns
+// their paths. With dryRun nothing is removed. Every removal is attempted; the
+// failures are returned together.
+func PruneBackups(backupDir, filePath string, retention BackupRetention, dryRun bool) ([]string, error) {
+	if err := retention.Validate(); err != nil {
+		return nil, err
+	}
+	backups, err := ListBackups(backupDir, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var failures []error
+	for _, backup := range SelectExpiredBackups(backups, retention, clock.Now()) {
+		if !dryRun {
+			if err := os.Remove(backup.Path); err != nil && !os.IsNotExist(err) {
+				failures = append(failures, fmt.Errorf("failed to remove backup %s: %w", backup.Path, err))
+				continue
+			}
+		}
+		removed = append(removed, backup.Path)
+	}
+	return removed, stderrors.Join(failures...)
+}
@@ -0,0 +1,123 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeBackups creates backups of values.yaml in dir, modified the given ages ago,
+// and returns their paths in the same order
+func writeBackups(t *testing.T, dir string, now time.Time, ages ...time.Duration) []string {
+	t.Helper()
+	paths := make([]string, 0, len(ages))
+	for _, age := range ages {
+		modTime := now.Add(-age)
+		path := filepath.Join(dir, "values.yaml."+modTime.Format(BackupTimestampFormat)+".backup")
+		if err := os.WriteFile(path, []byte(TestYAMLContent), DefaultFilePermissions); err != nil {
+			t.Fatalf("Failed to create backup: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set backup time: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestListBackups(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	// Written out of order so that name and modification time order differ from creation
+	paths := writeBackups(t, dir, now, 2*time.Hour, time.Hour, 3*time.Hour)
+	unrelated := filepath.Join(dir, "other.yaml.20240101_000000.backup")
+	if err := os.WriteFile(unrelated, nil, DefaultFilePermissions); err != nil {
+		t.Fatalf("Failed to create unrelated backup: %v", err)
+	}
+
+	backups, err := ListBackups(dir, filepath.Join("charts", "values.yaml"))
+	if err != nil {
+		t.Fatalf("ListBackups() unexpected error: %v", err)
+	}
+	got := make([]string, 0, len(backups))
+	for _, backup := range backups {
+		got = append(got, backup.Path)
+	}
+	if want := []string{paths[1], paths[0], paths[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListBackups() = %v, want newest first %v", got, want)
+	}
+
+	if _, err := ListBackups(dir, ""); err == nil {
+		t.Error("ListBackups() expected an error for an empty file path")
+	}
+}
+
+func TestSelectExpiredBackups(t *testing.T) {
+	now := time.Now()
+	backups := []BackupFile{
+		{Path: "a", ModTime: now.Add(-time.Hour)},
+		{Path: "b", ModTime: now.Add(-2 * time.Hour)},
+		{Path: "c", ModTime: now.Add(-3 * time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		retention BackupRetention
+		want      []string
+	}{
+		{name: "no limits"},
+		{name: "count", retention: BackupRetention{MaxCount: 1}, want: []string{"b", "c"}},
+		{name: "age", retention: BackupRetention{MaxAge: 90 * time.Minute}, want: []string{"b", "c"}},
+		{name: "count and age", retention: BackupRetention{MaxCount: 2, MaxAge: 150 * time.Minute}, want: []string{"c"}},
+		{name: "everything kept", retention: BackupRetention{MaxCount: 5, MaxAge: 24 * time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, backup := range SelectExpiredBackups(backups, tt.retention, now) {
+				got = append(got, backup.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectExpiredBackups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "values.yaml")
+	paths := writeBackups(t, dir, time.Now(), time.Hour, 2*time.Hour, 3*time.Hour)
+
+	if _, err := PruneBackups(dir, filePath, BackupRetention{MaxCount: -1}, false); err == nil {
+		t.Error("PruneBackups() expected an error for a negative count")
+	}
+
+	removed, err := PruneBackups("", filePath, BackupRetention{MaxCount: 1}, true)
+	if err != nil {
+		t.Fatalf("PruneBackups() dry run unexpected error: %v", err)
+	}
+	if want := paths[1:]; !reflect.DeepEqual(removed, want) {
+		t.Errorf("PruneBackups() dry run = %v, want %v", removed, want)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s: %v", path, err)
+		}
+	}
+
+	updater := NewUpdaterWithOptions(dir, true, true)
+	updater.SetBackupRetention(BackupRetention{MaxCount: 1})
+	if err := updater.CleanupOldBackups(filePath); err != nil {
+		t.Fatalf("CleanupOldBackups() unexpected error: %v", err)
+	}
+	for i, path := range paths {
+		_, err := os.Stat(path)
+		if kept := err == nil; kept != (i == 0) {
+			t.Errorf("backup %s kept = %v, want only the newest kept", path, kept)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package yaml
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	// CorpusDir holds real-world YAML fixtures and their golden outputs
+	CorpusDir = "testdata/corpus"
+	// GoldenExtension is the extension of the expected output of a fixture
+	GoldenExtension = ".golden"
+)
+
+// updateGolden rewrites the golden files from the current encoder output
+var updateGolden = flag.Bool("update", false, "rewrite corpus golden files")
+
+// TestParser_UpdateTagCorpus updates one tag in each fixture and asserts that the
+// output matches its golden file and differs from the input only on the expected
+// lines, so encoder regressions surface when yaml.v3 is upgraded
+func TestParser_UpdateTagCorpus(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		tagPath      []string
+		newValue     string
+		changedLines []int
+	}{
+		{
+			fixture:      "helm-values.yaml",
+			tagPath:      []string{"image", "tag"},
+			newValue:     "1.5.0",
+			changedLines: []int{7},
+		},
+		{
+			fixture:      "helm-umbrella-values.yaml",
+			tagPath:      []string{"api", "image", "tag"},
+			newValue:     "v2.8.0",
+			changedLines: []int{8},
+		},
+		{
+			fixture:      "k8s-deployment.yaml",
+			tagPath:      []string{"spec", "template", "spec", "containers", "[0]", "image"},
+			newValue:     "registry.example.com/shop/checkout:3.2.0",
+			changedLines: []int{26},
+		},
+		{
+			fixture:      "k8s-kustomization.yaml",
+			tagPath:      []string{"images", "[1]", "newTag"},
+			newValue:     "1.10.0",
+			changedLines: []int{11},
+		},
+		{
+			fixture:      "gitlab-ci.yaml",
+			tagPath:      []string{"variables", "DEPLOY_IMAGE_TAG"},
+			newValue:     "v5.3.0",
+			changedLines: []int{4},
+		},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join(CorpusDir, tt.fixture))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+
+			parseResult, err := parser.ParseContent(string(input))
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+
+			output, err := parser.UpdateTag(parseResult, &UpdateOptions{TagPath: tt.tagPath, NewValue: tt.newValue})
+			if err != nil {
+				t.Fatalf("UpdateTag() unexpected error: %v", err)
+			}
+
+			goldenPath := filepath.Join(CorpusDir, strings.TrimSuffix(tt.fixture, filepath.Ext(tt.fixture))+GoldenExtension)
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, []byte(output), DefaultFilePermissions); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if output != string(golden) {
+				t.Errorf("UpdateTag() output differs from %s:\n%s", goldenPath, output)
+			}
+
+			if changed := changedLines(string(input), output); !reflect.DeepEqual(changed, tt.changedLines) {
+				t.Errorf("UpdateTag() changed lines %v, want exactly %v", changed, tt.changedLines)
+			}
+		})
+	}
+}
+
+// changedLines returns the 1-based numbers of the lines that differ between two texts;
+// texts with a different number of lines report every line past the shorter one
+func changedLines(before, after string) []int {
+	beforeLines := strings.Split(before, "\n")
+	afterLines := strings.Split(after, "\n")
+
+	var changed []int
+	for i := 0; i < len(beforeLines) || i < len(afterLines); i++ {
+		if i >= len(beforeLines) || i >= len(afterLines) || beforeLines[i] != afterLines[i] {
+			changed = append(changed, i+1)
+		}
+	}
+	return changed
+}
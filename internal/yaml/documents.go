@@ -0,0 +1,246 @@
+// Package yaml provides YAML file parsing and manipulation utilities
+package yaml
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// DocumentSeparator starts a document of a multi-document stream
+	DocumentSeparator = "---"
+	// SelectorSeparator separates the field matchers of a document selector
+	SelectorSeparator = ","
+	// SelectorAssignment separates the field of a matcher from its value
+	SelectorAssignment = "="
+	// SelectorPathSeparator separates the keys of a nested matcher field
+	SelectorPathSeparator = "."
+)
+
+// selectorFieldAliases maps the short matcher fields of Kubernetes manifests to their paths
+var selectorFieldAliases = map[string]string{
+	"name":      "metadata.name",
+	"namespace": "metadata.namespace",
+}
+
+// DocumentSelector picks one document of a multi-document stream, either by its
+// zero-based index or by the values of top-level or nested fields
+type DocumentSelector struct {
+	// Index is the document index; -1 when the selector matches fields
+	Index int
+	// Fields maps dotted field paths, such as kind or metadata.name, to their values
+	Fields map[string]string
+
+	expr string
+}
+
+// ParseDocumentSelector parses a selector such as "1" or "kind=Deployment,name=api";
+// name and namespace stand for metadata.name and metadata.namespace. An empty
+// expression returns no selector.
+func ParseDocumentSelector(expr string) (*DocumentSelector, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	if index, err := strconv.Atoi(expr); err == nil {
+		if index < 0 {
+			return nil, errors.NewValidationError(fmt.Sprintf("document index %d cannot be negative", index))
+		}
+		return &DocumentSelector{Index: index, expr: expr}, nil
+	}
+
+	selector := &DocumentSelector{Index: -1, Fields: make(map[string]string), expr: expr}
+	for _, matcher := range strings.Split(expr, SelectorSeparator) {
+		field, value, ok := strings.Cut(matcher, SelectorAssignment)
+		field, value = strings.TrimSpace(field), strings.TrimSpace(value)
+		if !ok || field == "" || value == "" {
+			return nil, errors.NewValidationError(fmt.Sprintf(
+				"invalid document selector %q: expected an index or field=value pairs such as kind=Deployment,name=api",
+				expr))
+		}
+		if path, alias := selectorFieldAliases[field]; alias {
+			field = path
+		}
+		if _, duplicate := selector.Fields[field]; duplicate {
+			return nil, errors.NewValidationError(fmt.Sprintf("document selector %q matches %s twice", expr, field))
+		}
+		selector.Fields[field] = value
+	}
+	return selector, nil
+}
+
+// String returns the selector expression
+func (s *DocumentSelector) String() string {
+	if s.expr == "" && s.Index >= 0 {
+		return strconv.Itoa(s.Index)
+	}
+	return s.expr
+}
+
+// Matches reports whether the document at index matches the selector
+func (s *DocumentSelector) Matches(index int, document *yaml.Node) bool {
+	if s.Index >= 0 {
+		return index == s.Index
+	}
+
+	for field, want := range s.Fields {
+		node := document
+		if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+			node = node.Content[0]
+		}
+		for _, key := range strings.Split(field, SelectorPathSeparator) {
+			if node = childAt(node, key); node == nil {
+				return false
+			}
+		}
+		if node.Kind != yaml.ScalarNode || node.Value != want {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectDocument restricts the parse result to the one document the selector matches:
+// only its tag locations remain and Content points to it. Updates still re-emit every
+// document of the stream. A selector matching no or several documents is an error.
+func (r *ParseResult) SelectDocument(selector *DocumentSelector) error {
+	if selector == nil {
+		return nil
+	}
+
+	var matches []int
+	for i, document := range r.Documents {
+		if selector.Matches(i, document) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return errors.NewValidationError(fmt.Sprintf("no document matches selector %q (%d document(s))",
+			selector, len(r.Documents)))
+	case 1:
+	default:
+		return errors.NewValidationError(fmt.Sprintf("selector %q matches %d documents %v; narrow it down",
+			selector, len(matches), matches))
+	}
+
+	selected := matches[0]
+	locations := make([]TagLocation, 0, len(r.TagLocations))
+	for _, location := range r.TagLocations {
+		if location.Document == selected {
+			locations = append(locations, location)
+		}
+	}
+	r.TagLocations = locations
+	r.Content = r.Documents[selected]
+	return nil
+}
+
+// DecodeDocuments decodes every document of a YAML stream in order
+func DecodeDocuments(content string) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+
+	var documents []*yaml.Node
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if stderrors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, &document)
+	}
+}
+
+// locateTag finds the tag at tagPath; a path found in several documents is ambiguous
+// until a document is selected
+func (p *Parser) locateTag(parseResult *ParseResult, tagPath []string) (*TagLocation, error) {
+	tagLocation := p.findTagByPath(parseResult, tagPath)
+	if tagLocation == nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("tag not found at path: %v", tagPath))
+	}
+
+	documents := make(map[int]bool)
+	for _, location := range parseResult.TagLocations {
+		if p.pathsEqual(location.Path, tagPath) {
+			documents[location.Document] = true
+		}
+	}
+	if len(documents) > 1 {
+		indexes := make([]int, 0, len(documents))
+		for index := range documents {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		return nil, errors.NewValidationError(fmt.Sprintf(
+			"tag path %v is found in documents %v; select one with a document selector", tagPath, indexes))
+	}
+	return tagLocation, nil
+}
+
+// replaceDocument returns the original stream with the document at index replaced by
+// its encoding. The other documents keep their original text, and the separator
+// before the replaced document is kept.
+func (r *ParseResult) replaceDocument(index int, encoded string) string {
+	lines := strings.SplitAfter(r.OriginalContent, "\n")
+	if len(r.Documents) <= 1 && !r.explicitStart(0, lines) {
+		return encoded
+	}
+
+	var b strings.Builder
+	for i := range r.Documents {
+		if i != index {
+			start, end := r.documentLines(i, len(lines))
+			b.WriteString(strings.Join(lines[start:end], ""))
+			continue
+		}
+		if r.explicitStart(i, lines) {
+			b.WriteString(DocumentSeparator + "\n")
+		}
+		b.WriteString(encoded)
+	}
+	return b.String()
+}
+
+// documentLines returns the range of original lines of the document at index: from
+// its separator, or the start of the stream for the first document, to the next one
+func (r *ParseResult) documentLines(index, lineCount int) (start, end int) {
+	if index > 0 {
+		start = r.Documents[index].Line - 1
+	}
+	end = lineCount
+	if index+1 < len(r.Documents) {
+		end = r.Documents[index+1].Line - 1
+	}
+	return start, end
+}
+
+// explicitStart reports whether the document at index starts with a separator line;
+// every document but the first does
+func (r *ParseResult) explicitStart(index int, lines []string) bool {
+	if index > 0 {
+		return true
+	}
+	if len(r.Documents) == 0 {
+		return false
+	}
+
+	line := r.Documents[0].Line - 1
+	if line < 0 || line >= len(lines) {
+		return false
+	}
+	rest, found := strings.CutPrefix(strings.TrimRight(lines[line], "\r\n"), DocumentSeparator)
+	return found && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
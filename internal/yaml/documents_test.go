@@ -0,0 +1,278 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	TestMultiDocumentYAML = `# Rendered manifests
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  ports:
+    - port: 80 # http
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          image: registry.example.com/api:v1.0.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  template:
+    spec:
+      containers:
+        - name: worker
+          image: registry.example.com/worker:v1.0.0
+`
+	TestLeadingSeparatorYAML = `---
+image:
+  tag: v1.0.0
+---
+# untouched
+version:   "2"
+`
+)
+
+func TestParseDocumentSelector(t *testing.T) {
+	tests := []struct {
+		name        string
+		expr        string
+		wantIndex   int
+		wantFields  map[string]string
+		expectNil   bool
+		expectError bool
+	}{
+		{name: "empty", expr: " ", expectNil: true},
+		{name: "index", expr: "2", wantIndex: 2},
+		{
+			name:       "fields",
+			expr:       "kind=Deployment, name=api",
+			wantIndex:  -1,
+			wantFields: map[string]string{"kind": "Deployment", "metadata.name": "api"},
+		},
+		{name: "negative index", expr: "-1", expectError: true},
+		{name: "missing value", expr: "kind=", expectError: true},
+		{name: "missing assignment", expr: "Deployment", expectError: true},
+		{name: "duplicate field", expr: "name=api,metadata.name=web", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := ParseDocumentSelector(tt.expr)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseDocumentSelector(%q) expected error but got none", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDocumentSelector(%q) unexpected error: %v", tt.expr, err)
+			}
+			if tt.expectNil {
+				if selector != nil {
+					t.Errorf("ParseDocumentSelector(%q) = %+v, want nil", tt.expr, selector)
+				}
+				return
+			}
+			if selector.Index != tt.wantIndex || len(selector.Fields) != len(tt.wantFields) {
+				t.Fatalf("ParseDocumentSelector(%q) = %+v", tt.expr, selector)
+			}
+			for field, value := range tt.wantFields {
+				if selector.Fields[field] != value {
+					t.Errorf("Fields[%s] = %q, want %q", field, selector.Fields[field], value)
+				}
+			}
+		})
+	}
+}
+
+func TestParser_ParseContentDocuments(t *testing.T) {
+	parseResult, err := NewParser().ParseContent(TestMultiDocumentYAML)
+	if err != nil {
+		t.Fatalf("ParseContent() unexpected error: %v", err)
+	}
+
+	if len(parseResult.Documents) != 3 {
+		t.Fatalf("Documents = %d, want 3", len(parseResult.Documents))
+	}
+	documents := map[int]bool{}
+	for _, location := range parseResult.TagLocations {
+		documents[location.Document] = true
+	}
+	if !documents[1] || !documents[2] {
+		t.Errorf("tag locations cover documents %v, want 1 and 2", documents)
+	}
+}
+
+func TestParseResult_SelectDocument(t *testing.T) {
+	tests := []struct {
+		name         string
+		expr         string
+		wantDocument int
+		expectError  bool
+	}{
+		{name: "index", expr: "2", wantDocument: 2},
+		{name: "kind and name", expr: "kind=Deployment,name=api", wantDocument: 1},
+		{name: "nested field", expr: "apiVersion=v1", wantDocument: 0},
+		{name: "several matches", expr: "kind=Deployment", expectError: true},
+		{name: "no match", expr: "kind=StatefulSet", expectError: true},
+		{name: "index out of range", expr: "3", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := NewParser().ParseContent(TestMultiDocumentYAML)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+			selector, err := ParseDocumentSelector(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseDocumentSelector() unexpected error: %v", err)
+			}
+
+			err = parseResult.SelectDocument(selector)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("SelectDocument(%q) expected error but got none", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectDocument(%q) unexpected error: %v", tt.expr, err)
+			}
+			if parseResult.Content != parseResult.Documents[tt.wantDocument] {
+				t.Errorf("Content is not document %d", tt.wantDocument)
+			}
+			for _, location := range parseResult.TagLocations {
+				if location.Document != tt.wantDocument {
+					t.Errorf("tag location %v of document %d kept", location.Path, location.Document)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateTagInFileDocuments(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "manifests.yaml")
+	if err := os.WriteFile(filePath, []byte(TestMultiDocumentYAML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	updater := NewUpdater()
+	request := &UpdateRequest{
+		FilePath:    filePath,
+		NewTagValue: "registry.example.com/api:v1.2.3",
+		TagPath:     []string{"spec", "template", "spec", "containers", "[0]", "image"},
+		DryRun:      true,
+	}
+
+	if _, err := updater.UpdateTagInFile(request); err == nil {
+		t.Errorf("UpdateTagInFile() should refuse a tag path found in several documents")
+	}
+
+	request.DocumentSelector, _ = ParseDocumentSelector("kind=Deployment,name=api")
+	result, err := updater.UpdateTagInFile(request)
+	if err != nil {
+		t.Fatalf("UpdateTagInFile() unexpected error: %v", err)
+	}
+
+	// Only the selected document is re-encoded; the others keep their comments and layout
+	want := strings.Replace(TestMultiDocumentYAML, "api:v1.0.0", "api:v1.2.3", 1)
+	if result.UpdatedContent != want {
+		t.Errorf("UpdatedContent =\n%s\nwant\n%s", result.UpdatedContent, want)
+	}
+	if result.OldTagValue != "registry.example.com/api:v1.0.0" {
+		t.Errorf("OldTagValue = %q", result.OldTagValue)
+	}
+}
+
+func TestParser_UpdateTagDocuments(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name     string
+		content  string
+		tagPath  []string
+		expected string
+	}{
+		{
+			name:     "leading separator kept",
+			content:  TestLeadingSeparatorYAML,
+			tagPath:  []string{"image", "tag"},
+			expected: strings.Replace(TestLeadingSeparatorYAML, "v1.0.0", "v1.2.3", 1),
+		},
+		{
+			name:    "later document",
+			content: TestLeadingSeparatorYAML,
+			tagPath: []string{"version"},
+			expected: `---
+image:
+  tag: v1.0.0
+---
+# untouched
+version: "v1.2.3"
+`,
+		},
+		{
+			name:     "single document",
+			content:  "image:\n  tag: v1.0.0\n",
+			tagPath:  []string{"image", "tag"},
+			expected: "image:\n  tag: v1.2.3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := parser.ParseContent(tt.content)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+			updated, err := parser.UpdateTag(parseResult, &UpdateOptions{TagPath: tt.tagPath, NewValue: "v1.2.3"})
+			if err != nil {
+				t.Fatalf("UpdateTag() unexpected error: %v", err)
+			}
+			if updated != tt.expected {
+				t.Errorf("UpdateTag() =\n%s\nwant\n%s", updated, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParser_UpdateTagRawDocuments(t *testing.T) {
+	parser := NewParser()
+	parseResult, err := parser.ParseContent(TestMultiDocumentYAML)
+	if err != nil {
+		t.Fatalf("ParseContent() unexpected error: %v", err)
+	}
+	selector, _ := ParseDocumentSelector("name=worker")
+	if err := parseResult.SelectDocument(selector); err != nil {
+		t.Fatalf("SelectDocument() unexpected error: %v", err)
+	}
+
+	options := &UpdateOptions{
+		TagPath:  []string{"spec", "template", "spec", "containers", "[0]", "image"},
+		NewValue: "registry.example.com/worker:v1.2.3",
+	}
+	updated, err := parser.UpdateTagRaw(parseResult, options)
+	if err != nil {
+		t.Fatalf("UpdateTagRaw() unexpected error: %v", err)
+	}
+	if want := strings.Replace(TestMultiDocumentYAML, "worker:v1.0.0", "worker:v1.2.3", 1); updated != want {
+		t.Errorf("UpdateTagRaw() =\n%s\nwant\n%s", updated, want)
+	}
+}
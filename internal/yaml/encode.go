@@ -0,0 +1,306 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// MaxDiagnosticNodes bounds the number of nodes listed in the structure of a
+	// diagnostic bundle
+	MaxDiagnosticNodes = 500
+	// diagnosticIndent indents nested nodes in the structure of a diagnostic bundle
+	diagnosticIndent = "  "
+)
+
+// EncodeDiagnostics is the bundle captured when the encoder fails on a document. It
+// describes the document without its values, so users can attach it to a bug report.
+type EncodeDiagnostics struct {
+	// Attempts lists the error of every failed encode attempt
+	Attempts []string
+	// Recovered is set when a later attempt encoded the document
+	Recovered bool
+	// TagPath is the path of the updated tag and PathKinds the node kinds along it,
+	// starting at the document node
+	TagPath   []string
+	PathKinds []string
+	// Structure lists every node with its kind, tag, style, anchors and comment
+	// positions; scalar values are replaced by their length
+	Structure string
+}
+
+// String formats the bundle for logs and reports
+func (d *EncodeDiagnostics) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "attempts: %s\n", strings.Join(d.Attempts, "; "))
+	fmt.Fprintf(&b, "recovered: %t\n", d.Recovered)
+	fmt.Fprintf(&b, "tag path: %s\n", strings.Join(d.TagPath, "."))
+	fmt.Fprintf(&b, "path kinds: %s\n", strings.Join(d.PathKinds, " > "))
+	b.WriteString("structure:\n")
+	b.WriteString(d.Structure)
+	return b.String()
+}
+
+// EncodeError is the cause of the error returned when no attempt could encode a document
+type EncodeError struct {
+	Diagnostics *EncodeDiagnostics
+}
+
+// Error implements the error interface
+func (e *EncodeError) Error() string {
+	return "YAML encoder failed: " + strings.Join(e.Diagnostics.Attempts, "; ")
+}
+
+// encodeAttempt is one way of encoding a document; later attempts work on a copy
+// prepared to avoid encoder edge cases
+type encodeAttempt struct {
+	name    string
+	prepare func(*yaml.Node) *yaml.Node
+}
+
+// encodeAttempts are tried in order until one succeeds. The retry drops the scalar and
+// collection styles, which keeps every value and comment but lets the encoder choose
+// the representation.
+var encodeAttempts = []encodeAttempt{
+	{name: "as parsed", prepare: func(node *yaml.Node) *yaml.Node { return node }},
+	{name: "normalized styles", prepare: normalizedCopy},
+}
+
+// encodeNode encodes a document with the given indentation, reporting encoder panics
+// as errors
+var encodeNode = func(node *yaml.Node, indentation int) (content string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("encoder panic: %v", r)
+		}
+	}()
+
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indentation)
+
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// encodeWithRetry encodes a document, retrying failed attempts with a normalized copy.
+// When the first attempt fails, it returns a diagnostic bundle even if a retry succeeds.
+func (p *Parser) encodeWithRetry(root *yaml.Node, tagPath []string) (string, *EncodeDiagnostics, error) {
+	prepareForEncode(root)
+
+	var diagnostics *EncodeDiagnostics
+	for _, attempt := range encodeAttempts {
+		content, err := encodeNode(attempt.prepare(root), p.indentation)
+		if err == nil {
+			if diagnostics != nil {
+				diagnostics.Recovered = true
+			}
+			return content, diagnostics, nil
+		}
+
+		if diagnostics == nil {
+			diagnostics = newEncodeDiagnostics(root, tagPath)
+		}
+		diagnostics.Attempts = append(diagnostics.Attempts, fmt.Sprintf("%s: %v", attempt.name, err))
+	}
+
+	appErr := errors.NewInvalidYAMLError(fmt.Sprintf("failed to encode updated YAML: %s",
+		strings.Join(diagnostics.Attempts, "; ")))
+	appErr.Cause = &EncodeError{Diagnostics: diagnostics}
+	return "", diagnostics, appErr
+}
+
+// newEncodeDiagnostics describes a document that failed to encode
+func newEncodeDiagnostics(root *yaml.Node, tagPath []string) *EncodeDiagnostics {
+	var structure strings.Builder
+	count := 0
+	describeNode(&structure, root, 0, &count)
+	if count > MaxDiagnosticNodes {
+		fmt.Fprintf(&structure, "... %d more nodes\n", count-MaxDiagnosticNodes)
+	}
+
+	return &EncodeDiagnostics{
+		TagPath:   append([]string(nil), tagPath...),
+		PathKinds: pathKinds(root, tagPath),
+		Structure: structure.String(),
+	}
+}
+
+// describeNode writes one line per node below node, up to MaxDiagnosticNodes; count
+// keeps counting past the limit
+func describeNode(b *strings.Builder, node *yaml.Node, depth int, count *int) {
+	if node == nil {
+		return
+	}
+
+	*count++
+	if *count <= MaxDiagnosticNodes {
+		b.WriteString(strings.Repeat(diagnosticIndent, depth))
+		b.WriteString(nodeSummary(node))
+		b.WriteString("\n")
+	}
+
+	// Aliases are described by their anchor name; following them could loop
+	if node.Kind == yaml.AliasNode {
+		return
+	}
+	for _, child := range node.Content {
+		describeNode(b, child, depth+1, count)
+	}
+}
+
+// nodeSummary describes a node without its value
+func nodeSummary(node *yaml.Node) string {
+	parts := []string{kindName(node.Kind)}
+	if node.Tag != "" {
+		parts = append(parts, "tag="+node.Tag)
+	}
+	if style := styleName(node.Style); style != "" {
+		parts = append(parts, "style="+style)
+	}
+	if node.Anchor != "" {
+		parts = append(parts, "anchor="+node.Anchor)
+	}
+	if node.Kind == yaml.AliasNode {
+		parts = append(parts, "alias="+node.Value)
+	}
+	if node.Kind == yaml.ScalarNode {
+		parts = append(parts, fmt.Sprintf("len=%d", len(node.Value)))
+		if strings.Contains(node.Value, "\n") {
+			parts = append(parts, "multiline")
+		}
+	}
+
+	var comments []string
+	for _, comment := range []struct {
+		name, value string
+	}{{"head", node.HeadComment}, {"line", node.LineComment}, {"foot", node.FootComment}} {
+		if comment.value != "" {
+			comments = append(comments, comment.name)
+		}
+	}
+	if len(comments) > 0 {
+		parts = append(parts, "comments="+strings.Join(comments, ","))
+	}
+
+	parts = append(parts, fmt.Sprintf("at %d:%d", node.Line, node.Column))
+	return strings.Join(parts, " ")
+}
+
+// pathKinds returns the kinds of the nodes from the document node along tagPath,
+// stopping where the path leaves the document
+func pathKinds(root *yaml.Node, tagPath []string) []string {
+	node := root
+	kinds := []string{kindName(node.Kind)}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+		kinds = append(kinds, kindName(node.Kind))
+	}
+
+	for _, segment := range tagPath {
+		node = childAt(node, segment)
+		if node == nil {
+			break
+		}
+		kinds = append(kinds, kindName(node.Kind))
+	}
+	return kinds
+}
+
+// childAt returns the value of a mapping key or the sequence item of an index
+// segment such as [0], following aliases
+func childAt(node *yaml.Node, segment string) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for _, pair := range mappingPairs(node, 0) {
+			if pair.key.Value == segment {
+				return pair.value
+			}
+		}
+	case yaml.SequenceNode:
+		var index int
+		if _, err := fmt.Sscanf(segment, "[%d]", &index); err == nil && index >= 0 && index < len(node.Content) {
+			return node.Content[index]
+		}
+	}
+	return nil
+}
+
+// kindName names a node kind
+func kindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.DocumentNode:
+		return "document"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.ScalarNode:
+		return "scalar"
+	case yaml.AliasNode:
+		return "alias"
+	default:
+		return fmt.Sprintf("kind(%d)", kind)
+	}
+}
+
+// styleName names the style flags of a node, or returns "" for the default style
+func styleName(style yaml.Style) string {
+	var names []string
+	for _, flag := range []struct {
+		style yaml.Style
+		name  string
+	}{
+		{yaml.TaggedStyle, "tagged"},
+		{yaml.DoubleQuotedStyle, "double-quoted"},
+		{yaml.SingleQuotedStyle, "single-quoted"},
+		{yaml.LiteralStyle, "literal"},
+		{yaml.FoldedStyle, "folded"},
+		{yaml.FlowStyle, "flow"},
+	} {
+		if style&flag.style != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// normalizedCopy returns a deep copy of a document with every style except explicit
+// tags removed; aliases point to the copies of their anchored nodes
+func normalizedCopy(root *yaml.Node) *yaml.Node {
+	return copyNode(root, make(map[*yaml.Node]*yaml.Node))
+}
+
+// copyNode copies node and its children; copies maps original nodes to their copies
+func copyNode(node *yaml.Node, copies map[*yaml.Node]*yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	if existing, ok := copies[node]; ok {
+		return existing
+	}
+
+	clone := *node
+	clone.Style = node.Style & yaml.TaggedStyle
+	copies[node] = &clone
+
+	clone.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		clone.Content[i] = copyNode(child, copies)
+	}
+	clone.Alias = copyNode(node.Alias, copies)
+	return &clone
+}
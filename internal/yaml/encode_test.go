@@ -0,0 +1,110 @@
+package yaml
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const TestLiteralStyleYAML = `# deployment values
+image:
+  repository: registry.example.com/secret-app
+  tag: old-tag
+notes: |
+  line one
+  line two
+`
+
+// failingEncoder replaces the encoder with one failing on documents with a literal
+// style scalar, or on every document, until the test ends
+func failingEncoder(t *testing.T, failAll bool) {
+	t.Helper()
+
+	original := encodeNode
+	encodeNode = func(node *yaml.Node, indentation int) (string, error) {
+		if failAll || hasStyle(node, yaml.LiteralStyle) {
+			return "", fmt.Errorf("simulated encoder failure")
+		}
+		return original(node, indentation)
+	}
+	t.Cleanup(func() { encodeNode = original })
+}
+
+// hasStyle reports whether a node below node has the style
+func hasStyle(node *yaml.Node, style yaml.Style) bool {
+	if node.Style&style != 0 {
+		return true
+	}
+	for _, child := range node.Content {
+		if hasStyle(child, style) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParser_UpdateTagRetriesEncoding(t *testing.T) {
+	tests := []struct {
+		name          string
+		failAll       bool
+		wantRecovered bool
+	}{
+		{name: "retry recovers", wantRecovered: true},
+		{name: "every attempt fails", failAll: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser()
+			parsed, err := parser.ParseContent(TestLiteralStyleYAML)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+
+			failingEncoder(t, tt.failAll)
+			content, diagnostics, err := parser.updateTag(parsed, &UpdateOptions{
+				TagPath:  []string{"image", "tag"},
+				NewValue: "new-tag",
+			})
+
+			if diagnostics == nil {
+				t.Fatal("updateTag() returned no diagnostics")
+			}
+			if diagnostics.Recovered != tt.wantRecovered {
+				t.Errorf("diagnostics recovered = %t, want %t", diagnostics.Recovered, tt.wantRecovered)
+			}
+			if got := strings.Join(diagnostics.PathKinds, " > "); got != "document > mapping > mapping > scalar" {
+				t.Errorf("diagnostics path kinds = %q", got)
+			}
+			if strings.Contains(diagnostics.String(), "secret-app") || strings.Contains(diagnostics.String(), "line one") {
+				t.Errorf("diagnostics contain scalar values:\n%s", diagnostics)
+			}
+			if !strings.Contains(diagnostics.Structure, "style=literal len=18 multiline") {
+				t.Errorf("diagnostics structure does not describe the literal scalar:\n%s", diagnostics.Structure)
+			}
+
+			if tt.wantRecovered {
+				if err != nil {
+					t.Fatalf("updateTag() unexpected error: %v", err)
+				}
+				if !strings.Contains(content, "tag: new-tag") || !strings.Contains(content, "line two") {
+					t.Errorf("updateTag() content = %q, want the new tag and every value", content)
+				}
+				return
+			}
+
+			var encodeErr *EncodeError
+			if errors.GetErrorCode(err) != errors.ErrCodeInvalidYAML || !stderrors.As(err, &encodeErr) {
+				t.Fatalf("updateTag() error = %v, want an invalid YAML error carrying the diagnostics", err)
+			}
+			if len(encodeErr.Diagnostics.Attempts) != len(encodeAttempts) {
+				t.Errorf("diagnostics attempts = %v, want one per attempt", encodeErr.Diagnostics.Attempts)
+			}
+		})
+	}
+}
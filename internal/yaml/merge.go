@@ -0,0 +1,130 @@
+// Package yaml provides YAML file parsing and manipulation utilities
+package yaml
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// StringTag is the resolved tag of YAML strings
+	StringTag = "!!str"
+	// MaxMergeDepth bounds how deeply merge keys are resolved, guarding against alias cycles
+	MaxMergeDepth = 32
+)
+
+// mappingPair is an effective key/value pair of a mapping after merge keys are applied
+type mappingPair struct {
+	key       *yaml.Node
+	value     *yaml.Node
+	inherited bool
+}
+
+// isMergeKey reports whether a mapping key is a merge key (<<)
+func isMergeKey(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.ShortTag() == MergeKeyTag
+}
+
+// mappingPairs returns the pairs of a mapping node with merge keys resolved: local keys
+// come first and win over merged ones, and earlier merge sources win over later ones
+func mappingPairs(node *yaml.Node, depth int) []mappingPair {
+	var (
+		pairs   []mappingPair
+		sources []*yaml.Node
+		seen    = make(map[string]bool)
+	)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if isMergeKey(key) {
+			sources = append(sources, mergeSources(value)...)
+			continue
+		}
+		seen[key.Value] = true
+		pairs = append(pairs, mappingPair{key: key, value: value})
+	}
+
+	if depth >= MaxMergeDepth {
+		return pairs
+	}
+
+	for _, source := range sources {
+		for _, pair := range mappingPairs(source, depth+1) {
+			if seen[pair.key.Value] {
+				continue
+			}
+			seen[pair.key.Value] = true
+			pair.inherited = true
+			pairs = append(pairs, pair)
+		}
+	}
+
+	return pairs
+}
+
+// mergeSources returns the mappings referenced by a merge key value, which is a mapping,
+// an alias to a mapping, or a sequence of those
+func mergeSources(value *yaml.Node) []*yaml.Node {
+	switch value.Kind {
+	case yaml.AliasNode:
+		if value.Alias != nil {
+			return mergeSources(value.Alias)
+		}
+	case yaml.MappingNode:
+		return []*yaml.Node{value}
+	case yaml.SequenceNode:
+		var sources []*yaml.Node
+		for _, item := range value.Content {
+			if item.Kind != yaml.SequenceNode {
+				sources = append(sources, mergeSources(item)...)
+			}
+		}
+		return sources
+	}
+	return nil
+}
+
+// setScalarValue sets a new scalar value without changing the way its tag is written.
+// Implicitly typed values become strings when the new value would resolve to another
+// type, and explicitly tagged values keep their tag, which must still fit the value.
+func setScalarValue(node *yaml.Node, value string) error {
+	probe := yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	fits := probe.ShortTag() == node.ShortTag()
+
+	if node.Style&yaml.TaggedStyle != 0 {
+		if !fits && node.ShortTag() != StringTag && isStandardTag(node.ShortTag()) {
+			return errors.NewValidationError(fmt.Sprintf("value %q does not match the explicit tag %s",
+				value, node.Tag))
+		}
+	} else if !fits {
+		node.Tag = StringTag
+	}
+
+	node.Value = value
+	return nil
+}
+
+// isStandardTag reports whether a tag is defined by the YAML specification
+func isStandardTag(tag string) bool {
+	return strings.HasPrefix(tag, StandardTagPrefix)
+}
+
+// prepareForEncode clears the resolved tag of merge keys, which the encoder would
+// otherwise write out explicitly as "!!merge <<"; the keys still resolve as merge keys
+func prepareForEncode(node *yaml.Node) {
+	if node == nil {
+		return
+	}
+
+	if isMergeKey(node) && node.Style&yaml.TaggedStyle == 0 {
+		node.Tag = ""
+	}
+
+	for _, child := range node.Content {
+		prepareForEncode(child)
+	}
+}
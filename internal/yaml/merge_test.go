@@ -0,0 +1,151 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	TestMergeKeyYAML = `defaults: &defaults
+  image:
+    tag: old-tag
+  replicas: 2
+app:
+  <<: *defaults
+  replicas: 3
+worker:
+  <<: [*defaults]
+  version: 1.0
+  release: !ref 'old-tag'
+`
+)
+
+func TestParser_MergeKeyTagLocations(t *testing.T) {
+	parser := NewParser()
+
+	parseResult, err := parser.ParseContent(TestMergeKeyYAML)
+	if err != nil {
+		t.Fatalf("ParseContent() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		path      []string
+		inherited bool
+	}{
+		{name: "anchored source", path: []string{"defaults", "image", "tag"}},
+		{name: "merged through alias", path: []string{"app", "image", "tag"}, inherited: true},
+		{name: "merged through sequence", path: []string{"worker", "image", "tag"}, inherited: true},
+		{name: "custom tag", path: []string{"worker", "release"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := parser.findTagByPath(parseResult, tt.path)
+			if location == nil {
+				t.Fatalf("tag location %v not found in %v", tt.path, parseResult.TagLocations)
+			}
+			if location.Inherited != tt.inherited {
+				t.Errorf("Inherited = %v, want %v", location.Inherited, tt.inherited)
+			}
+		})
+	}
+
+	for _, location := range parseResult.TagLocations {
+		for _, segment := range location.Path {
+			if segment == "<<" {
+				t.Errorf("tag location path %v contains a merge key", location.Path)
+			}
+		}
+	}
+}
+
+func TestParser_UpdateTagMergeKeys(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name           string
+		path           []string
+		newValue       string
+		resolveAnchors bool
+		contains       []string
+		expectError    bool
+	}{
+		{
+			name:           "anchored source keeps merge keys",
+			path:           []string{"defaults", "image", "tag"},
+			newValue:       TestNewTag,
+			resolveAnchors: true,
+			contains:       []string{"tag: " + TestNewTag, "<<: *defaults", "<<: [*defaults]"},
+		},
+		{
+			name:        "anchored source without resolving anchors",
+			path:        []string{"defaults", "image", "tag"},
+			newValue:    TestNewTag,
+			expectError: true,
+		},
+		{
+			name:     "custom tag is preserved",
+			path:     []string{"worker", "release"},
+			newValue: TestNewTag,
+			contains: []string{"release: !ref '" + TestNewTag + "'"},
+		},
+		{
+			name:     "implicit float becomes a string",
+			path:     []string{"worker", "version"},
+			newValue: "v1.1",
+			contains: []string{"version: v1.1"},
+		},
+		{
+			name:           "string keeps its type",
+			path:           []string{"defaults", "image", "tag"},
+			newValue:       "1.10",
+			resolveAnchors: true,
+			contains:       []string{`tag: "1.10"`},
+		},
+		{
+			name:        "inherited value",
+			path:        []string{"app", "image", "tag"},
+			newValue:    TestNewTag,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := parser.ParseContent(TestMergeKeyYAML)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+
+			options := &UpdateOptions{TagPath: tt.path, NewValue: tt.newValue, ResolveAnchors: tt.resolveAnchors}
+			updated, err := parser.UpdateTag(parseResult, options)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("UpdateTag() expected error, got %q", updated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateTag() unexpected error: %v", err)
+			}
+
+			if strings.Contains(updated, "!!") {
+				t.Errorf("UpdateTag() wrote explicit standard tags:\n%s", updated)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(updated, want) {
+					t.Errorf("UpdateTag() output missing %q:\n%s", want, updated)
+				}
+			}
+
+			reparsed, err := parser.ParseContent(updated)
+			if err != nil {
+				t.Fatalf("updated content does not parse: %v", err)
+			}
+			if value, _ := parser.GetTagValue(reparsed, tt.path); value != tt.newValue {
+				t.Errorf("updated value = %q, want %q", value, tt.newValue)
+			}
+		})
+	}
+}
@@ -39,15 +39,27 @@ type TagLocation struct {
 	Column int         // Column number in original file
 	Value  interface{} // Current value
 	Node   *yaml.Node  // Reference to YAML node
+
+	// Document is the index of the document holding the tag in a multi-document stream
+	Document int
+
+	// Inherited is set for values merged in through a merge key (<<); the node belongs
+	// to the anchored source mapping, so changing it affects every mapping merging it
+	Inherited bool
 }
 
 // ParseResult contains the result of YAML parsing
 type ParseResult struct {
+	// Content is the first document, or the selected one after SelectDocument
 	Content         *yaml.Node
+	Documents       []*yaml.Node // Every document of the stream, in order
 	TagLocations    []TagLocation
 	OriginalContent string
 	IsValid         bool
 	Errors          []string
+
+	// UnsafeConstructs lists constructs that re-encoding would not preserve faithfully
+	UnsafeConstructs []string
 }
 
 // UpdateOptions contains options for tag updates
@@ -56,6 +68,7 @@ type UpdateOptions struct {
 	NewValue        string   // New value to set
 	CreateIfMissing bool     // Create the tag if it doesn't exist
 	BackupContent   bool     // Keep backup of original content
+	ResolveAnchors  bool     // Update a tag even when aliases refer to its anchor
 }
 
 // NewParser creates a new YAML parser with default settings
@@ -97,71 +110,91 @@ func (p *Parser) ParseContent(content string) (*ParseResult, error) {
 		Errors:          []string{},
 	}
 
-	// Parse the YAML content
-	var rootNode yaml.Node
-	err := yaml.Unmarshal([]byte(content), &rootNode)
+	// Parse every document of the YAML stream
+	documents, err := DecodeDocuments(content)
 	if err != nil {
 		result.IsValid = false
 		result.Errors = append(result.Errors, fmt.Sprintf("YAML parsing error: %v", err))
 		return result, errors.NewInvalidYAMLError(fmt.Sprintf("failed to parse YAML: %v", err))
 	}
 
-	result.Content = &rootNode
+	result.Documents = documents
+	result.Content = &yaml.Node{}
+	if len(documents) > 0 {
+		result.Content = documents[0]
+	}
 	result.IsValid = true
 
-	// Find all tag locations
-	p.findTagLocations(&rootNode, []string{}, result)
+	// Find all tag locations and unsafe constructs; anchors are scoped to their document
+	for i, document := range documents {
+		result.UnsafeConstructs = append(result.UnsafeConstructs, DetectUnsafeConstructs(document)...)
+
+		first := len(result.TagLocations)
+		p.findTagLocations(document, []string{}, result)
+		for j := first; j < len(result.TagLocations); j++ {
+			result.TagLocations[j].Document = i
+		}
+	}
 
 	return result, nil
 }
 
 // UpdateTag updates a specific tag value in the YAML content
 func (p *Parser) UpdateTag(parseResult *ParseResult, options *UpdateOptions) (string, error) {
+	content, _, err := p.updateTag(parseResult, options)
+	return content, err
+}
+
+// updateTag updates a tag value and re-encodes the document; the diagnostics are set
+// when the encoder failed, even if a retry recovered
+func (p *Parser) updateTag(parseResult *ParseResult, options *UpdateOptions) (string, *EncodeDiagnostics, error) {
 	if parseResult == nil {
-		return "", errors.NewValidationError("parse result cannot be nil")
+		return "", nil, errors.NewValidationError("parse result cannot be nil")
 	}
 
 	if options == nil {
-		return "", errors.NewValidationError("update options cannot be nil")
+		return "", nil, errors.NewValidationError("update options cannot be nil")
 	}
 
 	if options.NewValue == "" {
-		return "", errors.NewValidationError("new tag value cannot be empty")
+		return "", nil, errors.NewValidationError("new tag value cannot be empty")
 	}
 
 	if len(options.NewValue) > MaxTagValueLength {
-		return "", errors.NewValidationError(fmt.Sprintf(
+		return "", nil, errors.NewValidationError(fmt.Sprintf(
 			"tag value too long: %d characters (max %d)", len(options.NewValue), MaxTagValueLength))
 	}
 
 	// Find the tag to update
-	tagLocation := p.findTagByPath(parseResult, options.TagPath)
-	if tagLocation == nil {
-		if options.CreateIfMissing {
-			return p.createAndUpdateTag(parseResult, options)
-		}
-		return "", errors.NewValidationError(fmt.Sprintf("tag not found at path: %v", options.TagPath))
+	if options.CreateIfMissing && p.findTagByPath(parseResult, options.TagPath) == nil {
+		content, err := p.createAndUpdateTag(parseResult, options)
+		return content, nil, err
+	}
+	tagLocation, err := p.locateTag(parseResult, options.TagPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if tagLocation.Inherited {
+		return "", nil, inheritedTagError(tagLocation)
+	}
+	if err := checkSharedAnchors(parseResult, tagLocation, options); err != nil {
+		return "", nil, err
 	}
 
 	// Update the tag value
 	if tagLocation.Node != nil {
-		tagLocation.Node.Value = options.NewValue
+		if err := setScalarValue(tagLocation.Node, options.NewValue); err != nil {
+			return "", nil, err
+		}
 	}
 
-	// Convert back to YAML string
-	var buf strings.Builder
-	encoder := yaml.NewEncoder(&buf)
-	encoder.SetIndent(p.indentation)
-
-	err := encoder.Encode(parseResult.Content)
+	// Convert the document back to YAML, retrying encoder failures, and re-emit the stream
+	content, diagnostics, err := p.encodeWithRetry(parseResult.Documents[tagLocation.Document], options.TagPath)
 	if err != nil {
-		return "", errors.NewInvalidYAMLError(fmt.Sprintf("failed to encode updated YAML: %v", err))
+		return "", diagnostics, err
 	}
-
-	if err := encoder.Close(); err != nil {
-		return "", errors.NewInvalidYAMLError(fmt.Sprintf("failed to close YAML encoder: %v", err))
-	}
-	return buf.String(), nil
+	return parseResult.replaceDocument(tagLocation.Document, content), diagnostics, nil
 }
 
 // UpdateTagSimple provides a simple interface for updating a tag by searching for common patterns
@@ -202,9 +235,7 @@ func (p *Parser) ValidateYAML(content string) error {
 		return errors.NewValidationError("YAML content cannot be empty")
 	}
 
-	var temp interface{}
-	err := yaml.Unmarshal([]byte(content), &temp)
-	if err != nil {
+	if _, err := DecodeDocuments(content); err != nil {
 		return errors.NewInvalidYAMLError(fmt.Sprintf("invalid YAML syntax: %v", err))
 	}
 
@@ -219,25 +250,17 @@ func (p *Parser) FormatYAML(content string) (string, error) {
 	}
 
 	var buf strings.Builder
-	encoder := yaml.NewEncoder(&buf)
-	encoder.SetIndent(p.indentation)
-
-	err = encoder.Encode(parseResult.Content)
-	if err != nil {
+	if err := p.encodeDocuments(&buf, parseResult.Documents); err != nil {
 		return "", errors.NewInvalidYAMLError(fmt.Sprintf("failed to format YAML: %v", err))
 	}
-
-	if err := encoder.Close(); err != nil {
-		return "", errors.NewInvalidYAMLError(fmt.Sprintf("failed to close YAML encoder: %v", err))
-	}
 	return buf.String(), nil
 }
 
 // GetTagValue retrieves the value of a tag at the specified path
 func (p *Parser) GetTagValue(parseResult *ParseResult, tagPath []string) (string, error) {
-	tagLocation := p.findTagByPath(parseResult, tagPath)
-	if tagLocation == nil {
-		return "", errors.NewValidationError(fmt.Sprintf("tag not found at path: %v", tagPath))
+	tagLocation, err := p.locateTag(parseResult, tagPath)
+	if err != nil {
+		return "", err
 	}
 
 	if tagLocation.Node != nil && tagLocation.Node.Value != "" {
@@ -252,8 +275,15 @@ func (p *Parser) ListAllTags(parseResult *ParseResult) []TagLocation {
 	return parseResult.TagLocations
 }
 
-// findTagLocations recursively finds all tag locations in the YAML structure
+// findTagLocations recursively finds all tag locations in the YAML structure. Keys
+// merged in through merge keys are reported at the path of the merging mapping.
 func (p *Parser) findTagLocations(node *yaml.Node, path []string, result *ParseResult) {
+	p.walkTagLocations(node, path, 0, result)
+}
+
+// walkTagLocations finds tag locations below node; mergeDepth counts the merge keys
+// followed to reach it and bounds the walk for cyclic aliases
+func (p *Parser) walkTagLocations(node *yaml.Node, path []string, mergeDepth int, result *ParseResult) {
 	if node == nil {
 		return
 	}
@@ -261,43 +291,58 @@ func (p *Parser) findTagLocations(node *yaml.Node, path []string, result *ParseR
 	switch node.Kind {
 	case yaml.DocumentNode:
 		for _, child := range node.Content {
-			p.findTagLocations(child, path, result)
+			p.walkTagLocations(child, path, mergeDepth, result)
 		}
 	case yaml.MappingNode:
-		for i := 0; i < len(node.Content); i += 2 {
-			keyNode := node.Content[i]
-			valueNode := node.Content[i+1]
-
-			if keyNode.Value != "" {
-				nodePath := make([]string, len(path)+1)
-				copy(nodePath, path)
-				nodePath[len(path)] = keyNode.Value
-
-				// Check if this is a potential tag field
-				if p.isTagField(keyNode.Value, valueNode) {
-					location := TagLocation{
-						Path:   nodePath,
-						Line:   valueNode.Line,
-						Column: valueNode.Column,
-						Value:  valueNode.Value,
-						Node:   valueNode,
-					}
-					result.TagLocations = append(result.TagLocations, location)
+		for _, pair := range mappingPairs(node, 0) {
+			if pair.key.Value == "" {
+				continue
+			}
+
+			depth := mergeDepth
+			if pair.inherited {
+				if depth >= MaxMergeDepth {
+					continue
 				}
+				depth++
+			}
 
-				p.findTagLocations(valueNode, nodePath, result)
+			nodePath := make([]string, len(path)+1)
+			copy(nodePath, path)
+			nodePath[len(path)] = pair.key.Value
+
+			// Check if this is a potential tag field
+			if p.isTagField(pair.key.Value, pair.value) {
+				location := TagLocation{
+					Path:      nodePath,
+					Line:      pair.value.Line,
+					Column:    pair.value.Column,
+					Value:     pair.value.Value,
+					Node:      pair.value,
+					Inherited: depth > 0,
+				}
+				result.TagLocations = append(result.TagLocations, location)
 			}
+
+			p.walkTagLocations(pair.value, nodePath, depth, result)
 		}
 	case yaml.SequenceNode:
 		for i, child := range node.Content {
 			indexPath := make([]string, len(path)+1)
 			copy(indexPath, path)
 			indexPath[len(path)] = fmt.Sprintf("[%d]", i)
-			p.findTagLocations(child, indexPath, result)
+			p.walkTagLocations(child, indexPath, mergeDepth, result)
 		}
 	}
 }
 
+// inheritedTagError explains that a merged tag must be updated at its anchored source
+func inheritedTagError(location *TagLocation) error {
+	return errors.NewValidationError(fmt.Sprintf(
+		"tag at path %v is inherited through a merge key; update it at its source on line %d instead",
+		location.Path, location.Line))
+}
+
 // findTagByPath finds a tag at the specified path
 func (p *Parser) findTagByPath(parseResult *ParseResult, targetPath []string) *TagLocation {
 	for _, location := range parseResult.TagLocations {
@@ -326,6 +371,7 @@ func (p *Parser) isTagField(key string, valueNode *yaml.Node) bool {
 		"version",
 		"image",
 		"release",
+		"revision",
 	}
 
 	keyLower := strings.ToLower(key)
@@ -362,17 +408,26 @@ func (p *Parser) pathsEqual(path1, path2 []string) bool {
 
 // WriteToWriter writes formatted YAML content to an io.Writer
 func (p *Parser) WriteToWriter(parseResult *ParseResult, writer io.Writer) error {
+	if err := p.encodeDocuments(writer, parseResult.Documents); err != nil {
+		return errors.NewInvalidYAMLError(fmt.Sprintf("failed to write YAML: %v", err))
+	}
+	return nil
+}
+
+// encodeDocuments writes every document, separated by document separators
+func (p *Parser) encodeDocuments(writer io.Writer, documents []*yaml.Node) error {
 	encoder := yaml.NewEncoder(writer)
 	encoder.SetIndent(p.indentation)
 
-	err := encoder.Encode(parseResult.Content)
-	if err != nil {
-		return errors.NewInvalidYAMLError(fmt.Sprintf("failed to write YAML: %v", err))
+	for _, document := range documents {
+		prepareForEncode(document)
+		if err := encoder.Encode(document); err != nil {
+			return err
+		}
 	}
 
 	if err := encoder.Close(); err != nil {
-		return errors.NewInvalidYAMLError(fmt.Sprintf("failed to close YAML encoder: %v", err))
+		return fmt.Errorf("failed to close YAML encoder: %w", err)
 	}
-
 	return nil
 }
@@ -0,0 +1,204 @@
+// Package yaml provides YAML file parsing and manipulation utilities
+package yaml
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+const (
+	// MergeKeyTag is the resolved tag of YAML merge keys (<<)
+	MergeKeyTag = "!!merge"
+	// StandardTagPrefix is the prefix of tags defined by the YAML specification
+	StandardTagPrefix = "!!"
+	// CommentMarker starts a comment after a plain scalar
+	CommentMarker = " #"
+
+	// singleQuote and doubleQuote delimit quoted scalars on the tag line
+	singleQuote = "'"
+	doubleQuote = `"`
+
+	// quotedStyles are the scalar styles delimited by quotes
+	quotedStyles = yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+)
+
+// DetectUnsafeConstructs lists the constructs of a parsed document that re-encoding is
+// not trusted to preserve: values shared through plain aliases and their anchors.
+// Merge keys with their anchored sources and custom tags are handled by the encoder.
+func DetectUnsafeConstructs(node *yaml.Node) []string {
+	mergeAliases := make(map[*yaml.Node]bool)
+	sharedAnchors := make(map[string]bool)
+	collectAliases(node, mergeAliases, sharedAnchors)
+
+	return unsafeConstructs(node, mergeAliases, sharedAnchors, nil)
+}
+
+// collectAliases records the aliases used as merge sources and the anchors referenced
+// by any other alias
+func collectAliases(node *yaml.Node, mergeAliases map[*yaml.Node]bool, sharedAnchors map[string]bool) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if isMergeKey(node.Content[i]) {
+				markMergeAliases(node.Content[i+1], mergeAliases)
+			}
+		}
+	}
+
+	if node.Kind == yaml.AliasNode && !mergeAliases[node] {
+		sharedAnchors[node.Value] = true
+	}
+
+	for _, child := range node.Content {
+		collectAliases(child, mergeAliases, sharedAnchors)
+	}
+}
+
+// markMergeAliases marks the aliases of a merge key value
+func markMergeAliases(value *yaml.Node, mergeAliases map[*yaml.Node]bool) {
+	switch value.Kind {
+	case yaml.AliasNode:
+		mergeAliases[value] = true
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind == yaml.AliasNode {
+				mergeAliases[item] = true
+			}
+		}
+	}
+}
+
+// unsafeConstructs describes shared anchors and plain aliases in document order
+func unsafeConstructs(
+	node *yaml.Node,
+	mergeAliases map[*yaml.Node]bool,
+	sharedAnchors map[string]bool,
+	constructs []string,
+) []string {
+	if node == nil {
+		return constructs
+	}
+
+	if node.Anchor != "" && sharedAnchors[node.Anchor] {
+		constructs = append(constructs, fmt.Sprintf("anchor &%s at line %d", node.Anchor, node.Line))
+	}
+
+	if node.Kind == yaml.AliasNode && !mergeAliases[node] {
+		constructs = append(constructs, fmt.Sprintf("alias *%s at line %d", node.Value, node.Line))
+	}
+
+	for _, child := range node.Content {
+		constructs = unsafeConstructs(child, mergeAliases, sharedAnchors, constructs)
+	}
+
+	return constructs
+}
+
+// UpdateTagRaw replaces the tag value in place on its source line, leaving every other
+// byte of the original content untouched. It is the fallback for documents whose
+// structure cannot be re-encoded faithfully and only supports single-line scalars.
+func (p *Parser) UpdateTagRaw(parseResult *ParseResult, options *UpdateOptions) (string, error) {
+	if parseResult == nil {
+		return "", errors.NewValidationError("parse result cannot be nil")
+	}
+
+	if options == nil || options.NewValue == "" {
+		return "", errors.NewValidationError("new tag value cannot be empty")
+	}
+
+	tagLocation, err := p.locateTag(parseResult, options.TagPath)
+	if err != nil {
+		return "", err
+	}
+	if tagLocation.Node == nil {
+		return "", errors.NewValidationError(fmt.Sprintf("tag not found at path: %v", options.TagPath))
+	}
+	if tagLocation.Inherited {
+		return "", inheritedTagError(tagLocation)
+	}
+	if err := checkSharedAnchors(parseResult, tagLocation, options); err != nil {
+		return "", err
+	}
+
+	node := tagLocation.Node
+	oldToken, newToken, err := rawScalarTokens(node, options.NewValue)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(parseResult.OriginalContent, "\n")
+	if node.Line < 1 || node.Line > len(lines) || node.Column < 1 || node.Column > len(lines[node.Line-1])+1 {
+		return "", errors.NewInvalidYAMLError(fmt.Sprintf("tag position line %d column %d is out of range",
+			node.Line, node.Column))
+	}
+
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	value := line[start:]
+	if comment := strings.Index(value, CommentMarker); comment >= 0 && node.Style&quotedStyles == 0 {
+		value = value[:comment]
+	}
+
+	offset := strings.Index(value, oldToken)
+	if offset < 0 {
+		return "", errors.NewInvalidYAMLError(fmt.Sprintf("tag value %q not found on line %d", node.Value, node.Line))
+	}
+
+	offset += start
+	lines[node.Line-1] = line[:offset] + newToken + line[offset+len(oldToken):]
+	updated := strings.Join(lines, "\n")
+
+	if err := p.verifyRawUpdate(updated, tagLocation.Document, options); err != nil {
+		return "", err
+	}
+
+	return updated, nil
+}
+
+// rawScalarTokens returns the source text of the current and the new scalar value
+func rawScalarTokens(node *yaml.Node, newValue string) (oldToken, newToken string, err error) {
+	switch {
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return "", "", errors.NewInvalidYAMLError("raw fallback does not support block scalars")
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		return singleQuote + strings.ReplaceAll(node.Value, singleQuote, singleQuote+singleQuote) + singleQuote,
+			singleQuote + strings.ReplaceAll(newValue, singleQuote, singleQuote+singleQuote) + singleQuote, nil
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		return doubleQuote + node.Value + doubleQuote, fmt.Sprintf("%q", newValue), nil
+	case strings.Contains(node.Value, "\n"):
+		return "", "", errors.NewInvalidYAMLError("raw fallback does not support multi-line scalars")
+	default:
+		return node.Value, newValue, nil
+	}
+}
+
+// verifyRawUpdate re-parses the edited content and checks the tag of the document
+// now has the new value
+func (p *Parser) verifyRawUpdate(updated string, document int, options *UpdateOptions) error {
+	parseResult, err := p.ParseContent(updated)
+	if err != nil {
+		return fmt.Errorf("raw fallback produced invalid YAML: %w", err)
+	}
+	if err := parseResult.SelectDocument(&DocumentSelector{Index: document}); err != nil {
+		return fmt.Errorf("raw fallback lost the document: %w", err)
+	}
+
+	value, err := p.GetTagValue(parseResult, options.TagPath)
+	if err != nil {
+		return fmt.Errorf("raw fallback lost the tag: %w", err)
+	}
+
+	if value != options.NewValue {
+		return errors.NewInvalidYAMLError(fmt.Sprintf("raw fallback produced tag value %q instead of %q",
+			value, options.NewValue))
+	}
+
+	return nil
+}
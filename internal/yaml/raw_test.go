@@ -0,0 +1,146 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	TestSharedAliasYAML = `registry: &registry registry.example.com
+app:
+  registry: *registry
+  image:
+    tag: old-tag # pinned by CI
+`
+	TestCustomTagYAML = `image:
+  tag: !ref 'old-tag'
+`
+	TestDoubleQuotedYAML = `release: &rel "old-tag"
+`
+	TestBlockScalarYAML = `base: &base {}
+image:
+  tag: |
+    old-tag
+`
+)
+
+func TestDetectUnsafeConstructs(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{name: "plain document", content: TestYAMLContent},
+		{
+			name:    "shared anchor and alias",
+			content: TestSharedAliasYAML,
+			want:    []string{"anchor &registry at line 1", "alias *registry at line 3"},
+		},
+		{name: "merge key", content: TestMergeKeyYAML},
+		{name: "custom tag", content: TestCustomTagYAML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := parser.ParseContent(tt.content)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+			if strings.Join(parseResult.UnsafeConstructs, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("UnsafeConstructs = %v, want %v", parseResult.UnsafeConstructs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_UpdateTagRaw(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name        string
+		content     string
+		tagPath     []string
+		want        string
+		expectError bool
+	}{
+		{
+			name:    "plain scalar keeps comment and anchors",
+			content: TestSharedAliasYAML,
+			tagPath: []string{"app", "image", "tag"},
+			want:    strings.Replace(TestSharedAliasYAML, "tag: old-tag", "tag: "+TestNewTag, 1),
+		},
+		{
+			name:    "single quoted scalar with custom tag",
+			content: TestCustomTagYAML,
+			tagPath: []string{"image", "tag"},
+			want:    strings.Replace(TestCustomTagYAML, "'old-tag'", "'"+TestNewTag+"'", 1),
+		},
+		{
+			name:    "double quoted anchored scalar",
+			content: TestDoubleQuotedYAML,
+			tagPath: []string{"release"},
+			want:    strings.Replace(TestDoubleQuotedYAML, `"old-tag"`, `"`+TestNewTag+`"`, 1),
+		},
+		{
+			name:        "block scalar",
+			content:     TestBlockScalarYAML,
+			tagPath:     []string{"image", "tag"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := parser.ParseContent(tt.content)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+
+			got, err := parser.UpdateTagRaw(parseResult, &UpdateOptions{TagPath: tt.tagPath, NewValue: TestNewTag})
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("UpdateTagRaw() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateTagRaw() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UpdateTagRaw() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdater_UpdateTagInFileFallbackRaw(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), TestValidPath)
+	if err := os.WriteFile(testFile, []byte(TestSharedAliasYAML), DefaultFilePermissions); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	updater := NewUpdater()
+
+	request := &UpdateRequest{
+		FilePath:    testFile,
+		NewTagValue: TestNewTag,
+		TagPath:     []string{"app", "image", "tag"},
+		DryRun:      true,
+	}
+
+	request.FallbackRaw = true
+	result, err := updater.UpdateTagInFile(request)
+	if err != nil {
+		t.Fatalf("UpdateTagInFile() with fallback unexpected error: %v", err)
+	}
+	if !result.RawFallback || len(result.UnsafeConstructs) == 0 {
+		t.Errorf("UpdateTagInFile() result = %+v, want raw fallback with unsafe constructs", result)
+	}
+	if !strings.Contains(result.UpdatedContent, "tag: "+TestNewTag+" # pinned by CI") ||
+		!strings.Contains(result.UpdatedContent, "registry: *registry") {
+		t.Errorf("UpdateTagInFile() content = %q", result.UpdatedContent)
+	}
+}
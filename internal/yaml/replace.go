@@ -0,0 +1,114 @@
+package yaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// ImageTagSeparator separates the repository of an image reference from its tag
+const ImageTagSeparator = ":"
+
+// tagReplacement is one scalar value replaced by ReplaceTagValues
+type tagReplacement struct {
+	node     *yaml.Node
+	newValue string
+}
+
+// ReplaceTagValues replaces every scalar value equal to oldTag, and every image
+// reference ending in ":"+oldTag, with newTag. Values are edited in place on their
+// source lines so the rest of the content is kept byte for byte. It returns the
+// updated content and the number of values replaced; keys are never replaced.
+func ReplaceTagValues(content, oldTag, newTag string) (string, int, error) {
+	if oldTag == "" || newTag == "" {
+		return "", 0, errors.NewValidationError("old and new tag values cannot be empty")
+	}
+
+	documents, err := DecodeDocuments(content)
+	if err != nil {
+		return "", 0, errors.NewInvalidYAMLError(fmt.Sprintf("failed to parse YAML: %v", err))
+	}
+
+	var replacements []tagReplacement
+	for _, document := range documents {
+		collectTagValues(document, oldTag, newTag, &replacements)
+	}
+	if len(replacements) == 0 {
+		return content, 0, nil
+	}
+
+	// Edit from the end so earlier positions on the same line stay valid
+	sort.Slice(replacements, func(i, j int) bool {
+		a, b := replacements[i].node, replacements[j].node
+		if a.Line != b.Line {
+			return a.Line > b.Line
+		}
+		return a.Column > b.Column
+	})
+
+	lines := strings.Split(content, "\n")
+	for _, replacement := range replacements {
+		if err := replaceScalar(lines, replacement); err != nil {
+			return "", 0, err
+		}
+	}
+
+	updated := strings.Join(lines, "\n")
+	if _, err := DecodeDocuments(updated); err != nil {
+		return "", 0, errors.NewInvalidYAMLError(fmt.Sprintf("replacing %q produced invalid YAML: %v", oldTag, err))
+	}
+	return updated, len(replacements), nil
+}
+
+// collectTagValues records the scalar values under node that carry oldTag
+func collectTagValues(node *yaml.Node, oldTag, newTag string, replacements *[]tagReplacement) {
+	if node == nil {
+		return
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value == oldTag {
+			*replacements = append(*replacements, tagReplacement{node: node, newValue: newTag})
+		} else if strings.HasSuffix(node.Value, ImageTagSeparator+oldTag) {
+			repository := strings.TrimSuffix(node.Value, oldTag)
+			*replacements = append(*replacements, tagReplacement{node: node, newValue: repository + newTag})
+		}
+	case yaml.MappingNode:
+		// Only values are candidates
+		for i := 1; i < len(node.Content); i += 2 {
+			collectTagValues(node.Content[i], oldTag, newTag, replacements)
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			collectTagValues(child, oldTag, newTag, replacements)
+		}
+	}
+}
+
+// replaceScalar replaces the source text of one scalar on its line
+func replaceScalar(lines []string, replacement tagReplacement) error {
+	node := replacement.node
+	oldToken, newToken, err := rawScalarTokens(node, replacement.newValue)
+	if err != nil {
+		return fmt.Errorf("cannot replace value at line %d: %w", node.Line, err)
+	}
+
+	if node.Line < 1 || node.Line > len(lines) || node.Column < 1 || node.Column > len(lines[node.Line-1])+1 {
+		return errors.NewInvalidYAMLError(fmt.Sprintf("value position line %d column %d is out of range",
+			node.Line, node.Column))
+	}
+
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	if !strings.HasPrefix(line[start:], oldToken) {
+		return errors.NewInvalidYAMLError(fmt.Sprintf("value %q not found on line %d", node.Value, node.Line))
+	}
+
+	lines[node.Line-1] = line[:start] + newToken + line[start+len(oldToken):]
+	return nil
+}
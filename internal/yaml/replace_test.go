@@ -0,0 +1,67 @@
+package yaml
+
+import "testing"
+
+func TestReplaceTagValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        string
+		wantCount   int
+		expectError bool
+	}{
+		{
+			name: "tags and image references",
+			content: `image:
+  repository: registry.example.com/app
+  tag: "1.2.3" # pinned
+sidecar: registry.example.com/proxy:1.2.3
+---
+images: [app:1.2.3, 'worker:1.2.3', other:1.2.30]
+1.2.3: key is left alone
+`,
+			want: `image:
+  repository: registry.example.com/app
+  tag: "1.3.0" # pinned
+sidecar: registry.example.com/proxy:1.3.0
+---
+images: [app:1.3.0, 'worker:1.3.0', other:1.2.30]
+1.2.3: key is left alone
+`,
+			wantCount: 4,
+		},
+		{
+			name:    "no match",
+			content: "image:\n  tag: 2.0.0\n",
+			want:    "image:\n  tag: 2.0.0\n",
+		},
+		{
+			name:        "block scalar",
+			content:     "tag: >-\n  1.2.3\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid YAML",
+			content:     "tag: [1.2.3\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count, err := ReplaceTagValues(tt.content, "1.2.3", "1.3.0")
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("ReplaceTagValues() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReplaceTagValues() unexpected error: %v", err)
+			}
+			if got != tt.want || count != tt.wantCount {
+				t.Errorf("ReplaceTagValues() = %q, %d; want %q, %d", got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
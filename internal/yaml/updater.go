@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
-	"time"
+	"unicode"
 
+	"github.com/Gosayram/go-tag-updater/internal/clock"
 	"github.com/Gosayram/go-tag-updater/pkg/errors"
 )
 
@@ -23,34 +25,28 @@ const (
 	// BackupDirPermissions defines the permissions for backup directories
 	BackupDirPermissions = 0o750
 
-	// PathTraversalPattern defines the pattern used to detect path traversal attacks
+	// PathTraversalPattern defines the path element used in path traversal attacks
 	PathTraversalPattern = ".."
-	// WindowsDriveLetterSeparator defines the separator used in Windows drive letters
-	WindowsDriveLetterSeparator = ":"
 	// WindowsPathSeparator defines the Windows path separator
 	WindowsPathSeparator = "\\"
 	// UnixPathSeparator defines the Unix path separator
 	UnixPathSeparator = "/"
 
-	// UnixTempDir defines the Unix temporary directory prefix
-	UnixTempDir = "/tmp/"
-	// UnixVarTempDir defines the Unix variable temporary directory prefix
-	UnixVarTempDir = "/var/tmp/"
-	// WindowsCTempDir defines the Windows C: drive temporary directory prefix
-	WindowsCTempDir = "C:/temp/"
-	// WindowsCTmpDir defines the Windows C: drive tmp directory prefix
-	WindowsCTmpDir = "C:/tmp/"
-	// WindowsDTempDir defines the Windows D: drive temporary directory prefix
-	WindowsDTempDir = "D:/temp/"
-	// WindowsDTmpDir defines the Windows D: drive tmp directory prefix
-	WindowsDTmpDir = "D:/tmp/"
+	// UnixTempDir defines the Unix temporary directory
+	UnixTempDir = "/tmp"
+	// UnixVarTempDir defines the Unix variable temporary directory
+	UnixVarTempDir = "/var/tmp"
+	// WindowsDriveLetterSeparator defines the separator used in Windows drive letters
+	WindowsDriveLetterSeparator = ':'
+	// WindowsDrivePrefixLength defines the length of a drive letter prefix such as "C:"
+	WindowsDrivePrefixLength = 2
+	// WindowsOS is the GOOS value of Windows, where paths compare case-insensitively
+	WindowsOS = "windows"
 
 	// UnixEtcPath defines the Unix /etc directory path
 	UnixEtcPath = "/etc/"
 	// UnixUsrPath defines the Unix /usr directory path
 	UnixUsrPath = "/usr/"
-	// UnixVarPath defines the Unix /var directory path (except /var/tmp)
-	UnixVarPath = "/var/"
 	// UnixBinPath defines the Unix /bin directory path
 	UnixBinPath = "/bin/"
 	// UnixSbinPath defines the Unix /sbin directory path
@@ -59,14 +55,22 @@ const (
 	WindowsSystemPath = "/windows/system32/"
 	// WindowsProgramFilesPath defines the Windows Program Files path
 	WindowsProgramFilesPath = "/program files/"
+	// WindowsProgramFilesX86Path defines the Windows 32-bit Program Files path
+	WindowsProgramFilesX86Path = "/program files (x86)/"
 )
 
+// ErrNoChanges is returned when the file already contains the requested tag value;
+// callers should treat it as a skipped update rather than a failure
+var ErrNoChanges = errors.NewAppError(errors.ErrCodeNoChanges, errors.CategoryFile,
+	"no changes: tag already has the requested value")
+
 // Updater handles YAML file updates with backup and rollback capabilities
 type Updater struct {
 	parser      *Parser
 	backupDir   string
 	keepBackups bool
 	atomicWrite bool
+	retention   BackupRetention
 }
 
 // UpdateRequest contains all information needed for a tag update
@@ -77,6 +81,17 @@ type UpdateRequest struct {
 	CreateBackup  bool
 	ValidateAfter bool
 	DryRun        bool
+
+	// FallbackRaw replaces only the tag line when the document cannot be re-encoded faithfully
+	FallbackRaw bool
+
+	// ResolveAnchors updates a tag aliases refer to, and re-encodes documents with
+	// shared anchors when the output keeps every anchor and alias
+	ResolveAnchors bool
+
+	// DocumentSelector picks the document of a multi-document file to update; without
+	// it the tag path must be found in a single document
+	DocumentSelector *DocumentSelector
 }
 
 // UpdateResult contains the result of an update operation
@@ -85,8 +100,20 @@ type UpdateResult struct {
 	UpdatedContent  string
 	BackupPath      string
 	OriginalContent string
+	TagPath         []string
+	OldTagValue     string
 	ValidationError error
 	ChangesDetected bool
+
+	// RawFallback is set when the tag line was replaced in place; UnsafeConstructs explains why
+	RawFallback      bool
+	UnsafeConstructs []string
+
+	// SharedAnchors lists the anchors holding the updated tag that aliases refer to
+	SharedAnchors []AnchorUse
+
+	// EncodeDiagnostics is set when the encoder failed and a retry recovered
+	EncodeDiagnostics *EncodeDiagnostics
 }
 
 // NewUpdater creates a new YAML updater with default settings
@@ -95,6 +122,7 @@ func NewUpdater() *Updater {
 		parser:      NewParser(),
 		keepBackups: true,
 		atomicWrite: true,
+		retention:   DefaultBackupRetention(),
 	}
 }
 
@@ -105,10 +133,18 @@ func NewUpdaterWithOptions(backupDir string, keepBackups, atomicWrite bool) *Upd
 		backupDir:   backupDir,
 		keepBackups: keepBackups,
 		atomicWrite: atomicWrite,
+		retention:   DefaultBackupRetention(),
 	}
 }
 
-// UpdateTagInFile updates a tag in a YAML file with comprehensive error handling
+// SetBackupRetention sets which backups CleanupOldBackups keeps
+func (u *Updater) SetBackupRetention(retention BackupRetention) {
+	u.retention = retention
+}
+
+// UpdateTagInFile updates a tag in a YAML file with comprehensive error handling.
+// When the tag already has the requested value it returns the result together with
+// ErrNoChanges without creating a backup or writing the file.
 func (u *Updater) UpdateTagInFile(request *UpdateRequest) (*UpdateResult, error) {
 	if request == nil {
 		return nil, errors.NewValidationError("update request cannot be nil")
@@ -122,39 +158,111 @@ func (u *Updater) UpdateTagInFile(request *UpdateRequest) (*UpdateResult, error)
 		return nil, errors.NewValidationError("new tag value cannot be empty")
 	}
 
-	result := &UpdateResult{}
-
 	// Read the original file
 	originalContent, err := u.readFile(request.FilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", request.FilePath, err)
 	}
 
-	result.OriginalContent = originalContent
+	result, err := u.update(originalContent, request)
+	if err != nil {
+		return result, err
+	}
+
+	// Handle dry run
+	if request.DryRun {
+		result.Success = true
+		return result, nil
+	}
+
+	// Create backup if requested
+	if request.CreateBackup && u.keepBackups {
+		var backupErr error
+		result.BackupPath, backupErr = u.createBackup(request.FilePath, originalContent)
+		if backupErr != nil {
+			return nil, fmt.Errorf("failed to create backup: %w", backupErr)
+		}
+	}
+
+	// Write the updated content
+	err = u.writeFile(request.FilePath, result.UpdatedContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write updated file: %w", err)
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// UpdateContent updates a tag in YAML content without any filesystem access, with the
+// same checks as UpdateTagInFile. FilePath, when set, only names the content in error
+// messages; CreateBackup and DryRun do not apply. When the tag already has the
+// requested value it returns the result together with ErrNoChanges.
+func (u *Updater) UpdateContent(content string, request *UpdateRequest) (*UpdateResult, error) {
+	if request == nil {
+		return nil, errors.NewValidationError("update request cannot be nil")
+	}
+
+	if request.NewTagValue == "" {
+		return nil, errors.NewValidationError("new tag value cannot be empty")
+	}
+
+	result, err := u.update(content, request)
+	if err != nil {
+		return result, err
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// update applies the request to the original content and returns the result with the
+// updated content, or ErrNoChanges together with the result when nothing changes
+func (u *Updater) update(originalContent string, request *UpdateRequest) (*UpdateResult, error) {
+	source := request.FilePath
+	if source == "" {
+		source = "content"
+	}
+	result := &UpdateResult{OriginalContent: originalContent}
 
 	// Parse the YAML content
 	parseResult, err := u.parser.ParseContent(originalContent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse YAML file %s: %w", request.FilePath, err)
+		return nil, fmt.Errorf("failed to parse YAML file %s: %w", source, err)
+	}
+	if err := parseResult.SelectDocument(request.DocumentSelector); err != nil {
+		return nil, fmt.Errorf("failed to select document of %s: %w", source, err)
 	}
 
 	// Determine tag path if not provided
 	tagPath := request.TagPath
 	if len(tagPath) == 0 {
-		tagPath, err = u.autoDetectTagPath(parseResult)
+		tagPath, err = DetectTagPath(parseResult)
 		if err != nil {
 			return nil, fmt.Errorf("failed to auto-detect tag path: %w", err)
 		}
 	}
 
+	result.TagPath = tagPath
+
+	// Short-circuit before re-serializing when the tag already has the requested value
+	if currentValue, valueErr := u.parser.GetTagValue(parseResult, tagPath); valueErr == nil {
+		result.OldTagValue = currentValue
+		if currentValue == request.NewTagValue {
+			result.UpdatedContent = originalContent
+			return result, ErrNoChanges
+		}
+	}
+
 	// Update the tag
 	updateOptions := &UpdateOptions{
 		TagPath:         tagPath,
 		NewValue:        request.NewTagValue,
 		CreateIfMissing: false, // For safety, don't create missing tags
+		ResolveAnchors:  request.ResolveAnchors,
 	}
 
-	updatedContent, err := u.parser.UpdateTag(parseResult, updateOptions)
+	updatedContent, err := u.updateContent(parseResult, updateOptions, request.FallbackRaw, result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update tag: %w", err)
 	}
@@ -162,34 +270,68 @@ func (u *Updater) UpdateTagInFile(request *UpdateRequest) (*UpdateResult, error)
 	result.UpdatedContent = updatedContent
 	result.ChangesDetected = originalContent != updatedContent
 
+	// Nothing to validate, back up or write when the content is unchanged
+	if !result.ChangesDetected {
+		return result, ErrNoChanges
+	}
+
 	// Validate the updated content if requested
 	if request.ValidateAfter {
 		result.ValidationError = u.parser.ValidateYAML(updatedContent)
 	}
 
-	// Handle dry run
-	if request.DryRun {
-		result.Success = true
-		return result, nil
-	}
+	return result, nil
+}
 
-	// Create backup if requested
-	if request.CreateBackup && u.keepBackups {
-		var backupErr error
-		result.BackupPath, backupErr = u.createBackup(request.FilePath, originalContent)
-		if backupErr != nil {
-			return nil, fmt.Errorf("failed to create backup: %w", backupErr)
+// updateContent re-encodes the document with the new tag value and checks the output
+// keeps every anchor and alias. Anchors unrelated to the tag never block the update;
+// a tag shared through an anchor is refused unless ResolveAnchors is set. With
+// fallbackRaw documents with anchors and aliases get an in-place line edit instead.
+func (u *Updater) updateContent(
+	parseResult *ParseResult,
+	options *UpdateOptions,
+	fallbackRaw bool,
+	result *UpdateResult,
+) (string, error) {
+	if location, err := u.parser.locateTag(parseResult, options.TagPath); err == nil {
+		result.SharedAnchors = parseResult.SharedAnchors(location)
+	}
+
+	if len(parseResult.UnsafeConstructs) == 0 || !fallbackRaw {
+		content, diagnostics, err := u.parser.updateTag(parseResult, options)
+		result.EncodeDiagnostics = diagnostics
+		if err != nil {
+			return "", err
 		}
+		return content, verifyAnchors(parseResult.Documents, content)
 	}
 
-	// Write the updated content
-	err = u.writeFile(request.FilePath, updatedContent)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write updated file: %w", err)
+	result.UnsafeConstructs = parseResult.UnsafeConstructs
+	result.RawFallback = true
+	return u.parser.UpdateTagRaw(parseResult, options)
+}
+
+// ReadContent reads a file with the path checks of UpdateTagInFile
+func (u *Updater) ReadContent(filePath string) (string, error) {
+	return u.readFile(filePath)
+}
+
+// WriteContent writes content another engine updated, such as the key=value updater,
+// with the path checks, backup and atomic write of UpdateTagInFile. It returns the
+// path of the backup of originalContent, if one was created.
+func (u *Updater) WriteContent(filePath, originalContent, updatedContent string, createBackup bool) (string, error) {
+	var backupPath string
+	if createBackup && u.keepBackups {
+		var err error
+		if backupPath, err = u.createBackup(filePath, originalContent); err != nil {
+			return "", fmt.Errorf("failed to create backup: %w", err)
+		}
 	}
 
-	result.Success = true
-	return result, nil
+	if err := u.writeFile(filePath, updatedContent); err != nil {
+		return "", fmt.Errorf("failed to write updated file: %w", err)
+	}
+	return backupPath, nil
 }
 
 // UpdateTagSimpleInFile provides a simple interface for common tag updates
@@ -251,40 +393,17 @@ func (u *Updater) RollbackFromBackup(filePath, backupPath string) error {
 	return nil
 }
 
-// CleanupOldBackups removes old backup files to save disk space
+// CleanupOldBackups removes the backups of a file the backup retention does not keep,
+// newest first by modification time, from the backup directory or next to the file
 func (u *Updater) CleanupOldBackups(filePath string) error {
-	if u.backupDir == "" {
-		return nil // No backup directory configured
-	}
-
-	// List all backup files for this file
-	baseFileName := filepath.Base(filePath)
-	backupPattern := fmt.Sprintf("%s.*.backup", baseFileName)
-	backupGlob := filepath.Join(u.backupDir, backupPattern)
-
-	matches, err := filepath.Glob(backupGlob)
-	if err != nil {
-		return fmt.Errorf("failed to list backup files: %w", err)
-	}
-
-	// Sort by modification time and keep only recent ones
-	if len(matches) > MaxBackupFiles {
-		// This is a simplified cleanup - in a full implementation,
-		// you would sort by file modification time and remove oldest files
-		for i := MaxBackupFiles; i < len(matches); i++ {
-			err := os.Remove(matches[i])
-			if err != nil {
-				// Log warning but continue
-				continue
-			}
-		}
-	}
-
-	return nil
+	_, err := PruneBackups(u.backupDir, filePath, u.retention, false)
+	return err
 }
 
-// autoDetectTagPath attempts to automatically detect the tag path in YAML
-func (u *Updater) autoDetectTagPath(parseResult *ParseResult) ([]string, error) {
+// DetectTagPath returns the tag path an update uses when none is given: the first
+// field named tag, version or image in that order of preference, otherwise the first
+// tag field defined in place
+func DetectTagPath(parseResult *ParseResult) ([]string, error) {
 	if len(parseResult.TagLocations) == 0 {
 		return nil, errors.NewValidationError("no tag fields found in YAML content")
 	}
@@ -294,20 +413,26 @@ func (u *Updater) autoDetectTagPath(parseResult *ParseResult) ([]string, error)
 
 	for _, preferred := range preferredPaths {
 		for _, location := range parseResult.TagLocations {
-			if len(location.Path) > 0 &&
+			if !location.Inherited && len(location.Path) > 0 &&
 				strings.ToLower(location.Path[len(location.Path)-1]) == preferred {
 				return location.Path, nil
 			}
 		}
 	}
 
-	// If no preferred path found, return the first detected tag
+	// If no preferred path found, return the first tag defined in place
+	for _, location := range parseResult.TagLocations {
+		if !location.Inherited {
+			return location.Path, nil
+		}
+	}
+
 	return parseResult.TagLocations[0].Path, nil
 }
 
 // createBackup creates a backup of the original file
 func (u *Updater) createBackup(filePath, content string) (string, error) {
-	timestamp := time.Now().Format(BackupTimestampFormat)
+	timestamp := clock.Now().Format(BackupTimestampFormat)
 	baseFileName := filepath.Base(filePath)
 
 	var backupPath string
@@ -331,41 +456,36 @@ func (u *Updater) createBackup(filePath, content string) (string, error) {
 	return backupPath, nil
 }
 
-// validateAndCleanFilePath validates that the file path is safe and returns a cleaned version
+// validateAndCleanFilePath validates that the file path is safe and returns a cleaned
+// version using the separators of the current OS
 func (u *Updater) validateAndCleanFilePath(filePath string) (string, error) {
 	if filePath == "" {
 		return "", errors.NewValidationError("file path cannot be empty")
 	}
 
 	// Clean the path to resolve any .. or . elements
-	cleanPath := filepath.Clean(filePath)
+	cleanPath := filepath.Clean(filepath.FromSlash(filePath))
+	volume := filepath.VolumeName(cleanPath)
 
-	// Check for suspicious patterns that could indicate path traversal
-	if strings.Contains(cleanPath, PathTraversalPattern) {
-		return "", errors.NewValidationError("file path contains invalid traversal patterns")
-	}
+	// Security checks treat both separators and drive letters as such on every OS, so
+	// Windows-style paths are rejected consistently even where they are plain file names
+	checkPath := strings.ToLower(stripDriveLetter(slashPath(strings.TrimPrefix(cleanPath, volume))))
 
-	// Normalize path for consistent checking (convert backslashes to forward slashes)
-	normalizedPath := strings.ReplaceAll(cleanPath, WindowsPathSeparator, UnixPathSeparator)
-	lowerPath := strings.ToLower(normalizedPath)
+	// Check for traversal elements that survive cleaning
+	for _, element := range strings.Split(checkPath, UnixPathSeparator) {
+		if element == PathTraversalPattern {
+			return "", errors.NewValidationError("file path contains invalid traversal patterns")
+		}
+	}
 
 	// Check for suspicious system paths
-	if err := u.checkSuspiciousSystemPaths(lowerPath); err != nil {
+	if err := u.checkSuspiciousSystemPaths(checkPath); err != nil {
 		return "", err
 	}
 
-	// Check Windows-specific paths
-	if err := u.checkWindowsSystemPaths(lowerPath); err != nil {
-		return "", err
-	}
-
-	// Check absolute path permissions
-	if filepath.IsAbs(cleanPath) {
-		validatedPath, err := u.validateAbsolutePath(cleanPath, normalizedPath)
-		if err != nil {
-			return "", err
-		}
-		return validatedPath, nil
+	// Absolute, drive-relative (C:file) and UNC paths must stay in temporary directories
+	if filepath.IsAbs(cleanPath) || volume != "" || strings.HasPrefix(cleanPath, string(filepath.Separator)) {
+		return u.validateAbsolutePath(cleanPath)
 	}
 
 	return cleanPath, nil
@@ -380,79 +500,71 @@ func (u *Updater) checkSuspiciousSystemPaths(lowerPath string) error {
 		UnixSbinPath,
 		WindowsSystemPath,
 		WindowsProgramFilesPath,
+		WindowsProgramFilesX86Path,
+	}
+
+	// Relative paths are checked as if they started at the root
+	rootedPath := lowerPath
+	if !strings.HasPrefix(rootedPath, UnixPathSeparator) {
+		rootedPath = UnixPathSeparator + rootedPath
 	}
 
 	for _, suspiciousPath := range suspiciousPaths {
-		suspiciousLower := strings.ToLower(suspiciousPath)
-		// Check if path starts with suspicious directory (absolute or relative)
-		if strings.HasPrefix(lowerPath, suspiciousLower) {
-			// Allow /var/tmp but block other /var paths
-			if suspiciousPath == UnixVarPath && strings.HasPrefix(lowerPath, strings.ToLower(UnixVarTempDir)) {
-				continue
-			}
+		if strings.HasPrefix(rootedPath, suspiciousPath) {
 			return errors.NewValidationError("access to system directories is not allowed")
 		}
-		// Also check relative paths (without leading slash)
-		if suspiciousLower[0] == '/' {
-			relativeSuspicious := suspiciousLower[1:] // Remove leading slash
-			if strings.HasPrefix(lowerPath, relativeSuspicious) {
-				return errors.NewValidationError("access to system directories is not allowed")
-			}
-		}
 	}
 	return nil
 }
 
-// checkWindowsSystemPaths checks for Windows-specific system directory access
-func (u *Updater) checkWindowsSystemPaths(lowerPath string) error {
-	if strings.Contains(lowerPath, ":") { // Windows drive letter path
-		// Block access to Windows system directories with drive letters
-		windowsSystemPaths := []string{
-			":/windows/system32/",
-			":/program files/",
-			":/program files (x86)/",
-		}
-		for _, winPath := range windowsSystemPaths {
-			if strings.Contains(lowerPath, winPath) {
-				return errors.NewValidationError("access to system directories is not allowed")
-			}
+// validateAbsolutePath ensures absolute paths are inside a temporary directory
+func (u *Updater) validateAbsolutePath(cleanPath string) (string, error) {
+	allowedRoots := []string{os.TempDir()}
+	if runtime.GOOS != WindowsOS {
+		allowedRoots = append(allowedRoots, UnixTempDir, UnixVarTempDir)
+	}
+
+	for _, root := range allowedRoots {
+		if isWithinDir(root, cleanPath) {
+			return cleanPath, nil
 		}
 	}
-	return nil
+
+	return "", errors.NewValidationError("absolute file paths outside safe directories are not allowed")
 }
 
-// validateAbsolutePath validates absolute paths and ensures they're in safe directories
-func (u *Updater) validateAbsolutePath(cleanPath, normalizedPath string) (string, error) {
-	// Only allow absolute paths in specific safe directories
-	// For Unix-like systems: /tmp/, /var/tmp/
-	// For Windows: temp directories or explicitly allowed paths
-	allowedPrefixes := []string{
-		UnixTempDir,
-		UnixVarTempDir,
-	}
-
-	// Add Windows temp directory patterns
-	if strings.Contains(cleanPath, WindowsDriveLetterSeparator) { // Windows drive letter
-		allowedPrefixes = append(allowedPrefixes,
-			WindowsCTempDir, WindowsCTmpDir,
-			WindowsDTempDir, WindowsDTmpDir,
-		)
-		cleanPath = normalizedPath
-	}
-
-	allowed := false
-	for _, prefix := range allowedPrefixes {
-		if strings.HasPrefix(strings.ToLower(cleanPath), strings.ToLower(prefix)) {
-			allowed = true
-			break
-		}
+// isWithinDir reports whether path is located inside dir
+func isWithinDir(dir, path string) bool {
+	if runtime.GOOS == WindowsOS {
+		dir = strings.ToLower(dir)
+		path = strings.ToLower(path)
 	}
 
-	if !allowed {
-		return "", errors.NewValidationError("absolute file paths outside safe directories are not allowed")
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil || rel == "." || filepath.IsAbs(rel) {
+		return false
 	}
 
-	return cleanPath, nil
+	return rel != PathTraversalPattern && !strings.HasPrefix(rel, PathTraversalPattern+string(filepath.Separator))
+}
+
+// stripDriveLetter removes a leading Windows drive letter such as "C:" from a slash path
+func stripDriveLetter(path string) string {
+	if len(path) < WindowsDrivePrefixLength || path[1] != WindowsDriveLetterSeparator {
+		return path
+	}
+
+	letter := unicode.ToLower(rune(path[0]))
+	if letter < 'a' || letter > 'z' {
+		return path
+	}
+
+	return path[WindowsDrivePrefixLength:]
+}
+
+// slashPath converts both Windows and Unix separators to forward slashes
+func slashPath(path string) string {
+	return strings.ReplaceAll(filepath.ToSlash(path), WindowsPathSeparator, UnixPathSeparator)
 }
 
 // readFile reads content from a file with error handling and path validation
@@ -1,8 +1,10 @@
 package yaml
 
 import (
+	stderrors "errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -72,12 +74,6 @@ func TestUpdater_validateAndCleanFilePath(t *testing.T) {
 			expectError: false,
 			description: "should accept valid nested relative paths",
 		},
-		{
-			name:        "valid tmp path",
-			filePath:    "/tmp/test.yaml",
-			expectError: false,
-			description: "should accept /tmp paths",
-		},
 	}
 
 	for _, tt := range tests {
@@ -262,6 +258,107 @@ func TestUpdater_PreviewUpdateDryRun(t *testing.T) {
 	}
 }
 
+func TestUpdater_UpdateTagInFileNoChanges(t *testing.T) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	testFile := filepath.Join(workingDir, TestValidPath)
+	if err := os.WriteFile(testFile, []byte(TestYAMLContent), DefaultFilePermissions); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer func() {
+		if removeErr := os.Remove(testFile); removeErr != nil {
+			t.Logf("Failed to clean up test file: %v", removeErr)
+		}
+	}()
+
+	backupDir := t.TempDir()
+	updater := NewUpdaterWithOptions(backupDir, true, true)
+
+	result, err := updater.UpdateTagInFile(&UpdateRequest{
+		FilePath:     TestValidPath,
+		NewTagValue:  TestOldTag,
+		CreateBackup: true,
+	})
+	if !stderrors.Is(err, ErrNoChanges) {
+		t.Fatalf("UpdateTagInFile() error = %v, want ErrNoChanges", err)
+	}
+	if result == nil || result.ChangesDetected || result.Success {
+		t.Errorf("UpdateTagInFile() result = %+v, want unchanged and not successful", result)
+	}
+	if result != nil && result.BackupPath != "" {
+		t.Errorf("UpdateTagInFile() should not create a backup, got %q", result.BackupPath)
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to read backup directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("UpdateTagInFile() should not write backups for unchanged content, found %d", len(entries))
+	}
+}
+
+func TestUpdater_UpdateContent(t *testing.T) {
+	// The file does not exist: UpdateContent never touches the filesystem
+	const missingFile = "/nonexistent/values.yaml"
+
+	tests := []struct {
+		name        string
+		content     string
+		request     *UpdateRequest
+		wantContent string
+		wantErr     bool
+		wantNoChg   bool
+	}{
+		{
+			name:        "updates the detected tag",
+			content:     TestYAMLContent,
+			request:     &UpdateRequest{FilePath: missingFile, NewTagValue: TestNewTag},
+			wantContent: "tag: " + TestNewTag,
+		},
+		{
+			name:        "updates without a file name",
+			content:     TestYAMLContent,
+			request:     &UpdateRequest{NewTagValue: TestNewTag, TagPath: []string{"image", "tag"}},
+			wantContent: "tag: " + TestNewTag,
+		},
+		{
+			name:      "tag already set",
+			content:   TestYAMLContent,
+			request:   &UpdateRequest{NewTagValue: TestOldTag},
+			wantNoChg: true,
+		},
+		{name: "nil request", content: TestYAMLContent, wantErr: true},
+		{name: "empty tag", content: TestYAMLContent, request: &UpdateRequest{}, wantErr: true},
+		{name: "invalid YAML", content: "image: [", request: &UpdateRequest{NewTagValue: TestNewTag}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewUpdater().UpdateContent(tt.content, tt.request)
+			if tt.wantNoChg {
+				if !stderrors.Is(err, ErrNoChanges) {
+					t.Fatalf("UpdateContent() error = %v, want ErrNoChanges", err)
+				}
+				return
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !result.Success || !strings.Contains(result.UpdatedContent, tt.wantContent) || result.OldTagValue != TestOldTag {
+				t.Errorf("UpdateContent() = %+v, want content with %q", result, tt.wantContent)
+			}
+		})
+	}
+}
+
 func TestSecurityConstants(t *testing.T) {
 	// Test that security constants are properly defined
 	if DefaultFilePermissions == 0 {
@@ -333,10 +430,9 @@ func TestPathSecurityAllowedPaths(t *testing.T) {
 		"./config.yaml",
 		"configs/app.yaml",
 		"test/data/sample.yaml",
-		"/tmp/test.yaml",
-		"/var/tmp/backup.yaml",
-		"C:/temp/config.yaml",
-		"D:/tmp/test.yaml",
+		"configs\\app.yaml",
+		"release..notes.yaml",
+		filepath.Join(os.TempDir(), "config.yaml"),
 	}
 
 	for _, allowedPath := range allowedPaths {
@@ -370,3 +466,34 @@ func BenchmarkUpdater_fileExists(b *testing.B) {
 		updater.fileExists(TestValidPath)
 	}
 }
+
+func TestDetectTagPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{name: "prefers tag", content: "version: 1.0.0\nimage:\n  tag: v1\n", want: []string{"image", "tag"}},
+		{name: "falls back to version", content: "app:\n  version: 1.0.0\n", want: []string{"app", "version"}},
+		{name: "no tag fields", content: "name: app\n", wantErr: true},
+	}
+
+	parser := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseResult, err := parser.ParseContent(tt.content)
+			if err != nil {
+				t.Fatalf("ParseContent() unexpected error: %v", err)
+			}
+
+			got, err := DetectTagPath(parseResult)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectTagPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectTagPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
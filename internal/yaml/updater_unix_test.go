@@ -0,0 +1,47 @@
+//go:build !windows
+
+package yaml
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdater_validateAndCleanFilePath_Unix(t *testing.T) {
+	updater := NewUpdater()
+
+	tests := []struct {
+		name        string
+		filePath    string
+		expected    string
+		expectError bool
+	}{
+		{name: "tmp path", filePath: "/tmp/test.yaml", expected: "/tmp/test.yaml"},
+		{name: "var tmp path", filePath: "/var/tmp/backup.yaml", expected: "/var/tmp/backup.yaml"},
+		{name: "redundant separators", filePath: "configs//app/./deployment.yaml", expected: "configs/app/deployment.yaml"},
+		{name: "drive-like relative directory", filePath: "C:/temp/config.yaml", expected: "C:/temp/config.yaml"},
+		{name: "home directory", filePath: "/home/user/config.yaml", expectError: true},
+		{name: "escape from tmp", filePath: "/tmp/../etc/passwd", expectError: true},
+		{name: "tmp prefix lookalike", filePath: "/tmpfoo/config.yaml", expectError: true},
+		{name: "backslash traversal", filePath: "configs\\..\\..\\secret.yaml", expectError: true},
+		{name: "windows system path", filePath: "D:\\Windows\\System32\\config\\SAM", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := updater.validateAndCleanFilePath(tt.filePath)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("validateAndCleanFilePath(%q) expected error but got %q", tt.filePath, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAndCleanFilePath(%q) unexpected error: %v", tt.filePath, err)
+			}
+			if result != filepath.FromSlash(tt.expected) {
+				t.Errorf("validateAndCleanFilePath(%q) = %q, want %q", tt.filePath, result, tt.expected)
+			}
+		})
+	}
+}
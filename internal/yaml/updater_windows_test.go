@@ -0,0 +1,53 @@
+package yaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdater_validateAndCleanFilePath_Windows(t *testing.T) {
+	updater := NewUpdater()
+	tempFile := filepath.Join(os.TempDir(), "config.yaml")
+
+	tests := []struct {
+		name        string
+		filePath    string
+		expected    string
+		expectError bool
+	}{
+		{name: "backslash relative path", filePath: `configs\app\deployment.yaml`, expected: `configs\app\deployment.yaml`},
+		{name: "forward slash relative path", filePath: "configs/app/deployment.yaml", expected: `configs\app\deployment.yaml`},
+		{name: "temp directory", filePath: tempFile, expected: tempFile},
+		{name: "temp directory different case", filePath: strings.ToUpper(tempFile), expected: strings.ToUpper(tempFile)},
+		{name: "temp directory forward slashes", filePath: filepath.ToSlash(tempFile), expected: tempFile},
+		{name: "drive root outside temp", filePath: `C:\temp\config.yaml`, expectError: !isWithinDir(os.TempDir(), `C:\temp\config.yaml`)},
+		{name: "drive-relative path", filePath: `C:config.yaml`, expectError: true},
+		{name: "rooted path without drive", filePath: `\configs\app.yaml`, expectError: true},
+		{name: "UNC path", filePath: `\\server\share\config.yaml`, expectError: true},
+		{name: "UNC forward slashes", filePath: "//server/share/config.yaml", expectError: true},
+		{name: "system directory", filePath: `C:\Windows\System32\drivers\etc\hosts`, expectError: true},
+		{name: "program files x86", filePath: `D:\Program Files (x86)\app\config.yaml`, expectError: true},
+		{name: "backslash traversal", filePath: `configs\..\..\secret.yaml`, expectError: true},
+		{name: "escape from temp", filePath: filepath.Join(os.TempDir(), "..", "config.yaml"), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := updater.validateAndCleanFilePath(tt.filePath)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("validateAndCleanFilePath(%q) expected error but got %q", tt.filePath, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAndCleanFilePath(%q) unexpected error: %v", tt.filePath, err)
+			}
+			if tt.expected != "" && result != tt.expected {
+				t.Errorf("validateAndCleanFilePath(%q) = %q, want %q", tt.filePath, result, tt.expected)
+			}
+		})
+	}
+}
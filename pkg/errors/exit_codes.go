@@ -0,0 +1,101 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net"
+	"strings"
+)
+
+// Process exit codes by failure type, so CI pipelines can branch on the cause
+const (
+	// ExitCodeSuccess indicates successful program execution
+	ExitCodeSuccess = 0
+	// ExitCodeError indicates a failure without a more specific exit code
+	ExitCodeError = 1
+	// ExitCodeValidation indicates invalid input, configuration, YAML, or a policy refusal
+	ExitCodeValidation = 2
+	// ExitCodeAuth indicates an authentication or authorization failure
+	ExitCodeAuth = 3
+	// ExitCodeNotFound indicates a missing project, file, branch, or merge request
+	ExitCodeNotFound = 4
+	// ExitCodeConflict indicates a merge conflict or a conflicting resource
+	ExitCodeConflict = 5
+	// ExitCodeNetwork indicates a network connectivity failure
+	ExitCodeNetwork = 6
+	// ExitCodePipeline indicates the merge request pipeline failed or timed out
+	ExitCodePipeline = 7
+)
+
+// API error message fragments produced by the GitLab client for HTTP statuses
+const (
+	apiStatusUnauthorized = ": 401"
+	apiStatusForbidden    = ": 403"
+	apiStatusNotFound     = "404 Not Found"
+	apiStatusConflict     = ": 409"
+)
+
+// exitCodesByErrorCode maps application error codes to process exit codes
+var exitCodesByErrorCode = map[int]int{
+	ErrCodeInvalidProject:  ExitCodeNotFound,
+	ErrCodeFileNotFound:    ExitCodeNotFound,
+	ErrCodeInvalidYAML:     ExitCodeValidation,
+	ErrCodeMergeConflict:   ExitCodeConflict,
+	ErrCodeValidation:      ExitCodeValidation,
+	ErrCodeConfiguration:   ExitCodeValidation,
+	ErrCodeNetworkError:    ExitCodeNetwork,
+	ErrCodeAuthError:       ExitCodeAuth,
+	ErrCodePolicyViolation: ExitCodeValidation,
+	ErrCodePipelineFailed:  ExitCodePipeline,
+}
+
+// ExitCode returns the process exit code for an error. The first AppError in the
+// wrap chain decides; GitLab API errors and plain client errors are classified by
+// their HTTP status.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var appErr *AppError
+	if stderrors.As(err, &appErr) {
+		if code, ok := exitCodesByErrorCode[appErr.Code]; ok {
+			return code
+		}
+		if appErr.Code == ErrCodeAPIError {
+			return apiExitCode(appErr.Message)
+		}
+		return ExitCodeError
+	}
+
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		return ExitCodeNetwork
+	}
+
+	return apiExitCode(err.Error())
+}
+
+// apiExitCode classifies a GitLab API error message by its HTTP status
+func apiExitCode(message string) int {
+	switch {
+	case strings.Contains(message, apiStatusUnauthorized), strings.Contains(message, apiStatusForbidden):
+		return ExitCodeAuth
+	case strings.Contains(message, apiStatusNotFound):
+		return ExitCodeNotFound
+	case strings.Contains(message, apiStatusConflict):
+		return ExitCodeConflict
+	default:
+		return ExitCodeError
+	}
+}
+
+// ExitCodeHelp describes the exit codes for command help output
+const ExitCodeHelp = `Exit codes:
+  0  success
+  1  unclassified failure
+  2  validation, configuration, YAML, or policy error
+  3  authentication or authorization failure
+  4  project, file, or other resource not found
+  5  merge conflict or conflicting resource
+  6  network failure
+  7  merge request pipeline failed or timed out`
@@ -0,0 +1,36 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil error", err: nil, want: ExitCodeSuccess},
+		{name: "validation error", err: NewValidationError("bad tag"), want: ExitCodeValidation},
+		{name: "policy violation", err: NewPolicyError("denied"), want: ExitCodeValidation},
+		{name: "wrapped file error", err: fmt.Errorf("update: %w", NewFileNotFoundError("a.yaml")), want: ExitCodeNotFound},
+		{name: "merge conflict", err: NewMergeConflictError("conflict"), want: ExitCodeConflict},
+		{name: "api unauthorized", err: NewAPIError("GET https://gitlab/api/v4/user: 401 {message: 401 Unauthorized}"),
+			want: ExitCodeAuth},
+		{name: "api not found", err: NewAPIError("get file: 404 Not Found"), want: ExitCodeNotFound},
+		{name: "api server error", err: NewAPIError("GET https://gitlab/api/v4/user: 500"), want: ExitCodeError},
+		{name: "network error", err: fmt.Errorf("dial: %w", &net.DNSError{Err: "no such host"}), want: ExitCodeNetwork},
+		{name: "plain error", err: stderrors.New("unexpected"), want: ExitCodeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
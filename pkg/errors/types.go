@@ -26,6 +26,12 @@ const (
 	ErrCodeNetworkError = 1009
 	// ErrCodeAuthError indicates authentication or authorization failure
 	ErrCodeAuthError = 1010
+	// ErrCodePolicyViolation indicates a change refused by the configured policy
+	ErrCodePolicyViolation = 1011
+	// ErrCodePipelineFailed indicates a merge request pipeline failed or timed out
+	ErrCodePipelineFailed = 1012
+	// ErrCodeNoChanges indicates an update that would not change the file
+	ErrCodeNoChanges = 1013
 
 	// MaxErrorMessageLength defines the maximum length for error messages
 	MaxErrorMessageLength = 500
@@ -42,6 +48,8 @@ const (
 	CategoryGit        = "git"
 	CategoryValidation = "validation"
 	CategoryNetwork    = "network"
+	CategoryPolicy     = "policy"
+	CategoryPipeline   = "pipeline"
 )
 
 // AppError represents a structured application error
@@ -125,6 +133,13 @@ func NewFileNotFoundError(filePath string) *AppError {
 	return NewAppErrorWithContext(ErrCodeFileNotFound, CategoryFile, "file not found", filePath)
 }
 
+// NewFileRenamedError creates a file not found error for a file that was renamed,
+// naming its new path and the commit that moved it
+func NewFileRenamedError(oldPath, newPath, commitID string) *AppError {
+	return NewAppError(ErrCodeFileNotFound, CategoryFile,
+		fmt.Sprintf("file %s was renamed to %s in commit %s", oldPath, newPath, commitID))
+}
+
 // NewInvalidYAMLError creates a new invalid YAML format error
 func NewInvalidYAMLError(message string) *AppError {
 	return NewAppError(ErrCodeInvalidYAML, CategoryFile, message)
@@ -185,6 +200,21 @@ func NewNetworkErrorWithCause(message string, cause error) *AppError {
 	return NewAppErrorWithCause(ErrCodeNetworkError, CategoryNetwork, message, cause)
 }
 
+// NewPolicyError creates a new policy violation error
+func NewPolicyError(message string) *AppError {
+	return NewAppError(ErrCodePolicyViolation, CategoryPolicy, message)
+}
+
+// NewPolicyErrorWithContext creates a new policy violation error with additional context
+func NewPolicyErrorWithContext(message, context string) *AppError {
+	return NewAppErrorWithContext(ErrCodePolicyViolation, CategoryPolicy, message, context)
+}
+
+// NewPipelineError creates a new pipeline error
+func NewPipelineError(message string) *AppError {
+	return NewAppError(ErrCodePipelineFailed, CategoryPipeline, message)
+}
+
 // Helper functions
 
 // IsAppError checks if an error is an AppError
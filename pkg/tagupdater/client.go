@@ -0,0 +1,162 @@
+package tagupdater
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/policy"
+	"github.com/Gosayram/go-tag-updater/internal/yaml"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// ClientConfig holds the GitLab connection settings shared by the updates of a Client
+type ClientConfig struct {
+	// BaseURL is the GitLab instance, https://gitlab.com when empty
+	BaseURL string
+	// Token authenticates the API requests
+	Token string
+	// Debug enables debug logging
+	Debug bool
+}
+
+// UpdateTagRequest describes one tag update
+type UpdateTagRequest struct {
+	// ProjectID is the numeric ID or full path of the project, such as "group/project"
+	ProjectID string
+	// FilePath is the path of the YAML file in the repository
+	FilePath string
+	// NewTag is the tag to set
+	NewTag string
+
+	// YAMLPath selects the tag field, such as "image.tag"; auto-detected when empty
+	YAMLPath string
+	// DocSelector picks the document of a multi-document file, by index or by fields
+	// such as "kind=Deployment,name=api"
+	DocSelector string
+
+	// TargetBranch is the branch the merge request targets, the default branch when empty
+	TargetBranch string
+	// BranchName is the update branch, generated when empty
+	BranchName string
+
+	// AutoMerge merges the merge request once it can be merged
+	AutoMerge bool
+	// WaitPipeline waits for the merge request pipeline, up to PipelineTimeout when set
+	WaitPipeline    bool
+	PipelineTimeout time.Duration
+	// DryRun computes the update without changing anything in GitLab
+	DryRun bool
+}
+
+// UpdateTagResponse contains the outcome of a tag update
+type UpdateTagResponse struct {
+	// Skipped is set when the file already uses the new tag
+	Skipped bool
+	// FileUpdated is set when the update was committed
+	FileUpdated bool
+	Message     string
+	BranchName  string
+
+	// MergeRequestIID and MergeRequestURL identify the merge request, zero and empty
+	// when none was opened
+	MergeRequestIID int
+	MergeRequestURL string
+	CommitURL       string
+
+	// PipelineStatus is the final status of the pipeline when it was waited for
+	PipelineStatus string
+}
+
+// Client runs tag updates against one GitLab instance
+type Client struct {
+	config ClientConfig
+	opts   []Option
+}
+
+// NewClient creates a Client; the options apply to every update it runs
+func NewClient(cfg ClientConfig, opts ...Option) (*Client, error) {
+	if cfg.Token == "" {
+		return nil, errors.NewValidationError("GitLab token cannot be empty")
+	}
+	return &Client{config: cfg, opts: opts}, nil
+}
+
+// UpdateTag updates the tag in a file through a merge request
+func (c *Client) UpdateTag(ctx context.Context, req *UpdateTagRequest) (*UpdateTagResponse, error) {
+	if req == nil {
+		return nil, errors.NewValidationError("update tag request cannot be nil")
+	}
+
+	updater, err := New(&Config{
+		ProjectID:       req.ProjectID,
+		FilePath:        req.FilePath,
+		NewTag:          req.NewTag,
+		YAMLPath:        req.YAMLPath,
+		DocSelector:     req.DocSelector,
+		GitLabToken:     c.config.Token,
+		GitLabURL:       c.config.BaseURL,
+		BranchName:      req.BranchName,
+		TargetBranch:    req.TargetBranch,
+		AutoMerge:       req.AutoMerge,
+		WaitPipeline:    req.WaitPipeline,
+		PipelineTimeout: req.PipelineTimeout,
+		DryRun:          req.DryRun,
+		Debug:           c.config.Debug,
+	}, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := updater.Update(ctx)
+	if result == nil {
+		return nil, err
+	}
+	return newUpdateTagResponse(result), err
+}
+
+// newUpdateTagResponse copies the stable part of a workflow result
+func newUpdateTagResponse(result *Result) *UpdateTagResponse {
+	resp := &UpdateTagResponse{
+		Skipped:     result.Skipped,
+		FileUpdated: result.FileUpdated,
+		Message:     result.Message,
+		BranchName:  result.BranchName,
+		CommitURL:   result.CommitURL,
+	}
+	if result.MergeRequest != nil {
+		resp.MergeRequestIID = result.MergeRequest.IID
+		resp.MergeRequestURL = result.MergeRequest.WebURL
+	}
+	if result.Pipeline != nil {
+		resp.PipelineStatus = result.Pipeline.Status
+	}
+	return resp
+}
+
+// ContentUpdate contains the outcome of updating YAML held in memory
+type ContentUpdate struct {
+	// Content is the updated YAML, the original content when nothing changed
+	Content string
+	// OldTag is the tag found before the update
+	OldTag string
+	// Changed is false when the content already uses the new tag
+	Changed bool
+}
+
+// UpdateContent sets the tag at yamlPath, such as "image.tag", in YAML content without
+// GitLab or the filesystem. The tag field is auto-detected when yamlPath is empty.
+func UpdateContent(content, yamlPath, newTag string) (*ContentUpdate, error) {
+	result, err := yaml.NewUpdater().UpdateContent(content, &yaml.UpdateRequest{
+		NewTagValue:   newTag,
+		TagPath:       policy.SplitYAMLPath(yamlPath),
+		ValidateAfter: true,
+	})
+	if stderrors.Is(err, yaml.ErrNoChanges) {
+		return &ContentUpdate{Content: content, OldTag: result.OldTagValue}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ContentUpdate{Content: result.UpdatedContent, OldTag: result.OldTagValue, Changed: true}, nil
+}
@@ -0,0 +1,79 @@
+package tagupdater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+func TestClient_UpdateTag(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, TestYAMLContent)
+
+	if _, err := NewClient(ClientConfig{BaseURL: server.URL()}); err == nil {
+		t.Error("NewClient() expected an error without a token")
+	}
+	client, err := NewClient(ClientConfig{BaseURL: server.URL(), Token: "test-token"})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	if _, err := client.UpdateTag(context.Background(), nil); err == nil {
+		t.Error("UpdateTag(nil) expected an error")
+	}
+
+	resp, err := client.UpdateTag(context.Background(), &UpdateTagRequest{
+		ProjectID:    TestProjectID,
+		FilePath:     TestFilePath,
+		NewTag:       "v2.0.0",
+		TargetBranch: TestTargetBranch,
+		BranchName:   "update-tag",
+	})
+	if err != nil {
+		t.Fatalf("UpdateTag() unexpected error: %v", err)
+	}
+	if !resp.FileUpdated || resp.MergeRequestIID == 0 || resp.BranchName != "update-tag" {
+		t.Errorf("UpdateTag() = %+v, want an updated file and a merge request", resp)
+	}
+	if len(server.MergeRequests(projectID)) != 1 {
+		t.Errorf("expected one merge request, got %d", len(server.MergeRequests(projectID)))
+	}
+}
+
+func TestUpdateContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		yamlPath    string
+		newTag      string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{name: "explicit path", yamlPath: "image.tag", newTag: "v2.0.0", wantChanged: true},
+		{name: "detected path", newTag: "v2.0.0", wantChanged: true},
+		{name: "same tag", yamlPath: "image.tag", newTag: "v1.0.0"},
+		{name: "missing path", yamlPath: "missing.tag", newTag: "v2.0.0", wantErr: true},
+		{name: "empty tag", yamlPath: "image.tag", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := UpdateContent(TestYAMLContent, tt.yamlPath, tt.newTag)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("UpdateContent() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateContent() unexpected error: %v", err)
+			}
+			if update.Changed != tt.wantChanged || update.OldTag != "v1.0.0" {
+				t.Errorf("UpdateContent() = %+v, want changed %v from v1.0.0", update, tt.wantChanged)
+			}
+			if want := "image:\n  tag: " + tt.newTag + "\n"; update.Content != want {
+				t.Errorf("UpdateContent() content = %q, want %q", update.Content, want)
+			}
+		})
+	}
+}
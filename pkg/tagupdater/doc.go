@@ -0,0 +1,13 @@
+// Package tagupdater runs go-tag-updater tag updates from other Go programs, with
+// hooks that let the embedding application attach its own metrics and tracing.
+//
+// Client and its UpdateTagRequest and UpdateTagResponse types are the stable API:
+// they follow semantic versioning, so within a major version exported names are
+// never removed or changed and new fields are only added with a zero value that
+// keeps the previous behavior. UpdateContent updates YAML held in memory without
+// GitLab or the filesystem.
+//
+// Updater, Config and Result expose every setting and outcome of the CLI. They are
+// covered by the same guarantee, except that their fields grow with the CLI in
+// minor releases.
+package tagupdater
@@ -0,0 +1,185 @@
+package tagupdater
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Gosayram/go-tag-updater/internal/config"
+	gitlabapi "github.com/Gosayram/go-tag-updater/internal/gitlab"
+	"github.com/Gosayram/go-tag-updater/internal/logger"
+	"github.com/Gosayram/go-tag-updater/internal/workflow"
+	"github.com/Gosayram/go-tag-updater/pkg/errors"
+)
+
+// Config holds the settings of a tag update, the same ones the CLI accepts as flags.
+// It follows the CLI, so fields may be added in any minor release; prefer Client
+// and UpdateTagRequest for the stable subset.
+type Config = config.CLIConfig
+
+// Result contains the outcome of a tag update
+type Result = workflow.SimpleUpdateResult
+
+// Phase names a step of the tag update workflow
+type Phase = workflow.Phase
+
+// Workflow phases reported to phase hooks, in the order they run
+const (
+	PhaseInitialize   = workflow.PhaseInitialize
+	PhaseValidate     = workflow.PhaseValidate
+	PhaseReuse        = workflow.PhaseReuse
+	PhaseChecks       = workflow.PhaseChecks
+	PhaseBranch       = workflow.PhaseBranch
+	PhaseUpdate       = workflow.PhaseUpdate
+	PhaseMergeability = workflow.PhaseMergeability
+	PhasePipeline     = workflow.PhasePipeline
+)
+
+// PhaseHook is notified when a workflow phase starts and ends; phases that do not
+// apply to a run, such as the pipeline wait without WaitPipeline, are not reported
+type PhaseHook = workflow.PhaseHook
+
+// APIRequest describes one HTTP request attempt sent to the GitLab API
+type APIRequest struct {
+	Method     string
+	Path       string
+	StatusCode int // 0 when the request failed without a response
+	Duration   time.Duration
+	Err        error
+}
+
+// APIRequestHook is notified after every GitLab API request attempt, retries included;
+// ctx is the context of the request
+type APIRequestHook interface {
+	OnAPIRequest(ctx context.Context, req *APIRequest)
+}
+
+// Option configures an Updater
+type Option func(*Updater)
+
+// WithPhaseHook registers a hook notified of every workflow phase
+func WithPhaseHook(hook PhaseHook) Option {
+	return func(u *Updater) {
+		u.phaseHooks = append(u.phaseHooks, hook)
+	}
+}
+
+// WithAPIRequestHook registers a hook notified of every GitLab API request
+func WithAPIRequestHook(hook APIRequestHook) Option {
+	return func(u *Updater) {
+		u.apiHooks = append(u.apiHooks, hook)
+	}
+}
+
+// WithTransport sets the HTTP transport GitLab API requests are sent through
+func WithTransport(transport http.RoundTripper) Option {
+	return func(u *Updater) {
+		u.transport = transport
+	}
+}
+
+// Updater runs tag updates for an embedding application
+type Updater struct {
+	config     *Config
+	phaseHooks []PhaseHook
+	apiHooks   []APIRequestHook
+	transport  http.RoundTripper
+}
+
+// New creates an Updater for the given configuration
+func New(cfg *Config, opts ...Option) (*Updater, error) {
+	if cfg == nil {
+		return nil, errors.NewValidationError("config cannot be nil")
+	}
+
+	u := &Updater{config: cfg}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u, nil
+}
+
+// Update runs the tag update workflow once
+func (u *Updater) Update(ctx context.Context) (*Result, error) {
+	if u.config.ProjectID == "" || u.config.FilePath == "" || u.config.NewTag == "" {
+		return nil, errors.NewValidationError("project ID, file path and new tag are required")
+	}
+	if u.config.GitLabToken == "" {
+		return nil, errors.NewValidationError("GitLab token cannot be empty")
+	}
+
+	// The config is left as given, so every Update transforms the tag once
+	cfg := *u.config
+	if err := cfg.SetNewTag(u.config.NewTag); err != nil {
+		return nil, err
+	}
+
+	logger.RegisterSecret(cfg.GitLabToken)
+	logger.RegisterSecret(cfg.ApproverToken)
+	logger.RegisterSecret(cfg.NotifyEmailPassword)
+	updater, err := workflow.NewSimpleTagUpdater(&cfg, logger.New(cfg.Debug))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range u.phaseHooks {
+		updater.AddPhaseHook(hook)
+	}
+	transport, err := u.requestTransport()
+	if err != nil {
+		return nil, err
+	}
+	updater.SetTransport(transport)
+
+	if err := updater.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	return updater.Execute(ctx)
+}
+
+// requestTransport returns the transport that reports requests to the API request
+// hooks. Without a transport of their own, requests use the TLS settings of the config.
+func (u *Updater) requestTransport() (http.RoundTripper, error) {
+	if len(u.apiHooks) == 0 {
+		return u.transport, nil
+	}
+
+	next := u.transport
+	if next == nil {
+		var err error
+		if next, err = gitlabapi.NewTransport(workflow.TransportOptions(u.config)); err != nil {
+			return nil, err
+		}
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &hookTransport{next: next, hooks: u.apiHooks}, nil
+}
+
+// hookTransport reports each round trip made through it to the API request hooks
+type hookTransport struct {
+	next  http.RoundTripper
+	hooks []APIRequestHook
+}
+
+// RoundTrip performs the request and notifies the hooks of its outcome
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	info := &APIRequest{
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if resp != nil {
+		info.StatusCode = resp.StatusCode
+	}
+
+	for _, hook := range t.hooks {
+		hook.OnAPIRequest(req.Context(), info)
+	}
+	return resp, err
+}
@@ -0,0 +1,135 @@
+package tagupdater
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Gosayram/go-tag-updater/internal/gitlab/gitlabtest"
+)
+
+const (
+	TestProjectID    = "group/project"
+	TestFilePath     = "values.yaml"
+	TestTargetBranch = "main"
+	TestYAMLContent  = "image:\n  tag: v1.0.0\n"
+)
+
+// recordingHooks records the phases and API requests it is notified of
+type recordingHooks struct {
+	mu       sync.Mutex
+	started  []Phase
+	ended    []Phase
+	errs     []error
+	requests []APIRequest
+}
+
+func (h *recordingHooks) OnPhaseStart(_ context.Context, phase Phase) {
+	h.started = append(h.started, phase)
+}
+
+func (h *recordingHooks) OnPhaseEnd(_ context.Context, phase Phase, err error) {
+	h.ended = append(h.ended, phase)
+	if err != nil {
+		h.errs = append(h.errs, err)
+	}
+}
+
+func (h *recordingHooks) OnAPIRequest(_ context.Context, req *APIRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests = append(h.requests, *req)
+}
+
+func TestUpdater_Hooks(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, TestYAMLContent)
+
+	cfg := &Config{
+		ProjectID:    TestProjectID,
+		GitLabToken:  "test-token",
+		GitLabURL:    server.URL(),
+		FilePath:     TestFilePath,
+		NewTag:       "v2.0.0",
+		TargetBranch: TestTargetBranch,
+		BranchName:   "update-tag",
+	}
+
+	hooks := &recordingHooks{}
+	updater, err := New(cfg, WithPhaseHook(hooks), WithAPIRequestHook(hooks))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	result, err := updater.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	if !result.Success || result.MergeRequest == nil {
+		t.Fatalf("Update() = %+v, want a merge request", result)
+	}
+
+	wantPhases := []Phase{PhaseInitialize, PhaseValidate, PhaseChecks, PhaseBranch, PhaseUpdate}
+	if !reflect.DeepEqual(hooks.started, wantPhases) || !reflect.DeepEqual(hooks.ended, wantPhases) {
+		t.Errorf("phases started %v and ended %v, want %v", hooks.started, hooks.ended, wantPhases)
+	}
+	if len(hooks.errs) != 0 {
+		t.Errorf("phases ended with errors %v", hooks.errs)
+	}
+
+	if len(hooks.requests) == 0 {
+		t.Fatal("expected API requests to be reported")
+	}
+	for _, req := range hooks.requests {
+		if req.Method == "" || req.Path == "" || req.StatusCode == 0 {
+			t.Errorf("incomplete API request %+v", req)
+		}
+	}
+}
+
+func TestUpdater_TagTransforms(t *testing.T) {
+	server := gitlabtest.NewServer(t)
+	projectID := server.AddProject(TestProjectID)
+	server.SetFile(projectID, TestTargetBranch, TestFilePath, TestYAMLContent)
+
+	cfg := &Config{
+		ProjectID:     TestProjectID,
+		GitLabToken:   "test-token",
+		GitLabURL:     server.URL(),
+		FilePath:      TestFilePath,
+		NewTag:        "2.0.0",
+		TagTransforms: []string{"add-v"},
+		TargetBranch:  TestTargetBranch,
+		BranchName:    "update-tag",
+	}
+	updater, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if _, err := updater.Update(context.Background()); err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	if content, _ := server.File(projectID, "update-tag", TestFilePath); content != "image:\n  tag: v2.0.0\n" {
+		t.Errorf("updated file = %q, want the transformed tag v2.0.0", content)
+	}
+	if cfg.NewTag != "2.0.0" {
+		t.Errorf("config tag = %q, want it left as given", cfg.NewTag)
+	}
+}
+
+func TestUpdater_RequiresSettings(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("New(nil) expected an error")
+	}
+
+	updater, err := New(&Config{FilePath: TestFilePath})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err := updater.Update(context.Background()); err == nil {
+		t.Error("Update() expected an error for an incomplete config")
+	}
+}